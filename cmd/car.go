@@ -0,0 +1,149 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/vulcanize/vulcanizedb/pkg/ipfs"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/car"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+	v "github.com/vulcanize/vulcanizedb/version"
+)
+
+// carCmd represents the car command
+var carCmd = &cobra.Command{
+	Use:   "car",
+	Short: "Export and import super node data as CAR files",
+	Long: `Use the export and import subcommands to move the IPLDs and CID index of a range of blocks
+between super node deployments, or to archive them offline, as CARv1 files`,
+}
+
+// carExportCmd represents the car export command
+var carExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export a range of blocks to a CAR file",
+	Long: `Use this command to write all the IPLDs referenced by the eth or btc cid tables for a range of blocks,
+along with the index rows that reference them, to a CAR file with the header CIDs as its roots`,
+	Run: func(cmd *cobra.Command, args []string) {
+		subCommand = cmd.CalledAs()
+		logWithCommand = *log.WithField("SubCommand", subCommand)
+		carExport()
+	},
+}
+
+// carImportCmd represents the car import command
+var carImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import a CAR file written by car export",
+	Long: `Use this command to load the IPLDs in a CAR file written by car export into public.blocks or IPFS,
+and rebuild the CID index rows that reference them`,
+	Run: func(cmd *cobra.Command, args []string) {
+		subCommand = cmd.CalledAs()
+		logWithCommand = *log.WithField("SubCommand", subCommand)
+		carImport()
+	},
+}
+
+func carExport() {
+	cConfig := loadCARConfig()
+	if cConfig.Stop < cConfig.Start {
+		logWithCommand.Fatalf("car stop height %d is below the start height %d", cConfig.Stop, cConfig.Start)
+	}
+	exporter, err := super_node.NewCARExporter(cConfig.Chain, cConfig.IPFSPath, cConfig.IPFSRemoteConfig, cConfig.DB, cConfig.IPFSMode)
+	if err != nil {
+		logWithCommand.Fatal(err)
+	}
+	file, err := os.Create(cConfig.File)
+	if err != nil {
+		logWithCommand.Fatal(err)
+	}
+	defer file.Close()
+	logWithCommand.Infof("exporting %s blocks %d to %d to %s", cConfig.Chain.String(), cConfig.Start, cConfig.Stop, cConfig.File)
+	exported, err := exporter.Export(file, cConfig.Start, cConfig.Stop)
+	if err != nil {
+		logWithCommand.Fatal(err)
+	}
+	if err := file.Sync(); err != nil {
+		logWithCommand.Fatal(err)
+	}
+	logWithCommand.Infof("exported %d %s blocks to %s", exported, cConfig.Chain.String(), cConfig.File)
+}
+
+func carImport() {
+	cConfig := loadCARConfig()
+	importer, err := super_node.NewCARImporter(cConfig.Chain, cConfig.IPFSPath, cConfig.IPFSRemoteConfig, cConfig.DB, cConfig.IPFSMode)
+	if err != nil {
+		logWithCommand.Fatal(err)
+	}
+	file, err := os.Open(cConfig.File)
+	if err != nil {
+		logWithCommand.Fatal(err)
+	}
+	defer file.Close()
+	logWithCommand.Infof("importing %s blocks from %s", cConfig.Chain.String(), cConfig.File)
+	imported, err := importer.Import(file)
+	if err != nil {
+		logWithCommand.Fatalf("import failed after %d blocks: %v", imported, err)
+	}
+	logWithCommand.Infof("imported %d %s blocks from %s", imported, cConfig.Chain.String(), cConfig.File)
+}
+
+func loadCARConfig() *car.Config {
+	logWithCommand.Infof("running vdb version: %s", v.VersionWithMeta)
+	logWithCommand.Debug("loading car configuration variables")
+	cConfig, err := car.NewCARConfig()
+	if err != nil {
+		logWithCommand.Fatal(err)
+	}
+	logWithCommand.Infof("car config: %+v", cConfig)
+	if cConfig.IPFSMode == shared.LocalInterface {
+		if err := ipfs.InitIPFSPlugins(); err != nil {
+			logWithCommand.Fatal(err)
+		}
+	}
+	return cConfig
+}
+
+func init() {
+	rootCmd.AddCommand(carCmd)
+	carCmd.AddCommand(carExportCmd)
+	carCmd.AddCommand(carImportCmd)
+
+	// flags
+	carCmd.PersistentFlags().String("ipfs-path", "", "ipfs repository path")
+	carCmd.PersistentFlags().String("ipfs-url", "", "url of the http api of a remote ipfs node, used in the remote ipfs mode")
+
+	carCmd.PersistentFlags().String("car-chain", "", "which chain the data belongs to, options are currently Ethereum or Bitcoin.")
+	carCmd.PersistentFlags().String("car-file", "", "path of the CAR file to write to or read from")
+	carCmd.PersistentFlags().Int("car-start", 0, "first block height to export")
+	carCmd.PersistentFlags().Int("car-stop", 0, "last block height to export")
+
+	// and their bindings
+	viper.BindPFlag("ipfs.path", carCmd.PersistentFlags().Lookup("ipfs-path"))
+	viper.BindPFlag("ipfs.url", carCmd.PersistentFlags().Lookup("ipfs-url"))
+
+	viper.BindPFlag("car.chain", carCmd.PersistentFlags().Lookup("car-chain"))
+	viper.BindPFlag("car.file", carCmd.PersistentFlags().Lookup("car-file"))
+	viper.BindPFlag("car.start", carCmd.PersistentFlags().Lookup("car-start"))
+	viper.BindPFlag("car.stop", carCmd.PersistentFlags().Lookup("car-stop"))
+}
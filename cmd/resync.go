@@ -30,8 +30,18 @@ import (
 var resyncCmd = &cobra.Command{
 	Use:   "resync",
 	Short: "Resync historical data",
-	Long:  `Use this command to fill in sections of missing data in the super node`,
+	Long: `Use this command to fill in sections of missing data in the super node
+
+Each resync is persisted as a job along with the progress of its batches, and the unfinished jobs
+of the chain are resumed, skipping the batches they have already completed, before any new ranges are resynced`,
 	Run: func(cmd *cobra.Command, args []string) {
+		// the start and stop flags are only bound when given, so that an explicit 0-0 range can be told apart from no range
+		if cmd.Flags().Changed("resync-start") {
+			viper.BindPFlag("resync.start", cmd.Flags().Lookup("resync-start"))
+		}
+		if cmd.Flags().Changed("resync-stop") {
+			viper.BindPFlag("resync.stop", cmd.Flags().Lookup("resync-stop"))
+		}
 		subCommand = cmd.CalledAs()
 		logWithCommand = *log.WithField("SubCommand", subCommand)
 		rsyncCmdCommand()
@@ -46,6 +56,10 @@ func rsyncCmdCommand() {
 		logWithCommand.Fatal(err)
 	}
 	logWithCommand.Infof("resync config: %+v", rConfig)
+	if rConfig.Report {
+		reportResyncJobs(rConfig)
+		return
+	}
 	if rConfig.IPFSMode == shared.LocalInterface {
 		if err := ipfs.InitIPFSPlugins(); err != nil {
 			logWithCommand.Fatal(err)
@@ -63,21 +77,46 @@ func rsyncCmdCommand() {
 	logWithCommand.Infof("%s %s resync finished", rConfig.Chain.String(), rConfig.ResyncType.String())
 }
 
+func reportResyncJobs(rConfig *resync.Config) {
+	jobs := resync.NewJobRepository(rConfig.DB)
+	chainJobs, err := jobs.Jobs(rConfig.Chain)
+	if err != nil {
+		logWithCommand.Fatal(err)
+	}
+	if len(chainJobs) == 0 {
+		logWithCommand.Infof("no %s resync jobs found", rConfig.Chain.String())
+	}
+	for _, job := range chainJobs {
+		progress, err := jobs.Progress(job.ID)
+		if err != nil {
+			logWithCommand.Fatal(err)
+		}
+		logWithCommand.Infof("%s %s resync job %d over ranges %v is %s: %s", rConfig.Chain.String(), job.DataType, job.ID, job.Ranges, job.Status, progress.String())
+	}
+}
+
 func init() {
 	rootCmd.AddCommand(resyncCmd)
 
 	// flags
 	resyncCmd.PersistentFlags().String("ipfs-path", "", "ipfs repository path")
+	resyncCmd.PersistentFlags().String("ipfs-url", "", "url of the http api of a remote ipfs node, used in the remote ipfs mode")
 
 	resyncCmd.PersistentFlags().String("resync-chain", "", "which chain to support, options are currently Ethereum or Bitcoin.")
 	resyncCmd.PersistentFlags().String("resync-type", "", "which type of data to resync")
 	resyncCmd.PersistentFlags().Int("resync-start", 0, "block height to start resync")
 	resyncCmd.PersistentFlags().Int("resync-stop", 0, "block height to stop resync")
+	resyncCmd.PersistentFlags().StringSlice("resync-ranges", nil, "block height ranges to resync, written as start-stop")
+	resyncCmd.PersistentFlags().String("resync-ranges-file", "", "path to a file listing block height ranges to resync, one start-stop range per line")
+	resyncCmd.PersistentFlags().Bool("resync-resume", true, "if true, resume the unfinished resync jobs of the chain before resyncing the provided ranges")
+	resyncCmd.PersistentFlags().Bool("resync-report", false, "if true, only report the progress of the resync jobs of the chain")
 	resyncCmd.PersistentFlags().Int("resync-batch-size", 0, "data fetching batch size")
 	resyncCmd.PersistentFlags().Int("resync-batch-number", 0, "how many goroutines to fetch data concurrently")
 	resyncCmd.PersistentFlags().Bool("resync-clear-old-cache", false, "if true, clear out old data of the provided type within the resync range before resyncing")
 	resyncCmd.PersistentFlags().Bool("resync-reset-validation", false, "if true, reset times_validated to 0")
 	resyncCmd.PersistentFlags().Int("resync-timeout", 15, "timeout used for resync http requests")
+	resyncCmd.PersistentFlags().Bool("resync-bulk-index", false, "if true, publish and index each batch of blocks together with COPY")
+	resyncCmd.PersistentFlags().Bool("resync-detach-partitions", false, "if true, clearing the old cache detaches the eth partitions within the range instead of dropping them")
 
 	resyncCmd.PersistentFlags().String("btc-http-path", "", "http url for bitcoin node")
 	resyncCmd.PersistentFlags().String("btc-password", "", "password for btc node")
@@ -92,19 +131,25 @@ func init() {
 	resyncCmd.PersistentFlags().String("eth-client-name", "", "eth client name")
 	resyncCmd.PersistentFlags().String("eth-genesis-block", "", "eth genesis block hash")
 	resyncCmd.PersistentFlags().String("eth-network-id", "", "eth network id")
+	resyncCmd.PersistentFlags().String("eth-genesis-file", "", "path to a genesis json file whose chain config is used instead of the one for the eth network id")
 
 	// and their bindings
 	viper.BindPFlag("ipfs.path", resyncCmd.PersistentFlags().Lookup("ipfs-path"))
+	viper.BindPFlag("ipfs.url", resyncCmd.PersistentFlags().Lookup("ipfs-url"))
 
 	viper.BindPFlag("resync.chain", resyncCmd.PersistentFlags().Lookup("resync-chain"))
 	viper.BindPFlag("resync.type", resyncCmd.PersistentFlags().Lookup("resync-type"))
-	viper.BindPFlag("resync.start", resyncCmd.PersistentFlags().Lookup("resync-start"))
-	viper.BindPFlag("resync.stop", resyncCmd.PersistentFlags().Lookup("resync-stop"))
+	viper.BindPFlag("resync.ranges", resyncCmd.PersistentFlags().Lookup("resync-ranges"))
+	viper.BindPFlag("resync.rangesFile", resyncCmd.PersistentFlags().Lookup("resync-ranges-file"))
+	viper.BindPFlag("resync.resume", resyncCmd.PersistentFlags().Lookup("resync-resume"))
+	viper.BindPFlag("resync.report", resyncCmd.PersistentFlags().Lookup("resync-report"))
 	viper.BindPFlag("resync.batchSize", resyncCmd.PersistentFlags().Lookup("resync-batch-size"))
 	viper.BindPFlag("resync.batchNumber", resyncCmd.PersistentFlags().Lookup("resync-batch-number"))
 	viper.BindPFlag("resync.clearOldCache", resyncCmd.PersistentFlags().Lookup("resync-clear-old-cache"))
 	viper.BindPFlag("resync.resetValidation", resyncCmd.PersistentFlags().Lookup("resync-reset-validation"))
 	viper.BindPFlag("resync.timeout", resyncCmd.PersistentFlags().Lookup("resync-timeout"))
+	viper.BindPFlag("resync.bulkIndex", resyncCmd.PersistentFlags().Lookup("resync-bulk-index"))
+	viper.BindPFlag("resync.detachPartitions", resyncCmd.PersistentFlags().Lookup("resync-detach-partitions"))
 
 	viper.BindPFlag("bitcoin.httpPath", resyncCmd.PersistentFlags().Lookup("btc-http-path"))
 	viper.BindPFlag("bitcoin.pass", resyncCmd.PersistentFlags().Lookup("btc-password"))
@@ -119,4 +164,5 @@ func init() {
 	viper.BindPFlag("ethereum.clientName", resyncCmd.PersistentFlags().Lookup("eth-client-name"))
 	viper.BindPFlag("ethereum.genesisBlock", resyncCmd.PersistentFlags().Lookup("eth-genesis-block"))
 	viper.BindPFlag("ethereum.networkID", resyncCmd.PersistentFlags().Lookup("eth-network-id"))
+	viper.BindPFlag("ethereum.genesisFile", resyncCmd.PersistentFlags().Lookup("eth-genesis-file"))
 }
@@ -16,17 +16,25 @@
 package cmd
 
 import (
+	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"sync"
 
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
 	"github.com/vulcanize/vulcanizedb/pkg/ipfs"
 	"github.com/vulcanize/vulcanizedb/pkg/super_node"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/btc"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth/graphql"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/resync"
 	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
 	v "github.com/vulcanize/vulcanizedb/version"
 )
@@ -56,71 +64,168 @@ and fill in gaps in the data
 func superNode() {
 	logWithCommand.Infof("running vdb version: %s", v.VersionWithMeta)
 	logWithCommand.Debug("loading super node configuration variables")
-	superNodeConfig, err := super_node.NewSuperNodeConfig()
+	superNodeConfigs, err := super_node.NewSuperNodeConfigs()
 	if err != nil {
 		logWithCommand.Fatal(err)
 	}
-	logWithCommand.Infof("super node config: %+v", superNodeConfig)
-	if superNodeConfig.IPFSMode == shared.LocalInterface {
-		if err := ipfs.InitIPFSPlugins(); err != nil {
-			logWithCommand.Fatal(err)
+	for _, superNodeConfig := range superNodeConfigs {
+		logWithCommand.Infof("%s super node config: %+v", superNodeConfig.Chain.String(), superNodeConfig)
+		if superNodeConfig.IPFSMode == shared.LocalInterface {
+			if err := ipfs.InitIPFSPlugins(); err != nil {
+				logWithCommand.Fatal(err)
+			}
 		}
 	}
+	// Settings that are not chain specific (servers and metrics) are the same for every config
+	settings := superNodeConfigs[0]
 	wg := &sync.WaitGroup{}
-	logWithCommand.Debug("initializing new super node service")
-	superNode, err := super_node.NewSuperNode(superNodeConfig)
-	if err != nil {
-		logWithCommand.Fatal(err)
-	}
-	var forwardPayloadChan chan shared.ConvertedData
-	if superNodeConfig.Serve {
-		logWithCommand.Info("starting up super node servers")
-		forwardPayloadChan = make(chan shared.ConvertedData, super_node.PayloadChanBufferSize)
-		superNode.Serve(wg, forwardPayloadChan)
-		if err := startServers(superNode, superNodeConfig); err != nil {
+	superNodes := make([]super_node.SuperNode, 0, len(superNodeConfigs))
+	backFillers := make([]super_node.BackFillInterface, 0, len(superNodeConfigs))
+	for _, superNodeConfig := range superNodeConfigs {
+		logWithCommand.Debugf("initializing new %s super node service", superNodeConfig.Chain.String())
+		superNode, err := super_node.NewSuperNode(superNodeConfig)
+		if err != nil {
 			logWithCommand.Fatal(err)
 		}
-	}
-	if superNodeConfig.Sync {
-		logWithCommand.Info("starting up super node sync process")
-		if err := superNode.Sync(wg, forwardPayloadChan); err != nil {
-			logWithCommand.Fatal(err)
+		superNodes = append(superNodes, superNode)
+		var forwardPayloadChan chan shared.ConvertedData
+		if superNodeConfig.Serve {
+			logWithCommand.Infof("starting up %s super node serve process", superNodeConfig.Chain.String())
+			forwardPayloadChan = make(chan shared.ConvertedData, super_node.PayloadChanBufferSize)
+			superNode.Serve(wg, forwardPayloadChan)
+		}
+		if superNodeConfig.Sync {
+			logWithCommand.Infof("starting up %s super node sync process", superNodeConfig.Chain.String())
+			if err := superNode.Sync(wg, forwardPayloadChan); err != nil {
+				logWithCommand.Fatal(err)
+			}
+		}
+		if superNodeConfig.BackFill {
+			logWithCommand.Debugf("initializing new %s super node backfill service", superNodeConfig.Chain.String())
+			backFiller, err := super_node.NewBackFillService(superNodeConfig, forwardPayloadChan)
+			if err != nil {
+				logWithCommand.Fatal(err)
+			}
+			logWithCommand.Infof("starting up %s super node backfill process", superNodeConfig.Chain.String())
+			backFiller.BackFill(wg)
+			superNode.AttachBackFiller(backFiller)
+			backFillers = append(backFillers, backFiller)
+			resyncer, err := resync.NewLauncher(superNodeConfig)
+			if err != nil {
+				logWithCommand.Fatal(err)
+			}
+			superNode.AttachResyncer(resyncer)
 		}
 	}
-	var backFiller super_node.BackFillInterface
-	if superNodeConfig.BackFill {
-		logWithCommand.Debug("initializing new super node backfill service")
-		backFiller, err = super_node.NewBackFillService(superNodeConfig, forwardPayloadChan)
-		if err != nil {
+	if settings.MetricsEndpoint != "" {
+		startMetricsServer(settings.MetricsEndpoint, superNodes)
+	}
+	if settings.Serve {
+		logWithCommand.Info("starting up super node servers")
+		if err := startServers(superNodes, superNodeConfigs); err != nil {
 			logWithCommand.Fatal(err)
 		}
-		logWithCommand.Info("starting up super node backfill process")
-		backFiller.BackFill(wg)
 	}
 	shutdown := make(chan os.Signal)
 	signal.Notify(shutdown, os.Interrupt)
 	<-shutdown
-	if superNodeConfig.BackFill {
+	for _, backFiller := range backFillers {
 		backFiller.Stop()
 	}
-	superNode.Stop()
+	for _, superNode := range superNodes {
+		superNode.Stop()
+	}
 	wg.Wait()
 }
 
-func startServers(superNode super_node.SuperNode, settings *super_node.Config) error {
+// startServers serves the APIs of every super node over one set of IPC, WS, and HTTP servers
+// The admin APIs are only served over IPC
+func startServers(superNodes []super_node.SuperNode, superNodeConfigs []*super_node.Config) error {
+	settings := superNodeConfigs[0]
+	apis := super_node.InfoAPIs()
+	wsModules := make([]string, 0, 2*len(superNodes))
+	httpModules := make([]string, 0, len(superNodes))
+	for i, superNode := range superNodes {
+		apis = append(apis, superNode.APIs()...)
+		vdbNamespace := super_node.APIName
+		if superNodeConfigs[i].ChainNamespaces {
+			vdbNamespace = super_node.ChainNamespace(super_node.APIName, superNodeConfigs[i].Chain)
+		}
+		chainModules := []string{superNodeConfigs[i].Chain.API()}
+		if superNodeConfigs[i].Chain == shared.Omni {
+			// omni super nodes serve the bitcoin api over the data they index
+			chainModules = append(chainModules, btc.APIName)
+		}
+		wsModules = append(append(wsModules, vdbNamespace), chainModules...)
+		httpModules = append(httpModules, chainModules...)
+	}
 	logWithCommand.Debug("starting up IPC server")
-	_, _, err := rpc.StartIPCEndpoint(settings.IPCEndpoint, superNode.APIs())
+	_, _, err := rpc.StartIPCEndpoint(settings.IPCEndpoint, apis)
 	if err != nil {
 		return err
 	}
 	logWithCommand.Debug("starting up WS server")
-	_, _, err = rpc.StartWSEndpoint(settings.WSEndpoint, superNode.APIs(), []string{"vdb"}, nil, true)
+	_, _, err = super_node.StartWSEndpoint(settings.WSEndpoint, apis, wsModules)
 	if err != nil {
 		return err
 	}
 	logWithCommand.Debug("starting up HTTP server")
-	_, _, err = rpc.StartHTTPEndpoint(settings.HTTPEndpoint, superNode.APIs(), []string{settings.Chain.API()}, nil, nil, rpc.HTTPTimeouts{})
-	return err
+	_, _, err = super_node.StartHTTPEndpoint(settings.HTTPEndpoint, apis, httpModules)
+	if err != nil {
+		return err
+	}
+	if settings.GraphQLEndpoint == "" {
+		return nil
+	}
+	for _, superNodeConfig := range superNodeConfigs {
+		if superNodeConfig.Chain == shared.Ethereum {
+			return startGraphQLServer(superNodeConfig)
+		}
+	}
+	return startGraphQLServer(settings)
+}
+
+func startGraphQLServer(settings *super_node.Config) error {
+	if settings.Chain != shared.Ethereum {
+		return fmt.Errorf("graphql server is not supported for chain %s", settings.Chain.String())
+	}
+	logWithCommand.Debug("starting up GraphQL server")
+	chainConfig, ok := settings.ChainConfig.(*params.ChainConfig)
+	if !ok {
+		return fmt.Errorf("graphql server expected chain config type %T got %T", &params.ChainConfig{}, settings.ChainConfig)
+	}
+	backend, err := eth.NewEthBackend(settings.ServeDBConn, chainConfig)
+	if err != nil {
+		return err
+	}
+	graphQLServer, err := graphql.New(backend, settings.GraphQLEndpoint, nil, nil, rpc.HTTPTimeouts{})
+	if err != nil {
+		return err
+	}
+	return graphQLServer.Start()
+}
+
+// startMetricsServer serves the metrics handler on the endpoint
+func startMetricsServer(endpoint string, superNodes []super_node.SuperNode) {
+	logWithCommand.Infof("starting up metrics server on %s", endpoint)
+	handler := metricsHandler(superNodes)
+	go func() {
+		if err := http.ListenAndServe(endpoint, handler); err != nil {
+			logWithCommand.Errorf("metrics server error: %v", err)
+		}
+	}()
+}
+
+// metricsHandler serves the prometheus metrics, which are labeled by chain,
+// the combined health of the super nodes at /health, and the health of each one at /health/{chain}
+func metricsHandler(superNodes []super_node.SuperNode) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/health", super_node.HealthHandler(superNodes...))
+	for _, superNode := range superNodes {
+		mux.Handle("/health/"+superNode.Chain().API(), super_node.HealthHandler(superNode))
+	}
+	return mux
 }
 
 func init() {
@@ -128,8 +233,10 @@ func init() {
 
 	// flags for all config variables
 	superNodeCmd.PersistentFlags().String("ipfs-path", "", "ipfs repository path")
+	superNodeCmd.PersistentFlags().String("ipfs-url", "", "url of the http api of a remote ipfs node, used in the remote ipfs mode")
 
-	superNodeCmd.PersistentFlags().String("supernode-chain", "", "which chain to support, options are currently Ethereum or Bitcoin.")
+	superNodeCmd.PersistentFlags().String("supernode-chain", "", "which chain to support, options are currently Ethereum, Bitcoin or Omni.")
+	superNodeCmd.PersistentFlags().StringSlice("supernode-chains", nil, "chains to support in one process, overrides supernode-chain")
 	superNodeCmd.PersistentFlags().Bool("supernode-server", false, "turn vdb server on or off")
 	superNodeCmd.PersistentFlags().String("supernode-ws-path", "", "vdb server ws path")
 	superNodeCmd.PersistentFlags().String("supernode-http-path", "", "vdb server http path")
@@ -142,6 +249,10 @@ func init() {
 	superNodeCmd.PersistentFlags().Int("supernode-batch-number", 0, "how many goroutines to fetch data concurrently")
 	superNodeCmd.PersistentFlags().Int("supernode-validation-level", 0, "backfill will resync any data below this level")
 	superNodeCmd.PersistentFlags().Int("supernode-timeout", 0, "timeout used for backfill http requests")
+	superNodeCmd.PersistentFlags().Bool("supernode-bulk-index", false, "if true, backfill publishes and indexes each batch of blocks together with COPY")
+	superNodeCmd.PersistentFlags().String("supernode-metrics-path", "", "vdb metrics server http path, metrics are not served if unset")
+	superNodeCmd.PersistentFlags().String("supernode-graphql-path", "", "vdb graphql server http path, graphql is not served if unset")
+	superNodeCmd.PersistentFlags().Bool("supernode-verify-iplds", false, "if true, the IPLDs served from public.blocks are verified against their CIDs")
 
 	superNodeCmd.PersistentFlags().String("btc-ws-path", "", "ws url for bitcoin node")
 	superNodeCmd.PersistentFlags().String("btc-http-path", "", "http url for bitcoin node")
@@ -158,11 +269,14 @@ func init() {
 	superNodeCmd.PersistentFlags().String("eth-client-name", "", "eth client name")
 	superNodeCmd.PersistentFlags().String("eth-genesis-block", "", "eth genesis block hash")
 	superNodeCmd.PersistentFlags().String("eth-network-id", "", "eth network id")
+	superNodeCmd.PersistentFlags().String("eth-genesis-file", "", "path to a genesis json file whose chain config is used instead of the one for the eth network id")
 
 	// and their bindings
 	viper.BindPFlag("ipfs.path", superNodeCmd.PersistentFlags().Lookup("ipfs-path"))
+	viper.BindPFlag("ipfs.url", superNodeCmd.PersistentFlags().Lookup("ipfs-url"))
 
 	viper.BindPFlag("superNode.chain", superNodeCmd.PersistentFlags().Lookup("supernode-chain"))
+	viper.BindPFlag("superNode.chains", superNodeCmd.PersistentFlags().Lookup("supernode-chains"))
 	viper.BindPFlag("superNode.server", superNodeCmd.PersistentFlags().Lookup("supernode-server"))
 	viper.BindPFlag("superNode.wsPath", superNodeCmd.PersistentFlags().Lookup("supernode-ws-path"))
 	viper.BindPFlag("superNode.httpPath", superNodeCmd.PersistentFlags().Lookup("supernode-http-path"))
@@ -175,6 +289,10 @@ func init() {
 	viper.BindPFlag("superNode.batchNumber", superNodeCmd.PersistentFlags().Lookup("supernode-batch-number"))
 	viper.BindPFlag("superNode.validationLevel", superNodeCmd.PersistentFlags().Lookup("supernode-validation-level"))
 	viper.BindPFlag("superNode.timeout", superNodeCmd.PersistentFlags().Lookup("supernode-timeout"))
+	viper.BindPFlag("superNode.bulkIndex", superNodeCmd.PersistentFlags().Lookup("supernode-bulk-index"))
+	viper.BindPFlag("superNode.metricsPath", superNodeCmd.PersistentFlags().Lookup("supernode-metrics-path"))
+	viper.BindPFlag("superNode.graphqlPath", superNodeCmd.PersistentFlags().Lookup("supernode-graphql-path"))
+	viper.BindPFlag("superNode.verifyIPLDs", superNodeCmd.PersistentFlags().Lookup("supernode-verify-iplds"))
 
 	viper.BindPFlag("bitcoin.wsPath", superNodeCmd.PersistentFlags().Lookup("btc-ws-path"))
 	viper.BindPFlag("bitcoin.httpPath", superNodeCmd.PersistentFlags().Lookup("btc-http-path"))
@@ -191,4 +309,5 @@ func init() {
 	viper.BindPFlag("ethereum.clientName", superNodeCmd.PersistentFlags().Lookup("eth-client-name"))
 	viper.BindPFlag("ethereum.genesisBlock", superNodeCmd.PersistentFlags().Lookup("eth-genesis-block"))
 	viper.BindPFlag("ethereum.networkID", superNodeCmd.PersistentFlags().Lookup("eth-network-id"))
+	viper.BindPFlag("ethereum.genesisFile", superNodeCmd.PersistentFlags().Lookup("eth-genesis-file"))
 }
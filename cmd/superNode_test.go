@@ -0,0 +1,84 @@
+// Copyright © 2020 Vulcanize, Inc
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/vulcanizedb/pkg/super_node"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+var _ = Describe("Metrics server", func() {
+	var (
+		superNode super_node.SuperNode
+		server    *httptest.Server
+	)
+	BeforeEach(func() {
+		var err error
+		superNode, err = super_node.NewSuperNode(&super_node.Config{Chain: shared.Ethereum, Workers: 1})
+		Expect(err).ToNot(HaveOccurred())
+		superNode.Serve(new(sync.WaitGroup), make(chan shared.ConvertedData))
+		server = httptest.NewServer(metricsHandler([]super_node.SuperNode{superNode}))
+	})
+	AfterEach(func() {
+		server.Close()
+		superNode.Stop()
+	})
+
+	get := func(path string) (int, []byte) {
+		res, err := http.Get(server.URL + path)
+		Expect(err).ToNot(HaveOccurred())
+		defer res.Body.Close()
+		body, err := ioutil.ReadAll(res.Body)
+		Expect(err).ToNot(HaveOccurred())
+		return res.StatusCode, body
+	}
+
+	It("Serves the super node metrics", func() {
+		superNode.Subscribe(rpc.NewID(), make(chan super_node.SubscriptionPayload, 1), make(chan bool, 1), &eth.SubscriptionSettings{
+			Start: big.NewInt(0),
+			End:   big.NewInt(0),
+		})
+		code, body := get("/metrics")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(string(body)).To(ContainSubstring(`vdb_super_node_active_subscriptions{chain="Ethereum"`))
+	})
+
+	It("Serves the combined health of the super nodes, and the health of each one by chain", func() {
+		for _, path := range []string{"/health", "/health/eth"} {
+			code, body := get(path)
+			Expect(code).To(Equal(http.StatusOK))
+			var statuses []super_node.HealthStatus
+			Expect(json.Unmarshal(body, &statuses)).To(Succeed())
+			Expect(len(statuses)).To(Equal(1))
+			Expect(statuses[0].Chain).To(Equal(shared.Ethereum.String()))
+			Expect(statuses[0].Healthy).To(BeTrue())
+		}
+		code, _ := get("/health/btc")
+		Expect(code).To(Equal(http.StatusNotFound))
+	})
+})
@@ -0,0 +1,110 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/verify"
+	v "github.com/vulcanize/vulcanizedb/version"
+)
+
+// verifyCmd represents the verify command
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify the IPLDs stored for a range of blocks",
+	Long: `Use this command to check the IPLDs stored in public.blocks for a range of blocks against their CIDs,
+and the tx, receipt, and state roots of their headers against the stored IPLDs.
+Blocks found to be corrupt can be repaired by re-fetching them from the node.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		subCommand = cmd.CalledAs()
+		logWithCommand = *log.WithField("SubCommand", subCommand)
+		verifyCmdCommand()
+	},
+}
+
+func verifyCmdCommand() {
+	logWithCommand.Infof("running vdb version: %s", v.VersionWithMeta)
+	logWithCommand.Debug("loading verify configuration variables")
+	vConfig, err := verify.NewVerifyConfig()
+	if err != nil {
+		logWithCommand.Fatal(err)
+	}
+	logWithCommand.Infof("verify config: %+v", vConfig)
+	logWithCommand.Debug("initializing new verify service")
+	vService, err := verify.NewVerifyService(vConfig)
+	if err != nil {
+		logWithCommand.Fatal(err)
+	}
+	logWithCommand.Infof("verifying %s blocks %d to %d", vConfig.Chain.String(), vConfig.Start, vConfig.Stop)
+	report, err := vService.Verify()
+	if vConfig.ReportFile != "" && report != nil {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			logWithCommand.Fatal(err)
+		}
+		if err := ioutil.WriteFile(vConfig.ReportFile, data, 0644); err != nil {
+			logWithCommand.Fatal(err)
+		}
+	}
+	if err != nil {
+		logWithCommand.Fatal(err)
+	}
+	switch {
+	case len(report.Faults) == 0:
+		logWithCommand.Infof("%s blocks %d to %d verified, no faults found", vConfig.Chain.String(), vConfig.Start, vConfig.Stop)
+	case !vConfig.Repair:
+		logWithCommand.Fatalf("%d faults found in %s blocks %d to %d", len(report.Faults), vConfig.Chain.String(), vConfig.Start, vConfig.Stop)
+	case len(report.Remaining) > 0:
+		logWithCommand.Fatalf("%d of the %d faults found remain after repairing %d blocks", len(report.Remaining), len(report.Faults), len(report.Repaired))
+	default:
+		logWithCommand.Infof("repaired the %d faults found in %d blocks", len(report.Faults), len(report.Repaired))
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+
+	// flags
+	verifyCmd.PersistentFlags().String("verify-chain", "", "which chain to verify, options are currently Ethereum or Bitcoin.")
+	verifyCmd.PersistentFlags().Int("verify-start", 0, "block height to start verifying at")
+	verifyCmd.PersistentFlags().Int("verify-stop", 0, "block height to stop verifying at")
+	verifyCmd.PersistentFlags().Bool("verify-repair", false, "if true, blocks with faults are re-fetched from the node and republished")
+	verifyCmd.PersistentFlags().Int("verify-batch-size", 0, "number of blocks re-fetched from the node per request when repairing")
+	verifyCmd.PersistentFlags().String("verify-report", "", "if set, the faults found are written to this file as json")
+	verifyCmd.PersistentFlags().Int("verify-timeout", 15, "timeout used for the http requests made when repairing")
+
+	verifyCmd.PersistentFlags().String("btc-http-path", "", "http url for bitcoin node")
+	verifyCmd.PersistentFlags().String("eth-http-path", "", "http url for ethereum node")
+
+	// and their bindings
+	viper.BindPFlag("verify.chain", verifyCmd.PersistentFlags().Lookup("verify-chain"))
+	viper.BindPFlag("verify.start", verifyCmd.PersistentFlags().Lookup("verify-start"))
+	viper.BindPFlag("verify.stop", verifyCmd.PersistentFlags().Lookup("verify-stop"))
+	viper.BindPFlag("verify.repair", verifyCmd.PersistentFlags().Lookup("verify-repair"))
+	viper.BindPFlag("verify.batchSize", verifyCmd.PersistentFlags().Lookup("verify-batch-size"))
+	viper.BindPFlag("verify.report", verifyCmd.PersistentFlags().Lookup("verify-report"))
+	viper.BindPFlag("verify.timeout", verifyCmd.PersistentFlags().Lookup("verify-timeout"))
+
+	viper.BindPFlag("bitcoin.httpPath", verifyCmd.PersistentFlags().Lookup("btc-http-path"))
+	viper.BindPFlag("ethereum.httpPath", verifyCmd.PersistentFlags().Lookup("eth-http-path"))
+}
@@ -0,0 +1,29 @@
+-- +goose Up
+ALTER TABLE eth.header_cids
+ADD COLUMN canonical BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- Keep one canonical header at each height where competing headers are already indexed:
+-- the one that is the parent of a header at the next height, then the one validated the most times, then the latest indexed
+UPDATE eth.header_cids SET canonical = FALSE
+WHERE id IN (
+  SELECT id FROM (
+    SELECT header_cids.id, ROW_NUMBER() OVER (
+      PARTITION BY header_cids.block_number
+      ORDER BY EXISTS (SELECT 1 FROM eth.header_cids AS children
+                       WHERE children.block_number = header_cids.block_number + 1
+                       AND children.parent_hash = header_cids.block_hash) DESC,
+               header_cids.times_validated DESC,
+               header_cids.id DESC
+    ) AS rank
+    FROM eth.header_cids
+  ) AS ranked
+  WHERE rank > 1
+);
+
+CREATE INDEX header_cids_canonical_block_number_idx ON eth.header_cids USING btree (block_number) WHERE canonical;
+
+-- +goose Down
+DROP INDEX eth.header_cids_canonical_block_number_idx;
+
+ALTER TABLE eth.header_cids
+DROP COLUMN canonical;
@@ -0,0 +1,26 @@
+-- +goose Up
+CREATE TABLE eth.spill_queue (
+  id SERIAL PRIMARY KEY,
+  data BYTEA NOT NULL,
+  height BIGINT NOT NULL
+);
+
+CREATE TABLE eth.known_gaps (
+  block_number BIGINT PRIMARY KEY
+);
+
+CREATE TABLE btc.spill_queue (
+  id SERIAL PRIMARY KEY,
+  data BYTEA NOT NULL,
+  height BIGINT NOT NULL
+);
+
+CREATE TABLE btc.known_gaps (
+  block_number BIGINT PRIMARY KEY
+);
+
+-- +goose Down
+DROP TABLE btc.known_gaps;
+DROP TABLE btc.spill_queue;
+DROP TABLE eth.known_gaps;
+DROP TABLE eth.spill_queue;
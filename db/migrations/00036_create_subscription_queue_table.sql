@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE public.subscription_queue (
+  id SERIAL PRIMARY KEY,
+  subscription_id TEXT NOT NULL,
+  data BYTEA NOT NULL
+);
+
+CREATE INDEX subscription_queue_subscription_id_index ON public.subscription_queue USING btree (subscription_id, id);
+
+-- +goose Down
+DROP INDEX public.subscription_queue_subscription_id_index;
+DROP TABLE public.subscription_queue;
@@ -0,0 +1,81 @@
+-- +goose Up
+ALTER TABLE btc.header_cids
+ADD COLUMN canonical BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- Keep one canonical header at each height where competing headers are already indexed:
+-- the one that is the parent of a header at the next height, then the one validated the most times, then the latest indexed
+UPDATE btc.header_cids SET canonical = FALSE
+WHERE id IN (
+  SELECT id FROM (
+    SELECT header_cids.id, ROW_NUMBER() OVER (
+      PARTITION BY header_cids.block_number
+      ORDER BY EXISTS (SELECT 1 FROM btc.header_cids AS children
+                       WHERE children.block_number = header_cids.block_number + 1
+                       AND children.parent_hash = header_cids.block_hash) DESC,
+               header_cids.times_validated DESC,
+               header_cids.id DESC
+    ) AS rank
+    FROM btc.header_cids
+  ) AS ranked
+  WHERE rank > 1
+);
+
+CREATE INDEX header_cids_canonical_block_number_idx ON btc.header_cids USING btree (block_number) WHERE canonical;
+
+ALTER TABLE btc.tx_inputs
+ADD COLUMN spent_output_id INTEGER REFERENCES btc.tx_outputs (id) ON DELETE SET NULL DEFERRABLE INITIALLY DEFERRED;
+
+CREATE INDEX tx_inputs_outpoint_idx ON btc.tx_inputs USING btree (outpoint_tx_hash, outpoint_index);
+
+CREATE INDEX tx_inputs_spent_output_id_idx ON btc.tx_inputs USING btree (spent_output_id);
+
+CREATE INDEX tx_outputs_addresses_idx ON btc.tx_outputs USING gin (addresses);
+
+CREATE TABLE btc.address_history (
+  id       SERIAL PRIMARY KEY,
+  address  VARCHAR(66) NOT NULL,
+  tx_id    INTEGER NOT NULL REFERENCES btc.transaction_cids (id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED,
+  received BIGINT NOT NULL,
+  spent    BIGINT NOT NULL,
+  UNIQUE (address, tx_id)
+);
+
+CREATE INDEX address_history_tx_id_idx ON btc.address_history USING btree (tx_id);
+
+-- Link the inputs already indexed to the outputs they spend, in canonical headers only
+UPDATE btc.tx_inputs SET spent_output_id = tx_outputs.id
+FROM btc.tx_outputs
+INNER JOIN btc.transaction_cids ON (tx_outputs.tx_id = transaction_cids.id)
+INNER JOIN btc.header_cids ON (transaction_cids.header_id = header_cids.id)
+WHERE transaction_cids.tx_hash = tx_inputs.outpoint_tx_hash
+AND tx_outputs.index = tx_inputs.outpoint_index
+AND header_cids.canonical;
+
+-- Build the address history of the transactions already indexed from their outputs and linked inputs
+INSERT INTO btc.address_history (address, tx_id, received, spent)
+SELECT address, tx_id, SUM(received), SUM(spent) FROM (
+  SELECT UNNEST(addresses) AS address, tx_id, value AS received, 0 AS spent
+  FROM btc.tx_outputs
+  UNION ALL
+  SELECT UNNEST(tx_outputs.addresses), tx_inputs.tx_id, 0, tx_outputs.value
+  FROM btc.tx_inputs
+  INNER JOIN btc.tx_outputs ON (tx_inputs.spent_output_id = tx_outputs.id)
+) AS flows
+GROUP BY address, tx_id;
+
+-- +goose Down
+DROP TABLE btc.address_history;
+
+DROP INDEX btc.tx_outputs_addresses_idx;
+
+DROP INDEX btc.tx_inputs_spent_output_id_idx;
+
+DROP INDEX btc.tx_inputs_outpoint_idx;
+
+ALTER TABLE btc.tx_inputs
+DROP COLUMN spent_output_id;
+
+DROP INDEX btc.header_cids_canonical_block_number_idx;
+
+ALTER TABLE btc.header_cids
+DROP COLUMN canonical;
@@ -0,0 +1,32 @@
+-- +goose Up
+CREATE SCHEMA omni;
+
+CREATE TABLE omni.transactions (
+  id                  SERIAL PRIMARY KEY,
+  tx_id               INTEGER NOT NULL REFERENCES btc.transaction_cids (id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED,
+  class               SMALLINT NOT NULL,
+  version             INTEGER NOT NULL,
+  tx_type             INTEGER NOT NULL,
+  sender              VARCHAR(66),
+  reference           VARCHAR(66),
+  property_id         BIGINT,
+  amount              BIGINT,
+  desired_property_id BIGINT,
+  desired_amount      BIGINT,
+  ecosystem           SMALLINT,
+  payload             BYTEA NOT NULL,
+  UNIQUE (tx_id)
+);
+
+CREATE INDEX omni_transactions_tx_type_idx ON omni.transactions USING btree (tx_type);
+
+CREATE INDEX omni_transactions_property_id_idx ON omni.transactions USING btree (property_id);
+
+CREATE INDEX omni_transactions_sender_idx ON omni.transactions USING btree (sender);
+
+CREATE INDEX omni_transactions_reference_idx ON omni.transactions USING btree (reference);
+
+-- +goose Down
+DROP TABLE omni.transactions;
+
+DROP SCHEMA omni;
@@ -0,0 +1,380 @@
+-- +goose Up
+-- The eth index tables are rebuilt as tables partitioned by block number range
+-- Foreign keys cannot reference a partitioned table, so instead each partition of a child table references the partition of its
+-- parent table covering the same range; eth.create_block_partitions sets these up as it creates the partitions
+CREATE SCHEMA eth_unpartitioned;
+
+ALTER SEQUENCE eth.header_cids_id_seq OWNED BY NONE;
+ALTER SEQUENCE eth.uncle_cids_id_seq OWNED BY NONE;
+ALTER SEQUENCE eth.transaction_cids_id_seq OWNED BY NONE;
+ALTER SEQUENCE eth.receipt_cids_id_seq OWNED BY NONE;
+ALTER SEQUENCE eth.state_cids_id_seq OWNED BY NONE;
+ALTER SEQUENCE eth.state_accounts_id_seq OWNED BY NONE;
+ALTER SEQUENCE eth.storage_cids_id_seq OWNED BY NONE;
+
+ALTER TABLE eth.header_cids SET SCHEMA eth_unpartitioned;
+ALTER TABLE eth.uncle_cids SET SCHEMA eth_unpartitioned;
+ALTER TABLE eth.transaction_cids SET SCHEMA eth_unpartitioned;
+ALTER TABLE eth.receipt_cids SET SCHEMA eth_unpartitioned;
+ALTER TABLE eth.state_cids SET SCHEMA eth_unpartitioned;
+ALTER TABLE eth.state_accounts SET SCHEMA eth_unpartitioned;
+ALTER TABLE eth.storage_cids SET SCHEMA eth_unpartitioned;
+
+CREATE TABLE eth.header_cids (
+  id                    INTEGER NOT NULL DEFAULT nextval('eth.header_cids_id_seq'),
+  block_number          BIGINT NOT NULL,
+  block_hash            VARCHAR(66) NOT NULL,
+  parent_hash           VARCHAR(66) NOT NULL,
+  cid                   TEXT NOT NULL,
+  td                    NUMERIC NOT NULL,
+  node_id               INTEGER NOT NULL REFERENCES nodes (id) ON DELETE CASCADE,
+  reward                NUMERIC NOT NULL,
+  state_root            VARCHAR(66),
+  tx_root               VARCHAR(66),
+  receipt_root          VARCHAR(66),
+  uncle_root            VARCHAR(66),
+  bloom                 BYTEA,
+  timestamp             NUMERIC,
+  times_validated       INTEGER NOT NULL DEFAULT 1,
+  canonical             BOOLEAN NOT NULL DEFAULT TRUE,
+  PRIMARY KEY (id, block_number),
+  UNIQUE (block_number, block_hash)
+) PARTITION BY RANGE (block_number);
+
+CREATE INDEX header_cids_canonical_block_number_idx ON eth.header_cids USING btree (block_number) WHERE canonical;
+
+CREATE TABLE eth.uncle_cids (
+  id                    INTEGER NOT NULL DEFAULT nextval('eth.uncle_cids_id_seq'),
+  header_id             INTEGER NOT NULL,
+  block_number          BIGINT NOT NULL,
+  block_hash            VARCHAR(66) NOT NULL,
+  parent_hash           VARCHAR(66) NOT NULL,
+  cid                   TEXT NOT NULL,
+  reward                NUMERIC NOT NULL,
+  PRIMARY KEY (id, block_number),
+  UNIQUE (header_id, block_hash, block_number)
+) PARTITION BY RANGE (block_number);
+
+CREATE TABLE eth.transaction_cids (
+  id                    INTEGER NOT NULL DEFAULT nextval('eth.transaction_cids_id_seq'),
+  header_id             INTEGER NOT NULL,
+  block_number          BIGINT NOT NULL,
+  tx_hash               VARCHAR(66) NOT NULL,
+  index                 INTEGER NOT NULL,
+  cid                   TEXT NOT NULL,
+  dst                   VARCHAR(66) NOT NULL,
+  src                   VARCHAR(66) NOT NULL,
+  PRIMARY KEY (id, block_number),
+  UNIQUE (header_id, tx_hash, block_number)
+) PARTITION BY RANGE (block_number);
+
+CREATE TABLE eth.receipt_cids (
+  id                    INTEGER NOT NULL DEFAULT nextval('eth.receipt_cids_id_seq'),
+  tx_id                 INTEGER NOT NULL,
+  block_number          BIGINT NOT NULL,
+  cid                   TEXT NOT NULL,
+  contract              VARCHAR(66),
+  contract_hash         VARCHAR(66),
+  topic0s               VARCHAR(66)[],
+  topic1s               VARCHAR(66)[],
+  topic2s               VARCHAR(66)[],
+  topic3s               VARCHAR(66)[],
+  log_contracts         VARCHAR(66)[],
+  PRIMARY KEY (id, block_number),
+  UNIQUE (tx_id, block_number)
+) PARTITION BY RANGE (block_number);
+
+CREATE TABLE eth.state_cids (
+  id                    INTEGER NOT NULL DEFAULT nextval('eth.state_cids_id_seq'),
+  header_id             INTEGER NOT NULL,
+  block_number          BIGINT NOT NULL,
+  state_leaf_key        VARCHAR(66),
+  cid                   TEXT NOT NULL,
+  state_path            BYTEA,
+  node_type             INTEGER,
+  PRIMARY KEY (id, block_number),
+  UNIQUE (header_id, state_path, block_number)
+) PARTITION BY RANGE (block_number);
+
+CREATE TABLE eth.state_accounts (
+  id                    INTEGER NOT NULL DEFAULT nextval('eth.state_accounts_id_seq'),
+  state_id              INTEGER NOT NULL,
+  block_number          BIGINT NOT NULL,
+  balance               NUMERIC NOT NULL,
+  nonce                 INTEGER NOT NULL,
+  code_hash             BYTEA NOT NULL,
+  storage_root          VARCHAR(66) NOT NULL,
+  PRIMARY KEY (id, block_number),
+  UNIQUE (state_id, block_number)
+) PARTITION BY RANGE (block_number);
+
+CREATE TABLE eth.storage_cids (
+  id                    INTEGER NOT NULL DEFAULT nextval('eth.storage_cids_id_seq'),
+  state_id              INTEGER NOT NULL,
+  block_number          BIGINT NOT NULL,
+  storage_leaf_key      VARCHAR(66),
+  cid                   TEXT NOT NULL,
+  storage_path          BYTEA,
+  node_type             INTEGER,
+  PRIMARY KEY (id, block_number),
+  UNIQUE (state_id, storage_path, block_number)
+) PARTITION BY RANGE (block_number);
+
+ALTER SEQUENCE eth.header_cids_id_seq OWNED BY eth.header_cids.id;
+ALTER SEQUENCE eth.uncle_cids_id_seq OWNED BY eth.uncle_cids.id;
+ALTER SEQUENCE eth.transaction_cids_id_seq OWNED BY eth.transaction_cids.id;
+ALTER SEQUENCE eth.receipt_cids_id_seq OWNED BY eth.receipt_cids.id;
+ALTER SEQUENCE eth.state_cids_id_seq OWNED BY eth.state_cids.id;
+ALTER SEQUENCE eth.state_accounts_id_seq OWNED BY eth.state_accounts.id;
+ALTER SEQUENCE eth.storage_cids_id_seq OWNED BY eth.storage_cids.id;
+
+COMMENT ON TABLE eth.header_cids IS E'@name EthHeaderCids';
+COMMENT ON TABLE eth.transaction_cids IS E'@name EthTransactionCids';
+COMMENT ON COLUMN eth.header_cids.node_id IS E'@name EthNodeID';
+
+-- The number of blocks covered by each partition
+-- +goose StatementBegin
+CREATE FUNCTION eth.block_partition_size() RETURNS BIGINT AS $$
+  SELECT 100000::BIGINT
+$$ LANGUAGE SQL IMMUTABLE;
+-- +goose StatementEnd
+
+-- Creates whichever partitions of the eth index tables covering the provided block number do not exist yet
+-- Returns the first block number of the following partition
+-- +goose StatementBegin
+CREATE FUNCTION eth.create_block_partitions(block_number BIGINT) RETURNS BIGINT AS $$
+DECLARE
+  partition_size BIGINT := eth.block_partition_size();
+  partition_start BIGINT := block_number - block_number % partition_size;
+  suffix TEXT := '_' || partition_start;
+  fk TEXT := 'ALTER TABLE eth.%I ADD FOREIGN KEY (%I, block_number) REFERENCES eth.%I (id, block_number) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED';
+BEGIN
+  IF to_regclass('eth.header_cids' || suffix) IS NOT NULL AND to_regclass('eth.uncle_cids' || suffix) IS NOT NULL
+    AND to_regclass('eth.transaction_cids' || suffix) IS NOT NULL AND to_regclass('eth.receipt_cids' || suffix) IS NOT NULL
+    AND to_regclass('eth.state_cids' || suffix) IS NOT NULL AND to_regclass('eth.state_accounts' || suffix) IS NOT NULL
+    AND to_regclass('eth.storage_cids' || suffix) IS NOT NULL THEN
+    RETURN partition_start + partition_size;
+  END IF;
+  -- Serialize concurrent callers, the ones that wait will find the partitions already created
+  PERFORM pg_advisory_xact_lock(hashtext('eth.create_block_partitions'));
+  IF to_regclass('eth.header_cids' || suffix) IS NULL THEN
+    EXECUTE format('CREATE TABLE eth.%I PARTITION OF eth.header_cids FOR VALUES FROM (%s) TO (%s)', 'header_cids' || suffix, partition_start, partition_start + partition_size);
+  END IF;
+  IF to_regclass('eth.uncle_cids' || suffix) IS NULL THEN
+    EXECUTE format('CREATE TABLE eth.%I PARTITION OF eth.uncle_cids FOR VALUES FROM (%s) TO (%s)', 'uncle_cids' || suffix, partition_start, partition_start + partition_size);
+    EXECUTE format(fk, 'uncle_cids' || suffix, 'header_id', 'header_cids' || suffix);
+  END IF;
+  IF to_regclass('eth.transaction_cids' || suffix) IS NULL THEN
+    EXECUTE format('CREATE TABLE eth.%I PARTITION OF eth.transaction_cids FOR VALUES FROM (%s) TO (%s)', 'transaction_cids' || suffix, partition_start, partition_start + partition_size);
+    EXECUTE format(fk, 'transaction_cids' || suffix, 'header_id', 'header_cids' || suffix);
+  END IF;
+  IF to_regclass('eth.receipt_cids' || suffix) IS NULL THEN
+    EXECUTE format('CREATE TABLE eth.%I PARTITION OF eth.receipt_cids FOR VALUES FROM (%s) TO (%s)', 'receipt_cids' || suffix, partition_start, partition_start + partition_size);
+    EXECUTE format(fk, 'receipt_cids' || suffix, 'tx_id', 'transaction_cids' || suffix);
+  END IF;
+  IF to_regclass('eth.state_cids' || suffix) IS NULL THEN
+    EXECUTE format('CREATE TABLE eth.%I PARTITION OF eth.state_cids FOR VALUES FROM (%s) TO (%s)', 'state_cids' || suffix, partition_start, partition_start + partition_size);
+    EXECUTE format(fk, 'state_cids' || suffix, 'header_id', 'header_cids' || suffix);
+  END IF;
+  IF to_regclass('eth.state_accounts' || suffix) IS NULL THEN
+    EXECUTE format('CREATE TABLE eth.%I PARTITION OF eth.state_accounts FOR VALUES FROM (%s) TO (%s)', 'state_accounts' || suffix, partition_start, partition_start + partition_size);
+    EXECUTE format(fk, 'state_accounts' || suffix, 'state_id', 'state_cids' || suffix);
+  END IF;
+  IF to_regclass('eth.storage_cids' || suffix) IS NULL THEN
+    EXECUTE format('CREATE TABLE eth.%I PARTITION OF eth.storage_cids FOR VALUES FROM (%s) TO (%s)', 'storage_cids' || suffix, partition_start, partition_start + partition_size);
+    EXECUTE format(fk, 'storage_cids' || suffix, 'state_id', 'state_cids' || suffix);
+  END IF;
+  RETURN partition_start + partition_size;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+SELECT eth.create_block_partitions(partition_start)
+FROM (SELECT DISTINCT block_number - block_number % eth.block_partition_size() AS partition_start FROM eth_unpartitioned.header_cids) AS partitions;
+
+INSERT INTO eth.header_cids (id, block_number, block_hash, parent_hash, cid, td, node_id, reward, state_root, tx_root, receipt_root, uncle_root, bloom, timestamp, times_validated, canonical)
+SELECT id, block_number, block_hash, parent_hash, cid, td, node_id, reward, state_root, tx_root, receipt_root, uncle_root, bloom, timestamp, times_validated, canonical
+FROM eth_unpartitioned.header_cids;
+
+INSERT INTO eth.uncle_cids (id, header_id, block_number, block_hash, parent_hash, cid, reward)
+SELECT uncle_cids.id, uncle_cids.header_id, header_cids.block_number, uncle_cids.block_hash, uncle_cids.parent_hash, uncle_cids.cid, uncle_cids.reward
+FROM eth_unpartitioned.uncle_cids
+INNER JOIN eth_unpartitioned.header_cids ON (uncle_cids.header_id = header_cids.id);
+
+INSERT INTO eth.transaction_cids (id, header_id, block_number, tx_hash, index, cid, dst, src)
+SELECT transaction_cids.id, transaction_cids.header_id, header_cids.block_number, transaction_cids.tx_hash, transaction_cids.index,
+  transaction_cids.cid, transaction_cids.dst, transaction_cids.src
+FROM eth_unpartitioned.transaction_cids
+INNER JOIN eth_unpartitioned.header_cids ON (transaction_cids.header_id = header_cids.id);
+
+INSERT INTO eth.receipt_cids (id, tx_id, block_number, cid, contract, contract_hash, topic0s, topic1s, topic2s, topic3s, log_contracts)
+SELECT receipt_cids.id, receipt_cids.tx_id, header_cids.block_number, receipt_cids.cid, receipt_cids.contract, receipt_cids.contract_hash,
+  receipt_cids.topic0s, receipt_cids.topic1s, receipt_cids.topic2s, receipt_cids.topic3s, receipt_cids.log_contracts
+FROM eth_unpartitioned.receipt_cids
+INNER JOIN eth_unpartitioned.transaction_cids ON (receipt_cids.tx_id = transaction_cids.id)
+INNER JOIN eth_unpartitioned.header_cids ON (transaction_cids.header_id = header_cids.id);
+
+INSERT INTO eth.state_cids (id, header_id, block_number, state_leaf_key, cid, state_path, node_type)
+SELECT state_cids.id, state_cids.header_id, header_cids.block_number, state_cids.state_leaf_key, state_cids.cid, state_cids.state_path, state_cids.node_type
+FROM eth_unpartitioned.state_cids
+INNER JOIN eth_unpartitioned.header_cids ON (state_cids.header_id = header_cids.id);
+
+INSERT INTO eth.state_accounts (id, state_id, block_number, balance, nonce, code_hash, storage_root)
+SELECT state_accounts.id, state_accounts.state_id, header_cids.block_number, state_accounts.balance, state_accounts.nonce,
+  state_accounts.code_hash, state_accounts.storage_root
+FROM eth_unpartitioned.state_accounts
+INNER JOIN eth_unpartitioned.state_cids ON (state_accounts.state_id = state_cids.id)
+INNER JOIN eth_unpartitioned.header_cids ON (state_cids.header_id = header_cids.id);
+
+INSERT INTO eth.storage_cids (id, state_id, block_number, storage_leaf_key, cid, storage_path, node_type)
+SELECT storage_cids.id, storage_cids.state_id, header_cids.block_number, storage_cids.storage_leaf_key, storage_cids.cid,
+  storage_cids.storage_path, storage_cids.node_type
+FROM eth_unpartitioned.storage_cids
+INNER JOIN eth_unpartitioned.state_cids ON (storage_cids.state_id = state_cids.id)
+INNER JOIN eth_unpartitioned.header_cids ON (state_cids.header_id = header_cids.id);
+
+DROP SCHEMA eth_unpartitioned CASCADE;
+
+-- +goose Down
+CREATE SCHEMA eth_partitioned;
+
+ALTER SEQUENCE eth.header_cids_id_seq OWNED BY NONE;
+ALTER SEQUENCE eth.uncle_cids_id_seq OWNED BY NONE;
+ALTER SEQUENCE eth.transaction_cids_id_seq OWNED BY NONE;
+ALTER SEQUENCE eth.receipt_cids_id_seq OWNED BY NONE;
+ALTER SEQUENCE eth.state_cids_id_seq OWNED BY NONE;
+ALTER SEQUENCE eth.state_accounts_id_seq OWNED BY NONE;
+ALTER SEQUENCE eth.storage_cids_id_seq OWNED BY NONE;
+
+ALTER TABLE eth.header_cids SET SCHEMA eth_partitioned;
+ALTER TABLE eth.uncle_cids SET SCHEMA eth_partitioned;
+ALTER TABLE eth.transaction_cids SET SCHEMA eth_partitioned;
+ALTER TABLE eth.receipt_cids SET SCHEMA eth_partitioned;
+ALTER TABLE eth.state_cids SET SCHEMA eth_partitioned;
+ALTER TABLE eth.state_accounts SET SCHEMA eth_partitioned;
+ALTER TABLE eth.storage_cids SET SCHEMA eth_partitioned;
+
+CREATE TABLE eth.header_cids (
+  id                    INTEGER PRIMARY KEY DEFAULT nextval('eth.header_cids_id_seq'),
+  block_number          BIGINT NOT NULL,
+  block_hash            VARCHAR(66) NOT NULL,
+  parent_hash           VARCHAR(66) NOT NULL,
+  cid                   TEXT NOT NULL,
+  td                    NUMERIC NOT NULL,
+  node_id               INTEGER NOT NULL REFERENCES nodes (id) ON DELETE CASCADE,
+  reward                NUMERIC NOT NULL,
+  state_root            VARCHAR(66),
+  tx_root               VARCHAR(66),
+  receipt_root          VARCHAR(66),
+  uncle_root            VARCHAR(66),
+  bloom                 BYTEA,
+  timestamp             NUMERIC,
+  times_validated       INTEGER NOT NULL DEFAULT 1,
+  canonical             BOOLEAN NOT NULL DEFAULT TRUE,
+  UNIQUE (block_number, block_hash)
+);
+
+CREATE INDEX header_cids_canonical_block_number_idx ON eth.header_cids USING btree (block_number) WHERE canonical;
+
+CREATE TABLE eth.uncle_cids (
+  id                    INTEGER PRIMARY KEY DEFAULT nextval('eth.uncle_cids_id_seq'),
+  header_id             INTEGER NOT NULL REFERENCES eth.header_cids (id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED,
+  block_hash            VARCHAR(66) NOT NULL,
+  parent_hash           VARCHAR(66) NOT NULL,
+  cid                   TEXT NOT NULL,
+  reward                NUMERIC NOT NULL,
+  UNIQUE (header_id, block_hash)
+);
+
+CREATE TABLE eth.transaction_cids (
+  id                    INTEGER PRIMARY KEY DEFAULT nextval('eth.transaction_cids_id_seq'),
+  header_id             INTEGER NOT NULL REFERENCES eth.header_cids (id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED,
+  tx_hash               VARCHAR(66) NOT NULL,
+  index                 INTEGER NOT NULL,
+  cid                   TEXT NOT NULL,
+  dst                   VARCHAR(66) NOT NULL,
+  src                   VARCHAR(66) NOT NULL,
+  UNIQUE (header_id, tx_hash)
+);
+
+CREATE TABLE eth.receipt_cids (
+  id                    INTEGER PRIMARY KEY DEFAULT nextval('eth.receipt_cids_id_seq'),
+  tx_id                 INTEGER NOT NULL REFERENCES eth.transaction_cids (id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED,
+  cid                   TEXT NOT NULL,
+  contract              VARCHAR(66),
+  contract_hash         VARCHAR(66),
+  topic0s               VARCHAR(66)[],
+  topic1s               VARCHAR(66)[],
+  topic2s               VARCHAR(66)[],
+  topic3s               VARCHAR(66)[],
+  log_contracts         VARCHAR(66)[],
+  UNIQUE (tx_id)
+);
+
+CREATE TABLE eth.state_cids (
+  id                    INTEGER PRIMARY KEY DEFAULT nextval('eth.state_cids_id_seq'),
+  header_id             INTEGER NOT NULL REFERENCES eth.header_cids (id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED,
+  state_leaf_key        VARCHAR(66),
+  cid                   TEXT NOT NULL,
+  state_path            BYTEA,
+  node_type             INTEGER,
+  UNIQUE (header_id, state_path)
+);
+
+CREATE TABLE eth.state_accounts (
+  id                    INTEGER PRIMARY KEY DEFAULT nextval('eth.state_accounts_id_seq'),
+  state_id              INTEGER NOT NULL REFERENCES eth.state_cids (id) ON DELETE CASCADE,
+  balance               NUMERIC NOT NULL,
+  nonce                 INTEGER NOT NULL,
+  code_hash             BYTEA NOT NULL,
+  storage_root          VARCHAR(66) NOT NULL,
+  UNIQUE (state_id)
+);
+
+CREATE TABLE eth.storage_cids (
+  id                    INTEGER PRIMARY KEY DEFAULT nextval('eth.storage_cids_id_seq'),
+  state_id              INTEGER NOT NULL REFERENCES eth.state_cids (id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED,
+  storage_leaf_key      VARCHAR(66),
+  cid                   TEXT NOT NULL,
+  storage_path          BYTEA,
+  node_type             INTEGER,
+  UNIQUE (state_id, storage_path)
+);
+
+ALTER SEQUENCE eth.header_cids_id_seq OWNED BY eth.header_cids.id;
+ALTER SEQUENCE eth.uncle_cids_id_seq OWNED BY eth.uncle_cids.id;
+ALTER SEQUENCE eth.transaction_cids_id_seq OWNED BY eth.transaction_cids.id;
+ALTER SEQUENCE eth.receipt_cids_id_seq OWNED BY eth.receipt_cids.id;
+ALTER SEQUENCE eth.state_cids_id_seq OWNED BY eth.state_cids.id;
+ALTER SEQUENCE eth.state_accounts_id_seq OWNED BY eth.state_accounts.id;
+ALTER SEQUENCE eth.storage_cids_id_seq OWNED BY eth.storage_cids.id;
+
+COMMENT ON TABLE eth.header_cids IS E'@name EthHeaderCids';
+COMMENT ON TABLE eth.transaction_cids IS E'@name EthTransactionCids';
+COMMENT ON COLUMN eth.header_cids.node_id IS E'@name EthNodeID';
+
+INSERT INTO eth.header_cids (id, block_number, block_hash, parent_hash, cid, td, node_id, reward, state_root, tx_root, receipt_root, uncle_root, bloom, timestamp, times_validated, canonical)
+SELECT id, block_number, block_hash, parent_hash, cid, td, node_id, reward, state_root, tx_root, receipt_root, uncle_root, bloom, timestamp, times_validated, canonical
+FROM eth_partitioned.header_cids;
+
+INSERT INTO eth.uncle_cids (id, header_id, block_hash, parent_hash, cid, reward)
+SELECT id, header_id, block_hash, parent_hash, cid, reward FROM eth_partitioned.uncle_cids;
+
+INSERT INTO eth.transaction_cids (id, header_id, tx_hash, index, cid, dst, src)
+SELECT id, header_id, tx_hash, index, cid, dst, src FROM eth_partitioned.transaction_cids;
+
+INSERT INTO eth.receipt_cids (id, tx_id, cid, contract, contract_hash, topic0s, topic1s, topic2s, topic3s, log_contracts)
+SELECT id, tx_id, cid, contract, contract_hash, topic0s, topic1s, topic2s, topic3s, log_contracts FROM eth_partitioned.receipt_cids;
+
+INSERT INTO eth.state_cids (id, header_id, state_leaf_key, cid, state_path, node_type)
+SELECT id, header_id, state_leaf_key, cid, state_path, node_type FROM eth_partitioned.state_cids;
+
+INSERT INTO eth.state_accounts (id, state_id, balance, nonce, code_hash, storage_root)
+SELECT id, state_id, balance, nonce, code_hash, storage_root FROM eth_partitioned.state_accounts;
+
+INSERT INTO eth.storage_cids (id, state_id, storage_leaf_key, cid, storage_path, node_type)
+SELECT id, state_id, storage_leaf_key, cid, storage_path, node_type FROM eth_partitioned.storage_cids;
+
+DROP FUNCTION eth.create_block_partitions(BIGINT);
+DROP FUNCTION eth.block_partition_size();
+DROP SCHEMA eth_partitioned CASCADE;
@@ -0,0 +1,38 @@
+-- +goose Up
+CREATE TABLE eth.validation_audit (
+  id                    SERIAL PRIMARY KEY,
+  block_number          BIGINT NOT NULL,
+  block_hash            VARCHAR(66) NOT NULL,
+  object                VARCHAR(16) NOT NULL,
+  object_key            TEXT NOT NULL,
+  field                 VARCHAR(32) NOT NULL,
+  indexed               TEXT NOT NULL,
+  fetched               TEXT NOT NULL,
+  action                VARCHAR(16) NOT NULL,
+  node_id               INTEGER NOT NULL REFERENCES nodes (id) ON DELETE CASCADE,
+  validated_at          TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX validation_audit_block_number_idx ON eth.validation_audit USING btree (block_number);
+
+CREATE TABLE btc.validation_audit (
+  id                    SERIAL PRIMARY KEY,
+  block_number          BIGINT NOT NULL,
+  block_hash            VARCHAR(66) NOT NULL,
+  object                VARCHAR(16) NOT NULL,
+  object_key            TEXT NOT NULL,
+  field                 VARCHAR(32) NOT NULL,
+  indexed               TEXT NOT NULL,
+  fetched               TEXT NOT NULL,
+  action                VARCHAR(16) NOT NULL,
+  node_id               INTEGER NOT NULL REFERENCES nodes (id) ON DELETE CASCADE,
+  validated_at          TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX validation_audit_block_number_idx ON btc.validation_audit USING btree (block_number);
+
+-- +goose Down
+DROP INDEX btc.validation_audit_block_number_idx;
+DROP TABLE btc.validation_audit;
+DROP INDEX eth.validation_audit_block_number_idx;
+DROP TABLE eth.validation_audit;
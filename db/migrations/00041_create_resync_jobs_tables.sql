@@ -0,0 +1,38 @@
+-- +goose Up
+CREATE TABLE public.resync_jobs (
+  id                    SERIAL PRIMARY KEY,
+  chain                 VARCHAR(16) NOT NULL,
+  data_type             VARCHAR(16) NOT NULL,
+  clear_old_cache       BOOLEAN NOT NULL DEFAULT FALSE,
+  reset_validation      BOOLEAN NOT NULL DEFAULT FALSE,
+  prepared              BOOLEAN NOT NULL DEFAULT FALSE,
+  status                VARCHAR(16) NOT NULL DEFAULT 'pending',
+  created_at            TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at            TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE public.resync_ranges (
+  id                    SERIAL PRIMARY KEY,
+  job_id                INTEGER NOT NULL REFERENCES public.resync_jobs (id) ON DELETE CASCADE,
+  start_block           BIGINT NOT NULL,
+  stop_block            BIGINT NOT NULL
+);
+
+CREATE TABLE public.resync_batches (
+  id                    SERIAL PRIMARY KEY,
+  job_id                INTEGER NOT NULL REFERENCES public.resync_jobs (id) ON DELETE CASCADE,
+  start_block           BIGINT NOT NULL,
+  stop_block            BIGINT NOT NULL,
+  status                VARCHAR(16) NOT NULL DEFAULT 'pending',
+  error                 TEXT,
+  completed_at          TIMESTAMP,
+  UNIQUE (job_id, start_block)
+);
+
+CREATE INDEX resync_jobs_status_idx ON public.resync_jobs USING btree (status);
+
+-- +goose Down
+DROP INDEX public.resync_jobs_status_idx;
+DROP TABLE public.resync_batches;
+DROP TABLE public.resync_ranges;
+DROP TABLE public.resync_jobs;
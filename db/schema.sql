@@ -2,8 +2,8 @@
 -- PostgreSQL database dump
 --
 
--- Dumped from database version 10.10
--- Dumped by pg_dump version 10.10
+-- Dumped from database version 11.2
+-- Dumped by pg_dump version 11.2
 
 SET statement_timeout = 0;
 SET lock_timeout = 0;
@@ -30,6 +30,13 @@ CREATE SCHEMA btc;
 CREATE SCHEMA eth;
 
 
+--
+-- Name: omni; Type: SCHEMA; Schema: -; Owner: -
+--
+
+CREATE SCHEMA omni;
+
+
 --
 -- Name: plpgsql; Type: EXTENSION; Schema: -; Owner: -
 --
@@ -44,10 +51,107 @@ CREATE EXTENSION IF NOT EXISTS plpgsql WITH SCHEMA pg_catalog;
 COMMENT ON EXTENSION plpgsql IS 'PL/pgSQL procedural language';
 
 
+--
+-- Name: block_partition_size(); Type: FUNCTION; Schema: eth; Owner: -
+--
+
+CREATE FUNCTION eth.block_partition_size() RETURNS bigint
+    LANGUAGE sql IMMUTABLE
+    AS $$
+  SELECT 100000::BIGINT
+$$;
+
+
+--
+-- Name: create_block_partitions(bigint); Type: FUNCTION; Schema: eth; Owner: -
+--
+
+CREATE FUNCTION eth.create_block_partitions(block_number bigint) RETURNS bigint
+    LANGUAGE plpgsql
+    AS $$
+DECLARE
+  partition_size BIGINT := eth.block_partition_size();
+  partition_start BIGINT := block_number - block_number % partition_size;
+  suffix TEXT := '_' || partition_start;
+  fk TEXT := 'ALTER TABLE eth.%I ADD FOREIGN KEY (%I, block_number) REFERENCES eth.%I (id, block_number) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED';
+BEGIN
+  IF to_regclass('eth.header_cids' || suffix) IS NOT NULL AND to_regclass('eth.uncle_cids' || suffix) IS NOT NULL
+    AND to_regclass('eth.transaction_cids' || suffix) IS NOT NULL AND to_regclass('eth.receipt_cids' || suffix) IS NOT NULL
+    AND to_regclass('eth.state_cids' || suffix) IS NOT NULL AND to_regclass('eth.state_accounts' || suffix) IS NOT NULL
+    AND to_regclass('eth.storage_cids' || suffix) IS NOT NULL THEN
+    RETURN partition_start + partition_size;
+  END IF;
+  -- Serialize concurrent callers, the ones that wait will find the partitions already created
+  PERFORM pg_advisory_xact_lock(hashtext('eth.create_block_partitions'));
+  IF to_regclass('eth.header_cids' || suffix) IS NULL THEN
+    EXECUTE format('CREATE TABLE eth.%I PARTITION OF eth.header_cids FOR VALUES FROM (%s) TO (%s)', 'header_cids' || suffix, partition_start, partition_start + partition_size);
+  END IF;
+  IF to_regclass('eth.uncle_cids' || suffix) IS NULL THEN
+    EXECUTE format('CREATE TABLE eth.%I PARTITION OF eth.uncle_cids FOR VALUES FROM (%s) TO (%s)', 'uncle_cids' || suffix, partition_start, partition_start + partition_size);
+    EXECUTE format(fk, 'uncle_cids' || suffix, 'header_id', 'header_cids' || suffix);
+  END IF;
+  IF to_regclass('eth.transaction_cids' || suffix) IS NULL THEN
+    EXECUTE format('CREATE TABLE eth.%I PARTITION OF eth.transaction_cids FOR VALUES FROM (%s) TO (%s)', 'transaction_cids' || suffix, partition_start, partition_start + partition_size);
+    EXECUTE format(fk, 'transaction_cids' || suffix, 'header_id', 'header_cids' || suffix);
+  END IF;
+  IF to_regclass('eth.receipt_cids' || suffix) IS NULL THEN
+    EXECUTE format('CREATE TABLE eth.%I PARTITION OF eth.receipt_cids FOR VALUES FROM (%s) TO (%s)', 'receipt_cids' || suffix, partition_start, partition_start + partition_size);
+    EXECUTE format(fk, 'receipt_cids' || suffix, 'tx_id', 'transaction_cids' || suffix);
+  END IF;
+  IF to_regclass('eth.state_cids' || suffix) IS NULL THEN
+    EXECUTE format('CREATE TABLE eth.%I PARTITION OF eth.state_cids FOR VALUES FROM (%s) TO (%s)', 'state_cids' || suffix, partition_start, partition_start + partition_size);
+    EXECUTE format(fk, 'state_cids' || suffix, 'header_id', 'header_cids' || suffix);
+  END IF;
+  IF to_regclass('eth.state_accounts' || suffix) IS NULL THEN
+    EXECUTE format('CREATE TABLE eth.%I PARTITION OF eth.state_accounts FOR VALUES FROM (%s) TO (%s)', 'state_accounts' || suffix, partition_start, partition_start + partition_size);
+    EXECUTE format(fk, 'state_accounts' || suffix, 'state_id', 'state_cids' || suffix);
+  END IF;
+  IF to_regclass('eth.storage_cids' || suffix) IS NULL THEN
+    EXECUTE format('CREATE TABLE eth.%I PARTITION OF eth.storage_cids FOR VALUES FROM (%s) TO (%s)', 'storage_cids' || suffix, partition_start, partition_start + partition_size);
+    EXECUTE format(fk, 'storage_cids' || suffix, 'state_id', 'state_cids' || suffix);
+  END IF;
+  RETURN partition_start + partition_size;
+END;
+$$;
+
+
 SET default_tablespace = '';
 
 SET default_with_oids = false;
 
+--
+-- Name: address_history; Type: TABLE; Schema: btc; Owner: -
+--
+
+CREATE TABLE btc.address_history (
+    id integer NOT NULL,
+    address character varying(66) NOT NULL,
+    tx_id integer NOT NULL,
+    received bigint NOT NULL,
+    spent bigint NOT NULL
+);
+
+
+--
+-- Name: address_history_id_seq; Type: SEQUENCE; Schema: btc; Owner: -
+--
+
+CREATE SEQUENCE btc.address_history_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: address_history_id_seq; Type: SEQUENCE OWNED BY; Schema: btc; Owner: -
+--
+
+ALTER SEQUENCE btc.address_history_id_seq OWNED BY btc.address_history.id;
+
+
 --
 -- Name: header_cids; Type: TABLE; Schema: btc; Owner: -
 --
@@ -61,7 +165,8 @@ CREATE TABLE btc.header_cids (
     "timestamp" numeric NOT NULL,
     bits bigint NOT NULL,
     node_id integer NOT NULL,
-    times_validated integer DEFAULT 1 NOT NULL
+    times_validated integer DEFAULT 1 NOT NULL,
+    canonical boolean DEFAULT true NOT NULL
 );
 
 
@@ -99,6 +204,15 @@ CREATE SEQUENCE btc.header_cids_id_seq
 ALTER SEQUENCE btc.header_cids_id_seq OWNED BY btc.header_cids.id;
 
 
+--
+-- Name: known_gaps; Type: TABLE; Schema: btc; Owner: -
+--
+
+CREATE TABLE btc.known_gaps (
+    block_number bigint NOT NULL
+);
+
+
 --
 -- Name: queue_data; Type: TABLE; Schema: btc; Owner: -
 --
@@ -137,6 +251,37 @@ CREATE SEQUENCE btc.queue_data_id_seq
 ALTER SEQUENCE btc.queue_data_id_seq OWNED BY btc.queue_data.id;
 
 
+--
+-- Name: spill_queue; Type: TABLE; Schema: btc; Owner: -
+--
+
+CREATE TABLE btc.spill_queue (
+    id integer NOT NULL,
+    data bytea NOT NULL,
+    height bigint NOT NULL
+);
+
+
+--
+-- Name: spill_queue_id_seq; Type: SEQUENCE; Schema: btc; Owner: -
+--
+
+CREATE SEQUENCE btc.spill_queue_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: spill_queue_id_seq; Type: SEQUENCE OWNED BY; Schema: btc; Owner: -
+--
+
+ALTER SEQUENCE btc.spill_queue_id_seq OWNED BY btc.spill_queue.id;
+
+
 --
 -- Name: transaction_cids; Type: TABLE; Schema: btc; Owner: -
 --
@@ -190,7 +335,8 @@ CREATE TABLE btc.tx_inputs (
     witness character varying[],
     sig_script bytea NOT NULL,
     outpoint_tx_hash character varying(66) NOT NULL,
-    outpoint_index numeric NOT NULL
+    outpoint_index numeric NOT NULL,
+    spent_output_id integer
 );
 
 
@@ -250,6 +396,45 @@ CREATE SEQUENCE btc.tx_outputs_id_seq
 ALTER SEQUENCE btc.tx_outputs_id_seq OWNED BY btc.tx_outputs.id;
 
 
+--
+-- Name: validation_audit; Type: TABLE; Schema: btc; Owner: -
+--
+
+CREATE TABLE btc.validation_audit (
+    id integer NOT NULL,
+    block_number bigint NOT NULL,
+    block_hash character varying(66) NOT NULL,
+    object character varying(16) NOT NULL,
+    object_key text NOT NULL,
+    field character varying(32) NOT NULL,
+    indexed text NOT NULL,
+    fetched text NOT NULL,
+    action character varying(16) NOT NULL,
+    node_id integer NOT NULL,
+    validated_at timestamp without time zone DEFAULT now() NOT NULL
+);
+
+
+--
+-- Name: validation_audit_id_seq; Type: SEQUENCE; Schema: btc; Owner: -
+--
+
+CREATE SEQUENCE btc.validation_audit_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: validation_audit_id_seq; Type: SEQUENCE OWNED BY; Schema: btc; Owner: -
+--
+
+ALTER SEQUENCE btc.validation_audit_id_seq OWNED BY btc.validation_audit.id;
+
+
 --
 -- Name: header_cids; Type: TABLE; Schema: eth; Owner: -
 --
@@ -269,8 +454,10 @@ CREATE TABLE eth.header_cids (
     uncle_root character varying(66),
     bloom bytea,
     "timestamp" numeric,
-    times_validated integer DEFAULT 1 NOT NULL
-);
+    times_validated integer DEFAULT 1 NOT NULL,
+    canonical boolean DEFAULT true NOT NULL
+)
+PARTITION BY RANGE (block_number);
 
 
 --
@@ -307,6 +494,15 @@ CREATE SEQUENCE eth.header_cids_id_seq
 ALTER SEQUENCE eth.header_cids_id_seq OWNED BY eth.header_cids.id;
 
 
+--
+-- Name: known_gaps; Type: TABLE; Schema: eth; Owner: -
+--
+
+CREATE TABLE eth.known_gaps (
+    block_number bigint NOT NULL
+);
+
+
 --
 -- Name: queue_data; Type: TABLE; Schema: eth; Owner: -
 --
@@ -352,15 +548,17 @@ ALTER SEQUENCE eth.queue_data_id_seq OWNED BY eth.queue_data.id;
 CREATE TABLE eth.receipt_cids (
     id integer NOT NULL,
     tx_id integer NOT NULL,
+    block_number bigint NOT NULL,
     cid text NOT NULL,
     contract character varying(66),
+    contract_hash character varying(66),
     topic0s character varying(66)[],
     topic1s character varying(66)[],
     topic2s character varying(66)[],
     topic3s character varying(66)[],
-    log_contracts character varying(66)[],
-    contract_hash character varying(66)
-);
+    log_contracts character varying(66)[]
+)
+PARTITION BY RANGE (block_number);
 
 
 --
@@ -383,6 +581,37 @@ CREATE SEQUENCE eth.receipt_cids_id_seq
 ALTER SEQUENCE eth.receipt_cids_id_seq OWNED BY eth.receipt_cids.id;
 
 
+--
+-- Name: spill_queue; Type: TABLE; Schema: eth; Owner: -
+--
+
+CREATE TABLE eth.spill_queue (
+    id integer NOT NULL,
+    data bytea NOT NULL,
+    height bigint NOT NULL
+);
+
+
+--
+-- Name: spill_queue_id_seq; Type: SEQUENCE; Schema: eth; Owner: -
+--
+
+CREATE SEQUENCE eth.spill_queue_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: spill_queue_id_seq; Type: SEQUENCE OWNED BY; Schema: eth; Owner: -
+--
+
+ALTER SEQUENCE eth.spill_queue_id_seq OWNED BY eth.spill_queue.id;
+
+
 --
 -- Name: state_accounts; Type: TABLE; Schema: eth; Owner: -
 --
@@ -390,11 +619,13 @@ ALTER SEQUENCE eth.receipt_cids_id_seq OWNED BY eth.receipt_cids.id;
 CREATE TABLE eth.state_accounts (
     id integer NOT NULL,
     state_id integer NOT NULL,
+    block_number bigint NOT NULL,
     balance numeric NOT NULL,
     nonce integer NOT NULL,
     code_hash bytea NOT NULL,
     storage_root character varying(66) NOT NULL
-);
+)
+PARTITION BY RANGE (block_number);
 
 
 --
@@ -424,11 +655,13 @@ ALTER SEQUENCE eth.state_accounts_id_seq OWNED BY eth.state_accounts.id;
 CREATE TABLE eth.state_cids (
     id integer NOT NULL,
     header_id integer NOT NULL,
+    block_number bigint NOT NULL,
     state_leaf_key character varying(66),
     cid text NOT NULL,
     state_path bytea,
     node_type integer
-);
+)
+PARTITION BY RANGE (block_number);
 
 
 --
@@ -458,11 +691,13 @@ ALTER SEQUENCE eth.state_cids_id_seq OWNED BY eth.state_cids.id;
 CREATE TABLE eth.storage_cids (
     id integer NOT NULL,
     state_id integer NOT NULL,
+    block_number bigint NOT NULL,
     storage_leaf_key character varying(66),
     cid text NOT NULL,
     storage_path bytea,
     node_type integer
-);
+)
+PARTITION BY RANGE (block_number);
 
 
 --
@@ -492,12 +727,14 @@ ALTER SEQUENCE eth.storage_cids_id_seq OWNED BY eth.storage_cids.id;
 CREATE TABLE eth.transaction_cids (
     id integer NOT NULL,
     header_id integer NOT NULL,
+    block_number bigint NOT NULL,
     tx_hash character varying(66) NOT NULL,
     index integer NOT NULL,
     cid text NOT NULL,
     dst character varying(66) NOT NULL,
     src character varying(66) NOT NULL
-);
+)
+PARTITION BY RANGE (block_number);
 
 
 --
@@ -534,11 +771,13 @@ ALTER SEQUENCE eth.transaction_cids_id_seq OWNED BY eth.transaction_cids.id;
 CREATE TABLE eth.uncle_cids (
     id integer NOT NULL,
     header_id integer NOT NULL,
+    block_number bigint NOT NULL,
     block_hash character varying(66) NOT NULL,
     parent_hash character varying(66) NOT NULL,
     cid text NOT NULL,
     reward numeric NOT NULL
-);
+)
+PARTITION BY RANGE (block_number);
 
 
 --
@@ -561,6 +800,86 @@ CREATE SEQUENCE eth.uncle_cids_id_seq
 ALTER SEQUENCE eth.uncle_cids_id_seq OWNED BY eth.uncle_cids.id;
 
 
+--
+-- Name: validation_audit; Type: TABLE; Schema: eth; Owner: -
+--
+
+CREATE TABLE eth.validation_audit (
+    id integer NOT NULL,
+    block_number bigint NOT NULL,
+    block_hash character varying(66) NOT NULL,
+    object character varying(16) NOT NULL,
+    object_key text NOT NULL,
+    field character varying(32) NOT NULL,
+    indexed text NOT NULL,
+    fetched text NOT NULL,
+    action character varying(16) NOT NULL,
+    node_id integer NOT NULL,
+    validated_at timestamp without time zone DEFAULT now() NOT NULL
+);
+
+
+--
+-- Name: validation_audit_id_seq; Type: SEQUENCE; Schema: eth; Owner: -
+--
+
+CREATE SEQUENCE eth.validation_audit_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: validation_audit_id_seq; Type: SEQUENCE OWNED BY; Schema: eth; Owner: -
+--
+
+ALTER SEQUENCE eth.validation_audit_id_seq OWNED BY eth.validation_audit.id;
+
+
+--
+-- Name: transactions; Type: TABLE; Schema: omni; Owner: -
+--
+
+CREATE TABLE omni.transactions (
+    id integer NOT NULL,
+    tx_id integer NOT NULL,
+    class smallint NOT NULL,
+    version integer NOT NULL,
+    tx_type integer NOT NULL,
+    sender character varying(66),
+    reference character varying(66),
+    property_id bigint,
+    amount bigint,
+    desired_property_id bigint,
+    desired_amount bigint,
+    ecosystem smallint,
+    payload bytea NOT NULL
+);
+
+
+--
+-- Name: transactions_id_seq; Type: SEQUENCE; Schema: omni; Owner: -
+--
+
+CREATE SEQUENCE omni.transactions_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: transactions_id_seq; Type: SEQUENCE OWNED BY; Schema: omni; Owner: -
+--
+
+ALTER SEQUENCE omni.transactions_id_seq OWNED BY omni.transactions.id;
+
+
 --
 -- Name: addresses; Type: TABLE; Schema: public; Owner: -
 --
@@ -909,6 +1228,110 @@ CREATE SEQUENCE public.queued_storage_id_seq
 ALTER SEQUENCE public.queued_storage_id_seq OWNED BY public.queued_storage.id;
 
 
+--
+-- Name: resync_batches; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.resync_batches (
+    id integer NOT NULL,
+    job_id integer NOT NULL,
+    start_block bigint NOT NULL,
+    stop_block bigint NOT NULL,
+    status character varying(16) DEFAULT 'pending'::character varying NOT NULL,
+    error text,
+    completed_at timestamp without time zone
+);
+
+
+--
+-- Name: resync_batches_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.resync_batches_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: resync_batches_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.resync_batches_id_seq OWNED BY public.resync_batches.id;
+
+
+--
+-- Name: resync_jobs; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.resync_jobs (
+    id integer NOT NULL,
+    chain character varying(16) NOT NULL,
+    data_type character varying(16) NOT NULL,
+    clear_old_cache boolean DEFAULT false NOT NULL,
+    reset_validation boolean DEFAULT false NOT NULL,
+    prepared boolean DEFAULT false NOT NULL,
+    status character varying(16) DEFAULT 'pending'::character varying NOT NULL,
+    created_at timestamp without time zone DEFAULT now() NOT NULL,
+    updated_at timestamp without time zone DEFAULT now() NOT NULL
+);
+
+
+--
+-- Name: resync_jobs_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.resync_jobs_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: resync_jobs_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.resync_jobs_id_seq OWNED BY public.resync_jobs.id;
+
+
+--
+-- Name: resync_ranges; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.resync_ranges (
+    id integer NOT NULL,
+    job_id integer NOT NULL,
+    start_block bigint NOT NULL,
+    stop_block bigint NOT NULL
+);
+
+
+--
+-- Name: resync_ranges_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.resync_ranges_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: resync_ranges_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.resync_ranges_id_seq OWNED BY public.resync_ranges.id;
+
+
 --
 -- Name: storage_diff; Type: TABLE; Schema: public; Owner: -
 --
@@ -943,6 +1366,37 @@ CREATE SEQUENCE public.storage_diff_id_seq
 ALTER SEQUENCE public.storage_diff_id_seq OWNED BY public.storage_diff.id;
 
 
+--
+-- Name: subscription_queue; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.subscription_queue (
+    id integer NOT NULL,
+    subscription_id text NOT NULL,
+    data bytea NOT NULL
+);
+
+
+--
+-- Name: subscription_queue_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.subscription_queue_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: subscription_queue_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.subscription_queue_id_seq OWNED BY public.subscription_queue.id;
+
+
 --
 -- Name: watched_logs; Type: TABLE; Schema: public; Owner: -
 --
@@ -974,6 +1428,13 @@ CREATE SEQUENCE public.watched_logs_id_seq
 ALTER SEQUENCE public.watched_logs_id_seq OWNED BY public.watched_logs.id;
 
 
+--
+-- Name: address_history id; Type: DEFAULT; Schema: btc; Owner: -
+--
+
+ALTER TABLE ONLY btc.address_history ALTER COLUMN id SET DEFAULT nextval('btc.address_history_id_seq'::regclass);
+
+
 --
 -- Name: header_cids id; Type: DEFAULT; Schema: btc; Owner: -
 --
@@ -988,6 +1449,13 @@ ALTER TABLE ONLY btc.header_cids ALTER COLUMN id SET DEFAULT nextval('btc.header
 ALTER TABLE ONLY btc.queue_data ALTER COLUMN id SET DEFAULT nextval('btc.queue_data_id_seq'::regclass);
 
 
+--
+-- Name: spill_queue id; Type: DEFAULT; Schema: btc; Owner: -
+--
+
+ALTER TABLE ONLY btc.spill_queue ALTER COLUMN id SET DEFAULT nextval('btc.spill_queue_id_seq'::regclass);
+
+
 --
 -- Name: transaction_cids id; Type: DEFAULT; Schema: btc; Owner: -
 --
@@ -1009,11 +1477,18 @@ ALTER TABLE ONLY btc.tx_inputs ALTER COLUMN id SET DEFAULT nextval('btc.tx_input
 ALTER TABLE ONLY btc.tx_outputs ALTER COLUMN id SET DEFAULT nextval('btc.tx_outputs_id_seq'::regclass);
 
 
+--
+-- Name: validation_audit id; Type: DEFAULT; Schema: btc; Owner: -
+--
+
+ALTER TABLE ONLY btc.validation_audit ALTER COLUMN id SET DEFAULT nextval('btc.validation_audit_id_seq'::regclass);
+
+
 --
 -- Name: header_cids id; Type: DEFAULT; Schema: eth; Owner: -
 --
 
-ALTER TABLE ONLY eth.header_cids ALTER COLUMN id SET DEFAULT nextval('eth.header_cids_id_seq'::regclass);
+ALTER TABLE eth.header_cids ALTER COLUMN id SET DEFAULT nextval('eth.header_cids_id_seq'::regclass);
 
 
 --
@@ -1027,42 +1502,63 @@ ALTER TABLE ONLY eth.queue_data ALTER COLUMN id SET DEFAULT nextval('eth.queue_d
 -- Name: receipt_cids id; Type: DEFAULT; Schema: eth; Owner: -
 --
 
-ALTER TABLE ONLY eth.receipt_cids ALTER COLUMN id SET DEFAULT nextval('eth.receipt_cids_id_seq'::regclass);
+ALTER TABLE eth.receipt_cids ALTER COLUMN id SET DEFAULT nextval('eth.receipt_cids_id_seq'::regclass);
+
+
+--
+-- Name: spill_queue id; Type: DEFAULT; Schema: eth; Owner: -
+--
+
+ALTER TABLE ONLY eth.spill_queue ALTER COLUMN id SET DEFAULT nextval('eth.spill_queue_id_seq'::regclass);
 
 
 --
 -- Name: state_accounts id; Type: DEFAULT; Schema: eth; Owner: -
 --
 
-ALTER TABLE ONLY eth.state_accounts ALTER COLUMN id SET DEFAULT nextval('eth.state_accounts_id_seq'::regclass);
+ALTER TABLE eth.state_accounts ALTER COLUMN id SET DEFAULT nextval('eth.state_accounts_id_seq'::regclass);
 
 
 --
 -- Name: state_cids id; Type: DEFAULT; Schema: eth; Owner: -
 --
 
-ALTER TABLE ONLY eth.state_cids ALTER COLUMN id SET DEFAULT nextval('eth.state_cids_id_seq'::regclass);
+ALTER TABLE eth.state_cids ALTER COLUMN id SET DEFAULT nextval('eth.state_cids_id_seq'::regclass);
 
 
 --
 -- Name: storage_cids id; Type: DEFAULT; Schema: eth; Owner: -
 --
 
-ALTER TABLE ONLY eth.storage_cids ALTER COLUMN id SET DEFAULT nextval('eth.storage_cids_id_seq'::regclass);
+ALTER TABLE eth.storage_cids ALTER COLUMN id SET DEFAULT nextval('eth.storage_cids_id_seq'::regclass);
 
 
 --
 -- Name: transaction_cids id; Type: DEFAULT; Schema: eth; Owner: -
 --
 
-ALTER TABLE ONLY eth.transaction_cids ALTER COLUMN id SET DEFAULT nextval('eth.transaction_cids_id_seq'::regclass);
+ALTER TABLE eth.transaction_cids ALTER COLUMN id SET DEFAULT nextval('eth.transaction_cids_id_seq'::regclass);
 
 
 --
 -- Name: uncle_cids id; Type: DEFAULT; Schema: eth; Owner: -
 --
 
-ALTER TABLE ONLY eth.uncle_cids ALTER COLUMN id SET DEFAULT nextval('eth.uncle_cids_id_seq'::regclass);
+ALTER TABLE eth.uncle_cids ALTER COLUMN id SET DEFAULT nextval('eth.uncle_cids_id_seq'::regclass);
+
+
+--
+-- Name: validation_audit id; Type: DEFAULT; Schema: eth; Owner: -
+--
+
+ALTER TABLE ONLY eth.validation_audit ALTER COLUMN id SET DEFAULT nextval('eth.validation_audit_id_seq'::regclass);
+
+
+--
+-- Name: transactions id; Type: DEFAULT; Schema: omni; Owner: -
+--
+
+ALTER TABLE ONLY omni.transactions ALTER COLUMN id SET DEFAULT nextval('omni.transactions_id_seq'::regclass);
 
 
 --
@@ -1128,6 +1624,27 @@ ALTER TABLE ONLY public.nodes ALTER COLUMN id SET DEFAULT nextval('public.nodes_
 ALTER TABLE ONLY public.queued_storage ALTER COLUMN id SET DEFAULT nextval('public.queued_storage_id_seq'::regclass);
 
 
+--
+-- Name: resync_batches id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.resync_batches ALTER COLUMN id SET DEFAULT nextval('public.resync_batches_id_seq'::regclass);
+
+
+--
+-- Name: resync_jobs id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.resync_jobs ALTER COLUMN id SET DEFAULT nextval('public.resync_jobs_id_seq'::regclass);
+
+
+--
+-- Name: resync_ranges id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.resync_ranges ALTER COLUMN id SET DEFAULT nextval('public.resync_ranges_id_seq'::regclass);
+
+
 --
 -- Name: storage_diff id; Type: DEFAULT; Schema: public; Owner: -
 --
@@ -1135,6 +1652,13 @@ ALTER TABLE ONLY public.queued_storage ALTER COLUMN id SET DEFAULT nextval('publ
 ALTER TABLE ONLY public.storage_diff ALTER COLUMN id SET DEFAULT nextval('public.storage_diff_id_seq'::regclass);
 
 
+--
+-- Name: subscription_queue id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.subscription_queue ALTER COLUMN id SET DEFAULT nextval('public.subscription_queue_id_seq'::regclass);
+
+
 --
 -- Name: watched_logs id; Type: DEFAULT; Schema: public; Owner: -
 --
@@ -1142,6 +1666,22 @@ ALTER TABLE ONLY public.storage_diff ALTER COLUMN id SET DEFAULT nextval('public
 ALTER TABLE ONLY public.watched_logs ALTER COLUMN id SET DEFAULT nextval('public.watched_logs_id_seq'::regclass);
 
 
+--
+-- Name: address_history address_history_address_tx_id_key; Type: CONSTRAINT; Schema: btc; Owner: -
+--
+
+ALTER TABLE ONLY btc.address_history
+    ADD CONSTRAINT address_history_address_tx_id_key UNIQUE (address, tx_id);
+
+
+--
+-- Name: address_history address_history_pkey; Type: CONSTRAINT; Schema: btc; Owner: -
+--
+
+ALTER TABLE ONLY btc.address_history
+    ADD CONSTRAINT address_history_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: header_cids header_cids_block_number_block_hash_key; Type: CONSTRAINT; Schema: btc; Owner: -
 --
@@ -1158,6 +1698,14 @@ ALTER TABLE ONLY btc.header_cids
     ADD CONSTRAINT header_cids_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: known_gaps known_gaps_pkey; Type: CONSTRAINT; Schema: btc; Owner: -
+--
+
+ALTER TABLE ONLY btc.known_gaps
+    ADD CONSTRAINT known_gaps_pkey PRIMARY KEY (block_number);
+
+
 --
 -- Name: queue_data queue_data_height_key; Type: CONSTRAINT; Schema: btc; Owner: -
 --
@@ -1174,6 +1722,14 @@ ALTER TABLE ONLY btc.queue_data
     ADD CONSTRAINT queue_data_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: spill_queue spill_queue_pkey; Type: CONSTRAINT; Schema: btc; Owner: -
+--
+
+ALTER TABLE ONLY btc.spill_queue
+    ADD CONSTRAINT spill_queue_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: transaction_cids transaction_cids_pkey; Type: CONSTRAINT; Schema: btc; Owner: -
 --
@@ -1222,11 +1778,19 @@ ALTER TABLE ONLY btc.tx_outputs
     ADD CONSTRAINT tx_outputs_tx_id_index_key UNIQUE (tx_id, index);
 
 
+--
+-- Name: validation_audit validation_audit_pkey; Type: CONSTRAINT; Schema: btc; Owner: -
+--
+
+ALTER TABLE ONLY btc.validation_audit
+    ADD CONSTRAINT validation_audit_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: header_cids header_cids_block_number_block_hash_key; Type: CONSTRAINT; Schema: eth; Owner: -
 --
 
-ALTER TABLE ONLY eth.header_cids
+ALTER TABLE eth.header_cids
     ADD CONSTRAINT header_cids_block_number_block_hash_key UNIQUE (block_number, block_hash);
 
 
@@ -1234,8 +1798,16 @@ ALTER TABLE ONLY eth.header_cids
 -- Name: header_cids header_cids_pkey; Type: CONSTRAINT; Schema: eth; Owner: -
 --
 
-ALTER TABLE ONLY eth.header_cids
-    ADD CONSTRAINT header_cids_pkey PRIMARY KEY (id);
+ALTER TABLE eth.header_cids
+    ADD CONSTRAINT header_cids_pkey PRIMARY KEY (id, block_number);
+
+
+--
+-- Name: known_gaps known_gaps_pkey; Type: CONSTRAINT; Schema: eth; Owner: -
+--
+
+ALTER TABLE ONLY eth.known_gaps
+    ADD CONSTRAINT known_gaps_pkey PRIMARY KEY (block_number);
 
 
 --
@@ -1258,96 +1830,128 @@ ALTER TABLE ONLY eth.queue_data
 -- Name: receipt_cids receipt_cids_pkey; Type: CONSTRAINT; Schema: eth; Owner: -
 --
 
-ALTER TABLE ONLY eth.receipt_cids
-    ADD CONSTRAINT receipt_cids_pkey PRIMARY KEY (id);
+ALTER TABLE eth.receipt_cids
+    ADD CONSTRAINT receipt_cids_pkey PRIMARY KEY (id, block_number);
+
+
+--
+-- Name: receipt_cids receipt_cids_tx_id_block_number_key; Type: CONSTRAINT; Schema: eth; Owner: -
+--
+
+ALTER TABLE eth.receipt_cids
+    ADD CONSTRAINT receipt_cids_tx_id_block_number_key UNIQUE (tx_id, block_number);
 
 
 --
--- Name: receipt_cids receipt_cids_tx_id_key; Type: CONSTRAINT; Schema: eth; Owner: -
+-- Name: spill_queue spill_queue_pkey; Type: CONSTRAINT; Schema: eth; Owner: -
 --
 
-ALTER TABLE ONLY eth.receipt_cids
-    ADD CONSTRAINT receipt_cids_tx_id_key UNIQUE (tx_id);
+ALTER TABLE ONLY eth.spill_queue
+    ADD CONSTRAINT spill_queue_pkey PRIMARY KEY (id);
 
 
 --
 -- Name: state_accounts state_accounts_pkey; Type: CONSTRAINT; Schema: eth; Owner: -
 --
 
-ALTER TABLE ONLY eth.state_accounts
-    ADD CONSTRAINT state_accounts_pkey PRIMARY KEY (id);
+ALTER TABLE eth.state_accounts
+    ADD CONSTRAINT state_accounts_pkey PRIMARY KEY (id, block_number);
 
 
 --
--- Name: state_accounts state_accounts_state_id_key; Type: CONSTRAINT; Schema: eth; Owner: -
+-- Name: state_accounts state_accounts_state_id_block_number_key; Type: CONSTRAINT; Schema: eth; Owner: -
 --
 
-ALTER TABLE ONLY eth.state_accounts
-    ADD CONSTRAINT state_accounts_state_id_key UNIQUE (state_id);
+ALTER TABLE eth.state_accounts
+    ADD CONSTRAINT state_accounts_state_id_block_number_key UNIQUE (state_id, block_number);
 
 
 --
--- Name: state_cids state_cids_header_id_state_path_key; Type: CONSTRAINT; Schema: eth; Owner: -
+-- Name: state_cids state_cids_header_id_state_path_block_number_key; Type: CONSTRAINT; Schema: eth; Owner: -
 --
 
-ALTER TABLE ONLY eth.state_cids
-    ADD CONSTRAINT state_cids_header_id_state_path_key UNIQUE (header_id, state_path);
+ALTER TABLE eth.state_cids
+    ADD CONSTRAINT state_cids_header_id_state_path_block_number_key UNIQUE (header_id, state_path, block_number);
 
 
 --
 -- Name: state_cids state_cids_pkey; Type: CONSTRAINT; Schema: eth; Owner: -
 --
 
-ALTER TABLE ONLY eth.state_cids
-    ADD CONSTRAINT state_cids_pkey PRIMARY KEY (id);
+ALTER TABLE eth.state_cids
+    ADD CONSTRAINT state_cids_pkey PRIMARY KEY (id, block_number);
 
 
 --
 -- Name: storage_cids storage_cids_pkey; Type: CONSTRAINT; Schema: eth; Owner: -
 --
 
-ALTER TABLE ONLY eth.storage_cids
-    ADD CONSTRAINT storage_cids_pkey PRIMARY KEY (id);
+ALTER TABLE eth.storage_cids
+    ADD CONSTRAINT storage_cids_pkey PRIMARY KEY (id, block_number);
 
 
 --
--- Name: storage_cids storage_cids_state_id_storage_path_key; Type: CONSTRAINT; Schema: eth; Owner: -
+-- Name: storage_cids storage_cids_state_id_storage_path_block_number_key; Type: CONSTRAINT; Schema: eth; Owner: -
 --
 
-ALTER TABLE ONLY eth.storage_cids
-    ADD CONSTRAINT storage_cids_state_id_storage_path_key UNIQUE (state_id, storage_path);
+ALTER TABLE eth.storage_cids
+    ADD CONSTRAINT storage_cids_state_id_storage_path_block_number_key UNIQUE (state_id, storage_path, block_number);
 
 
 --
--- Name: transaction_cids transaction_cids_header_id_tx_hash_key; Type: CONSTRAINT; Schema: eth; Owner: -
+-- Name: transaction_cids transaction_cids_header_id_tx_hash_block_number_key; Type: CONSTRAINT; Schema: eth; Owner: -
 --
 
-ALTER TABLE ONLY eth.transaction_cids
-    ADD CONSTRAINT transaction_cids_header_id_tx_hash_key UNIQUE (header_id, tx_hash);
+ALTER TABLE eth.transaction_cids
+    ADD CONSTRAINT transaction_cids_header_id_tx_hash_block_number_key UNIQUE (header_id, tx_hash, block_number);
 
 
 --
 -- Name: transaction_cids transaction_cids_pkey; Type: CONSTRAINT; Schema: eth; Owner: -
 --
 
-ALTER TABLE ONLY eth.transaction_cids
-    ADD CONSTRAINT transaction_cids_pkey PRIMARY KEY (id);
+ALTER TABLE eth.transaction_cids
+    ADD CONSTRAINT transaction_cids_pkey PRIMARY KEY (id, block_number);
 
 
 --
--- Name: uncle_cids uncle_cids_header_id_block_hash_key; Type: CONSTRAINT; Schema: eth; Owner: -
+-- Name: uncle_cids uncle_cids_header_id_block_hash_block_number_key; Type: CONSTRAINT; Schema: eth; Owner: -
 --
 
-ALTER TABLE ONLY eth.uncle_cids
-    ADD CONSTRAINT uncle_cids_header_id_block_hash_key UNIQUE (header_id, block_hash);
+ALTER TABLE eth.uncle_cids
+    ADD CONSTRAINT uncle_cids_header_id_block_hash_block_number_key UNIQUE (header_id, block_hash, block_number);
 
 
 --
 -- Name: uncle_cids uncle_cids_pkey; Type: CONSTRAINT; Schema: eth; Owner: -
 --
 
-ALTER TABLE ONLY eth.uncle_cids
-    ADD CONSTRAINT uncle_cids_pkey PRIMARY KEY (id);
+ALTER TABLE eth.uncle_cids
+    ADD CONSTRAINT uncle_cids_pkey PRIMARY KEY (id, block_number);
+
+
+--
+-- Name: validation_audit validation_audit_pkey; Type: CONSTRAINT; Schema: eth; Owner: -
+--
+
+ALTER TABLE ONLY eth.validation_audit
+    ADD CONSTRAINT validation_audit_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: transactions transactions_pkey; Type: CONSTRAINT; Schema: omni; Owner: -
+--
+
+ALTER TABLE ONLY omni.transactions
+    ADD CONSTRAINT transactions_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: transactions transactions_tx_id_key; Type: CONSTRAINT; Schema: omni; Owner: -
+--
+
+ALTER TABLE ONLY omni.transactions
+    ADD CONSTRAINT transactions_tx_id_key UNIQUE (tx_id);
 
 
 --
@@ -1494,6 +2098,38 @@ ALTER TABLE ONLY public.queued_storage
     ADD CONSTRAINT queued_storage_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: resync_batches resync_batches_job_id_start_block_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.resync_batches
+    ADD CONSTRAINT resync_batches_job_id_start_block_key UNIQUE (job_id, start_block);
+
+
+--
+-- Name: resync_batches resync_batches_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.resync_batches
+    ADD CONSTRAINT resync_batches_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: resync_jobs resync_jobs_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.resync_jobs
+    ADD CONSTRAINT resync_jobs_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: resync_ranges resync_ranges_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.resync_ranges
+    ADD CONSTRAINT resync_ranges_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: storage_diff storage_diff_block_height_block_hash_hashed_address_storage_key; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -1510,6 +2146,14 @@ ALTER TABLE ONLY public.storage_diff
     ADD CONSTRAINT storage_diff_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: subscription_queue subscription_queue_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.subscription_queue
+    ADD CONSTRAINT subscription_queue_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: watched_logs watched_logs_pkey; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -1518,6 +2162,90 @@ ALTER TABLE ONLY public.watched_logs
     ADD CONSTRAINT watched_logs_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: address_history_tx_id_idx; Type: INDEX; Schema: btc; Owner: -
+--
+
+CREATE INDEX address_history_tx_id_idx ON btc.address_history USING btree (tx_id);
+
+
+--
+-- Name: header_cids_canonical_block_number_idx; Type: INDEX; Schema: btc; Owner: -
+--
+
+CREATE INDEX header_cids_canonical_block_number_idx ON btc.header_cids USING btree (block_number) WHERE canonical;
+
+
+--
+-- Name: tx_inputs_outpoint_idx; Type: INDEX; Schema: btc; Owner: -
+--
+
+CREATE INDEX tx_inputs_outpoint_idx ON btc.tx_inputs USING btree (outpoint_tx_hash, outpoint_index);
+
+
+--
+-- Name: tx_inputs_spent_output_id_idx; Type: INDEX; Schema: btc; Owner: -
+--
+
+CREATE INDEX tx_inputs_spent_output_id_idx ON btc.tx_inputs USING btree (spent_output_id);
+
+
+--
+-- Name: tx_outputs_addresses_idx; Type: INDEX; Schema: btc; Owner: -
+--
+
+CREATE INDEX tx_outputs_addresses_idx ON btc.tx_outputs USING gin (addresses);
+
+
+--
+-- Name: validation_audit_block_number_idx; Type: INDEX; Schema: btc; Owner: -
+--
+
+CREATE INDEX validation_audit_block_number_idx ON btc.validation_audit USING btree (block_number);
+
+
+--
+-- Name: header_cids_canonical_block_number_idx; Type: INDEX; Schema: eth; Owner: -
+--
+
+CREATE INDEX header_cids_canonical_block_number_idx ON ONLY eth.header_cids USING btree (block_number) WHERE canonical;
+
+
+--
+-- Name: validation_audit_block_number_idx; Type: INDEX; Schema: eth; Owner: -
+--
+
+CREATE INDEX validation_audit_block_number_idx ON eth.validation_audit USING btree (block_number);
+
+
+--
+-- Name: omni_transactions_property_id_idx; Type: INDEX; Schema: omni; Owner: -
+--
+
+CREATE INDEX omni_transactions_property_id_idx ON omni.transactions USING btree (property_id);
+
+
+--
+-- Name: omni_transactions_reference_idx; Type: INDEX; Schema: omni; Owner: -
+--
+
+CREATE INDEX omni_transactions_reference_idx ON omni.transactions USING btree (reference);
+
+
+--
+-- Name: omni_transactions_sender_idx; Type: INDEX; Schema: omni; Owner: -
+--
+
+CREATE INDEX omni_transactions_sender_idx ON omni.transactions USING btree (sender);
+
+
+--
+-- Name: omni_transactions_tx_type_idx; Type: INDEX; Schema: omni; Owner: -
+--
+
+CREATE INDEX omni_transactions_tx_type_idx ON omni.transactions USING btree (tx_type);
+
+
 --
 -- Name: header_sync_receipts_header; Type: INDEX; Schema: public; Owner: -
 --
@@ -1561,91 +2289,97 @@ CREATE INDEX headers_block_timestamp ON public.headers USING btree (block_timest
 
 
 --
--- Name: header_cids header_cids_node_id_fkey; Type: FK CONSTRAINT; Schema: btc; Owner: -
+-- Name: resync_jobs_status_idx; Type: INDEX; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY btc.header_cids
-    ADD CONSTRAINT header_cids_node_id_fkey FOREIGN KEY (node_id) REFERENCES public.nodes(id) ON DELETE CASCADE;
+CREATE INDEX resync_jobs_status_idx ON public.resync_jobs USING btree (status);
 
 
 --
--- Name: transaction_cids transaction_cids_header_id_fkey; Type: FK CONSTRAINT; Schema: btc; Owner: -
+-- Name: subscription_queue_subscription_id_index; Type: INDEX; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY btc.transaction_cids
-    ADD CONSTRAINT transaction_cids_header_id_fkey FOREIGN KEY (header_id) REFERENCES btc.header_cids(id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED;
+CREATE INDEX subscription_queue_subscription_id_index ON public.subscription_queue USING btree (subscription_id, id);
 
 
 --
--- Name: tx_inputs tx_inputs_tx_id_fkey; Type: FK CONSTRAINT; Schema: btc; Owner: -
+-- Name: address_history address_history_tx_id_fkey; Type: FK CONSTRAINT; Schema: btc; Owner: -
 --
 
-ALTER TABLE ONLY btc.tx_inputs
-    ADD CONSTRAINT tx_inputs_tx_id_fkey FOREIGN KEY (tx_id) REFERENCES btc.transaction_cids(id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED;
+ALTER TABLE ONLY btc.address_history
+    ADD CONSTRAINT address_history_tx_id_fkey FOREIGN KEY (tx_id) REFERENCES btc.transaction_cids(id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED;
 
 
 --
--- Name: tx_outputs tx_outputs_tx_id_fkey; Type: FK CONSTRAINT; Schema: btc; Owner: -
+-- Name: header_cids header_cids_node_id_fkey; Type: FK CONSTRAINT; Schema: btc; Owner: -
 --
 
-ALTER TABLE ONLY btc.tx_outputs
-    ADD CONSTRAINT tx_outputs_tx_id_fkey FOREIGN KEY (tx_id) REFERENCES btc.transaction_cids(id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED;
+ALTER TABLE ONLY btc.header_cids
+    ADD CONSTRAINT header_cids_node_id_fkey FOREIGN KEY (node_id) REFERENCES public.nodes(id) ON DELETE CASCADE;
 
 
 --
--- Name: header_cids header_cids_node_id_fkey; Type: FK CONSTRAINT; Schema: eth; Owner: -
+-- Name: transaction_cids transaction_cids_header_id_fkey; Type: FK CONSTRAINT; Schema: btc; Owner: -
 --
 
-ALTER TABLE ONLY eth.header_cids
-    ADD CONSTRAINT header_cids_node_id_fkey FOREIGN KEY (node_id) REFERENCES public.nodes(id) ON DELETE CASCADE;
+ALTER TABLE ONLY btc.transaction_cids
+    ADD CONSTRAINT transaction_cids_header_id_fkey FOREIGN KEY (header_id) REFERENCES btc.header_cids(id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED;
 
 
 --
--- Name: receipt_cids receipt_cids_tx_id_fkey; Type: FK CONSTRAINT; Schema: eth; Owner: -
+-- Name: tx_inputs tx_inputs_spent_output_id_fkey; Type: FK CONSTRAINT; Schema: btc; Owner: -
 --
 
-ALTER TABLE ONLY eth.receipt_cids
-    ADD CONSTRAINT receipt_cids_tx_id_fkey FOREIGN KEY (tx_id) REFERENCES eth.transaction_cids(id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED;
+ALTER TABLE ONLY btc.tx_inputs
+    ADD CONSTRAINT tx_inputs_spent_output_id_fkey FOREIGN KEY (spent_output_id) REFERENCES btc.tx_outputs(id) ON DELETE SET NULL DEFERRABLE INITIALLY DEFERRED;
 
 
 --
--- Name: state_accounts state_accounts_state_id_fkey; Type: FK CONSTRAINT; Schema: eth; Owner: -
+-- Name: tx_inputs tx_inputs_tx_id_fkey; Type: FK CONSTRAINT; Schema: btc; Owner: -
 --
 
-ALTER TABLE ONLY eth.state_accounts
-    ADD CONSTRAINT state_accounts_state_id_fkey FOREIGN KEY (state_id) REFERENCES eth.state_cids(id) ON DELETE CASCADE;
+ALTER TABLE ONLY btc.tx_inputs
+    ADD CONSTRAINT tx_inputs_tx_id_fkey FOREIGN KEY (tx_id) REFERENCES btc.transaction_cids(id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED;
 
 
 --
--- Name: state_cids state_cids_header_id_fkey; Type: FK CONSTRAINT; Schema: eth; Owner: -
+-- Name: tx_outputs tx_outputs_tx_id_fkey; Type: FK CONSTRAINT; Schema: btc; Owner: -
 --
 
-ALTER TABLE ONLY eth.state_cids
-    ADD CONSTRAINT state_cids_header_id_fkey FOREIGN KEY (header_id) REFERENCES eth.header_cids(id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED;
+ALTER TABLE ONLY btc.tx_outputs
+    ADD CONSTRAINT tx_outputs_tx_id_fkey FOREIGN KEY (tx_id) REFERENCES btc.transaction_cids(id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED;
 
 
 --
--- Name: storage_cids storage_cids_state_id_fkey; Type: FK CONSTRAINT; Schema: eth; Owner: -
+-- Name: validation_audit validation_audit_node_id_fkey; Type: FK CONSTRAINT; Schema: btc; Owner: -
 --
 
-ALTER TABLE ONLY eth.storage_cids
-    ADD CONSTRAINT storage_cids_state_id_fkey FOREIGN KEY (state_id) REFERENCES eth.state_cids(id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED;
+ALTER TABLE ONLY btc.validation_audit
+    ADD CONSTRAINT validation_audit_node_id_fkey FOREIGN KEY (node_id) REFERENCES public.nodes(id) ON DELETE CASCADE;
 
 
 --
--- Name: transaction_cids transaction_cids_header_id_fkey; Type: FK CONSTRAINT; Schema: eth; Owner: -
+-- Name: header_cids header_cids_node_id_fkey; Type: FK CONSTRAINT; Schema: eth; Owner: -
 --
 
-ALTER TABLE ONLY eth.transaction_cids
-    ADD CONSTRAINT transaction_cids_header_id_fkey FOREIGN KEY (header_id) REFERENCES eth.header_cids(id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED;
+ALTER TABLE eth.header_cids
+    ADD CONSTRAINT header_cids_node_id_fkey FOREIGN KEY (node_id) REFERENCES public.nodes(id) ON DELETE CASCADE;
 
 
 --
--- Name: uncle_cids uncle_cids_header_id_fkey; Type: FK CONSTRAINT; Schema: eth; Owner: -
+-- Name: validation_audit validation_audit_node_id_fkey; Type: FK CONSTRAINT; Schema: eth; Owner: -
 --
 
-ALTER TABLE ONLY eth.uncle_cids
-    ADD CONSTRAINT uncle_cids_header_id_fkey FOREIGN KEY (header_id) REFERENCES eth.header_cids(id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED;
+ALTER TABLE ONLY eth.validation_audit
+    ADD CONSTRAINT validation_audit_node_id_fkey FOREIGN KEY (node_id) REFERENCES public.nodes(id) ON DELETE CASCADE;
+
+
+--
+-- Name: transactions transactions_tx_id_fkey; Type: FK CONSTRAINT; Schema: omni; Owner: -
+--
+
+ALTER TABLE ONLY omni.transactions
+    ADD CONSTRAINT transactions_tx_id_fkey FOREIGN KEY (tx_id) REFERENCES btc.transaction_cids(id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED;
 
 
 --
@@ -1720,6 +2454,22 @@ ALTER TABLE ONLY public.queued_storage
     ADD CONSTRAINT queued_storage_diff_id_fkey FOREIGN KEY (diff_id) REFERENCES public.storage_diff(id);
 
 
+--
+-- Name: resync_batches resync_batches_job_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.resync_batches
+    ADD CONSTRAINT resync_batches_job_id_fkey FOREIGN KEY (job_id) REFERENCES public.resync_jobs(id) ON DELETE CASCADE;
+
+
+--
+-- Name: resync_ranges resync_ranges_job_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.resync_ranges
+    ADD CONSTRAINT resync_ranges_job_id_fkey FOREIGN KEY (job_id) REFERENCES public.resync_jobs(id) ON DELETE CASCADE;
+
+
 --
 -- PostgreSQL database dump complete
 --
@@ -17,6 +17,7 @@ require (
 	github.com/ethereum/go-ethereum v1.9.1
 	github.com/fjl/memsize v0.0.0-20190710130421-bcb5799ab5e5 // indirect
 	github.com/golang/protobuf v1.3.2 // indirect
+	github.com/graph-gophers/graphql-go v1.3.0
 	github.com/hashicorp/golang-lru v0.5.3
 	github.com/hpcloud/tail v1.0.0
 	github.com/ipfs/go-bitswap v0.1.6 // indirect
@@ -34,7 +35,7 @@ require (
 	github.com/ipfs/go-ipfs-config v0.0.3 // indirect
 	github.com/ipfs/go-ipfs-ds-help v0.0.1
 	github.com/ipfs/go-ipfs-exchange-interface v0.0.1
-	github.com/ipfs/go-ipld-cbor v0.0.3 // indirect
+	github.com/ipfs/go-ipld-cbor v0.0.3
 	github.com/ipfs/go-ipld-format v0.0.2
 	github.com/ipfs/go-ipld-git v0.0.2 // indirect
 	github.com/ipfs/go-ipns v0.0.1 // indirect
@@ -69,6 +70,7 @@ require (
 	github.com/pelletier/go-toml v1.4.0 // indirect
 	github.com/polydawn/refmt v0.0.0-20190731040541-eff0b363297a // indirect
 	github.com/pressly/goose v2.6.0+incompatible
+	github.com/prometheus/client_golang v1.0.0
 	github.com/prometheus/tsdb v0.10.0 // indirect
 	github.com/rjeczalik/notify v0.9.2 // indirect
 	github.com/sirupsen/logrus v1.4.2
@@ -57,7 +57,7 @@ func MissingHeightsToGaps(heights []uint64) []shared.Gap {
 	validationGaps := make([]shared.Gap, 0)
 	start := heights[0]
 	lastHeight := start
-	for i, height := range heights[1:] {
+	for _, height := range heights[1:] {
 		if height != lastHeight+1 {
 			validationGaps = append(validationGaps, shared.Gap{
 				Start: start,
@@ -65,13 +65,10 @@ func MissingHeightsToGaps(heights []uint64) []shared.Gap {
 			})
 			start = height
 		}
-		if i+2 == len(heights) {
-			validationGaps = append(validationGaps, shared.Gap{
-				Start: start,
-				Stop:  height,
-			})
-		}
 		lastHeight = height
 	}
-	return validationGaps
+	return append(validationGaps, shared.Gap{
+		Start: start,
+		Stop:  lastHeight,
+	})
 }
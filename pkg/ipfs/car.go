@@ -0,0 +1,183 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package ipfs
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	mh "github.com/multiformats/go-multihash"
+)
+
+// carVersion is the version of the CAR format read and written here, see https://github.com/ipld/specs/blob/master/block-layer/content-addressable-archives.md
+const carVersion = 1
+
+// maxCARSectionSize caps the length of a header or block section read from a CAR file, so that a corrupt length prefix cannot exhaust memory
+const maxCARSectionSize = 32 << 20
+
+func init() {
+	cbor.RegisterCborType(carHeader{})
+}
+
+// carHeader is the dag-cbor encoded header at the start of a CARv1 file
+type carHeader struct {
+	Roots   []cid.Cid `refmt:"roots"`
+	Version uint64    `refmt:"version"`
+}
+
+// CARWriter writes blocks out to a CARv1 file
+// Every section, including the header, is prefixed with its length as an unsigned varint
+// and each block section holds the binary CID of the block followed by its data
+type CARWriter struct {
+	w io.Writer
+}
+
+// NewCARWriter writes the header with the provided roots to w and returns a CARWriter for the blocks that follow it
+func NewCARWriter(w io.Writer, roots []cid.Cid) (*CARWriter, error) {
+	if len(roots) == 0 {
+		return nil, errors.New("car writer: a CAR file needs at least one root")
+	}
+	header, err := cbor.DumpObject(&carHeader{Roots: roots, Version: carVersion})
+	if err != nil {
+		return nil, err
+	}
+	cw := &CARWriter{w: w}
+	return cw, cw.writeSection(header)
+}
+
+// Put writes a block to the CAR file
+func (cw *CARWriter) Put(block blocks.Block) error {
+	return cw.writeSection(block.Cid().Bytes(), block.RawData())
+}
+
+func (cw *CARWriter) writeSection(parts ...[]byte) error {
+	var size int
+	for _, part := range parts {
+		size += len(part)
+	}
+	prefix := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(prefix, uint64(size))
+	if _, err := cw.w.Write(prefix[:n]); err != nil {
+		return err
+	}
+	for _, part := range parts {
+		if _, err := cw.w.Write(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CARReader reads blocks from a CARv1 file
+type CARReader struct {
+	r     *bufio.Reader
+	Roots []cid.Cid
+}
+
+// NewCARReader reads the header of the CAR file from r and returns a CARReader for the blocks that follow it
+func NewCARReader(r io.Reader) (*CARReader, error) {
+	cr := &CARReader{r: bufio.NewReader(r)}
+	data, err := cr.readSection()
+	if err == io.EOF {
+		return nil, errors.New("car reader: missing CAR header")
+	}
+	if err != nil {
+		return nil, err
+	}
+	header := new(carHeader)
+	if err := cbor.DecodeInto(data, header); err != nil {
+		return nil, fmt.Errorf("car reader: invalid CAR header: %v", err)
+	}
+	if header.Version != carVersion {
+		return nil, fmt.Errorf("car reader: unsupported CAR version %d", header.Version)
+	}
+	cr.Roots = header.Roots
+	return cr, nil
+}
+
+// Next returns the next block in the CAR file, it returns io.EOF once all the blocks have been read
+// The data of each block is verified against its CID
+func (cr *CARReader) Next() (blocks.Block, error) {
+	data, err := cr.readSection()
+	if err != nil {
+		return nil, err
+	}
+	n, err := cidLength(data)
+	if err != nil {
+		return nil, err
+	}
+	c, err := cid.Cast(data[:n])
+	if err != nil {
+		return nil, fmt.Errorf("car reader: invalid block CID: %v", err)
+	}
+	raw := data[n:]
+	check, err := c.Prefix().Sum(raw)
+	if err != nil {
+		return nil, err
+	}
+	if !check.Equals(c) {
+		return nil, fmt.Errorf("car reader: data of block %s does not match its CID", c.String())
+	}
+	return blocks.NewBlockWithCid(raw, c)
+}
+
+func (cr *CARReader) readSection() ([]byte, error) {
+	size, err := binary.ReadUvarint(cr.r)
+	if err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("car reader: unable to read section length: %v", err)
+	}
+	if size == 0 || size > maxCARSectionSize {
+		return nil, fmt.Errorf("car reader: invalid section length %d", size)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(cr.r, data); err != nil {
+		return nil, fmt.Errorf("car reader: truncated section: %v", err)
+	}
+	return data, nil
+}
+
+// cidLength returns the length of the binary CID at the start of a block section
+// A CIDv0 is a bare sha2-256 multihash, a CIDv1 is its version and codec varints followed by a multihash
+func cidLength(data []byte) (int, error) {
+	if len(data) >= 2 && data[0] == mh.SHA2_256 && data[1] == 32 {
+		return 34, nil
+	}
+	var offset int
+	// version, codec, multihash type and digest length
+	var digestLength uint64
+	for i := 0; i < 4; i++ {
+		v, n := binary.Uvarint(data[offset:])
+		if n <= 0 {
+			return 0, errors.New("car reader: invalid block CID varint")
+		}
+		offset += n
+		digestLength = v
+	}
+	if uint64(len(data)-offset) < digestLength {
+		return 0, errors.New("car reader: block CID runs past the end of its section")
+	}
+	return offset + int(digestLength), nil
+}
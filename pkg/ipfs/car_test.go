@@ -0,0 +1,103 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package ipfs_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+
+	"github.com/ipfs/go-cid"
+	node "github.com/ipfs/go-ipld-format"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/vulcanizedb/pkg/ipfs"
+)
+
+var _ = Describe("CAR files", func() {
+	It("Writes and reads back the roots and blocks", func() {
+		headers := mockHeaderNodes(2)
+		tx := mockTxNode()
+		roots := []cid.Cid{headers[0].Cid(), headers[1].Cid()}
+		buf := new(bytes.Buffer)
+		writer, err := ipfs.NewCARWriter(buf, roots)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(writer.Put(headers[0])).To(Succeed())
+		Expect(writer.Put(tx)).To(Succeed())
+		Expect(writer.Put(headers[1])).To(Succeed())
+
+		reader, err := ipfs.NewCARReader(buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reader.Roots).To(Equal(roots))
+		for _, expected := range []node.Node{headers[0], tx, headers[1]} {
+			block, err := reader.Next()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(block.Cid()).To(Equal(expected.Cid()))
+			Expect(block.RawData()).To(Equal(expected.RawData()))
+		}
+		_, err = reader.Next()
+		Expect(err).To(Equal(io.EOF))
+	})
+
+	It("Writes the header as a dag-cbor map of roots and version", func() {
+		root, err := cid.Decode("bafyreihyrpefhacm6kkp4ql6j6udakdit7g3dmkzfriqfykhjw6cad5lrm")
+		Expect(err).ToNot(HaveOccurred())
+		buf := new(bytes.Buffer)
+		_, err = ipfs.NewCARWriter(buf, []cid.Cid{root})
+		Expect(err).ToNot(HaveOccurred())
+		// varint length, then {"roots": [root], "version": 1}
+		expected := "3a" + "a2" + "65726f6f7473" + "81" + "d82a58250001" + hex.EncodeToString(root.Bytes()[1:]) + "6776657273696f6e" + "01"
+		Expect(hex.EncodeToString(buf.Bytes())).To(Equal(expected))
+	})
+
+	It("Rejects blocks whose data does not match their CID", func() {
+		headers := mockHeaderNodes(2)
+		buf := new(bytes.Buffer)
+		writer, err := ipfs.NewCARWriter(buf, []cid.Cid{headers[0].Cid()})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(writer.Put(headers[0])).To(Succeed())
+		data := buf.Bytes()
+		data[len(data)-1] ^= 0xff
+
+		reader, err := ipfs.NewCARReader(bytes.NewReader(data))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = reader.Next()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("does not match its CID"))
+	})
+
+	It("Rejects truncated files", func() {
+		headers := mockHeaderNodes(1)
+		buf := new(bytes.Buffer)
+		writer, err := ipfs.NewCARWriter(buf, []cid.Cid{headers[0].Cid()})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(writer.Put(headers[0])).To(Succeed())
+		data := buf.Bytes()
+
+		reader, err := ipfs.NewCARReader(bytes.NewReader(data[:len(data)-10]))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = reader.Next()
+		Expect(err).To(HaveOccurred())
+		Expect(err).ToNot(Equal(io.EOF))
+	})
+
+	It("Requires at least one root", func() {
+		_, err := ipfs.NewCARWriter(new(bytes.Buffer), nil)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -17,6 +17,10 @@
 package ipfs
 
 import (
+	"context"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
 	ipld "github.com/ipfs/go-ipld-format"
 )
 
@@ -24,3 +28,31 @@ import (
 type DagPutter interface {
 	DagPut(n ipld.Node) (string, error)
 }
+
+// Adder is used to add IPLD nodes to IPFS, either through a local repository or a remote node
+type Adder interface {
+	Add(node ipld.Node) error
+}
+
+// BatchAdder is an Adder which can also add many IPLD nodes at once, it is satisfied by the RemoteClient
+type BatchAdder interface {
+	Adder
+	AddMany(nodes []ipld.Node) error
+}
+
+// BlockGetter is used to retrieve blocks from IPFS, it is satisfied by a local blockservice.BlockService and the RemoteClient
+type BlockGetter interface {
+	GetBlock(ctx context.Context, c cid.Cid) (blocks.Block, error)
+	GetBlocks(ctx context.Context, cs []cid.Cid) <-chan blocks.Block
+}
+
+// BlockAdder is used to add raw blocks to IPFS, it is satisfied by a local blockservice.BlockService and the RemoteClient
+type BlockAdder interface {
+	AddBlocks(bs []blocks.Block) error
+}
+
+// BlockService is used to both retrieve and add raw blocks
+type BlockService interface {
+	BlockGetter
+	BlockAdder
+}
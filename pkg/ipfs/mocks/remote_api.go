@@ -0,0 +1,200 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mocks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+)
+
+// RemoteAPI is an in-process stand-in for the block/put and block/get commands of the IPFS HTTP API
+type RemoteAPI struct {
+	server *httptest.Server
+
+	lock     sync.Mutex
+	blocks   map[string][]byte
+	requests map[string]int
+	failures int
+	delay    time.Duration
+}
+
+// NewRemoteAPI starts a new RemoteAPI server
+func NewRemoteAPI() *RemoteAPI {
+	api := &RemoteAPI{
+		blocks:   make(map[string][]byte),
+		requests: make(map[string]int),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v0/block/put", api.handle("block/put", api.blockPut))
+	mux.HandleFunc("/api/v0/block/get", api.handle("block/get", api.blockGet))
+	api.server = httptest.NewServer(mux)
+	return api
+}
+
+// URL returns the address the server is listening on
+func (api *RemoteAPI) URL() string {
+	return api.server.URL
+}
+
+// Close shuts the server down
+func (api *RemoteAPI) Close() {
+	api.server.Close()
+}
+
+// FailNext makes the server respond to the next n requests with a 503 and no error object, as a proxy in front of the API would
+func (api *RemoteAPI) FailNext(n int) {
+	api.lock.Lock()
+	defer api.lock.Unlock()
+	api.failures = n
+}
+
+// SetDelay makes the server wait before responding to each request
+func (api *RemoteAPI) SetDelay(delay time.Duration) {
+	api.lock.Lock()
+	defer api.lock.Unlock()
+	api.delay = delay
+}
+
+// Requests returns the number of requests made to the provided command
+func (api *RemoteAPI) Requests(command string) int {
+	api.lock.Lock()
+	defer api.lock.Unlock()
+	return api.requests[command]
+}
+
+// Block returns the data stored for the provided CID
+func (api *RemoteAPI) Block(c cid.Cid) ([]byte, bool) {
+	api.lock.Lock()
+	defer api.lock.Unlock()
+	data, ok := api.blocks[c.String()]
+	return data, ok
+}
+
+// SetBlock stores data under the provided CID without checking that it matches
+func (api *RemoteAPI) SetBlock(c cid.Cid, data []byte) {
+	api.lock.Lock()
+	defer api.lock.Unlock()
+	api.blocks[c.String()] = data
+}
+
+func (api *RemoteAPI) handle(command string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		api.lock.Lock()
+		api.requests[command]++
+		delay := api.delay
+		fail := api.failures > 0
+		if fail {
+			api.failures--
+		}
+		api.lock.Unlock()
+		time.Sleep(delay)
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Sprintf("method %s not allowed", r.Method))
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func (api *RemoteAPI) blockPut(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	codec, ok := cid.Codecs[query.Get("format")]
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("unrecognized format: %s", query.Get("format")))
+		return
+	}
+	mhType, ok := mh.Names[query.Get("mhtype")]
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("unrecognized multihash function: %s", query.Get("mhtype")))
+		return
+	}
+	mhLength := -1
+	if _, err := fmt.Sscan(query.Get("mhlen"), &mhLength); err != nil {
+		mhLength = -1
+	}
+	prefix := cid.Prefix{Version: 1, Codec: codec, MhType: mhType, MhLength: mhLength}
+	reader, err := r.MultipartReader()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	type blockStat struct {
+		Key  string
+		Size int
+	}
+	stats := make([]blockStat, 0)
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			break
+		}
+		data, err := ioutil.ReadAll(part)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		c, err := prefix.Sum(data)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		api.SetBlock(c, data)
+		stats = append(stats, blockStat{Key: c.String(), Size: len(data)})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	for _, stat := range stats {
+		enc.Encode(stat)
+	}
+}
+
+func (api *RemoteAPI) blockGet(w http.ResponseWriter, r *http.Request) {
+	c, err := cid.Decode(r.URL.Query().Get("arg"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	data, ok := api.Block(c)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "blockservice: key not found")
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write(data)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"Message": msg,
+		"Code":    0,
+		"Type":    "error",
+	})
+}
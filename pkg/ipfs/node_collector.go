@@ -0,0 +1,45 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package ipfs
+
+import (
+	ipld "github.com/ipfs/go-ipld-format"
+)
+
+// NodeCollector is an Adder which collects the nodes added to it instead of publishing them,
+// so that they can be put to IPFS together with a BatchAdder
+type NodeCollector struct {
+	nodes []ipld.Node
+}
+
+// NewNodeCollector creates a pointer to a new, empty NodeCollector
+func NewNodeCollector() *NodeCollector {
+	return &NodeCollector{
+		nodes: make([]ipld.Node, 0),
+	}
+}
+
+// Add satisfies the Adder interface, it appends the node to the collected nodes
+func (nc *NodeCollector) Add(node ipld.Node) error {
+	nc.nodes = append(nc.nodes, node)
+	return nil
+}
+
+// Nodes returns the nodes collected so far, in the order they were added
+func (nc *NodeCollector) Nodes() []ipld.Node {
+	return nc.nodes
+}
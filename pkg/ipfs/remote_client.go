@@ -0,0 +1,325 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package ipfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
+	mh "github.com/multiformats/go-multihash"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultRemoteTimeout   = 30 * time.Second
+	defaultRemoteRetries   = 3
+	defaultRemoteBatchSize = 100
+	remoteRetryBackoff     = 100 * time.Millisecond
+
+	// error code the IPFS HTTP API uses when it is rate limiting requests, see go-ipfs-cmds
+	rateLimitedErrorCode = 3
+)
+
+// RemoteClientConfig holds the settings for a RemoteClient
+type RemoteClientConfig struct {
+	URL        string        // address of the IPFS HTTP API, e.g. http://127.0.0.1:5001
+	Timeout    time.Duration // timeout for each request to the API
+	MaxRetries int           // number of times a request that failed to reach the API is retried
+	BatchSize  int           // max number of blocks put in one request, and fetched concurrently
+}
+
+// RemoteClient publishes and fetches IPLD blocks through the HTTP API of a remote IPFS node
+// Blocks are put with block/put along with the codec and multihash type of their CID, rather than with dag/put,
+// so that the remote node does not need input parsers for the eth and btc IPLD formats
+type RemoteClient struct {
+	url        string
+	client     *http.Client
+	maxRetries int
+	batchSize  int
+}
+
+// NewRemoteClient creates a pointer to a new RemoteClient, unset (zero) config values are replaced with defaults
+func NewRemoteClient(config RemoteClientConfig) *RemoteClient {
+	u := strings.TrimRight(config.URL, "/")
+	if !strings.Contains(u, "://") {
+		u = "http://" + u
+	}
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = defaultRemoteTimeout
+	}
+	retries := config.MaxRetries
+	if retries <= 0 {
+		retries = defaultRemoteRetries
+	}
+	batchSize := config.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultRemoteBatchSize
+	}
+	return &RemoteClient{
+		url:        u,
+		client:     &http.Client{Timeout: timeout},
+		maxRetries: retries,
+		batchSize:  batchSize,
+	}
+}
+
+// apiError is the error object returned by the IPFS HTTP API when a command fails
+type apiError struct {
+	Message string
+	Code    int
+}
+
+func (e *apiError) Error() string {
+	return e.Message
+}
+
+// blockStat is the object returned by the IPFS HTTP API for each block put
+type blockStat struct {
+	Key  string
+	Size int
+}
+
+// Add puts an IPLD node to the remote IPFS node
+func (rc *RemoteClient) Add(node ipld.Node) error {
+	return rc.AddMany([]ipld.Node{node})
+}
+
+// AddMany puts IPLD nodes to the remote IPFS node
+func (rc *RemoteClient) AddMany(nodes []ipld.Node) error {
+	blks := make([]blocks.Block, len(nodes))
+	for i, node := range nodes {
+		blks[i] = node
+	}
+	return rc.AddBlocks(blks)
+}
+
+// AddBlocks puts raw blocks to the remote IPFS node
+// Blocks that share a codec and multihash type are put together, in batches of up to BatchSize blocks per request
+func (rc *RemoteClient) AddBlocks(bs []blocks.Block) error {
+	groups := make(map[cid.Prefix][]blocks.Block)
+	prefixes := make([]cid.Prefix, 0, 1)
+	seen := make(map[cid.Cid]bool, len(bs))
+	for _, block := range bs {
+		c := block.Cid()
+		if seen[c] {
+			continue
+		}
+		seen[c] = true
+		prefix := c.Prefix()
+		if _, ok := groups[prefix]; !ok {
+			prefixes = append(prefixes, prefix)
+		}
+		groups[prefix] = append(groups[prefix], block)
+	}
+	for _, prefix := range prefixes {
+		group := groups[prefix]
+		for i := 0; i < len(group); i += rc.batchSize {
+			end := i + rc.batchSize
+			if end > len(group) {
+				end = len(group)
+			}
+			if err := rc.putBlocks(prefix, group[i:end]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (rc *RemoteClient) putBlocks(prefix cid.Prefix, bs []blocks.Block) error {
+	format, ok := cid.CodecToStr[prefix.Codec]
+	if !ok {
+		return fmt.Errorf("ipfs remote client: unrecognized codec %d", prefix.Codec)
+	}
+	mhType, ok := mh.Codes[prefix.MhType]
+	if !ok {
+		return fmt.Errorf("ipfs remote client: unrecognized multihash type %d", prefix.MhType)
+	}
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	for _, block := range bs {
+		part, err := writer.CreateFormFile("file", block.Cid().String())
+		if err != nil {
+			return err
+		}
+		if _, err := part.Write(block.RawData()); err != nil {
+			return err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	params := url.Values{}
+	params.Set("format", format)
+	params.Set("mhtype", mhType)
+	params.Set("mhlen", fmt.Sprintf("%d", prefix.MhLength))
+	res, err := rc.post(context.Background(), "block/put", params, body.Bytes(), writer.FormDataContentType())
+	if err != nil {
+		return err
+	}
+	defer res.Close()
+	dec := json.NewDecoder(res)
+	for _, block := range bs {
+		var stat blockStat
+		if err := dec.Decode(&stat); err != nil {
+			return fmt.Errorf("ipfs remote client: block/put returned an unexpected response for block %s: %v", block.Cid().String(), err)
+		}
+		if stat.Key != block.Cid().String() {
+			return fmt.Errorf("ipfs remote client: block/put stored block %s as %s", block.Cid().String(), stat.Key)
+		}
+	}
+	return nil
+}
+
+// GetBlock retrieves a block from the remote IPFS node, the block is verified against its CID
+func (rc *RemoteClient) GetBlock(ctx context.Context, c cid.Cid) (blocks.Block, error) {
+	params := url.Values{}
+	params.Set("arg", c.String())
+	res, err := rc.post(ctx, "block/get", params, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+	data, err := ioutil.ReadAll(res)
+	if err != nil {
+		return nil, err
+	}
+	sum, err := c.Prefix().Sum(data)
+	if err != nil {
+		return nil, err
+	}
+	if !sum.Equals(c) {
+		return nil, fmt.Errorf("ipfs remote client: block/get returned data for %s that hashes to %s", c.String(), sum.String())
+	}
+	return blocks.NewBlockWithCid(data, c)
+}
+
+// GetBlocks retrieves a set of blocks from the remote IPFS node, up to BatchSize at a time
+// Like the blockservice, blocks that cannot be retrieved are left out of the results
+func (rc *RemoteClient) GetBlocks(ctx context.Context, cs []cid.Cid) <-chan blocks.Block {
+	blockChan := make(chan blocks.Block)
+	cidChan := make(chan cid.Cid)
+	wg := new(sync.WaitGroup)
+	workers := rc.batchSize
+	if len(cs) < workers {
+		workers = len(cs)
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range cidChan {
+				blk, err := rc.GetBlock(ctx, c)
+				if err != nil {
+					logrus.Errorf("ipfs remote client: unable to retrieve block %s: %v", c.String(), err)
+					continue
+				}
+				select {
+				case blockChan <- blk:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		defer close(blockChan)
+		defer wg.Wait()
+		defer close(cidChan)
+		for _, c := range cs {
+			select {
+			case cidChan <- c:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return blockChan
+}
+
+// post sends a request to the provided command of the IPFS HTTP API and returns the body of a successful response
+// Requests that fail to reach the API, or that it cannot serve at the moment, are retried up to MaxRetries times
+func (rc *RemoteClient) post(ctx context.Context, command string, params url.Values, body []byte, contentType string) (io.ReadCloser, error) {
+	endpoint := fmt.Sprintf("%s/api/v0/%s?%s", rc.url, command, params.Encode())
+	var err error
+	for attempt := 0; attempt <= rc.maxRetries; attempt++ {
+		if attempt > 0 {
+			logrus.Warnf("ipfs remote client: retrying %s after error: %v", command, err)
+			select {
+			case <-time.After(remoteRetryBackoff << uint(attempt-1)):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		var req *http.Request
+		req, err = http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		var res *http.Response
+		res, err = rc.client.Do(req.WithContext(ctx))
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			continue
+		}
+		if res.StatusCode == http.StatusOK {
+			return res.Body, nil
+		}
+		err = readAPIError(command, res)
+		if apiErr, ok := err.(*apiError); ok && apiErr.Code != rateLimitedErrorCode {
+			// the command itself failed, retrying it will not help
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+func readAPIError(command string, res *http.Response) error {
+	defer res.Body.Close()
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	apiErr := new(apiError)
+	if err := json.Unmarshal(data, apiErr); err == nil && apiErr.Message != "" {
+		return apiErr
+	}
+	msg := strings.TrimSpace(string(data))
+	if msg == "" {
+		msg = res.Status
+	}
+	return fmt.Errorf("ipfs remote client: %s responded with status %d: %s", command, res.StatusCode, msg)
+}
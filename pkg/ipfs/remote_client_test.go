@@ -0,0 +1,182 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package ipfs_test
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	node "github.com/ipfs/go-ipld-format"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/vulcanizedb/pkg/ipfs"
+	"github.com/vulcanize/vulcanizedb/pkg/ipfs/ipld"
+	"github.com/vulcanize/vulcanizedb/pkg/ipfs/mocks"
+)
+
+func mockHeaderNodes(n int) []node.Node {
+	nodes := make([]node.Node, n)
+	for i := 0; i < n; i++ {
+		header, err := ipld.NewEthHeader(&types.Header{Number: big.NewInt(int64(i)), Difficulty: big.NewInt(1)})
+		Expect(err).ToNot(HaveOccurred())
+		nodes[i] = header
+	}
+	return nodes
+}
+
+func mockTxNode() node.Node {
+	tx, err := ipld.NewEthTx(types.NewTransaction(1, common.HexToAddress("0x01"), big.NewInt(1), 21000, big.NewInt(1), nil))
+	Expect(err).ToNot(HaveOccurred())
+	return tx
+}
+
+var _ = Describe("RemoteClient", func() {
+	var (
+		api    *mocks.RemoteAPI
+		client *ipfs.RemoteClient
+	)
+	BeforeEach(func() {
+		api = mocks.NewRemoteAPI()
+		client = ipfs.NewRemoteClient(ipfs.RemoteClientConfig{
+			URL:        api.URL(),
+			Timeout:    time.Second,
+			MaxRetries: 2,
+			BatchSize:  2,
+		})
+	})
+	AfterEach(func() {
+		api.Close()
+	})
+
+	Describe("Add", func() {
+		It("Puts the node under its own CID", func() {
+			header := mockHeaderNodes(1)[0]
+			err := client.Add(header)
+			Expect(err).ToNot(HaveOccurred())
+			data, ok := api.Block(header.Cid())
+			Expect(ok).To(BeTrue())
+			Expect(data).To(Equal(header.RawData()))
+			Expect(api.Requests("block/put")).To(Equal(1))
+		})
+
+		It("Retries requests that fail to reach the API", func() {
+			api.FailNext(2)
+			header := mockHeaderNodes(1)[0]
+			err := client.Add(header)
+			Expect(err).ToNot(HaveOccurred())
+			_, ok := api.Block(header.Cid())
+			Expect(ok).To(BeTrue())
+			Expect(api.Requests("block/put")).To(Equal(3))
+		})
+
+		It("Gives up after the max number of retries", func() {
+			api.FailNext(3)
+			err := client.Add(mockHeaderNodes(1)[0])
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("503"))
+			Expect(api.Requests("block/put")).To(Equal(3))
+		})
+
+		It("Times out requests that take too long", func() {
+			api.SetDelay(300 * time.Millisecond)
+			client = ipfs.NewRemoteClient(ipfs.RemoteClientConfig{
+				URL:        api.URL(),
+				Timeout:    100 * time.Millisecond,
+				MaxRetries: 1,
+			})
+			err := client.Add(mockHeaderNodes(1)[0])
+			Expect(err).To(HaveOccurred())
+			Expect(api.Requests("block/put")).To(Equal(2))
+		})
+	})
+
+	Describe("AddMany", func() {
+		It("Puts nodes in batches grouped by their codec", func() {
+			nodes := append(mockHeaderNodes(3), mockTxNode())
+			err := client.AddMany(nodes)
+			Expect(err).ToNot(HaveOccurred())
+			for _, n := range nodes {
+				data, ok := api.Block(n.Cid())
+				Expect(ok).To(BeTrue())
+				Expect(data).To(Equal(n.RawData()))
+			}
+			// two batches for the three headers and one for the transaction
+			Expect(api.Requests("block/put")).To(Equal(3))
+		})
+
+		It("Puts a node that is passed more than once only once", func() {
+			header := mockHeaderNodes(1)[0]
+			err := client.AddMany([]node.Node{header, header})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(api.Requests("block/put")).To(Equal(1))
+		})
+	})
+
+	Describe("GetBlock", func() {
+		It("Fetches a block that was put", func() {
+			header := mockHeaderNodes(1)[0]
+			err := client.Add(header)
+			Expect(err).ToNot(HaveOccurred())
+			blk, err := client.GetBlock(context.Background(), header.Cid())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(blk.Cid()).To(Equal(header.Cid()))
+			Expect(blk.RawData()).To(Equal(header.RawData()))
+		})
+
+		It("Does not retry a command that failed", func() {
+			_, err := client.GetBlock(context.Background(), mockHeaderNodes(1)[0].Cid())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("blockservice: key not found"))
+			Expect(api.Requests("block/get")).To(Equal(1))
+		})
+
+		It("Rejects data that does not match the CID", func() {
+			header := mockHeaderNodes(1)[0]
+			api.SetBlock(header.Cid(), []byte("not the header"))
+			_, err := client.GetBlock(context.Background(), header.Cid())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("hashes to"))
+		})
+	})
+
+	Describe("GetBlocks", func() {
+		It("Fetches the blocks that can be found", func() {
+			nodes := mockHeaderNodes(5)
+			err := client.AddMany(nodes[:4])
+			Expect(err).ToNot(HaveOccurred())
+			cids := make([]cid.Cid, len(nodes))
+			for i, n := range nodes {
+				cids[i] = n.Cid()
+			}
+			fetched := make(map[cid.Cid]blocks.Block)
+			for blk := range client.GetBlocks(context.Background(), cids) {
+				fetched[blk.Cid()] = blk
+			}
+			Expect(len(fetched)).To(Equal(4))
+			for _, n := range nodes[:4] {
+				Expect(fetched[n.Cid()].RawData()).To(Equal(n.RawData()))
+			}
+			Expect(api.Requests("block/get")).To(Equal(5))
+		})
+	})
+})
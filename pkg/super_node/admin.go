@@ -0,0 +1,188 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package super_node
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+// SubscriptionInfo describes an active subscription
+type SubscriptionInfo struct {
+	ID       rpc.ID                      `json:"id"`
+	Type     common.Hash                 `json:"type"` // hash of the rlp-serialized subscription settings
+	Settings shared.SubscriptionSettings `json:"settings"`
+	Stats    SubscriptionStats           `json:"stats"`
+}
+
+// ResyncParams are the parameters for a resync launched through the admin API
+type ResyncParams struct {
+	Type             string `json:"type"` // the type of data to resync, e.g. "full" or "state"
+	Start            uint64 `json:"start"`
+	Stop             uint64 `json:"stop"`
+	ClearOldCache    bool   `json:"clearOldCache"`
+	ResetValidation  bool   `json:"resetValidation"`
+	DetachPartitions bool   `json:"detachPartitions"`
+}
+
+// Resyncer launches resyncs of ranges of data for a running super node
+type Resyncer interface {
+	Launch(params ResyncParams) error
+}
+
+// AttachBackFiller attaches the backfill process so that it can be managed through the admin API
+func (sap *Service) AttachBackFiller(backFiller BackFillInterface) {
+	sap.backFiller = backFiller
+}
+
+// AttachResyncer attaches a Resyncer so that resyncs can be launched through the admin API
+func (sap *Service) AttachResyncer(resyncer Resyncer) {
+	sap.resyncer = resyncer
+}
+
+// ActiveSubscriptions returns the ID, settings, and delivery counters of each active subscription
+func (sap *Service) ActiveSubscriptions() []SubscriptionInfo {
+	sap.Lock()
+	defer sap.Unlock()
+	subs := make([]SubscriptionInfo, 0, len(sap.deliveries))
+	for id, d := range sap.deliveries {
+		subs = append(subs, SubscriptionInfo{
+			ID:       id,
+			Type:     d.subType,
+			Settings: d.params,
+			Stats:    d.stats(),
+		})
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].ID < subs[j].ID })
+	return subs
+}
+
+// CloseSubscription forcibly closes the subscription with the given ID
+func (sap *Service) CloseSubscription(id rpc.ID) error {
+	sap.Lock()
+	defer sap.Unlock()
+	if _, ok := sap.deliveries[id]; !ok {
+		return fmt.Errorf("%s super node has no subscription %s", sap.chain.String(), id)
+	}
+	for _, subs := range sap.Subscriptions {
+		if sub, ok := subs[id]; ok {
+			sendNonBlockingErr(sub, fmt.Errorf("subscription %s closed by the super node administrator", id))
+			sendNonBlockingQuit(sub)
+			break
+		}
+	}
+	// subscriptions that are only being sent historical data are closed by their historical data feed once their delivery is stopped
+	sap.removeSubscription(id)
+	log.Infof("%s subscription %s closed through the admin API", sap.chain.String(), id)
+	return nil
+}
+
+// Gaps returns the gaps in the indexed data, including the blocks that have been validated fewer than validationLevel times
+func (sap *Service) Gaps(validationLevel int) ([]shared.Gap, error) {
+	if sap.Retriever == nil {
+		return nil, errors.New("gap retrieval requires the super node to be serving")
+	}
+	return sap.Retriever.RetrieveGapsInData(validationLevel)
+}
+
+// ValidationLevels returns the number of indexed blocks that have been validated each number of times
+func (sap *Service) ValidationLevels() ([]shared.ValidationLevel, error) {
+	if sap.Retriever == nil {
+		return nil, errors.New("validation level retrieval requires the super node to be serving")
+	}
+	return sap.Retriever.RetrieveValidationLevels()
+}
+
+// Resync launches a resync of the given range of data in the background
+func (sap *Service) Resync(params ResyncParams) error {
+	if sap.resyncer == nil {
+		return fmt.Errorf("%s super node is not configured to resync data", sap.chain.String())
+	}
+	return sap.resyncer.Launch(params)
+}
+
+// PauseBackFill pauses the backfill process
+func (sap *Service) PauseBackFill() error {
+	if sap.backFiller == nil {
+		return fmt.Errorf("%s super node has no backfill process running", sap.chain.String())
+	}
+	sap.backFiller.Pause()
+	return nil
+}
+
+// ResumeBackFill resumes a paused backfill process
+func (sap *Service) ResumeBackFill() error {
+	if sap.backFiller == nil {
+		return fmt.Errorf("%s super node has no backfill process running", sap.chain.String())
+	}
+	sap.backFiller.Resume()
+	return nil
+}
+
+// AdminAPI is the private api for operating a running super node
+type AdminAPI struct {
+	sn SuperNode
+}
+
+// NewAdminAPI creates a new AdminAPI with the provided underlying SuperNode
+func NewAdminAPI(superNodeInterface SuperNode) *AdminAPI {
+	return &AdminAPI{
+		sn: superNodeInterface,
+	}
+}
+
+// Subscriptions lists the active subscriptions with their settings and delivery counters
+func (api *AdminAPI) Subscriptions() []SubscriptionInfo {
+	return api.sn.ActiveSubscriptions()
+}
+
+// CloseSubscription forcibly closes the subscription with the given ID
+func (api *AdminAPI) CloseSubscription(id rpc.ID) error {
+	return api.sn.CloseSubscription(id)
+}
+
+// Gaps reports the gaps in the indexed data, including blocks validated fewer than validationLevel times
+func (api *AdminAPI) Gaps(validationLevel int) ([]shared.Gap, error) {
+	return api.sn.Gaps(validationLevel)
+}
+
+// ValidationLevels reports the number of indexed blocks that have been validated each number of times
+func (api *AdminAPI) ValidationLevels() ([]shared.ValidationLevel, error) {
+	return api.sn.ValidationLevels()
+}
+
+// Resync launches a resync of a range of data
+func (api *AdminAPI) Resync(params ResyncParams) error {
+	return api.sn.Resync(params)
+}
+
+// PauseBackFill pauses the backfill process
+func (api *AdminAPI) PauseBackFill() error {
+	return api.sn.PauseBackFill()
+}
+
+// ResumeBackFill resumes the backfill process
+func (api *AdminAPI) ResumeBackFill() error {
+	return api.sn.ResumeBackFill()
+}
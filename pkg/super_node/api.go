@@ -18,6 +18,9 @@ package super_node
 
 import (
 	"context"
+	"encoding/json"
+	"math/big"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/rlp"
@@ -27,6 +30,7 @@ import (
 	"github.com/vulcanize/vulcanizedb/pkg/eth/core"
 	"github.com/vulcanize/vulcanizedb/pkg/super_node/btc"
 	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/omni"
 	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
 	v "github.com/vulcanize/vulcanizedb/version"
 )
@@ -37,6 +41,16 @@ const APIName = "vdb"
 // APIVersion is the version of the state diffing service API
 const APIVersion = "0.0.1"
 
+// ChainNamespace returns the namespace an API is registered under when several chains are served from one process, e.g. vdbEth
+// The rpc server splits method names on their first underscore, so the chain is appended to the namespace without one
+func ChainNamespace(namespace string, chain shared.ChainType) string {
+	api := chain.API()
+	if api == "" {
+		return namespace
+	}
+	return namespace + strings.ToUpper(api[:1]) + api[1:]
+}
+
 // PublicSuperNodeAPI is the public api for the super node
 type PublicSuperNodeAPI struct {
 	sn SuperNode
@@ -65,9 +79,94 @@ func (api *PublicSuperNodeAPI) Stream(ctx context.Context, rlpParams []byte) (*r
 			return nil, err
 		}
 		params = &btcParams
+	case shared.Omni:
+		var omniParams omni.SubscriptionSettings
+		if err := rlp.DecodeBytes(rlpParams, &omniParams); err != nil {
+			return nil, err
+		}
+		params = &omniParams
+	default:
+		panic("SuperNode is not configured for a specific chain type")
+	}
+	return api.stream(ctx, params, func(packet SubscriptionPayload) interface{} {
+		return packet
+	})
+}
+
+// StreamJSON is the public method to setup a subscription that fires off super node payloads as they are processed
+// It takes JSON subscription settings and sends payloads with their data decoded into JSON objects
+func (api *PublicSuperNodeAPI) StreamJSON(ctx context.Context, jsonParams json.RawMessage) (*rpc.Subscription, error) {
+	var params shared.SubscriptionSettings
+	switch api.sn.Chain() {
+	case shared.Ethereum:
+		var ethParams eth.SubscriptionSettings
+		if err := json.Unmarshal(jsonParams, &ethParams); err != nil {
+			return nil, err
+		}
+		ethParams.Start, ethParams.End = defaultRange(ethParams.Start, ethParams.End)
+		params = &ethParams
+	case shared.Bitcoin:
+		var btcParams btc.SubscriptionSettings
+		if err := json.Unmarshal(jsonParams, &btcParams); err != nil {
+			return nil, err
+		}
+		btcParams.Start, btcParams.End = defaultRange(btcParams.Start, btcParams.End)
+		params = &btcParams
+	case shared.Omni:
+		var omniParams omni.SubscriptionSettings
+		if err := json.Unmarshal(jsonParams, &omniParams); err != nil {
+			return nil, err
+		}
+		omniParams.Start, omniParams.End = defaultRange(omniParams.Start, omniParams.End)
+		params = &omniParams
 	default:
 		panic("SuperNode is not configured for a specific chain type")
 	}
+	return api.stream(ctx, params, func(packet SubscriptionPayload) interface{} {
+		jsonPacket := JSONSubscriptionPayload{
+			Height:             packet.Height,
+			Cursor:             packet.Cursor,
+			Err:                packet.Err,
+			Flag:               packet.Flag,
+			InvalidatedHeights: packet.InvalidatedHeights,
+		}
+		if len(packet.Data) == 0 {
+			return jsonPacket
+		}
+		data, err := NewJSONPayload(api.sn.Chain(), packet.Data)
+		if err != nil {
+			log.Errorf("super node json decoding error for chain %s: %v", api.sn.Chain().String(), err)
+			jsonPacket.Err = err.Error()
+			return jsonPacket
+		}
+		jsonPacket.Data = data
+		return jsonPacket
+	})
+}
+
+// defaultRange defaults the starting and ending blocks omitted from JSON subscription settings to 0
+// which subscribes from the first block with no ending block
+func defaultRange(start, end *big.Int) (*big.Int, *big.Int) {
+	if start == nil {
+		start = big.NewInt(0)
+	}
+	if end == nil {
+		end = big.NewInt(0)
+	}
+	return start, end
+}
+
+// stream subscribes to the super node with the provided settings and relays its payloads to the subscriber,
+// transforming each payload into the form sent over the wire
+func (api *PublicSuperNodeAPI) stream(ctx context.Context, params shared.SubscriptionSettings, transform func(SubscriptionPayload) interface{}) (*rpc.Subscription, error) {
+	return subscribe(ctx, api.sn, params, func(notifier *rpc.Notifier, id rpc.ID, packet SubscriptionPayload) error {
+		return notifier.Notify(id, transform(packet))
+	})
+}
+
+// subscribe subscribes to the super node with the provided settings and relays its payloads to the rpc subscription
+// using the provided notify function, which can send any number of notifications for a payload
+func subscribe(ctx context.Context, sn SuperNode, params shared.SubscriptionSettings, notify func(*rpc.Notifier, rpc.ID, SubscriptionPayload) error) (*rpc.Subscription, error) {
 	// ensure that the RPC connection supports subscriptions
 	notifier, supported := rpc.NotifierFromContext(ctx)
 	if !supported {
@@ -81,23 +180,34 @@ func (api *PublicSuperNodeAPI) Stream(ctx context.Context, rlpParams []byte) (*r
 		// subscribe to events from the SyncPublishScreenAndServe service
 		payloadChannel := make(chan SubscriptionPayload, PayloadChanBufferSize)
 		quitChan := make(chan bool, 1)
-		go api.sn.Subscribe(rpcSub.ID, payloadChannel, quitChan, params)
+		go sn.Subscribe(rpcSub.ID, payloadChannel, quitChan, params)
 
 		// loop and await payloads and relay them to the subscriber using notifier
 		for {
 			select {
 			case packet := <-payloadChannel:
-				if err := notifier.Notify(rpcSub.ID, packet); err != nil {
+				if err := notify(notifier, rpcSub.ID, packet); err != nil {
 					log.Error("Failed to send super node packet", "err", err)
-					api.sn.Unsubscribe(rpcSub.ID)
+					sn.Unsubscribe(rpcSub.ID)
 					return
 				}
 			case <-rpcSub.Err():
-				api.sn.Unsubscribe(rpcSub.ID)
+				sn.Unsubscribe(rpcSub.ID)
 				return
 			case <-quitChan:
 				// don't need to unsubscribe to super node, the service does so before sending the quit signal this way
-				return
+				// relay any payloads sent ahead of the quit signal, such as a disconnection notice
+				for {
+					select {
+					case packet := <-payloadChannel:
+						if err := notify(notifier, rpcSub.ID, packet); err != nil {
+							log.Error("Failed to send super node packet", "err", err)
+							return
+						}
+					default:
+						return
+					}
+				}
 			}
 		}
 	}()
@@ -116,6 +226,21 @@ func (api *PublicSuperNodeAPI) Chain() shared.ChainType {
 	return api.sn.Chain()
 }
 
+// SubscriptionStats returns the number of payloads sent to, dropped for, and queued for each current subscription
+func (api *PublicSuperNodeAPI) SubscriptionStats() []SubscriptionStats {
+	return api.sn.SubscriptionStats()
+}
+
+// DroppedPayloads returns the total number of payloads dropped for subscriptions that fell behind
+func (api *PublicSuperNodeAPI) DroppedPayloads() uint64 {
+	return api.sn.DroppedPayloads()
+}
+
+// Health returns the sync status of this super node instance
+func (api *PublicSuperNodeAPI) Health() HealthStatus {
+	return api.sn.Health()
+}
+
 // Struct for holding super node meta data
 type InfoAPI struct{}
 
@@ -127,7 +252,32 @@ func NewInfoAPI() *InfoAPI {
 // Modules returns modules supported by this api
 func (iapi *InfoAPI) Modules() map[string]string {
 	return map[string]string{
-		"vdb": "Stream",
+		"vdb": "Stream, StreamJSON",
+	}
+}
+
+// InfoAPIs returns the RPC descriptors of the info API, which is served once for all of the super nodes in a process
+func InfoAPIs() []rpc.API {
+	infoAPI := NewInfoAPI()
+	return []rpc.API{
+		{
+			Namespace: "rpc",
+			Version:   APIVersion,
+			Service:   infoAPI,
+			Public:    true,
+		},
+		{
+			Namespace: "net",
+			Version:   APIVersion,
+			Service:   infoAPI,
+			Public:    true,
+		},
+		{
+			Namespace: "admin",
+			Version:   APIVersion,
+			Service:   infoAPI,
+			Public:    true,
+		},
 	}
 }
 
@@ -0,0 +1,77 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package super_node_test
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/vulcanizedb/pkg/super_node"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth/mocks"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+var _ = Describe("PublicSuperNodeAPI", func() {
+	var (
+		service   *super_node.Service
+		wg        *sync.WaitGroup
+		client    *rpc.Client
+		serveChan chan shared.ConvertedData
+	)
+	BeforeEach(func() {
+		sn, err := super_node.NewSuperNode(&super_node.Config{Chain: shared.Ethereum, Workers: 1})
+		Expect(err).ToNot(HaveOccurred())
+		service = sn.(*super_node.Service)
+		service.Filterer = eth.NewResponseFilterer()
+		serveChan = make(chan shared.ConvertedData, 1)
+		wg = new(sync.WaitGroup)
+		service.Serve(wg, serveChan)
+
+		server := rpc.NewServer()
+		Expect(server.RegisterName(super_node.APIName, super_node.NewPublicSuperNodeAPI(service))).ToNot(HaveOccurred())
+		client = rpc.DialInProc(server)
+	})
+	AfterEach(func() {
+		client.Close()
+		close(service.QuitChan)
+		wg.Wait()
+	})
+
+	Describe("StreamJSON", func() {
+		It("Streams from the first block with no ending block when the settings omit start and end", func() {
+			payloads := make(chan super_node.JSONSubscriptionPayload, 1)
+			sub, err := client.Subscribe(context.Background(), super_node.APIName, payloads, "streamJSON", json.RawMessage(`{"headerFilter": {"uncles": true}}`))
+			Expect(err).ToNot(HaveOccurred())
+			defer sub.Unsubscribe()
+			Eventually(func() int { return len(service.ActiveSubscriptions()) }, time.Second).Should(Equal(1))
+
+			serveChan <- mocks.MockConvertedPayload
+			var payload super_node.JSONSubscriptionPayload
+			Eventually(payloads, time.Second).Should(Receive(&payload))
+			Expect(payload.Err).To(BeEmpty())
+			Expect(payload.Height).To(Equal(mocks.MockConvertedPayload.Height()))
+			Expect(payload.Data).ToNot(BeNil())
+		})
+	})
+})
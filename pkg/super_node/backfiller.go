@@ -18,6 +18,7 @@ package super_node
 
 import (
 	"sync"
+	"sync/atomic"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -36,6 +37,10 @@ type BackFillInterface interface {
 	// Method for the super node to periodically check for and fill in gaps in its data using an archival node
 	BackFill(wg *sync.WaitGroup)
 	Stop() error
+	// Methods to pause and resume the search for and filling in of gaps
+	Pause()
+	Resume()
+	Paused() bool
 }
 
 // BackFillService for filling in gaps in the super node
@@ -46,6 +51,10 @@ type BackFillService struct {
 	Publisher shared.IPLDPublisher
 	// Interface for indexing the CIDs of the published IPLDs in Postgres
 	Indexer shared.CIDIndexer
+	// Interface for publishing and indexing each batch of IPLD payloads together, used in place of the Publisher and Indexer if set
+	BulkIndexer shared.BulkPublisherAndIndexer
+	// Interface for validating the indexed data of blocks below the validation level, used in place of re-indexing them if set
+	Validator shared.BlockValidator
 	// Interface for searching and retrieving CIDs from Postgres index
 	Retriever shared.CIDRetriever
 	// Interface for fetching payloads over at historical blocks; over http
@@ -62,13 +71,15 @@ type BackFillService struct {
 	QuitChan chan bool
 	// Chain type
 	chain shared.ChainType
-	// Headers with times_validated lower than this will be resynced
+	// Headers with times_validated lower than this will be validated, or resynced if there is no Validator
 	validationLevel int
+	// Set while the backfill process is paused
+	paused int32
 }
 
 // NewBackFillService returns a new BackFillInterface
 func NewBackFillService(settings *Config, screenAndServeChan chan shared.ConvertedData) (BackFillInterface, error) {
-	publisher, err := NewIPLDPublisher(settings.Chain, settings.IPFSPath, settings.BackFillDBConn, settings.IPFSMode)
+	publisher, err := NewIPLDPublisher(settings.Chain, settings.IPFSPath, settings.IPFSRemoteConfig, settings.BackFillDBConn, settings.IPFSMode)
 	if err != nil {
 		return nil, err
 	}
@@ -76,7 +87,7 @@ func NewBackFillService(settings *Config, screenAndServeChan chan shared.Convert
 	if err != nil {
 		return nil, err
 	}
-	converter, err := NewPayloadConverter(settings.Chain)
+	converter, err := NewPayloadConverter(settings.Chain, settings.ChainConfig, settings.HTTPClient, settings.Timeout)
 	if err != nil {
 		return nil, err
 	}
@@ -88,6 +99,20 @@ func NewBackFillService(settings *Config, screenAndServeChan chan shared.Convert
 	if err != nil {
 		return nil, err
 	}
+	var bulkIndexer shared.BulkPublisherAndIndexer
+	if settings.BulkIndex {
+		bulkIndexer, err = NewBulkPublisherAndIndexer(settings.Chain, settings.BackFillDBConn, settings.IPFSMode)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var validator shared.BlockValidator
+	if settings.ValidationLevel > 0 {
+		validator, err = NewBlockValidator(settings.Chain, settings.BackFillDBConn, settings.IPFSMode)
+		if err != nil {
+			log.Warnf("%s backFill process will re-index blocks below the validation level instead of validating them: %v", settings.Chain.String(), err)
+		}
+	}
 	batchSize := settings.BatchSize
 	if batchSize == 0 {
 		batchSize = DefaultMaxBatchSize
@@ -98,6 +123,8 @@ func NewBackFillService(settings *Config, screenAndServeChan chan shared.Convert
 	}
 	return &BackFillService{
 		Indexer:            indexer,
+		BulkIndexer:        bulkIndexer,
+		Validator:          validator,
 		Converter:          converter,
 		Publisher:          publisher,
 		Retriever:          retriever,
@@ -115,6 +142,7 @@ func NewBackFillService(settings *Config, screenAndServeChan chan shared.Convert
 // BackFill periodically checks for and fills in gaps in the super node db
 func (bfs *BackFillService) BackFill(wg *sync.WaitGroup) {
 	ticker := time.NewTicker(bfs.GapCheckFrequency)
+	workers.WithLabelValues(bfs.chain.String(), backFillProcess).Set(float64(bfs.BatchNumber))
 	go func() {
 		wg.Add(1)
 		defer wg.Done()
@@ -124,32 +152,45 @@ func (bfs *BackFillService) BackFill(wg *sync.WaitGroup) {
 				log.Infof("quiting %s FillGapsInSuperNode process", bfs.chain.String())
 				return
 			case <-ticker.C:
-				gaps, err := bfs.Retriever.RetrieveGapsInData(bfs.validationLevel)
+				if bfs.Paused() {
+					log.Debugf("%s BackFill process is paused", bfs.chain.String())
+					continue
+				}
+				sections, err := bfs.findSections()
 				if err != nil {
-					log.Errorf("%s super node db backFill RetrieveGapsInData error: %v", bfs.chain.String(), err)
+					log.Errorf("%s super node db backFill error searching for gaps: %v", bfs.chain.String(), err)
 					continue
 				}
 				// spin up worker goroutines for this search pass
 				// we start and kill a new batch of workers for each pass
 				// so that we know each of the previous workers is done before we search for new gaps
-				heightsChan := make(chan []uint64)
+				batchChan := make(chan backFillBatch)
 				for i := 1; i <= int(bfs.BatchNumber); i++ {
-					go bfs.backFill(wg, i, heightsChan)
+					go bfs.backFill(wg, i, batchChan)
 				}
-				for _, gap := range gaps {
-					log.Infof("backFilling %s data from %d to %d", bfs.chain.String(), gap.Start, gap.Stop)
-					blockRangeBins, err := utils.GetBlockHeightBins(gap.Start, gap.Stop, bfs.BatchSize)
+			dispatch:
+				for _, section := range sections {
+					if section.validate {
+						log.Infof("validating %s data from %d to %d", bfs.chain.String(), section.Start, section.Stop)
+					} else {
+						log.Infof("backFilling %s data from %d to %d", bfs.chain.String(), section.Start, section.Stop)
+					}
+					blockRangeBins, err := utils.GetBlockHeightBins(section.Start, section.Stop, bfs.BatchSize)
 					if err != nil {
 						log.Errorf("%s super node db backFill GetBlockHeightBins error: %v", bfs.chain.String(), err)
 						continue
 					}
 					for _, heights := range blockRangeBins {
+						if bfs.Paused() {
+							log.Infof("%s BackFill process paused, stopping this pass", bfs.chain.String())
+							break dispatch
+						}
 						select {
 						case <-bfs.QuitChan:
 							log.Infof("quiting %s BackFill process", bfs.chain.String())
 							return
 						default:
-							heightsChan <- heights
+							batchChan <- backFillBatch{heights: heights, validate: section.validate}
 						}
 					}
 				}
@@ -164,38 +205,118 @@ func (bfs *BackFillService) BackFill(wg *sync.WaitGroup) {
 	log.Infof("%s BackFill goroutine successfully spun up", bfs.chain.String())
 }
 
-func (bfs *BackFillService) backFill(wg *sync.WaitGroup, id int, heightChan chan []uint64) {
+// backFillSection is a section of blocks for the backfill process to fill in, or to validate if validate is set
+type backFillSection struct {
+	shared.Gap
+	validate bool
+}
+
+// backFillBatch is a batch of heights for a backfill worker to fill in, or to validate if validate is set
+type backFillBatch struct {
+	heights  []uint64
+	validate bool
+}
+
+// findSections searches for the gaps in the indexed data and the sections of blocks below the validation level
+// If there is a Validator the sections below the validation level are validated, otherwise they are filled in like gaps
+func (bfs *BackFillService) findSections() ([]backFillSection, error) {
+	if bfs.Validator == nil {
+		gaps, err := bfs.Retriever.RetrieveGapsInData(bfs.validationLevel)
+		if err != nil {
+			return nil, err
+		}
+		bfs.countGaps(gaps)
+		return toSections(gaps, false), nil
+	}
+	gaps, err := bfs.Retriever.RetrieveGapsInData(0)
+	if err != nil {
+		return nil, err
+	}
+	bfs.countGaps(gaps)
+	validationGaps, err := bfs.Retriever.RetrieveValidationGaps(bfs.validationLevel)
+	if err != nil {
+		return nil, err
+	}
+	return append(toSections(gaps, false), toSections(validationGaps, true)...), nil
+}
+
+func toSections(gaps []shared.Gap, validate bool) []backFillSection {
+	sections := make([]backFillSection, len(gaps))
+	for i, gap := range gaps {
+		sections[i] = backFillSection{Gap: gap, validate: validate}
+	}
+	return sections
+}
+
+func (bfs *BackFillService) backFill(wg *sync.WaitGroup, id int, batchChan chan backFillBatch) {
 	wg.Add(1)
 	defer wg.Done()
 	for {
 		select {
-		case heights := <-heightChan:
+		case batch := <-batchChan:
+			if batch.validate {
+				bfs.validate(id, batch.heights)
+				continue
+			}
+			heights := batch.heights
 			log.Debugf("%s backFill worker %d processing section from %d to %d", bfs.chain.String(), id, heights[0], heights[len(heights)-1])
+			busyWorkers.WithLabelValues(bfs.chain.String(), backFillProcess).Inc()
+			batchStart := time.Now()
 			payloads, err := bfs.Fetcher.FetchAt(heights)
 			if err != nil {
 				log.Errorf("%s backFill worker %d fetcher error: %s", bfs.chain.String(), id, err.Error())
 			}
+			converted := make([]shared.ConvertedData, 0, len(payloads))
 			for _, payload := range payloads {
+				start := time.Now()
 				ipldPayload, err := bfs.Converter.Convert(payload)
 				if err != nil {
 					log.Errorf("%s backFill worker %d converter error: %s", bfs.chain.String(), id, err.Error())
 				}
+				observeStage(bfs.chain.String(), backFillProcess, convertStage, start)
 				// If there is a ScreenAndServe process listening, forward converted payload to it
 				select {
 				case bfs.ScreenAndServeChan <- ipldPayload:
 					log.Debugf("%s backFill worker %d forwarded converted payload to server", bfs.chain.String(), id)
 				default:
 					log.Debugf("%s backFill worker %d unable to forward converted payload to server; no channel ready to receive", bfs.chain.String(), id)
+					if bfs.ScreenAndServeChan != nil {
+						droppedPayloads.WithLabelValues(bfs.chain.String(), serveBuffer).Inc()
+					}
 				}
+				if bfs.BulkIndexer != nil {
+					if err == nil {
+						converted = append(converted, ipldPayload)
+					}
+					continue
+				}
+				start = time.Now()
 				cidPayload, err := bfs.Publisher.Publish(ipldPayload)
 				if err != nil {
 					log.Errorf("%s backFill worker %d publisher error: %s", bfs.chain.String(), id, err.Error())
 					continue
 				}
+				observeStage(bfs.chain.String(), backFillProcess, publishStage, start)
+				start = time.Now()
 				if err := bfs.Indexer.Index(cidPayload); err != nil {
 					log.Errorf("%s backFill worker %d indexer error: %s", bfs.chain.String(), id, err.Error())
+					continue
 				}
+				observeStage(bfs.chain.String(), backFillProcess, indexStage, start)
+				backFillBlocks.WithLabelValues(bfs.chain.String()).Inc()
 			}
+			if bfs.BulkIndexer != nil && len(converted) > 0 {
+				// the heights of a failed batch are left as gaps, to be retried on the next pass
+				start := time.Now()
+				if err := bfs.BulkIndexer.PublishAndIndexBatch(converted); err != nil {
+					log.Errorf("%s backFill worker %d bulk indexer error: %s", bfs.chain.String(), id, err.Error())
+				} else {
+					observeStage(bfs.chain.String(), backFillProcess, indexStage, start)
+					backFillBlocks.WithLabelValues(bfs.chain.String()).Add(float64(len(converted)))
+				}
+			}
+			backFillBatchDuration.WithLabelValues(bfs.chain.String()).Observe(time.Since(batchStart).Seconds())
+			busyWorkers.WithLabelValues(bfs.chain.String(), backFillProcess).Dec()
 			log.Infof("%s backFill worker %d finished section from %d to %d", bfs.chain.String(), id, heights[0], heights[len(heights)-1])
 		case <-bfs.QuitChan:
 			log.Infof("%s backFill worker %d shutting down", bfs.chain.String(), id)
@@ -204,6 +325,68 @@ func (bfs *BackFillService) backFill(wg *sync.WaitGroup, id int, heightChan chan
 	}
 }
 
+// validate fetches the blocks at the provided heights and validates the data indexed for them
+// Blocks which have already been validated were already forwarded to the ScreenAndServe process when they were first indexed
+func (bfs *BackFillService) validate(id int, heights []uint64) {
+	log.Debugf("%s backFill worker %d validating section from %d to %d", bfs.chain.String(), id, heights[0], heights[len(heights)-1])
+	busyWorkers.WithLabelValues(bfs.chain.String(), backFillProcess).Inc()
+	defer busyWorkers.WithLabelValues(bfs.chain.String(), backFillProcess).Dec()
+	payloads, err := bfs.Fetcher.FetchAt(heights)
+	if err != nil {
+		log.Errorf("%s backFill worker %d fetcher error: %s", bfs.chain.String(), id, err.Error())
+	}
+	for _, payload := range payloads {
+		start := time.Now()
+		ipldPayload, err := bfs.Converter.Convert(payload)
+		if err != nil {
+			log.Errorf("%s backFill worker %d converter error: %s", bfs.chain.String(), id, err.Error())
+			continue
+		}
+		observeStage(bfs.chain.String(), backFillProcess, convertStage, start)
+		start = time.Now()
+		mismatches, err := bfs.Validator.Validate(ipldPayload)
+		if err != nil {
+			log.Errorf("%s backFill worker %d validator error: %s", bfs.chain.String(), id, err.Error())
+			continue
+		}
+		observeStage(bfs.chain.String(), backFillProcess, validateStage, start)
+		validatedBlocks.WithLabelValues(bfs.chain.String()).Inc()
+		if len(mismatches) > 0 {
+			validationMismatches.WithLabelValues(bfs.chain.String()).Add(float64(len(mismatches)))
+			log.Warnf("%s backFill worker %d found and resolved %d mismatches in the data indexed for block %d", bfs.chain.String(), id, len(mismatches), ipldPayload.Height())
+		}
+	}
+	log.Infof("%s backFill worker %d finished validating section from %d to %d", bfs.chain.String(), id, heights[0], heights[len(heights)-1])
+}
+
+// countGaps records the number of gaps, and the number of blocks missing, found by a backfill pass
+func (bfs *BackFillService) countGaps(found []shared.Gap) {
+	var missing uint64
+	for _, gap := range found {
+		missing += gap.Stop - gap.Start + 1
+	}
+	gaps.WithLabelValues(bfs.chain.String()).Set(float64(len(found)))
+	gapBlocks.WithLabelValues(bfs.chain.String()).Set(float64(missing))
+}
+
+// Pause stops the backfill process from searching for and filling in gaps until it is resumed
+// batches which are already being processed are finished
+func (bfs *BackFillService) Pause() {
+	log.Infof("Pausing %s backFill service", bfs.chain.String())
+	atomic.StoreInt32(&bfs.paused, 1)
+}
+
+// Resume resumes a paused backfill process
+func (bfs *BackFillService) Resume() {
+	log.Infof("Resuming %s backFill service", bfs.chain.String())
+	atomic.StoreInt32(&bfs.paused, 0)
+}
+
+// Paused returns true if the backfill process is paused
+func (bfs *BackFillService) Paused() bool {
+	return atomic.LoadInt32(&bfs.paused) == 1
+}
+
 func (bfs *BackFillService) Stop() error {
 	log.Infof("Stopping %s backFill service", bfs.chain.String())
 	close(bfs.QuitChan)
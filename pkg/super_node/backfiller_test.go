@@ -196,5 +196,175 @@ var _ = Describe("BackFiller", func() {
 			Expect(len(mockFetcher.CalledAtBlockHeights)).To(Equal(1))
 			Expect(mockFetcher.CalledAtBlockHeights[0]).To(Equal([]uint64{0, 1, 2}))
 		})
+
+		It("Publishes and indexes each batch together when a bulk indexer is set", func() {
+			mockCidRepo := &mocks.CIDIndexer{
+				ReturnErr: nil,
+			}
+			mockPublisher := &mocks.IterativeIPLDPublisher{
+				ReturnErr: nil,
+			}
+			mockBulkIndexer := &mocks.BulkPublisherAndIndexer{
+				ReturnErr: nil,
+			}
+			mockConverter := &mocks.IterativePayloadConverter{
+				ReturnIPLDPayload: []eth.ConvertedPayload{mocks.MockConvertedPayload, mocks.MockConvertedPayload},
+				ReturnErr:         nil,
+			}
+			mockRetriever := &mocks2.CIDRetriever{
+				FirstBlockNumberToReturn: 0,
+				GapsToRetrieve: []shared.Gap{
+					{
+						Start: 100, Stop: 101,
+					},
+				},
+			}
+			mockFetcher := &mocks2.PayloadFetcher{
+				PayloadsToReturn: map[uint64]shared.RawChainData{
+					100: mocks.MockStateDiffPayload,
+					101: mocks.MockStateDiffPayload,
+				},
+			}
+			quitChan := make(chan bool, 1)
+			backfiller := &super_node.BackFillService{
+				Indexer:           mockCidRepo,
+				Publisher:         mockPublisher,
+				BulkIndexer:       mockBulkIndexer,
+				Converter:         mockConverter,
+				Fetcher:           mockFetcher,
+				Retriever:         mockRetriever,
+				GapCheckFrequency: time.Second * 2,
+				BatchSize:         super_node.DefaultMaxBatchSize,
+				BatchNumber:       super_node.DefaultMaxBatchNumber,
+				QuitChan:          quitChan,
+			}
+			wg := &sync.WaitGroup{}
+			backfiller.BackFill(wg)
+			time.Sleep(time.Second * 3)
+			quitChan <- true
+			Expect(len(mockBulkIndexer.PassedIPLDPayloads)).To(Equal(1))
+			Expect(mockBulkIndexer.PassedIPLDPayloads[0]).To(Equal([]eth.ConvertedPayload{mocks.MockConvertedPayload, mocks.MockConvertedPayload}))
+			Expect(len(mockPublisher.PassedIPLDPayload)).To(Equal(0))
+			Expect(len(mockCidRepo.PassedCIDPayload)).To(Equal(0))
+			Expect(len(mockConverter.PassedStatediffPayload)).To(Equal(2))
+			Expect(mockRetriever.CalledTimes).To(Equal(1))
+			Expect(len(mockFetcher.CalledAtBlockHeights)).To(Equal(1))
+			Expect(mockFetcher.CalledAtBlockHeights[0]).To(Equal([]uint64{100, 101}))
+		})
+
+		It("Validates the blocks below the validation level instead of re-indexing them when a validator is set", func() {
+			mockCidRepo := &mocks.CIDIndexer{
+				ReturnErr: nil,
+			}
+			mockPublisher := &mocks.IterativeIPLDPublisher{
+				ReturnCIDPayload: []*eth.CIDPayload{mocks.MockCIDPayload},
+				ReturnErr:        nil,
+			}
+			mockValidator := &mocks.BlockValidator{
+				ReturnErr: nil,
+			}
+			mockConverter := &mocks.IterativePayloadConverter{
+				ReturnIPLDPayload: []eth.ConvertedPayload{mocks.MockConvertedPayload, mocks.MockConvertedPayload},
+				ReturnErr:         nil,
+			}
+			mockRetriever := &mocks2.CIDRetriever{
+				FirstBlockNumberToReturn: 0,
+				GapsToRetrieve: []shared.Gap{
+					{
+						Start: 100, Stop: 100,
+					},
+				},
+				ValidationGapsToRetrieve: []shared.Gap{
+					{
+						Start: 101, Stop: 101,
+					},
+				},
+			}
+			mockFetcher := &mocks2.PayloadFetcher{
+				PayloadsToReturn: map[uint64]shared.RawChainData{
+					100: mocks.MockStateDiffPayload,
+					101: mocks.MockStateDiffPayload,
+				},
+			}
+			quitChan := make(chan bool, 1)
+			backfiller := &super_node.BackFillService{
+				Indexer:           mockCidRepo,
+				Publisher:         mockPublisher,
+				Validator:         mockValidator,
+				Converter:         mockConverter,
+				Fetcher:           mockFetcher,
+				Retriever:         mockRetriever,
+				GapCheckFrequency: time.Second * 2,
+				BatchSize:         super_node.DefaultMaxBatchSize,
+				BatchNumber:       1,
+				QuitChan:          quitChan,
+			}
+			wg := &sync.WaitGroup{}
+			backfiller.BackFill(wg)
+			time.Sleep(time.Second * 3)
+			quitChan <- true
+			Expect(len(mockPublisher.PassedIPLDPayload)).To(Equal(1))
+			Expect(len(mockCidRepo.PassedCIDPayload)).To(Equal(1))
+			Expect(len(mockValidator.PassedIPLDPayloads)).To(Equal(1))
+			Expect(mockValidator.PassedIPLDPayloads[0]).To(Equal(mocks.MockConvertedPayload))
+			Expect(len(mockConverter.PassedStatediffPayload)).To(Equal(2))
+			Expect(mockRetriever.CalledTimes).To(Equal(1))
+			Expect(len(mockFetcher.CalledAtBlockHeights)).To(Equal(2))
+			Expect(mockFetcher.CalledAtBlockHeights[0]).To(Equal([]uint64{100}))
+			Expect(mockFetcher.CalledAtBlockHeights[1]).To(Equal([]uint64{101}))
+		})
+
+		It("Does not search for or fill in gaps while paused", func() {
+			mockCidRepo := &mocks.CIDIndexer{
+				ReturnErr: nil,
+			}
+			mockPublisher := &mocks.IterativeIPLDPublisher{
+				ReturnCIDPayload: []*eth.CIDPayload{mocks.MockCIDPayload},
+				ReturnErr:        nil,
+			}
+			mockConverter := &mocks.IterativePayloadConverter{
+				ReturnIPLDPayload: []eth.ConvertedPayload{mocks.MockConvertedPayload},
+				ReturnErr:         nil,
+			}
+			mockRetriever := &mocks2.CIDRetriever{
+				FirstBlockNumberToReturn: 0,
+				GapsToRetrieve: []shared.Gap{
+					{
+						Start: 100, Stop: 100,
+					},
+				},
+			}
+			mockFetcher := &mocks2.PayloadFetcher{
+				PayloadsToReturn: map[uint64]shared.RawChainData{
+					100: mocks.MockStateDiffPayload,
+				},
+			}
+			quitChan := make(chan bool, 1)
+			backfiller := &super_node.BackFillService{
+				Indexer:           mockCidRepo,
+				Publisher:         mockPublisher,
+				Converter:         mockConverter,
+				Fetcher:           mockFetcher,
+				Retriever:         mockRetriever,
+				GapCheckFrequency: time.Second * 2,
+				BatchSize:         super_node.DefaultMaxBatchSize,
+				BatchNumber:       super_node.DefaultMaxBatchNumber,
+				QuitChan:          quitChan,
+			}
+			backfiller.Pause()
+			Expect(backfiller.Paused()).To(BeTrue())
+			wg := &sync.WaitGroup{}
+			backfiller.BackFill(wg)
+			time.Sleep(time.Second * 3)
+			Expect(mockRetriever.CalledTimes).To(Equal(0))
+			Expect(len(mockCidRepo.PassedCIDPayload)).To(Equal(0))
+			backfiller.Resume()
+			Expect(backfiller.Paused()).To(BeFalse())
+			time.Sleep(time.Second * 2)
+			quitChan <- true
+			Expect(mockRetriever.CalledTimes).To(Equal(1))
+			Expect(len(mockCidRepo.PassedCIDPayload)).To(Equal(1))
+			Expect(mockCidRepo.PassedCIDPayload[0]).To(Equal(mocks.MockCIDPayload))
+		})
 	})
 })
@@ -0,0 +1,495 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package btc
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+// APIName is the namespace for the super node's btc api
+const APIName = "btc"
+
+// APIVersion is the version of the super node's btc api
+const APIVersion = "0.0.1"
+
+// PublicBtcAPI serves the bitcoind JSON-RPC block and transaction queries from the indexed data
+type PublicBtcAPI struct {
+	B *Backend
+}
+
+// NewPublicBtcAPI creates a new PublicBtcAPI with the provided underlying Backend
+func NewPublicBtcAPI(b *Backend) *PublicBtcAPI {
+	return &PublicBtcAPI{
+		B: b,
+	}
+}
+
+// GetBlockVerboseResult is the result of getblock with verbosity 1 or 2
+// Tx holds the transaction ids for verbosity 1 and the decoded transactions for verbosity 2
+type GetBlockVerboseResult struct {
+	Hash          string      `json:"hash"`
+	Confirmations int64       `json:"confirmations"`
+	StrippedSize  int32       `json:"strippedsize"`
+	Size          int32       `json:"size"`
+	Weight        int32       `json:"weight"`
+	Height        int64       `json:"height"`
+	Version       int32       `json:"version"`
+	VersionHex    string      `json:"versionHex"`
+	MerkleRoot    string      `json:"merkleroot"`
+	Tx            interface{} `json:"tx"`
+	Time          int64       `json:"time"`
+	Nonce         uint32      `json:"nonce"`
+	Bits          string      `json:"bits"`
+	Difficulty    float64     `json:"difficulty"`
+	NTx           int         `json:"nTx"`
+	PreviousHash  string      `json:"previousblockhash,omitempty"`
+	NextHash      string      `json:"nextblockhash,omitempty"`
+}
+
+// AddressBalanceResult is the result of getaddressbalance, amounts are in satoshis
+type AddressBalanceResult struct {
+	Balance  int64 `json:"balance"`
+	Received int64 `json:"received"`
+	Height   int64 `json:"height"`
+}
+
+// AddressUTXOResult is an unspent output in the result of getaddressutxos
+type AddressUTXOResult struct {
+	Address     string `json:"address"`
+	TxID        string `json:"txid"`
+	OutputIndex int64  `json:"outputIndex"`
+	Script      string `json:"script"`
+	Satoshis    int64  `json:"satoshis"`
+	Height      int64  `json:"height"`
+}
+
+// AddressHistoryResult is a transaction in the result of getaddresshistory, amounts are in satoshis
+type AddressHistoryResult struct {
+	TxID      string `json:"txid"`
+	Height    int64  `json:"height"`
+	BlockHash string `json:"blockhash"`
+	Received  int64  `json:"received"`
+	Spent     int64  `json:"spent"`
+}
+
+// rpcError is an error carrying the bitcoind JSON-RPC error code
+type rpcError struct {
+	code    btcjson.RPCErrorCode
+	message string
+}
+
+// Error satisfies the error interface
+func (e *rpcError) Error() string {
+	return e.message
+}
+
+// ErrorCode satisfies the rpc.Error interface so that the code is returned to the client
+func (e *rpcError) ErrorCode() int {
+	return int(e.code)
+}
+
+// GetBlockCount returns the height of the most recent indexed block
+//
+// https://developer.bitcoin.org/reference/rpc/getblockcount.html
+func (pba *PublicBtcAPI) GetBlockCount() (int64, error) {
+	return pba.B.Retriever.RetrieveLastBlockNumber()
+}
+
+// GetBlockHash returns the hash of the indexed block at the given height
+//
+// https://developer.bitcoin.org/reference/rpc/getblockhash.html
+func (pba *PublicBtcAPI) GetBlockHash(height int64) (string, error) {
+	_, headerCID, err := pba.B.BlockByNumber(height)
+	if err != nil {
+		return "", &rpcError{code: btcjson.ErrRPCOutOfRange, message: "Block height out of range"}
+	}
+	return headerCID.BlockHash, nil
+}
+
+// GetBlockHeader returns the header with the given hash
+// When verbose is false it returns the serialized header as hex, otherwise (the default) it returns the decoded header
+//
+// https://developer.bitcoin.org/reference/rpc/getblockheader.html
+func (pba *PublicBtcAPI) GetBlockHeader(blockHash string, verbose *bool) (interface{}, error) {
+	hash, err := decodeHash(blockHash)
+	if err != nil {
+		return nil, err
+	}
+	header, headerCID, err := pba.B.HeaderByHash(*hash)
+	if err == sql.ErrNoRows {
+		return nil, &rpcError{code: btcjson.ErrRPCBlockNotFound, message: "Block not found"}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if verbose != nil && !*verbose {
+		buf := bytes.NewBuffer(make([]byte, 0, wire.MaxBlockHeaderPayload))
+		if err := header.Serialize(buf); err != nil {
+			return nil, err
+		}
+		return hex.EncodeToString(buf.Bytes()), nil
+	}
+	height, confirmations, nextHash, err := pba.chainPosition(headerCID)
+	if err != nil {
+		return nil, err
+	}
+	return btcjson.GetBlockHeaderVerboseResult{
+		Hash:          headerCID.BlockHash,
+		Confirmations: confirmations,
+		Height:        int32(height),
+		Version:       header.Version,
+		VersionHex:    fmt.Sprintf("%08x", header.Version),
+		MerkleRoot:    header.MerkleRoot.String(),
+		Time:          header.Timestamp.Unix(),
+		Nonce:         uint64(header.Nonce),
+		Bits:          fmt.Sprintf("%08x", header.Bits),
+		Difficulty:    difficultyRatio(header.Bits, pba.B.Params),
+		PreviousHash:  previousHash(header),
+		NextHash:      nextHash,
+	}, nil
+}
+
+// GetBlock returns the block with the given hash
+// Verbosity 0 returns the serialized block as hex, 1 (the default) returns the decoded block with transaction ids,
+// and 2 returns the decoded block with decoded transactions
+//
+// https://developer.bitcoin.org/reference/rpc/getblock.html
+func (pba *PublicBtcAPI) GetBlock(blockHash string, verbosity *int) (interface{}, error) {
+	hash, err := decodeHash(blockHash)
+	if err != nil {
+		return nil, err
+	}
+	level := 1
+	if verbosity != nil {
+		level = *verbosity
+	}
+	if level < 0 || level > 2 {
+		return nil, &rpcError{code: btcjson.ErrRPCInvalidParameter, message: fmt.Sprintf("Invalid verbosity %d", level)}
+	}
+	block, headerCID, err := pba.B.BlockByHash(*hash)
+	if err == sql.ErrNoRows {
+		return nil, &rpcError{code: btcjson.ErrRPCBlockNotFound, message: "Block not found"}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if level == 0 {
+		buf := bytes.NewBuffer(make([]byte, 0, block.SerializeSize()))
+		if err := block.Serialize(buf); err != nil {
+			return nil, err
+		}
+		return hex.EncodeToString(buf.Bytes()), nil
+	}
+	height, confirmations, nextHash, err := pba.chainPosition(headerCID)
+	if err != nil {
+		return nil, err
+	}
+	result := GetBlockVerboseResult{
+		Hash:          headerCID.BlockHash,
+		Confirmations: confirmations,
+		StrippedSize:  int32(block.SerializeSizeStripped()),
+		Size:          int32(block.SerializeSize()),
+		Weight:        int32(block.SerializeSizeStripped()*(blockchain.WitnessScaleFactor-1) + block.SerializeSize()),
+		Height:        height,
+		Version:       block.Header.Version,
+		VersionHex:    fmt.Sprintf("%08x", block.Header.Version),
+		MerkleRoot:    block.Header.MerkleRoot.String(),
+		Time:          block.Header.Timestamp.Unix(),
+		Nonce:         block.Header.Nonce,
+		Bits:          fmt.Sprintf("%08x", block.Header.Bits),
+		Difficulty:    difficultyRatio(block.Header.Bits, pba.B.Params),
+		NTx:           len(block.Transactions),
+		PreviousHash:  previousHash(&block.Header),
+		NextHash:      nextHash,
+	}
+	if level == 1 {
+		txIDs := make([]string, len(block.Transactions))
+		for i, tx := range block.Transactions {
+			txIDs[i] = tx.TxHash().String()
+		}
+		result.Tx = txIDs
+		return result, nil
+	}
+	txs := make([]btcjson.TxRawResult, len(block.Transactions))
+	for i, tx := range block.Transactions {
+		txs[i], err = createTxRawResult(pba.B.Params, tx, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+	result.Tx = txs
+	return result, nil
+}
+
+// GetRawTransaction returns the transaction with the given id
+// When verbose is false (the default) it returns the serialized transaction as hex, otherwise it returns the decoded
+// transaction along with the block it was included in
+//
+// https://developer.bitcoin.org/reference/rpc/getrawtransaction.html
+func (pba *PublicBtcAPI) GetRawTransaction(txid string, verbose *bool) (interface{}, error) {
+	hash, err := decodeHash(txid)
+	if err != nil {
+		return nil, err
+	}
+	tx, headerCID, err := pba.B.TransactionByHash(*hash)
+	if err == sql.ErrNoRows {
+		return nil, &rpcError{code: btcjson.ErrRPCNoTxInfo, message: "No such mempool or blockchain transaction"}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if verbose == nil || !*verbose {
+		buf := bytes.NewBuffer(make([]byte, 0, tx.SerializeSize()))
+		if err := tx.Serialize(buf); err != nil {
+			return nil, err
+		}
+		return hex.EncodeToString(buf.Bytes()), nil
+	}
+	confirmations, err := pba.B.Confirmations(headerCID)
+	if err != nil {
+		return nil, err
+	}
+	return createTxRawResult(pba.B.Params, tx, &headerCID, confirmations)
+}
+
+// GetAddressBalance returns the balance of the address, and the total it has received, as of the given height
+// The height defaults to the latest indexed block
+func (pba *PublicBtcAPI) GetAddressBalance(address string, height *int64) (*AddressBalanceResult, error) {
+	addr, number, err := pba.addressAndHeight(address, height)
+	if err != nil {
+		return nil, err
+	}
+	received, spent, err := pba.B.Retriever.RetrieveAddressTotals(addr, number)
+	if err != nil {
+		return nil, err
+	}
+	return &AddressBalanceResult{
+		Balance:  received - spent,
+		Received: received,
+		Height:   number,
+	}, nil
+}
+
+// GetAddressUtxos returns the unspent outputs paying to the address as of the given height
+// The height defaults to the latest indexed block
+func (pba *PublicBtcAPI) GetAddressUtxos(address string, height *int64) ([]AddressUTXOResult, error) {
+	addr, number, err := pba.addressAndHeight(address, height)
+	if err != nil {
+		return nil, err
+	}
+	utxos, err := pba.B.Retriever.RetrieveUTXOs(addr, number)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]AddressUTXOResult, len(utxos))
+	for i, utxo := range utxos {
+		results[i] = AddressUTXOResult{
+			Address:     addr,
+			TxID:        utxo.TxHash,
+			OutputIndex: utxo.Index,
+			Script:      hex.EncodeToString(utxo.PkScript),
+			Satoshis:    utxo.Value,
+			Height:      utxo.BlockNumber,
+		}
+	}
+	return results, nil
+}
+
+// GetAddressHistory returns the transactions which paid to or spent from the address up to the given height,
+// in chain order, along with the amounts the address received and spent in each
+// The height defaults to the latest indexed block
+func (pba *PublicBtcAPI) GetAddressHistory(address string, height *int64) ([]AddressHistoryResult, error) {
+	addr, number, err := pba.addressAndHeight(address, height)
+	if err != nil {
+		return nil, err
+	}
+	history, err := pba.B.Retriever.RetrieveAddressHistory(addr, number)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]AddressHistoryResult, len(history))
+	for i, entry := range history {
+		results[i] = AddressHistoryResult{
+			TxID:      entry.TxHash,
+			Height:    entry.BlockNumber,
+			BlockHash: entry.BlockHash,
+			Received:  entry.Received,
+			Spent:     entry.Spent,
+		}
+	}
+	return results, nil
+}
+
+// addressAndHeight validates the address for the chain and resolves the optional height, defaulting to the latest indexed block
+func (pba *PublicBtcAPI) addressAndHeight(address string, height *int64) (string, int64, error) {
+	addr, err := btcutil.DecodeAddress(address, pba.B.Params)
+	if err != nil || !addr.IsForNet(pba.B.Params) {
+		return "", 0, &rpcError{code: btcjson.ErrRPCInvalidAddressOrKey, message: "Invalid address"}
+	}
+	if height != nil {
+		return addr.EncodeAddress(), *height, nil
+	}
+	number, err := pba.B.Retriever.RetrieveLastBlockNumber()
+	return addr.EncodeAddress(), number, err
+}
+
+// chainPosition returns the height, number of confirmations and the next block hash for the given header
+func (pba *PublicBtcAPI) chainPosition(headerCID HeaderModel) (int64, int64, string, error) {
+	height, err := strconv.ParseInt(headerCID.BlockNumber, 10, 64)
+	if err != nil {
+		return 0, 0, "", err
+	}
+	confirmations, err := pba.B.Confirmations(headerCID)
+	if err != nil {
+		return 0, 0, "", err
+	}
+	nextHash, err := pba.B.NextBlockHash(headerCID)
+	return height, confirmations, nextHash, err
+}
+
+// createTxRawResult decodes a transaction into the bitcoind getrawtransaction result
+// The block fields are only set when a header is provided
+func createTxRawResult(params *chaincfg.Params, tx *wire.MsgTx, headerCID *HeaderModel, confirmations int64) (btcjson.TxRawResult, error) {
+	buf := bytes.NewBuffer(make([]byte, 0, tx.SerializeSize()))
+	if err := tx.Serialize(buf); err != nil {
+		return btcjson.TxRawResult{}, err
+	}
+	weight := tx.SerializeSizeStripped()*(blockchain.WitnessScaleFactor-1) + tx.SerializeSize()
+	result := btcjson.TxRawResult{
+		Hex:      hex.EncodeToString(buf.Bytes()),
+		Txid:     tx.TxHash().String(),
+		Hash:     tx.WitnessHash().String(),
+		Size:     int32(tx.SerializeSize()),
+		Vsize:    int32((weight + blockchain.WitnessScaleFactor - 1) / blockchain.WitnessScaleFactor),
+		Version:  tx.Version,
+		LockTime: tx.LockTime,
+		Vin:      createVinList(tx),
+		Vout:     createVoutList(tx, params),
+	}
+	if headerCID != nil {
+		// timestamps are indexed in nanoseconds
+		blockTime := headerCID.Timestamp / 1e9
+		result.BlockHash = headerCID.BlockHash
+		result.Confirmations = uint64(confirmations)
+		result.Time = blockTime
+		result.Blocktime = blockTime
+	}
+	return result, nil
+}
+
+// createVinList returns the bitcoind JSON objects for the inputs of the transaction
+func createVinList(tx *wire.MsgTx) []btcjson.Vin {
+	vinList := make([]btcjson.Vin, len(tx.TxIn))
+	if blockchain.IsCoinBaseTx(tx) {
+		txIn := tx.TxIn[0]
+		vinList[0].Coinbase = hex.EncodeToString(txIn.SignatureScript)
+		vinList[0].Sequence = txIn.Sequence
+		vinList[0].Witness = witnessToHex(txIn.Witness)
+		return vinList
+	}
+	for i, txIn := range tx.TxIn {
+		// the disassembled script contains [error] inline if it doesn't fully parse
+		disbuf, _ := txscript.DisasmString(txIn.SignatureScript)
+		vinList[i].Txid = txIn.PreviousOutPoint.Hash.String()
+		vinList[i].Vout = txIn.PreviousOutPoint.Index
+		vinList[i].Sequence = txIn.Sequence
+		vinList[i].ScriptSig = &btcjson.ScriptSig{
+			Asm: disbuf,
+			Hex: hex.EncodeToString(txIn.SignatureScript),
+		}
+		if tx.HasWitness() {
+			vinList[i].Witness = witnessToHex(txIn.Witness)
+		}
+	}
+	return vinList
+}
+
+// createVoutList returns the bitcoind JSON objects for the outputs of the transaction
+func createVoutList(tx *wire.MsgTx, params *chaincfg.Params) []btcjson.Vout {
+	voutList := make([]btcjson.Vout, len(tx.TxOut))
+	for i, txOut := range tx.TxOut {
+		disbuf, _ := txscript.DisasmString(txOut.PkScript)
+		// an error means the script is non-standard and there are no addresses to extract
+		scriptClass, addrs, reqSigs, _ := txscript.ExtractPkScriptAddrs(txOut.PkScript, params)
+		encodedAddrs := make([]string, len(addrs))
+		for j, addr := range addrs {
+			encodedAddrs[j] = addr.EncodeAddress()
+		}
+		voutList[i] = btcjson.Vout{
+			Value: btcutil.Amount(txOut.Value).ToBTC(),
+			N:     uint32(i),
+			ScriptPubKey: btcjson.ScriptPubKeyResult{
+				Asm:       disbuf,
+				Hex:       hex.EncodeToString(txOut.PkScript),
+				ReqSigs:   int32(reqSigs),
+				Type:      scriptClass.String(),
+				Addresses: encodedAddrs,
+			},
+		}
+	}
+	return voutList
+}
+
+// witnessToHex hex encodes each item of the witness stack
+func witnessToHex(witness wire.TxWitness) []string {
+	if len(witness) == 0 {
+		return nil
+	}
+	result := make([]string, len(witness))
+	for i, item := range witness {
+		result[i] = hex.EncodeToString(item)
+	}
+	return result
+}
+
+// difficultyRatio returns the proof-of-work difficulty as a multiple of the minimum difficulty of the network
+func difficultyRatio(bits uint32, params *chaincfg.Params) float64 {
+	max := blockchain.CompactToBig(params.PowLimitBits)
+	target := blockchain.CompactToBig(bits)
+	difficulty, _ := strconv.ParseFloat(new(big.Rat).SetFrac(max, target).FloatString(8), 64)
+	return difficulty
+}
+
+// previousHash returns the parent hash of the header, or an empty string for the genesis block
+func previousHash(header *wire.BlockHeader) string {
+	if header.PrevBlock == (chainhash.Hash{}) {
+		return ""
+	}
+	return header.PrevBlock.String()
+}
+
+// decodeHash parses a hex encoded block or transaction hash
+func decodeHash(hash string) (*chainhash.Hash, error) {
+	h, err := chainhash.NewHashFromStr(hash)
+	if err != nil || len(hash) != chainhash.MaxHashStringSize {
+		return nil, &rpcError{code: btcjson.ErrRPCInvalidParameter, message: fmt.Sprintf("%s must be of length %d (not %d)", hash, chainhash.MaxHashStringSize, len(hash))}
+	}
+	return h, nil
+}
@@ -0,0 +1,318 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package btc_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"time"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/vulcanizedb/pkg/postgres"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/btc"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/btc/mocks"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+var (
+	alice, _ = btcutil.NewAddressPubKeyHash(bytes.Repeat([]byte{1}, 20), &chaincfg.MainNetParams)
+	bob, _   = btcutil.NewAddressPubKeyHash(bytes.Repeat([]byte{2}, 20), &chaincfg.MainNetParams)
+	carol, _ = btcutil.NewAddressPubKeyHash(bytes.Repeat([]byte{3}, 20), &chaincfg.MainNetParams)
+
+	// block one pays 50 BTC to alice, block two pays 50 BTC to bob and spends alice's output to pay bob 30 BTC and alice 20 BTC
+	// competingBlockTwo replaces block two with a block which only pays 50 BTC to carol
+	blockOne          = testBlock(chainhash.Hash{}, 1, coinbaseTx(1, alice, 50e8))
+	aliceOutput       = wire.OutPoint{Hash: blockOne.Transactions[0].TxHash(), Index: 0}
+	spendTx           = paymentTx(aliceOutput, txOut(bob, 30e8), txOut(alice, 20e8))
+	blockTwo          = testBlock(blockOne.BlockHash(), 2, coinbaseTx(2, bob, 50e8), spendTx)
+	competingBlockTwo = testBlock(blockOne.BlockHash(), 3, coinbaseTx(2, carol, 50e8))
+)
+
+func txOut(addr btcutil.Address, value int64) *wire.TxOut {
+	pkScript, _ := txscript.PayToAddrScript(addr)
+	return wire.NewTxOut(value, pkScript)
+}
+
+func coinbaseTx(height int64, addr btcutil.Address, value int64) *wire.MsgTx {
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{}, wire.MaxPrevOutIndex), []byte{byte(height)}, nil))
+	tx.AddTxOut(txOut(addr, value))
+	return tx
+}
+
+func paymentTx(prevOut wire.OutPoint, outs ...*wire.TxOut) *wire.MsgTx {
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(&prevOut, []byte{0x51}, nil))
+	for _, out := range outs {
+		tx.AddTxOut(out)
+	}
+	return tx
+}
+
+func testBlock(parent chainhash.Hash, nonce uint32, txs ...*wire.MsgTx) *wire.MsgBlock {
+	block := wire.NewMsgBlock(wire.NewBlockHeader(1, &parent, &chainhash.Hash{}, mocks.MockBlock.Header.Bits, nonce))
+	block.Header.Timestamp = time.Unix(1500000000+int64(nonce), 0)
+	for _, tx := range txs {
+		block.AddTransaction(tx)
+	}
+	return block
+}
+
+// publishBlock converts and publishes the block at the given height
+func publishBlock(db *postgres.DB, block *wire.MsgBlock, height int64) {
+	txs := make([]*btcutil.Tx, len(block.Transactions))
+	for i, tx := range block.Transactions {
+		txs[i] = btcutil.NewTx(tx)
+	}
+	converted, err := btc.NewPayloadConverter(&chaincfg.MainNetParams).Convert(btc.BlockPayload{
+		BlockHeight: height,
+		Header:      &block.Header,
+		Txs:         txs,
+	})
+	Expect(err).ToNot(HaveOccurred())
+	_, err = btc.NewIPLDPublisherAndIndexer(db).Publish(converted)
+	Expect(err).ToNot(HaveOccurred())
+}
+
+var _ = Describe("API", func() {
+	var (
+		db          *postgres.DB
+		api         *btc.PublicBtcAPI
+		blockHash   = mocks.MockBlock.Header.BlockHash().String()
+		coinbaseTx  = mocks.MockBlock.Transactions[0]
+		verbose     = true
+		notVerbose  = false
+		verbosities = []int{0, 1, 2}
+	)
+	BeforeEach(func() {
+		var err error
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = btc.NewIPLDPublisherAndIndexer(db).Publish(mocks.MockConvertedPayload)
+		Expect(err).ToNot(HaveOccurred())
+		backend, err := btc.NewBtcBackend(db, &chaincfg.MainNetParams)
+		Expect(err).ToNot(HaveOccurred())
+		api = btc.NewPublicBtcAPI(backend)
+	})
+	AfterEach(func() {
+		btc.TearDownDB(db)
+	})
+
+	Describe("GetBlockCount", func() {
+		It("Returns the height of the latest indexed block", func() {
+			count, err := api.GetBlockCount()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(count).To(Equal(mocks.MockBlockHeight))
+		})
+	})
+
+	Describe("GetBlockHash", func() {
+		It("Returns the hash of the block at the given height", func() {
+			hash, err := api.GetBlockHash(mocks.MockBlockHeight)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hash).To(Equal(blockHash))
+		})
+		It("Returns an out of range error for heights that have not been indexed", func() {
+			_, err := api.GetBlockHash(mocks.MockBlockHeight + 1)
+			Expect(err).To(HaveOccurred())
+			Expect(err.(interface{ ErrorCode() int }).ErrorCode()).To(Equal(int(btcjson.ErrRPCOutOfRange)))
+		})
+	})
+
+	Describe("GetBlockHeader", func() {
+		It("Returns the serialized header when verbose is false", func() {
+			header, err := api.GetBlockHeader(blockHash, &notVerbose)
+			Expect(err).ToNot(HaveOccurred())
+			buf := new(bytes.Buffer)
+			err = mocks.MockBlock.Header.Serialize(buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(header).To(Equal(hex.EncodeToString(buf.Bytes())))
+		})
+		It("Returns the decoded header by default", func() {
+			header, err := api.GetBlockHeader(blockHash, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(header).To(Equal(btcjson.GetBlockHeaderVerboseResult{
+				Hash:          blockHash,
+				Confirmations: 1,
+				Height:        int32(mocks.MockBlockHeight),
+				Version:       1,
+				VersionHex:    "00000001",
+				MerkleRoot:    mocks.MockBlock.Header.MerkleRoot.String(),
+				Time:          mocks.MockBlock.Header.Timestamp.Unix(),
+				Nonce:         uint64(mocks.MockBlock.Header.Nonce),
+				Bits:          "1b04864c",
+				Difficulty:    14484.16236123,
+				PreviousHash:  mocks.MockBlock.Header.PrevBlock.String(),
+			}))
+		})
+		It("Returns a block not found error for unknown hashes", func() {
+			_, err := api.GetBlockHeader(chainhash.Hash{}.String(), &verbose)
+			Expect(err).To(HaveOccurred())
+			Expect(err.(interface{ ErrorCode() int }).ErrorCode()).To(Equal(int(btcjson.ErrRPCBlockNotFound)))
+		})
+	})
+
+	Describe("GetBlock", func() {
+		It("Returns the serialized block for verbosity 0", func() {
+			block, err := api.GetBlock(blockHash, &verbosities[0])
+			Expect(err).ToNot(HaveOccurred())
+			buf := new(bytes.Buffer)
+			err = mocks.MockBlock.Serialize(buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(block).To(Equal(hex.EncodeToString(buf.Bytes())))
+		})
+		It("Returns the decoded block with transaction ids for verbosity 1", func() {
+			block, err := api.GetBlock(blockHash, &verbosities[1])
+			Expect(err).ToNot(HaveOccurred())
+			result := block.(btc.GetBlockVerboseResult)
+			Expect(result.Hash).To(Equal(blockHash))
+			Expect(result.Height).To(Equal(mocks.MockBlockHeight))
+			Expect(result.Size).To(Equal(int32(mocks.MockBlock.SerializeSize())))
+			Expect(result.NTx).To(Equal(len(mocks.MockBlock.Transactions)))
+			Expect(result.NextHash).To(BeEmpty())
+			txIDs := make([]string, len(mocks.MockBlock.Transactions))
+			for i, tx := range mocks.MockBlock.Transactions {
+				txIDs[i] = tx.TxHash().String()
+			}
+			Expect(result.Tx).To(Equal(txIDs))
+		})
+		It("Returns the decoded block with decoded transactions for verbosity 2", func() {
+			block, err := api.GetBlock(blockHash, &verbosities[2])
+			Expect(err).ToNot(HaveOccurred())
+			txs := block.(btc.GetBlockVerboseResult).Tx.([]btcjson.TxRawResult)
+			Expect(len(txs)).To(Equal(len(mocks.MockBlock.Transactions)))
+			Expect(txs[0].Txid).To(Equal(coinbaseTx.TxHash().String()))
+			Expect(txs[0].Vin[0].Coinbase).To(Equal(hex.EncodeToString(coinbaseTx.TxIn[0].SignatureScript)))
+			Expect(txs[0].Vout[0].Value).To(Equal(50.0))
+			Expect(txs[0].Vout[0].ScriptPubKey.Type).To(Equal("pubkey"))
+			Expect(txs[1].Vin[0].Txid).To(Equal(mocks.MockBlock.Transactions[1].TxIn[0].PreviousOutPoint.Hash.String()))
+			Expect(txs[1].Vout[0].ScriptPubKey.Addresses).To(Equal([]string(mocks.MockTxsMetaData[1].TxOutputs[0].Addresses)))
+		})
+	})
+
+	Describe("GetRawTransaction", func() {
+		It("Returns the serialized transaction by default", func() {
+			tx, err := api.GetRawTransaction(coinbaseTx.TxHash().String(), nil)
+			Expect(err).ToNot(HaveOccurred())
+			buf := new(bytes.Buffer)
+			err = coinbaseTx.Serialize(buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tx).To(Equal(hex.EncodeToString(buf.Bytes())))
+		})
+		It("Returns the decoded transaction and its block when verbose is true", func() {
+			tx, err := api.GetRawTransaction(coinbaseTx.TxHash().String(), &verbose)
+			Expect(err).ToNot(HaveOccurred())
+			result := tx.(btcjson.TxRawResult)
+			Expect(result.Txid).To(Equal(coinbaseTx.TxHash().String()))
+			Expect(result.BlockHash).To(Equal(blockHash))
+			Expect(result.Confirmations).To(Equal(uint64(1)))
+			Expect(result.Blocktime).To(Equal(mocks.MockBlock.Header.Timestamp.Unix()))
+		})
+		It("Returns an error for unknown transactions", func() {
+			_, err := api.GetRawTransaction(chainhash.Hash{}.String(), &verbose)
+			Expect(err).To(HaveOccurred())
+			Expect(err.(interface{ ErrorCode() int }).ErrorCode()).To(Equal(int(btcjson.ErrRPCNoTxInfo)))
+		})
+	})
+	Describe("Address index", func() {
+		var (
+			one = int64(1)
+			two = int64(2)
+		)
+		BeforeEach(func() {
+			publishBlock(db, blockOne, 1)
+			publishBlock(db, blockTwo, 2)
+		})
+
+		It("Returns an address's balance as of a height", func() {
+			balance, err := api.GetAddressBalance(alice.EncodeAddress(), &one)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*balance).To(Equal(btc.AddressBalanceResult{Balance: 50e8, Received: 50e8, Height: 1}))
+			balance, err = api.GetAddressBalance(alice.EncodeAddress(), &two)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*balance).To(Equal(btc.AddressBalanceResult{Balance: 20e8, Received: 70e8, Height: 2}))
+			balance, err = api.GetAddressBalance(bob.EncodeAddress(), &two)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*balance).To(Equal(btc.AddressBalanceResult{Balance: 80e8, Received: 80e8, Height: 2}))
+		})
+
+		It("Returns an address's unspent outputs as of a height", func() {
+			utxos, err := api.GetAddressUtxos(alice.EncodeAddress(), &one)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(utxos).To(Equal([]btc.AddressUTXOResult{{
+				Address:     alice.EncodeAddress(),
+				TxID:        aliceOutput.Hash.String(),
+				OutputIndex: 0,
+				Script:      hex.EncodeToString(blockOne.Transactions[0].TxOut[0].PkScript),
+				Satoshis:    50e8,
+				Height:      1,
+			}}))
+			utxos, err = api.GetAddressUtxos(alice.EncodeAddress(), &two)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(utxos)).To(Equal(1))
+			Expect(utxos[0].TxID).To(Equal(spendTx.TxHash().String()))
+			Expect(utxos[0].OutputIndex).To(Equal(int64(1)))
+			Expect(utxos[0].Satoshis).To(Equal(int64(20e8)))
+		})
+
+		It("Returns an address's transaction history", func() {
+			history, err := api.GetAddressHistory(alice.EncodeAddress(), &two)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(history).To(Equal([]btc.AddressHistoryResult{
+				{TxID: aliceOutput.Hash.String(), Height: 1, BlockHash: blockOne.BlockHash().String(), Received: 50e8},
+				{TxID: spendTx.TxHash().String(), Height: 2, BlockHash: blockTwo.BlockHash().String(), Received: 20e8, Spent: 50e8},
+			}))
+		})
+
+		It("Rolls back spends and history when a block is reorged out", func() {
+			publishBlock(db, competingBlockTwo, 2)
+			balance, err := api.GetAddressBalance(alice.EncodeAddress(), &two)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(balance.Balance).To(Equal(int64(50e8)))
+			utxos, err := api.GetAddressUtxos(alice.EncodeAddress(), &two)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(utxos)).To(Equal(1))
+			Expect(utxos[0].TxID).To(Equal(aliceOutput.Hash.String()))
+			history, err := api.GetAddressHistory(bob.EncodeAddress(), &two)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(history).To(BeEmpty())
+			balance, err = api.GetAddressBalance(carol.EncodeAddress(), &two)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(balance.Balance).To(Equal(int64(50e8)))
+
+			// Re-indexing the original block makes it canonical again
+			publishBlock(db, blockTwo, 2)
+			balance, err = api.GetAddressBalance(alice.EncodeAddress(), &two)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(balance.Balance).To(Equal(int64(20e8)))
+		})
+
+		It("Rejects invalid addresses", func() {
+			_, err := api.GetAddressBalance("not an address", nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.(interface{ ErrorCode() int }).ErrorCode()).To(Equal(int(btcjson.ErrRPCInvalidAddressOrKey)))
+		})
+	})
+})
@@ -0,0 +1,196 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package btc
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/vulcanize/vulcanizedb/pkg/postgres"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+// Backend assembles bitcoin blocks, headers and transactions from the indexed CIDs and their IPLD blocks
+type Backend struct {
+	Retriever *CIDRetriever
+	Fetcher   *IPLDPGFetcher
+	DB        *postgres.DB
+	Params    *chaincfg.Params
+}
+
+// NewBtcBackend creates a new Backend for the provided chain params
+func NewBtcBackend(db *postgres.DB, params *chaincfg.Params) (*Backend, error) {
+	return &Backend{
+		Retriever: NewCIDRetriever(db),
+		Fetcher:   NewIPLDPGFetcher(db),
+		DB:        db,
+		Params:    params,
+	}, nil
+}
+
+// BlockByHash returns the block with the given hash along with its header CID
+func (b *Backend) BlockByHash(hash chainhash.Hash) (*wire.MsgBlock, HeaderModel, error) {
+	headerCID, txCIDs, err := b.Retriever.RetrieveBlockByHash(hash)
+	if err != nil {
+		return nil, HeaderModel{}, err
+	}
+	block, err := b.fetchBlock(headerCID, txCIDs)
+	return block, headerCID, err
+}
+
+// BlockByNumber returns the block at the given height along with its header CID
+func (b *Backend) BlockByNumber(number int64) (*wire.MsgBlock, HeaderModel, error) {
+	headerCID, txCIDs, err := b.Retriever.RetrieveBlockByNumber(number)
+	if err != nil {
+		return nil, HeaderModel{}, err
+	}
+	block, err := b.fetchBlock(headerCID, txCIDs)
+	return block, headerCID, err
+}
+
+// HeaderByHash returns the header with the given hash along with its header CID
+func (b *Backend) HeaderByHash(hash chainhash.Hash) (*wire.BlockHeader, HeaderModel, error) {
+	tx, err := b.DB.Beginx()
+	if err != nil {
+		return nil, HeaderModel{}, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+	headerCID, err := b.Retriever.RetrieveHeaderCIDByHash(tx, hash)
+	if err != nil {
+		return nil, HeaderModel{}, err
+	}
+	headerIPLD, err := b.Fetcher.FetchHeader(tx, headerCID)
+	if err != nil {
+		return nil, HeaderModel{}, err
+	}
+	header := new(wire.BlockHeader)
+	err = header.Deserialize(bytes.NewReader(headerIPLD.Data))
+	return header, headerCID, err
+}
+
+// TransactionByHash returns the transaction with the given hash along with the header CID of the block that includes it
+func (b *Backend) TransactionByHash(hash chainhash.Hash) (*wire.MsgTx, HeaderModel, error) {
+	headerCID, txCID, err := b.Retriever.RetrieveTxCIDByHash(hash)
+	if err != nil {
+		return nil, HeaderModel{}, err
+	}
+	tx, err := b.DB.Beginx()
+	if err != nil {
+		return nil, HeaderModel{}, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+	txIPLDs, err := b.Fetcher.FetchTrxs(tx, []TxModel{txCID})
+	if err != nil {
+		return nil, HeaderModel{}, err
+	}
+	msgTx := new(wire.MsgTx)
+	err = msgTx.Deserialize(bytes.NewReader(txIPLDs[0].Data))
+	return msgTx, headerCID, err
+}
+
+// NextBlockHash returns the hash of the indexed block built on top of the given header, or an empty string if there is none
+func (b *Backend) NextBlockHash(headerCID HeaderModel) (string, error) {
+	number, err := strconv.ParseInt(headerCID.BlockNumber, 10, 64)
+	if err != nil {
+		return "", err
+	}
+	pgStr := `SELECT block_hash FROM btc.header_cids
+			WHERE block_number = $1 AND parent_hash = $2
+			LIMIT 1`
+	var nextHash string
+	err = b.DB.Get(&nextHash, pgStr, number+1, headerCID.BlockHash)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return nextHash, err
+}
+
+// Confirmations returns the number of confirmations a block at the given height has with respect to the latest indexed block
+func (b *Backend) Confirmations(headerCID HeaderModel) (int64, error) {
+	number, err := strconv.ParseInt(headerCID.BlockNumber, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	head, err := b.Retriever.RetrieveLastBlockNumber()
+	if err != nil {
+		return 0, err
+	}
+	return head - number + 1, nil
+}
+
+// fetchBlock fetches the header and transaction IPLDs referenced by the CIDs and decodes them into a block
+func (b *Backend) fetchBlock(headerCID HeaderModel, txCIDs []TxModel) (*wire.MsgBlock, error) {
+	tx, err := b.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+	headerIPLD, err := b.Fetcher.FetchHeader(tx, headerCID)
+	if err != nil {
+		return nil, err
+	}
+	block := new(wire.MsgBlock)
+	if err = block.Header.Deserialize(bytes.NewReader(headerIPLD.Data)); err != nil {
+		return nil, err
+	}
+	txIPLDs, err := b.Fetcher.FetchTrxs(tx, txCIDs)
+	if err != nil {
+		return nil, err
+	}
+	block.Transactions = make([]*wire.MsgTx, len(txIPLDs))
+	for i, txIPLD := range txIPLDs {
+		msgTx := new(wire.MsgTx)
+		if err = msgTx.Deserialize(bytes.NewReader(txIPLD.Data)); err != nil {
+			return nil, fmt.Errorf("unable to decode transaction %s: %v", txCIDs[i].TxHash, err)
+		}
+		block.Transactions[i] = msgTx
+	}
+	return block, nil
+}
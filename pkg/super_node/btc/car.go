@@ -0,0 +1,264 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package btc
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/vulcanizedb/pkg/ipfs"
+	"github.com/vulcanize/vulcanizedb/pkg/postgres"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+// CARExporter satisfies the CARExporter interface for bitcoin
+type CARExporter struct {
+	db          *postgres.DB
+	blockGetter ipfs.BlockGetter
+}
+
+// NewCARExporter creates a pointer to a new CARExporter which retrieves the IPLDs it exports with the provided BlockGetter
+func NewCARExporter(db *postgres.DB, blockGetter ipfs.BlockGetter) *CARExporter {
+	return &CARExporter{
+		db:          db,
+		blockGetter: blockGetter,
+	}
+}
+
+// Export writes all of the IPLDs referenced by the btc cid tables for the blocks within the range out to a CAR file, with the header CIDs as its roots
+// The IPLDs of each block are followed by an index record holding the rows that reference them, the number of blocks exported is returned
+func (e *CARExporter) Export(w io.Writer, start, stop uint64) (int, error) {
+	roots, err := e.retrieveRoots(start, stop)
+	if err != nil {
+		return 0, err
+	}
+	if len(roots) == 0 {
+		return 0, fmt.Errorf("btc CAR exporter: no headers indexed between blocks %d and %d", start, stop)
+	}
+	car, err := ipfs.NewCARWriter(w, roots)
+	if err != nil {
+		return 0, err
+	}
+	written := make(map[cid.Cid]bool)
+	var exported int
+	for height := start; height <= stop; height++ {
+		payloads, err := e.retrieveCIDPayloads(height)
+		if err != nil {
+			return exported, err
+		}
+		for _, payload := range payloads {
+			if err := shared.WriteCARIPLDs(car, e.blockGetter, payloadCIDs(payload), written); err != nil {
+				return exported, err
+			}
+			record, err := shared.NewIndexRecord(payload)
+			if err != nil {
+				return exported, err
+			}
+			if err := car.Put(record); err != nil {
+				return exported, err
+			}
+			exported++
+		}
+		log.Debugf("btc CAR exporter: exported block %d", height)
+	}
+	return exported, nil
+}
+
+func (e *CARExporter) retrieveRoots(start, stop uint64) ([]cid.Cid, error) {
+	cidStrs := make([]string, 0)
+	pgStr := `SELECT cid FROM btc.header_cids
+			WHERE block_number BETWEEN $1 AND $2
+			ORDER BY block_number, canonical, id`
+	if err := e.db.Select(&cidStrs, pgStr, start, stop); err != nil {
+		return nil, err
+	}
+	roots := make([]cid.Cid, len(cidStrs))
+	for i, cidStr := range cidStrs {
+		c, err := cid.Decode(cidStr)
+		if err != nil {
+			return nil, err
+		}
+		roots[i] = c
+	}
+	return roots, nil
+}
+
+// retrieveCIDPayloads retrieves the index rows of every header indexed at the provided height
+// Non-canonical headers come first, so that the canonical header is the last one indexed at the height on import
+func (e *CARExporter) retrieveCIDPayloads(height uint64) (payloads []*CIDPayload, err error) {
+	tx, err := e.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+	headers := make([]HeaderModel, 0)
+	pgStr := `SELECT * FROM btc.header_cids
+			WHERE block_number = $1
+			ORDER BY canonical, id`
+	if err = tx.Select(&headers, pgStr, height); err != nil {
+		return nil, err
+	}
+	payloads = make([]*CIDPayload, 0, len(headers))
+	for _, header := range headers {
+		var payload *CIDPayload
+		payload, err = e.retrieveCIDPayload(tx, header)
+		if err != nil {
+			return nil, err
+		}
+		payloads = append(payloads, payload)
+	}
+	return payloads, nil
+}
+
+// txInputModel is used to scan btc.tx_inputs rows, whose witness array needs a pq.StringArray
+type txInputModel struct {
+	ID                    int64          `db:"id"`
+	TxID                  int64          `db:"tx_id"`
+	Index                 int64          `db:"index"`
+	TxWitness             pq.StringArray `db:"witness"`
+	SignatureScript       []byte         `db:"sig_script"`
+	PreviousOutPointIndex uint32         `db:"outpoint_index"`
+	PreviousOutPointHash  string         `db:"outpoint_tx_hash"`
+}
+
+func (e *CARExporter) retrieveCIDPayload(tx *sqlx.Tx, header HeaderModel) (*CIDPayload, error) {
+	payload := &CIDPayload{
+		HeaderCID:       header,
+		TransactionCIDs: make([]TxModelWithInsAndOuts, 0),
+	}
+	pgStr := `SELECT id, header_id, index, tx_hash, cid, segwit, witness_hash FROM btc.transaction_cids
+			WHERE header_id = $1
+			ORDER BY index`
+	if err := tx.Select(&payload.TransactionCIDs, pgStr, header.ID); err != nil {
+		return nil, err
+	}
+	for i, trx := range payload.TransactionCIDs {
+		inputs := make([]txInputModel, 0)
+		pgStr := `SELECT id, tx_id, index, witness, sig_script, outpoint_tx_hash, outpoint_index FROM btc.tx_inputs
+				WHERE tx_id = $1
+				ORDER BY index`
+		if err := tx.Select(&inputs, pgStr, trx.ID); err != nil {
+			return nil, err
+		}
+		payload.TransactionCIDs[i].TxInputs = make([]TxInput, len(inputs))
+		for j, input := range inputs {
+			payload.TransactionCIDs[i].TxInputs[j] = TxInput{
+				ID:                    input.ID,
+				TxID:                  input.TxID,
+				Index:                 input.Index,
+				TxWitness:             input.TxWitness,
+				SignatureScript:       input.SignatureScript,
+				PreviousOutPointIndex: input.PreviousOutPointIndex,
+				PreviousOutPointHash:  input.PreviousOutPointHash,
+			}
+		}
+		payload.TransactionCIDs[i].TxOutputs = make([]TxOutput, 0)
+		pgStr = `SELECT id, tx_id, index, value, pk_script, script_class, addresses, required_sigs FROM btc.tx_outputs
+				WHERE tx_id = $1
+				ORDER BY index`
+		if err := tx.Select(&payload.TransactionCIDs[i].TxOutputs, pgStr, trx.ID); err != nil {
+			return nil, err
+		}
+	}
+	return payload, nil
+}
+
+// CARImporter satisfies the CARImporter interface for bitcoin
+type CARImporter struct {
+	indexer    *CIDIndexer
+	blockAdder ipfs.BlockAdder
+}
+
+// NewCARImporter creates a pointer to a new CARImporter which adds the IPLDs it imports with the provided BlockAdder
+func NewCARImporter(db *postgres.DB, blockAdder ipfs.BlockAdder) *CARImporter {
+	return &CARImporter{
+		indexer:    NewCIDIndexer(db),
+		blockAdder: blockAdder,
+	}
+}
+
+// Import loads the IPLDs of each block in a CAR file written by the CARExporter, and indexes their CIDs once its index record is reached
+// The number of blocks imported is returned
+func (im *CARImporter) Import(r io.Reader) (int, error) {
+	car, err := ipfs.NewCARReader(r)
+	if err != nil {
+		return 0, err
+	}
+	pending := make([]blocks.Block, 0)
+	imported := make(map[cid.Cid]bool)
+	var count int
+	for {
+		block, err := car.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return count, err
+		}
+		if !shared.IsIndexRecord(block.Cid()) {
+			pending = append(pending, block)
+			continue
+		}
+		payload := new(CIDPayload)
+		if err := shared.DecodeIndexRecord(block, payload); err != nil {
+			return count, fmt.Errorf("btc CAR importer: invalid index record %s: %v", block.Cid().String(), err)
+		}
+		if err := im.blockAdder.AddBlocks(pending); err != nil {
+			return count, err
+		}
+		for _, ipld := range pending {
+			imported[ipld.Cid()] = true
+		}
+		pending = pending[:0]
+		if err := shared.CheckCARIPLDs(payloadCIDs(payload), imported); err != nil {
+			return count, fmt.Errorf("btc CAR importer: block %s: %v", payload.HeaderCID.BlockHash, err)
+		}
+		if err := im.indexer.Index(payload); err != nil {
+			return count, err
+		}
+		count++
+		log.Debugf("btc CAR importer: imported block %s", payload.HeaderCID.BlockNumber)
+	}
+	if len(pending) > 0 {
+		return count, fmt.Errorf("btc CAR importer: CAR file ends with %d IPLDs that are not referenced by an index record", len(pending))
+	}
+	return count, nil
+}
+
+// payloadCIDs returns the CIDs of all of the IPLDs referenced by the CID payload
+func payloadCIDs(payload *CIDPayload) []string {
+	cids := []string{payload.HeaderCID.CID}
+	for _, trx := range payload.TransactionCIDs {
+		cids = append(cids, trx.CID)
+	}
+	return cids
+}
@@ -0,0 +1,138 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package btc_test
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/ipfs/go-cid"
+	"github.com/lib/pq"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/vulcanizedb/pkg/ipfs"
+	"github.com/vulcanize/vulcanizedb/pkg/postgres"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/btc"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/btc/mocks"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+type indexedTx struct {
+	CID       string         `db:"cid"`
+	Inputs    int            `db:"inputs"`
+	Outputs   int            `db:"outputs"`
+	Witnesses pq.StringArray `db:"witnesses"`
+}
+
+// indexedTxs returns the cid of each indexed transaction along with its inputs and outputs
+func indexedTxs(db *postgres.DB) []indexedTx {
+	txs := make([]indexedTx, 0)
+	err := db.Select(&txs, `SELECT cid,
+					(SELECT COUNT(*) FROM btc.tx_inputs WHERE tx_id = transaction_cids.id) AS inputs,
+					(SELECT COUNT(*) FROM btc.tx_outputs WHERE tx_id = transaction_cids.id) AS outputs,
+					(SELECT array_agg(w) FROM btc.tx_inputs, unnest(witness) AS w WHERE tx_id = transaction_cids.id) AS witnesses
+					FROM btc.transaction_cids ORDER BY cid`)
+	Expect(err).ToNot(HaveOccurred())
+	return txs
+}
+
+var _ = Describe("CAR export and import", func() {
+	var (
+		db       *postgres.DB
+		err      error
+		blocks   *shared.PGBlockService
+		exporter *btc.CARExporter
+		importer *btc.CARImporter
+	)
+	BeforeEach(func() {
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+		blocks = shared.NewPGBlockService(db)
+		exporter = btc.NewCARExporter(db, blocks)
+		importer = btc.NewCARImporter(db, blocks)
+		_, err = btc.NewIPLDPublisherAndIndexer(db).Publish(mocks.MockConvertedPayload)
+		Expect(err).ToNot(HaveOccurred())
+	})
+	AfterEach(func() {
+		btc.TearDownDB(db)
+	})
+
+	It("Rebuilds the index and blocks from an exported CAR file", func() {
+		var header btc.HeaderModel
+		err = db.Get(&header, `SELECT * FROM btc.header_cids WHERE block_number = $1`, mocks.MockBlockHeight)
+		Expect(err).ToNot(HaveOccurred())
+		txs := indexedTxs(db)
+		Expect(len(txs)).To(Equal(len(mocks.MockTransactions)))
+		buf := new(bytes.Buffer)
+		exported, err := exporter.Export(buf, uint64(mocks.MockBlockHeight), uint64(mocks.MockBlockHeight))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(exported).To(Equal(1))
+		reader, err := ipfs.NewCARReader(bytes.NewReader(buf.Bytes()))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(reader.Roots)).To(Equal(1))
+		Expect(reader.Roots[0].String()).To(Equal(header.CID))
+		btc.TearDownDB(db)
+
+		imported, err := importer.Import(buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(imported).To(Equal(1))
+		var importedHeader btc.HeaderModel
+		err = db.Get(&importedHeader, `SELECT * FROM btc.header_cids WHERE block_number = $1`, mocks.MockBlockHeight)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(importedHeader.CID).To(Equal(header.CID))
+		Expect(importedHeader.BlockHash).To(Equal(header.BlockHash))
+		Expect(importedHeader.Bits).To(Equal(header.Bits))
+		Expect(importedHeader.Timestamp).To(Equal(header.Timestamp))
+		Expect(indexedTxs(db)).To(Equal(txs))
+		for _, cidStr := range append([]string{header.CID}, mocks.MockCIDPayload.TransactionCIDs[0].CID) {
+			c, err := cid.Decode(cidStr)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = blocks.GetBlock(context.Background(), c)
+			Expect(err).ToNot(HaveOccurred())
+		}
+	})
+
+	It("Does not index blocks whose IPLDs are missing from the CAR file", func() {
+		buf := new(bytes.Buffer)
+		_, err = exporter.Export(buf, uint64(mocks.MockBlockHeight), uint64(mocks.MockBlockHeight))
+		Expect(err).ToNot(HaveOccurred())
+		reader, err := ipfs.NewCARReader(bytes.NewReader(buf.Bytes()))
+		Expect(err).ToNot(HaveOccurred())
+		// rewrite the file without its first IPLD
+		stripped := new(bytes.Buffer)
+		writer, err := ipfs.NewCARWriter(stripped, reader.Roots)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = reader.Next()
+		Expect(err).ToNot(HaveOccurred())
+		for {
+			block, err := reader.Next()
+			if err != nil {
+				break
+			}
+			Expect(writer.Put(block)).To(Succeed())
+		}
+		btc.TearDownDB(db)
+
+		_, err = importer.Import(stripped)
+		Expect(err).To(HaveOccurred())
+		var count int
+		err = db.Get(&count, `SELECT COUNT(*) FROM btc.header_cids`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(count).To(Equal(0))
+	})
+})
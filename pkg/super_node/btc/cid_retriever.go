@@ -21,7 +21,7 @@ import (
 	"fmt"
 	"math/big"
 
-	"github.com/ethereum/go-ethereum/common"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
 	log "github.com/sirupsen/logrus"
@@ -92,6 +92,7 @@ func (bcr *CIDRetriever) Retrieve(filter shared.SubscriptionSettings, blockNumbe
 	for i, header := range headers {
 		cw := new(CIDWrapper)
 		cw.BlockNumber = big.NewInt(blockNumber)
+		cw.BlockHash = header.BlockHash
 		if !streamFilter.HeaderFilter.Off {
 			cw.Header = header
 			empty = false
@@ -113,12 +114,13 @@ func (bcr *CIDRetriever) Retrieve(filter shared.SubscriptionSettings, blockNumbe
 	return cws, empty, err
 }
 
-// RetrieveHeaderCIDs retrieves and returns all of the header cids at the provided blockheight
+// RetrieveHeaderCIDs retrieves and returns the canonical header cids at the provided blockheight
 func (bcr *CIDRetriever) RetrieveHeaderCIDs(tx *sqlx.Tx, blockNumber int64) ([]HeaderModel, error) {
 	log.Debug("retrieving header cids for block ", blockNumber)
 	headers := make([]HeaderModel, 0)
 	pgStr := `SELECT * FROM btc.header_cids
-				WHERE block_number = $1`
+				WHERE block_number = $1
+				AND canonical = true`
 	return headers, tx.Select(&headers, pgStr, blockNumber)
 }
 
@@ -168,6 +170,8 @@ func (bcr *CIDRetriever) RetrieveTxCIDs(tx *sqlx.Tx, txFilter TxFilter, headerID
 }
 
 // RetrieveGapsInData is used to find the the block numbers at which we are missing data in the db
+// it finds the union of heights where no data exists and where the times_validated is lower than the validation level
+// a validation level of 0 finds only the heights where no data exists
 func (bcr *CIDRetriever) RetrieveGapsInData(validationLevel int) ([]shared.Gap, error) {
 	log.Info("searching for gaps in the btc super node database")
 	startingBlock, err := bcr.RetrieveFirstBlockNumber()
@@ -206,18 +210,55 @@ func (bcr *CIDRetriever) RetrieveGapsInData(validationLevel int) ([]shared.Gap,
 
 	// Find sections of blocks where we are below the validation level
 	// There will be no overlap between these "gaps" and the ones above
-	pgStr = `SELECT block_number FROM btc.header_cids
-			WHERE times_validated < $1
+	validationGaps, err := bcr.RetrieveValidationGaps(validationLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	// Find the known gaps recorded for data that was spilled or dropped at the head of the chain
+	// Known gaps below the latest indexed block are already covered by the empty gaps above
+	pgStr = `SELECT block_number FROM btc.known_gaps
+			WHERE block_number > (SELECT max(block_number) FROM btc.header_cids)
+			ORDER BY block_number`
+	var knownHeights []uint64
+	if err := bcr.db.Select(&knownHeights, pgStr); err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	gaps := append(append(initialGap, emptyGaps...), validationGaps...)
+	return append(gaps, utils.MissingHeightsToGaps(knownHeights)...), nil
+}
+
+// RetrieveValidationGaps finds the sections of blocks which are indexed but have been validated fewer than validationLevel times
+// Only the canonical header at a height counts, so heights with orphaned headers are not revalidated once their canonical header has been,
+// while heights left without a canonical header by a reorg are
+func (bcr *CIDRetriever) RetrieveValidationGaps(validationLevel int) ([]shared.Gap, error) {
+	pgStr := `SELECT DISTINCT block_number FROM btc.header_cids
+			WHERE NOT EXISTS (
+				SELECT 1 FROM btc.header_cids AS canonical_headers
+				WHERE canonical_headers.block_number = header_cids.block_number
+				AND canonical_headers.canonical = true
+				AND canonical_headers.times_validated >= $1
+			)
 			ORDER BY block_number`
 	var heights []uint64
 	if err := bcr.db.Select(&heights, pgStr, validationLevel); err != nil && err != sql.ErrNoRows {
 		return nil, err
 	}
-	return append(append(initialGap, emptyGaps...), utils.MissingHeightsToGaps(heights)...), nil
+	return utils.MissingHeightsToGaps(heights), nil
+}
+
+// RetrieveValidationLevels returns the number of canonical blocks that have been validated each number of times
+func (bcr *CIDRetriever) RetrieveValidationLevels() ([]shared.ValidationLevel, error) {
+	pgStr := `SELECT times_validated, COUNT(*) AS blocks FROM btc.header_cids
+			WHERE canonical = true
+			GROUP BY times_validated
+			ORDER BY times_validated`
+	levels := make([]shared.ValidationLevel, 0)
+	return levels, bcr.db.Select(&levels, pgStr)
 }
 
 // RetrieveBlockByHash returns all of the CIDs needed to compose an entire block, for a given block hash
-func (bcr *CIDRetriever) RetrieveBlockByHash(blockHash common.Hash) (HeaderModel, []TxModel, error) {
+func (bcr *CIDRetriever) RetrieveBlockByHash(blockHash chainhash.Hash) (HeaderModel, []TxModel, error) {
 	log.Debug("retrieving block cids for block hash ", blockHash.String())
 
 	// Begin new db tx
@@ -284,7 +325,7 @@ func (bcr *CIDRetriever) RetrieveBlockByNumber(blockNumber int64) (HeaderModel,
 }
 
 // RetrieveHeaderCIDByHash returns the header for the given block hash
-func (bcr *CIDRetriever) RetrieveHeaderCIDByHash(tx *sqlx.Tx, blockHash common.Hash) (HeaderModel, error) {
+func (bcr *CIDRetriever) RetrieveHeaderCIDByHash(tx *sqlx.Tx, blockHash chainhash.Hash) (HeaderModel, error) {
 	log.Debug("retrieving header cids for block hash ", blockHash.String())
 	pgStr := `SELECT * FROM btc.header_cids
 			WHERE block_hash = $1`
@@ -296,7 +337,82 @@ func (bcr *CIDRetriever) RetrieveHeaderCIDByHash(tx *sqlx.Tx, blockHash common.H
 func (bcr *CIDRetriever) RetrieveTxCIDsByHeaderID(tx *sqlx.Tx, headerID int64) ([]TxModel, error) {
 	log.Debug("retrieving tx cids for block id ", headerID)
 	pgStr := `SELECT * FROM btc.transaction_cids
-			WHERE header_id = $1`
+			WHERE header_id = $1
+			ORDER BY index`
 	var txCIDs []TxModel
 	return txCIDs, tx.Select(&txCIDs, pgStr, headerID)
 }
+
+// RetrieveTxCIDByHash returns the tx CID for the given tx hash along with the header CID of the block it was included in
+func (bcr *CIDRetriever) RetrieveTxCIDByHash(txHash chainhash.Hash) (HeaderModel, TxModel, error) {
+	log.Debug("retrieving tx cid for tx hash ", txHash.String())
+	pgStr := `SELECT transaction_cids.id, transaction_cids.header_id, transaction_cids.index, transaction_cids.tx_hash,
+			transaction_cids.cid, transaction_cids.segwit, transaction_cids.witness_hash
+			FROM btc.transaction_cids
+			WHERE tx_hash = $1`
+	var txCID TxModel
+	if err := bcr.db.Get(&txCID, pgStr, txHash.String()); err != nil {
+		return HeaderModel{}, TxModel{}, err
+	}
+	pgStr = `SELECT * FROM btc.header_cids
+			WHERE id = $1`
+	var headerCID HeaderModel
+	return headerCID, txCID, bcr.db.Get(&headerCID, pgStr, txCID.HeaderID)
+}
+
+// RetrieveUTXOs returns the outputs paying to the address that were created and not yet spent on the canonical chain as of the provided height
+func (bcr *CIDRetriever) RetrieveUTXOs(address string, blockNumber int64) ([]UTXOModel, error) {
+	log.Debug("retrieving utxos for address ", address)
+	pgStr := `SELECT transaction_cids.tx_hash, tx_outputs.index, tx_outputs.value, tx_outputs.pk_script, header_cids.block_number
+			FROM btc.tx_outputs
+			INNER JOIN btc.transaction_cids ON (tx_outputs.tx_id = transaction_cids.id)
+			INNER JOIN btc.header_cids ON (transaction_cids.header_id = header_cids.id)
+			WHERE tx_outputs.addresses @> ARRAY[$1]::VARCHAR(66)[]
+			AND header_cids.canonical = true
+			AND header_cids.block_number <= $2
+			AND NOT EXISTS (
+				SELECT 1 FROM btc.tx_inputs
+				INNER JOIN btc.transaction_cids AS spenders ON (tx_inputs.tx_id = spenders.id)
+				INNER JOIN btc.header_cids AS spender_headers ON (spenders.header_id = spender_headers.id)
+				WHERE tx_inputs.spent_output_id = tx_outputs.id
+				AND spender_headers.canonical = true
+				AND spender_headers.block_number <= $2
+			)
+			ORDER BY header_cids.block_number, transaction_cids.index, tx_outputs.index`
+	utxos := make([]UTXOModel, 0)
+	return utxos, bcr.db.Select(&utxos, pgStr, address, blockNumber)
+}
+
+// RetrieveAddressHistory returns the amounts received and spent by the address in each canonical transaction up to the provided height
+func (bcr *CIDRetriever) RetrieveAddressHistory(address string, blockNumber int64) ([]AddressHistoryModel, error) {
+	log.Debug("retrieving history for address ", address)
+	pgStr := `SELECT transaction_cids.tx_hash, transaction_cids.index AS tx_index, header_cids.block_number, header_cids.block_hash,
+			address_history.received, address_history.spent
+			FROM btc.address_history
+			INNER JOIN btc.transaction_cids ON (address_history.tx_id = transaction_cids.id)
+			INNER JOIN btc.header_cids ON (transaction_cids.header_id = header_cids.id)
+			WHERE address_history.address = $1
+			AND header_cids.canonical = true
+			AND header_cids.block_number <= $2
+			ORDER BY header_cids.block_number, transaction_cids.index`
+	history := make([]AddressHistoryModel, 0)
+	return history, bcr.db.Select(&history, pgStr, address, blockNumber)
+}
+
+// RetrieveAddressTotals returns the total amounts received and spent by the address on the canonical chain up to the provided height
+func (bcr *CIDRetriever) RetrieveAddressTotals(address string, blockNumber int64) (int64, int64, error) {
+	log.Debug("retrieving totals for address ", address)
+	pgStr := `SELECT COALESCE(SUM(address_history.received), 0) AS received, COALESCE(SUM(address_history.spent), 0) AS spent
+			FROM btc.address_history
+			INNER JOIN btc.transaction_cids ON (address_history.tx_id = transaction_cids.id)
+			INNER JOIN btc.header_cids ON (transaction_cids.header_id = header_cids.id)
+			WHERE address_history.address = $1
+			AND header_cids.canonical = true
+			AND header_cids.block_number <= $2`
+	var totals struct {
+		Received int64 `db:"received"`
+		Spent    int64 `db:"spent"`
+	}
+	err := bcr.db.Get(&totals, pgStr, address, blockNumber)
+	return totals.Received, totals.Spent, err
+}
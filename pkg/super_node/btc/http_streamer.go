@@ -17,85 +17,250 @@
 package btc
 
 import (
-	"bytes"
+	"fmt"
+	"sync"
 	"time"
 
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/btcsuite/btcd/wire"
 	"github.com/sirupsen/logrus"
 
 	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
 )
 
+const (
+	// DefaultPollInterval is how often the HTTPPayloadStreamer polls bitcoind for new blocks
+	DefaultPollInterval = 5 * time.Second
+	// ForkTrackingDepth is the number of recently streamed blocks the HTTPPayloadStreamer remembers the hashes of for finding fork points
+	ForkTrackingDepth = 100
+)
+
+// BlockClient is the subset of the bitcoind rpc client used by the HTTPPayloadStreamer
+type BlockClient interface {
+	GetBlockCount() (int64, error)
+	GetBlockHash(blockHeight int64) (*chainhash.Hash, error)
+	GetBlock(blockHash *chainhash.Hash) (*wire.MsgBlock, error)
+	GetBlockHeader(blockHash *chainhash.Hash) (*wire.BlockHeader, error)
+	Shutdown()
+}
+
 // HTTPPayloadStreamer satisfies the PayloadStreamer interface for bitcoin over http endpoints (since bitcoin core doesn't support websockets)
+// It starts at the node's tip and then streams every block in order, polling for new blocks; when the node's chain no longer
+// includes blocks it has streamed, it streams a BlockRollback for them before streaming the blocks of the new chain
 type HTTPPayloadStreamer struct {
-	Config   *rpcclient.ConnConfig
-	lastHash []byte
+	Config       *rpcclient.ConnConfig
+	Client       BlockClient
+	PollInterval time.Duration
+	firstHeight  int64
+	lastHeight   int64
+	lastHash     *chainhash.Hash
+	// hashes of the recently streamed blocks, and of the parent of the first streamed block, by height
+	hashes map[int64]chainhash.Hash
 }
 
 // NewHTTPPayloadStreamer creates a pointer to a new PayloadStreamer which satisfies the PayloadStreamer interface for bitcoin
 func NewHTTPPayloadStreamer(clientConfig *rpcclient.ConnConfig) *HTTPPayloadStreamer {
 	return &HTTPPayloadStreamer{
-		Config: clientConfig,
+		Config:       clientConfig,
+		PollInterval: DefaultPollInterval,
 	}
 }
 
-// Stream is the main loop for subscribing to data from the btc block notifications
+// Stream is the main loop for polling the btc node for blocks
 // Satisfies the shared.PayloadStreamer interface
 func (ps *HTTPPayloadStreamer) Stream(payloadChan chan shared.RawChainData) (shared.ClientSubscription, error) {
 	logrus.Debug("streaming block payloads from btc")
-	client, err := rpcclient.New(ps.Config, nil)
-	if err != nil {
-		return nil, err
+	if ps.Client == nil {
+		client, err := rpcclient.New(ps.Config, nil)
+		if err != nil {
+			return nil, err
+		}
+		ps.Client = client
+	}
+	if ps.PollInterval <= 0 {
+		ps.PollInterval = DefaultPollInterval
+	}
+	ps.hashes = make(map[int64]chainhash.Hash)
+	sub := &HTTPClientSubscription{
+		client:  ps.Client,
+		errChan: make(chan error),
+		quit:    make(chan bool),
 	}
-	ticker := time.NewTicker(time.Second * 5)
-	errChan := make(chan error)
 	go func() {
+		ticker := time.NewTicker(ps.PollInterval)
+		defer ticker.Stop()
 		for {
-			// start at
+			if err := ps.poll(payloadChan, sub.quit); err != nil {
+				select {
+				case sub.errChan <- err:
+				case <-sub.quit:
+					return
+				}
+			}
 			select {
 			case <-ticker.C:
-				height, err := client.GetBlockCount()
-				if err != nil {
-					errChan <- err
-					continue
-				}
-				blockHash, err := client.GetBlockHash(height)
-				if err != nil {
-					errChan <- err
-					continue
-				}
-				blockHashBytes := blockHash.CloneBytes()
-				if bytes.Equal(blockHashBytes, ps.lastHash) {
-					continue
-				}
-				block, err := client.GetBlock(blockHash)
-				if err != nil {
-					errChan <- err
-					continue
-				}
-				ps.lastHash = blockHashBytes
-				payloadChan <- BlockPayload{
-					Header:      &block.Header,
-					BlockHeight: height,
-					Txs:         msgTxsToUtilTxs(block.Transactions),
-				}
-			default:
+			case <-sub.quit:
+				return
 			}
 		}
 	}()
-	return &HTTPClientSubscription{client: client, errChan: errChan}, nil
+	return sub, nil
+}
+
+// poll streams every block after the last streamed block up to the tip of the node's chain
+func (ps *HTTPPayloadStreamer) poll(payloadChan chan shared.RawChainData, quit <-chan bool) error {
+	tip, err := ps.Client.GetBlockCount()
+	if err != nil {
+		return err
+	}
+	if ps.lastHash == nil {
+		// Start at the tip, earlier blocks are left to the backfill process
+		hash, err := ps.Client.GetBlockHash(tip)
+		if err != nil {
+			return err
+		}
+		return ps.streamBlock(payloadChan, quit, tip, hash)
+	}
+	// Check the node's chain still includes the last block we streamed
+	checkHeight := ps.lastHeight
+	if tip < checkHeight {
+		checkHeight = tip
+	}
+	hash, err := ps.Client.GetBlockHash(checkHeight)
+	if err != nil {
+		return err
+	}
+	if checkHeight < ps.lastHeight || !hash.IsEqual(ps.lastHash) {
+		if err := ps.rollback(payloadChan, quit, *hash, checkHeight); err != nil {
+			return err
+		}
+	}
+	for height := ps.lastHeight + 1; height <= tip; height++ {
+		hash, err := ps.Client.GetBlockHash(height)
+		if err != nil {
+			return err
+		}
+		if err := ps.streamBlock(payloadChan, quit, height, hash); err != nil {
+			return err
+		}
+		if ps.lastHeight != height {
+			// The node reorged while we were catching up, the blocks of its new chain are streamed on the next poll
+			return nil
+		}
+	}
+	return nil
+}
+
+// streamBlock streams the block at the height, unless it does not build on the last streamed block,
+// in which case a rollback is streamed instead
+func (ps *HTTPPayloadStreamer) streamBlock(payloadChan chan shared.RawChainData, quit <-chan bool, height int64, hash *chainhash.Hash) error {
+	block, err := ps.Client.GetBlock(hash)
+	if err != nil {
+		return err
+	}
+	if ps.lastHash != nil && !block.Header.PrevBlock.IsEqual(ps.lastHash) {
+		return ps.rollback(payloadChan, quit, block.Header.PrevBlock, height-1)
+	}
+	if ps.lastHash == nil {
+		// The parent of the first block is where a fork off it would start
+		ps.firstHeight = height
+		ps.hashes[height-1] = block.Header.PrevBlock
+	}
+	payload := BlockPayload{
+		Header:      &block.Header,
+		BlockHeight: height,
+		Txs:         msgTxsToUtilTxs(block.Transactions),
+	}
+	select {
+	case payloadChan <- payload:
+	case <-quit:
+		return nil
+	}
+	ps.lastHeight = height
+	ps.lastHash = hash
+	ps.hashes[height] = *hash
+	delete(ps.hashes, height-ForkTrackingDepth)
+	return nil
+}
+
+// rollback finds the fork point between the streamed blocks and the node's chain, which includes the block with the
+// provided hash and height, and streams a BlockRollback for the streamed blocks above it
+// if the node cannot be queried while looking for the fork point, nothing is rolled back and the error is returned
+// so that the fork point is looked for again on the next poll
+func (ps *HTTPPayloadStreamer) rollback(payloadChan chan shared.RawChainData, quit <-chan bool, hash chainhash.Hash, height int64) error {
+	forkHeight, found, err := ps.forkPoint(hash, height)
+	if err != nil {
+		return err
+	}
+	if !found {
+		// None of the blocks we remember are on the node's chain, so roll back all of them that were streamed and restart at the tip
+		forkHeight = ps.lastHeight - int64(len(ps.hashes))
+		if forkHeight < ps.firstHeight-1 {
+			forkHeight = ps.firstHeight - 1
+		}
+	}
+	rollback := BlockRollback{
+		ForkHeight:   forkHeight,
+		Disconnected: make([]DisconnectedBlock, 0, ps.lastHeight-forkHeight),
+	}
+	for h := forkHeight + 1; h <= ps.lastHeight; h++ {
+		rollback.Disconnected = append(rollback.Disconnected, DisconnectedBlock{Height: h, Hash: ps.hashes[h]})
+		delete(ps.hashes, h)
+	}
+	logrus.Warnf("btc reorg detected, rolling back %d blocks above height %d", len(rollback.Disconnected), forkHeight)
+	select {
+	case payloadChan <- rollback:
+	case <-quit:
+		return nil
+	}
+	if !found {
+		ps.lastHash = nil
+		ps.hashes = make(map[int64]chainhash.Hash)
+		return fmt.Errorf("btc streamer could not find a fork point within the last %d streamed blocks", ForkTrackingDepth)
+	}
+	forkHash := ps.hashes[forkHeight]
+	ps.lastHeight = forkHeight
+	ps.lastHash = &forkHash
+	return nil
+}
+
+// forkPoint walks back from the block with the provided hash and height through the parent hashes of the node's chain
+// until it reaches a block we have streamed, and returns its height
+// it returns false if the walk runs past the streamed blocks we remember without finding one
+func (ps *HTTPPayloadStreamer) forkPoint(hash chainhash.Hash, height int64) (int64, bool, error) {
+	for {
+		streamed, ok := ps.hashes[height]
+		if !ok {
+			return 0, false, nil
+		}
+		if streamed.IsEqual(&hash) {
+			return height, true, nil
+		}
+		header, err := ps.Client.GetBlockHeader(&hash)
+		if err != nil {
+			return 0, false, err
+		}
+		hash = header.PrevBlock
+		height--
+	}
 }
 
 // HTTPClientSubscription is a wrapper around the underlying bitcoind rpc client
 // to fit the shared.ClientSubscription interface
 type HTTPClientSubscription struct {
-	client  *rpcclient.Client
-	errChan chan error
+	client   BlockClient
+	errChan  chan error
+	quit     chan bool
+	quitOnce sync.Once
 }
 
 // Unsubscribe satisfies the rpc.Subscription interface
 func (bcs *HTTPClientSubscription) Unsubscribe() {
-	bcs.client.Shutdown()
+	bcs.quitOnce.Do(func() {
+		close(bcs.quit)
+		bcs.client.Shutdown()
+	})
 }
 
 // Err() satisfies the rpc.Subscription interface
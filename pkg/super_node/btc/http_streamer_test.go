@@ -0,0 +1,156 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package btc_test
+
+import (
+	"errors"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/btc"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/btc/mocks"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+// extendChain returns the chain with n blocks appended, the fork nonce distinguishes the blocks of competing chains
+func extendChain(chain []*wire.MsgBlock, n int, fork uint32) []*wire.MsgBlock {
+	extended := append([]*wire.MsgBlock{}, chain...)
+	for i := 0; i < n; i++ {
+		parent := chainhash.Hash{}
+		if len(extended) > 0 {
+			parent = extended[len(extended)-1].BlockHash()
+		}
+		extended = append(extended, testBlock(parent, fork<<16|uint32(len(extended))))
+	}
+	return extended
+}
+
+var _ = Describe("HTTPPayloadStreamer", func() {
+	var (
+		chain       []*wire.MsgBlock
+		client      *mocks.BlockClient
+		payloadChan chan shared.RawChainData
+		sub         shared.ClientSubscription
+	)
+	BeforeEach(func() {
+		chain = extendChain(nil, 3, 0)
+		client = mocks.NewBlockClient(chain)
+		payloadChan = make(chan shared.RawChainData, 10)
+		streamer := &btc.HTTPPayloadStreamer{Client: client, PollInterval: 10 * time.Millisecond}
+		var err error
+		sub, err = streamer.Stream(payloadChan)
+		Expect(err).ToNot(HaveOccurred())
+	})
+	AfterEach(func() {
+		sub.Unsubscribe()
+	})
+
+	expectBlock := func(block *wire.MsgBlock, height int64) {
+		var payload shared.RawChainData
+		Eventually(payloadChan).Should(Receive(&payload))
+		blockPayload, ok := payload.(btc.BlockPayload)
+		Expect(ok).To(BeTrue())
+		Expect(blockPayload.BlockHeight).To(Equal(height))
+		Expect(blockPayload.Header.BlockHash()).To(Equal(block.BlockHash()))
+	}
+	expectRollback := func(forkHeight int64, disconnected ...*wire.MsgBlock) {
+		var payload shared.RawChainData
+		Eventually(payloadChan).Should(Receive(&payload))
+		rollback, ok := payload.(btc.BlockRollback)
+		Expect(ok).To(BeTrue())
+		Expect(rollback.ForkHeight).To(Equal(forkHeight))
+		Expect(len(rollback.Disconnected)).To(Equal(len(disconnected)))
+		for i, block := range disconnected {
+			Expect(rollback.Disconnected[i]).To(Equal(btc.DisconnectedBlock{Height: forkHeight + int64(i) + 1, Hash: block.BlockHash()}))
+		}
+	}
+
+	It("Starts at the tip and streams every block mined after it in order", func() {
+		expectBlock(chain[2], 2)
+		chain = extendChain(chain, 3, 0)
+		client.SetChain(chain)
+		expectBlock(chain[3], 3)
+		expectBlock(chain[4], 4)
+		expectBlock(chain[5], 5)
+		Consistently(payloadChan).ShouldNot(Receive())
+	})
+
+	It("Rolls back the blocks disconnected by a reorg before streaming the new chain", func() {
+		expectBlock(chain[2], 2)
+		chain = extendChain(chain, 2, 0)
+		client.SetChain(chain)
+		expectBlock(chain[3], 3)
+		expectBlock(chain[4], 4)
+
+		fork := extendChain(chain[:3], 3, 1)
+		client.SetChain(fork)
+		expectRollback(2, chain[3], chain[4])
+		expectBlock(fork[3], 3)
+		expectBlock(fork[4], 4)
+		expectBlock(fork[5], 5)
+		Consistently(payloadChan).ShouldNot(Receive())
+	})
+
+	It("Detects a competing block at the same height", func() {
+		expectBlock(chain[2], 2)
+		fork := extendChain(chain[:2], 1, 1)
+		client.SetChain(fork)
+		expectRollback(1, chain[2])
+		expectBlock(fork[2], 2)
+		Consistently(payloadChan).ShouldNot(Receive())
+	})
+
+	It("Rolls back to the tip when the node's chain becomes shorter", func() {
+		expectBlock(chain[2], 2)
+		client.SetChain(chain[:2])
+		expectRollback(1, chain[2])
+		Consistently(payloadChan).ShouldNot(Receive())
+	})
+
+	It("Retries on the next poll instead of rolling back everything when the node fails while looking for the fork point", func() {
+		expectBlock(chain[2], 2)
+		chain = extendChain(chain, 2, 0)
+		client.SetChain(chain)
+		expectBlock(chain[3], 3)
+		expectBlock(chain[4], 4)
+
+		client.SetGetBlockHeaderErr(errors.New("mock rpc error"))
+		fork := extendChain(chain[:3], 3, 1)
+		client.SetChain(fork)
+		errs := make(chan error, 100)
+		go func() {
+			for err := range sub.Err() {
+				errs <- err
+			}
+		}()
+		var err error
+		Eventually(errs).Should(Receive(&err))
+		Expect(err).To(MatchError("mock rpc error"))
+		Consistently(payloadChan).ShouldNot(Receive())
+
+		client.SetGetBlockHeaderErr(nil)
+		expectRollback(2, chain[3], chain[4])
+		expectBlock(fork[3], 3)
+		expectBlock(fork[4], 4)
+		expectBlock(fork[5], 5)
+		Consistently(payloadChan).ShouldNot(Receive())
+	})
+})
@@ -17,7 +17,9 @@
 package btc
 
 import (
+	"database/sql"
 	"fmt"
+	"strconv"
 
 	"github.com/sirupsen/logrus"
 
@@ -60,12 +62,18 @@ func (in *CIDIndexer) Index(cids shared.CIDsForIndexing) error {
 		}
 	}()
 
-	headerID, err := in.indexHeaderCID(tx, cidWrapper.HeaderCID)
+	err = in.IndexCIDs(tx, cidWrapper)
+	return err
+}
+
+// IndexCIDs indexes the header and transaction CIDs of the provided payload within the provided db tx
+func (in *CIDIndexer) IndexCIDs(tx *sqlx.Tx, cids *CIDPayload) error {
+	headerID, err := in.indexHeaderCID(tx, cids.HeaderCID)
 	if err != nil {
 		logrus.Error("btc indexer error when indexing header")
 		return err
 	}
-	err = in.indexTransactionCIDs(tx, cidWrapper.TransactionCIDs, headerID)
+	err = in.indexTransactionCIDs(tx, cids.TransactionCIDs, headerID)
 	if err != nil {
 		logrus.Error("btc indexer error when indexing transactions")
 	}
@@ -79,7 +87,95 @@ func (in *CIDIndexer) indexHeaderCID(tx *sqlx.Tx, header HeaderModel) (int64, er
 							ON CONFLICT (block_number, block_hash) DO UPDATE SET (parent_hash, cid, timestamp, bits, node_id, times_validated) = ($3, $4, $5, $6, $7, btc.header_cids.times_validated + 1)
 							RETURNING id`,
 		header.BlockNumber, header.BlockHash, header.ParentHash, header.CID, header.Timestamp, header.Bits, in.db.NodeID, 1).Scan(&headerID)
-	return headerID, err
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`DELETE FROM btc.known_gaps WHERE block_number = $1`, header.BlockNumber); err != nil {
+		return 0, err
+	}
+	return headerID, in.indexCanonical(tx, header)
+}
+
+// indexCanonical marks the provided header as canonical, the most recently indexed header at a height is taken to be the canonical one
+// The flag is then propagated backwards and forwards along the chain of headers linked to this one by parent hash,
+// competing headers at each of these heights are marked non-canonical, and the spends at these heights are relinked
+func (in *CIDIndexer) indexCanonical(tx *sqlx.Tx, header HeaderModel) error {
+	heights, err := in.markCanonical(tx, header)
+	if err != nil {
+		return err
+	}
+	return in.relinkSpends(tx, heights)
+}
+
+// markCanonical propagates the canonical flag from the provided header and returns the heights at which it was set
+func (in *CIDIndexer) markCanonical(tx *sqlx.Tx, header HeaderModel) ([]int64, error) {
+	blockNumber, err := strconv.ParseInt(header.BlockNumber, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`UPDATE btc.header_cids SET canonical = (block_hash = $2) WHERE block_number = $1`,
+		blockNumber, header.BlockHash); err != nil {
+		return nil, err
+	}
+	heights := []int64{blockNumber}
+	// Walk back through the ancestors until we reach one that is already canonical or one we do not have
+	parentHash := header.ParentHash
+	for num := blockNumber - 1; num >= 0; num-- {
+		var ancestor HeaderModel
+		err := tx.Get(&ancestor, `SELECT parent_hash, canonical FROM btc.header_cids
+									WHERE block_number = $1 AND block_hash = $2`, num, parentHash)
+		if err == sql.ErrNoRows {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if ancestor.Canonical {
+			break
+		}
+		if _, err := tx.Exec(`UPDATE btc.header_cids SET canonical = (block_hash = $2) WHERE block_number = $1`,
+			num, parentHash); err != nil {
+			return nil, err
+		}
+		heights = append(heights, num)
+		parentHash = ancestor.ParentHash
+	}
+	// Walk forward through the descendants until we reach a height we do not have
+	// Headers at these heights which do not link back to this header are not canonical
+	hash := header.BlockHash
+	for num := blockNumber + 1; ; num++ {
+		descendants := make([]HeaderModel, 0)
+		if err := tx.Select(&descendants, `SELECT block_hash, parent_hash, canonical FROM btc.header_cids
+									WHERE block_number = $1
+									ORDER BY canonical DESC, id DESC`, num); err != nil {
+			return nil, err
+		}
+		if len(descendants) == 0 {
+			return heights, nil
+		}
+		child := ""
+		for _, descendant := range descendants {
+			if hash != "" && descendant.ParentHash == hash {
+				child = descendant.BlockHash
+				break
+			}
+		}
+		res, err := tx.Exec(`UPDATE btc.header_cids SET canonical = (block_hash = $2)
+									WHERE block_number = $1 AND canonical <> (block_hash = $2)`, num, child)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := res.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		// If nothing changed at this height, the rest of the chain is already consistent
+		if updated == 0 {
+			return heights, nil
+		}
+		heights = append(heights, num)
+		hash = child
+	}
 }
 
 func (in *CIDIndexer) indexTransactionCIDs(tx *sqlx.Tx, transactions []TxModelWithInsAndOuts, headerID int64) error {
@@ -101,6 +197,10 @@ func (in *CIDIndexer) indexTransactionCIDs(tx *sqlx.Tx, transactions []TxModelWi
 				return err
 			}
 		}
+		if err := in.indexSpends(tx, transaction.TxHash, txID); err != nil {
+			logrus.Error("btc indexer error when linking spent outputs")
+			return err
+		}
 	}
 	return nil
 }
@@ -130,3 +230,109 @@ func (in *CIDIndexer) indexTxOutput(tx *sqlx.Tx, txOuput TxOutput, txID int64) e
 		txID, txOuput.Index, txOuput.Value, txOuput.PkScript, txOuput.ScriptClass, txOuput.Addresses, txOuput.RequiredSigs)
 	return err
 }
+
+// indexSpends links the inputs of the transaction to the outputs they spend, and links inputs indexed earlier
+// to the outputs of this transaction they spend, so that blocks can be indexed in any order
+// Inputs are only linked to outputs of transactions in canonical headers
+// The address history of this transaction, and of any transactions whose inputs were newly linked, is then rebuilt
+func (in *CIDIndexer) indexSpends(tx *sqlx.Tx, txHash string, txID int64) error {
+	_, err := tx.Exec(`UPDATE btc.tx_inputs SET spent_output_id = (
+								SELECT tx_outputs.id FROM btc.tx_outputs
+								INNER JOIN btc.transaction_cids ON (tx_outputs.tx_id = transaction_cids.id)
+								INNER JOIN btc.header_cids ON (transaction_cids.header_id = header_cids.id)
+								WHERE transaction_cids.tx_hash = tx_inputs.outpoint_tx_hash
+								AND tx_outputs.index = tx_inputs.outpoint_index
+								AND header_cids.canonical = true
+							)
+							WHERE tx_inputs.tx_id = $1`, txID)
+	if err != nil {
+		return err
+	}
+	spenderIDs := make([]int64, 0)
+	err = tx.Select(&spenderIDs, `UPDATE btc.tx_inputs SET spent_output_id = tx_outputs.id
+							FROM btc.tx_outputs
+							INNER JOIN btc.transaction_cids ON (tx_outputs.tx_id = transaction_cids.id)
+							INNER JOIN btc.header_cids ON (transaction_cids.header_id = header_cids.id)
+							WHERE tx_outputs.tx_id = $1
+							AND header_cids.canonical = true
+							AND tx_inputs.outpoint_tx_hash = $2
+							AND tx_inputs.outpoint_index = tx_outputs.index
+							AND tx_inputs.spent_output_id IS DISTINCT FROM tx_outputs.id
+							RETURNING tx_inputs.tx_id`, txID, txHash)
+	if err != nil {
+		return err
+	}
+	if err := in.indexAddressHistory(tx, txID); err != nil {
+		return err
+	}
+	for _, spenderID := range spenderIDs {
+		if err := in.indexAddressHistory(tx, spenderID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// relinkSpends re-resolves the spends of outputs created at the provided heights after their canonical flags have changed
+// Inputs linked to outputs that are no longer in a canonical header are unlinked, and inputs spending outputs that are now
+// in a canonical header are linked; the address history of every transaction whose inputs changed is then rebuilt
+func (in *CIDIndexer) relinkSpends(tx *sqlx.Tx, heights []int64) error {
+	unlinkedIDs := make([]int64, 0)
+	if err := tx.Select(&unlinkedIDs, `UPDATE btc.tx_inputs SET spent_output_id = NULL
+							FROM btc.tx_outputs
+							INNER JOIN btc.transaction_cids ON (tx_outputs.tx_id = transaction_cids.id)
+							INNER JOIN btc.header_cids ON (transaction_cids.header_id = header_cids.id)
+							WHERE tx_inputs.spent_output_id = tx_outputs.id
+							AND header_cids.block_number = ANY($1)
+							AND header_cids.canonical = false
+							RETURNING tx_inputs.tx_id`, pq.Array(heights)); err != nil {
+		return err
+	}
+	linkedIDs := make([]int64, 0)
+	if err := tx.Select(&linkedIDs, `UPDATE btc.tx_inputs SET spent_output_id = tx_outputs.id
+							FROM btc.tx_outputs
+							INNER JOIN btc.transaction_cids ON (tx_outputs.tx_id = transaction_cids.id)
+							INNER JOIN btc.header_cids ON (transaction_cids.header_id = header_cids.id)
+							WHERE transaction_cids.tx_hash = tx_inputs.outpoint_tx_hash
+							AND tx_outputs.index = tx_inputs.outpoint_index
+							AND header_cids.block_number = ANY($1)
+							AND header_cids.canonical = true
+							AND tx_inputs.spent_output_id IS DISTINCT FROM tx_outputs.id
+							RETURNING tx_inputs.tx_id`, pq.Array(heights)); err != nil {
+		return err
+	}
+	rebuilt := make(map[int64]bool, len(unlinkedIDs)+len(linkedIDs))
+	for _, spenderID := range append(unlinkedIDs, linkedIDs...) {
+		if rebuilt[spenderID] {
+			continue
+		}
+		if err := in.indexAddressHistory(tx, spenderID); err != nil {
+			return err
+		}
+		rebuilt[spenderID] = true
+	}
+	return nil
+}
+
+// indexAddressHistory rebuilds the amounts received and spent by each address in the transaction
+// Received amounts come from the transaction's outputs, spent amounts from the outputs its inputs have been linked to
+func (in *CIDIndexer) indexAddressHistory(tx *sqlx.Tx, txID int64) error {
+	// Addresses whose spends were unlinked may no longer appear in the transaction
+	if _, err := tx.Exec(`DELETE FROM btc.address_history WHERE tx_id = $1`, txID); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`INSERT INTO btc.address_history (address, tx_id, received, spent)
+							SELECT address, $1::INTEGER, SUM(received), SUM(spent) FROM (
+								SELECT UNNEST(addresses) AS address, value AS received, 0 AS spent
+								FROM btc.tx_outputs
+								WHERE tx_id = $1
+								UNION ALL
+								SELECT UNNEST(tx_outputs.addresses), 0, tx_outputs.value
+								FROM btc.tx_inputs
+								INNER JOIN btc.tx_outputs ON (tx_inputs.spent_output_id = tx_outputs.id)
+								WHERE tx_inputs.tx_id = $1
+							) AS flows
+							GROUP BY address
+							ON CONFLICT (address, tx_id) DO UPDATE SET (received, spent) = (EXCLUDED.received, EXCLUDED.spent)`, txID)
+	return err
+}
@@ -84,4 +84,69 @@ var _ = Describe("Indexer", func() {
 			}
 		})
 	})
+	Describe("Index canonical headers", func() {
+		headerPayload := func(number, hash, parentHash string) *btc.CIDPayload {
+			return &btc.CIDPayload{
+				HeaderCID: btc.HeaderModel{
+					BlockNumber: number,
+					BlockHash:   hash,
+					ParentHash:  parentHash,
+					CID:         mocks.MockHeaderMetaData.CID,
+				},
+			}
+		}
+		canonical := func(hash string) bool {
+			var c bool
+			err := db.Get(&c, `SELECT canonical FROM btc.header_cids WHERE block_hash = $1`, hash)
+			Expect(err).ToNot(HaveOccurred())
+			return c
+		}
+
+		It("Marks competing headers, and the chains they head, as non-canonical", func() {
+			err = repo.Index(headerPayload("1", "a1", "a0"))
+			Expect(err).ToNot(HaveOccurred())
+			err = repo.Index(headerPayload("2", "a2", "a1"))
+			Expect(err).ToNot(HaveOccurred())
+			err = repo.Index(headerPayload("2", "b2", "a1"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(canonical("a1")).To(BeTrue())
+			Expect(canonical("a2")).To(BeFalse())
+			Expect(canonical("b2")).To(BeTrue())
+
+			err = repo.Index(headerPayload("3", "a3", "a2"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(canonical("a2")).To(BeTrue())
+			Expect(canonical("b2")).To(BeFalse())
+			Expect(canonical("a3")).To(BeTrue())
+
+			tx, err := db.Beginx()
+			Expect(err).ToNot(HaveOccurred())
+			headers, err := btc.NewCIDRetriever(db).RetrieveHeaderCIDs(tx, 2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(headers)).To(Equal(1))
+			Expect(headers[0].BlockHash).To(Equal("a2"))
+			err = tx.Commit()
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("Only counts the canonical header at each height as a validation gap", func() {
+			// b1 replaces a1 once a1's child a2 has been indexed, leaving no canonical header at height 2
+			for _, payload := range []*btc.CIDPayload{
+				headerPayload("1", "a1", "a0"),
+				headerPayload("2", "a2", "a1"),
+				headerPayload("1", "b1", "a0"),
+				headerPayload("1", "b1", "a0"),
+			} {
+				err = repo.Index(payload)
+				Expect(err).ToNot(HaveOccurred())
+			}
+			Expect(canonical("a1")).To(BeFalse())
+			Expect(canonical("a2")).To(BeFalse())
+			Expect(canonical("b1")).To(BeTrue())
+
+			gaps, err := btc.NewCIDRetriever(db).RetrieveValidationGaps(2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(gaps).To(Equal([]shared.Gap{{Start: 2, Stop: 2}}))
+		})
+	})
 })
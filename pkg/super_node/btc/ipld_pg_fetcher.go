@@ -31,6 +31,8 @@ import (
 // it interfaces directly with PG-IPFS instead of going through a node-interface or remote node
 type IPLDPGFetcher struct {
 	db *postgres.DB
+	// If true, the data of each IPLD is checked against the multihash of its CID before it is returned
+	Verify bool
 }
 
 // NewIPLDPGFetcher creates a pointer to a new IPLDPGFetcher
@@ -79,7 +81,7 @@ func (f *IPLDPGFetcher) Fetch(cids shared.CIDsForFetching) (shared.IPLDs, error)
 // FetchHeaders fetches headers
 func (f *IPLDPGFetcher) FetchHeader(tx *sqlx.Tx, c HeaderModel) (ipfs.BlockModel, error) {
 	log.Debug("fetching header ipld")
-	headerBytes, err := shared.FetchIPLD(tx, c.CID)
+	headerBytes, err := f.fetchIPLD(tx, c.CID)
 	if err != nil {
 		return ipfs.BlockModel{}, err
 	}
@@ -94,7 +96,7 @@ func (f *IPLDPGFetcher) FetchTrxs(tx *sqlx.Tx, cids []TxModel) ([]ipfs.BlockMode
 	log.Debug("fetching transaction iplds")
 	trxIPLDs := make([]ipfs.BlockModel, len(cids))
 	for i, c := range cids {
-		trxBytes, err := shared.FetchIPLD(tx, c.CID)
+		trxBytes, err := f.fetchIPLD(tx, c.CID)
 		if err != nil {
 			return nil, err
 		}
@@ -105,3 +107,12 @@ func (f *IPLDPGFetcher) FetchTrxs(tx *sqlx.Tx, cids []TxModel) ([]ipfs.BlockMode
 	}
 	return trxIPLDs, nil
 }
+
+// fetchIPLD retrieves an IPLD from public.blocks, verifying it against its CID if Verify is set
+func (f *IPLDPGFetcher) fetchIPLD(tx *sqlx.Tx, c string) ([]byte, error) {
+	data, err := shared.FetchIPLD(tx, c)
+	if err != nil || !f.Verify {
+		return data, err
+	}
+	return data, shared.VerifyIPLD(c, data)
+}
@@ -0,0 +1,129 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package btc
+
+import (
+	"bytes"
+	"encoding/hex"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// JSONPayload is the decoded form of the IPLDs sent to JSON subscriptions
+type JSONPayload struct {
+	BlockNumber  int64             `json:"blockNumber"`
+	Header       *JSONHeader       `json:"header"`
+	Transactions []JSONTransaction `json:"transactions"`
+}
+
+// JSONHeader is a decoded header with its CID
+type JSONHeader struct {
+	CID        string `json:"cid"`
+	Hash       string `json:"hash"`
+	Version    int32  `json:"version"`
+	PrevBlock  string `json:"previousBlockHash"`
+	MerkleRoot string `json:"merkleRoot"`
+	Timestamp  int64  `json:"timestamp"`
+	Bits       uint32 `json:"bits"`
+	Nonce      uint32 `json:"nonce"`
+}
+
+// JSONTransaction is a decoded transaction with its CID
+type JSONTransaction struct {
+	CID         string      `json:"cid"`
+	Hash        string      `json:"hash"`
+	WitnessHash string      `json:"witnessHash"`
+	Version     int32       `json:"version"`
+	LockTime    uint32      `json:"lockTime"`
+	Inputs      []JSONTxIn  `json:"inputs"`
+	Outputs     []JSONTxOut `json:"outputs"`
+}
+
+// JSONTxIn is a decoded transaction input
+type JSONTxIn struct {
+	PreviousOutPointHash  string   `json:"previousOutPointHash"`
+	PreviousOutPointIndex uint32   `json:"previousOutPointIndex"`
+	SignatureScript       string   `json:"signatureScript"`
+	Witness               []string `json:"witness,omitempty"`
+	Sequence              uint32   `json:"sequence"`
+}
+
+// JSONTxOut is a decoded transaction output
+type JSONTxOut struct {
+	Value    int64  `json:"value"`
+	PkScript string `json:"pkScript"`
+}
+
+// NewJSONPayload decodes the IPLDs into a JSONPayload
+func NewJSONPayload(iplds IPLDs) (JSONPayload, error) {
+	payload := JSONPayload{
+		BlockNumber:  iplds.BlockNumber.Int64(),
+		Transactions: make([]JSONTransaction, 0, len(iplds.Transactions)),
+	}
+	if len(iplds.Header.Data) > 0 {
+		header := new(wire.BlockHeader)
+		if err := header.Deserialize(bytes.NewReader(iplds.Header.Data)); err != nil {
+			return JSONPayload{}, err
+		}
+		payload.Header = &JSONHeader{
+			CID:        iplds.Header.CID,
+			Hash:       header.BlockHash().String(),
+			Version:    header.Version,
+			PrevBlock:  header.PrevBlock.String(),
+			MerkleRoot: header.MerkleRoot.String(),
+			Timestamp:  header.Timestamp.Unix(),
+			Bits:       header.Bits,
+			Nonce:      header.Nonce,
+		}
+	}
+	for _, txIPLD := range iplds.Transactions {
+		tx := new(wire.MsgTx)
+		if err := tx.Deserialize(bytes.NewReader(txIPLD.Data)); err != nil {
+			return JSONPayload{}, err
+		}
+		jsonTx := JSONTransaction{
+			CID:         txIPLD.CID,
+			Hash:        tx.TxHash().String(),
+			WitnessHash: tx.WitnessHash().String(),
+			Version:     tx.Version,
+			LockTime:    tx.LockTime,
+			Inputs:      make([]JSONTxIn, 0, len(tx.TxIn)),
+			Outputs:     make([]JSONTxOut, 0, len(tx.TxOut)),
+		}
+		for _, in := range tx.TxIn {
+			witness := make([]string, 0, len(in.Witness))
+			for _, item := range in.Witness {
+				witness = append(witness, hex.EncodeToString(item))
+			}
+			jsonTx.Inputs = append(jsonTx.Inputs, JSONTxIn{
+				PreviousOutPointHash:  in.PreviousOutPoint.Hash.String(),
+				PreviousOutPointIndex: in.PreviousOutPoint.Index,
+				SignatureScript:       hex.EncodeToString(in.SignatureScript),
+				Witness:               witness,
+				Sequence:              in.Sequence,
+			})
+		}
+		for _, out := range tx.TxOut {
+			jsonTx.Outputs = append(jsonTx.Outputs, JSONTxOut{
+				Value:    out.Value,
+				PkScript: hex.EncodeToString(out.PkScript),
+			})
+		}
+		payload.Transactions = append(payload.Transactions, jsonTx)
+	}
+	return payload, nil
+}
@@ -0,0 +1,71 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package btc_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/vulcanizedb/pkg/ipfs"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/btc"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/btc/mocks"
+)
+
+var _ = Describe("JSONPayload", func() {
+	Describe("NewJSONPayload", func() {
+		It("Decodes the IPLDs into a JSON payload that carries their CIDs", func() {
+			headerBuf := new(bytes.Buffer)
+			Expect(mocks.MockBlock.Header.Serialize(headerBuf)).ToNot(HaveOccurred())
+			iplds := btc.IPLDs{
+				BlockNumber:  big.NewInt(mocks.MockBlockHeight),
+				Header:       ipfs.BlockModel{CID: "mockHeaderCID", Data: headerBuf.Bytes()},
+				Transactions: make([]ipfs.BlockModel, 0, len(mocks.MockTransactions)),
+			}
+			for i, tx := range mocks.MockTransactions {
+				txBuf := new(bytes.Buffer)
+				Expect(tx.MsgTx().Serialize(txBuf)).ToNot(HaveOccurred())
+				iplds.Transactions = append(iplds.Transactions, ipfs.BlockModel{CID: mocks.MockTxsMetaDataPostPublish[i].CID, Data: txBuf.Bytes()})
+			}
+
+			payload, err := btc.NewJSONPayload(iplds)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(payload.BlockNumber).To(Equal(mocks.MockBlockHeight))
+			Expect(payload.Header.CID).To(Equal("mockHeaderCID"))
+			Expect(payload.Header.Hash).To(Equal(mocks.MockBlock.Header.BlockHash().String()))
+			Expect(payload.Header.PrevBlock).To(Equal(mocks.MockBlock.Header.PrevBlock.String()))
+			Expect(payload.Header.Bits).To(Equal(mocks.MockBlock.Header.Bits))
+			Expect(len(payload.Transactions)).To(Equal(len(mocks.MockTransactions)))
+			for i, tx := range payload.Transactions {
+				msgTx := mocks.MockTransactions[i].MsgTx()
+				Expect(tx.CID).To(Equal(mocks.MockTxsMetaDataPostPublish[i].CID))
+				Expect(tx.Hash).To(Equal(msgTx.TxHash().String()))
+				Expect(len(tx.Inputs)).To(Equal(len(msgTx.TxIn)))
+				Expect(len(tx.Outputs)).To(Equal(len(msgTx.TxOut)))
+				for j, out := range tx.Outputs {
+					Expect(out.Value).To(Equal(msgTx.TxOut[j].Value))
+				}
+			}
+
+			_, err = json.Marshal(payload)
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+})
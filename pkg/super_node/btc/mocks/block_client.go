@@ -0,0 +1,105 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mocks
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// BlockClient is a mock bitcoind client serving a chain of blocks which can be replaced to simulate reorgs
+type BlockClient struct {
+	mu     sync.Mutex
+	chain  []*wire.MsgBlock
+	blocks map[chainhash.Hash]*wire.MsgBlock
+	// error returned by GetBlockHeader, to simulate the node failing to serve a request
+	headerErr error
+}
+
+// NewBlockClient returns a BlockClient serving the chain, the block at index i is at height i
+func NewBlockClient(chain []*wire.MsgBlock) *BlockClient {
+	client := &BlockClient{blocks: make(map[chainhash.Hash]*wire.MsgBlock)}
+	client.SetChain(chain)
+	return client
+}
+
+// SetChain replaces the chain served by the client, blocks of the previous chains can still be looked up by hash
+func (c *BlockClient) SetChain(chain []*wire.MsgBlock) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.chain = chain
+	for _, block := range chain {
+		c.blocks[block.BlockHash()] = block
+	}
+}
+
+// SetGetBlockHeaderErr sets the error returned by GetBlockHeader, a nil error restores it
+func (c *BlockClient) SetGetBlockHeaderErr(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.headerErr = err
+}
+
+// GetBlockCount mock method
+func (c *BlockClient) GetBlockCount() (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return int64(len(c.chain) - 1), nil
+}
+
+// GetBlockHash mock method
+func (c *BlockClient) GetBlockHash(blockHeight int64) (*chainhash.Hash, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if blockHeight < 0 || blockHeight >= int64(len(c.chain)) {
+		return nil, fmt.Errorf("block height %d out of range", blockHeight)
+	}
+	hash := c.chain[blockHeight].BlockHash()
+	return &hash, nil
+}
+
+// GetBlock mock method
+func (c *BlockClient) GetBlock(blockHash *chainhash.Hash) (*wire.MsgBlock, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	block, ok := c.blocks[*blockHash]
+	if !ok {
+		return nil, fmt.Errorf("block %s not found", blockHash.String())
+	}
+	return block, nil
+}
+
+// GetBlockHeader mock method
+func (c *BlockClient) GetBlockHeader(blockHash *chainhash.Hash) (*wire.BlockHeader, error) {
+	c.mu.Lock()
+	headerErr := c.headerErr
+	c.mu.Unlock()
+	if headerErr != nil {
+		return nil, headerErr
+	}
+	block, err := c.GetBlock(blockHash)
+	if err != nil {
+		return nil, err
+	}
+	return &block.Header, nil
+}
+
+// Shutdown mock method
+func (c *BlockClient) Shutdown() {}
@@ -29,6 +29,7 @@ type HeaderModel struct {
 	Bits           uint32 `db:"bits"`
 	NodeID         int64  `db:"node_id"`
 	TimesValidated int64  `db:"times_validated"`
+	Canonical      bool   `db:"canonical"`
 }
 
 // TxModel is the db model for btc.transaction_cids table
@@ -62,8 +63,8 @@ type TxInput struct {
 	Index                 int64    `db:"index"`
 	TxWitness             []string `db:"witness"`
 	SignatureScript       []byte   `db:"sig_script"`
-	PreviousOutPointIndex uint32   `db:"outpoint_tx_hash"`
-	PreviousOutPointHash  string   `db:"outpoint_index"`
+	PreviousOutPointIndex uint32   `db:"outpoint_index"`
+	PreviousOutPointHash  string   `db:"outpoint_tx_hash"`
 }
 
 // TxOutput is the db model for btc.tx_outputs table
@@ -77,3 +78,22 @@ type TxOutput struct {
 	RequiredSigs int64          `db:"required_sigs"`
 	Addresses    pq.StringArray `db:"addresses"`
 }
+
+// UTXOModel is an unspent btc.tx_outputs row along with the transaction and block that created it
+type UTXOModel struct {
+	TxHash      string `db:"tx_hash"`
+	Index       int64  `db:"index"`
+	Value       int64  `db:"value"`
+	PkScript    []byte `db:"pk_script"`
+	BlockNumber int64  `db:"block_number"`
+}
+
+// AddressHistoryModel is the db model for btc.address_history table joined with the transaction and block it refers to
+type AddressHistoryModel struct {
+	TxHash      string `db:"tx_hash"`
+	TxIndex     int64  `db:"tx_index"`
+	BlockNumber int64  `db:"block_number"`
+	BlockHash   string `db:"block_hash"`
+	Received    int64  `db:"received"`
+	Spent       int64  `db:"spent"`
+}
@@ -20,6 +20,8 @@ import (
 	"fmt"
 	"strconv"
 
+	"github.com/jmoiron/sqlx"
+
 	"github.com/vulcanize/vulcanizedb/pkg/ipfs/ipld"
 	"github.com/vulcanize/vulcanizedb/pkg/postgres"
 	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
@@ -45,12 +47,6 @@ func (pub *IPLDPublisherAndIndexer) Publish(payload shared.ConvertedData) (share
 	if !ok {
 		return nil, fmt.Errorf("btc publisher expected payload type %T got %T", ConvertedPayload{}, payload)
 	}
-	// Generate the iplds
-	headerNode, txNodes, txTrieNodes, err := ipld.FromHeaderAndTxs(ipldPayload.Header, ipldPayload.Txs)
-	if err != nil {
-		return nil, err
-	}
-
 	// Begin new db tx
 	tx, err := pub.indexer.db.Beginx()
 	if err != nil {
@@ -67,55 +63,78 @@ func (pub *IPLDPublisherAndIndexer) Publish(payload shared.ConvertedData) (share
 		}
 	}()
 
+	err = pub.PublishAndIndex(tx, ipldPayload)
+	// This IPLDPublisher does both publishing and indexing, we do not need to pass anything forward to the indexer
+	return nil, err
+}
+
+// PublishAndIndex publishes and indexes the IPLDs of the provided payload within the provided db tx
+func (pub *IPLDPublisherAndIndexer) PublishAndIndex(tx *sqlx.Tx, ipldPayload ConvertedPayload) error {
+	// Generate the iplds
+	headerNode, txNodes, txTrieNodes, err := ipld.FromHeaderAndTxs(ipldPayload.Header, ipldPayload.Txs)
+	if err != nil {
+		return err
+	}
+
 	// Publish trie nodes
 	for _, node := range txTrieNodes {
 		if err := shared.PublishIPLD(tx, node); err != nil {
-			return nil, err
+			return err
 		}
 	}
 
 	// Publish and index header
 	if err := shared.PublishIPLD(tx, headerNode); err != nil {
-		return nil, err
-	}
-	header := HeaderModel{
-		CID:         headerNode.Cid().String(),
-		ParentHash:  ipldPayload.Header.PrevBlock.String(),
-		BlockNumber: strconv.Itoa(int(ipldPayload.BlockPayload.BlockHeight)),
-		BlockHash:   ipldPayload.Header.BlockHash().String(),
-		Timestamp:   ipldPayload.Header.Timestamp.UnixNano(),
-		Bits:        ipldPayload.Header.Bits,
+		return err
 	}
-	headerID, err := pub.indexer.indexHeaderCID(tx, header)
+	headerID, err := pub.indexer.indexHeaderCID(tx, newHeaderModel(ipldPayload, headerNode))
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	// Publish and index txs
 	for i, txNode := range txNodes {
-		if err := shared.PublishIPLD(tx, txNode); err != nil {
-			return nil, err
-		}
 		txModel := ipldPayload.TxMetaData[i]
 		txModel.CID = txNode.Cid().String()
-		txID, err := pub.indexer.indexTransactionCID(tx, txModel, headerID)
-		if err != nil {
-			return nil, err
+		if err := pub.publishAndIndexTx(tx, txNode, txModel, headerID); err != nil {
+			return err
 		}
-		for _, input := range txModel.TxInputs {
-			if err := pub.indexer.indexTxInput(tx, input, txID); err != nil {
-				return nil, err
-			}
+	}
+	return nil
+}
+
+// publishAndIndexTx publishes and indexes a transaction along with its inputs and outputs
+func (pub *IPLDPublisherAndIndexer) publishAndIndexTx(tx *sqlx.Tx, txNode *ipld.BtcTx, txModel TxModelWithInsAndOuts, headerID int64) error {
+	if err := shared.PublishIPLD(tx, txNode); err != nil {
+		return err
+	}
+	txID, err := pub.indexer.indexTransactionCID(tx, txModel, headerID)
+	if err != nil {
+		return err
+	}
+	for _, input := range txModel.TxInputs {
+		if err := pub.indexer.indexTxInput(tx, input, txID); err != nil {
+			return err
 		}
-		for _, output := range txModel.TxOutputs {
-			if err := pub.indexer.indexTxOutput(tx, output, txID); err != nil {
-				return nil, err
-			}
+	}
+	for _, output := range txModel.TxOutputs {
+		if err := pub.indexer.indexTxOutput(tx, output, txID); err != nil {
+			return err
 		}
 	}
+	return pub.indexer.indexSpends(tx, txModel.TxHash, txID)
+}
 
-	// This IPLDPublisher does both publishing and indexing, we do not need to pass anything forward to the indexer
-	return nil, err
+// newHeaderModel returns the header_cids row for the block of the payload
+func newHeaderModel(ipldPayload ConvertedPayload, headerNode *ipld.BtcHeader) HeaderModel {
+	return HeaderModel{
+		CID:         headerNode.Cid().String(),
+		ParentHash:  ipldPayload.Header.PrevBlock.String(),
+		BlockNumber: strconv.Itoa(int(ipldPayload.BlockPayload.BlockHeight)),
+		BlockHash:   ipldPayload.Header.BlockHash().String(),
+		Timestamp:   ipldPayload.Header.Timestamp.UnixNano(),
+		Bits:        ipldPayload.Header.Bits,
+	}
 }
 
 // Index satisfies the shared.CIDIndexer interface
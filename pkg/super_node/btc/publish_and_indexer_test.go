@@ -18,7 +18,9 @@ package btc_test
 
 import (
 	"bytes"
+	"database/sql"
 
+	"github.com/btcsuite/btcd/wire"
 	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-ipfs-blockstore"
 	"github.com/ipfs/go-ipfs-ds-help"
@@ -118,4 +120,77 @@ var _ = Describe("PublishAndIndexer", func() {
 			}
 		})
 	})
+	Describe("Index spends", func() {
+		It("Links inputs to the outputs they spend regardless of the order blocks are indexed in", func() {
+			publishBlock(db, blockTwo, 2)
+			var spentOutputID sql.NullInt64
+			pgStr := `SELECT tx_inputs.spent_output_id FROM btc.tx_inputs
+				INNER JOIN btc.transaction_cids ON (tx_inputs.tx_id = transaction_cids.id)
+				WHERE transaction_cids.tx_hash = $1`
+			err = db.Get(&spentOutputID, pgStr, spendTx.TxHash().String())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(spentOutputID.Valid).To(BeFalse())
+
+			publishBlock(db, blockOne, 1)
+			err = db.Get(&spentOutputID, pgStr, spendTx.TxHash().String())
+			Expect(err).ToNot(HaveOccurred())
+			var aliceOutputID int64
+			err = db.Get(&aliceOutputID, `SELECT tx_outputs.id FROM btc.tx_outputs
+				INNER JOIN btc.transaction_cids ON (tx_outputs.tx_id = transaction_cids.id)
+				WHERE transaction_cids.tx_hash = $1 AND tx_outputs.index = 0`, aliceOutput.Hash.String())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(spentOutputID.Int64).To(Equal(aliceOutputID))
+
+			history := make([]btc.AddressHistoryModel, 0)
+			err = db.Select(&history, `SELECT address_history.received, address_history.spent FROM btc.address_history
+				INNER JOIN btc.transaction_cids ON (address_history.tx_id = transaction_cids.id)
+				WHERE transaction_cids.tx_hash = $1 AND address_history.address = $2`, spendTx.TxHash().String(), alice.EncodeAddress())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(history).To(Equal([]btc.AddressHistoryModel{{Received: 20e8, Spent: 50e8}}))
+		})
+
+		It("Unlinks spends of outputs that are reorged out of the canonical chain and relinks them when they return", func() {
+			// block three spends bob's output from the spend tx in block two
+			bobOutput := wire.OutPoint{Hash: spendTx.TxHash(), Index: 0}
+			bobSpendTx := paymentTx(bobOutput, txOut(carol, 30e8))
+			blockThree := testBlock(blockTwo.BlockHash(), 4, coinbaseTx(3, carol, 50e8), bobSpendTx)
+			publishBlock(db, blockOne, 1)
+			publishBlock(db, blockTwo, 2)
+			publishBlock(db, blockThree, 3)
+			var spentOutputID sql.NullInt64
+			pgStr := `SELECT tx_inputs.spent_output_id FROM btc.tx_inputs
+				INNER JOIN btc.transaction_cids ON (tx_inputs.tx_id = transaction_cids.id)
+				WHERE transaction_cids.tx_hash = $1`
+			err = db.Get(&spentOutputID, pgStr, bobSpendTx.TxHash().String())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(spentOutputID.Valid).To(BeTrue())
+			historyPgStr := `SELECT address_history.received, address_history.spent FROM btc.address_history
+				INNER JOIN btc.transaction_cids ON (address_history.tx_id = transaction_cids.id)
+				WHERE transaction_cids.tx_hash = $1 AND address_history.address = $2`
+			history := make([]btc.AddressHistoryModel, 0)
+			err = db.Select(&history, historyPgStr, bobSpendTx.TxHash().String(), bob.EncodeAddress())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(history).To(Equal([]btc.AddressHistoryModel{{Received: 0, Spent: 30e8}}))
+
+			// The competing block two orphans block two, and block three along with it
+			publishBlock(db, competingBlockTwo, 2)
+			err = db.Get(&spentOutputID, pgStr, bobSpendTx.TxHash().String())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(spentOutputID.Valid).To(BeFalse())
+			history = make([]btc.AddressHistoryModel, 0)
+			err = db.Select(&history, historyPgStr, bobSpendTx.TxHash().String(), bob.EncodeAddress())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(history).To(BeEmpty())
+
+			// Block two becomes canonical again
+			publishBlock(db, blockTwo, 2)
+			err = db.Get(&spentOutputID, pgStr, bobSpendTx.TxHash().String())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(spentOutputID.Valid).To(BeTrue())
+			history = make([]btc.AddressHistoryModel, 0)
+			err = db.Select(&history, historyPgStr, bobSpendTx.TxHash().String(), bob.EncodeAddress())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(history).To(Equal([]btc.AddressHistoryModel{{Received: 0, Spent: 30e8}}))
+		})
+	})
 })
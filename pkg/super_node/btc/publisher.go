@@ -31,6 +31,8 @@ type IPLDPublisher struct {
 	HeaderPutter          ipfs.DagPutter
 	TransactionPutter     ipfs.DagPutter
 	TransactionTriePutter ipfs.DagPutter
+	// If set, the IPLDs of each payload are collected and put to IPFS in one batch rather than one request at a time
+	batchAdder ipfs.BatchAdder
 }
 
 // NewIPLDPublisher creates a pointer to a new Publisher which satisfies the IPLDPublisher interface
@@ -39,11 +41,24 @@ func NewIPLDPublisher(ipfsPath string) (*IPLDPublisher, error) {
 	if err != nil {
 		return nil, err
 	}
+	return newIPLDPublisher(node), nil
+}
+
+// NewRemoteIPLDPublisher creates a pointer to a new Publisher which publishes through the HTTP API of a remote IPFS node
+// The IPLDs of each payload are put to the remote node in batches
+func NewRemoteIPLDPublisher(config ipfs.RemoteClientConfig) *IPLDPublisher {
+	client := ipfs.NewRemoteClient(config)
+	pub := newIPLDPublisher(client)
+	pub.batchAdder = client
+	return pub
+}
+
+func newIPLDPublisher(adder ipfs.Adder) *IPLDPublisher {
 	return &IPLDPublisher{
-		HeaderPutter:          dag_putters.NewBtcHeaderDagPutter(node),
-		TransactionPutter:     dag_putters.NewBtcTxDagPutter(node),
-		TransactionTriePutter: dag_putters.NewBtcTxTrieDagPutter(node),
-	}, nil
+		HeaderPutter:          dag_putters.NewBtcHeaderDagPutter(adder),
+		TransactionPutter:     dag_putters.NewBtcTxDagPutter(adder),
+		TransactionTriePutter: dag_putters.NewBtcTxTrieDagPutter(adder),
+	}
 }
 
 // Publish publishes an IPLDPayload to IPFS and returns the corresponding CIDPayload
@@ -52,6 +67,23 @@ func (pub *IPLDPublisher) Publish(payload shared.ConvertedData) (shared.CIDsForI
 	if !ok {
 		return nil, fmt.Errorf("eth publisher expected payload type %T got %T", &ConvertedPayload{}, payload)
 	}
+	if pub.batchAdder == nil {
+		return pub.publish(ipldPayload)
+	}
+	// The putters of a fresh publisher collect the payload's IPLDs, which are then added in one batch
+	collector := ipfs.NewNodeCollector()
+	cids, err := newIPLDPublisher(collector).publish(ipldPayload)
+	if err != nil {
+		return nil, err
+	}
+	if err := pub.batchAdder.AddMany(collector.Nodes()); err != nil {
+		return nil, err
+	}
+	return cids, nil
+}
+
+// publish puts the IPLDs of the payload with the publisher's putters and returns the corresponding CIDPayload
+func (pub *IPLDPublisher) publish(ipldPayload ConvertedPayload) (*CIDPayload, error) {
 	// Generate nodes
 	headerNode, txNodes, txTrieNodes, err := ipld.FromHeaderAndTxs(ipldPayload.Header, ipldPayload.Txs)
 	if err != nil {
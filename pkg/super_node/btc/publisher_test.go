@@ -20,9 +20,11 @@ import (
 	"bytes"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ipfs/go-cid"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	"github.com/vulcanize/vulcanizedb/pkg/ipfs"
 	mocks2 "github.com/vulcanize/vulcanizedb/pkg/ipfs/mocks"
 	"github.com/vulcanize/vulcanizedb/pkg/super_node/btc"
 	"github.com/vulcanize/vulcanizedb/pkg/super_node/btc/mocks"
@@ -77,5 +79,30 @@ var _ = Describe("Publisher", func() {
 			Expect(cidPayload.HeaderCID).To(Equal(mocks.MockHeaderMetaData))
 			Expect(cidPayload.TransactionCIDs).To(Equal(mocks.MockTxsMetaDataPostPublish))
 		})
+
+		It("Puts the IPLDs of a payload to a remote IPFS node in one batch", func() {
+			api := mocks2.NewRemoteAPI()
+			defer api.Close()
+			publisher := btc.NewRemoteIPLDPublisher(ipfs.RemoteClientConfig{URL: api.URL()})
+			payload, err := publisher.Publish(mocks.MockConvertedPayload)
+			Expect(err).ToNot(HaveOccurred())
+			cidPayload, ok := payload.(*btc.CIDPayload)
+			Expect(ok).To(BeTrue())
+			Expect(cidPayload.HeaderCID.BlockHash).To(Equal(mocks.MockHeaderMetaData.BlockHash))
+			Expect(len(cidPayload.TransactionCIDs)).To(Equal(len(mocks.MockTransactions)))
+
+			headerCID, err := cid.Decode(cidPayload.HeaderCID.CID)
+			Expect(err).ToNot(HaveOccurred())
+			_, ok = api.Block(headerCID)
+			Expect(ok).To(BeTrue())
+			for _, tx := range cidPayload.TransactionCIDs {
+				txCID, err := cid.Decode(tx.CID)
+				Expect(err).ToNot(HaveOccurred())
+				_, ok = api.Block(txCID)
+				Expect(ok).To(BeTrue())
+			}
+			// One request for the header codec and one for the tx codec, which the tx trie nodes share, rather than one for each IPLD
+			Expect(api.Requests("block/put")).To(Equal(2))
+		})
 	})
 })
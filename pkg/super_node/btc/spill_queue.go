@@ -0,0 +1,112 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package btc
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/vulcanize/vulcanizedb/pkg/postgres"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+// SpillQueue satisfies the shared.SpillQueue interface for bitcoin
+// It holds serialized blocks in Postgres until they can be published and indexed
+type SpillQueue struct {
+	db *postgres.DB
+}
+
+// NewSpillQueue creates a pointer to a new SpillQueue which satisfies the shared.SpillQueue interface
+func NewSpillQueue(db *postgres.DB) *SpillQueue {
+	return &SpillQueue{
+		db: db,
+	}
+}
+
+// Spill adds the block payload to the queue and records its height as a known gap until it has been indexed
+func (sq *SpillQueue) Spill(payload shared.RawChainData, height int64) error {
+	blockPayload, ok := payload.(BlockPayload)
+	if !ok {
+		return fmt.Errorf("btc spill queue: expected payload type %T got %T", BlockPayload{}, payload)
+	}
+	msgBlock := wire.MsgBlock{
+		Header:       *blockPayload.Header,
+		Transactions: make([]*wire.MsgTx, len(blockPayload.Txs)),
+	}
+	for i, tx := range blockPayload.Txs {
+		msgBlock.Transactions[i] = tx.MsgTx()
+	}
+	buf := new(bytes.Buffer)
+	if err := msgBlock.Serialize(buf); err != nil {
+		return err
+	}
+	tx, err := sq.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+	if _, err = tx.Exec(`INSERT INTO btc.spill_queue (data, height) VALUES ($1, $2)`, buf.Bytes(), height); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO btc.known_gaps (block_number) VALUES ($1) ON CONFLICT DO NOTHING`, height)
+	return err
+}
+
+// Pop removes the oldest block payload from the queue and returns it
+// The returned bool is false if the queue is empty
+func (sq *SpillQueue) Pop() (shared.RawChainData, bool, error) {
+	var res struct {
+		Data   []byte `db:"data"`
+		Height int64  `db:"height"`
+	}
+	err := sq.db.Get(&res, `DELETE FROM btc.spill_queue
+			WHERE id = (SELECT id FROM btc.spill_queue ORDER BY id LIMIT 1 FOR UPDATE SKIP LOCKED)
+			RETURNING data, height`)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	msgBlock := new(wire.MsgBlock)
+	if err := msgBlock.Deserialize(bytes.NewReader(res.Data)); err != nil {
+		return nil, false, err
+	}
+	return BlockPayload{
+		BlockHeight: res.Height,
+		Header:      &msgBlock.Header,
+		Txs:         msgTxsToUtilTxs(msgBlock.Transactions),
+	}, true, nil
+}
+
+// RecordGap records the height of data that was dropped as a known gap
+func (sq *SpillQueue) RecordGap(height int64) error {
+	_, err := sq.db.Exec(`INSERT INTO btc.known_gaps (block_number) VALUES ($1) ON CONFLICT DO NOTHING`, height)
+	return err
+}
@@ -27,28 +27,30 @@ import (
 
 // SubscriptionSettings config is used by a subscriber to specify what bitcoin data to stream from the super node
 type SubscriptionSettings struct {
-	BackFill     bool
-	BackFillOnly bool
-	Start        *big.Int
-	End          *big.Int // set to 0 or a negative value to have no ending block
-	HeaderFilter HeaderFilter
-	TxFilter     TxFilter
+	BackFill     bool                      `json:"backFill"`
+	BackFillOnly bool                      `json:"backFillOnly"`
+	Start        *big.Int                  `json:"start"`
+	End          *big.Int                  `json:"end"`          // set to 0 or a negative value to have no ending block
+	Cursor       shared.Cursor             `json:"cursor"`       // set to resume from the block after this cursor
+	SlowConsumer shared.SlowConsumerPolicy `json:"slowConsumer"` // what to do with payloads when the subscription falls behind
+	HeaderFilter HeaderFilter              `json:"headerFilter"`
+	TxFilter     TxFilter                  `json:"txFilter"`
 }
 
 // HeaderFilter contains filter settings for headers
 type HeaderFilter struct {
-	Off bool
+	Off bool `json:"off"`
 }
 
 // TxFilter contains filter settings for txs
 type TxFilter struct {
-	Off             bool
-	Segwit          bool     // allow filtering for segwit trxs
-	WitnessHashes   []string // allow filtering for specific witness hashes
-	Indexes         []int64  // allow filtering for specific transaction indexes (e.g. 0 for coinbase transactions)
-	PkScriptClasses []uint8  // allow filtering for txs that have at least one tx output with the specified pkscript class
-	MultiSig        bool     // allow filtering for txs that have at least one tx output that requires more than one signature
-	Addresses       []string // allow filtering for txs that have at least one tx output with at least one of the provided addresses
+	Off             bool     `json:"off"`
+	Segwit          bool     `json:"segwit"`          // allow filtering for segwit trxs
+	WitnessHashes   []string `json:"witnessHashes"`   // allow filtering for specific witness hashes
+	Indexes         []int64  `json:"indexes"`         // allow filtering for specific transaction indexes (e.g. 0 for coinbase transactions)
+	PkScriptClasses []uint8  `json:"pkScriptClasses"` // allow filtering for txs that have at least one tx output with the specified pkscript class
+	MultiSig        bool     `json:"multiSig"`        // allow filtering for txs that have at least one tx output that requires more than one signature
+	Addresses       []string `json:"addresses"`       // allow filtering for txs that have at least one tx output with at least one of the provided addresses
 }
 
 // Init is used to initialize a EthSubscription struct with env variables
@@ -61,6 +63,21 @@ func NewBtcSubscriptionConfig() (*SubscriptionSettings, error) {
 	// 0 start means we start at the beginning and 0 end means we continue indefinitely
 	sc.Start = big.NewInt(viper.GetInt64("superNode.btcSubscription.startingBlock"))
 	sc.End = big.NewInt(viper.GetInt64("superNode.btcSubscription.endingBlock"))
+	// Below defaults to the zero cursor, which means we are not resuming a previous subscription
+	cursor, err := shared.ParseCursor(viper.GetString("superNode.btcSubscription.cursor"))
+	if err != nil {
+		return nil, err
+	}
+	sc.Cursor = cursor
+	// Below defaults to dropping payloads when the subscription falls behind
+	mode, err := shared.NewSlowConsumerMode(viper.GetString("superNode.btcSubscription.slowConsumer.policy"))
+	if err != nil {
+		return nil, err
+	}
+	sc.SlowConsumer = shared.SlowConsumerPolicy{
+		Mode:    mode,
+		Timeout: uint64(viper.GetInt64("superNode.btcSubscription.slowConsumer.timeout")),
+	}
 	// Below default to false, which means we get all headers by default
 	sc.HeaderFilter = HeaderFilter{
 		Off: viper.GetBool("superNode.btcSubscription.headerFilter.off"),
@@ -113,3 +130,13 @@ func (sc *SubscriptionSettings) HistoricalDataOnly() bool {
 func (sc *SubscriptionSettings) ChainType() shared.ChainType {
 	return shared.Bitcoin
 }
+
+// ResumeCursor satisfies the SubscriptionSettings() interface
+func (sc *SubscriptionSettings) ResumeCursor() shared.Cursor {
+	return sc.Cursor
+}
+
+// SlowConsumerPolicy satisfies the SubscriptionSettings() interface
+func (sc *SubscriptionSettings) SlowConsumerPolicy() shared.SlowConsumerPolicy {
+	return sc.SlowConsumer
+}
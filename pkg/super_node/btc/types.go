@@ -21,6 +21,7 @@ import (
 
 	"github.com/vulcanize/vulcanizedb/pkg/ipfs"
 
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcutil"
 )
@@ -32,6 +33,33 @@ type BlockPayload struct {
 	Txs         []*btcutil.Tx
 }
 
+// BlockRollback is streamed by the HTTPPayloadStreamer, ahead of the blocks of the new chain, when blocks it has streamed
+// are disconnected from the node's chain
+type BlockRollback struct {
+	ForkHeight   int64
+	Disconnected []DisconnectedBlock
+}
+
+// DisconnectedBlock identifies a block that has been disconnected from the chain
+type DisconnectedBlock struct {
+	Height int64
+	Hash   chainhash.Hash
+}
+
+// Height satisfies the shared.RollbackSignal interface
+func (br BlockRollback) Height() int64 {
+	return br.ForkHeight
+}
+
+// InvalidatedHeights satisfies the shared.RollbackSignal interface
+func (br BlockRollback) InvalidatedHeights() []int64 {
+	heights := make([]int64, len(br.Disconnected))
+	for i, block := range br.Disconnected {
+		heights[i] = block.Height
+	}
+	return heights
+}
+
 // ConvertedPayload is a custom type which packages raw BTC data for publishing to IPFS and filtering to subscribers
 // Returned by PayloadConverter
 // Passed to IPLDPublisher and ResponseFilterer
@@ -58,6 +86,7 @@ type CIDPayload struct {
 // Passed to IPLDFetcher
 type CIDWrapper struct {
 	BlockNumber  *big.Int
+	BlockHash    string // hash of the block the CIDs belong to, set even when the header is filtered out
 	Header       HeaderModel
 	Transactions []TxModel
 }
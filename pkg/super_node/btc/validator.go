@@ -0,0 +1,208 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package btc
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/vulcanize/vulcanizedb/pkg/ipfs/ipld"
+	"github.com/vulcanize/vulcanizedb/pkg/postgres"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+// Objects of the btc index recorded in the validation audit
+const (
+	headerObject = "header"
+	txObject     = "transaction"
+)
+
+// Validator satisfies the BlockValidator interface for bitcoin
+// It compares a block fetched from the node with the rows indexed for it, and publishes and reindexes only the objects that differ
+// Like the IPLDPublisherAndIndexer, it interfaces directly with the public.blocks table of PG-IPFS
+type Validator struct {
+	publisher *IPLDPublisherAndIndexer
+	indexer   *CIDIndexer
+	db        *postgres.DB
+}
+
+// NewValidator creates a pointer to a new Validator which satisfies the BlockValidator interface
+func NewValidator(db *postgres.DB) *Validator {
+	publisher := NewIPLDPublisherAndIndexer(db)
+	return &Validator{
+		publisher: publisher,
+		indexer:   publisher.indexer,
+		db:        db,
+	}
+}
+
+// Validate compares the header and transactions of the payload with the indexed ones
+// If the block is not indexed at all it is published and indexed in full, otherwise only the objects that are missing or differ are
+// rewritten and the transactions that were indexed for the block but are not part of it are removed
+// Either way the header is marked validated once more, and the mismatches found are recorded in btc.validation_audit
+func (v *Validator) Validate(payload shared.ConvertedData) (mismatches []shared.Mismatch, err error) {
+	ipldPayload, ok := payload.(ConvertedPayload)
+	if !ok {
+		return nil, fmt.Errorf("btc validator expected payload type %T got %T", ConvertedPayload{}, payload)
+	}
+	headerNode, txNodes, txTrieNodes, err := ipld.FromHeaderAndTxs(ipldPayload.Header, ipldPayload.Txs)
+	if err != nil {
+		return nil, err
+	}
+	header := newHeaderModel(ipldPayload, headerNode)
+	recorder := shared.NewMismatchRecorder(uint64(ipldPayload.BlockHeight), header.BlockHash)
+
+	// Begin new db tx
+	tx, err := v.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	var indexed HeaderModel
+	err = tx.Get(&indexed, `SELECT * FROM btc.header_cids WHERE block_number = $1 AND block_hash = $2`, header.BlockNumber, header.BlockHash)
+	switch {
+	case err == sql.ErrNoRows:
+		// The block is not indexed, most likely because a competing block was indexed at this height in its place
+		recorder.Record(headerObject, header.BlockHash, "cid", "", header.CID, shared.InsertedAction)
+		if err = v.publisher.PublishAndIndex(tx, ipldPayload); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	default:
+		if err = v.validateHeader(tx, recorder, indexed, header, headerNode); err != nil {
+			return nil, err
+		}
+		var txsChanged bool
+		if txsChanged, err = v.validateTxs(tx, recorder, ipldPayload, indexed.ID, txNodes); err != nil {
+			return nil, err
+		}
+		if txsChanged {
+			for _, node := range txTrieNodes {
+				if err = shared.PublishIPLD(tx, node); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	if err = v.audit(tx, recorder.Mismatches); err != nil {
+		return nil, err
+	}
+	return recorder.Mismatches, nil
+}
+
+// validateHeader rewrites the header row if any of its columns differ from the fetched header, and marks it validated and canonical
+func (v *Validator) validateHeader(tx *sqlx.Tx, recorder *shared.MismatchRecorder, indexed, header HeaderModel, headerNode *ipld.BtcHeader) error {
+	if recorder.Compare(headerObject, header.BlockHash,
+		shared.Field{Name: "cid", Indexed: indexed.CID, Fetched: header.CID},
+		shared.Field{Name: "parent_hash", Indexed: indexed.ParentHash, Fetched: header.ParentHash},
+		shared.Field{Name: "timestamp", Indexed: strconv.FormatInt(indexed.Timestamp, 10), Fetched: strconv.FormatInt(header.Timestamp, 10)},
+		shared.Field{Name: "bits", Indexed: strconv.FormatUint(uint64(indexed.Bits), 10), Fetched: strconv.FormatUint(uint64(header.Bits), 10)},
+	) {
+		if indexed.CID != header.CID {
+			if err := shared.PublishIPLD(tx, headerNode); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.Exec(`UPDATE btc.header_cids SET (parent_hash, cid, timestamp, bits) = ($2, $3, $4, $5) WHERE id = $1`,
+			indexed.ID, header.ParentHash, header.CID, header.Timestamp, header.Bits); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`UPDATE btc.header_cids SET times_validated = times_validated + 1 WHERE id = $1`, indexed.ID); err != nil {
+		return err
+	}
+	// The node only serves blocks on its best chain
+	if !indexed.Canonical {
+		recorder.Record(headerObject, header.BlockHash, "canonical", "false", "true", shared.RewrittenAction)
+		return v.indexer.indexCanonical(tx, header)
+	}
+	return nil
+}
+
+// validateTxs rewrites the transactions that differ from the fetched ones, and returns whether any were rewritten
+// The inputs and outputs of a transaction are decoded from the transaction itself, so they only need to be rewritten along with it
+func (v *Validator) validateTxs(tx *sqlx.Tx, recorder *shared.MismatchRecorder, ipldPayload ConvertedPayload, headerID int64, txNodes []*ipld.BtcTx) (bool, error) {
+	indexedTxs := make([]TxModel, 0)
+	if err := tx.Select(&indexedTxs, `SELECT id, header_id, index, tx_hash, cid, segwit, witness_hash FROM btc.transaction_cids
+									WHERE header_id = $1
+									ORDER BY index`, headerID); err != nil {
+		return false, err
+	}
+	byHash := make(map[string]TxModel, len(indexedTxs))
+	for _, trx := range indexedTxs {
+		byHash[trx.TxHash] = trx
+	}
+	changed := false
+	for i, txNode := range txNodes {
+		txModel := ipldPayload.TxMetaData[i]
+		txModel.CID = txNode.Cid().String()
+		indexed, ok := byHash[txModel.TxHash]
+		delete(byHash, txModel.TxHash)
+		if !ok {
+			recorder.Record(txObject, txModel.TxHash, "cid", "", txModel.CID, shared.InsertedAction)
+		} else if !recorder.Compare(txObject, txModel.TxHash,
+			shared.Field{Name: "cid", Indexed: indexed.CID, Fetched: txModel.CID},
+			shared.Field{Name: "index", Indexed: strconv.FormatInt(indexed.Index, 10), Fetched: strconv.FormatInt(txModel.Index, 10)},
+			shared.Field{Name: "segwit", Indexed: strconv.FormatBool(indexed.SegWit), Fetched: strconv.FormatBool(txModel.SegWit)},
+			shared.Field{Name: "witness_hash", Indexed: indexed.WitnessHash, Fetched: txModel.WitnessHash},
+		) {
+			continue
+		}
+		changed = true
+		if err := v.publisher.publishAndIndexTx(tx, txNode, txModel, headerID); err != nil {
+			return false, err
+		}
+	}
+	// Transactions that are not part of the block are removed along with their inputs and outputs
+	for _, indexed := range indexedTxs {
+		if _, ok := byHash[indexed.TxHash]; !ok {
+			continue
+		}
+		recorder.Record(txObject, indexed.TxHash, "cid", indexed.CID, "", shared.RemovedAction)
+		if _, err := tx.Exec(`DELETE FROM btc.transaction_cids WHERE id = $1`, indexed.ID); err != nil {
+			return false, err
+		}
+	}
+	return changed, nil
+}
+
+// audit records the mismatches found for a block in btc.validation_audit
+func (v *Validator) audit(tx *sqlx.Tx, mismatches []shared.Mismatch) error {
+	for _, mismatch := range mismatches {
+		if _, err := tx.Exec(`INSERT INTO btc.validation_audit (block_number, block_hash, object, object_key, field, indexed, fetched, action, node_id)
+								VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+			mismatch.BlockNumber, mismatch.BlockHash, mismatch.Object, mismatch.Key, mismatch.Field, mismatch.Indexed,
+			mismatch.Fetched, mismatch.Action, v.db.NodeID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package btc_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/vulcanizedb/pkg/postgres"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/btc"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/btc/mocks"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+var _ = Describe("Validator", func() {
+	var (
+		db        *postgres.DB
+		err       error
+		validator *btc.Validator
+		blockHash = mocks.MockBlock.Header.BlockHash().String()
+	)
+	BeforeEach(func() {
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = btc.NewIPLDPublisherAndIndexer(db).Publish(mocks.MockConvertedPayload)
+		Expect(err).ToNot(HaveOccurred())
+		validator = btc.NewValidator(db)
+	})
+	AfterEach(func() {
+		btc.TearDownDB(db)
+	})
+
+	It("Finds no mismatches for a block indexed from the same payload, and marks it validated", func() {
+		mismatches, err := validator.Validate(mocks.MockConvertedPayload)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(mismatches)).To(Equal(0))
+		var timesValidated int
+		err = db.Get(&timesValidated, `SELECT times_validated FROM btc.header_cids WHERE block_hash = $1`, blockHash)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(timesValidated).To(Equal(2))
+	})
+
+	It("Rewrites the transactions that differ and inserts those that are missing, and audits them", func() {
+		changedHash := mocks.MockTxsMetaData[1].TxHash
+		missingHash := mocks.MockTxsMetaData[2].TxHash
+		_, err = db.Exec(`UPDATE btc.transaction_cids SET index = 9 WHERE tx_hash = $1`, changedHash)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = db.Exec(`DELETE FROM btc.transaction_cids WHERE tx_hash = $1`, missingHash)
+		Expect(err).ToNot(HaveOccurred())
+		mismatches, err := validator.Validate(mocks.MockConvertedPayload)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(mismatches)).To(Equal(2))
+		Expect(mismatches[0]).To(Equal(shared.Mismatch{
+			BlockNumber: uint64(mocks.MockBlockHeight),
+			BlockHash:   blockHash,
+			Object:      "transaction",
+			Key:         changedHash,
+			Field:       "index",
+			Indexed:     "9",
+			Fetched:     "1",
+			Action:      shared.RewrittenAction,
+		}))
+		Expect(mismatches[1].Key).To(Equal(missingHash))
+		Expect(mismatches[1].Action).To(Equal(shared.InsertedAction))
+		audited := make([]shared.Mismatch, 0)
+		err = db.Select(&audited, `SELECT block_number, block_hash, object, object_key, field, indexed, fetched, action
+									FROM btc.validation_audit ORDER BY id`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(audited).To(Equal(mismatches))
+		txs := make([]btc.TxModel, 0)
+		err = db.Select(&txs, `SELECT id, header_id, index, tx_hash, cid, segwit, witness_hash FROM btc.transaction_cids ORDER BY index`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(txs)).To(Equal(len(mocks.MockTxsMetaData)))
+		for i, trx := range txs {
+			Expect(trx.TxHash).To(Equal(mocks.MockTxsMetaData[i].TxHash))
+			Expect(trx.Index).To(Equal(int64(i)))
+		}
+	})
+
+	It("Removes transactions that are not part of the block", func() {
+		_, err = db.Exec(`INSERT INTO btc.transaction_cids (header_id, index, tx_hash, cid, segwit, witness_hash)
+							SELECT id, 9, 'bad', 'mockCID', false, '' FROM btc.header_cids`)
+		Expect(err).ToNot(HaveOccurred())
+		mismatches, err := validator.Validate(mocks.MockConvertedPayload)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(mismatches)).To(Equal(1))
+		Expect(mismatches[0].Key).To(Equal("bad"))
+		Expect(mismatches[0].Indexed).To(Equal("mockCID"))
+		Expect(mismatches[0].Action).To(Equal(shared.RemovedAction))
+		var txs int
+		err = db.Get(&txs, `SELECT COUNT(*) FROM btc.transaction_cids`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(txs).To(Equal(len(mocks.MockTxsMetaData)))
+	})
+})
@@ -0,0 +1,167 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package btc
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/vulcanize/vulcanizedb/pkg/postgres"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+// Verifier satisfies the IPLDVerifier interface for bitcoin
+type Verifier struct {
+	db        *postgres.DB
+	retriever *CIDRetriever
+}
+
+// NewVerifier creates a pointer to a new Verifier
+func NewVerifier(db *postgres.DB) *Verifier {
+	return &Verifier{
+		db:        db,
+		retriever: NewCIDRetriever(db),
+	}
+}
+
+// Verify checks every IPLD referenced by the btc cid tables for the headers at the provided height against the multihash of its CID
+// The header IPLD is then checked against the header row, and its merkle root against the root derived from the tx IPLDs
+func (v *Verifier) Verify(blockNumber uint64) (faults []shared.Fault, err error) {
+	tx, err := v.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+	headers := make([]HeaderModel, 0)
+	pgStr := `SELECT * FROM btc.header_cids
+			WHERE block_number = $1
+			ORDER BY id`
+	if err = tx.Select(&headers, pgStr, blockNumber); err != nil {
+		return nil, err
+	}
+	faults = make([]shared.Fault, 0)
+	for _, header := range headers {
+		bv := &blockVerification{
+			tx:          tx,
+			blockNumber: blockNumber,
+			header:      header,
+		}
+		if err = v.verifyBlock(bv); err != nil {
+			return nil, err
+		}
+		faults = append(faults, bv.faults...)
+	}
+	return faults, nil
+}
+
+func (v *Verifier) verifyBlock(bv *blockVerification) error {
+	data, ok, err := bv.fetch(bv.header.CID)
+	if err != nil {
+		return err
+	}
+	var header *wire.BlockHeader
+	if ok {
+		header = new(wire.BlockHeader)
+		if err := header.Deserialize(bytes.NewReader(data)); err != nil {
+			bv.fault(bv.header.CID, fmt.Sprintf("unable to decode header: %v", err))
+			header = nil
+		} else if header.BlockHash().String() != bv.header.BlockHash {
+			bv.fault(bv.header.CID, fmt.Sprintf("header IPLD hashes to block %s", header.BlockHash().String()))
+		}
+	}
+	txCIDs, err := v.retriever.RetrieveTxCIDsByHeaderID(bv.tx, bv.header.ID)
+	if err != nil {
+		return err
+	}
+	trxs := make([]*btcutil.Tx, 0, len(txCIDs))
+	for _, txCID := range txCIDs {
+		data, ok, err := bv.fetch(txCID.CID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		msgTx := new(wire.MsgTx)
+		if err := msgTx.Deserialize(bytes.NewReader(data)); err != nil {
+			bv.fault(txCID.CID, fmt.Sprintf("unable to decode transaction: %v", err))
+			continue
+		}
+		trxs = append(trxs, btcutil.NewTx(msgTx))
+	}
+	if header == nil || len(trxs) != len(txCIDs) {
+		return nil
+	}
+	if len(trxs) == 0 {
+		bv.fault("", "no transactions are indexed for the block")
+		return nil
+	}
+	merkles := blockchain.BuildMerkleTreeStore(trxs, false)
+	if root := merkles[len(merkles)-1]; !root.IsEqual(&header.MerkleRoot) {
+		bv.fault("", fmt.Sprintf("merkle root %s of the header IPLD does not match the root derived from the transactions %s", header.MerkleRoot.String(), root.String()))
+	}
+	return nil
+}
+
+// blockVerification collects the faults found while verifying the IPLDs of a single header
+type blockVerification struct {
+	tx          *sqlx.Tx
+	blockNumber uint64
+	header      HeaderModel
+	faults      []shared.Fault
+}
+
+func (bv *blockVerification) fault(c, reason string) {
+	bv.faults = append(bv.faults, shared.Fault{
+		BlockNumber: bv.blockNumber,
+		BlockHash:   bv.header.BlockHash,
+		CID:         c,
+		Reason:      reason,
+	})
+}
+
+// fetch retrieves an IPLD from public.blocks and verifies it against its CID, the data is only returned if it is intact
+func (bv *blockVerification) fetch(c string) ([]byte, bool, error) {
+	data, err := shared.FetchIPLD(bv.tx, c)
+	if err == sql.ErrNoRows {
+		bv.fault(c, "IPLD is missing from public.blocks")
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if err := shared.VerifyIPLD(c, data); err != nil {
+		bv.fault(c, err.Error())
+		return nil, false, nil
+	}
+	return data, true, nil
+}
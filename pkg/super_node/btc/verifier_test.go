@@ -0,0 +1,104 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package btc_test
+
+import (
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/vulcanizedb/pkg/postgres"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/btc"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/btc/mocks"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+var _ = Describe("Verifier", func() {
+	var (
+		db       *postgres.DB
+		err      error
+		verifier *btc.Verifier
+		header   btc.HeaderModel
+	)
+	BeforeEach(func() {
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+		// the mock block only holds some of the transactions of the real block, so give it the merkle root of those it holds
+		mockHeader := mocks.MockBlock.Header
+		store := blockchain.BuildMerkleTreeStore(mocks.MockTransactions, false)
+		mockHeader.MerkleRoot = *store[len(store)-1]
+		converted, err := btc.NewPayloadConverter(&chaincfg.MainNetParams).Convert(btc.BlockPayload{
+			Header:      &mockHeader,
+			Txs:         mocks.MockTransactions,
+			BlockHeight: mocks.MockBlockHeight,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = btc.NewIPLDPublisherAndIndexer(db).Publish(converted)
+		Expect(err).ToNot(HaveOccurred())
+		err = db.Get(&header, `SELECT * FROM btc.header_cids WHERE block_number = $1`, mocks.MockBlockHeight)
+		Expect(err).ToNot(HaveOccurred())
+		verifier = btc.NewVerifier(db)
+	})
+	AfterEach(func() {
+		btc.TearDownDB(db)
+	})
+
+	It("Finds no faults in an intact block", func() {
+		faults, err := verifier.Verify(uint64(mocks.MockBlockHeight))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(faults)).To(Equal(0))
+	})
+
+	It("Reports a header IPLD whose data does not match its cid", func() {
+		key, err := shared.MultihashKeyFromCIDString(header.CID)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = db.Exec(`UPDATE public.blocks SET data = $1 WHERE key = $2`, []byte("corrupted"), key)
+		Expect(err).ToNot(HaveOccurred())
+		faults, err := verifier.Verify(uint64(mocks.MockBlockHeight))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(faults)).To(Equal(1))
+		Expect(faults[0].BlockNumber).To(Equal(uint64(mocks.MockBlockHeight)))
+		Expect(faults[0].BlockHash).To(Equal(header.BlockHash))
+		Expect(faults[0].CID).To(Equal(header.CID))
+	})
+
+	It("Reports transaction IPLDs missing from public.blocks", func() {
+		var txCID string
+		err = db.Get(&txCID, `SELECT cid FROM btc.transaction_cids WHERE index = 1`)
+		Expect(err).ToNot(HaveOccurred())
+		key, err := shared.MultihashKeyFromCIDString(txCID)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = db.Exec(`DELETE FROM public.blocks WHERE key = $1`, key)
+		Expect(err).ToNot(HaveOccurred())
+		faults, err := verifier.Verify(uint64(mocks.MockBlockHeight))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(faults)).To(Equal(1))
+		Expect(faults[0].CID).To(Equal(txCID))
+		Expect(faults[0].Reason).To(ContainSubstring("missing"))
+	})
+
+	It("Reports a merkle root that does not match the stored transactions", func() {
+		_, err = db.Exec(`DELETE FROM btc.transaction_cids WHERE index = 2`)
+		Expect(err).ToNot(HaveOccurred())
+		faults, err := verifier.Verify(uint64(mocks.MockBlockHeight))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(faults)).To(Equal(1))
+		Expect(faults[0].CID).To(BeEmpty())
+		Expect(faults[0].Reason).To(ContainSubstring("merkle root"))
+	})
+})
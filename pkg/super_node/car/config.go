@@ -0,0 +1,109 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package car
+
+import (
+	"errors"
+
+	"github.com/spf13/viper"
+
+	"github.com/vulcanize/vulcanizedb/pkg/config"
+	"github.com/vulcanize/vulcanizedb/pkg/eth/core"
+	"github.com/vulcanize/vulcanizedb/pkg/ipfs"
+	"github.com/vulcanize/vulcanizedb/pkg/postgres"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+	"github.com/vulcanize/vulcanizedb/utils"
+)
+
+// Env variables
+const (
+	CAR_CHAIN = "CAR_CHAIN"
+	CAR_FILE  = "CAR_FILE"
+	CAR_START = "CAR_START"
+	CAR_STOP  = "CAR_STOP"
+)
+
+// Config holds the parameters needed to export or import a CAR file
+type Config struct {
+	Chain shared.ChainType // The chain whose data is exported or imported
+	File  string           // Path of the CAR file
+	Start uint64           // First block height to export
+	Stop  uint64           // Last block height to export
+
+	// DB info
+	DB       *postgres.DB
+	DBConfig config.Database
+	IPFSPath string
+	IPFSMode shared.IPFSMode
+	// Settings for the client of the remote ipfs node, used in the remote ipfs mode
+	IPFSRemoteConfig ipfs.RemoteClientConfig
+
+	NodeInfo core.Node // Info for the node that the imported headers are attributed to
+}
+
+// NewCARConfig fills and returns a CAR config from toml parameters
+func NewCARConfig() (*Config, error) {
+	c := new(Config)
+	var err error
+
+	viper.BindEnv("car.chain", CAR_CHAIN)
+	viper.BindEnv("car.file", CAR_FILE)
+	viper.BindEnv("car.start", CAR_START)
+	viper.BindEnv("car.stop", CAR_STOP)
+
+	c.File = viper.GetString("car.file")
+	if c.File == "" {
+		return nil, errors.New("a CAR file path is required")
+	}
+	c.Start = uint64(viper.GetInt64("car.start"))
+	c.Stop = uint64(viper.GetInt64("car.stop"))
+
+	c.IPFSMode, err = shared.GetIPFSMode()
+	if err != nil {
+		return nil, err
+	}
+	switch c.IPFSMode {
+	case shared.LocalInterface:
+		c.IPFSPath, err = shared.GetIPFSPath()
+		if err != nil {
+			return nil, err
+		}
+	case shared.RemoteClient:
+		c.IPFSRemoteConfig, err = shared.GetIPFSRemoteConfig()
+		if err != nil {
+			return nil, err
+		}
+	}
+	chain := viper.GetString("car.chain")
+	c.Chain, err = shared.NewChainType(chain)
+	if err != nil {
+		return nil, err
+	}
+	switch c.Chain {
+	case shared.Ethereum:
+		c.NodeInfo = shared.GetEthNodeInfo()
+	case shared.Bitcoin:
+		c.NodeInfo, _ = shared.GetBtcNodeAndClient(viper.GetString("bitcoin.httpPath"))
+	default:
+		return nil, errors.New("CAR files can only be exported and imported for the ethereum and bitcoin chains")
+	}
+
+	c.DBConfig.Init()
+	db := utils.LoadPostgres(c.DBConfig, c.NodeInfo)
+	c.DB = &db
+	return c, nil
+}
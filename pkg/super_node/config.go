@@ -20,12 +20,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
 
 	"github.com/vulcanize/vulcanizedb/pkg/config"
 	"github.com/vulcanize/vulcanizedb/pkg/eth/core"
+	"github.com/vulcanize/vulcanizedb/pkg/ipfs"
 	"github.com/vulcanize/vulcanizedb/pkg/postgres"
 	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
 	"github.com/vulcanize/vulcanizedb/utils"
@@ -34,6 +36,7 @@ import (
 // Env variables
 const (
 	SUPERNODE_CHAIN            = "SUPERNODE_CHAIN"
+	SUPERNODE_CHAINS           = "SUPERNODE_CHAINS"
 	SUPERNODE_SYNC             = "SUPERNODE_SYNC"
 	SUPERNODE_WORKERS          = "SUPERNODE_WORKERS"
 	SUPERNODE_SERVER           = "SUPERNODE_SERVER"
@@ -45,6 +48,10 @@ const (
 	SUPERNODE_BATCH_SIZE       = "SUPERNODE_BATCH_SIZE"
 	SUPERNODE_BATCH_NUMBER     = "SUPERNODE_BATCH_NUMBER"
 	SUPERNODE_VALIDATION_LEVEL = "SUPERNODE_VALIDATION_LEVEL"
+	SUPERNODE_BULK_INDEX       = "SUPERNODE_BULK_INDEX"
+	SUPERNODE_METRICS_PATH     = "SUPERNODE_METRICS_PATH"
+	SUPERNODE_GRAPHQL_PATH     = "SUPERNODE_GRAPHQL_PATH"
+	SUPERNODE_VERIFY_IPLDS     = "SUPERNODE_VERIFY_IPLDS"
 
 	SYNC_MAX_IDLE_CONNECTIONS = "SYNC_MAX_IDLE_CONNECTIONS"
 	SYNC_MAX_OPEN_CONNECTIONS = "SYNC_MAX_OPEN_CONNECTIONS"
@@ -65,13 +72,25 @@ type Config struct {
 	Chain    shared.ChainType
 	IPFSPath string
 	IPFSMode shared.IPFSMode
-	DBConfig config.Database
+	// Settings for the client of the remote ipfs node, used in the remote ipfs mode
+	IPFSRemoteConfig ipfs.RemoteClientConfig
+	DBConfig         config.Database
+	// Chain config (*params.ChainConfig or *chaincfg.Params) selected by network id or genesis file
+	ChainConfig interface{}
+	// Register the vdb and admin APIs under chain-specific namespaces, set when several chains are served from one process
+	ChainNamespaces bool
+	// Endpoint the /metrics http endpoint is served on, metrics are not served if this is empty
+	MetricsEndpoint string
 	// Server fields
 	Serve        bool
 	ServeDBConn  *postgres.DB
 	WSEndpoint   string
 	HTTPEndpoint string
 	IPCEndpoint  string
+	// Endpoint the /graphql http endpoint is served on, graphql is not served if this is empty
+	GraphQLEndpoint string
+	// Verify the IPLDs fetched from public.blocks against their CIDs before serving them
+	VerifyIPLDs bool
 	// Sync params
 	Sync       bool
 	SyncDBConn *postgres.DB
@@ -87,15 +106,74 @@ type Config struct {
 	BatchNumber     uint64
 	ValidationLevel int
 	Timeout         time.Duration // HTTP connection timeout in seconds
+	BulkIndex       bool          // Publish and index each batch of backfilled blocks together with COPY
+}
+
+// NewSuperNodeConfigs is used to initialize a SuperNode config for each of the chains listed in superNode.chains
+// If no chains are listed it falls back to the single superNode.chain
+// Chains run in one process would share the ipfs repository so they need to use the postgres or remote ipfs mode
+func NewSuperNodeConfigs() ([]*Config, error) {
+	viper.BindEnv("superNode.chains", SUPERNODE_CHAINS)
+	var names []string
+	for _, name := range viper.GetStringSlice("superNode.chains") {
+		for _, n := range strings.Split(name, ",") {
+			if n = strings.TrimSpace(n); n != "" {
+				names = append(names, n)
+			}
+		}
+	}
+	if len(names) == 0 {
+		c, err := NewSuperNodeConfig()
+		if err != nil {
+			return nil, err
+		}
+		return []*Config{c}, nil
+	}
+	configs := make([]*Config, 0, len(names))
+	seen := make(map[shared.ChainType]bool, len(names))
+	for _, name := range names {
+		chain, err := shared.NewChainType(name)
+		if err != nil {
+			return nil, err
+		}
+		if seen[chain] {
+			return nil, fmt.Errorf("chain %s is listed more than once", chain.String())
+		}
+		seen[chain] = true
+		if seen[shared.Bitcoin] && seen[shared.Omni] {
+			// omni super nodes index the bitcoin chain into the btc tables themselves
+			return nil, fmt.Errorf("chains %s and %s can not be run together, %s indexes the bitcoin data too", shared.Bitcoin.String(), shared.Omni.String(), shared.Omni.String())
+		}
+		c, err := newSuperNodeConfig(chain)
+		if err != nil {
+			return nil, err
+		}
+		if len(names) > 1 {
+			if c.IPFSMode == shared.LocalInterface {
+				return nil, fmt.Errorf("running several chains in one process requires the %s or %s ipfs mode, have %s", shared.DirectPostgres.String(), shared.RemoteClient.String(), c.IPFSMode.String())
+			}
+			c.ChainNamespaces = true
+		}
+		configs = append(configs, c)
+	}
+	return configs, nil
 }
 
 // NewSuperNodeConfig is used to initialize a SuperNode config from a .toml file
 // Separate chain supernode instances need to be ran with separate ipfs path in order to avoid lock contention on the ipfs repository lockfile
 func NewSuperNodeConfig() (*Config, error) {
+	viper.BindEnv("superNode.chain", SUPERNODE_CHAIN)
+	chain, err := shared.NewChainType(viper.GetString("superNode.chain"))
+	if err != nil {
+		return nil, err
+	}
+	return newSuperNodeConfig(chain)
+}
+
+func newSuperNodeConfig(chain shared.ChainType) (*Config, error) {
 	c := new(Config)
 	var err error
 
-	viper.BindEnv("superNode.chain", SUPERNODE_CHAIN)
 	viper.BindEnv("superNode.sync", SUPERNODE_SYNC)
 	viper.BindEnv("superNode.workers", SUPERNODE_WORKERS)
 	viper.BindEnv("ethereum.wsPath", shared.ETH_WS_PATH)
@@ -105,9 +183,20 @@ func NewSuperNodeConfig() (*Config, error) {
 	viper.BindEnv("superNode.ipcPath", SUPERNODE_IPC_PATH)
 	viper.BindEnv("superNode.httpPath", SUPERNODE_HTTP_PATH)
 	viper.BindEnv("superNode.backFill", SUPERNODE_BACKFILL)
+	viper.BindEnv("superNode.metricsPath", SUPERNODE_METRICS_PATH)
+	viper.BindEnv("superNode.graphqlPath", SUPERNODE_GRAPHQL_PATH)
+	viper.BindEnv("superNode.verifyIPLDs", SUPERNODE_VERIFY_IPLDS)
+	viper.BindEnv("superNode.timeout", shared.HTTP_TIMEOUT)
+
+	// The timeout applies to the requests for contract code made while syncing as well as to backfilling
+	timeout := viper.GetInt("superNode.timeout")
+	if timeout < 15 {
+		timeout = 15
+	}
+	c.Timeout = time.Second * time.Duration(timeout)
 
-	chain := viper.GetString("superNode.chain")
-	c.Chain, err = shared.NewChainType(chain)
+	c.Chain = chain
+	c.ChainConfig, err = shared.GetChainConfig(c.Chain)
 	if err != nil {
 		return nil, err
 	}
@@ -116,15 +205,23 @@ func NewSuperNodeConfig() (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
-	if c.IPFSMode == shared.LocalInterface || c.IPFSMode == shared.RemoteClient {
+	switch c.IPFSMode {
+	case shared.LocalInterface:
 		c.IPFSPath, err = shared.GetIPFSPath()
 		if err != nil {
 			return nil, err
 		}
+	case shared.RemoteClient:
+		c.IPFSRemoteConfig, err = shared.GetIPFSRemoteConfig()
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	c.DBConfig.Init()
 
+	c.MetricsEndpoint = viper.GetString("superNode.metricsPath")
+
 	c.Sync = viper.GetBool("superNode.sync")
 	if c.Sync {
 		workers := viper.GetInt("superNode.workers")
@@ -139,7 +236,7 @@ func NewSuperNodeConfig() (*Config, error) {
 			if err != nil {
 				return nil, err
 			}
-		case shared.Bitcoin:
+		case shared.Bitcoin, shared.Omni:
 			btcWS := viper.GetString("bitcoin.wsPath")
 			c.NodeInfo, c.WSClient = shared.GetBtcNodeAndClient(btcWS)
 		}
@@ -169,6 +266,8 @@ func NewSuperNodeConfig() (*Config, error) {
 			httpPath = "127.0.0.1:8081"
 		}
 		c.HTTPEndpoint = httpPath
+		c.GraphQLEndpoint = viper.GetString("superNode.graphqlPath")
+		c.VerifyIPLDs = viper.GetBool("superNode.verifyIPLDs")
 		serveDBConn := overrideDBConnConfig(c.DBConfig, Serve)
 		serveDB := utils.LoadPostgres(serveDBConn, c.NodeInfo)
 		c.ServeDBConn = &serveDB
@@ -194,13 +293,7 @@ func (c *Config) BackFillFields() error {
 	viper.BindEnv("superNode.batchSize", SUPERNODE_BATCH_SIZE)
 	viper.BindEnv("superNode.batchNumber", SUPERNODE_BATCH_NUMBER)
 	viper.BindEnv("superNode.validationLevel", SUPERNODE_VALIDATION_LEVEL)
-	viper.BindEnv("superNode.timeout", shared.HTTP_TIMEOUT)
-
-	timeout := viper.GetInt("superNode.timeout")
-	if timeout < 15 {
-		timeout = 15
-	}
-	c.Timeout = time.Second * time.Duration(timeout)
+	viper.BindEnv("superNode.bulkIndex", SUPERNODE_BULK_INDEX)
 
 	switch c.Chain {
 	case shared.Ethereum:
@@ -209,7 +302,7 @@ func (c *Config) BackFillFields() error {
 		if err != nil {
 			return err
 		}
-	case shared.Bitcoin:
+	case shared.Bitcoin, shared.Omni:
 		btcHTTP := viper.GetString("bitcoin.httpPath")
 		c.NodeInfo, c.HTTPClient = shared.GetBtcNodeAndClient(btcHTTP)
 	}
@@ -225,6 +318,7 @@ func (c *Config) BackFillFields() error {
 	c.BatchSize = uint64(viper.GetInt64("superNode.batchSize"))
 	c.BatchNumber = uint64(viper.GetInt64("superNode.batchNumber"))
 	c.ValidationLevel = viper.GetInt("superNode.validationLevel")
+	c.BulkIndex = viper.GetBool("superNode.bulkIndex")
 
 	backFillDBConn := overrideDBConnConfig(c.DBConfig, BackFill)
 	backFillDB := utils.LoadPostgres(backFillDBConn, c.NodeInfo)
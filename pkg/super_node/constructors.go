@@ -23,11 +23,14 @@ import (
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/rpcclient"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
 
+	"github.com/vulcanize/vulcanizedb/pkg/ipfs"
 	"github.com/vulcanize/vulcanizedb/pkg/postgres"
 	"github.com/vulcanize/vulcanizedb/pkg/super_node/btc"
 	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/omni"
 	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
 )
 
@@ -38,6 +41,8 @@ func NewResponseFilterer(chain shared.ChainType) (shared.ResponseFilterer, error
 		return eth.NewResponseFilterer(), nil
 	case shared.Bitcoin:
 		return btc.NewResponseFilterer(), nil
+	case shared.Omni:
+		return omni.NewResponseFilterer(), nil
 	default:
 		return nil, fmt.Errorf("invalid chain %s for filterer constructor", chain.String())
 	}
@@ -64,11 +69,36 @@ func NewCIDIndexer(chain shared.ChainType, db *postgres.DB, ipfsMode shared.IPFS
 		default:
 			return nil, fmt.Errorf("bitcoin CIDIndexer unexpected ipfs mode %s", ipfsMode.String())
 		}
+	case shared.Omni:
+		switch ipfsMode {
+		case shared.LocalInterface, shared.RemoteClient:
+			return omni.NewCIDIndexer(db), nil
+		case shared.DirectPostgres:
+			return omni.NewIPLDPublisherAndIndexer(db), nil
+		default:
+			return nil, fmt.Errorf("omni CIDIndexer unexpected ipfs mode %s", ipfsMode.String())
+		}
 	default:
 		return nil, fmt.Errorf("invalid chain %s for indexer constructor", chain.String())
 	}
 }
 
+// NewBulkPublisherAndIndexer constructs a BulkPublisherAndIndexer for the provided chain type
+// Bulk indexing writes the IPLDs straight to the public.blocks table so it is only supported in the postgres ipfs mode
+func NewBulkPublisherAndIndexer(chain shared.ChainType, db *postgres.DB, ipfsMode shared.IPFSMode) (shared.BulkPublisherAndIndexer, error) {
+	switch chain {
+	case shared.Ethereum:
+		switch ipfsMode {
+		case shared.DirectPostgres:
+			return eth.NewBulkIndexer(db), nil
+		default:
+			return nil, fmt.Errorf("ethereum BulkPublisherAndIndexer unexpected ipfs mode %s, bulk indexing requires the %s mode", ipfsMode.String(), shared.DirectPostgres.String())
+		}
+	default:
+		return nil, fmt.Errorf("invalid chain %s for bulk publisher and indexer constructor", chain.String())
+	}
+}
+
 // NewCIDRetriever constructs a CIDRetriever for the provided chain type
 func NewCIDRetriever(chain shared.ChainType, db *postgres.DB) (shared.CIDRetriever, error) {
 	switch chain {
@@ -76,6 +106,8 @@ func NewCIDRetriever(chain shared.ChainType, db *postgres.DB) (shared.CIDRetriev
 		return eth.NewCIDRetriever(db), nil
 	case shared.Bitcoin:
 		return btc.NewCIDRetriever(db), nil
+	case shared.Omni:
+		return omni.NewCIDRetriever(db), nil
 	default:
 		return nil, fmt.Errorf("invalid chain %s for retriever constructor", chain.String())
 	}
@@ -91,7 +123,7 @@ func NewPayloadStreamer(chain shared.ChainType, clientOrConfig interface{}) (sha
 		}
 		streamChan := make(chan shared.RawChainData, eth.PayloadChanBufferSize)
 		return eth.NewPayloadStreamer(ethClient), streamChan, nil
-	case shared.Bitcoin:
+	case shared.Bitcoin, shared.Omni:
 		btcClientConn, ok := clientOrConfig.(*rpcclient.ConnConfig)
 		if !ok {
 			return nil, nil, fmt.Errorf("bitcoin payload streamer constructor expected client config type %T got %T", rpcclient.ConnConfig{}, clientOrConfig)
@@ -112,7 +144,7 @@ func NewPaylaodFetcher(chain shared.ChainType, client interface{}, timeout time.
 			return nil, fmt.Errorf("ethereum payload fetcher constructor expected client type %T got %T", &rpc.Client{}, client)
 		}
 		return eth.NewPayloadFetcher(batchClient, timeout), nil
-	case shared.Bitcoin:
+	case shared.Bitcoin, shared.Omni:
 		connConfig, ok := client.(*rpcclient.ConnConfig)
 		if !ok {
 			return nil, fmt.Errorf("bitcoin payload fetcher constructor expected client type %T got %T", &rpcclient.Client{}, client)
@@ -124,35 +156,68 @@ func NewPaylaodFetcher(chain shared.ChainType, client interface{}, timeout time.
 }
 
 // NewPayloadConverter constructs a PayloadConverter for the provided chain type
-func NewPayloadConverter(chain shared.ChainType) (shared.PayloadConverter, error) {
+// The client is only used for Ethereum, to fetch the contract code that statediffs do not carry; no code is fetched if it is nil
+func NewPayloadConverter(chain shared.ChainType, chainConfig interface{}, client interface{}, timeout time.Duration) (shared.PayloadConverter, error) {
 	switch chain {
 	case shared.Ethereum:
-		return eth.NewPayloadConverter(params.MainnetChainConfig), nil
+		ethConfig, ok := chainConfig.(*params.ChainConfig)
+		if !ok {
+			return nil, fmt.Errorf("ethereum converter constructor expected config type %T got %T", &params.ChainConfig{}, chainConfig)
+		}
+		converter := eth.NewPayloadConverter(ethConfig)
+		if client != nil {
+			batchClient, ok := client.(*rpc.Client)
+			if !ok {
+				return nil, fmt.Errorf("ethereum converter constructor expected client type %T got %T", &rpc.Client{}, client)
+			}
+			converter.CodeFetcher = eth.NewCodeFetcher(batchClient, timeout)
+		}
+		return converter, nil
 	case shared.Bitcoin:
-		return btc.NewPayloadConverter(&chaincfg.MainNetParams), nil
+		btcParams, ok := chainConfig.(*chaincfg.Params)
+		if !ok {
+			return nil, fmt.Errorf("bitcoin converter constructor expected config type %T got %T", &chaincfg.Params{}, chainConfig)
+		}
+		return btc.NewPayloadConverter(btcParams), nil
+	case shared.Omni:
+		btcParams, ok := chainConfig.(*chaincfg.Params)
+		if !ok {
+			return nil, fmt.Errorf("omni converter constructor expected config type %T got %T", &chaincfg.Params{}, chainConfig)
+		}
+		return omni.NewPayloadConverter(btcParams), nil
 	default:
 		return nil, fmt.Errorf("invalid chain %s for converter constructor", chain.String())
 	}
 }
 
 // NewIPLDFetcher constructs an IPLDFetcher for the provided chain type
-func NewIPLDFetcher(chain shared.ChainType, ipfsPath string, db *postgres.DB, ipfsMode shared.IPFSMode) (shared.IPLDFetcher, error) {
+// The remote config is only used in the remote ipfs mode
+// verify only applies to the DirectPostgres ipfs mode, blocks fetched through IPFS are already verified against their CIDs
+func NewIPLDFetcher(chain shared.ChainType, ipfsPath string, remoteConfig ipfs.RemoteClientConfig, db *postgres.DB, ipfsMode shared.IPFSMode, verify bool) (shared.IPLDFetcher, error) {
 	switch chain {
 	case shared.Ethereum:
 		switch ipfsMode {
-		case shared.LocalInterface, shared.RemoteClient:
+		case shared.LocalInterface:
 			return eth.NewIPLDFetcher(ipfsPath)
+		case shared.RemoteClient:
+			return eth.NewRemoteIPLDFetcher(remoteConfig), nil
 		case shared.DirectPostgres:
-			return eth.NewIPLDPGFetcher(db), nil
+			fetcher := eth.NewIPLDPGFetcher(db)
+			fetcher.Verify = verify
+			return fetcher, nil
 		default:
 			return nil, fmt.Errorf("ethereum IPLDFetcher unexpected ipfs mode %s", ipfsMode.String())
 		}
-	case shared.Bitcoin:
+	case shared.Bitcoin, shared.Omni:
 		switch ipfsMode {
-		case shared.LocalInterface, shared.RemoteClient:
+		case shared.LocalInterface:
 			return btc.NewIPLDFetcher(ipfsPath)
+		case shared.RemoteClient:
+			return btc.NewRemoteIPLDFetcher(remoteConfig), nil
 		case shared.DirectPostgres:
-			return btc.NewIPLDPGFetcher(db), nil
+			fetcher := btc.NewIPLDPGFetcher(db)
+			fetcher.Verify = verify
+			return fetcher, nil
 		default:
 			return nil, fmt.Errorf("bitcoin IPLDFetcher unexpected ipfs mode %s", ipfsMode.String())
 		}
@@ -162,12 +227,15 @@ func NewIPLDFetcher(chain shared.ChainType, ipfsPath string, db *postgres.DB, ip
 }
 
 // NewIPLDPublisher constructs an IPLDPublisher for the provided chain type
-func NewIPLDPublisher(chain shared.ChainType, ipfsPath string, db *postgres.DB, ipfsMode shared.IPFSMode) (shared.IPLDPublisher, error) {
+// The remote config is only used in the remote ipfs mode
+func NewIPLDPublisher(chain shared.ChainType, ipfsPath string, remoteConfig ipfs.RemoteClientConfig, db *postgres.DB, ipfsMode shared.IPFSMode) (shared.IPLDPublisher, error) {
 	switch chain {
 	case shared.Ethereum:
 		switch ipfsMode {
-		case shared.LocalInterface, shared.RemoteClient:
+		case shared.LocalInterface:
 			return eth.NewIPLDPublisher(ipfsPath)
+		case shared.RemoteClient:
+			return eth.NewRemoteIPLDPublisher(remoteConfig), nil
 		case shared.DirectPostgres:
 			return eth.NewIPLDPublisherAndIndexer(db), nil
 		default:
@@ -175,23 +243,40 @@ func NewIPLDPublisher(chain shared.ChainType, ipfsPath string, db *postgres.DB,
 		}
 	case shared.Bitcoin:
 		switch ipfsMode {
-		case shared.LocalInterface, shared.RemoteClient:
+		case shared.LocalInterface:
 			return btc.NewIPLDPublisher(ipfsPath)
+		case shared.RemoteClient:
+			return btc.NewRemoteIPLDPublisher(remoteConfig), nil
 		case shared.DirectPostgres:
 			return btc.NewIPLDPublisherAndIndexer(db), nil
 		default:
 			return nil, fmt.Errorf("bitcoin IPLDPublisher unexpected ipfs mode %s", ipfsMode.String())
 		}
+	case shared.Omni:
+		switch ipfsMode {
+		case shared.LocalInterface:
+			return omni.NewIPLDPublisher(ipfsPath)
+		case shared.RemoteClient:
+			return omni.NewRemoteIPLDPublisher(remoteConfig), nil
+		case shared.DirectPostgres:
+			return omni.NewIPLDPublisherAndIndexer(db), nil
+		default:
+			return nil, fmt.Errorf("omni IPLDPublisher unexpected ipfs mode %s", ipfsMode.String())
+		}
 	default:
 		return nil, fmt.Errorf("invalid chain %s for publisher constructor", chain.String())
 	}
 }
 
 // NewPublicAPI constructs a PublicAPI for the provided chain type
-func NewPublicAPI(chain shared.ChainType, db *postgres.DB, ipfsPath string) (rpc.API, error) {
+func NewPublicAPI(chain shared.ChainType, db *postgres.DB, ipfsPath string, chainConfig interface{}) (rpc.API, error) {
 	switch chain {
 	case shared.Ethereum:
-		backend, err := eth.NewEthBackend(db)
+		ethConfig, ok := chainConfig.(*params.ChainConfig)
+		if !ok {
+			return rpc.API{}, fmt.Errorf("ethereum public api constructor expected config type %T got %T", &params.ChainConfig{}, chainConfig)
+		}
+		backend, err := eth.NewEthBackend(db, ethConfig)
 		if err != nil {
 			return rpc.API{}, err
 		}
@@ -201,19 +286,144 @@ func NewPublicAPI(chain shared.ChainType, db *postgres.DB, ipfsPath string) (rpc
 			Service:   eth.NewPublicEthAPI(backend),
 			Public:    true,
 		}, nil
+	case shared.Bitcoin, shared.Omni:
+		btcParams, ok := chainConfig.(*chaincfg.Params)
+		if !ok {
+			return rpc.API{}, fmt.Errorf("bitcoin public api constructor expected config type %T got %T", &chaincfg.Params{}, chainConfig)
+		}
+		backend, err := btc.NewBtcBackend(db, btcParams)
+		if err != nil {
+			return rpc.API{}, err
+		}
+		return rpc.API{
+			Namespace: btc.APIName,
+			Version:   btc.APIVersion,
+			Service:   btc.NewPublicBtcAPI(backend),
+			Public:    true,
+		}, nil
 	default:
 		return rpc.API{}, fmt.Errorf("invalid chain %s for public api constructor", chain.String())
 	}
 }
 
 // NewCleaner constructs a Cleaner for the provided chain type
-func NewCleaner(chain shared.ChainType, db *postgres.DB) (shared.Cleaner, error) {
+// detachPartitions only applies to Ethereum, whose index tables are partitioned by block range
+func NewCleaner(chain shared.ChainType, db *postgres.DB, detachPartitions bool) (shared.Cleaner, error) {
 	switch chain {
 	case shared.Ethereum:
-		return eth.NewCleaner(db), nil
-	case shared.Bitcoin:
+		cleaner := eth.NewCleaner(db)
+		cleaner.DetachPartitions = detachPartitions
+		return cleaner, nil
+	case shared.Bitcoin, shared.Omni:
 		return btc.NewCleaner(db), nil
 	default:
 		return nil, fmt.Errorf("invalid chain %s for cleaner constructor", chain.String())
 	}
 }
+
+// NewSpillQueue constructs a SpillQueue for the provided chain type
+func NewSpillQueue(chain shared.ChainType, db *postgres.DB) (shared.SpillQueue, error) {
+	switch chain {
+	case shared.Ethereum:
+		return eth.NewSpillQueue(db), nil
+	case shared.Bitcoin, shared.Omni:
+		return btc.NewSpillQueue(db), nil
+	default:
+		return nil, fmt.Errorf("invalid chain %s for spill queue constructor", chain.String())
+	}
+}
+
+// NewJSONPayload decodes the rlp-serialized IPLDs sent to subscriptions into a JSON payload for the provided chain type
+func NewJSONPayload(chain shared.ChainType, rlpData []byte) (interface{}, error) {
+	switch chain {
+	case shared.Ethereum:
+		var iplds eth.IPLDs
+		if err := rlp.DecodeBytes(rlpData, &iplds); err != nil {
+			return nil, err
+		}
+		return eth.NewJSONPayload(iplds)
+	case shared.Bitcoin, shared.Omni:
+		var iplds btc.IPLDs
+		if err := rlp.DecodeBytes(rlpData, &iplds); err != nil {
+			return nil, err
+		}
+		return btc.NewJSONPayload(iplds)
+	default:
+		return nil, fmt.Errorf("invalid chain %s for json payload constructor", chain.String())
+	}
+}
+
+// NewCARExporter constructs a CARExporter for the provided chain type
+// The IPLDs are read from public.blocks in the DirectPostgres ipfs mode, and through IPFS otherwise
+func NewCARExporter(chain shared.ChainType, ipfsPath string, remoteConfig ipfs.RemoteClientConfig, db *postgres.DB, ipfsMode shared.IPFSMode) (shared.CARExporter, error) {
+	blockService, err := newCARBlockService(ipfsPath, remoteConfig, db, ipfsMode)
+	if err != nil {
+		return nil, err
+	}
+	switch chain {
+	case shared.Ethereum:
+		return eth.NewCARExporter(db, blockService), nil
+	case shared.Bitcoin:
+		return btc.NewCARExporter(db, blockService), nil
+	default:
+		return nil, fmt.Errorf("invalid chain %s for CAR exporter constructor", chain.String())
+	}
+}
+
+// NewCARImporter constructs a CARImporter for the provided chain type
+// The IPLDs are written to public.blocks in the DirectPostgres ipfs mode, and through IPFS otherwise
+func NewCARImporter(chain shared.ChainType, ipfsPath string, remoteConfig ipfs.RemoteClientConfig, db *postgres.DB, ipfsMode shared.IPFSMode) (shared.CARImporter, error) {
+	blockService, err := newCARBlockService(ipfsPath, remoteConfig, db, ipfsMode)
+	if err != nil {
+		return nil, err
+	}
+	switch chain {
+	case shared.Ethereum:
+		return eth.NewCARImporter(db, blockService), nil
+	case shared.Bitcoin:
+		return btc.NewCARImporter(db, blockService), nil
+	default:
+		return nil, fmt.Errorf("invalid chain %s for CAR importer constructor", chain.String())
+	}
+}
+
+func newCARBlockService(ipfsPath string, remoteConfig ipfs.RemoteClientConfig, db *postgres.DB, ipfsMode shared.IPFSMode) (ipfs.BlockService, error) {
+	switch ipfsMode {
+	case shared.LocalInterface:
+		return ipfs.InitIPFSBlockService(ipfsPath)
+	case shared.RemoteClient:
+		return ipfs.NewRemoteClient(remoteConfig), nil
+	case shared.DirectPostgres:
+		return shared.NewPGBlockService(db), nil
+	default:
+		return nil, fmt.Errorf("unexpected ipfs mode %s for CAR block service", ipfsMode.String())
+	}
+}
+
+// NewIPLDVerifier constructs an IPLDVerifier for the provided chain type
+func NewIPLDVerifier(chain shared.ChainType, db *postgres.DB) (shared.IPLDVerifier, error) {
+	switch chain {
+	case shared.Ethereum:
+		return eth.NewVerifier(db), nil
+	case shared.Bitcoin, shared.Omni:
+		return btc.NewVerifier(db), nil
+	default:
+		return nil, fmt.Errorf("invalid chain %s for IPLD verifier constructor", chain.String())
+	}
+}
+
+// NewBlockValidator constructs a BlockValidator for the provided chain type
+// Validators rewrite the objects they find to differ directly in Postgres, so they require the postgres ipfs mode
+func NewBlockValidator(chain shared.ChainType, db *postgres.DB, ipfsMode shared.IPFSMode) (shared.BlockValidator, error) {
+	if ipfsMode != shared.DirectPostgres {
+		return nil, fmt.Errorf("%s BlockValidator unexpected ipfs mode %s, validation requires the %s mode", chain.String(), ipfsMode.String(), shared.DirectPostgres.String())
+	}
+	switch chain {
+	case shared.Ethereum:
+		return eth.NewValidator(db), nil
+	case shared.Bitcoin:
+		return btc.NewValidator(db), nil
+	default:
+		return nil, fmt.Errorf("invalid chain %s for block validator constructor", chain.String())
+	}
+}
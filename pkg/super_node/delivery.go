@@ -0,0 +1,279 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package super_node
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+// DeliveryOutboxSize is the number of live payloads held for a subscription with the BlockWithTimeout policy
+// while it is blocking on the delivery of earlier payloads
+const DeliveryOutboxSize = 1000
+
+// SubscriptionStats holds the delivery counters for a subscription
+type SubscriptionStats struct {
+	ID      rpc.ID `json:"id"`
+	Policy  string `json:"policy"`
+	Sent    uint64 `json:"sent"`
+	Dropped uint64 `json:"dropped"`
+	Queued  uint64 `json:"queued"` // payloads spilled to the subscription's durable queue
+}
+
+// delivery applies a subscription's slow consumer policy and counts the payloads sent to, dropped for, and queued for it
+type delivery struct {
+	sent    uint64
+	dropped uint64
+	queued  uint64
+	id      rpc.ID
+	subType common.Hash
+	params  shared.SubscriptionSettings
+	policy  shared.SlowConsumerPolicy
+	// guards spilling, which is set while the subscription has payloads waiting in its durable queue
+	sync.Mutex
+	spilling bool
+	// closed once the subscription has been removed
+	done     chan struct{}
+	stopOnce sync.Once
+	// live payloads waiting to be delivered, in order, to a subscription with the BlockWithTimeout policy
+	outbox chan SubscriptionPayload
+}
+
+func newDelivery(id rpc.ID, subType common.Hash, params shared.SubscriptionSettings) *delivery {
+	d := &delivery{
+		id:      id,
+		subType: subType,
+		params:  params,
+		policy:  params.SlowConsumerPolicy(),
+		done:    make(chan struct{}),
+	}
+	if d.policy.Mode == shared.BlockWithTimeout {
+		d.outbox = make(chan SubscriptionPayload, DeliveryOutboxSize)
+	}
+	return d
+}
+
+func (d *delivery) stop() {
+	d.stopOnce.Do(func() {
+		close(d.done)
+	})
+}
+
+func (d *delivery) stats() SubscriptionStats {
+	return SubscriptionStats{
+		ID:      d.id,
+		Policy:  d.policy.Mode.String(),
+		Sent:    atomic.LoadUint64(&d.sent),
+		Dropped: atomic.LoadUint64(&d.dropped),
+		Queued:  atomic.LoadUint64(&d.queued),
+	}
+}
+
+// serve delivers a live payload to the subscription without holding up the serving of the other subscriptions
+// the payloads for a subscription that blocks are added to its outbox, which delivers them in order, and are dropped if its outbox is full
+func (sap *Service) serve(sub Subscription, payload SubscriptionPayload) {
+	d := sub.delivery
+	if d.outbox == nil {
+		if sap.deliver(sub, payload) {
+			sap.disconnect(sub)
+		}
+		return
+	}
+	select {
+	case d.outbox <- payload:
+	default:
+		sap.drop(sub, payload)
+	}
+}
+
+// drainOutbox delivers the payloads added to the subscription's outbox, until the subscription is removed
+func (sap *Service) drainOutbox(sub Subscription) {
+	d := sub.delivery
+	for {
+		select {
+		case payload := <-d.outbox:
+			sap.deliver(sub, payload)
+		case <-d.done:
+			return
+		case <-sap.QuitChan:
+			return
+		}
+	}
+}
+
+// deliver sends the payload to the subscription, applying its slow consumer policy if its buffer is full
+// it returns true if the subscription has fallen behind and needs to be disconnected
+func (sap *Service) deliver(sub Subscription, payload SubscriptionPayload) bool {
+	d := sub.delivery
+	switch d.policy.Mode {
+	case shared.SpillToQueue:
+		return sap.deliverOrSpill(sub, payload)
+	case shared.BlockWithTimeout:
+		select {
+		case sub.PayloadChan <- payload:
+			atomic.AddUint64(&d.sent, 1)
+			return false
+		case <-d.done:
+			return false
+		case <-time.After(d.policy.BlockTimeout()):
+		}
+	default:
+		select {
+		case sub.PayloadChan <- payload:
+			atomic.AddUint64(&d.sent, 1)
+			return false
+		default:
+		}
+	}
+	sap.drop(sub, payload)
+	return d.policy.Mode == shared.Disconnect
+}
+
+// deliverOrSpill sends the payload to the subscription if its buffer has room and nothing is waiting in its durable queue
+// otherwise the payload is added to the back of its queue, and the queue is drained into the subscription as it catches up
+func (sap *Service) deliverOrSpill(sub Subscription, payload SubscriptionPayload) bool {
+	d := sub.delivery
+	d.Lock()
+	defer d.Unlock()
+	select {
+	case <-d.done:
+		return false
+	default:
+	}
+	if !d.spilling {
+		select {
+		case sub.PayloadChan <- payload:
+			atomic.AddUint64(&d.sent, 1)
+			return false
+		default:
+		}
+	}
+	if sap.subscriptionQueue == nil {
+		sap.drop(sub, payload)
+		return false
+	}
+	if err := sap.subscriptionQueue.Push(sub.ID, payload); err != nil {
+		log.Errorf("%s super node unable to queue payload for subscription %s: %v", sap.chain.String(), sub.ID, err)
+		sap.drop(sub, payload)
+		return false
+	}
+	atomic.AddUint64(&d.queued, 1)
+	if !d.spilling {
+		log.Infof("%s subscription %s has fallen behind, queueing its payloads", sap.chain.String(), sub.ID)
+		d.spilling = true
+		go sap.drainSubscriptionQueue(sub)
+	}
+	return false
+}
+
+// drainSubscriptionQueue sends the payloads queued for the subscription in order, until its queue is empty
+func (sap *Service) drainSubscriptionQueue(sub Subscription) {
+	d := sub.delivery
+	for {
+		d.Lock()
+		payload, ok, err := sap.subscriptionQueue.Pop(sub.ID)
+		if err != nil || !ok {
+			if err != nil {
+				log.Errorf("%s super node unable to drain queue for subscription %s: %v", sap.chain.String(), sub.ID, err)
+			}
+			d.spilling = false
+			d.Unlock()
+			return
+		}
+		d.Unlock()
+		select {
+		case sub.PayloadChan <- payload:
+			atomic.AddUint64(&d.sent, 1)
+		case <-d.done:
+			return
+		case <-sap.QuitChan:
+			return
+		}
+	}
+}
+
+func (sap *Service) drop(sub Subscription, payload SubscriptionPayload) {
+	dropped := atomic.AddUint64(&sub.delivery.dropped, 1)
+	atomic.AddUint64(&sap.dropped, 1)
+	droppedPayloads.WithLabelValues(sap.chain.String(), subscriptionBuffer).Inc()
+	log.Infof("unable to send %s payload at height %d to subscription %s; %d payloads dropped", sap.chain.String(), payload.Height, sub.ID, dropped)
+}
+
+// disconnect removes a subscription that has fallen behind and sends it a final payload with the DisconnectFlag set
+func (sap *Service) disconnect(sub Subscription) {
+	sap.Lock()
+	removed := sap.removeSubscription(sub.ID)
+	sap.Unlock()
+	if removed {
+		go sap.sendDisconnect(sub)
+	}
+}
+
+// sendDisconnect waits for room in the subscription's buffer to notify it of its disconnection, before closing it
+func (sap *Service) sendDisconnect(sub Subscription) {
+	log.Infof("disconnecting %s subscription %s for falling behind", sap.chain.String(), sub.ID)
+	select {
+	case sub.PayloadChan <- SubscriptionPayload{Err: fmt.Sprintf("subscription %s disconnected for falling behind", sub.ID), Flag: DisconnectFlag}:
+	case <-time.After(sub.delivery.policy.BlockTimeout()):
+		log.Infof("unable to send disconnection notice to %s subscription %s", sap.chain.String(), sub.ID)
+	}
+	sendNonBlockingQuit(sub)
+}
+
+// removeSubscription removes the subscription from the service, stops its delivery, and clears its durable queue
+// it returns false if the subscription was not found
+// removeSubscription needs to be called with subscription access locked
+func (sap *Service) removeSubscription(id rpc.ID) bool {
+	d, ok := sap.deliveries[id]
+	for ty := range sap.Subscriptions {
+		delete(sap.Subscriptions[ty], id)
+		if len(sap.Subscriptions[ty]) == 0 {
+			// If we removed the last subscription of this type, remove the subscription type outright
+			delete(sap.Subscriptions, ty)
+			delete(sap.SubscriptionTypes, ty)
+		}
+		sap.countSubscriptions(ty)
+	}
+	if ok {
+		sap.releaseDelivery(d)
+	}
+	return ok
+}
+
+// releaseDelivery stops the delivery and clears any payloads left in its durable queue
+// releaseDelivery needs to be called with subscription access locked
+func (sap *Service) releaseDelivery(d *delivery) {
+	// stop under the delivery's lock so that no more payloads are queued for it once it is cleared
+	d.Lock()
+	d.stop()
+	d.Unlock()
+	delete(sap.deliveries, d.id)
+	if sap.subscriptionQueue == nil || atomic.LoadUint64(&d.queued) == 0 {
+		return
+	}
+	if err := sap.subscriptionQueue.Clear(d.id); err != nil {
+		log.Errorf("%s super node unable to clear queue for subscription %s: %v", sap.chain.String(), d.id, err)
+	}
+}
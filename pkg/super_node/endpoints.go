@@ -0,0 +1,44 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package super_node
+
+import (
+	"net"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// PublicAPIs returns the APIs that are public, filtering out those such as the admin API which are only served over IPC
+func PublicAPIs(apis []rpc.API) []rpc.API {
+	public := make([]rpc.API, 0, len(apis))
+	for _, api := range apis {
+		if api.Public {
+			public = append(public, api)
+		}
+	}
+	return public
+}
+
+// StartWSEndpoint serves the public APIs in the provided modules over websocket
+func StartWSEndpoint(endpoint string, apis []rpc.API, modules []string) (net.Listener, *rpc.Server, error) {
+	return rpc.StartWSEndpoint(endpoint, PublicAPIs(apis), modules, nil, false)
+}
+
+// StartHTTPEndpoint serves the public APIs in the provided modules over HTTP
+func StartHTTPEndpoint(endpoint string, apis []rpc.API, modules []string) (net.Listener, *rpc.Server, error) {
+	return rpc.StartHTTPEndpoint(endpoint, PublicAPIs(apis), modules, nil, nil, rpc.HTTPTimeouts{})
+}
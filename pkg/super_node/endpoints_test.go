@@ -0,0 +1,68 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package super_node_test
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/vulcanizedb/pkg/super_node"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+var _ = Describe("Endpoints", func() {
+	var apis []rpc.API
+	BeforeEach(func() {
+		sn, err := super_node.NewSuperNode(&super_node.Config{Chain: shared.Bitcoin, Workers: 1})
+		Expect(err).ToNot(HaveOccurred())
+		apis = append(super_node.InfoAPIs(), sn.APIs()...)
+	})
+
+	It("Filters out the APIs that are not public", func() {
+		for _, api := range super_node.PublicAPIs(apis) {
+			Expect(api.Public).To(BeTrue())
+			_, isAdmin := api.Service.(*super_node.AdminAPI)
+			Expect(isAdmin).To(BeFalse())
+		}
+	})
+
+	It("Does not serve the admin API over websocket", func() {
+		listener, server, err := super_node.StartWSEndpoint("127.0.0.1:0", apis, []string{super_node.APIName, "admin"})
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Stop()
+		defer listener.Close()
+		client, err := rpc.Dial(fmt.Sprintf("ws://%s", listener.Addr().String()))
+		Expect(err).ToNot(HaveOccurred())
+		defer client.Close()
+
+		var health super_node.HealthStatus
+		err = client.Call(&health, "vdb_health")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(health.Chain).To(Equal(shared.Bitcoin.String()))
+
+		err = client.Call(nil, "admin_pauseBackFill")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("does not exist"))
+		var subscriptions []super_node.SubscriptionInfo
+		err = client.Call(&subscriptions, "admin_subscriptions")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("does not exist"))
+	})
+})
@@ -18,7 +18,10 @@ package eth
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
 	"math/big"
+	"time"
 
 	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
 
@@ -194,3 +197,226 @@ func (pea *PublicEthAPI) GetTransactionByHash(ctx context.Context, hash common.H
 	// Transaction unknown, return as such
 	return nil, nil
 }
+
+// GetBalance returns the amount of wei for the given address in the state of the
+// given block number or hash. The rpc.LatestBlockNumber block number can be used to
+// retrieve the balance at the chain head.
+func (pea *PublicEthAPI) GetBalance(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Big, error) {
+	number, err := pea.B.blockNumberOrHashToNumber(blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	account, _, err := pea.B.StateAccountAt(address, number)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return (*hexutil.Big)(new(big.Int)), nil
+	}
+	return (*hexutil.Big)(account.Balance), nil
+}
+
+// GetTransactionCount returns the nonce of the given address in the state of the given block number or hash
+func (pea *PublicEthAPI) GetTransactionCount(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Uint64, error) {
+	number, err := pea.B.blockNumberOrHashToNumber(blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	account, _, err := pea.B.StateAccountAt(address, number)
+	if err != nil {
+		return nil, err
+	}
+	var nonce uint64
+	if account != nil {
+		nonce = account.Nonce
+	}
+	return (*hexutil.Uint64)(&nonce), nil
+}
+
+// GetStorageAt returns the storage from the state at the given address, key and
+// block number or hash. Empty slots are returned as the zero value.
+func (pea *PublicEthAPI) GetStorageAt(ctx context.Context, address common.Address, key string, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	number, err := pea.B.blockNumberOrHashToNumber(blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	value, _, err := pea.B.StorageValueAt(address, common.HexToHash(key), number)
+	if err != nil {
+		return nil, err
+	}
+	return common.BytesToHash(value).Bytes(), nil
+}
+
+// GetCode returns the code stored at the given address in the state for the given block number or hash
+// Accounts without code return empty bytes. The code is fetched from the node as the state diffs are synced, and a
+// "code unavailable" error is returned for a contract account whose code could not be fetched
+func (pea *PublicEthAPI) GetCode(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	number, err := pea.B.blockNumberOrHashToNumber(blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	account, _, err := pea.B.StateAccountAt(address, number)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return hexutil.Bytes{}, nil
+	}
+	return pea.B.CodeByHash(common.BytesToHash(account.CodeHash))
+}
+
+// GetTransactionReceipt returns the receipt for the given canonical transaction hash
+// nil is returned if the transaction is unknown
+func (pea *PublicEthAPI) GetTransactionReceipt(ctx context.Context, hash common.Hash) (map[string]interface{}, error) {
+	tx, blockHash, blockNumber, index, err := pea.B.GetTransaction(ctx, hash)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	receipts, err := pea.B.GetReceipts(ctx, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(receipts)) <= index {
+		return nil, fmt.Errorf("receipt for transaction %s is not available", hash.Hex())
+	}
+	receipt := receipts[index]
+
+	var signer types.Signer = types.FrontierSigner{}
+	if tx.Protected() {
+		signer = types.NewEIP155Signer(tx.ChainId())
+	}
+	from, _ := types.Sender(signer, tx)
+
+	fields := map[string]interface{}{
+		"blockHash":         blockHash,
+		"blockNumber":       hexutil.Uint64(blockNumber),
+		"transactionHash":   hash,
+		"transactionIndex":  hexutil.Uint64(index),
+		"from":              from,
+		"to":                tx.To(),
+		"gasUsed":           hexutil.Uint64(receipt.GasUsed),
+		"cumulativeGasUsed": hexutil.Uint64(receipt.CumulativeGasUsed),
+		"contractAddress":   nil,
+		"logs":              receipt.Logs,
+		"logsBloom":         receipt.Bloom,
+	}
+
+	// Assign receipt status or post state.
+	if len(receipt.PostState) > 0 {
+		fields["root"] = hexutil.Bytes(receipt.PostState)
+	} else {
+		fields["status"] = hexutil.Uint(receipt.Status)
+	}
+	if receipt.Logs == nil {
+		fields["logs"] = [][]*types.Log{}
+	}
+	// If the ContractAddress is 20 0x0 bytes, assume it is not a contract creation
+	if receipt.ContractAddress != (common.Address{}) {
+		fields["contractAddress"] = receipt.ContractAddress
+	}
+	return fields, nil
+}
+
+// GetUncleByBlockNumberAndIndex returns the uncle block for the given block number and index
+// When blockNr is -1 the chain head is used
+func (pea *PublicEthAPI) GetUncleByBlockNumberAndIndex(ctx context.Context, blockNr rpc.BlockNumber, index hexutil.Uint) (map[string]interface{}, error) {
+	block, err := pea.B.BlockByNumber(ctx, blockNr)
+	if block != nil && err == nil {
+		return uncleByIndex(block, index)
+	}
+	return nil, err
+}
+
+// GetUncleByBlockHashAndIndex returns the uncle block for the given block hash and index
+func (pea *PublicEthAPI) GetUncleByBlockHashAndIndex(ctx context.Context, blockHash common.Hash, index hexutil.Uint) (map[string]interface{}, error) {
+	block, err := pea.B.BlockByHash(ctx, blockHash)
+	if block != nil && err == nil {
+		return uncleByIndex(block, index)
+	}
+	return nil, err
+}
+
+// GetBlockTransactionCountByNumber returns the number of transactions in the block with the given block number
+func (pea *PublicEthAPI) GetBlockTransactionCountByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*hexutil.Uint, error) {
+	block, err := pea.B.BlockByNumber(ctx, blockNr)
+	if block != nil && err == nil {
+		n := hexutil.Uint(len(block.Transactions()))
+		return &n, nil
+	}
+	return nil, err
+}
+
+// GetBlockTransactionCountByHash returns the number of transactions in the block with the given hash
+func (pea *PublicEthAPI) GetBlockTransactionCountByHash(ctx context.Context, blockHash common.Hash) (*hexutil.Uint, error) {
+	block, err := pea.B.BlockByHash(ctx, blockHash)
+	if block != nil && err == nil {
+		n := hexutil.Uint(len(block.Transactions()))
+		return &n, nil
+	}
+	return nil, err
+}
+
+// uncleByIndex marshals the uncle at the index of the block
+// nil is returned if the index is out of range
+func uncleByIndex(block *types.Block, index hexutil.Uint) (map[string]interface{}, error) {
+	uncles := block.Uncles()
+	if index >= hexutil.Uint(len(uncles)) {
+		return nil, nil
+	}
+	return RPCMarshalBlock(types.NewBlockWithHeader(uncles[index]), false, false)
+}
+
+// CallArgs represents the arguments for a call
+type CallArgs struct {
+	From     *common.Address `json:"from"`
+	To       *common.Address `json:"to"`
+	Gas      *hexutil.Uint64 `json:"gas"`
+	GasPrice *hexutil.Big    `json:"gasPrice"`
+	Value    *hexutil.Big    `json:"value"`
+	Data     *hexutil.Bytes  `json:"data"`
+}
+
+// Call executes the given transaction on the state for the given block number or hash
+// Note, this function doesn't make any changes in the state/blockchain and is useful to execute and retrieve values
+// The state trie nodes the execution touches must have been indexed for that block
+func (pea *PublicEthAPI) Call(ctx context.Context, args CallArgs, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	result, _, _, err := pea.B.DoCall(ctx, args, blockNrOrHash, 5*time.Second)
+	return (hexutil.Bytes)(result), err
+}
+
+// EstimateGas returns an estimate of the amount of gas needed to execute the given transaction on top of the state
+// for the given block number or hash; if none is provided the chain head is used
+func (pea *PublicEthAPI) EstimateGas(ctx context.Context, args CallArgs, blockNrOrHash *rpc.BlockNumberOrHash) (hexutil.Uint64, error) {
+	bNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	if blockNrOrHash != nil {
+		bNrOrHash = *blockNrOrHash
+	}
+	return pea.B.DoEstimateGas(ctx, args, bNrOrHash)
+}
+
+// AccountResult is the result of an eth_getProof call
+type AccountResult struct {
+	Address      common.Address  `json:"address"`
+	AccountProof []string        `json:"accountProof"`
+	Balance      *hexutil.Big    `json:"balance"`
+	CodeHash     common.Hash     `json:"codeHash"`
+	Nonce        hexutil.Uint64  `json:"nonce"`
+	StorageHash  common.Hash     `json:"storageHash"`
+	StorageProof []StorageResult `json:"storageProof"`
+}
+
+// StorageResult is the proof for a single storage key of an eth_getProof call
+type StorageResult struct {
+	Key   string       `json:"key"`
+	Value *hexutil.Big `json:"value"`
+	Proof []string     `json:"proof"`
+}
+
+// GetProof returns the Merkle-proof for a given account and optionally some storage keys (EIP-1186)
+// The proofs are built from the state and storage trie nodes indexed for the given block number or hash
+func (pea *PublicEthAPI) GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNrOrHash rpc.BlockNumberOrHash) (*AccountResult, error) {
+	return pea.B.GetProof(ctx, address, storageKeys, blockNrOrHash)
+}
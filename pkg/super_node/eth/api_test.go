@@ -18,18 +18,31 @@ package eth_test
 
 import (
 	"context"
+	"math/big"
 	"strconv"
+	"time"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/core/types"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/statediff"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	"github.com/vulcanize/vulcanizedb/pkg/ipfs/ipld"
 	"github.com/vulcanize/vulcanizedb/pkg/postgres"
 	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth"
 	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth/mocks"
@@ -97,9 +110,11 @@ var _ = Describe("API", func() {
 		fetcher = eth.NewIPLDPGFetcher(db)
 		indexAndPublisher = eth.NewIPLDPublisherAndIndexer(db)
 		backend = &eth.Backend{
-			Retriever: retriever,
-			Fetcher:   fetcher,
-			DB:        db,
+			Retriever:     retriever,
+			Fetcher:       fetcher,
+			DB:            db,
+			StateDatabase: eth.NewStateDatabase(db),
+			Config:        params.MainnetChainConfig,
 		}
 		api = eth.NewPublicEthAPI(backend)
 		_, err = indexAndPublisher.Publish(mocks.MockConvertedPayload)
@@ -579,4 +594,366 @@ var _ = Describe("API", func() {
 			Expect(logs).To(Equal([]*types.Log{mocks.MockLog1, mocks.MockLog2}))
 		})
 	})
+
+	Describe("GetBalance", func() {
+		It("Retrieves the balance of an account at the provided block", func() {
+			number := rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(mocks.BlockNumber.Int64()))
+			bal, err := api.GetBalance(context.Background(), mocks.AccountAddresss, number)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(bal).To(Equal((*hexutil.Big)(big.NewInt(1000))))
+
+			bal, err = api.GetBalance(context.Background(), mocks.AccountAddresss, rpc.BlockNumberOrHashWithHash(mocks.MockBlock.Hash(), false))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(bal).To(Equal((*hexutil.Big)(big.NewInt(1000))))
+		})
+
+		It("Returns zero for an account that does not exist at the provided block", func() {
+			number := rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(mocks.BlockNumber.Int64() - 1))
+			bal, err := api.GetBalance(context.Background(), mocks.AccountAddresss, number)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(bal).To(Equal((*hexutil.Big)(big.NewInt(0))))
+		})
+	})
+
+	Describe("GetTransactionCount", func() {
+		It("Retrieves the nonce of an account at the provided block", func() {
+			count, err := api.GetTransactionCount(context.Background(), mocks.ContractAddress, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*count).To(Equal(hexutil.Uint64(1)))
+
+			count, err = api.GetTransactionCount(context.Background(), mocks.AccountAddresss, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*count).To(Equal(hexutil.Uint64(0)))
+		})
+
+		It("Throws an error for the pending block", func() {
+			_, err := api.GetTransactionCount(context.Background(), mocks.ContractAddress, rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("GetStorageAt", func() {
+		It("Retrieves the value at a storage slot at the provided block", func() {
+			number := rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(mocks.BlockNumber.Int64()))
+			val, err := api.GetStorageAt(context.Background(), mocks.ContractAddress, "0x0", number)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(val).To(Equal(hexutil.Bytes(common.BytesToHash(mocks.StorageValue).Bytes())))
+		})
+
+		It("Returns the zero value for an empty slot", func() {
+			number := rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(mocks.BlockNumber.Int64()))
+			val, err := api.GetStorageAt(context.Background(), mocks.ContractAddress, "0x1", number)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(val).To(Equal(hexutil.Bytes(common.Hash{}.Bytes())))
+		})
+	})
+
+	Describe("GetCode", func() {
+		It("Returns empty code for an account without code", func() {
+			code, err := api.GetCode(context.Background(), mocks.AccountAddresss, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(code).To(BeEmpty())
+		})
+
+		It("Throws a code unavailable error for a contract account whose code has not been fetched", func() {
+			_, err := api.GetCode(context.Background(), mocks.ContractAddress, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("is unavailable: it has not been fetched from the node"))
+		})
+
+		It("Retrieves code that has been written to the blockstore by its hash", func() {
+			code := []byte{0x60, 0x80, 0x60, 0x40, 0x52}
+			tx, err := db.Beginx()
+			Expect(err).ToNot(HaveOccurred())
+			codeCID, err := shared.PublishRaw(tx, cid.Raw, multihash.KECCAK_256, code)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tx.Commit()).ToNot(HaveOccurred())
+			Expect(codeCID).To(Equal(eth.CodeCID(crypto.Keccak256Hash(code)).String()))
+			retrievedCode, err := backend.CodeByHash(crypto.Keccak256Hash(code))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(retrievedCode).To(Equal(code))
+		})
+	})
+
+	Describe("GetTransactionReceipt", func() {
+		It("Retrieves the receipt for a transaction", func() {
+			receipt, err := api.GetTransactionReceipt(context.Background(), mocks.MockTransactions[1].Hash())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(receipt["blockHash"]).To(Equal(mocks.MockBlock.Hash()))
+			Expect(receipt["blockNumber"]).To(Equal(hexutil.Uint64(mocks.BlockNumber.Uint64())))
+			Expect(receipt["transactionHash"]).To(Equal(mocks.MockTransactions[1].Hash()))
+			Expect(receipt["transactionIndex"]).To(Equal(hexutil.Uint64(1)))
+			Expect(receipt["from"]).To(Equal(mocks.SenderAddr))
+			Expect(receipt["to"]).To(Equal(mocks.MockTransactions[1].To()))
+			Expect(receipt["cumulativeGasUsed"]).To(Equal(hexutil.Uint64(100)))
+			Expect(receipt["gasUsed"]).To(Equal(hexutil.Uint64(50)))
+			Expect(receipt["contractAddress"]).To(BeNil())
+			logs, ok := receipt["logs"].([]*types.Log)
+			Expect(ok).To(BeTrue())
+			Expect(len(logs)).To(Equal(1))
+			Expect(logs[0].Address).To(Equal(mocks.AnotherAddress))
+			Expect(logs[0].TxHash).To(Equal(mocks.MockTransactions[1].Hash()))
+			Expect(logs[0].TxIndex).To(Equal(uint(1)))
+			Expect(logs[0].Index).To(Equal(uint(1)))
+			Expect(logs[0].BlockHash).To(Equal(mocks.MockBlock.Hash()))
+		})
+
+		It("Sets the contract address for contract creations", func() {
+			receipt, err := api.GetTransactionReceipt(context.Background(), mocks.MockTransactions[2].Hash())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(receipt["contractAddress"]).To(Equal(mocks.ContractAddress))
+		})
+
+		It("Returns nil for an unknown transaction", func() {
+			receipt, err := api.GetTransactionReceipt(context.Background(), common.HexToHash("0x01"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(receipt).To(BeNil())
+		})
+	})
+
+	Describe("GetUncleByBlockNumberAndIndex", func() {
+		It("Returns nil if the block has no uncle at the index", func() {
+			uncle, err := api.GetUncleByBlockNumberAndIndex(context.Background(), rpc.BlockNumber(mocks.BlockNumber.Int64()), 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(uncle).To(BeNil())
+			uncle, err = api.GetUncleByBlockHashAndIndex(context.Background(), mocks.MockBlock.Hash(), 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(uncle).To(BeNil())
+		})
+	})
+
+	Describe("GetBlockTransactionCount", func() {
+		It("Retrieves the number of transactions in a block by number and by hash", func() {
+			count, err := api.GetBlockTransactionCountByNumber(context.Background(), rpc.BlockNumber(mocks.BlockNumber.Int64()))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*count).To(Equal(hexutil.Uint(len(mocks.MockTransactions))))
+			count, err = api.GetBlockTransactionCountByHash(context.Background(), mocks.MockBlock.Hash())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*count).To(Equal(hexutil.Uint(len(mocks.MockTransactions))))
+		})
+	})
+
+	Describe("Call", func() {
+		It("Executes a call against the state at the provided block", func() {
+			args := eth.CallArgs{
+				From: &mocks.SenderAddr,
+				To:   &mocks.AnotherAddress,
+			}
+			res, err := api.Call(context.Background(), args, rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(mocks.BlockNumber.Int64())))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res).To(BeEmpty())
+		})
+
+		It("Throws an error for the pending block", func() {
+			_, err := api.Call(context.Background(), eth.CallArgs{To: &mocks.AnotherAddress}, rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("EstimateGas", func() {
+		It("Estimates the gas needed to execute a transaction", func() {
+			gas := hexutil.Uint64(100000)
+			args := eth.CallArgs{
+				From: &mocks.SenderAddr,
+				To:   &mocks.AnotherAddress,
+				Gas:  &gas,
+			}
+			latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+			estimate, err := api.EstimateGas(context.Background(), args, &latest)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(estimate).To(Equal(hexutil.Uint64(params.TxGas)))
+		})
+	})
+
+	Describe("Contract code synced from the node", func() {
+		var (
+			contract  = common.HexToAddress("0xaE9BEa628c4Ce503DcFD7E305CaB4e29E7476595")
+			slot      = common.HexToHash("0x0")
+			slotValue = common.HexToHash("0x2a")
+			// PUSH1 0 SLOAD PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN, which returns the value in storage slot 0
+			code       = []byte{0x60, 0x00, 0x54, 0x60, 0x00, 0x52, 0x60, 0x20, 0x60, 0x00, 0xf3}
+			syncedNum  = rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(mocks.BlockNumber.Int64() + 1))
+			syncedHash common.Hash
+		)
+		BeforeEach(func() {
+			// Build the state of a block holding only the contract, whose state and storage tries are each a single leaf node
+			memDB := rawdb.NewMemoryDatabase()
+			stateDB, err := state.New(common.Hash{}, state.NewDatabase(memDB))
+			Expect(err).ToNot(HaveOccurred())
+			stateDB.SetNonce(contract, 1)
+			stateDB.SetCode(contract, code)
+			stateDB.SetState(contract, slot, slotValue)
+			root, err := stateDB.Commit(true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stateDB.Database().TrieDB().Commit(root, false)).ToNot(HaveOccurred())
+			stateLeaf, err := memDB.Get(root.Bytes())
+			Expect(err).ToNot(HaveOccurred())
+			storageLeaf, err := memDB.Get(stateDB.StorageTrie(contract).Hash().Bytes())
+			Expect(err).ToNot(HaveOccurred())
+
+			// Package it as the statediff payload the node sends, which carries no code
+			block := types.NewBlock(&types.Header{
+				ParentHash: mocks.MockBlock.Hash(),
+				Number:     new(big.Int).Add(mocks.BlockNumber, big.NewInt(1)),
+				Root:       root,
+				Difficulty: big.NewInt(1),
+				GasLimit:   8000000,
+			}, nil, nil, nil)
+			syncedHash = block.Hash()
+			blockRLP, err := rlp.EncodeToBytes(block)
+			Expect(err).ToNot(HaveOccurred())
+			receiptsRLP, err := rlp.EncodeToBytes(types.Receipts{})
+			Expect(err).ToNot(HaveOccurred())
+			stateDiffRLP, err := rlp.EncodeToBytes(statediff.StateObject{
+				BlockNumber: block.Number(),
+				BlockHash:   block.Hash(),
+				Nodes: []statediff.StateNode{{
+					NodeType:  statediff.Leaf,
+					Path:      []byte{},
+					NodeValue: stateLeaf,
+					LeafKey:   crypto.Keccak256(contract.Bytes()),
+					StorageNodes: []statediff.StorageNode{{
+						NodeType:  statediff.Leaf,
+						Path:      []byte{},
+						NodeValue: storageLeaf,
+						LeafKey:   crypto.Keccak256(slot.Bytes()),
+					}},
+				}},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			// Convert it, fetching the code from the node, then publish and index it
+			node := newTestNode()
+			node.setCode(contract, code)
+			converter := eth.NewPayloadConverter(params.MainnetChainConfig)
+			converter.CodeFetcher = eth.NewCodeFetcher(node.client(), time.Second*10)
+			converted, err := converter.Convert(statediff.Payload{
+				BlockRlp:        blockRLP,
+				ReceiptsRlp:     receiptsRLP,
+				StateObjectRlp:  stateDiffRLP,
+				TotalDifficulty: big.NewInt(1),
+			})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = indexAndPublisher.Publish(converted)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("Returns the code of a contract synced from the node", func() {
+			res, err := api.GetCode(context.Background(), contract, syncedNum)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res).To(Equal(hexutil.Bytes(code)))
+		})
+
+		It("Returns the code of a contract synced from the node by block hash", func() {
+			res, err := api.GetCode(context.Background(), contract, rpc.BlockNumberOrHashWithHash(syncedHash, false))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res).To(Equal(hexutil.Bytes(code)))
+		})
+
+		It("Executes a call which runs the contract's code", func() {
+			res, err := api.Call(context.Background(), eth.CallArgs{To: &contract}, syncedNum)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res).To(Equal(hexutil.Bytes(slotValue.Bytes())))
+		})
+
+		It("Estimates the gas needed to run the contract's code", func() {
+			estimate, err := api.EstimateGas(context.Background(), eth.CallArgs{To: &contract}, &syncedNum)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(uint64(estimate)).To(BeNumerically(">", params.TxGas))
+		})
+	})
+
+	Describe("GetProof", func() {
+		var (
+			root      common.Hash
+			addr      = common.HexToAddress("0xaE9BEa628c4Ce503DcFD7E305CaB4e29E7476594")
+			slot      = common.HexToHash("0x0")
+			slotValue = common.HexToHash("0x2a")
+			proofNum  = rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(2))
+		)
+		BeforeEach(func() {
+			// Build a state trie in memory, publish its nodes, and index a header with its root
+			memDB := rawdb.NewMemoryDatabase()
+			stateDB, err := state.New(common.Hash{}, state.NewDatabase(memDB))
+			Expect(err).ToNot(HaveOccurred())
+			stateDB.SetBalance(addr, big.NewInt(1000))
+			stateDB.SetNonce(addr, 3)
+			stateDB.SetState(addr, slot, slotValue)
+			root, err = stateDB.Commit(true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stateDB.Database().TrieDB().Commit(root, false)).ToNot(HaveOccurred())
+			tx, err := db.Beginx()
+			Expect(err).ToNot(HaveOccurred())
+			publishAll(tx, memDB, common.Hash{})
+			Expect(tx.Commit()).ToNot(HaveOccurred())
+			header := &types.Header{
+				Number:     big.NewInt(2),
+				ParentHash: mocks.MockBlock.Hash(),
+				Root:       root,
+				Difficulty: big.NewInt(5000000),
+				Extra:      []byte{},
+			}
+			_, err = indexAndPublisher.Publish(eth.ConvertedPayload{
+				TotalDifficulty: big.NewInt(10000000),
+				Block:           types.NewBlock(header, nil, nil, nil),
+			})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("Builds account and storage proofs which verify against the state root", func() {
+			res, err := api.GetProof(context.Background(), addr, []string{slot.Hex()}, proofNum)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.Balance).To(Equal((*hexutil.Big)(big.NewInt(1000))))
+			Expect(res.Nonce).To(Equal(hexutil.Uint64(3)))
+			Expect(res.CodeHash).To(Equal(crypto.Keccak256Hash(nil)))
+
+			value := verifyProof(root, crypto.Keccak256(addr.Bytes()), res.AccountProof)
+			var account state.Account
+			Expect(rlp.DecodeBytes(value, &account)).ToNot(HaveOccurred())
+			Expect(account.Balance).To(Equal(big.NewInt(1000)))
+			Expect(account.Root).To(Equal(res.StorageHash))
+
+			Expect(len(res.StorageProof)).To(Equal(1))
+			Expect(res.StorageProof[0].Value).To(Equal((*hexutil.Big)(slotValue.Big())))
+			value = verifyProof(res.StorageHash, crypto.Keccak256(slot.Bytes()), res.StorageProof[0].Proof)
+			var storageValue []byte
+			Expect(rlp.DecodeBytes(value, &storageValue)).ToNot(HaveOccurred())
+			Expect(common.BytesToHash(storageValue)).To(Equal(slotValue))
+		})
+
+		It("Proves the absence of an account", func() {
+			missing := common.HexToAddress("0x01")
+			res, err := api.GetProof(context.Background(), missing, []string{slot.Hex()}, proofNum)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.Balance).To(Equal((*hexutil.Big)(big.NewInt(0))))
+			Expect(res.StorageHash).To(Equal(types.EmptyRootHash))
+			Expect(res.StorageProof[0].Proof).To(BeEmpty())
+			Expect(len(res.AccountProof)).ToNot(BeZero())
+		})
+
+		It("Throws an error if the trie nodes needed for the proof have not been indexed", func() {
+			mh, err := multihash.Encode(root.Bytes(), multihash.KECCAK_256)
+			Expect(err).ToNot(HaveOccurred())
+			mhKey, err := shared.MultihashKeyFromCIDString(cid.NewCidV1(ipld.MEthStateTrie, mh).String())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = db.Exec(`DELETE FROM public.blocks WHERE key = $1`, mhKey)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = api.GetProof(context.Background(), addr, nil, proofNum)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("state at block 2 is not available"))
+		})
+	})
 })
+
+// verifyProof checks the hex encoded proof nodes against the root and returns the proven value
+func verifyProof(root common.Hash, key []byte, proof []string) []byte {
+	proofDB := memorydb.New()
+	for _, node := range proof {
+		b, err := hexutil.Decode(node)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(proofDB.Put(crypto.Keccak256(b), b)).ToNot(HaveOccurred())
+	}
+	value, _, err := trie.VerifyProof(root, key, proofDB)
+	Expect(err).ToNot(HaveOccurred())
+	return value
+}
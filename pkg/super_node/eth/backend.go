@@ -18,36 +18,55 @@ package eth
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"math"
 	"math/big"
+	"time"
 
 	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/statediff"
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+	log "github.com/sirupsen/logrus"
 	"github.com/vulcanize/vulcanizedb/pkg/ipfs"
 	"github.com/vulcanize/vulcanizedb/pkg/postgres"
 )
 
 var (
 	errPendingBlockNumber = errors.New("pending block number not supported")
+	emptyCodeHash         = crypto.Keccak256Hash(nil)
 )
 
 type Backend struct {
-	Retriever *CIDRetriever
-	Fetcher   *IPLDPGFetcher
-	DB        *postgres.DB
+	Retriever     *CIDRetriever
+	Fetcher       *IPLDPGFetcher
+	DB            *postgres.DB
+	StateDatabase state.Database
+	Config        *params.ChainConfig
 }
 
-func NewEthBackend(db *postgres.DB) (*Backend, error) {
+func NewEthBackend(db *postgres.DB, chainConfig *params.ChainConfig) (*Backend, error) {
 	r := NewCIDRetriever(db)
 	return &Backend{
-		Retriever: r,
-		DB:        db,
+		Retriever:     r,
+		Fetcher:       NewIPLDPGFetcher(db),
+		DB:            db,
+		StateDatabase: NewStateDatabase(db),
+		Config:        chainConfig,
 	}, nil
 }
 
@@ -329,6 +348,7 @@ func (b *Backend) GetTransaction(ctx context.Context, txHash common.Hash) (*type
 	pgStr := `SELECT transaction_cids.cid, transaction_cids.index, header_cids.block_hash, header_cids.block_number
 			FROM eth.transaction_cids, eth.header_cids
 			WHERE transaction_cids.header_id = header_cids.id
+			AND header_cids.canonical = true
 			AND transaction_cids.tx_hash = $1`
 	var txCIDWithHeaderInfo struct {
 		CID         string `db:"cid"`
@@ -370,6 +390,489 @@ func (b *Backend) GetTransaction(ctx context.Context, txHash common.Hash) (*type
 	return &transaction, common.HexToHash(txCIDWithHeaderInfo.BlockHash), uint64(txCIDWithHeaderInfo.BlockNumber), uint64(txCIDWithHeaderInfo.Index), err
 }
 
+// StateAccountAt returns the account at the address, and the CID of its state leaf node, as of the provided canonical block height
+// A nil account is returned if the account does not exist at that height
+func (b *Backend) StateAccountAt(address common.Address, blockNumber int64) (*state.Account, string, error) {
+	// Begin tx
+	tx, err := b.DB.Beginx()
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	stateLeafKey := crypto.Keccak256Hash(address.Bytes())
+	stateCID, err := b.Retriever.RetrieveStateCIDAt(tx, stateLeafKey, blockNumber)
+	if err == sql.ErrNoRows {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	// The most recent node for the account is a removed node if the account has been deleted
+	if ResolveToNodeType(stateCID.NodeType) != statediff.Leaf {
+		return nil, "", nil
+	}
+	stateNodes, err := b.Fetcher.FetchState(tx, []StateNodeModel{stateCID})
+	if err != nil {
+		return nil, "", err
+	}
+	if len(stateNodes) < 1 {
+		return nil, "", fmt.Errorf("state leaf node %s is not available", stateCID.CID)
+	}
+	value, err := leafValue(stateNodes[0].IPLD.Data)
+	if err != nil {
+		return nil, "", err
+	}
+	account := new(state.Account)
+	if err := rlp.DecodeBytes(value, account); err != nil {
+		return nil, "", err
+	}
+	return account, stateCID.CID, err
+}
+
+// StorageValueAt returns the value at the storage slot of the address, and the CID of its storage leaf node, as of the provided canonical block height
+// A nil value is returned if the slot is empty at that height
+func (b *Backend) StorageValueAt(address common.Address, slot common.Hash, blockNumber int64) ([]byte, string, error) {
+	// Begin tx
+	tx, err := b.DB.Beginx()
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	stateLeafKey := crypto.Keccak256Hash(address.Bytes())
+	storageLeafKey := crypto.Keccak256Hash(slot.Bytes())
+	storageCID, err := b.Retriever.RetrieveStorageCIDAt(tx, stateLeafKey, storageLeafKey, blockNumber)
+	if err == sql.ErrNoRows {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if ResolveToNodeType(storageCID.NodeType) != statediff.Leaf {
+		return nil, "", nil
+	}
+	storageNodes, err := b.Fetcher.FetchStorage(tx, []StorageNodeWithStateKeyModel{storageCID})
+	if err != nil {
+		return nil, "", err
+	}
+	if len(storageNodes) < 1 {
+		return nil, "", fmt.Errorf("storage leaf node %s is not available", storageCID.CID)
+	}
+	value, err := leafValue(storageNodes[0].IPLD.Data)
+	if err != nil {
+		return nil, "", err
+	}
+	var storageValue []byte
+	if err := rlp.DecodeBytes(value, &storageValue); err != nil {
+		return nil, "", err
+	}
+	return storageValue, storageCID.CID, err
+}
+
+// CodeByHash returns the contract code with the provided code hash
+// Code is keyed in the blockstore by the raw codec, keccak256 multihash CID of the code itself, where it is published as state diffs are
+// synced. A codeUnavailableError is returned if the code could not be fetched from the node
+func (b *Backend) CodeByHash(codeHash common.Hash) ([]byte, error) {
+	if codeHash == emptyCodeHash {
+		return []byte{}, nil
+	}
+	mhKey, err := shared.MultihashKeyFromCIDString(CodeCID(codeHash).String())
+	if err != nil {
+		return nil, err
+	}
+	var code []byte
+	err = b.DB.Get(&code, `SELECT data FROM public.blocks WHERE key = $1`, mhKey)
+	if err == sql.ErrNoRows {
+		return nil, codeUnavailableError{codeHash: codeHash}
+	}
+	return code, err
+}
+
+// GetReceipts returns the receipts for the block with the provided hash
+// The derived fields (tx hash, contract address, gas used, block and log location fields) are populated from the indexed CIDs
+func (b *Backend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	// Retrieve all the CIDs for the block
+	headerCID, _, txCIDs, rctCIDs, err := b.Retriever.RetrieveBlockByHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	if len(txCIDs) != len(rctCIDs) {
+		return nil, fmt.Errorf("block %s has %d transaction cids but %d receipt cids", hash.Hex(), len(txCIDs), len(rctCIDs))
+	}
+	blockNumber, ok := new(big.Int).SetString(headerCID.BlockNumber, 10)
+	if !ok {
+		return nil, fmt.Errorf("block number %s cannot be converted to an integer", headerCID.BlockNumber)
+	}
+
+	// Begin tx
+	tx, err := b.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	rctIPLDs, err := b.Fetcher.FetchRcts(tx, rctCIDs)
+	if err != nil {
+		return nil, err
+	}
+	receipts := make(types.Receipts, len(rctIPLDs))
+	var logIndex uint
+	for i, rctIPLD := range rctIPLDs {
+		receipt := new(types.Receipt)
+		if err := rlp.DecodeBytes(rctIPLD.Data, receipt); err != nil {
+			return nil, err
+		}
+		receipt.TxHash = common.HexToHash(txCIDs[i].TxHash)
+		if rctCIDs[i].Contract != "" {
+			receipt.ContractAddress = common.HexToAddress(rctCIDs[i].Contract)
+		}
+		receipt.GasUsed = receipt.CumulativeGasUsed
+		if i > 0 {
+			receipt.GasUsed -= receipts[i-1].CumulativeGasUsed
+		}
+		receipt.BlockHash = hash
+		receipt.BlockNumber = blockNumber
+		receipt.TransactionIndex = uint(txCIDs[i].Index)
+		for _, l := range receipt.Logs {
+			l.BlockNumber = blockNumber.Uint64()
+			l.TxHash = receipt.TxHash
+			l.TxIndex = receipt.TransactionIndex
+			l.BlockHash = hash
+			l.Index = logIndex
+			logIndex++
+		}
+		receipts[i] = receipt
+	}
+	return receipts, err
+}
+
+// blockNumberOrHashToNumber resolves the canonical block height referenced by the provided block number or hash
+func (b *Backend) blockNumberOrHashToNumber(blockNrOrHash rpc.BlockNumberOrHash) (int64, error) {
+	if blockNumber, ok := blockNrOrHash.Number(); ok {
+		switch blockNumber {
+		case rpc.PendingBlockNumber:
+			return 0, errPendingBlockNumber
+		case rpc.LatestBlockNumber:
+			return b.Retriever.RetrieveLastBlockNumber()
+		default:
+			return blockNumber.Int64(), nil
+		}
+	}
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		pgStr := `SELECT block_number, canonical FROM eth.header_cids
+				WHERE block_hash = $1`
+		var header struct {
+			BlockNumber int64 `db:"block_number"`
+			Canonical   bool  `db:"canonical"`
+		}
+		if err := b.DB.Get(&header, pgStr, hash.String()); err != nil {
+			if err == sql.ErrNoRows {
+				return 0, fmt.Errorf("header for hash %s is not available", hash.Hex())
+			}
+			return 0, err
+		}
+		// State is only indexed along the canonical chain, so it cannot be served for a non-canonical block
+		if !header.Canonical {
+			return 0, fmt.Errorf("block %s is not canonical", hash.Hex())
+		}
+		return header.BlockNumber, nil
+	}
+	return 0, errors.New("invalid arguments; neither block number nor hash specified")
+}
+
+// CodeCID returns the raw codec, keccak256 multihash CID for contract code with the provided code hash
+func CodeCID(codeHash common.Hash) cid.Cid {
+	mh, _ := multihash.Encode(codeHash.Bytes(), multihash.KECCAK_256)
+	return cid.NewCidV1(cid.Raw, mh)
+}
+
+// HeaderByHash returns the header with the provided hash
+func (b *Backend) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	// Begin tx
+	tx, err := b.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	headerCID, err := b.Retriever.RetrieveHeaderCIDByHash(tx, hash)
+	if err != nil {
+		return nil, err
+	}
+	headerIPLD, err := b.Fetcher.FetchHeader(tx, headerCID)
+	if err != nil {
+		return nil, err
+	}
+	var header types.Header
+	err = rlp.DecodeBytes(headerIPLD.Data, &header)
+	return &header, err
+}
+
+// Engine satisfies the core.ChainContext interface
+// The super node does not run a consensus engine; the EVM context is always given the header's coinbase as the author
+func (b *Backend) Engine() consensus.Engine {
+	return nil
+}
+
+// GetHeader satisfies the core.ChainContext interface, it is used to resolve the BLOCKHASH opcode
+func (b *Backend) GetHeader(hash common.Hash, number uint64) *types.Header {
+	header, err := b.HeaderByHash(context.Background(), hash)
+	if err != nil {
+		log.Errorf("super node eth backend unable to retrieve header %s: %v", hash.Hex(), err)
+		return nil
+	}
+	return header
+}
+
+// StateAndHeaderByNumberOrHash returns the state and the header of the canonical block referenced by the provided block number or hash
+func (b *Backend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	number, err := b.blockNumberOrHashToNumber(blockNrOrHash)
+	if err != nil {
+		return nil, nil, err
+	}
+	header, err := b.HeaderByNumber(ctx, rpc.BlockNumber(number))
+	if err != nil {
+		return nil, nil, err
+	}
+	stateDB, err := state.New(header.Root, b.StateDatabase)
+	if err != nil {
+		return nil, nil, stateError{blockNumber: number, err: err}
+	}
+	return stateDB, header, nil
+}
+
+// DoCall executes the call message on top of the state at the provided block number or hash
+// It returns the return data, the gas used, and whether or not the execution failed
+func (b *Backend) DoCall(ctx context.Context, args CallArgs, blockNrOrHash rpc.BlockNumberOrHash, timeout time.Duration) ([]byte, uint64, bool, error) {
+	stateDB, header, err := b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	// Set the sender address or use the zero address if none is specified
+	var from common.Address
+	if args.From != nil {
+		from = *args.From
+	}
+	// Set the default gas & gas price if none were set
+	gas := uint64(math.MaxUint64 / 2)
+	if args.Gas != nil {
+		gas = uint64(*args.Gas)
+	}
+	// There are no local accounts to pay for gas so the gas price defaults to zero
+	gasPrice := new(big.Int)
+	if args.GasPrice != nil {
+		gasPrice = args.GasPrice.ToInt()
+	}
+	value := new(big.Int)
+	if args.Value != nil {
+		value = args.Value.ToInt()
+	}
+	var data []byte
+	if args.Data != nil {
+		data = []byte(*args.Data)
+	}
+	msg := types.NewMessage(from, args.To, 0, value, gas, gasPrice, data, false)
+
+	// Setup context so it may be cancelled when the call has completed
+	// or, in case of unmetered gas, setup a context with a timeout
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	evmCtx := core.NewEVMContext(msg, header, b, &header.Coinbase)
+	evm := vm.NewEVM(evmCtx, stateDB, b.Config, vm.Config{})
+	// Wait for the context to be done and cancel the evm. Even if the
+	// EVM has finished, cancelling may be done (repeatedly)
+	go func() {
+		<-ctx.Done()
+		evm.Cancel()
+	}()
+	gp := new(core.GasPool).AddGas(math.MaxUint64)
+	res, gasUsed, failed, err := core.ApplyMessage(evm, msg, gp)
+	// Trie nodes which could not be resolved during execution are recorded on the state
+	if err := stateDB.Error(); err != nil {
+		return nil, 0, false, stateError{blockNumber: header.Number.Int64(), err: err}
+	}
+	if evm.Cancelled() {
+		return nil, 0, false, fmt.Errorf("execution aborted (timeout = %v)", timeout)
+	}
+	return res, gasUsed, failed, err
+}
+
+// DoEstimateGas binary searches for the lowest gas limit the call message executes successfully with,
+// on top of the state at the provided block number or hash
+func (b *Backend) DoEstimateGas(ctx context.Context, args CallArgs, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Uint64, error) {
+	var (
+		lo  = params.TxGas - 1
+		hi  uint64
+		cap uint64
+	)
+	// Make sure the header and the root of its state trie are available before searching
+	_, header, err := b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return 0, err
+	}
+	if args.Gas != nil && uint64(*args.Gas) >= params.TxGas {
+		hi = uint64(*args.Gas)
+	} else {
+		// Use the block's gas limit as the gas ceiling
+		hi = header.GasLimit
+	}
+	cap = hi
+	// Helper to check if a gas allowance results in an executable transaction
+	// Errors caused by missing state are returned rather than treated as a failed execution
+	executable := func(gas uint64) (bool, error) {
+		args.Gas = (*hexutil.Uint64)(&gas)
+		_, _, failed, err := b.DoCall(ctx, args, blockNrOrHash, 0)
+		if err != nil {
+			if _, ok := err.(stateError); ok {
+				return false, err
+			}
+			return false, nil
+		}
+		return !failed, nil
+	}
+	for lo+1 < hi {
+		mid := (hi + lo) / 2
+		ok, err := executable(mid)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	// Reject the transaction as invalid if it still fails at the highest allowance
+	if hi == cap {
+		ok, err := executable(hi)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			return 0, fmt.Errorf("gas required exceeds allowance (%d) or always failing transaction", cap)
+		}
+	}
+	return hexutil.Uint64(hi), nil
+}
+
+// GetProof builds the EIP-1186 account proof for the address, and the storage proofs for the provided storage keys,
+// by walking the state and storage tries from the state root of the block referenced by the provided block number or hash
+// An error is returned if any of the trie nodes needed for the proofs have not been indexed
+func (b *Backend) GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNrOrHash rpc.BlockNumberOrHash) (*AccountResult, error) {
+	stateDB, header, err := b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	number := header.Number.Int64()
+	storageTrie := stateDB.StorageTrie(address)
+	storageHash := types.EmptyRootHash
+	codeHash := stateDB.GetCodeHash(address)
+	storageProof := make([]StorageResult, len(storageKeys))
+	// If we have a storage trie the account exists, and we can update the storage hash
+	if storageTrie != nil {
+		storageHash = storageTrie.Hash()
+	} else {
+		// No storage trie means the account does not exist, so the code hash is the hash of an empty byte array
+		codeHash = emptyCodeHash
+	}
+	// Create the proofs for the storage keys
+	for i, key := range storageKeys {
+		if storageTrie == nil {
+			storageProof[i] = StorageResult{Key: key, Value: &hexutil.Big{}, Proof: []string{}}
+			continue
+		}
+		proof, err := stateDB.GetStorageProof(address, common.HexToHash(key))
+		if err != nil {
+			return nil, stateError{blockNumber: number, err: err}
+		}
+		storageProof[i] = StorageResult{
+			Key:   key,
+			Value: (*hexutil.Big)(stateDB.GetState(address, common.HexToHash(key)).Big()),
+			Proof: common.ToHexArray(proof),
+		}
+	}
+	// Create the account proof
+	accountProof, err := stateDB.GetProof(address)
+	if err != nil {
+		return nil, stateError{blockNumber: number, err: err}
+	}
+	// Trie nodes which could not be resolved while loading the account or its storage are recorded on the state
+	if err := stateDB.Error(); err != nil {
+		return nil, stateError{blockNumber: number, err: err}
+	}
+	return &AccountResult{
+		Address:      address,
+		AccountProof: common.ToHexArray(accountProof),
+		Balance:      (*hexutil.Big)(stateDB.GetBalance(address)),
+		CodeHash:     codeHash,
+		Nonce:        hexutil.Uint64(stateDB.GetNonce(address)),
+		StorageHash:  storageHash,
+		StorageProof: storageProof,
+	}, nil
+}
+
+// stateError is returned when the state needed to execute a call message or build a proof has not been indexed
+type stateError struct {
+	blockNumber int64
+	err         error
+}
+
+func (e stateError) Error() string {
+	return fmt.Sprintf("state at block %d is not available: %v", e.blockNumber, e.err)
+}
+
+// codeUnavailableError is returned when contract code is needed but is not in the blockstore
+type codeUnavailableError struct {
+	codeHash common.Hash
+}
+
+func (e codeUnavailableError) Error() string {
+	return fmt.Sprintf("code for hash %s is unavailable: it has not been fetched from the node", e.codeHash.Hex())
+}
+
 // extractLogsOfInterest returns logs from the receipt IPLD
 func extractLogsOfInterest(rctIPLDs []ipfs.BlockModel, wantedTopics [][]string) ([]*types.Log, error) {
 	var logs []*types.Log
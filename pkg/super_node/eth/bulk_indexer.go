@@ -0,0 +1,375 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/statediff"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/multiformats/go-multihash"
+	log "github.com/sirupsen/logrus"
+
+	common2 "github.com/vulcanize/vulcanizedb/pkg/eth/converters/common"
+	"github.com/vulcanize/vulcanizedb/pkg/ipfs/ipld"
+	"github.com/vulcanize/vulcanizedb/pkg/postgres"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+// Temporary staging tables the bulk indexer copies a batch into, they are dropped when the db tx ends
+// Rows reference their header, transaction, and state node by block number and hash, transaction hash, and state path
+// since the ids of those rows are not known until they have been merged
+const createStagingTablesPgStr = `CREATE TEMPORARY TABLE eth_stage_blocks (key TEXT NOT NULL, data BYTEA NOT NULL) ON COMMIT DROP;
+CREATE TEMPORARY TABLE eth_stage_headers (block_number BIGINT NOT NULL, block_hash VARCHAR(66) NOT NULL, parent_hash VARCHAR(66) NOT NULL,
+	cid TEXT NOT NULL, td NUMERIC NOT NULL, reward NUMERIC NOT NULL, state_root VARCHAR(66), tx_root VARCHAR(66), receipt_root VARCHAR(66),
+	uncle_root VARCHAR(66), bloom BYTEA, timestamp NUMERIC) ON COMMIT DROP;
+CREATE TEMPORARY TABLE eth_stage_uncles (header_number BIGINT NOT NULL, header_hash VARCHAR(66) NOT NULL, block_hash VARCHAR(66) NOT NULL,
+	parent_hash VARCHAR(66) NOT NULL, cid TEXT NOT NULL, reward NUMERIC NOT NULL) ON COMMIT DROP;
+CREATE TEMPORARY TABLE eth_stage_transactions (header_number BIGINT NOT NULL, header_hash VARCHAR(66) NOT NULL, tx_hash VARCHAR(66) NOT NULL,
+	index INTEGER NOT NULL, cid TEXT NOT NULL, dst VARCHAR(66) NOT NULL, src VARCHAR(66) NOT NULL) ON COMMIT DROP;
+CREATE TEMPORARY TABLE eth_stage_receipts (header_number BIGINT NOT NULL, header_hash VARCHAR(66) NOT NULL, tx_hash VARCHAR(66) NOT NULL,
+	cid TEXT NOT NULL, contract VARCHAR(66), contract_hash VARCHAR(66), topic0s VARCHAR(66)[], topic1s VARCHAR(66)[], topic2s VARCHAR(66)[],
+	topic3s VARCHAR(66)[], log_contracts VARCHAR(66)[]) ON COMMIT DROP;
+CREATE TEMPORARY TABLE eth_stage_state (header_number BIGINT NOT NULL, header_hash VARCHAR(66) NOT NULL, state_leaf_key VARCHAR(66),
+	cid TEXT NOT NULL, state_path BYTEA, node_type INTEGER) ON COMMIT DROP;
+CREATE TEMPORARY TABLE eth_stage_accounts (header_number BIGINT NOT NULL, header_hash VARCHAR(66) NOT NULL, state_path BYTEA,
+	balance NUMERIC NOT NULL, nonce INTEGER NOT NULL, code_hash BYTEA NOT NULL, storage_root VARCHAR(66) NOT NULL) ON COMMIT DROP;
+CREATE TEMPORARY TABLE eth_stage_storage (header_number BIGINT NOT NULL, header_hash VARCHAR(66) NOT NULL, state_path BYTEA,
+	storage_leaf_key VARCHAR(66), cid TEXT NOT NULL, storage_path BYTEA, node_type INTEGER) ON COMMIT DROP;`
+
+// Statements merging the staging tables into the eth and public.blocks tables
+// Each conflicts the same way the row by row inserts of the CIDIndexer do, so that retrying a batch is idempotent
+const (
+	mergeBlocksPgStr = `INSERT INTO public.blocks (key, data)
+		SELECT DISTINCT ON (key) key, data FROM eth_stage_blocks
+		ON CONFLICT (key) DO NOTHING`
+	mergeHeadersPgStr = `INSERT INTO eth.header_cids (block_number, block_hash, parent_hash, cid, td, node_id, reward, state_root, tx_root, receipt_root, uncle_root, bloom, timestamp, times_validated)
+		SELECT DISTINCT ON (block_number, block_hash) block_number, block_hash, parent_hash, cid, td, $1::INTEGER, reward, state_root, tx_root, receipt_root, uncle_root, bloom, timestamp, 1
+		FROM eth_stage_headers
+		ON CONFLICT (block_number, block_hash) DO UPDATE SET (parent_hash, cid, td, node_id, reward, state_root, tx_root, receipt_root, uncle_root, bloom, timestamp, times_validated) =
+		(excluded.parent_hash, excluded.cid, excluded.td, excluded.node_id, excluded.reward, excluded.state_root, excluded.tx_root, excluded.receipt_root, excluded.uncle_root,
+		excluded.bloom, excluded.timestamp, eth.header_cids.times_validated + 1)`
+)
+
+// Statements merging the staging tables of the rows that reference a header into the eth tables, in foreign key order
+var mergeCIDsPgStrs = []string{
+	`INSERT INTO eth.uncle_cids (header_id, block_number, block_hash, parent_hash, cid, reward)
+		SELECT DISTINCT ON (header_cids.id, s.block_hash) header_cids.id, header_cids.block_number, s.block_hash, s.parent_hash, s.cid, s.reward
+		FROM eth_stage_uncles AS s
+		INNER JOIN eth.header_cids ON (header_cids.block_number = s.header_number AND header_cids.block_hash = s.header_hash)
+		ON CONFLICT (header_id, block_hash, block_number) DO UPDATE SET (parent_hash, cid, reward) = (excluded.parent_hash, excluded.cid, excluded.reward)`,
+	`INSERT INTO eth.transaction_cids (header_id, block_number, tx_hash, cid, dst, src, index)
+		SELECT DISTINCT ON (header_cids.id, s.tx_hash) header_cids.id, header_cids.block_number, s.tx_hash, s.cid, s.dst, s.src, s.index
+		FROM eth_stage_transactions AS s
+		INNER JOIN eth.header_cids ON (header_cids.block_number = s.header_number AND header_cids.block_hash = s.header_hash)
+		ON CONFLICT (header_id, tx_hash, block_number) DO UPDATE SET (cid, dst, src, index) = (excluded.cid, excluded.dst, excluded.src, excluded.index)`,
+	`INSERT INTO eth.receipt_cids (tx_id, block_number, cid, contract, contract_hash, topic0s, topic1s, topic2s, topic3s, log_contracts)
+		SELECT DISTINCT ON (transaction_cids.id) transaction_cids.id, header_cids.block_number, s.cid, s.contract, s.contract_hash, s.topic0s, s.topic1s, s.topic2s, s.topic3s, s.log_contracts
+		FROM eth_stage_receipts AS s
+		INNER JOIN eth.header_cids ON (header_cids.block_number = s.header_number AND header_cids.block_hash = s.header_hash)
+		INNER JOIN eth.transaction_cids ON (transaction_cids.header_id = header_cids.id AND transaction_cids.block_number = header_cids.block_number
+			AND transaction_cids.tx_hash = s.tx_hash)
+		ON CONFLICT (tx_id, block_number) DO UPDATE SET (cid, contract, contract_hash, topic0s, topic1s, topic2s, topic3s, log_contracts) =
+		(excluded.cid, excluded.contract, excluded.contract_hash, excluded.topic0s, excluded.topic1s, excluded.topic2s, excluded.topic3s, excluded.log_contracts)`,
+	`INSERT INTO eth.state_cids (header_id, block_number, state_leaf_key, cid, state_path, node_type)
+		SELECT DISTINCT ON (header_cids.id, s.state_path) header_cids.id, header_cids.block_number, s.state_leaf_key, s.cid, s.state_path, s.node_type
+		FROM eth_stage_state AS s
+		INNER JOIN eth.header_cids ON (header_cids.block_number = s.header_number AND header_cids.block_hash = s.header_hash)
+		ON CONFLICT (header_id, state_path, block_number) DO UPDATE SET (state_leaf_key, cid, node_type) = (excluded.state_leaf_key, excluded.cid, excluded.node_type)`,
+	`INSERT INTO eth.state_accounts (state_id, block_number, balance, nonce, code_hash, storage_root)
+		SELECT DISTINCT ON (state_cids.id) state_cids.id, header_cids.block_number, s.balance, s.nonce, s.code_hash, s.storage_root
+		FROM eth_stage_accounts AS s
+		INNER JOIN eth.header_cids ON (header_cids.block_number = s.header_number AND header_cids.block_hash = s.header_hash)
+		INNER JOIN eth.state_cids ON (state_cids.header_id = header_cids.id AND state_cids.block_number = header_cids.block_number
+			AND state_cids.state_path IS NOT DISTINCT FROM s.state_path)
+		ON CONFLICT (state_id, block_number) DO UPDATE SET (balance, nonce, code_hash, storage_root) = (excluded.balance, excluded.nonce, excluded.code_hash, excluded.storage_root)`,
+	`INSERT INTO eth.storage_cids (state_id, block_number, storage_leaf_key, cid, storage_path, node_type)
+		SELECT DISTINCT ON (state_cids.id, s.storage_path) state_cids.id, header_cids.block_number, s.storage_leaf_key, s.cid, s.storage_path, s.node_type
+		FROM eth_stage_storage AS s
+		INNER JOIN eth.header_cids ON (header_cids.block_number = s.header_number AND header_cids.block_hash = s.header_hash)
+		INNER JOIN eth.state_cids ON (state_cids.header_id = header_cids.id AND state_cids.block_number = header_cids.block_number
+			AND state_cids.state_path IS NOT DISTINCT FROM s.state_path)
+		ON CONFLICT (state_id, storage_path, block_number) DO UPDATE SET (storage_leaf_key, cid, node_type) = (excluded.storage_leaf_key, excluded.cid, excluded.node_type)`,
+}
+
+// BulkIndexer satisfies the BulkPublisherAndIndexer interface for ethereum
+// It interfaces directly with the public.blocks table of PG-IPFS, like the IPLDPublisherAndIndexer, but instead of inserting
+// the rows of each block one by one it COPYs a whole batch of blocks into staging tables and merges them in a single sqlx.Tx
+type BulkIndexer struct {
+	indexer *CIDIndexer
+}
+
+// NewBulkIndexer creates a pointer to a new BulkIndexer which satisfies the BulkPublisherAndIndexer interface
+func NewBulkIndexer(db *postgres.DB) *BulkIndexer {
+	return &BulkIndexer{
+		indexer: NewCIDIndexer(db),
+	}
+}
+
+// stagedRows holds the rows of a batch for each of the staging tables
+type stagedRows struct {
+	blocks, headers, uncles, txs, rcts, states, accounts, storage [][]interface{}
+	headerModels                                                  []HeaderModel
+}
+
+// PublishAndIndexBatch publishes and indexes a batch of ConvertedPayloads in a single db tx
+func (bi *BulkIndexer) PublishAndIndexBatch(payloads []shared.ConvertedData) error {
+	rows := new(stagedRows)
+	for _, payload := range payloads {
+		ipldPayload, ok := payload.(ConvertedPayload)
+		if !ok {
+			return fmt.Errorf("eth BulkIndexer expected payload type %T got %T", ConvertedPayload{}, payload)
+		}
+		if err := rows.stage(ipldPayload); err != nil {
+			return err
+		}
+	}
+	if len(rows.headerModels) == 0 {
+		return nil
+	}
+	for _, header := range rows.headerModels {
+		if err := bi.indexer.ensurePartitions(header.BlockNumber); err != nil {
+			return err
+		}
+	}
+
+	// Begin new db tx
+	tx, err := bi.indexer.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	if _, err = tx.Exec(createStagingTablesPgStr); err != nil {
+		return err
+	}
+	if err = rows.copy(tx); err != nil {
+		log.Error("eth bulk indexer error when copying into the staging tables")
+		return err
+	}
+	if _, err = tx.Exec(mergeBlocksPgStr); err != nil {
+		log.Error("eth bulk indexer error when merging the staged blocks")
+		return err
+	}
+	if _, err = tx.Exec(mergeHeadersPgStr, bi.indexer.db.NodeID); err != nil {
+		log.Error("eth bulk indexer error when merging the staged headers")
+		return err
+	}
+	for _, pgStr := range mergeCIDsPgStrs {
+		if _, err = tx.Exec(pgStr); err != nil {
+			log.Error("eth bulk indexer error when merging the staged cids")
+			return err
+		}
+	}
+	err = bi.indexHeaders(tx, rows.headerModels)
+	return err // return err variable explicitly so that we return the err = tx.Commit() assignment in the defer
+}
+
+// indexHeaders clears the known gaps at the heights of the batch and marks its headers canonical, in ascending order
+func (bi *BulkIndexer) indexHeaders(tx *sqlx.Tx, headers []HeaderModel) error {
+	heights := make(map[string]int64, len(headers))
+	blockNumbers := make([]int64, 0, len(headers))
+	for _, header := range headers {
+		height, err := strconv.ParseInt(header.BlockNumber, 10, 64)
+		if err != nil {
+			return err
+		}
+		heights[header.BlockNumber] = height
+		blockNumbers = append(blockNumbers, height)
+	}
+	if _, err := tx.Exec(`DELETE FROM eth.known_gaps WHERE block_number = ANY($1::BIGINT[])`, pq.Array(blockNumbers)); err != nil {
+		return err
+	}
+	sort.SliceStable(headers, func(i, j int) bool { return heights[headers[i].BlockNumber] < heights[headers[j].BlockNumber] })
+	for _, header := range headers {
+		if err := bi.indexer.indexCanonical(tx, header); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stage generates the IPLDs and models for a payload and adds them to the staged rows
+func (rows *stagedRows) stage(ipldPayload ConvertedPayload) error {
+	headerNode, uncleNodes, txNodes, txTrieNodes, rctNodes, rctTrieNodes, err := ipld.FromBlockAndReceipts(ipldPayload.Block, ipldPayload.Receipts)
+	if err != nil {
+		return err
+	}
+	for _, node := range txTrieNodes {
+		rows.blocks = append(rows.blocks, []interface{}{shared.BlockKey(node.Cid()), node.RawData()})
+	}
+	for _, node := range rctTrieNodes {
+		rows.blocks = append(rows.blocks, []interface{}{shared.BlockKey(node.Cid()), node.RawData()})
+	}
+
+	block := ipldPayload.Block
+	blockNumber, blockHash := block.Number().Int64(), block.Hash().String()
+	rows.blocks = append(rows.blocks, []interface{}{shared.BlockKey(headerNode.Cid()), headerNode.RawData()})
+	reward := common2.CalcEthBlockReward(block.Header(), block.Uncles(), block.Transactions(), ipldPayload.Receipts)
+	header := HeaderModel{
+		CID:             headerNode.Cid().String(),
+		ParentHash:      block.ParentHash().String(),
+		BlockNumber:     block.Number().String(),
+		BlockHash:       blockHash,
+		TotalDifficulty: ipldPayload.TotalDifficulty.String(),
+		Reward:          reward.String(),
+		Bloom:           block.Bloom().Bytes(),
+		StateRoot:       block.Root().String(),
+		RctRoot:         block.ReceiptHash().String(),
+		TxRoot:          block.TxHash().String(),
+		UncleRoot:       block.UncleHash().String(),
+		Timestamp:       block.Time(),
+	}
+	rows.headerModels = append(rows.headerModels, header)
+	rows.headers = append(rows.headers, []interface{}{blockNumber, blockHash, header.ParentHash, header.CID, header.TotalDifficulty,
+		header.Reward, header.StateRoot, header.TxRoot, header.RctRoot, header.UncleRoot, header.Bloom, strconv.FormatUint(header.Timestamp, 10)})
+
+	for _, uncleNode := range uncleNodes {
+		rows.blocks = append(rows.blocks, []interface{}{shared.BlockKey(uncleNode.Cid()), uncleNode.RawData()})
+		uncleReward := common2.CalcUncleMinerReward(blockNumber, uncleNode.Number.Int64())
+		rows.uncles = append(rows.uncles, []interface{}{blockNumber, blockHash, uncleNode.Hash().String(), uncleNode.ParentHash.String(),
+			uncleNode.Cid().String(), uncleReward.String()})
+	}
+
+	for i, txNode := range txNodes {
+		rctNode := rctNodes[i]
+		rows.blocks = append(rows.blocks, []interface{}{shared.BlockKey(txNode.Cid()), txNode.RawData()},
+			[]interface{}{shared.BlockKey(rctNode.Cid()), rctNode.RawData()})
+		txModel := ipldPayload.TxMetaData[i]
+		rows.txs = append(rows.txs, []interface{}{blockNumber, blockHash, txModel.TxHash, txModel.Index, txNode.Cid().String(), txModel.Dst, txModel.Src})
+		rctModel := ipldPayload.ReceiptMetaData[i]
+		rows.rcts = append(rows.rcts, []interface{}{blockNumber, blockHash, txModel.TxHash, rctNode.Cid().String(), rctModel.Contract, rctModel.ContractHash,
+			rctModel.Topic0s, rctModel.Topic1s, rctModel.Topic2s, rctModel.Topic3s, rctModel.LogContracts})
+	}
+
+	for _, stateNode := range ipldPayload.StateNodes {
+		stateCID, err := ipld.RawdataToCid(ipld.MEthStateTrie, stateNode.Value, multihash.KECCAK_256)
+		if err != nil {
+			return err
+		}
+		rows.blocks = append(rows.blocks, []interface{}{shared.BlockKey(stateCID), stateNode.Value})
+		var stateKey string
+		if stateNode.LeafKey != nullHash {
+			stateKey = stateNode.LeafKey.String()
+		}
+		rows.states = append(rows.states, []interface{}{blockNumber, blockHash, stateKey, stateCID.String(), stateNode.Path,
+			ResolveFromNodeType(stateNode.Type)})
+		// If we have a leaf, decode the account data and stage any associated storage diffs
+		if stateNode.Type != statediff.Leaf {
+			continue
+		}
+		var i []interface{}
+		if err := rlp.DecodeBytes(stateNode.Value, &i); err != nil {
+			return err
+		}
+		if len(i) != 2 {
+			return fmt.Errorf("eth BulkIndexer expected state leaf node rlp to decode into two elements")
+		}
+		var account state.Account
+		if err := rlp.DecodeBytes(i[1].([]byte), &account); err != nil {
+			return err
+		}
+		rows.accounts = append(rows.accounts, []interface{}{blockNumber, blockHash, stateNode.Path, account.Balance.String(), int64(account.Nonce),
+			account.CodeHash, account.Root.String()})
+		for _, storageNode := range ipldPayload.StorageNodes[common.Bytes2Hex(stateNode.Path)] {
+			storageCID, err := ipld.RawdataToCid(ipld.MEthStorageTrie, storageNode.Value, multihash.KECCAK_256)
+			if err != nil {
+				return err
+			}
+			rows.blocks = append(rows.blocks, []interface{}{shared.BlockKey(storageCID), storageNode.Value})
+			var storageKey string
+			if storageNode.LeafKey != nullHash {
+				storageKey = storageNode.LeafKey.Hex()
+			}
+			rows.storage = append(rows.storage, []interface{}{blockNumber, blockHash, stateNode.Path, storageKey, storageCID.String(),
+				storageNode.Path, ResolveFromNodeType(storageNode.Type)})
+		}
+	}
+
+	// Contract code is keyed by its hash rather than indexed
+	for _, code := range ipldPayload.Codes {
+		codeCID, err := ipld.RawdataToCid(ipld.RawBinary, code, multihash.KECCAK_256)
+		if err != nil {
+			return err
+		}
+		rows.blocks = append(rows.blocks, []interface{}{shared.BlockKey(codeCID), code})
+	}
+	return nil
+}
+
+// copy COPYs the staged rows into the staging tables
+func (rows *stagedRows) copy(tx *sqlx.Tx) error {
+	tables := []struct {
+		name    string
+		columns []string
+		rows    [][]interface{}
+	}{
+		{"eth_stage_blocks", []string{"key", "data"}, rows.blocks},
+		{"eth_stage_headers", []string{"block_number", "block_hash", "parent_hash", "cid", "td", "reward", "state_root", "tx_root",
+			"receipt_root", "uncle_root", "bloom", "timestamp"}, rows.headers},
+		{"eth_stage_uncles", []string{"header_number", "header_hash", "block_hash", "parent_hash", "cid", "reward"}, rows.uncles},
+		{"eth_stage_transactions", []string{"header_number", "header_hash", "tx_hash", "index", "cid", "dst", "src"}, rows.txs},
+		{"eth_stage_receipts", []string{"header_number", "header_hash", "tx_hash", "cid", "contract", "contract_hash", "topic0s",
+			"topic1s", "topic2s", "topic3s", "log_contracts"}, rows.rcts},
+		{"eth_stage_state", []string{"header_number", "header_hash", "state_leaf_key", "cid", "state_path", "node_type"}, rows.states},
+		{"eth_stage_accounts", []string{"header_number", "header_hash", "state_path", "balance", "nonce", "code_hash", "storage_root"}, rows.accounts},
+		{"eth_stage_storage", []string{"header_number", "header_hash", "state_path", "storage_leaf_key", "cid", "storage_path", "node_type"}, rows.storage},
+	}
+	for _, table := range tables {
+		if len(table.rows) == 0 {
+			continue
+		}
+		if err := copyIn(tx, table.name, table.columns, table.rows); err != nil {
+			return fmt.Errorf("eth BulkIndexer copy into %s error: %v", table.name, err)
+		}
+	}
+	return nil
+}
+
+func copyIn(tx *sqlx.Tx, table string, columns []string, rows [][]interface{}) error {
+	stmt, err := tx.Prepare(pq.CopyIn(table, columns...))
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if _, err := stmt.Exec(row...); err != nil {
+			stmt.Close()
+			return err
+		}
+	}
+	// Flush the buffered rows
+	if _, err := stmt.Exec(); err != nil {
+		stmt.Close()
+		return err
+	}
+	return stmt.Close()
+}
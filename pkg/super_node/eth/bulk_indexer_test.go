@@ -0,0 +1,193 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth_test
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-ipfs-blockstore"
+	"github.com/ipfs/go-ipfs-ds-help"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/vulcanizedb/pkg/postgres"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth/mocks"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+var _ = Describe("BulkIndexer", func() {
+	var (
+		db        *postgres.DB
+		err       error
+		repo      *eth.BulkIndexer
+		ipfsPgGet = `SELECT data FROM public.blocks
+					WHERE key = $1`
+	)
+	BeforeEach(func() {
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+		repo = eth.NewBulkIndexer(db)
+	})
+	AfterEach(func() {
+		eth.TearDownDB(db)
+	})
+
+	Describe("PublishAndIndexBatch", func() {
+		It("Publishes and indexes header IPLDs", func() {
+			err = repo.PublishAndIndexBatch([]shared.ConvertedData{mocks.MockConvertedPayload})
+			Expect(err).ToNot(HaveOccurred())
+			pgStr := `SELECT cid, td, reward, times_validated
+				FROM eth.header_cids
+				WHERE block_number = $1`
+			type res struct {
+				CID            string
+				TD             string
+				Reward         string
+				TimesValidated int `db:"times_validated"`
+			}
+			header := new(res)
+			err = db.QueryRowx(pgStr, 1).StructScan(header)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(header.CID).To(Equal(mocks.HeaderCID.String()))
+			Expect(header.TD).To(Equal(mocks.MockBlock.Difficulty().String()))
+			Expect(header.Reward).To(Equal("5000000000000000000"))
+			Expect(header.TimesValidated).To(Equal(1))
+			dc, err := cid.Decode(header.CID)
+			Expect(err).ToNot(HaveOccurred())
+			prefixedKey := blockstore.BlockPrefix.String() + dshelp.CidToDsKey(dc).String()
+			var data []byte
+			err = db.Get(&data, ipfsPgGet, prefixedKey)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(mocks.MockHeaderRlp))
+		})
+
+		It("Publishes and indexes transaction and receipt IPLDs", func() {
+			err = repo.PublishAndIndexBatch([]shared.ConvertedData{mocks.MockConvertedPayload})
+			Expect(err).ToNot(HaveOccurred())
+			trxs := make([]string, 0)
+			pgStr := `SELECT transaction_cids.cid FROM eth.transaction_cids INNER JOIN eth.header_cids ON (transaction_cids.header_id = header_cids.id)
+				WHERE header_cids.block_number = $1`
+			err = db.Select(&trxs, pgStr, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(trxs)).To(Equal(3))
+			Expect(shared.ListContainsString(trxs, mocks.Trx1CID.String())).To(BeTrue())
+			Expect(shared.ListContainsString(trxs, mocks.Trx2CID.String())).To(BeTrue())
+			Expect(shared.ListContainsString(trxs, mocks.Trx3CID.String())).To(BeTrue())
+			rcts := make([]string, 0)
+			pgStr = `SELECT receipt_cids.cid FROM eth.receipt_cids, eth.transaction_cids, eth.header_cids
+				WHERE receipt_cids.tx_id = transaction_cids.id
+				AND transaction_cids.header_id = header_cids.id
+				AND header_cids.block_number = $1`
+			err = db.Select(&rcts, pgStr, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(rcts)).To(Equal(3))
+			Expect(shared.ListContainsString(rcts, mocks.Rct1CID.String())).To(BeTrue())
+			Expect(shared.ListContainsString(rcts, mocks.Rct2CID.String())).To(BeTrue())
+			Expect(shared.ListContainsString(rcts, mocks.Rct3CID.String())).To(BeTrue())
+			for _, c := range append(trxs, rcts...) {
+				dc, err := cid.Decode(c)
+				Expect(err).ToNot(HaveOccurred())
+				prefixedKey := blockstore.BlockPrefix.String() + dshelp.CidToDsKey(dc).String()
+				var data []byte
+				err = db.Get(&data, ipfsPgGet, prefixedKey)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(len(data)).ToNot(BeZero())
+			}
+		})
+
+		It("Publishes and indexes state and storage IPLDs", func() {
+			err = repo.PublishAndIndexBatch([]shared.ConvertedData{mocks.MockConvertedPayload})
+			Expect(err).ToNot(HaveOccurred())
+			stateNodes := make([]eth.StateNodeModel, 0)
+			pgStr := `SELECT state_cids.id, state_cids.cid, state_cids.state_leaf_key, state_cids.node_type, state_cids.state_path, state_cids.header_id
+				FROM eth.state_cids INNER JOIN eth.header_cids ON (state_cids.header_id = header_cids.id)
+				WHERE header_cids.block_number = $1`
+			err = db.Select(&stateNodes, pgStr, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(stateNodes)).To(Equal(2))
+			for _, stateNode := range stateNodes {
+				var account eth.StateAccountModel
+				err = db.Get(&account, `SELECT id, state_id, balance, nonce, code_hash, storage_root FROM eth.state_accounts WHERE state_id = $1`, stateNode.ID)
+				Expect(err).ToNot(HaveOccurred())
+				if stateNode.CID == mocks.State1CID.String() {
+					Expect(stateNode.StateKey).To(Equal(common.BytesToHash(mocks.ContractLeafKey).Hex()))
+					Expect(stateNode.Path).To(Equal([]byte{'\x06'}))
+					Expect(account.Nonce).To(Equal(uint64(1)))
+				}
+				if stateNode.CID == mocks.State2CID.String() {
+					Expect(stateNode.StateKey).To(Equal(common.BytesToHash(mocks.AccountLeafKey).Hex()))
+					Expect(stateNode.Path).To(Equal([]byte{'\x0c'}))
+					Expect(account.Balance).To(Equal("1000"))
+				}
+			}
+			storageNodes := make([]eth.StorageNodeWithStateKeyModel, 0)
+			pgStr = `SELECT storage_cids.cid, state_cids.state_leaf_key, storage_cids.storage_leaf_key, storage_cids.node_type, storage_cids.storage_path
+				FROM eth.storage_cids, eth.state_cids, eth.header_cids
+				WHERE storage_cids.state_id = state_cids.id
+				AND state_cids.header_id = header_cids.id
+				AND header_cids.block_number = $1`
+			err = db.Select(&storageNodes, pgStr, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(storageNodes)).To(Equal(1))
+			Expect(storageNodes[0]).To(Equal(eth.StorageNodeWithStateKeyModel{
+				CID:        mocks.StorageCID.String(),
+				NodeType:   2,
+				StorageKey: common.BytesToHash(mocks.StorageLeafKey).Hex(),
+				StateKey:   common.BytesToHash(mocks.ContractLeafKey).Hex(),
+				Path:       []byte{},
+			}))
+		})
+
+		It("Is idempotent when the same batch is indexed again", func() {
+			err = repo.PublishAndIndexBatch([]shared.ConvertedData{mocks.MockConvertedPayload})
+			Expect(err).ToNot(HaveOccurred())
+			err = repo.PublishAndIndexBatch([]shared.ConvertedData{mocks.MockConvertedPayload})
+			Expect(err).ToNot(HaveOccurred())
+			var timesValidated int
+			err = db.Get(&timesValidated, `SELECT times_validated FROM eth.header_cids WHERE block_number = $1`, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(timesValidated).To(Equal(2))
+			counts := map[string]int{
+				`SELECT COUNT(*) FROM eth.transaction_cids`: 3,
+				`SELECT COUNT(*) FROM eth.receipt_cids`:     3,
+				`SELECT COUNT(*) FROM eth.state_cids`:       2,
+				`SELECT COUNT(*) FROM eth.state_accounts`:   2,
+				`SELECT COUNT(*) FROM eth.storage_cids`:     1,
+			}
+			for pgStr, expected := range counts {
+				var count int
+				err = db.Get(&count, pgStr)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(count).To(Equal(expected))
+			}
+		})
+
+		It("Publishes contract code under the raw keccak256 CID of the code", func() {
+			code := []byte{0x60, 0x2a, 0x60, 0x00, 0x52, 0x60, 0x20, 0x60, 0x00, 0xf3}
+			payload := mocks.MockConvertedPayload
+			payload.Codes = map[common.Hash][]byte{crypto.Keccak256Hash(code): code}
+			err = repo.PublishAndIndexBatch([]shared.ConvertedData{payload})
+			Expect(err).ToNot(HaveOccurred())
+			var data []byte
+			err = db.Get(&data, ipfsPgGet, shared.BlockKey(eth.CodeCID(crypto.Keccak256Hash(code))))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(code))
+		})
+	})
+})
@@ -0,0 +1,126 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth_test
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/ipfs/go-cid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/vulcanizedb/pkg/ipfs"
+	"github.com/vulcanize/vulcanizedb/pkg/postgres"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth/mocks"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+// indexedCIDs returns the cids in each of the eth cid tables, along with the storage roots of the state accounts
+func indexedCIDs(db *postgres.DB) map[string][]string {
+	cids := make(map[string][]string)
+	for _, table := range []string{"header_cids", "uncle_cids", "transaction_cids", "receipt_cids", "state_cids", "storage_cids"} {
+		tableCIDs := make([]string, 0)
+		err := db.Select(&tableCIDs, `SELECT cid FROM eth.`+table+` ORDER BY cid`)
+		Expect(err).ToNot(HaveOccurred())
+		cids[table] = tableCIDs
+	}
+	accounts := make([]string, 0)
+	err := db.Select(&accounts, `SELECT storage_root FROM eth.state_accounts ORDER BY storage_root`)
+	Expect(err).ToNot(HaveOccurred())
+	cids["state_accounts"] = accounts
+	return cids
+}
+
+var _ = Describe("CAR export and import", func() {
+	var (
+		db          *postgres.DB
+		err         error
+		blocks      *shared.PGBlockService
+		exporter    *eth.CARExporter
+		importer    *eth.CARImporter
+		blockCounts = `SELECT COUNT(*) FROM public.blocks`
+	)
+	BeforeEach(func() {
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+		blocks = shared.NewPGBlockService(db)
+		exporter = eth.NewCARExporter(db, blocks)
+		importer = eth.NewCARImporter(db, blocks)
+		_, err = eth.NewIPLDPublisherAndIndexer(db).Publish(mocks.MockConvertedPayload)
+		Expect(err).ToNot(HaveOccurred())
+	})
+	AfterEach(func() {
+		eth.TearDownDB(db)
+	})
+
+	It("Exports the IPLDs of a range with the header CIDs as roots", func() {
+		buf := new(bytes.Buffer)
+		exported, err := exporter.Export(buf, 0, 10)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(exported).To(Equal(1))
+		reader, err := ipfs.NewCARReader(bytes.NewReader(buf.Bytes()))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(reader.Roots)).To(Equal(1))
+		Expect(reader.Roots[0]).To(Equal(mocks.HeaderCID))
+	})
+
+	It("Rebuilds the index and blocks from an exported CAR file", func() {
+		before := indexedCIDs(db)
+		var blockCount int
+		err = db.Get(&blockCount, blockCounts)
+		Expect(err).ToNot(HaveOccurred())
+		buf := new(bytes.Buffer)
+		_, err = exporter.Export(buf, 1, 1)
+		Expect(err).ToNot(HaveOccurred())
+		eth.TearDownDB(db)
+
+		imported, err := importer.Import(buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(imported).To(Equal(1))
+		Expect(indexedCIDs(db)).To(Equal(before))
+		var header eth.HeaderModel
+		err = db.Get(&header, `SELECT * FROM eth.header_cids WHERE block_number = $1`, 1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(header.CID).To(Equal(mocks.HeaderCID.String()))
+		Expect(header.TotalDifficulty).To(Equal(mocks.MockBlock.Difficulty().String()))
+		Expect(header.Canonical).To(BeTrue())
+		// the published trie nodes are not referenced by the index, so only the referenced IPLDs come back
+		var importedCount int
+		err = db.Get(&importedCount, blockCounts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(importedCount).To(BeNumerically(">", 0))
+		Expect(importedCount).To(BeNumerically("<=", blockCount))
+		for table, cidStrs := range before {
+			if table == "state_accounts" {
+				continue
+			}
+			for _, cidStr := range cidStrs {
+				c, err := cid.Decode(cidStr)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = blocks.GetBlock(context.Background(), c)
+				Expect(err).ToNot(HaveOccurred())
+			}
+		}
+	})
+
+	It("Fails when there are no headers in the range", func() {
+		_, err := exporter.Export(new(bytes.Buffer), 100, 200)
+		Expect(err).To(HaveOccurred())
+	})
+})
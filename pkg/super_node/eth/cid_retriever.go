@@ -93,6 +93,7 @@ func (ecr *CIDRetriever) Retrieve(filter shared.SubscriptionSettings, blockNumbe
 	for i, header := range headers {
 		cw := new(CIDWrapper)
 		cw.BlockNumber = big.NewInt(blockNumber)
+		cw.BlockHash = header.BlockHash
 		if !streamFilter.HeaderFilter.Off {
 			cw.Header = header
 			empty = false
@@ -160,12 +161,13 @@ func (ecr *CIDRetriever) Retrieve(filter shared.SubscriptionSettings, blockNumbe
 	return cws, empty, err
 }
 
-// RetrieveHeaderCIDs retrieves and returns all of the header cids at the provided blockheight
+// RetrieveHeaderCIDs retrieves and returns the canonical header cids at the provided blockheight
 func (ecr *CIDRetriever) RetrieveHeaderCIDs(tx *sqlx.Tx, blockNumber int64) ([]HeaderModel, error) {
 	log.Debug("retrieving header cids for block ", blockNumber)
 	headers := make([]HeaderModel, 0)
 	pgStr := `SELECT * FROM eth.header_cids
-				WHERE block_number = $1`
+				WHERE block_number = $1
+				AND canonical = true`
 	return headers, tx.Select(&headers, pgStr, blockNumber)
 }
 
@@ -173,7 +175,7 @@ func (ecr *CIDRetriever) RetrieveHeaderCIDs(tx *sqlx.Tx, blockNumber int64) ([]H
 func (ecr *CIDRetriever) RetrieveUncleCIDsByHeaderID(tx *sqlx.Tx, headerID int64) ([]UncleModel, error) {
 	log.Debug("retrieving uncle cids for block id ", headerID)
 	headers := make([]UncleModel, 0)
-	pgStr := `SELECT * FROM eth.uncle_cids
+	pgStr := `SELECT id, header_id, block_hash, parent_hash, cid, reward FROM eth.uncle_cids
 				WHERE header_id = $1`
 	return headers, tx.Select(&headers, pgStr, headerID)
 }
@@ -306,6 +308,9 @@ func (ecr *CIDRetriever) RetrieveRctCIDs(tx *sqlx.Tx, rctFilter ReceiptFilter, b
 		pgStr += fmt.Sprintf(` AND header_cids.block_hash = $%d`, id)
 		args = append(args, blockHash.String())
 		id++
+	} else {
+		// Without a block hash to pin the header we default to the canonical chain
+		pgStr += ` AND header_cids.canonical = true`
 	}
 	if len(rctFilter.LogAddresses) > 0 {
 		// Filter on log contract addresses if there are any
@@ -442,6 +447,7 @@ func (ecr *CIDRetriever) RetrieveStorageCIDs(tx *sqlx.Tx, storageFilter StorageF
 
 // RetrieveGapsInData is used to find the the block numbers at which we are missing data in the db
 // it finds the union of heights where no data exists and where the times_validated is lower than the validation level
+// a validation level of 0 finds only the heights where no data exists
 func (ecr *CIDRetriever) RetrieveGapsInData(validationLevel int) ([]shared.Gap, error) {
 	log.Info("searching for gaps in the eth super node database")
 	startingBlock, err := ecr.RetrieveFirstBlockNumber()
@@ -480,14 +486,51 @@ func (ecr *CIDRetriever) RetrieveGapsInData(validationLevel int) ([]shared.Gap,
 
 	// Find sections of blocks where we are below the validation level
 	// There will be no overlap between these "gaps" and the ones above
-	pgStr = `SELECT block_number FROM eth.header_cids
-			WHERE times_validated < $1
+	validationGaps, err := ecr.RetrieveValidationGaps(validationLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	// Find the known gaps recorded for data that was spilled or dropped at the head of the chain
+	// Known gaps below the latest indexed block are already covered by the empty gaps above
+	pgStr = `SELECT block_number FROM eth.known_gaps
+			WHERE block_number > (SELECT max(block_number) FROM eth.header_cids)
+			ORDER BY block_number`
+	var knownHeights []uint64
+	if err := ecr.db.Select(&knownHeights, pgStr); err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	gaps := append(append(initialGap, emptyGaps...), validationGaps...)
+	return append(gaps, utils.MissingHeightsToGaps(knownHeights)...), nil
+}
+
+// RetrieveValidationGaps finds the sections of blocks which are indexed but have been validated fewer than validationLevel times
+// Only the canonical header at a height counts, so heights with orphaned headers are not revalidated once their canonical header has been,
+// while heights left without a canonical header by a reorg are
+func (ecr *CIDRetriever) RetrieveValidationGaps(validationLevel int) ([]shared.Gap, error) {
+	pgStr := `SELECT DISTINCT block_number FROM eth.header_cids
+			WHERE NOT EXISTS (
+				SELECT 1 FROM eth.header_cids AS canonical_headers
+				WHERE canonical_headers.block_number = header_cids.block_number
+				AND canonical_headers.canonical = true
+				AND canonical_headers.times_validated >= $1
+			)
 			ORDER BY block_number`
 	var heights []uint64
 	if err := ecr.db.Select(&heights, pgStr, validationLevel); err != nil && err != sql.ErrNoRows {
 		return nil, err
 	}
-	return append(append(initialGap, emptyGaps...), utils.MissingHeightsToGaps(heights)...), nil
+	return utils.MissingHeightsToGaps(heights), nil
+}
+
+// RetrieveValidationLevels returns the number of canonical blocks that have been validated each number of times
+func (ecr *CIDRetriever) RetrieveValidationLevels() ([]shared.ValidationLevel, error) {
+	pgStr := `SELECT times_validated, COUNT(*) AS blocks FROM eth.header_cids
+			WHERE canonical = true
+			GROUP BY times_validated
+			ORDER BY times_validated`
+	levels := make([]shared.ValidationLevel, 0)
+	return levels, ecr.db.Select(&levels, pgStr)
 }
 
 // RetrieveBlockByHash returns all of the CIDs needed to compose an entire block, for a given block hash
@@ -597,7 +640,7 @@ func (ecr *CIDRetriever) RetrieveHeaderCIDByHash(tx *sqlx.Tx, blockHash common.H
 // RetrieveTxCIDsByHeaderID retrieves all tx CIDs for the given header id
 func (ecr *CIDRetriever) RetrieveTxCIDsByHeaderID(tx *sqlx.Tx, headerID int64) ([]TxModel, error) {
 	log.Debug("retrieving tx cids for block id ", headerID)
-	pgStr := `SELECT * FROM eth.transaction_cids
+	pgStr := `SELECT id, header_id, tx_hash, index, cid, dst, src FROM eth.transaction_cids
 			WHERE header_id = $1
 			ORDER BY index`
 	var txCIDs []TxModel
@@ -617,3 +660,60 @@ func (ecr *CIDRetriever) RetrieveReceiptCIDsByTxIDs(tx *sqlx.Tx, txIDs []int64)
 	var rctCIDs []ReceiptModel
 	return rctCIDs, tx.Select(&rctCIDs, pgStr, pq.Array(txIDs))
 }
+
+// RetrieveHeaderCIDByID returns the header for the given header id
+func (ecr *CIDRetriever) RetrieveHeaderCIDByID(tx *sqlx.Tx, headerID int64) (HeaderModel, error) {
+	log.Debug("retrieving header cid for header id ", headerID)
+	pgStr := `SELECT * FROM eth.header_cids
+			WHERE id = $1`
+	var headerCID HeaderModel
+	return headerCID, tx.Get(&headerCID, pgStr, headerID)
+}
+
+// RetrieveTxCIDByHash returns the canonical tx cid for the given tx hash
+func (ecr *CIDRetriever) RetrieveTxCIDByHash(tx *sqlx.Tx, txHash common.Hash) (TxModel, error) {
+	log.Debug("retrieving tx cid for tx hash ", txHash.String())
+	pgStr := `SELECT transaction_cids.id, transaction_cids.header_id,
+ 			transaction_cids.tx_hash, transaction_cids.cid,
+ 			transaction_cids.dst, transaction_cids.src, transaction_cids.index
+ 			FROM eth.transaction_cids INNER JOIN eth.header_cids ON (transaction_cids.header_id = header_cids.id)
+			WHERE transaction_cids.tx_hash = $1
+			AND header_cids.canonical = true`
+	var txCID TxModel
+	return txCID, tx.Get(&txCID, pgStr, txHash.String())
+}
+
+// RetrieveStateCIDAt retrieves the most recent canonical state node cid for the provided state leaf key
+// at or below the provided block height
+func (ecr *CIDRetriever) RetrieveStateCIDAt(tx *sqlx.Tx, stateLeafKey common.Hash, blockNumber int64) (StateNodeModel, error) {
+	log.Debugf("retrieving state cid for leaf key %s at block %d", stateLeafKey.String(), blockNumber)
+	pgStr := `SELECT state_cids.id, state_cids.header_id,
+			state_cids.state_leaf_key, state_cids.node_type, state_cids.cid, state_cids.state_path
+			FROM eth.state_cids INNER JOIN eth.header_cids ON (state_cids.header_id = header_cids.id)
+			WHERE state_cids.state_leaf_key = $1
+			AND header_cids.block_number <= $2
+			AND header_cids.canonical = true
+			ORDER BY header_cids.block_number DESC
+			LIMIT 1`
+	var stateCID StateNodeModel
+	return stateCID, tx.Get(&stateCID, pgStr, stateLeafKey.String(), blockNumber)
+}
+
+// RetrieveStorageCIDAt retrieves the most recent canonical storage node cid for the provided state and storage leaf keys
+// at or below the provided block height
+func (ecr *CIDRetriever) RetrieveStorageCIDAt(tx *sqlx.Tx, stateLeafKey, storageLeafKey common.Hash, blockNumber int64) (StorageNodeWithStateKeyModel, error) {
+	log.Debugf("retrieving storage cid for leaf keys %s and %s at block %d", stateLeafKey.String(), storageLeafKey.String(), blockNumber)
+	pgStr := `SELECT storage_cids.id, storage_cids.state_id, storage_cids.storage_leaf_key,
+			storage_cids.node_type, storage_cids.cid, storage_cids.storage_path, state_cids.state_leaf_key
+			FROM eth.storage_cids, eth.state_cids, eth.header_cids
+			WHERE storage_cids.state_id = state_cids.id
+			AND state_cids.header_id = header_cids.id
+			AND state_cids.state_leaf_key = $1
+			AND storage_cids.storage_leaf_key = $2
+			AND header_cids.block_number <= $3
+			AND header_cids.canonical = true
+			ORDER BY header_cids.block_number DESC
+			LIMIT 1`
+	var storageCID StorageNodeWithStateKeyModel
+	return storageCID, tx.Get(&storageCID, pgStr, stateLeafKey.String(), storageLeafKey.String(), blockNumber)
+}
@@ -236,6 +236,7 @@ var _ = Describe("Retriever", func() {
 			cidWrapper, ok := cids[0].(*eth.CIDWrapper)
 			Expect(ok).To(BeTrue())
 			Expect(cidWrapper.BlockNumber).To(Equal(mocks.MockCIDWrapper.BlockNumber))
+			Expect(cidWrapper.BlockHash).To(Equal(mocks.MockBlock.Hash().String()))
 			expectedHeaderCID := mocks.MockCIDWrapper.Header
 			expectedHeaderCID.ID = cidWrapper.Header.ID
 			expectedHeaderCID.NodeID = cidWrapper.Header.NodeID
@@ -674,4 +675,52 @@ var _ = Describe("Retriever", func() {
 			Expect(shared.ListContainsGap(gaps, shared.Gap{Start: 1001, Stop: 1010100})).To(BeTrue())
 		})
 	})
+
+	Describe("RetrieveValidationGaps", func() {
+		It("Only counts the canonical header at each height", func() {
+			// b1 replaces a1 once a1's child a2 has been indexed, leaving no canonical header at height 2
+			a1 := *mocks.MockCIDPayload
+			a1.HeaderCID.BlockNumber = "1"
+			a1.HeaderCID.BlockHash = common.HexToHash("0xa1").String()
+			a2 := *mocks.MockCIDPayload
+			a2.HeaderCID.BlockNumber = "2"
+			a2.HeaderCID.BlockHash = common.HexToHash("0xa2").String()
+			a2.HeaderCID.ParentHash = a1.HeaderCID.BlockHash
+			b1 := a1
+			b1.HeaderCID.BlockHash = common.HexToHash("0xb1").String()
+			for _, payload := range []eth.CIDPayload{a1, a2, b1, b1} {
+				err := repo.Index(&payload)
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			gaps, err := retriever.RetrieveValidationGaps(2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(gaps).To(Equal([]shared.Gap{{Start: 2, Stop: 2}}))
+		})
+	})
+
+	Describe("RetrieveValidationLevels", func() {
+		It("Counts the blocks that have been validated each number of times", func() {
+			payload0 := *mocks.MockCIDPayload
+			payload0.HeaderCID.BlockNumber = "0"
+			payload1 := *mocks.MockCIDPayload
+			payload1.HeaderCID.BlockNumber = "1"
+			payload2 := *mocks.MockCIDPayload
+			payload2.HeaderCID.BlockNumber = "2"
+			err := repo.Index(&payload0)
+			Expect(err).ToNot(HaveOccurred())
+			err = repo.Index(&payload1)
+			Expect(err).ToNot(HaveOccurred())
+			err = repo.Index(&payload2)
+			Expect(err).ToNot(HaveOccurred())
+			err = repo.Index(&payload2)
+			Expect(err).ToNot(HaveOccurred())
+			levels, err := retriever.RetrieveValidationLevels()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(levels).To(Equal([]shared.ValidationLevel{
+				{TimesValidated: 1, Blocks: 2},
+				{TimesValidated: 2, Blocks: 1},
+			}))
+		})
+	})
 })
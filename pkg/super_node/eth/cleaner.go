@@ -28,13 +28,17 @@ import (
 
 // Cleaner satisfies the shared.Cleaner interface fo ethereum
 type Cleaner struct {
-	db *postgres.DB
+	db          *postgres.DB
+	partitioner *Partitioner
+	// If true, the partitions that lie within a cleaned range are detached and kept rather than dropped
+	DetachPartitions bool
 }
 
 // NewCleaner returns a new Cleaner struct that satisfies the shared.Cleaner interface
 func NewCleaner(db *postgres.DB) *Cleaner {
 	return &Cleaner{
-		db: db,
+		db:          db,
+		partitioner: NewPartitioner(db),
 	}
 }
 
@@ -58,59 +62,151 @@ func (c *Cleaner) ResetValidation(rngs [][2]uint64) error {
 }
 
 // Clean removes the specified data from the db within the provided block range
+// The partitions of the cleaned tables that lie entirely within a range are removed whole, rows are only deleted from the partitions it covers in part
 func (c *Cleaner) Clean(rngs [][2]uint64, t shared.DataType) error {
+	tables, err := partitionedTables(t)
+	if err != nil {
+		return err
+	}
 	tx, err := c.db.Beginx()
 	if err != nil {
 		return err
 	}
+	rowsDeleted := false
 	for _, rng := range rngs {
 		logrus.Infof("eth db cleaner cleaning up block range %d to %d", rng[0], rng[1])
-		if err := c.clean(tx, rng, t); err != nil {
+		// The IPLDs are found through the index, so they are removed before it is
+		if err := c.cleanIPLDs(tx, rng, t); err != nil {
+			shared.Rollback(tx)
+			return err
+		}
+		partitions, err := c.partitioner.Partitions(tx, tables[len(tables)-1])
+		if err != nil {
 			shared.Rollback(tx)
 			return err
 		}
+		covered := make([]Partition, 0, len(partitions))
+		for _, partition := range partitions {
+			if partition.Start >= rng[0] && partition.Stop <= rng[1] {
+				covered = append(covered, partition)
+			}
+		}
+		for _, partition := range covered {
+			if err := c.removePartitions(tx, tables, partition); err != nil {
+				shared.Rollback(tx)
+				return err
+			}
+		}
+		for _, uncovered := range uncoveredRanges(rng, covered) {
+			rowsDeleted = true
+			if err := c.cleanMetaData(tx, uncovered, t); err != nil {
+				shared.Rollback(tx)
+				return err
+			}
+		}
 	}
 	if err := tx.Commit(); err != nil {
 		return err
 	}
+	if !rowsDeleted {
+		logrus.Infof("eth db cleaner vacuum analyzing public.blocks to free up space from deleted rows")
+		return c.vacuumIPLDs()
+	}
 	logrus.Infof("eth db cleaner vacuum analyzing cleaned tables to free up space from deleted rows")
 	return c.vacuumAnalyze(t)
 }
 
-func (c *Cleaner) clean(tx *sqlx.Tx, rng [2]uint64, t shared.DataType) error {
+// partitionedTables returns the partitioned tables holding the provided type of data, the tables referencing another come first
+func partitionedTables(t shared.DataType) ([]string, error) {
 	switch t {
 	case shared.Full, shared.Headers:
-		return c.cleanFull(tx, rng)
+		return []string{"storage_cids", "state_accounts", "state_cids", "receipt_cids", "transaction_cids", "uncle_cids", "header_cids"}, nil
 	case shared.Uncles:
-		if err := c.cleanUncleIPLDs(tx, rng); err != nil {
+		return []string{"uncle_cids"}, nil
+	case shared.Transactions:
+		return []string{"receipt_cids", "transaction_cids"}, nil
+	case shared.Receipts:
+		return []string{"receipt_cids"}, nil
+	case shared.State:
+		return []string{"storage_cids", "state_accounts", "state_cids"}, nil
+	case shared.Storage:
+		return []string{"storage_cids"}, nil
+	default:
+		return nil, fmt.Errorf("eth cleaner unrecognized type: %s", t.String())
+	}
+}
+
+// uncoveredRanges returns the parts of the block range not covered by the provided partitions, which lie within the range in ascending order
+func uncoveredRanges(rng [2]uint64, partitions []Partition) [][2]uint64 {
+	uncovered := make([][2]uint64, 0, 1)
+	next := rng[0]
+	for _, partition := range partitions {
+		if partition.Start > next {
+			uncovered = append(uncovered, [2]uint64{next, partition.Start - 1})
+		}
+		next = partition.Stop + 1
+	}
+	if next <= rng[1] {
+		uncovered = append(uncovered, [2]uint64{next, rng[1]})
+	}
+	return uncovered
+}
+
+func (c *Cleaner) removePartitions(tx *sqlx.Tx, tables []string, partition Partition) error {
+	for _, table := range tables {
+		if c.DetachPartitions {
+			logrus.Infof("eth db cleaner detaching the eth.%s partition for block range %d to %d", table, partition.Start, partition.Stop)
+			if err := c.partitioner.DetachPartition(tx, table, partition.Start); err != nil {
+				return err
+			}
+			continue
+		}
+		logrus.Infof("eth db cleaner dropping the eth.%s partition for block range %d to %d", table, partition.Start, partition.Stop)
+		if err := c.partitioner.DropPartition(tx, table, partition.Start); err != nil {
 			return err
 		}
-		return c.cleanUncleMetaData(tx, rng)
+	}
+	return nil
+}
+
+func (c *Cleaner) cleanIPLDs(tx *sqlx.Tx, rng [2]uint64, t shared.DataType) error {
+	switch t {
+	case shared.Full, shared.Headers:
+		return c.cleanFullIPLDs(tx, rng)
+	case shared.Uncles:
+		return c.cleanUncleIPLDs(tx, rng)
 	case shared.Transactions:
 		if err := c.cleanReceiptIPLDs(tx, rng); err != nil {
 			return err
 		}
-		if err := c.cleanTransactionIPLDs(tx, rng); err != nil {
+		return c.cleanTransactionIPLDs(tx, rng)
+	case shared.Receipts:
+		return c.cleanReceiptIPLDs(tx, rng)
+	case shared.State:
+		if err := c.cleanStorageIPLDs(tx, rng); err != nil {
 			return err
 		}
+		return c.cleanStateIPLDs(tx, rng)
+	case shared.Storage:
+		return c.cleanStorageIPLDs(tx, rng)
+	default:
+		return fmt.Errorf("eth cleaner unrecognized type: %s", t.String())
+	}
+}
+
+func (c *Cleaner) cleanMetaData(tx *sqlx.Tx, rng [2]uint64, t shared.DataType) error {
+	switch t {
+	case shared.Full, shared.Headers:
+		return c.cleanHeaderMetaData(tx, rng)
+	case shared.Uncles:
+		return c.cleanUncleMetaData(tx, rng)
+	case shared.Transactions:
 		return c.cleanTransactionMetaData(tx, rng)
 	case shared.Receipts:
-		if err := c.cleanReceiptIPLDs(tx, rng); err != nil {
-			return err
-		}
 		return c.cleanReceiptMetaData(tx, rng)
 	case shared.State:
-		if err := c.cleanStorageIPLDs(tx, rng); err != nil {
-			return err
-		}
-		if err := c.cleanStateIPLDs(tx, rng); err != nil {
-			return err
-		}
 		return c.cleanStateMetaData(tx, rng)
 	case shared.Storage:
-		if err := c.cleanStorageIPLDs(tx, rng); err != nil {
-			return err
-		}
 		return c.cleanStorageMetaData(tx, rng)
 	default:
 		return fmt.Errorf("eth cleaner unrecognized type: %s", t.String())
@@ -218,7 +314,7 @@ func (c *Cleaner) vacuumIPLDs() error {
 	return err
 }
 
-func (c *Cleaner) cleanFull(tx *sqlx.Tx, rng [2]uint64) error {
+func (c *Cleaner) cleanFullIPLDs(tx *sqlx.Tx, rng [2]uint64) error {
 	if err := c.cleanStorageIPLDs(tx, rng); err != nil {
 		return err
 	}
@@ -234,10 +330,7 @@ func (c *Cleaner) cleanFull(tx *sqlx.Tx, rng [2]uint64) error {
 	if err := c.cleanUncleIPLDs(tx, rng); err != nil {
 		return err
 	}
-	if err := c.cleanHeaderIPLDs(tx, rng); err != nil {
-		return err
-	}
-	return c.cleanHeaderMetaData(tx, rng)
+	return c.cleanHeaderIPLDs(tx, rng)
 }
 
 func (c *Cleaner) cleanStorageIPLDs(tx *sqlx.Tx, rng [2]uint64) error {
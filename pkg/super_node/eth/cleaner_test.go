@@ -17,6 +17,7 @@
 package eth_test
 
 import (
+	"fmt"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -610,6 +611,139 @@ var _ = Describe("Cleaner", func() {
 			Expect(storageCount).To(Equal(0))
 			Expect(blocksCount).To(Equal(12))
 		})
+		It("Drops the partitions that lie within the range", func() {
+			err := cleaner.Clean([][2]uint64{{0, 99999}}, shared.Full)
+			Expect(err).ToNot(HaveOccurred())
+
+			var partition *string
+			pgStr := `SELECT to_regclass('eth.header_cids_0')::TEXT`
+			err = db.Get(&partition, pgStr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(partition).To(BeNil())
+			pgStr = `SELECT to_regclass('eth.storage_cids_0')::TEXT`
+			err = db.Get(&partition, pgStr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(partition).To(BeNil())
+			var headerCount int
+			pgStr = `SELECT COUNT(*) FROM eth.header_cids`
+			err = db.Get(&headerCount, pgStr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(headerCount).To(Equal(0))
+			var blocksCount int
+			pgStr = `SELECT COUNT(*) FROM public.blocks`
+			err = db.Get(&blocksCount, pgStr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(blocksCount).To(Equal(0))
+
+			// The partitions are created again when data in their range is indexed
+			err = repo.Index(mockCIDPayload1)
+			Expect(err).ToNot(HaveOccurred())
+			pgStr = `SELECT COUNT(*) FROM eth.header_cids_0`
+			err = db.Get(&headerCount, pgStr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(headerCount).To(Equal(1))
+		})
+		It("Detaches the partitions that lie within the range if configured to", func() {
+			cleaner.DetachPartitions = true
+			err := cleaner.Clean([][2]uint64{{0, 99999}}, shared.State)
+			Expect(err).ToNot(HaveOccurred())
+
+			var detached []string
+			pgStr := `SELECT tablename FROM pg_tables WHERE schemaname = 'eth' AND tablename LIKE '%\_0\_detached\_%' ORDER BY tablename`
+			err = db.Select(&detached, pgStr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(detached)).To(Equal(3))
+			Expect(detached[0]).To(HavePrefix("state_accounts_0_detached_"))
+			Expect(detached[1]).To(HavePrefix("state_cids_0_detached_"))
+			Expect(detached[2]).To(HavePrefix("storage_cids_0_detached_"))
+			var stateCount int
+			pgStr = `SELECT COUNT(*) FROM eth.state_cids`
+			err = db.Get(&stateCount, pgStr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stateCount).To(Equal(0))
+			pgStr = fmt.Sprintf(`SELECT COUNT(*) FROM eth.%s`, detached[1])
+			err = db.Get(&stateCount, pgStr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stateCount).To(Equal(3))
+			var headerCount int
+			pgStr = `SELECT COUNT(*) FROM eth.header_cids`
+			err = db.Get(&headerCount, pgStr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(headerCount).To(Equal(2))
+
+			for _, table := range detached {
+				_, err = db.Exec(fmt.Sprintf(`DROP TABLE eth.%s CASCADE`, table))
+				Expect(err).ToNot(HaveOccurred())
+			}
+		})
+		Describe("Ranges covering some partitions fully and another in part", func() {
+			// The headers at 100000 and 100001 lie in the second partition, only the first of them is within the cleaned range
+			mixedRange := [][2]uint64{{0, 100000}}
+			BeforeEach(func() {
+				for _, number := range []int64{100000, 100001} {
+					err := repo.Index(&eth.CIDPayload{
+						HeaderCID: eth2.HeaderModel{
+							BlockHash:       crypto.Keccak256Hash(big.NewInt(number).Bytes()).String(),
+							BlockNumber:     big.NewInt(number).String(),
+							CID:             fmt.Sprintf("mockHeaderCID%d", number),
+							ParentHash:      parentHash.String(),
+							TotalDifficulty: totalDifficulty,
+							Reward:          reward,
+						},
+					})
+					Expect(err).ToNot(HaveOccurred())
+				}
+			})
+
+			It("Drops the partitions within the range and deletes the rows in range from the partition it covers in part", func() {
+				err := cleaner.Clean(mixedRange, shared.Full)
+				Expect(err).ToNot(HaveOccurred())
+
+				var partition *string
+				err = db.Get(&partition, `SELECT to_regclass('eth.header_cids_0')::TEXT`)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(partition).To(BeNil())
+				err = db.Get(&partition, `SELECT to_regclass('eth.header_cids_100000')::TEXT`)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(partition).ToNot(BeNil())
+				var blockNumbers []string
+				err = db.Select(&blockNumbers, `SELECT block_number FROM eth.header_cids ORDER BY block_number`)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(blockNumbers).To(Equal([]string{"100001"}))
+			})
+
+			It("Detaches the partitions within the range and deletes the rows in range from the partition it covers in part", func() {
+				cleaner.DetachPartitions = true
+				err := cleaner.Clean(mixedRange, shared.Full)
+				Expect(err).ToNot(HaveOccurred())
+
+				var detached []string
+				pgStr := `SELECT tablename FROM pg_tables WHERE schemaname = 'eth' AND tablename LIKE '%\_detached\_%' ORDER BY tablename`
+				err = db.Select(&detached, pgStr)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(len(detached)).To(Equal(7))
+				for _, table := range detached {
+					Expect(table).To(MatchRegexp(`^[a-z_]+_0_detached_\d+$`))
+				}
+				var headerTable string
+				pgStr = `SELECT tablename FROM pg_tables WHERE schemaname = 'eth' AND tablename LIKE 'header\_cids\_0\_detached\_%'`
+				err = db.Get(&headerTable, pgStr)
+				Expect(err).ToNot(HaveOccurred())
+				var headerCount int
+				err = db.Get(&headerCount, fmt.Sprintf(`SELECT COUNT(*) FROM eth.%s`, headerTable))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(headerCount).To(Equal(2))
+				var blockNumbers []string
+				err = db.Select(&blockNumbers, `SELECT block_number FROM eth.header_cids ORDER BY block_number`)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(blockNumbers).To(Equal([]string{"100001"}))
+
+				for _, table := range detached {
+					_, err = db.Exec(fmt.Sprintf(`DROP TABLE eth.%s CASCADE`, table))
+					Expect(err).ToNot(HaveOccurred())
+				}
+			})
+		})
 	})
 
 	Describe("ResetValidation", func() {
@@ -0,0 +1,225 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/statediff"
+	"github.com/hashicorp/golang-lru"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	getCodeMethod  = "eth_getCode"
+	preimageMethod = "debug_preimage"
+	// fetchedCodeCacheSize is the number of code hashes remembered as fetched
+	fetchedCodeCacheSize = 100000
+)
+
+// CodeFetcher fetches the contract code that statediffs do not carry from the node the statediffs come from
+// It is thread-safe as long as the underlying client is thread-safe
+type CodeFetcher struct {
+	client  BatchClient
+	timeout time.Duration
+	// code hashes whose code has already been fetched, so that code is only requested once per process
+	fetched *lru.Cache
+}
+
+// NewCodeFetcher returns a CodeFetcher
+func NewCodeFetcher(bc BatchClient, timeout time.Duration) *CodeFetcher {
+	fetched, _ := lru.New(fetchedCodeCacheSize)
+	return &CodeFetcher{
+		client:  bc,
+		timeout: timeout,
+		fetched: fetched,
+	}
+}
+
+// FetchCode returns the code, keyed by code hash, of the accounts in the payload's state diff whose code has not already been fetched
+// State leaf keys are the keccak256 hashes of the account addresses, so the addresses are first resolved from the addresses
+// the block's transactions, receipts and logs touch, and any left over are requested with debug_preimage
+// The code is then requested with eth_getCode at the payload's block and checked against the account's code hash
+// Accounts whose address or code cannot be found are logged and skipped, only a failure to reach the node is returned as an error
+func (cf *CodeFetcher) FetchCode(payload ConvertedPayload) (map[common.Hash][]byte, error) {
+	// Collect the code hash of each account with code we have not fetched, keyed by leaf key
+	wanted := make(map[common.Hash]common.Hash)
+	for _, stateNode := range payload.StateNodes {
+		if stateNode.Type != statediff.Leaf {
+			continue
+		}
+		account, err := decodeAccount(stateNode.Value)
+		if err != nil {
+			return nil, err
+		}
+		codeHash := common.BytesToHash(account.CodeHash)
+		if codeHash == emptyCodeHash || cf.fetched.Contains(codeHash) {
+			continue
+		}
+		wanted[stateNode.LeafKey] = codeHash
+	}
+	if len(wanted) == 0 {
+		return nil, nil
+	}
+	addresses, err := cf.resolveAddresses(payload, wanted)
+	if err != nil {
+		return nil, err
+	}
+	return cf.getCode(payload, wanted, addresses)
+}
+
+// resolveAddresses returns the address of each wanted leaf key, and one address for each code hash
+func (cf *CodeFetcher) resolveAddresses(payload ConvertedPayload, wanted map[common.Hash]common.Hash) (map[common.Hash]common.Address, error) {
+	addresses := make(map[common.Hash]common.Address, len(wanted))
+	for _, addr := range touchedAddresses(payload) {
+		leafKey := crypto.Keccak256Hash(addr.Bytes())
+		if _, ok := wanted[leafKey]; ok {
+			addresses[leafKey] = addr
+		}
+	}
+	// Only one account is needed for each code hash
+	resolved := make(map[common.Hash]bool, len(wanted))
+	for leafKey := range addresses {
+		resolved[wanted[leafKey]] = true
+	}
+	batch := make([]rpc.BatchElem, 0)
+	for leafKey, codeHash := range wanted {
+		if resolved[codeHash] {
+			continue
+		}
+		resolved[codeHash] = true
+		batch = append(batch, rpc.BatchElem{
+			Method: preimageMethod,
+			Args:   []interface{}{leafKey},
+			Result: new(hexutil.Bytes),
+		})
+	}
+	if len(batch) == 0 {
+		return addresses, nil
+	}
+	if err := cf.batchCall(batch); err != nil {
+		return nil, fmt.Errorf("ethereum CodeFetcher preimage batch err at blockheight %d: %v", payload.Height(), err)
+	}
+	for _, batchElem := range batch {
+		leafKey := batchElem.Args[0].(common.Hash)
+		preimage := *batchElem.Result.(*hexutil.Bytes)
+		if batchElem.Error != nil || len(preimage) != common.AddressLength {
+			log.Warnf("ethereum CodeFetcher unable to resolve the address of state leaf key %s at blockheight %d, its code %s is not fetched: %v",
+				leafKey.Hex(), payload.Height(), wanted[leafKey].Hex(), batchElem.Error)
+			continue
+		}
+		addresses[leafKey] = common.BytesToAddress(preimage)
+	}
+	return addresses, nil
+}
+
+// getCode requests the code of one resolved account for each code hash
+func (cf *CodeFetcher) getCode(payload ConvertedPayload, wanted map[common.Hash]common.Hash, addresses map[common.Hash]common.Address) (map[common.Hash][]byte, error) {
+	blockHash := map[string]interface{}{"blockHash": payload.Block.Hash()}
+	batch := make([]rpc.BatchElem, 0, len(addresses))
+	requested := make(map[common.Hash]bool, len(addresses))
+	codeHashes := make([]common.Hash, 0, len(addresses))
+	for leafKey, addr := range addresses {
+		codeHash := wanted[leafKey]
+		if requested[codeHash] {
+			continue
+		}
+		requested[codeHash] = true
+		codeHashes = append(codeHashes, codeHash)
+		batch = append(batch, rpc.BatchElem{
+			Method: getCodeMethod,
+			Args:   []interface{}{addr, blockHash},
+			Result: new(hexutil.Bytes),
+		})
+	}
+	codes := make(map[common.Hash][]byte, len(batch))
+	if len(batch) == 0 {
+		return codes, nil
+	}
+	if err := cf.batchCall(batch); err != nil {
+		return nil, fmt.Errorf("ethereum CodeFetcher code batch err at blockheight %d: %v", payload.Height(), err)
+	}
+	for i, batchElem := range batch {
+		codeHash := codeHashes[i]
+		if batchElem.Error != nil {
+			log.Warnf("ethereum CodeFetcher unable to fetch code %s of %s at blockheight %d: %v",
+				codeHash.Hex(), batchElem.Args[0].(common.Address).Hex(), payload.Height(), batchElem.Error)
+			continue
+		}
+		code := []byte(*batchElem.Result.(*hexutil.Bytes))
+		if crypto.Keccak256Hash(code) != codeHash {
+			log.Warnf("ethereum CodeFetcher code of %s at blockheight %d does not match its code hash %s",
+				batchElem.Args[0].(common.Address).Hex(), payload.Height(), codeHash.Hex())
+			continue
+		}
+		codes[codeHash] = code
+		cf.fetched.Add(codeHash, struct{}{})
+	}
+	return codes, nil
+}
+
+func (cf *CodeFetcher) batchCall(batch []rpc.BatchElem) error {
+	ctx, cancel := context.WithTimeout(context.Background(), cf.timeout)
+	defer cancel()
+	return cf.client.BatchCallContext(ctx, batch)
+}
+
+// touchedAddresses returns the addresses the block's transactions, receipts and logs touch, and the block's coinbase
+func touchedAddresses(payload ConvertedPayload) []common.Address {
+	addrs := []common.Address{payload.Block.Coinbase()}
+	for _, txMeta := range payload.TxMetaData {
+		for _, addr := range []string{txMeta.Src, txMeta.Dst} {
+			if addr != "" {
+				addrs = append(addrs, common.HexToAddress(addr))
+			}
+		}
+	}
+	for _, rctMeta := range payload.ReceiptMetaData {
+		if rctMeta.Contract != "" {
+			addrs = append(addrs, common.HexToAddress(rctMeta.Contract))
+		}
+		for _, addr := range rctMeta.LogContracts {
+			addrs = append(addrs, common.HexToAddress(addr))
+		}
+	}
+	return addrs
+}
+
+// decodeAccount decodes the account held in a state leaf node
+func decodeAccount(leafNode []byte) (*state.Account, error) {
+	var i []interface{}
+	if err := rlp.DecodeBytes(leafNode, &i); err != nil {
+		return nil, err
+	}
+	if len(i) != 2 {
+		return nil, fmt.Errorf("eth expected state leaf node rlp to decode into two elements")
+	}
+	accountRLP, ok := i[1].([]byte)
+	if !ok {
+		return nil, fmt.Errorf("eth expected state leaf node to hold an rlp encoded account")
+	}
+	account := new(state.Account)
+	return account, rlp.DecodeBytes(accountRLP, account)
+}
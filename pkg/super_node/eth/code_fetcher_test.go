@@ -0,0 +1,226 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/statediff"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth"
+)
+
+var _ = Describe("CodeFetcher", func() {
+	var (
+		node      *testNode
+		fetcher   *eth.CodeFetcher
+		touched   = common.HexToAddress("0x1111111111111111111111111111111111111111")
+		untouched = common.HexToAddress("0x2222222222222222222222222222222222222222")
+		// PUSH1 0 SLOAD PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+		code      = []byte{0x60, 0x00, 0x54, 0x60, 0x00, 0x52, 0x60, 0x20, 0x60, 0x00, 0xf3}
+		otherCode = []byte{0x60, 0x2a, 0x60, 0x00, 0x52, 0x60, 0x20, 0x60, 0x00, 0xf3}
+	)
+	BeforeEach(func() {
+		node = newTestNode()
+		fetcher = eth.NewCodeFetcher(node.client(), time.Second*10)
+	})
+
+	It("Fetches the code of accounts touched by the block without resolving their addresses through the node", func() {
+		node.setCode(touched, code)
+		payload := codePayload([]common.Address{touched}, contractLeaf(touched, code))
+		codes, err := fetcher.FetchCode(payload)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(codes).To(Equal(map[common.Hash][]byte{crypto.Keccak256Hash(code): code}))
+		Expect(node.requests("eth_getCode")).To(Equal(1))
+		Expect(node.requests("debug_preimage")).To(Equal(0))
+		// The code is requested at the payload's block
+		Expect(node.blockHashes).To(Equal([]common.Hash{payload.Block.Hash()}))
+	})
+
+	It("Resolves the addresses of other accounts from the preimages of their leaf keys", func() {
+		node.setCode(untouched, otherCode)
+		payload := codePayload(nil, contractLeaf(untouched, otherCode))
+		codes, err := fetcher.FetchCode(payload)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(codes).To(Equal(map[common.Hash][]byte{crypto.Keccak256Hash(otherCode): otherCode}))
+		Expect(node.requests("debug_preimage")).To(Equal(1))
+		Expect(node.requests("eth_getCode")).To(Equal(1))
+	})
+
+	It("Only fetches each code once", func() {
+		node.setCode(touched, code)
+		node.setCode(untouched, code)
+		payload := codePayload([]common.Address{touched}, contractLeaf(touched, code), contractLeaf(untouched, code))
+		codes, err := fetcher.FetchCode(payload)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(codes).To(HaveLen(1))
+		codes, err = fetcher.FetchCode(payload)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(codes).To(BeEmpty())
+		Expect(node.requests("eth_getCode")).To(Equal(1))
+		Expect(node.requests("debug_preimage")).To(Equal(0))
+	})
+
+	It("Skips accounts without code", func() {
+		payload := codePayload([]common.Address{touched}, contractLeaf(touched, nil))
+		codes, err := fetcher.FetchCode(payload)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(codes).To(BeEmpty())
+		Expect(node.requests("eth_getCode")).To(Equal(0))
+	})
+
+	It("Skips code whose address is unknown or which does not match the code hash, and fetches it later", func() {
+		node.setCode(touched, otherCode)
+		payload := codePayload([]common.Address{touched}, contractLeaf(touched, code), contractLeaf(untouched, otherCode))
+		delete(node.preimages, crypto.Keccak256Hash(untouched.Bytes()))
+		codes, err := fetcher.FetchCode(payload)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(codes).To(BeEmpty())
+
+		node.setCode(touched, code)
+		node.setCode(untouched, otherCode)
+		codes, err = fetcher.FetchCode(payload)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(codes).To(HaveLen(2))
+	})
+
+	It("Returns an error if the node cannot be reached", func() {
+		fetcher = eth.NewCodeFetcher(failingBatchClient{}, time.Second)
+		_, err := fetcher.FetchCode(codePayload([]common.Address{touched}, contractLeaf(touched, code)))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("connection refused"))
+	})
+})
+
+// testNode serves the eth_getCode and debug_preimage methods the CodeFetcher calls
+type testNode struct {
+	mu          sync.Mutex
+	codes       map[common.Address][]byte
+	preimages   map[common.Hash][]byte
+	calls       map[string]int
+	blockHashes []common.Hash
+}
+
+func newTestNode() *testNode {
+	return &testNode{
+		codes:     make(map[common.Address][]byte),
+		preimages: make(map[common.Hash][]byte),
+		calls:     make(map[string]int),
+	}
+}
+
+// setCode sets the code of the address and records the preimage of its leaf key
+func (n *testNode) setCode(addr common.Address, code []byte) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.codes[addr] = code
+	n.preimages[crypto.Keccak256Hash(addr.Bytes())] = addr.Bytes()
+}
+
+func (n *testNode) requests(method string) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.calls[method]
+}
+
+// client returns an in-process rpc client to the node
+func (n *testNode) client() *rpc.Client {
+	server := rpc.NewServer()
+	Expect(server.RegisterName("eth", &testEthService{node: n})).ToNot(HaveOccurred())
+	Expect(server.RegisterName("debug", &testDebugService{node: n})).ToNot(HaveOccurred())
+	return rpc.DialInProc(server)
+}
+
+type testEthService struct {
+	node *testNode
+}
+
+func (s *testEthService) GetCode(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	s.node.mu.Lock()
+	defer s.node.mu.Unlock()
+	s.node.calls["eth_getCode"]++
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		s.node.blockHashes = append(s.node.blockHashes, hash)
+	}
+	return s.node.codes[address], nil
+}
+
+type testDebugService struct {
+	node *testNode
+}
+
+func (s *testDebugService) Preimage(ctx context.Context, hash common.Hash) (hexutil.Bytes, error) {
+	s.node.mu.Lock()
+	defer s.node.mu.Unlock()
+	s.node.calls["debug_preimage"]++
+	if preimage, ok := s.node.preimages[hash]; ok {
+		return preimage, nil
+	}
+	return nil, errors.New("unknown preimage")
+}
+
+type failingBatchClient struct{}
+
+func (failingBatchClient) BatchCallContext(ctx context.Context, batch []rpc.BatchElem) error {
+	return errors.New("dial tcp 127.0.0.1:8545: connect: connection refused")
+}
+
+// codePayload returns a payload whose block has a transaction to each of the touched addresses, and whose state diff holds the provided leaves
+func codePayload(touched []common.Address, leaves ...eth.TrieNode) eth.ConvertedPayload {
+	txMeta := make([]eth.TxModel, len(touched))
+	for i, addr := range touched {
+		txMeta[i] = eth.TxModel{Dst: addr.Hex(), Src: common.HexToAddress("0x3333333333333333333333333333333333333333").Hex()}
+	}
+	return eth.ConvertedPayload{
+		Block:      types.NewBlock(&types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(1)}, nil, nil, nil),
+		TxMetaData: txMeta,
+		StateNodes: leaves,
+	}
+}
+
+// contractLeaf returns a state leaf node for an account at the address with the provided code
+func contractLeaf(addr common.Address, code []byte) eth.TrieNode {
+	account, err := rlp.EncodeToBytes(state.Account{
+		Nonce:    1,
+		Balance:  big.NewInt(0),
+		Root:     common.HexToHash("0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421"),
+		CodeHash: crypto.Keccak256(code),
+	})
+	Expect(err).ToNot(HaveOccurred())
+	leafKey := crypto.Keccak256Hash(addr.Bytes())
+	leaf, err := rlp.EncodeToBytes([]interface{}{append([]byte{0x20}, leafKey.Bytes()...), account})
+	Expect(err).ToNot(HaveOccurred())
+	return eth.TrieNode{
+		Path:    leafKey.Bytes()[:1],
+		LeafKey: leafKey,
+		Value:   leaf,
+		Type:    statediff.Leaf,
+	}
+}
@@ -32,6 +32,8 @@ import (
 // PayloadConverter satisfies the PayloadConverter interface for ethereum
 type PayloadConverter struct {
 	chainConfig *params.ChainConfig
+	// If set, the contract code of the accounts in each state diff is fetched from the node, since statediffs do not carry it
+	CodeFetcher *CodeFetcher
 }
 
 // NewPayloadConverter creates a pointer to a new PayloadConverter which satisfies the PayloadConverter interface
@@ -148,5 +150,12 @@ func (pc *PayloadConverter) Convert(payload shared.RawChainData) (shared.Convert
 		}
 	}
 
+	if pc.CodeFetcher != nil {
+		codes, err := pc.CodeFetcher.FetchCode(convertedPayload)
+		if err != nil {
+			return nil, err
+		}
+		convertedPayload.Codes = codes
+	}
 	return convertedPayload, nil
 }
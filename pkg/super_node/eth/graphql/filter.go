@@ -0,0 +1,94 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth"
+)
+
+// logFilter holds the log filter criteria passed to a `logs` accessor
+type logFilter struct {
+	addresses []common.Address
+	topics    [][]common.Hash
+}
+
+func newLogFilter(addresses *[]common.Address, topics *[][]common.Hash) logFilter {
+	var filter logFilter
+	if addresses != nil {
+		filter.addresses = *addresses
+	}
+	if topics != nil {
+		filter.topics = *topics
+	}
+	return filter
+}
+
+// receiptFilter maps the log filter criteria onto a receipt filter, to narrow down the receipts which contain logs of interest
+func (f logFilter) receiptFilter() eth.ReceiptFilter {
+	topics := make([][]string, 4)
+	for i, topicSet := range f.topics {
+		if i > 3 {
+			// logs don't have more than 4 topics
+			break
+		}
+		for _, topic := range topicSet {
+			topics[i] = append(topics[i], topic.Hex())
+		}
+	}
+	return eth.ReceiptFilter{
+		LogAddresses: addressStrings(f.addresses),
+		Topics:       topics,
+	}
+}
+
+// matches returns true if the log was emitted by one of the addresses of interest and matches the topics of interest
+// The receipt filter matches receipts which contain logs of interest, not the logs themselves, so logs are checked individually
+func (f logFilter) matches(log *types.Log) bool {
+	if len(f.addresses) > 0 && !includesAddress(f.addresses, log.Address) {
+		return false
+	}
+	if len(f.topics) > len(log.Topics) {
+		return false
+	}
+	for i, topicSet := range f.topics {
+		if len(topicSet) > 0 && !includesHash(topicSet, log.Topics[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func includesAddress(addresses []common.Address, address common.Address) bool {
+	for _, addr := range addresses {
+		if addr == address {
+			return true
+		}
+	}
+	return false
+}
+
+func includesHash(hashes []common.Hash, hash common.Hash) bool {
+	for _, h := range hashes {
+		if h == hash {
+			return true
+		}
+	}
+	return false
+}
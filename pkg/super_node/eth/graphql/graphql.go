@@ -0,0 +1,1137 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/vulcanize/vulcanizedb/pkg/ipfs"
+	"github.com/vulcanize/vulcanizedb/pkg/postgres"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+// MaxBlockRange is the largest number of blocks that a single `blocks` or `logs` query may span
+const MaxBlockRange = 1000
+
+var (
+	errOmmerData  = errors.New("data is not available for ommer blocks")
+	emptyCodeHash = crypto.Keccak256Hash(nil)
+)
+
+// Account represents an Ethereum account at a particular block
+type Account struct {
+	backend     *eth.Backend
+	address     common.Address
+	blockNumber int64
+
+	mu       sync.Mutex
+	resolved bool
+	account  *state.Account
+	cid      string
+}
+
+// resolve fetches the account from the state leaf node for the address at the account's block, if it has not already been fetched
+// A nil account is returned if the account does not exist at that block
+func (a *Account) resolve() (*state.Account, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.resolved {
+		return a.account, nil
+	}
+	account, cid, err := a.backend.StateAccountAt(a.address, a.blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	a.account, a.cid, a.resolved = account, cid, true
+	return account, nil
+}
+
+func (a *Account) Address(ctx context.Context) (common.Address, error) {
+	return a.address, nil
+}
+
+func (a *Account) Balance(ctx context.Context) (hexutil.Big, error) {
+	account, err := a.resolve()
+	if err != nil || account == nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*account.Balance), nil
+}
+
+func (a *Account) TransactionCount(ctx context.Context) (hexutil.Uint64, error) {
+	account, err := a.resolve()
+	if err != nil || account == nil {
+		return 0, err
+	}
+	return hexutil.Uint64(account.Nonce), nil
+}
+
+func (a *Account) CodeHash(ctx context.Context) (common.Hash, error) {
+	account, err := a.resolve()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if account == nil {
+		return emptyCodeHash, nil
+	}
+	return common.BytesToHash(account.CodeHash), nil
+}
+
+func (a *Account) StorageRoot(ctx context.Context) (common.Hash, error) {
+	account, err := a.resolve()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if account == nil {
+		return types.EmptyRootHash, nil
+	}
+	return account.Root, nil
+}
+
+func (a *Account) Storage(ctx context.Context, args struct{ Slot common.Hash }) (common.Hash, error) {
+	value, _, err := a.backend.StorageValueAt(a.address, args.Slot, a.blockNumber)
+	return common.BytesToHash(value), err
+}
+
+func (a *Account) CID(ctx context.Context) (*string, error) {
+	account, err := a.resolve()
+	if err != nil || account == nil {
+		return nil, err
+	}
+	return &a.cid, nil
+}
+
+// Log represents an individual log
+type Log struct {
+	backend     *eth.Backend
+	transaction *Transaction
+	log         *types.Log
+	index       int32
+}
+
+func (l *Log) Transaction(ctx context.Context) *Transaction {
+	return l.transaction
+}
+
+func (l *Log) Account(ctx context.Context, args BlockNumberArgs) (*Account, error) {
+	block, err := l.transaction.resolveBlock()
+	if err != nil {
+		return nil, err
+	}
+	return &Account{
+		backend:     l.backend,
+		address:     l.log.Address,
+		blockNumber: args.NumberOr(block.number()),
+	}, nil
+}
+
+func (l *Log) Index(ctx context.Context) int32 {
+	return l.index
+}
+
+func (l *Log) Topics(ctx context.Context) []common.Hash {
+	return l.log.Topics
+}
+
+func (l *Log) Data(ctx context.Context) hexutil.Bytes {
+	return l.log.Data
+}
+
+// Transaction represents an indexed Ethereum transaction
+type Transaction struct {
+	backend *eth.Backend
+	model   eth.TxModel
+
+	mu        sync.Mutex
+	block     *Block
+	tx        *types.Transaction
+	rctModel  *eth.ReceiptModel
+	receipt   *types.Receipt
+	noReceipt bool
+}
+
+// resolve fetches and decodes the transaction IPLD, if it has not already been fetched
+func (t *Transaction) resolve() (*types.Transaction, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.tx != nil {
+		return t.tx, nil
+	}
+	var txIPLDs []ipfs.BlockModel
+	err := withTx(t.backend.DB, func(tx *sqlx.Tx) error {
+		iplds, err := t.backend.Fetcher.FetchTrxs(tx, []eth.TxModel{t.model})
+		txIPLDs = iplds
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(txIPLDs) < 1 {
+		return nil, fmt.Errorf("transaction %s is not available", t.model.CID)
+	}
+	transaction := new(types.Transaction)
+	if err := rlp.DecodeBytes(txIPLDs[0].Data, transaction); err != nil {
+		return nil, err
+	}
+	t.tx = transaction
+	return transaction, nil
+}
+
+// resolveBlock returns the block the transaction was included in, fetching it if it has not already been fetched
+func (t *Transaction) resolveBlock() (*Block, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.block != nil {
+		return t.block, nil
+	}
+	var headerCID eth.HeaderModel
+	err := withTx(t.backend.DB, func(tx *sqlx.Tx) error {
+		var err error
+		headerCID, err = t.backend.Retriever.RetrieveHeaderCIDByID(tx, t.model.HeaderID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	t.block = &Block{backend: t.backend, model: headerCID}
+	return t.block, nil
+}
+
+// resolveReceipt fetches and decodes the transaction's receipt IPLD, if it has not already been fetched
+// A nil receipt is returned if the receipt is not available
+func (t *Transaction) resolveReceipt() (*types.Receipt, *eth.ReceiptModel, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.receipt != nil || t.noReceipt {
+		return t.receipt, t.rctModel, nil
+	}
+	var rctIPLDs []ipfs.BlockModel
+	err := withTx(t.backend.DB, func(tx *sqlx.Tx) error {
+		if t.rctModel == nil {
+			rctCIDs, err := t.backend.Retriever.RetrieveReceiptCIDsByTxIDs(tx, []int64{t.model.ID})
+			if err != nil {
+				return err
+			}
+			if len(rctCIDs) < 1 {
+				return nil
+			}
+			t.rctModel = &rctCIDs[0]
+		}
+		iplds, err := t.backend.Fetcher.FetchRcts(tx, []eth.ReceiptModel{*t.rctModel})
+		rctIPLDs = iplds
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rctIPLDs) < 1 {
+		t.noReceipt = true
+		return nil, nil, nil
+	}
+	receipt := new(types.Receipt)
+	if err := rlp.DecodeBytes(rctIPLDs[0].Data, receipt); err != nil {
+		return nil, nil, err
+	}
+	t.receipt = receipt
+	return receipt, t.rctModel, nil
+}
+
+func (t *Transaction) Hash(ctx context.Context) common.Hash {
+	return common.HexToHash(t.model.TxHash)
+}
+
+func (t *Transaction) InputData(ctx context.Context) (hexutil.Bytes, error) {
+	tx, err := t.resolve()
+	if err != nil {
+		return hexutil.Bytes{}, err
+	}
+	return tx.Data(), nil
+}
+
+func (t *Transaction) Gas(ctx context.Context) (hexutil.Uint64, error) {
+	tx, err := t.resolve()
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(tx.Gas()), nil
+}
+
+func (t *Transaction) GasPrice(ctx context.Context) (hexutil.Big, error) {
+	tx, err := t.resolve()
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*tx.GasPrice()), nil
+}
+
+func (t *Transaction) Value(ctx context.Context) (hexutil.Big, error) {
+	tx, err := t.resolve()
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*tx.Value()), nil
+}
+
+func (t *Transaction) Nonce(ctx context.Context) (hexutil.Uint64, error) {
+	tx, err := t.resolve()
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(tx.Nonce()), nil
+}
+
+func (t *Transaction) To(ctx context.Context, args BlockNumberArgs) (*Account, error) {
+	tx, err := t.resolve()
+	if err != nil || tx.To() == nil {
+		return nil, err
+	}
+	return t.account(*tx.To(), args)
+}
+
+func (t *Transaction) From(ctx context.Context, args BlockNumberArgs) (*Account, error) {
+	return t.account(common.HexToAddress(t.model.Src), args)
+}
+
+// account returns the account at the address as of the provided block, or the transaction's block if none is provided
+func (t *Transaction) account(address common.Address, args BlockNumberArgs) (*Account, error) {
+	block, err := t.resolveBlock()
+	if err != nil {
+		return nil, err
+	}
+	return &Account{
+		backend:     t.backend,
+		address:     address,
+		blockNumber: args.NumberOr(block.number()),
+	}, nil
+}
+
+func (t *Transaction) Block(ctx context.Context) (*Block, error) {
+	return t.resolveBlock()
+}
+
+func (t *Transaction) Index(ctx context.Context) *int32 {
+	index := int32(t.model.Index)
+	return &index
+}
+
+func (t *Transaction) Status(ctx context.Context) (*hexutil.Uint64, error) {
+	receipt, _, err := t.resolveReceipt()
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	status := hexutil.Uint64(receipt.Status)
+	return &status, nil
+}
+
+func (t *Transaction) CumulativeGasUsed(ctx context.Context) (*hexutil.Uint64, error) {
+	receipt, _, err := t.resolveReceipt()
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	gasUsed := hexutil.Uint64(receipt.CumulativeGasUsed)
+	return &gasUsed, nil
+}
+
+func (t *Transaction) CreatedContract(ctx context.Context, args BlockNumberArgs) (*Account, error) {
+	_, rctModel, err := t.resolveReceipt()
+	if err != nil || rctModel == nil || rctModel.Contract == "" {
+		return nil, err
+	}
+	return t.account(common.HexToAddress(rctModel.Contract), args)
+}
+
+func (t *Transaction) Logs(ctx context.Context) (*[]*Log, error) {
+	receipt, _, err := t.resolveReceipt()
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	logs := make([]*Log, 0, len(receipt.Logs))
+	for i, log := range receipt.Logs {
+		logs = append(logs, &Log{
+			backend:     t.backend,
+			transaction: t,
+			log:         log,
+			index:       int32(i),
+		})
+	}
+	return &logs, nil
+}
+
+func (t *Transaction) R(ctx context.Context) (hexutil.Big, error) {
+	tx, err := t.resolve()
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	_, r, _ := tx.RawSignatureValues()
+	return hexutil.Big(*r), nil
+}
+
+func (t *Transaction) S(ctx context.Context) (hexutil.Big, error) {
+	tx, err := t.resolve()
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	_, _, s := tx.RawSignatureValues()
+	return hexutil.Big(*s), nil
+}
+
+func (t *Transaction) V(ctx context.Context) (hexutil.Big, error) {
+	tx, err := t.resolve()
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	v, _, _ := tx.RawSignatureValues()
+	return hexutil.Big(*v), nil
+}
+
+func (t *Transaction) CID(ctx context.Context) string {
+	return t.model.CID
+}
+
+func (t *Transaction) ReceiptCID(ctx context.Context) (*string, error) {
+	_, rctModel, err := t.resolveReceipt()
+	if err != nil || rctModel == nil {
+		return nil, err
+	}
+	return &rctModel.CID, nil
+}
+
+// Block represents an indexed Ethereum block, or an ommer of one
+type Block struct {
+	backend *eth.Backend
+	// the header cid of an indexed block, only the CID is set for an ommer block
+	model eth.HeaderModel
+	ommer bool
+
+	mu     sync.Mutex
+	header *types.Header
+	txs    []eth.TxModel
+	ommers []*Block
+}
+
+// resolveHeader fetches and decodes the block's header IPLD, if it has not already been fetched
+func (b *Block) resolveHeader() (*types.Header, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.header != nil {
+		return b.header, nil
+	}
+	var headerIPLD ipfs.BlockModel
+	err := withTx(b.backend.DB, func(tx *sqlx.Tx) error {
+		var err error
+		headerIPLD, err = b.backend.Fetcher.FetchHeader(tx, b.model)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(headerIPLD.Data, header); err != nil {
+		return nil, err
+	}
+	b.header = header
+	return header, nil
+}
+
+// resolveTxs retrieves the CIDs of all of the block's transactions, if they have not already been retrieved
+func (b *Block) resolveTxs() ([]eth.TxModel, error) {
+	if b.ommer {
+		return nil, errOmmerData
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.txs != nil {
+		return b.txs, nil
+	}
+	var txCIDs []eth.TxModel
+	err := withTx(b.backend.DB, func(tx *sqlx.Tx) error {
+		var err error
+		txCIDs, err = b.backend.Retriever.RetrieveTxCIDsByHeaderID(tx, b.model.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if txCIDs == nil {
+		txCIDs = make([]eth.TxModel, 0)
+	}
+	b.txs = txCIDs
+	return txCIDs, nil
+}
+
+// resolveOmmers fetches and decodes the block's uncle IPLDs, if they have not already been fetched
+func (b *Block) resolveOmmers() ([]*Block, error) {
+	if b.ommer {
+		return nil, errOmmerData
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.ommers != nil {
+		return b.ommers, nil
+	}
+	var uncleIPLDs []ipfs.BlockModel
+	err := withTx(b.backend.DB, func(tx *sqlx.Tx) error {
+		uncleCIDs, err := b.backend.Retriever.RetrieveUncleCIDsByHeaderID(tx, b.model.ID)
+		if err != nil {
+			return err
+		}
+		uncleIPLDs, err = b.backend.Fetcher.FetchUncles(tx, uncleCIDs)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	ommers := make([]*Block, 0, len(uncleIPLDs))
+	for _, uncleIPLD := range uncleIPLDs {
+		uncle := new(types.Header)
+		if err := rlp.DecodeBytes(uncleIPLD.Data, uncle); err != nil {
+			return nil, err
+		}
+		ommers = append(ommers, &Block{
+			backend: b.backend,
+			model:   eth.HeaderModel{CID: uncleIPLD.CID},
+			ommer:   true,
+			header:  uncle,
+		})
+	}
+	b.ommers = ommers
+	return ommers, nil
+}
+
+// number returns the height of an indexed block
+func (b *Block) number() int64 {
+	number, _ := new(big.Int).SetString(b.model.BlockNumber, 10)
+	if number == nil {
+		return 0
+	}
+	return number.Int64()
+}
+
+func (b *Block) Number(ctx context.Context) (hexutil.Uint64, error) {
+	header, err := b.resolveHeader()
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(header.Number.Uint64()), nil
+}
+
+func (b *Block) Hash(ctx context.Context) (common.Hash, error) {
+	header, err := b.resolveHeader()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return header.Hash(), nil
+}
+
+func (b *Block) GasLimit(ctx context.Context) (hexutil.Uint64, error) {
+	header, err := b.resolveHeader()
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(header.GasLimit), nil
+}
+
+func (b *Block) GasUsed(ctx context.Context) (hexutil.Uint64, error) {
+	header, err := b.resolveHeader()
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(header.GasUsed), nil
+}
+
+func (b *Block) Parent(ctx context.Context) (*Block, error) {
+	header, err := b.resolveHeader()
+	if err != nil || header.Number.Sign() == 0 {
+		return nil, err
+	}
+	return blockByHash(b.backend, header.ParentHash)
+}
+
+func (b *Block) Difficulty(ctx context.Context) (hexutil.Big, error) {
+	header, err := b.resolveHeader()
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*header.Difficulty), nil
+}
+
+func (b *Block) TotalDifficulty(ctx context.Context) (*hexutil.Big, error) {
+	if b.ommer {
+		return nil, nil
+	}
+	td, ok := new(big.Int).SetString(b.model.TotalDifficulty, 10)
+	if !ok {
+		return nil, fmt.Errorf("total difficulty %s of block %s cannot be converted to an integer", b.model.TotalDifficulty, b.model.BlockHash)
+	}
+	return (*hexutil.Big)(td), nil
+}
+
+func (b *Block) Timestamp(ctx context.Context) (hexutil.Uint64, error) {
+	header, err := b.resolveHeader()
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(header.Time), nil
+}
+
+func (b *Block) Nonce(ctx context.Context) (hexutil.Bytes, error) {
+	header, err := b.resolveHeader()
+	if err != nil {
+		return hexutil.Bytes{}, err
+	}
+	return header.Nonce[:], nil
+}
+
+func (b *Block) MixHash(ctx context.Context) (common.Hash, error) {
+	header, err := b.resolveHeader()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return header.MixDigest, nil
+}
+
+func (b *Block) TransactionsRoot(ctx context.Context) (common.Hash, error) {
+	header, err := b.resolveHeader()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return header.TxHash, nil
+}
+
+func (b *Block) StateRoot(ctx context.Context) (common.Hash, error) {
+	header, err := b.resolveHeader()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return header.Root, nil
+}
+
+func (b *Block) ReceiptsRoot(ctx context.Context) (common.Hash, error) {
+	header, err := b.resolveHeader()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return header.ReceiptHash, nil
+}
+
+func (b *Block) OmmerHash(ctx context.Context) (common.Hash, error) {
+	header, err := b.resolveHeader()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return header.UncleHash, nil
+}
+
+func (b *Block) OmmerCount(ctx context.Context) (*int32, error) {
+	if b.ommer {
+		return nil, nil
+	}
+	ommers, err := b.resolveOmmers()
+	if err != nil {
+		return nil, err
+	}
+	count := int32(len(ommers))
+	return &count, nil
+}
+
+func (b *Block) Ommers(ctx context.Context) (*[]*Block, error) {
+	if b.ommer {
+		return nil, nil
+	}
+	ommers, err := b.resolveOmmers()
+	if err != nil {
+		return nil, err
+	}
+	return &ommers, nil
+}
+
+func (b *Block) OmmerAt(ctx context.Context, args struct{ Index int32 }) (*Block, error) {
+	if b.ommer {
+		return nil, nil
+	}
+	ommers, err := b.resolveOmmers()
+	if err != nil || args.Index < 0 || int(args.Index) >= len(ommers) {
+		return nil, err
+	}
+	return ommers[args.Index], nil
+}
+
+func (b *Block) ExtraData(ctx context.Context) (hexutil.Bytes, error) {
+	header, err := b.resolveHeader()
+	if err != nil {
+		return hexutil.Bytes{}, err
+	}
+	return header.Extra, nil
+}
+
+func (b *Block) LogsBloom(ctx context.Context) (hexutil.Bytes, error) {
+	header, err := b.resolveHeader()
+	if err != nil {
+		return hexutil.Bytes{}, err
+	}
+	return header.Bloom.Bytes(), nil
+}
+
+// BlockNumberArgs encapsulates arguments to accessors that specify a block number
+type BlockNumberArgs struct {
+	Block *hexutil.Uint64
+}
+
+// NumberOr returns the provided block number argument, or the "current" block number if none is provided
+func (a BlockNumberArgs) NumberOr(current int64) int64 {
+	if a.Block != nil {
+		return int64(*a.Block)
+	}
+	return current
+}
+
+func (b *Block) Miner(ctx context.Context, args BlockNumberArgs) (*Account, error) {
+	header, err := b.resolveHeader()
+	if err != nil {
+		return nil, err
+	}
+	return &Account{
+		backend:     b.backend,
+		address:     header.Coinbase,
+		blockNumber: args.NumberOr(header.Number.Int64()),
+	}, nil
+}
+
+func (b *Block) TransactionCount(ctx context.Context) (*int32, error) {
+	if b.ommer {
+		return nil, nil
+	}
+	txCIDs, err := b.resolveTxs()
+	if err != nil {
+		return nil, err
+	}
+	count := int32(len(txCIDs))
+	return &count, nil
+}
+
+// TransactionFilterCriteria encapsulates criteria passed to a `transactions` accessor inside a block
+type TransactionFilterCriteria struct {
+	Src *[]common.Address // restricts matches to transactions sent from specific addresses
+	Dst *[]common.Address // restricts matches to transactions sent to specific addresses
+}
+
+func (b *Block) Transactions(ctx context.Context, args struct {
+	Filter *TransactionFilterCriteria
+	First  *int32
+	Skip   *int32
+}) (*[]*Transaction, error) {
+	if b.ommer {
+		return nil, nil
+	}
+	var txCIDs []eth.TxModel
+	var err error
+	if args.Filter == nil {
+		txCIDs, err = b.resolveTxs()
+	} else {
+		txFilter := eth.TxFilter{}
+		if args.Filter.Src != nil {
+			txFilter.Src = addressStrings(*args.Filter.Src)
+		}
+		if args.Filter.Dst != nil {
+			txFilter.Dst = addressStrings(*args.Filter.Dst)
+		}
+		err = withTx(b.backend.DB, func(tx *sqlx.Tx) error {
+			var err error
+			txCIDs, err = b.backend.Retriever.RetrieveTxCIDs(tx, txFilter, b.model.ID)
+			return err
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	start, end := paginate(len(txCIDs), args.First, args.Skip)
+	txs := make([]*Transaction, 0, end-start)
+	for _, txCID := range txCIDs[start:end] {
+		txs = append(txs, &Transaction{backend: b.backend, model: txCID, block: b})
+	}
+	return &txs, nil
+}
+
+func (b *Block) TransactionAt(ctx context.Context, args struct{ Index int32 }) (*Transaction, error) {
+	if b.ommer {
+		return nil, nil
+	}
+	txCIDs, err := b.resolveTxs()
+	if err != nil || args.Index < 0 || int(args.Index) >= len(txCIDs) {
+		return nil, err
+	}
+	return &Transaction{backend: b.backend, model: txCIDs[args.Index], block: b}, nil
+}
+
+// BlockFilterCriteria encapsulates criteria passed to a `logs` accessor inside a block
+type BlockFilterCriteria struct {
+	Addresses *[]common.Address // restricts matches to events created by specific contracts
+
+	// The Topic list restricts matches to particular event topics. Each event has a list
+	// of topics. Topics matches a prefix of that list. An empty element slice matches any
+	// topic. Non-empty elements represent an alternative that matches any of the
+	// contained topics.
+	//
+	// Examples:
+	// {} or nil          matches any topic list
+	// {{A}}              matches topic A in first position
+	// {{}, {B}}          matches any topic in first position, B in second position
+	// {{A}, {B}}         matches topic A in first position, B in second position
+	// {{A, B}}, {C, D}}  matches topic (A OR B) in first position, (C OR D) in second position
+	Topics *[][]common.Hash
+}
+
+func (b *Block) Logs(ctx context.Context, args struct {
+	Filter BlockFilterCriteria
+	First  *int32
+	Skip   *int32
+}) ([]*Log, error) {
+	if b.ommer {
+		return nil, errOmmerData
+	}
+	filter := newLogFilter(args.Filter.Addresses, args.Filter.Topics)
+	var logs []*Log
+	err := withTx(b.backend.DB, func(tx *sqlx.Tx) error {
+		var err error
+		logs, err = b.logs(tx, filter)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	start, end := paginate(len(logs), args.First, args.Skip)
+	return logs[start:end], nil
+}
+
+// logs returns the block's logs that match the filter, in the order they were emitted
+func (b *Block) logs(tx *sqlx.Tx, filter logFilter) ([]*Log, error) {
+	// Narrow down to the receipts which contain logs of interest
+	rctCIDs, err := b.backend.Retriever.RetrieveRctCIDsByHeaderID(tx, filter.receiptFilter(), b.model.ID, nil)
+	if err != nil || len(rctCIDs) == 0 {
+		return []*Log{}, err
+	}
+	txCIDs, err := b.backend.Retriever.RetrieveTxCIDsByHeaderID(tx, b.model.ID)
+	if err != nil {
+		return nil, err
+	}
+	txCIDsByID := make(map[int64]eth.TxModel, len(txCIDs))
+	for _, txCID := range txCIDs {
+		txCIDsByID[txCID.ID] = txCID
+	}
+	rctIPLDs, err := b.backend.Fetcher.FetchRcts(tx, rctCIDs)
+	if err != nil {
+		return nil, err
+	}
+	logs := make([]*Log, 0)
+	for i, rctIPLD := range rctIPLDs {
+		receipt := new(types.Receipt)
+		if err := rlp.DecodeBytes(rctIPLD.Data, receipt); err != nil {
+			return nil, err
+		}
+		// rctIPLDs are fetched in the same order as rctCIDs
+		rctCID := rctCIDs[i]
+		transaction := &Transaction{
+			backend:  b.backend,
+			model:    txCIDsByID[rctCID.TxID],
+			block:    b,
+			rctModel: &rctCID,
+			receipt:  receipt,
+		}
+		for j, log := range receipt.Logs {
+			if filter.matches(log) {
+				logs = append(logs, &Log{
+					backend:     b.backend,
+					transaction: transaction,
+					log:         log,
+					index:       int32(j),
+				})
+			}
+		}
+	}
+	return logs, nil
+}
+
+func (b *Block) Account(ctx context.Context, args struct {
+	Address common.Address
+}) (*Account, error) {
+	header, err := b.resolveHeader()
+	if err != nil {
+		return nil, err
+	}
+	return &Account{
+		backend:     b.backend,
+		address:     args.Address,
+		blockNumber: header.Number.Int64(),
+	}, nil
+}
+
+func (b *Block) CID(ctx context.Context) string {
+	return b.model.CID
+}
+
+// Resolver is the top-level object in the GraphQL hierarchy
+type Resolver struct {
+	backend *eth.Backend
+}
+
+func (r *Resolver) Block(ctx context.Context, args struct {
+	Number *hexutil.Uint64
+	Hash   *common.Hash
+}) (*Block, error) {
+	if args.Hash != nil {
+		return blockByHash(r.backend, *args.Hash)
+	}
+	var number int64
+	if args.Number != nil {
+		number = int64(*args.Number)
+	} else {
+		var err error
+		number, err = r.backend.Retriever.RetrieveLastBlockNumber()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return blockByNumber(r.backend, number)
+}
+
+func (r *Resolver) Blocks(ctx context.Context, args struct {
+	From hexutil.Uint64
+	To   *hexutil.Uint64
+}) ([]*Block, error) {
+	from := int64(args.From)
+	var to int64
+	if args.To != nil {
+		to = int64(*args.To)
+	} else {
+		var err error
+		to, err = r.backend.Retriever.RetrieveLastBlockNumber()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if to < from {
+		return []*Block{}, nil
+	}
+	if err := checkBlockRange(from, to); err != nil {
+		return nil, err
+	}
+	blocks := make([]*Block, 0)
+	err := withTx(r.backend.DB, func(tx *sqlx.Tx) error {
+		for i := from; i <= to; i++ {
+			headerCIDs, err := r.backend.Retriever.RetrieveHeaderCIDs(tx, i)
+			if err != nil {
+				return err
+			}
+			// Heights which have not been indexed yet are skipped
+			if len(headerCIDs) > 0 {
+				blocks = append(blocks, &Block{backend: r.backend, model: headerCIDs[0]})
+			}
+		}
+		return nil
+	})
+	return blocks, err
+}
+
+func (r *Resolver) Transaction(ctx context.Context, args struct{ Hash common.Hash }) (*Transaction, error) {
+	var txCID eth.TxModel
+	err := withTx(r.backend.DB, func(tx *sqlx.Tx) error {
+		var err error
+		txCID, err = r.backend.Retriever.RetrieveTxCIDByHash(tx, args.Hash)
+		return err
+	})
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Transaction{backend: r.backend, model: txCID}, nil
+}
+
+// FilterCriteria encapsulates the arguments to `logs` on the root resolver object
+type FilterCriteria struct {
+	FromBlock *hexutil.Uint64   // beginning of the queried range, nil means the first indexed block
+	ToBlock   *hexutil.Uint64   // end of the range, nil means latest block
+	Addresses *[]common.Address // restricts matches to events created by specific contracts
+
+	// The Topic list restricts matches to particular event topics. Each event has a list
+	// of topics. Topics matches a prefix of that list. An empty element slice matches any
+	// topic. Non-empty elements represent an alternative that matches any of the
+	// contained topics.
+	//
+	// Examples:
+	// {} or nil          matches any topic list
+	// {{A}}              matches topic A in first position
+	// {{}, {B}}          matches any topic in first position, B in second position
+	// {{A}, {B}}         matches topic A in first position, B in second position
+	// {{A, B}}, {C, D}}  matches topic (A OR B) in first position, (C OR D) in second position
+	Topics *[][]common.Hash
+}
+
+func (r *Resolver) Logs(ctx context.Context, args struct {
+	Filter FilterCriteria
+	First  *int32
+	Skip   *int32
+}) ([]*Log, error) {
+	var err error
+	var from, to int64
+	if args.Filter.FromBlock != nil {
+		from = int64(*args.Filter.FromBlock)
+	} else if from, err = r.backend.Retriever.RetrieveFirstBlockNumber(); err != nil {
+		return nil, err
+	}
+	if args.Filter.ToBlock != nil {
+		to = int64(*args.Filter.ToBlock)
+	} else if to, err = r.backend.Retriever.RetrieveLastBlockNumber(); err != nil {
+		return nil, err
+	}
+	if err := checkBlockRange(from, to); err != nil {
+		return nil, err
+	}
+	filter := newLogFilter(args.Filter.Addresses, args.Filter.Topics)
+	// Stop searching once the requested page has been filled
+	limit := -1
+	if args.First != nil {
+		limit = int(*args.First)
+		if args.Skip != nil && *args.Skip > 0 {
+			limit += int(*args.Skip)
+		}
+	}
+	logs := make([]*Log, 0)
+	err = withTx(r.backend.DB, func(tx *sqlx.Tx) error {
+		for i := from; i <= to && (limit < 0 || len(logs) < limit); i++ {
+			headerCIDs, err := r.backend.Retriever.RetrieveHeaderCIDs(tx, i)
+			if err != nil {
+				return err
+			}
+			if len(headerCIDs) == 0 {
+				continue
+			}
+			block := &Block{backend: r.backend, model: headerCIDs[0]}
+			blockLogs, err := block.logs(tx, filter)
+			if err != nil {
+				return err
+			}
+			logs = append(logs, blockLogs...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	start, end := paginate(len(logs), args.First, args.Skip)
+	return logs[start:end], nil
+}
+
+// checkBlockRange returns an error if the range from-to spans more than MaxBlockRange blocks
+func checkBlockRange(from, to int64) error {
+	if to-from >= MaxBlockRange {
+		return fmt.Errorf("block range %d-%d exceeds the maximum of %d blocks", from, to, MaxBlockRange)
+	}
+	return nil
+}
+
+// blockByNumber returns the canonical block at the provided height, or nil if it has not been indexed
+func blockByNumber(backend *eth.Backend, number int64) (*Block, error) {
+	var headerCIDs []eth.HeaderModel
+	err := withTx(backend.DB, func(tx *sqlx.Tx) error {
+		var err error
+		headerCIDs, err = backend.Retriever.RetrieveHeaderCIDs(tx, number)
+		return err
+	})
+	if err != nil || len(headerCIDs) == 0 {
+		return nil, err
+	}
+	return &Block{backend: backend, model: headerCIDs[0]}, nil
+}
+
+// blockByHash returns the block with the provided hash, or nil if it has not been indexed
+func blockByHash(backend *eth.Backend, hash common.Hash) (*Block, error) {
+	var headerCID eth.HeaderModel
+	err := withTx(backend.DB, func(tx *sqlx.Tx) error {
+		var err error
+		headerCID, err = backend.Retriever.RetrieveHeaderCIDByHash(tx, hash)
+		return err
+	})
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Block{backend: backend, model: headerCID}, nil
+}
+
+// withTx runs f within a new Postgres transaction, which is committed if f succeeds and rolled back otherwise
+func withTx(db *postgres.DB, f func(tx *sqlx.Tx) error) (err error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+	err = f(tx)
+	return err
+}
+
+// paginate returns the bounds of the page of a result set of the provided length,
+// skipping the first skip results and including at most first results
+func paginate(length int, first, skip *int32) (int, int) {
+	start := 0
+	if skip != nil && *skip > 0 {
+		start = int(*skip)
+	}
+	if start > length {
+		start = length
+	}
+	end := length
+	if first != nil && *first >= 0 && start+int(*first) < end {
+		end = start + int(*first)
+	}
+	return start, end
+}
+
+// addressStrings converts addresses into the form they are indexed in
+func addressStrings(addresses []common.Address) []string {
+	strs := make([]string, len(addresses))
+	for i, addr := range addresses {
+		strs[i] = addr.Hex()
+	}
+	return strs
+}
@@ -0,0 +1,163 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package graphql_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/params"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/vulcanizedb/pkg/postgres"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth/graphql"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth/mocks"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+type response struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []interface{}          `json:"errors"`
+}
+
+func query(handler http.Handler, q string) response {
+	body, err := json.Marshal(map[string]string{"query": q})
+	Expect(err).ToNot(HaveOccurred())
+	req := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	Expect(rec.Code).To(Equal(http.StatusOK))
+	var res response
+	Expect(json.Unmarshal(rec.Body.Bytes(), &res)).To(Succeed())
+	Expect(res.Errors).To(BeEmpty())
+	return res
+}
+
+var _ = Describe("GraphQL", func() {
+	It("Builds a handler whose resolvers match the schema", func() {
+		_, err := graphql.NewHandler(nil)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Rejects block ranges larger than the maximum", func() {
+		handler, err := graphql.NewHandler(nil)
+		Expect(err).ToNot(HaveOccurred())
+		for _, q := range []string{
+			fmt.Sprintf(`{ blocks(from: 0, to: %d) { number } }`, graphql.MaxBlockRange),
+			`{ blocks(from: 0, to: "0x7fffffffffffffff") { number } }`,
+			fmt.Sprintf(`{ logs(filter: {fromBlock: 1, toBlock: %d}) { index } }`, graphql.MaxBlockRange+1),
+		} {
+			body, err := json.Marshal(map[string]string{"query": q})
+			Expect(err).ToNot(HaveOccurred())
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body)))
+			var res response
+			Expect(json.Unmarshal(rec.Body.Bytes(), &res)).To(Succeed())
+			Expect(len(res.Errors)).To(Equal(1))
+			Expect(fmt.Sprint(res.Errors[0])).To(ContainSubstring("exceeds the maximum"))
+		}
+	})
+
+	Describe("Queries", func() {
+		var (
+			db      *postgres.DB
+			handler http.Handler
+		)
+		BeforeEach(func() {
+			var err error
+			db, err = shared.SetupDB()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = eth.NewIPLDPublisherAndIndexer(db).Publish(mocks.MockConvertedPayload)
+			Expect(err).ToNot(HaveOccurred())
+			backend, err := eth.NewEthBackend(db, params.MainnetChainConfig)
+			Expect(err).ToNot(HaveOccurred())
+			handler, err = graphql.NewHandler(backend)
+			Expect(err).ToNot(HaveOccurred())
+		})
+		AfterEach(func() {
+			eth.TearDownDB(db)
+		})
+
+		It("Retrieves a block and its transactions, with their CIDs", func() {
+			res := query(handler, `{ block(number: 1) { number hash cid transactionCount transactions { hash cid index } } }`)
+			block := res.Data["block"].(map[string]interface{})
+			Expect(block["number"]).To(Equal(hexutil.EncodeUint64(1)))
+			Expect(block["hash"]).To(Equal(mocks.MockBlock.Hash().Hex()))
+			Expect(block["cid"]).To(Equal(mocks.HeaderCID.String()))
+			Expect(block["transactionCount"]).To(BeNumerically("==", 3))
+			txs := block["transactions"].([]interface{})
+			Expect(len(txs)).To(Equal(3))
+			for i, tx := range txs {
+				Expect(tx.(map[string]interface{})["hash"]).To(Equal(mocks.MockTransactions[i].Hash().Hex()))
+				Expect(tx.(map[string]interface{})["cid"]).To(Equal(mocks.MockIPLDs.Transactions[i].CID))
+			}
+		})
+
+		It("Filters and paginates a block's transactions", func() {
+			res := query(handler, fmt.Sprintf(`{ block(number: 1) { transactions(filter: {dst: ["%s"]}) { hash } } }`, mocks.AnotherAddress.Hex()))
+			txs := res.Data["block"].(map[string]interface{})["transactions"].([]interface{})
+			Expect(len(txs)).To(Equal(1))
+			Expect(txs[0].(map[string]interface{})["hash"]).To(Equal(mocks.MockTransactions[1].Hash().Hex()))
+
+			res = query(handler, `{ block(number: 1) { transactions(first: 1, skip: 1) { hash } } }`)
+			txs = res.Data["block"].(map[string]interface{})["transactions"].([]interface{})
+			Expect(len(txs)).To(Equal(1))
+			Expect(txs[0].(map[string]interface{})["hash"]).To(Equal(mocks.MockTransactions[1].Hash().Hex()))
+		})
+
+		It("Retrieves a transaction by hash along with its receipt data", func() {
+			res := query(handler, fmt.Sprintf(`{ transaction(hash: "%s") { from { address } to { address } cumulativeGasUsed receiptCID logs { topics } block { number } } }`, mocks.MockTransactions[0].Hash().Hex()))
+			tx := res.Data["transaction"].(map[string]interface{})
+			Expect(tx["from"].(map[string]interface{})["address"]).To(Equal(hexutil.Encode(mocks.SenderAddr.Bytes())))
+			Expect(tx["to"].(map[string]interface{})["address"]).To(Equal(hexutil.Encode(mocks.Address.Bytes())))
+			Expect(tx["cumulativeGasUsed"]).To(Equal(hexutil.EncodeUint64(50)))
+			Expect(tx["receiptCID"]).To(Equal(mocks.MockIPLDs.Receipts[0].CID))
+			Expect(len(tx["logs"].([]interface{}))).To(Equal(1))
+			Expect(tx["block"].(map[string]interface{})["number"]).To(Equal(hexutil.EncodeUint64(1)))
+		})
+
+		It("Retrieves logs matching the filter", func() {
+			res := query(handler, fmt.Sprintf(`{ logs(filter: {fromBlock: 1, toBlock: 1, topics: [["%s"]]}) { index topics transaction { hash } } }`, mocks.MockLog2.Topics[0].Hex()))
+			logs := res.Data["logs"].([]interface{})
+			Expect(len(logs)).To(Equal(1))
+			log := logs[0].(map[string]interface{})
+			Expect(log["transaction"].(map[string]interface{})["hash"]).To(Equal(mocks.MockTransactions[1].Hash().Hex()))
+
+			res = query(handler, fmt.Sprintf(`{ block(number: 1) { logs(filter: {addresses: ["%s"]}) { topics } } }`, mocks.Address.Hex()))
+			logs = res.Data["block"].(map[string]interface{})["logs"].([]interface{})
+			Expect(len(logs)).To(Equal(1))
+		})
+
+		It("Retrieves accounts and storage at a block", func() {
+			res := query(handler, fmt.Sprintf(`{ block(number: 1) { account(address: "%s") { balance transactionCount cid } } }`, mocks.AccountAddresss.Hex()))
+			account := res.Data["block"].(map[string]interface{})["account"].(map[string]interface{})
+			Expect(account["balance"]).To(Equal(hexutil.EncodeUint64(1000)))
+			Expect(account["transactionCount"]).To(Equal(hexutil.EncodeUint64(0)))
+			Expect(account["cid"]).To(Equal(mocks.State2IPLD.Cid().String()))
+
+			res = query(handler, fmt.Sprintf(`{ block(number: 1) { account(address: "%s") { storage(slot: "0x0000000000000000000000000000000000000000000000000000000000000000") } } }`, mocks.ContractAddress.Hex()))
+			account = res.Data["block"].(map[string]interface{})["account"].(map[string]interface{})
+			Expect(account["storage"]).To(Equal("0x0000000000000000000000000000000000000000000000000000000000000001"))
+		})
+	})
+})
@@ -0,0 +1,93 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth"
+)
+
+// Service encapsulates a GraphQL service
+type Service struct {
+	endpoint string           // The host:port endpoint for this service
+	cors     []string         // Allowed CORS domains
+	vhosts   []string         // Recognised vhosts
+	timeouts rpc.HTTPTimeouts // Timeout settings for HTTP requests
+	backend  *eth.Backend     // The backend that queries will operate on
+	handler  http.Handler     // The `http.Handler` used to answer queries
+	listener net.Listener     // The listening socket
+}
+
+// New constructs a new GraphQL service instance
+func New(backend *eth.Backend, endpoint string, cors, vhosts []string, timeouts rpc.HTTPTimeouts) (*Service, error) {
+	return &Service{
+		endpoint: endpoint,
+		cors:     cors,
+		vhosts:   vhosts,
+		timeouts: timeouts,
+		backend:  backend,
+	}, nil
+}
+
+// Start begins serving GraphQL queries on the service's endpoint
+func (s *Service) Start() error {
+	var err error
+	s.handler, err = NewHandler(s.backend)
+	if err != nil {
+		return err
+	}
+	if s.listener, err = net.Listen("tcp", s.endpoint); err != nil {
+		return err
+	}
+	go rpc.NewHTTPServer(s.cors, s.vhosts, s.timeouts, s.handler).Serve(s.listener)
+	log.Infof("graphql endpoint opened on %s", fmt.Sprintf("http://%s/graphql", s.endpoint))
+	return nil
+}
+
+// Stop stops serving GraphQL queries
+func (s *Service) Stop() error {
+	if s.listener != nil {
+		err := s.listener.Close()
+		s.listener = nil
+		log.Infof("graphql endpoint closed on %s", fmt.Sprintf("http://%s/graphql", s.endpoint))
+		return err
+	}
+	return nil
+}
+
+// NewHandler returns a new `http.Handler` that will answer GraphQL queries at /graphql
+// It parses the schema against the resolvers, so it fails if the two do not match
+func NewHandler(backend *eth.Backend) (http.Handler, error) {
+	q := Resolver{backend}
+	s, err := graphql.ParseSchema(schema, &q)
+	if err != nil {
+		return nil, err
+	}
+	h := &relay.Handler{Schema: s}
+	mux := http.NewServeMux()
+	mux.Handle("/graphql", h)
+	mux.Handle("/graphql/", h)
+	return mux, nil
+}
@@ -17,7 +17,9 @@
 package eth
 
 import (
+	"database/sql"
 	"fmt"
+	"strconv"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/jmoiron/sqlx"
@@ -33,13 +35,15 @@ var (
 
 // Indexer satisfies the Indexer interface for ethereum
 type CIDIndexer struct {
-	db *postgres.DB
+	db          *postgres.DB
+	partitioner *Partitioner
 }
 
 // NewCIDIndexer creates a new pointer to a Indexer which satisfies the CIDIndexer interface
 func NewCIDIndexer(db *postgres.DB) *CIDIndexer {
 	return &CIDIndexer{
-		db: db,
+		db:          db,
+		partitioner: NewPartitioner(db),
 	}
 }
 
@@ -49,6 +53,9 @@ func (in *CIDIndexer) Index(cids shared.CIDsForIndexing) error {
 	if !ok {
 		return fmt.Errorf("eth indexer expected cids type %T got %T", &CIDPayload{}, cids)
 	}
+	if err := in.ensurePartitions(cidPayload.HeaderCID.BlockNumber); err != nil {
+		return err
+	}
 
 	// Begin new db tx
 	tx, err := in.db.Beginx()
@@ -71,23 +78,34 @@ func (in *CIDIndexer) Index(cids shared.CIDsForIndexing) error {
 		log.Error("eth indexer error when indexing header")
 		return err
 	}
+	blockNumber := cidPayload.HeaderCID.BlockNumber
 	for _, uncle := range cidPayload.UncleCIDs {
-		if err := in.indexUncleCID(tx, uncle, headerID); err != nil {
+		if err := in.indexUncleCID(tx, uncle, headerID, blockNumber); err != nil {
 			log.Error("eth indexer error when indexing uncle")
 			return err
 		}
 	}
-	if err := in.indexTransactionAndReceiptCIDs(tx, cidPayload, headerID); err != nil {
+	if err := in.indexTransactionAndReceiptCIDs(tx, cidPayload, headerID, blockNumber); err != nil {
 		log.Error("eth indexer error when indexing transactions and receipts")
 		return err
 	}
-	err = in.indexStateAndStorageCIDs(tx, cidPayload, headerID)
+	err = in.indexStateAndStorageCIDs(tx, cidPayload, headerID, blockNumber)
 	if err != nil {
 		log.Error("eth indexer error when indexing state and storage nodes")
 	}
 	return err
 }
 
+// ensurePartitions makes sure the partitions of the eth tables covering the block exist before it is indexed
+// This happens outside of the indexing tx, so that the lock taken on the partitioned tables while creating them is not held for the whole tx
+func (in *CIDIndexer) ensurePartitions(blockNumber string) error {
+	height, err := strconv.ParseUint(blockNumber, 10, 64)
+	if err != nil {
+		return err
+	}
+	return in.partitioner.EnsurePartitions(height)
+}
+
 func (in *CIDIndexer) indexHeaderCID(tx *sqlx.Tx, header HeaderModel) (int64, error) {
 	var headerID int64
 	err := tx.QueryRowx(`INSERT INTO eth.header_cids (block_number, block_hash, parent_hash, cid, td, node_id, reward, state_root, tx_root, receipt_root, uncle_root, bloom, timestamp, times_validated)
@@ -96,29 +114,105 @@ func (in *CIDIndexer) indexHeaderCID(tx *sqlx.Tx, header HeaderModel) (int64, er
 								RETURNING id`,
 		header.BlockNumber, header.BlockHash, header.ParentHash, header.CID, header.TotalDifficulty, in.db.NodeID, header.Reward, header.StateRoot, header.TxRoot,
 		header.RctRoot, header.UncleRoot, header.Bloom, header.Timestamp, 1).Scan(&headerID)
-	return headerID, err
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`DELETE FROM eth.known_gaps WHERE block_number = $1`, header.BlockNumber); err != nil {
+		return 0, err
+	}
+	return headerID, in.indexCanonical(tx, header)
+}
+
+// indexCanonical marks the provided header as canonical, the most recently indexed header at a height is taken to be the canonical one
+// The flag is then propagated backwards and forwards along the chain of headers linked to this one by parent hash,
+// competing headers at each of these heights are marked non-canonical
+func (in *CIDIndexer) indexCanonical(tx *sqlx.Tx, header HeaderModel) error {
+	blockNumber, err := strconv.ParseInt(header.BlockNumber, 10, 64)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE eth.header_cids SET canonical = (block_hash = $2) WHERE block_number = $1`,
+		blockNumber, header.BlockHash); err != nil {
+		return err
+	}
+	// Walk back through the ancestors until we reach one that is already canonical or one we do not have
+	parentHash := header.ParentHash
+	for num := blockNumber - 1; num >= 0; num-- {
+		var ancestor HeaderModel
+		err := tx.Get(&ancestor, `SELECT parent_hash, canonical FROM eth.header_cids
+									WHERE block_number = $1 AND block_hash = $2`, num, parentHash)
+		if err == sql.ErrNoRows {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if ancestor.Canonical {
+			break
+		}
+		if _, err := tx.Exec(`UPDATE eth.header_cids SET canonical = (block_hash = $2) WHERE block_number = $1`,
+			num, parentHash); err != nil {
+			return err
+		}
+		parentHash = ancestor.ParentHash
+	}
+	// Walk forward through the descendants until we reach a height we do not have
+	// Headers at these heights which do not link back to this header are not canonical
+	hash := header.BlockHash
+	for num := blockNumber + 1; ; num++ {
+		descendants := make([]HeaderModel, 0)
+		if err := tx.Select(&descendants, `SELECT block_hash, parent_hash, canonical FROM eth.header_cids
+									WHERE block_number = $1
+									ORDER BY canonical DESC, id DESC`, num); err != nil {
+			return err
+		}
+		if len(descendants) == 0 {
+			return nil
+		}
+		child := ""
+		for _, descendant := range descendants {
+			if hash != "" && descendant.ParentHash == hash {
+				child = descendant.BlockHash
+				break
+			}
+		}
+		res, err := tx.Exec(`UPDATE eth.header_cids SET canonical = (block_hash = $2)
+									WHERE block_number = $1 AND canonical <> (block_hash = $2)`, num, child)
+		if err != nil {
+			return err
+		}
+		updated, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		// If nothing changed at this height, the rest of the chain is already consistent
+		if updated == 0 {
+			return nil
+		}
+		hash = child
+	}
 }
 
-func (in *CIDIndexer) indexUncleCID(tx *sqlx.Tx, uncle UncleModel, headerID int64) error {
-	_, err := tx.Exec(`INSERT INTO eth.uncle_cids (block_hash, header_id, parent_hash, cid, reward) VALUES ($1, $2, $3, $4, $5)
-								ON CONFLICT (header_id, block_hash) DO UPDATE SET (parent_hash, cid, reward) = ($3, $4, $5)`,
-		uncle.BlockHash, headerID, uncle.ParentHash, uncle.CID, uncle.Reward)
+func (in *CIDIndexer) indexUncleCID(tx *sqlx.Tx, uncle UncleModel, headerID int64, blockNumber string) error {
+	_, err := tx.Exec(`INSERT INTO eth.uncle_cids (block_hash, header_id, parent_hash, cid, reward, block_number) VALUES ($1, $2, $3, $4, $5, $6)
+								ON CONFLICT (header_id, block_hash, block_number) DO UPDATE SET (parent_hash, cid, reward) = ($3, $4, $5)`,
+		uncle.BlockHash, headerID, uncle.ParentHash, uncle.CID, uncle.Reward, blockNumber)
 	return err
 }
 
-func (in *CIDIndexer) indexTransactionAndReceiptCIDs(tx *sqlx.Tx, payload *CIDPayload, headerID int64) error {
+func (in *CIDIndexer) indexTransactionAndReceiptCIDs(tx *sqlx.Tx, payload *CIDPayload, headerID int64, blockNumber string) error {
 	for _, trxCidMeta := range payload.TransactionCIDs {
 		var txID int64
-		err := tx.QueryRowx(`INSERT INTO eth.transaction_cids (header_id, tx_hash, cid, dst, src, index) VALUES ($1, $2, $3, $4, $5, $6)
-									ON CONFLICT (header_id, tx_hash) DO UPDATE SET (cid, dst, src, index) = ($3, $4, $5, $6)
+		err := tx.QueryRowx(`INSERT INTO eth.transaction_cids (header_id, tx_hash, cid, dst, src, index, block_number) VALUES ($1, $2, $3, $4, $5, $6, $7)
+									ON CONFLICT (header_id, tx_hash, block_number) DO UPDATE SET (cid, dst, src, index) = ($3, $4, $5, $6)
 									RETURNING id`,
-			headerID, trxCidMeta.TxHash, trxCidMeta.CID, trxCidMeta.Dst, trxCidMeta.Src, trxCidMeta.Index).Scan(&txID)
+			headerID, trxCidMeta.TxHash, trxCidMeta.CID, trxCidMeta.Dst, trxCidMeta.Src, trxCidMeta.Index, blockNumber).Scan(&txID)
 		if err != nil {
 			return err
 		}
 		receiptCidMeta, ok := payload.ReceiptCIDs[common.HexToHash(trxCidMeta.TxHash)]
 		if ok {
-			if err := in.indexReceiptCID(tx, receiptCidMeta, txID); err != nil {
+			if err := in.indexReceiptCID(tx, receiptCidMeta, txID, blockNumber); err != nil {
 				return err
 			}
 		}
@@ -126,33 +220,33 @@ func (in *CIDIndexer) indexTransactionAndReceiptCIDs(tx *sqlx.Tx, payload *CIDPa
 	return nil
 }
 
-func (in *CIDIndexer) indexTransactionCID(tx *sqlx.Tx, transaction TxModel, headerID int64) (int64, error) {
+func (in *CIDIndexer) indexTransactionCID(tx *sqlx.Tx, transaction TxModel, headerID int64, blockNumber string) (int64, error) {
 	var txID int64
-	err := tx.QueryRowx(`INSERT INTO eth.transaction_cids (header_id, tx_hash, cid, dst, src, index) VALUES ($1, $2, $3, $4, $5, $6)
-									ON CONFLICT (header_id, tx_hash) DO UPDATE SET (cid, dst, src, index) = ($3, $4, $5, $6)
+	err := tx.QueryRowx(`INSERT INTO eth.transaction_cids (header_id, tx_hash, cid, dst, src, index, block_number) VALUES ($1, $2, $3, $4, $5, $6, $7)
+									ON CONFLICT (header_id, tx_hash, block_number) DO UPDATE SET (cid, dst, src, index) = ($3, $4, $5, $6)
 									RETURNING id`,
-		headerID, transaction.TxHash, transaction.CID, transaction.Dst, transaction.Src, transaction.Index).Scan(&txID)
+		headerID, transaction.TxHash, transaction.CID, transaction.Dst, transaction.Src, transaction.Index, blockNumber).Scan(&txID)
 	return txID, err
 }
 
-func (in *CIDIndexer) indexReceiptCID(tx *sqlx.Tx, cidMeta ReceiptModel, txID int64) error {
-	_, err := tx.Exec(`INSERT INTO eth.receipt_cids (tx_id, cid, contract, contract_hash, topic0s, topic1s, topic2s, topic3s, log_contracts) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-							  ON CONFLICT (tx_id) DO UPDATE SET (cid, contract, contract_hash, topic0s, topic1s, topic2s, topic3s, log_contracts) = ($2, $3, $4, $5, $6, $7, $8, $9)`,
-		txID, cidMeta.CID, cidMeta.Contract, cidMeta.ContractHash, cidMeta.Topic0s, cidMeta.Topic1s, cidMeta.Topic2s, cidMeta.Topic3s, cidMeta.LogContracts)
+func (in *CIDIndexer) indexReceiptCID(tx *sqlx.Tx, cidMeta ReceiptModel, txID int64, blockNumber string) error {
+	_, err := tx.Exec(`INSERT INTO eth.receipt_cids (tx_id, cid, contract, contract_hash, topic0s, topic1s, topic2s, topic3s, log_contracts, block_number) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+							  ON CONFLICT (tx_id, block_number) DO UPDATE SET (cid, contract, contract_hash, topic0s, topic1s, topic2s, topic3s, log_contracts) = ($2, $3, $4, $5, $6, $7, $8, $9)`,
+		txID, cidMeta.CID, cidMeta.Contract, cidMeta.ContractHash, cidMeta.Topic0s, cidMeta.Topic1s, cidMeta.Topic2s, cidMeta.Topic3s, cidMeta.LogContracts, blockNumber)
 	return err
 }
 
-func (in *CIDIndexer) indexStateAndStorageCIDs(tx *sqlx.Tx, payload *CIDPayload, headerID int64) error {
+func (in *CIDIndexer) indexStateAndStorageCIDs(tx *sqlx.Tx, payload *CIDPayload, headerID int64, blockNumber string) error {
 	for _, stateCID := range payload.StateNodeCIDs {
 		var stateID int64
 		var stateKey string
 		if stateCID.StateKey != nullHash.String() {
 			stateKey = stateCID.StateKey
 		}
-		err := tx.QueryRowx(`INSERT INTO eth.state_cids (header_id, state_leaf_key, cid, state_path, node_type) VALUES ($1, $2, $3, $4, $5)
-									ON CONFLICT (header_id, state_path) DO UPDATE SET (state_leaf_key, cid, node_type) = ($2, $3, $5)
+		err := tx.QueryRowx(`INSERT INTO eth.state_cids (header_id, state_leaf_key, cid, state_path, node_type, block_number) VALUES ($1, $2, $3, $4, $5, $6)
+									ON CONFLICT (header_id, state_path, block_number) DO UPDATE SET (state_leaf_key, cid, node_type) = ($2, $3, $5)
 									RETURNING id`,
-			headerID, stateKey, stateCID.CID, stateCID.Path, stateCID.NodeType).Scan(&stateID)
+			headerID, stateKey, stateCID.CID, stateCID.Path, stateCID.NodeType, blockNumber).Scan(&stateID)
 		if err != nil {
 			return err
 		}
@@ -160,12 +254,12 @@ func (in *CIDIndexer) indexStateAndStorageCIDs(tx *sqlx.Tx, payload *CIDPayload,
 		if stateCID.NodeType == 2 {
 			statePath := common.Bytes2Hex(stateCID.Path)
 			for _, storageCID := range payload.StorageNodeCIDs[statePath] {
-				if err := in.indexStorageCID(tx, storageCID, stateID); err != nil {
+				if err := in.indexStorageCID(tx, storageCID, stateID, blockNumber); err != nil {
 					return err
 				}
 			}
 			if stateAccount, ok := payload.StateAccounts[statePath]; ok {
-				if err := in.indexStateAccount(tx, stateAccount, stateID); err != nil {
+				if err := in.indexStateAccount(tx, stateAccount, stateID, blockNumber); err != nil {
 					return err
 				}
 			}
@@ -174,33 +268,33 @@ func (in *CIDIndexer) indexStateAndStorageCIDs(tx *sqlx.Tx, payload *CIDPayload,
 	return nil
 }
 
-func (in *CIDIndexer) indexStateCID(tx *sqlx.Tx, stateNode StateNodeModel, headerID int64) (int64, error) {
+func (in *CIDIndexer) indexStateCID(tx *sqlx.Tx, stateNode StateNodeModel, headerID int64, blockNumber string) (int64, error) {
 	var stateID int64
 	var stateKey string
 	if stateNode.StateKey != nullHash.String() {
 		stateKey = stateNode.StateKey
 	}
-	err := tx.QueryRowx(`INSERT INTO eth.state_cids (header_id, state_leaf_key, cid, state_path, node_type) VALUES ($1, $2, $3, $4, $5)
-									ON CONFLICT (header_id, state_path) DO UPDATE SET (state_leaf_key, cid, node_type) = ($2, $3, $5)
+	err := tx.QueryRowx(`INSERT INTO eth.state_cids (header_id, state_leaf_key, cid, state_path, node_type, block_number) VALUES ($1, $2, $3, $4, $5, $6)
+									ON CONFLICT (header_id, state_path, block_number) DO UPDATE SET (state_leaf_key, cid, node_type) = ($2, $3, $5)
 									RETURNING id`,
-		headerID, stateKey, stateNode.CID, stateNode.Path, stateNode.NodeType).Scan(&stateID)
+		headerID, stateKey, stateNode.CID, stateNode.Path, stateNode.NodeType, blockNumber).Scan(&stateID)
 	return stateID, err
 }
 
-func (in *CIDIndexer) indexStateAccount(tx *sqlx.Tx, stateAccount StateAccountModel, stateID int64) error {
-	_, err := tx.Exec(`INSERT INTO eth.state_accounts (state_id, balance, nonce, code_hash, storage_root) VALUES ($1, $2, $3, $4, $5)
-							  ON CONFLICT (state_id) DO UPDATE SET (balance, nonce, code_hash, storage_root) = ($2, $3, $4, $5)`,
-		stateID, stateAccount.Balance, stateAccount.Nonce, stateAccount.CodeHash, stateAccount.StorageRoot)
+func (in *CIDIndexer) indexStateAccount(tx *sqlx.Tx, stateAccount StateAccountModel, stateID int64, blockNumber string) error {
+	_, err := tx.Exec(`INSERT INTO eth.state_accounts (state_id, balance, nonce, code_hash, storage_root, block_number) VALUES ($1, $2, $3, $4, $5, $6)
+							  ON CONFLICT (state_id, block_number) DO UPDATE SET (balance, nonce, code_hash, storage_root) = ($2, $3, $4, $5)`,
+		stateID, stateAccount.Balance, stateAccount.Nonce, stateAccount.CodeHash, stateAccount.StorageRoot, blockNumber)
 	return err
 }
 
-func (in *CIDIndexer) indexStorageCID(tx *sqlx.Tx, storageCID StorageNodeModel, stateID int64) error {
+func (in *CIDIndexer) indexStorageCID(tx *sqlx.Tx, storageCID StorageNodeModel, stateID int64, blockNumber string) error {
 	var storageKey string
 	if storageCID.StorageKey != nullHash.String() {
 		storageKey = storageCID.StorageKey
 	}
-	_, err := tx.Exec(`INSERT INTO eth.storage_cids (state_id, storage_leaf_key, cid, storage_path, node_type) VALUES ($1, $2, $3, $4, $5) 
-							  ON CONFLICT (state_id, storage_path) DO UPDATE SET (storage_leaf_key, cid, node_type) = ($2, $3, $5)`,
-		stateID, storageKey, storageCID.CID, storageCID.Path, storageCID.NodeType)
+	_, err := tx.Exec(`INSERT INTO eth.storage_cids (state_id, storage_leaf_key, cid, storage_path, node_type, block_number) VALUES ($1, $2, $3, $4, $5, $6)
+							  ON CONFLICT (state_id, storage_path, block_number) DO UPDATE SET (storage_leaf_key, cid, node_type) = ($2, $3, $5)`,
+		stateID, storageKey, storageCID.CID, storageCID.Path, storageCID.NodeType, blockNumber)
 	return err
 }
@@ -123,4 +123,64 @@ var _ = Describe("Indexer", func() {
 			}))
 		})
 	})
+
+	Describe("Index canonical headers", func() {
+		headerPayload := func(number, hash, parentHash string) *eth.CIDPayload {
+			return &eth.CIDPayload{
+				HeaderCID: eth.HeaderModel{
+					BlockNumber:     number,
+					BlockHash:       hash,
+					ParentHash:      parentHash,
+					CID:             mocks.HeaderCID.String(),
+					TotalDifficulty: "1",
+					Reward:          "0",
+				},
+			}
+		}
+		canonical := func(hash string) bool {
+			var c bool
+			err := db.Get(&c, `SELECT canonical FROM eth.header_cids WHERE block_hash = $1`, hash)
+			Expect(err).ToNot(HaveOccurred())
+			return c
+		}
+
+		It("Marks competing headers, and the chains they head, as non-canonical", func() {
+			err = repo.Index(headerPayload("1", "0xa1", "0xa0"))
+			Expect(err).ToNot(HaveOccurred())
+			err = repo.Index(headerPayload("2", "0xa2", "0xa1"))
+			Expect(err).ToNot(HaveOccurred())
+			err = repo.Index(headerPayload("2", "0xb2", "0xa1"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(canonical("0xa1")).To(BeTrue())
+			Expect(canonical("0xa2")).To(BeFalse())
+			Expect(canonical("0xb2")).To(BeTrue())
+
+			err = repo.Index(headerPayload("3", "0xa3", "0xa2"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(canonical("0xa1")).To(BeTrue())
+			Expect(canonical("0xa2")).To(BeTrue())
+			Expect(canonical("0xb2")).To(BeFalse())
+			Expect(canonical("0xa3")).To(BeTrue())
+
+			err = repo.Index(headerPayload("2", "0xb2", "0xa1"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(canonical("0xa1")).To(BeTrue())
+			Expect(canonical("0xa2")).To(BeFalse())
+			Expect(canonical("0xb2")).To(BeTrue())
+			Expect(canonical("0xa3")).To(BeFalse())
+
+			retriever := eth.NewCIDRetriever(db)
+			tx, err := db.Beginx()
+			Expect(err).ToNot(HaveOccurred())
+			headers, err := retriever.RetrieveHeaderCIDs(tx, 2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(headers)).To(Equal(1))
+			Expect(headers[0].BlockHash).To(Equal("0xb2"))
+			headers, err = retriever.RetrieveHeaderCIDs(tx, 3)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(headers)).To(Equal(0))
+			err = tx.Commit()
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
 })
@@ -24,7 +24,6 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ipfs/go-block-format"
-	"github.com/ipfs/go-blockservice"
 	"github.com/ipfs/go-cid"
 	log "github.com/sirupsen/logrus"
 
@@ -38,7 +37,7 @@ var (
 
 // IPLDFetcher satisfies the IPLDFetcher interface for ethereum
 type IPLDFetcher struct {
-	BlockService blockservice.BlockService
+	BlockService ipfs.BlockGetter
 }
 
 // NewIPLDFetcher creates a pointer to a new IPLDFetcher
@@ -52,6 +51,13 @@ func NewIPLDFetcher(ipfsPath string) (*IPLDFetcher, error) {
 	}, nil
 }
 
+// NewRemoteIPLDFetcher creates a pointer to a new IPLDFetcher which fetches through the HTTP API of a remote IPFS node
+func NewRemoteIPLDFetcher(config ipfs.RemoteClientConfig) *IPLDFetcher {
+	return &IPLDFetcher{
+		BlockService: ipfs.NewRemoteClient(config),
+	}
+}
+
 // Fetch is the exported method for fetching and returning all the IPLDS specified in the CIDWrapper
 func (f *IPLDFetcher) Fetch(cids shared.CIDsForFetching) (shared.IPLDs, error) {
 	cidWrapper, ok := cids.(*CIDWrapper)
@@ -34,6 +34,8 @@ import (
 // It interfaces directly with PG-IPFS
 type IPLDPGFetcher struct {
 	db *postgres.DB
+	// If true, the data of each IPLD is checked against the multihash of its CID before it is returned
+	Verify bool
 }
 
 // NewIPLDPGFetcher creates a pointer to a new IPLDPGFetcher
@@ -102,7 +104,7 @@ func (f *IPLDPGFetcher) Fetch(cids shared.CIDsForFetching) (shared.IPLDs, error)
 // FetchHeaders fetches headers
 func (f *IPLDPGFetcher) FetchHeader(tx *sqlx.Tx, c HeaderModel) (ipfs.BlockModel, error) {
 	log.Debug("fetching header ipld")
-	headerBytes, err := shared.FetchIPLD(tx, c.CID)
+	headerBytes, err := f.fetchIPLD(tx, c.CID)
 	if err != nil {
 		return ipfs.BlockModel{}, err
 	}
@@ -117,7 +119,7 @@ func (f *IPLDPGFetcher) FetchUncles(tx *sqlx.Tx, cids []UncleModel) ([]ipfs.Bloc
 	log.Debug("fetching uncle iplds")
 	uncleIPLDs := make([]ipfs.BlockModel, len(cids))
 	for i, c := range cids {
-		uncleBytes, err := shared.FetchIPLD(tx, c.CID)
+		uncleBytes, err := f.fetchIPLD(tx, c.CID)
 		if err != nil {
 			return nil, err
 		}
@@ -134,7 +136,7 @@ func (f *IPLDPGFetcher) FetchTrxs(tx *sqlx.Tx, cids []TxModel) ([]ipfs.BlockMode
 	log.Debug("fetching transaction iplds")
 	trxIPLDs := make([]ipfs.BlockModel, len(cids))
 	for i, c := range cids {
-		txBytes, err := shared.FetchIPLD(tx, c.CID)
+		txBytes, err := f.fetchIPLD(tx, c.CID)
 		if err != nil {
 			return nil, err
 		}
@@ -151,7 +153,7 @@ func (f *IPLDPGFetcher) FetchRcts(tx *sqlx.Tx, cids []ReceiptModel) ([]ipfs.Bloc
 	log.Debug("fetching receipt iplds")
 	rctIPLDs := make([]ipfs.BlockModel, len(cids))
 	for i, c := range cids {
-		rctBytes, err := shared.FetchIPLD(tx, c.CID)
+		rctBytes, err := f.fetchIPLD(tx, c.CID)
 		if err != nil {
 			return nil, err
 		}
@@ -171,7 +173,7 @@ func (f *IPLDPGFetcher) FetchState(tx *sqlx.Tx, cids []StateNodeModel) ([]StateN
 		if stateNode.CID == "" {
 			continue
 		}
-		stateBytes, err := shared.FetchIPLD(tx, stateNode.CID)
+		stateBytes, err := f.fetchIPLD(tx, stateNode.CID)
 		if err != nil {
 			return nil, err
 		}
@@ -196,7 +198,7 @@ func (f *IPLDPGFetcher) FetchStorage(tx *sqlx.Tx, cids []StorageNodeWithStateKey
 		if storageNode.CID == "" || storageNode.StateKey == "" {
 			continue
 		}
-		storageBytes, err := shared.FetchIPLD(tx, storageNode.CID)
+		storageBytes, err := f.fetchIPLD(tx, storageNode.CID)
 		if err != nil {
 			return nil, err
 		}
@@ -213,3 +215,12 @@ func (f *IPLDPGFetcher) FetchStorage(tx *sqlx.Tx, cids []StorageNodeWithStateKey
 	}
 	return storageNodes, nil
 }
+
+// fetchIPLD retrieves an IPLD from public.blocks, verifying it against its CID if Verify is set
+func (f *IPLDPGFetcher) fetchIPLD(tx *sqlx.Tx, c string) ([]byte, error) {
+	data, err := shared.FetchIPLD(tx, c)
+	if err != nil || !f.Verify {
+		return data, err
+	}
+	return data, shared.VerifyIPLD(c, data)
+}
@@ -61,5 +61,16 @@ var _ = Describe("IPLDPGFetcher", func() {
 			Expect(iplds.StateNodes).To(Equal(mocks.MockIPLDs.StateNodes))
 			Expect(iplds.StorageNodes).To(Equal(mocks.MockIPLDs.StorageNodes))
 		})
+
+		It("Errors on corrupted IPLDs when verifying", func() {
+			_, err := db.Exec(`UPDATE public.blocks SET data = $1 WHERE key = $2`, []byte("corrupted"), shared.BlockKey(mocks.Trx2CID))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = fetcher.Fetch(mocks.MockCIDWrapper)
+			Expect(err).ToNot(HaveOccurred())
+			fetcher.Verify = true
+			_, err = fetcher.Fetch(mocks.MockCIDWrapper)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(mocks.Trx2CID.String()))
+		})
 	})
 })
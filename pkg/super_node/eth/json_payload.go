@@ -0,0 +1,193 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/statediff"
+)
+
+// JSONPayload is the decoded form of the IPLDs sent to JSON subscriptions
+type JSONPayload struct {
+	BlockNumber     *hexutil.Big      `json:"blockNumber"`
+	TotalDifficulty *hexutil.Big      `json:"totalDifficulty"`
+	Header          *JSONHeader       `json:"header"`
+	Uncles          []JSONHeader      `json:"uncles"`
+	Transactions    []JSONTransaction `json:"transactions"`
+	Receipts        []JSONReceipt     `json:"receipts"`
+	StateNodes      []JSONStateNode   `json:"stateNodes"`
+	StorageNodes    []JSONStorageNode `json:"storageNodes"`
+}
+
+// JSONHeader is a decoded header or uncle with its CID
+type JSONHeader struct {
+	CID    string        `json:"cid"`
+	Header *types.Header `json:"header"`
+}
+
+// JSONTransaction is a decoded transaction with its CID
+type JSONTransaction struct {
+	CID         string             `json:"cid"`
+	Transaction *types.Transaction `json:"transaction"`
+}
+
+// JSONReceipt is a decoded receipt, including its logs, with its CID
+type JSONReceipt struct {
+	CID     string         `json:"cid"`
+	Receipt *types.Receipt `json:"receipt"`
+}
+
+// JSONAccount is the decoded account held in a state leaf node
+type JSONAccount struct {
+	Nonce       hexutil.Uint64 `json:"nonce"`
+	Balance     *hexutil.Big   `json:"balance"`
+	StorageRoot common.Hash    `json:"storageRoot"`
+	CodeHash    hexutil.Bytes  `json:"codeHash"`
+}
+
+// JSONStateNode is a state trie node with its CID; leaf nodes include their decoded account
+type JSONStateNode struct {
+	CID          string             `json:"cid"`
+	Type         statediff.NodeType `json:"type"`
+	StateLeafKey common.Hash        `json:"stateLeafKey"`
+	Path         hexutil.Bytes      `json:"path"`
+	Account      *JSONAccount       `json:"account,omitempty"`
+	Data         hexutil.Bytes      `json:"data"`
+}
+
+// JSONStorageNode is a storage trie node with its CID; leaf nodes include their decoded value
+type JSONStorageNode struct {
+	CID            string             `json:"cid"`
+	Type           statediff.NodeType `json:"type"`
+	StateLeafKey   common.Hash        `json:"stateLeafKey"`
+	StorageLeafKey common.Hash        `json:"storageLeafKey"`
+	Path           hexutil.Bytes      `json:"path"`
+	Value          hexutil.Bytes      `json:"value,omitempty"`
+	Data           hexutil.Bytes      `json:"data"`
+}
+
+// NewJSONPayload decodes the IPLDs into a JSONPayload
+func NewJSONPayload(iplds IPLDs) (JSONPayload, error) {
+	payload := JSONPayload{
+		BlockNumber:     (*hexutil.Big)(iplds.BlockNumber),
+		TotalDifficulty: (*hexutil.Big)(iplds.TotalDifficulty),
+		Uncles:          make([]JSONHeader, 0, len(iplds.Uncles)),
+		Transactions:    make([]JSONTransaction, 0, len(iplds.Transactions)),
+		Receipts:        make([]JSONReceipt, 0, len(iplds.Receipts)),
+		StateNodes:      make([]JSONStateNode, 0, len(iplds.StateNodes)),
+		StorageNodes:    make([]JSONStorageNode, 0, len(iplds.StorageNodes)),
+	}
+	if len(iplds.Header.Data) > 0 {
+		header := new(types.Header)
+		if err := rlp.DecodeBytes(iplds.Header.Data, header); err != nil {
+			return JSONPayload{}, err
+		}
+		payload.Header = &JSONHeader{CID: iplds.Header.CID, Header: header}
+	}
+	for _, uncleIPLD := range iplds.Uncles {
+		uncle := new(types.Header)
+		if err := rlp.DecodeBytes(uncleIPLD.Data, uncle); err != nil {
+			return JSONPayload{}, err
+		}
+		payload.Uncles = append(payload.Uncles, JSONHeader{CID: uncleIPLD.CID, Header: uncle})
+	}
+	for _, txIPLD := range iplds.Transactions {
+		tx := new(types.Transaction)
+		if err := rlp.DecodeBytes(txIPLD.Data, tx); err != nil {
+			return JSONPayload{}, err
+		}
+		payload.Transactions = append(payload.Transactions, JSONTransaction{CID: txIPLD.CID, Transaction: tx})
+	}
+	for _, rctIPLD := range iplds.Receipts {
+		rct := new(types.Receipt)
+		if err := rlp.DecodeBytes(rctIPLD.Data, rct); err != nil {
+			return JSONPayload{}, err
+		}
+		payload.Receipts = append(payload.Receipts, JSONReceipt{CID: rctIPLD.CID, Receipt: rct})
+	}
+	for _, stateNode := range iplds.StateNodes {
+		node := JSONStateNode{
+			CID:          stateNode.IPLD.CID,
+			Type:         stateNode.Type,
+			StateLeafKey: stateNode.StateLeafKey,
+			Path:         stateNode.Path,
+			Data:         stateNode.IPLD.Data,
+		}
+		if stateNode.Type == statediff.Leaf {
+			value, err := leafValue(stateNode.IPLD.Data)
+			if err != nil {
+				return JSONPayload{}, err
+			}
+			var account state.Account
+			if err := rlp.DecodeBytes(value, &account); err != nil {
+				return JSONPayload{}, err
+			}
+			node.Account = &JSONAccount{
+				Nonce:       hexutil.Uint64(account.Nonce),
+				Balance:     (*hexutil.Big)(account.Balance),
+				StorageRoot: account.Root,
+				CodeHash:    account.CodeHash,
+			}
+		}
+		payload.StateNodes = append(payload.StateNodes, node)
+	}
+	for _, storageNode := range iplds.StorageNodes {
+		node := JSONStorageNode{
+			CID:            storageNode.IPLD.CID,
+			Type:           storageNode.Type,
+			StateLeafKey:   storageNode.StateLeafKey,
+			StorageLeafKey: storageNode.StorageLeafKey,
+			Path:           storageNode.Path,
+			Data:           storageNode.IPLD.Data,
+		}
+		if storageNode.Type == statediff.Leaf {
+			value, err := leafValue(storageNode.IPLD.Data)
+			if err != nil {
+				return JSONPayload{}, err
+			}
+			var storageValue []byte
+			if err := rlp.DecodeBytes(value, &storageValue); err != nil {
+				return JSONPayload{}, err
+			}
+			node.Value = storageValue
+		}
+		payload.StorageNodes = append(payload.StorageNodes, node)
+	}
+	return payload, nil
+}
+
+// leafValue returns the value held in an rlp-encoded leaf node
+func leafValue(node []byte) ([]byte, error) {
+	var i []interface{}
+	if err := rlp.DecodeBytes(node, &i); err != nil {
+		return nil, err
+	}
+	if len(i) != 2 {
+		return nil, fmt.Errorf("eth leaf node expected to have 2 elements, has %d", len(i))
+	}
+	value, ok := i[1].([]byte)
+	if !ok {
+		return nil, fmt.Errorf("eth leaf node value expected to be a byte slice, got %T", i[1])
+	}
+	return value, nil
+}
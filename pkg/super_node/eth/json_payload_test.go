@@ -0,0 +1,76 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/statediff"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth/mocks"
+)
+
+var _ = Describe("JSONPayload", func() {
+	Describe("NewJSONPayload", func() {
+		It("Decodes the IPLDs into a JSON payload that carries their CIDs", func() {
+			payload, err := eth.NewJSONPayload(mocks.MockIPLDs)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(payload.BlockNumber.ToInt().Int64()).To(Equal(mocks.MockIPLDs.BlockNumber.Int64()))
+			Expect(payload.Header.CID).To(Equal(mocks.HeaderCID.String()))
+			Expect(payload.Header.Header.Hash()).To(Equal(mocks.MockBlock.Hash()))
+			Expect(len(payload.Uncles)).To(Equal(0))
+			Expect(len(payload.Transactions)).To(Equal(3))
+			for i, tx := range payload.Transactions {
+				Expect(tx.CID).To(Equal(mocks.MockIPLDs.Transactions[i].CID))
+				Expect(tx.Transaction.Hash()).To(Equal(mocks.MockTransactions[i].Hash()))
+			}
+			Expect(len(payload.Receipts)).To(Equal(3))
+			for i, rct := range payload.Receipts {
+				Expect(rct.CID).To(Equal(mocks.MockIPLDs.Receipts[i].CID))
+				Expect(len(rct.Receipt.Logs)).To(Equal(len(mocks.MockReceipts[i].Logs)))
+			}
+			Expect(len(payload.StateNodes)).To(Equal(2))
+			for _, stateNode := range payload.StateNodes {
+				Expect(stateNode.Type).To(Equal(statediff.Leaf))
+				Expect(stateNode.Account).ToNot(BeNil())
+				if bytes.Equal(stateNode.StateLeafKey.Bytes(), mocks.AccountLeafKey) {
+					Expect(stateNode.CID).To(Equal(mocks.State2IPLD.Cid().String()))
+					Expect(stateNode.Account.Balance.ToInt()).To(Equal(big.NewInt(1000)))
+					Expect(stateNode.Account.StorageRoot).To(Equal(common.HexToHash(mocks.AccountRoot)))
+				}
+				if bytes.Equal(stateNode.StateLeafKey.Bytes(), mocks.ContractLeafKey) {
+					Expect(stateNode.CID).To(Equal(mocks.State1IPLD.Cid().String()))
+					Expect(uint64(stateNode.Account.Nonce)).To(Equal(uint64(1)))
+					Expect(stateNode.Account.StorageRoot).To(Equal(common.HexToHash(mocks.ContractRoot)))
+				}
+			}
+			Expect(len(payload.StorageNodes)).To(Equal(1))
+			Expect(payload.StorageNodes[0].CID).To(Equal(mocks.StorageIPLD.Cid().String()))
+			Expect(payload.StorageNodes[0].StorageLeafKey.Bytes()).To(Equal(mocks.StorageLeafKey))
+			Expect([]byte(payload.StorageNodes[0].Value)).To(Equal(mocks.StorageValue))
+
+			_, err = json.Marshal(payload)
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+})
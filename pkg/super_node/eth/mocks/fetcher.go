@@ -0,0 +1,39 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mocks
+
+import (
+	"fmt"
+
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+// IPLDFetcher is a mock IPLD fetcher for use in tests
+// it returns IPLDs holding only the block number of the CIDs it is passed
+type IPLDFetcher struct {
+	ReturnErr error
+}
+
+// Fetch mock method
+func (f *IPLDFetcher) Fetch(cids shared.CIDsForFetching) (shared.IPLDs, error) {
+	cidWrapper, ok := cids.(*eth.CIDWrapper)
+	if !ok {
+		return nil, fmt.Errorf("ipld fetcher expected cids type %T got %T", &eth.CIDWrapper{}, cids)
+	}
+	return eth.IPLDs{BlockNumber: cidWrapper.BlockNumber}, f.ReturnErr
+}
@@ -63,3 +63,23 @@ func (pub *IterativeIPLDPublisher) Publish(payload shared.ConvertedData) (shared
 	pub.iteration++
 	return returnPayload, pub.ReturnErr
 }
+
+// BulkPublisherAndIndexer is the underlying struct for the BulkPublisherAndIndexer interface; used in testing
+type BulkPublisherAndIndexer struct {
+	PassedIPLDPayloads [][]eth.ConvertedPayload
+	ReturnErr          error
+}
+
+// PublishAndIndexBatch records the batch of IPLDPayloads it is passed
+func (pub *BulkPublisherAndIndexer) PublishAndIndexBatch(payloads []shared.ConvertedData) error {
+	batch := make([]eth.ConvertedPayload, 0, len(payloads))
+	for _, payload := range payloads {
+		ipldPayload, ok := payload.(eth.ConvertedPayload)
+		if !ok {
+			return fmt.Errorf("publish and index batch expected payload type %T got %T", eth.ConvertedPayload{}, payload)
+		}
+		batch = append(batch, ipldPayload)
+	}
+	pub.PassedIPLDPayloads = append(pub.PassedIPLDPayloads, batch)
+	return pub.ReturnErr
+}
@@ -0,0 +1,41 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mocks
+
+import (
+	"fmt"
+
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+// BlockValidator is the underlying struct for the BlockValidator interface; used in testing
+type BlockValidator struct {
+	PassedIPLDPayloads []eth.ConvertedPayload
+	ReturnMismatches   []shared.Mismatch
+	ReturnErr          error
+}
+
+// Validate records the IPLDPayload it is passed
+func (v *BlockValidator) Validate(payload shared.ConvertedData) ([]shared.Mismatch, error) {
+	ipldPayload, ok := payload.(eth.ConvertedPayload)
+	if !ok {
+		return nil, fmt.Errorf("validator expected payload type %T got %T", eth.ConvertedPayload{}, payload)
+	}
+	v.PassedIPLDPayloads = append(v.PassedIPLDPayloads, ipldPayload)
+	return v.ReturnMismatches, v.ReturnErr
+}
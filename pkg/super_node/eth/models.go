@@ -35,6 +35,7 @@ type HeaderModel struct {
 	Bloom           []byte `db:"bloom"`
 	Timestamp       uint64 `db:"timestamp"`
 	TimesValidated  int64  `db:"times_validated"`
+	Canonical       bool   `db:"canonical"`
 }
 
 // UncleModel is the db model for eth.uncle_cids
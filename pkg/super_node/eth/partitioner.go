@@ -0,0 +1,110 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/vulcanize/vulcanizedb/pkg/postgres"
+)
+
+// partitionLookahead is how close to the end of its partition a block has to be for the following partition to be created ahead of time
+const partitionLookahead = 1000
+
+// Partition is a block number range covered by a partition of the eth index tables, both ends are inclusive
+type Partition struct {
+	Start uint64
+	Stop  uint64
+}
+
+// Partitioner creates and removes the block number range partitions of the eth index tables
+// The partitions are created by the eth.create_block_partitions function, which links each one to the partitions
+// of the tables it references that cover the same range
+type Partitioner struct {
+	db *postgres.DB
+}
+
+// NewPartitioner returns a new Partitioner
+func NewPartitioner(db *postgres.DB) *Partitioner {
+	return &Partitioner{
+		db: db,
+	}
+}
+
+// EnsurePartitions creates the partitions covering the provided block number if they do not exist yet
+// When the block is near the end of its partition the following partition is created as well, so that it is in place before the head reaches it
+func (p *Partitioner) EnsurePartitions(blockNumber uint64) error {
+	var next uint64
+	if err := p.db.Get(&next, `SELECT eth.create_block_partitions($1)`, blockNumber); err != nil {
+		return err
+	}
+	if next-blockNumber > partitionLookahead {
+		return nil
+	}
+	_, err := p.db.Exec(`SELECT eth.create_block_partitions($1)`, next)
+	return err
+}
+
+// Partitions returns the block ranges of the partitions currently attached to the provided eth table, in ascending order
+func (p *Partitioner) Partitions(tx *sqlx.Tx, table string) ([]Partition, error) {
+	var size uint64
+	if err := tx.Get(&size, `SELECT eth.block_partition_size()`); err != nil {
+		return nil, err
+	}
+	starts := make([]uint64, 0)
+	pgStr := `SELECT substring(child.relname FROM '_(\d+)$')::BIGINT AS partition_start
+			FROM pg_inherits
+			INNER JOIN pg_class AS child ON (pg_inherits.inhrelid = child.oid)
+			WHERE pg_inherits.inhparent = $1::REGCLASS
+			ORDER BY partition_start`
+	if err := tx.Select(&starts, pgStr, "eth."+table); err != nil {
+		return nil, err
+	}
+	partitions := make([]Partition, len(starts))
+	for i, start := range starts {
+		partitions[i] = Partition{Start: start, Stop: start + size - 1}
+	}
+	return partitions, nil
+}
+
+// DropPartition drops the partition of the provided eth table starting at the provided block number, if it exists
+func (p *Partitioner) DropPartition(tx *sqlx.Tx, table string, start uint64) error {
+	// CASCADE only removes the foreign keys of previously detached partitions that still reference this one
+	_, err := tx.Exec(fmt.Sprintf(`DROP TABLE IF EXISTS eth.%s_%d CASCADE`, table, start))
+	return err
+}
+
+// DetachPartition detaches the partition of the provided eth table starting at the provided block number, if it exists
+// The detached table is kept, renamed with a _detached suffix and the time it was detached, so that the range can be partitioned again
+func (p *Partitioner) DetachPartition(tx *sqlx.Tx, table string, start uint64) error {
+	partition := fmt.Sprintf("%s_%d", table, start)
+	var exists bool
+	if err := tx.Get(&exists, `SELECT to_regclass($1) IS NOT NULL`, "eth."+partition); err != nil {
+		return err
+	}
+	if !exists {
+		return nil
+	}
+	if _, err := tx.Exec(fmt.Sprintf(`ALTER TABLE eth.%s DETACH PARTITION eth.%s`, table, partition)); err != nil {
+		return err
+	}
+	_, err := tx.Exec(fmt.Sprintf(`ALTER TABLE eth.%s RENAME TO %s_detached_%d`, partition, partition, time.Now().Unix()))
+	return err
+}
@@ -52,9 +52,7 @@ func (pub *IPLDPublisherAndIndexer) Publish(payload shared.ConvertedData) (share
 	if !ok {
 		return nil, fmt.Errorf("eth IPLDPublisherAndIndexer expected payload type %T got %T", ConvertedPayload{}, payload)
 	}
-	// Generate the iplds
-	headerNode, uncleNodes, txNodes, txTrieNodes, rctNodes, rctTrieNodes, err := ipld.FromBlockAndReceipts(ipldPayload.Block, ipldPayload.Receipts)
-	if err != nil {
+	if err := pub.indexer.ensurePartitions(ipldPayload.Block.Number().String()); err != nil {
 		return nil, err
 	}
 
@@ -74,139 +72,126 @@ func (pub *IPLDPublisherAndIndexer) Publish(payload shared.ConvertedData) (share
 		}
 	}()
 
+	err = pub.publishAndIndex(tx, ipldPayload)
+	// This IPLDPublisher does both publishing and indexing, we do not need to pass anything forward to the indexer
+	return nil, err // return err variable explicitly so that we return the err = tx.Commit() assignment in the defer
+}
+
+// publishAndIndex publishes and indexes the IPLDs of the provided payload within the provided db tx
+// The partitions covering the block need to exist before it is called
+func (pub *IPLDPublisherAndIndexer) publishAndIndex(tx *sqlx.Tx, ipldPayload ConvertedPayload) error {
+	// Generate the iplds
+	headerNode, uncleNodes, txNodes, txTrieNodes, rctNodes, rctTrieNodes, err := ipld.FromBlockAndReceipts(ipldPayload.Block, ipldPayload.Receipts)
+	if err != nil {
+		return err
+	}
+
 	// Publish trie nodes
 	for _, node := range txTrieNodes {
 		if err := shared.PublishIPLD(tx, node); err != nil {
-			return nil, err
+			return err
 		}
 	}
 	for _, node := range rctTrieNodes {
 		if err := shared.PublishIPLD(tx, node); err != nil {
-			return nil, err
+			return err
 		}
 	}
 
 	// Publish and index header
 	if err := shared.PublishIPLD(tx, headerNode); err != nil {
-		return nil, err
-	}
-	reward := common2.CalcEthBlockReward(ipldPayload.Block.Header(), ipldPayload.Block.Uncles(), ipldPayload.Block.Transactions(), ipldPayload.Receipts)
-	header := HeaderModel{
-		CID:             headerNode.Cid().String(),
-		ParentHash:      ipldPayload.Block.ParentHash().String(),
-		BlockNumber:     ipldPayload.Block.Number().String(),
-		BlockHash:       ipldPayload.Block.Hash().String(),
-		TotalDifficulty: ipldPayload.TotalDifficulty.String(),
-		Reward:          reward.String(),
-		Bloom:           ipldPayload.Block.Bloom().Bytes(),
-		StateRoot:       ipldPayload.Block.Root().String(),
-		RctRoot:         ipldPayload.Block.ReceiptHash().String(),
-		TxRoot:          ipldPayload.Block.TxHash().String(),
-		UncleRoot:       ipldPayload.Block.UncleHash().String(),
-		Timestamp:       ipldPayload.Block.Time(),
+		return err
 	}
+	header := newHeaderModel(ipldPayload, headerNode)
 	headerID, err := pub.indexer.indexHeaderCID(tx, header)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	// Publish and index uncles
 	for _, uncleNode := range uncleNodes {
 		if err := shared.PublishIPLD(tx, uncleNode); err != nil {
-			return nil, err
-		}
-		uncleReward := common2.CalcUncleMinerReward(ipldPayload.Block.Number().Int64(), uncleNode.Number.Int64())
-		uncle := UncleModel{
-			CID:        uncleNode.Cid().String(),
-			ParentHash: uncleNode.ParentHash.String(),
-			BlockHash:  uncleNode.Hash().String(),
-			Reward:     uncleReward.String(),
+			return err
 		}
-		if err := pub.indexer.indexUncleCID(tx, uncle, headerID); err != nil {
-			return nil, err
+		if err := pub.indexer.indexUncleCID(tx, newUncleModel(ipldPayload, uncleNode), headerID, header.BlockNumber); err != nil {
+			return err
 		}
 	}
 
 	// Publish and index txs and receipts
 	for i, txNode := range txNodes {
 		if err := shared.PublishIPLD(tx, txNode); err != nil {
-			return nil, err
+			return err
 		}
 		rctNode := rctNodes[i]
 		if err := shared.PublishIPLD(tx, rctNode); err != nil {
-			return nil, err
+			return err
 		}
 		txModel := ipldPayload.TxMetaData[i]
 		txModel.CID = txNode.Cid().String()
-		txID, err := pub.indexer.indexTransactionCID(tx, txModel, headerID)
+		txID, err := pub.indexer.indexTransactionCID(tx, txModel, headerID, header.BlockNumber)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		rctModel := ipldPayload.ReceiptMetaData[i]
 		rctModel.CID = rctNode.Cid().String()
-		if err := pub.indexer.indexReceiptCID(tx, rctModel, txID); err != nil {
-			return nil, err
+		if err := pub.indexer.indexReceiptCID(tx, rctModel, txID, header.BlockNumber); err != nil {
+			return err
+		}
+	}
+
+	// Publish contract code, it is keyed by its hash rather than indexed
+	for _, code := range ipldPayload.Codes {
+		if _, err := shared.PublishRaw(tx, ipld.RawBinary, multihash.KECCAK_256, code); err != nil {
+			return err
 		}
 	}
 
 	// Publish and index state and storage
-	err = pub.publishAndIndexStateAndStorage(tx, ipldPayload, headerID)
+	return pub.publishAndIndexStateAndStorage(tx, ipldPayload, headerID, header.BlockNumber)
+}
 
-	// This IPLDPublisher does both publishing and indexing, we do not need to pass anything forward to the indexer
-	return nil, err // return err variable explicitly so that we return the err = tx.Commit() assignment in the defer
+// newHeaderModel returns the header_cids row for the block of the payload
+func newHeaderModel(ipldPayload ConvertedPayload, headerNode *ipld.EthHeader) HeaderModel {
+	reward := common2.CalcEthBlockReward(ipldPayload.Block.Header(), ipldPayload.Block.Uncles(), ipldPayload.Block.Transactions(), ipldPayload.Receipts)
+	return HeaderModel{
+		CID:             headerNode.Cid().String(),
+		ParentHash:      ipldPayload.Block.ParentHash().String(),
+		BlockNumber:     ipldPayload.Block.Number().String(),
+		BlockHash:       ipldPayload.Block.Hash().String(),
+		TotalDifficulty: ipldPayload.TotalDifficulty.String(),
+		Reward:          reward.String(),
+		Bloom:           ipldPayload.Block.Bloom().Bytes(),
+		StateRoot:       ipldPayload.Block.Root().String(),
+		RctRoot:         ipldPayload.Block.ReceiptHash().String(),
+		TxRoot:          ipldPayload.Block.TxHash().String(),
+		UncleRoot:       ipldPayload.Block.UncleHash().String(),
+		Timestamp:       ipldPayload.Block.Time(),
+	}
+}
+
+// newUncleModel returns the uncle_cids row for an uncle of the block of the payload
+func newUncleModel(ipldPayload ConvertedPayload, uncleNode *ipld.EthHeader) UncleModel {
+	uncleReward := common2.CalcUncleMinerReward(ipldPayload.Block.Number().Int64(), uncleNode.Number.Int64())
+	return UncleModel{
+		CID:        uncleNode.Cid().String(),
+		ParentHash: uncleNode.ParentHash.String(),
+		BlockHash:  uncleNode.Hash().String(),
+		Reward:     uncleReward.String(),
+	}
 }
 
-func (pub *IPLDPublisherAndIndexer) publishAndIndexStateAndStorage(tx *sqlx.Tx, ipldPayload ConvertedPayload, headerID int64) error {
+func (pub *IPLDPublisherAndIndexer) publishAndIndexStateAndStorage(tx *sqlx.Tx, ipldPayload ConvertedPayload, headerID int64, blockNumber string) error {
 	// Publish and index state and storage
 	for _, stateNode := range ipldPayload.StateNodes {
-		stateCIDStr, err := shared.PublishRaw(tx, ipld.MEthStateTrie, multihash.KECCAK_256, stateNode.Value)
+		stateID, err := pub.publishAndIndexStateNode(tx, stateNode, headerID, blockNumber)
 		if err != nil {
 			return err
 		}
-		stateModel := StateNodeModel{
-			Path:     stateNode.Path,
-			StateKey: stateNode.LeafKey.String(),
-			CID:      stateCIDStr,
-			NodeType: ResolveFromNodeType(stateNode.Type),
-		}
-		stateID, err := pub.indexer.indexStateCID(tx, stateModel, headerID)
-		if err != nil {
-			return err
-		}
-		// If we have a leaf, decode and index the account data and any associated storage diffs
+		// If we have a leaf, index any associated storage diffs
 		if stateNode.Type == statediff.Leaf {
-			var i []interface{}
-			if err := rlp.DecodeBytes(stateNode.Value, &i); err != nil {
-				return err
-			}
-			if len(i) != 2 {
-				return fmt.Errorf("eth IPLDPublisherAndIndexer expected state leaf node rlp to decode into two elements")
-			}
-			var account state.Account
-			if err := rlp.DecodeBytes(i[1].([]byte), &account); err != nil {
-				return err
-			}
-			accountModel := StateAccountModel{
-				Balance:     account.Balance.String(),
-				Nonce:       account.Nonce,
-				CodeHash:    account.CodeHash,
-				StorageRoot: account.Root.String(),
-			}
-			if err := pub.indexer.indexStateAccount(tx, accountModel, stateID); err != nil {
-				return err
-			}
 			for _, storageNode := range ipldPayload.StorageNodes[common.Bytes2Hex(stateNode.Path)] {
-				storageCIDStr, err := shared.PublishRaw(tx, ipld.MEthStorageTrie, multihash.KECCAK_256, storageNode.Value)
-				if err != nil {
-					return err
-				}
-				storageModel := StorageNodeModel{
-					Path:       storageNode.Path,
-					StorageKey: storageNode.LeafKey.Hex(),
-					CID:        storageCIDStr,
-					NodeType:   ResolveFromNodeType(storageNode.Type),
-				}
-				if err := pub.indexer.indexStorageCID(tx, storageModel, stateID); err != nil {
+				if err := pub.publishAndIndexStorageNode(tx, storageNode, stateID, blockNumber); err != nil {
 					return err
 				}
 			}
@@ -215,6 +200,61 @@ func (pub *IPLDPublisherAndIndexer) publishAndIndexStateAndStorage(tx *sqlx.Tx,
 	return nil
 }
 
+// publishAndIndexStateNode publishes and indexes a state node, and the account it holds if it is a leaf, and returns the id of its state_cids row
+func (pub *IPLDPublisherAndIndexer) publishAndIndexStateNode(tx *sqlx.Tx, stateNode TrieNode, headerID int64, blockNumber string) (int64, error) {
+	stateCIDStr, err := shared.PublishRaw(tx, ipld.MEthStateTrie, multihash.KECCAK_256, stateNode.Value)
+	if err != nil {
+		return 0, err
+	}
+	stateModel := StateNodeModel{
+		Path:     stateNode.Path,
+		StateKey: stateNode.LeafKey.String(),
+		CID:      stateCIDStr,
+		NodeType: ResolveFromNodeType(stateNode.Type),
+	}
+	stateID, err := pub.indexer.indexStateCID(tx, stateModel, headerID, blockNumber)
+	if err != nil {
+		return 0, err
+	}
+	// If we have a leaf, decode and index the account data
+	if stateNode.Type != statediff.Leaf {
+		return stateID, nil
+	}
+	var i []interface{}
+	if err := rlp.DecodeBytes(stateNode.Value, &i); err != nil {
+		return 0, err
+	}
+	if len(i) != 2 {
+		return 0, fmt.Errorf("eth IPLDPublisherAndIndexer expected state leaf node rlp to decode into two elements")
+	}
+	var account state.Account
+	if err := rlp.DecodeBytes(i[1].([]byte), &account); err != nil {
+		return 0, err
+	}
+	accountModel := StateAccountModel{
+		Balance:     account.Balance.String(),
+		Nonce:       account.Nonce,
+		CodeHash:    account.CodeHash,
+		StorageRoot: account.Root.String(),
+	}
+	return stateID, pub.indexer.indexStateAccount(tx, accountModel, stateID, blockNumber)
+}
+
+// publishAndIndexStorageNode publishes and indexes a storage node of the state leaf with the provided state_cids id
+func (pub *IPLDPublisherAndIndexer) publishAndIndexStorageNode(tx *sqlx.Tx, storageNode TrieNode, stateID int64, blockNumber string) error {
+	storageCIDStr, err := shared.PublishRaw(tx, ipld.MEthStorageTrie, multihash.KECCAK_256, storageNode.Value)
+	if err != nil {
+		return err
+	}
+	storageModel := StorageNodeModel{
+		Path:       storageNode.Path,
+		StorageKey: storageNode.LeafKey.Hex(),
+		CID:        storageCIDStr,
+		NodeType:   ResolveFromNodeType(storageNode.Type),
+	}
+	return pub.indexer.indexStorageCID(tx, storageModel, stateID, blockNumber)
+}
+
 // Index satisfies the shared.CIDIndexer interface
 func (pub *IPLDPublisherAndIndexer) Index(cids shared.CIDsForIndexing) error {
 	return nil
@@ -18,6 +18,7 @@ package eth_test
 
 import (
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-ipfs-blockstore"
 	"github.com/ipfs/go-ipfs-ds-help"
@@ -167,7 +168,7 @@ var _ = Describe("PublishAndIndexer", func() {
 				prefixedKey := blockstore.BlockPrefix.String() + mhKey.String()
 				err = db.Get(&data, ipfsPgGet, prefixedKey)
 				Expect(err).ToNot(HaveOccurred())
-				pgStr = `SELECT * from eth.state_accounts WHERE state_id = $1`
+				pgStr = `SELECT id, state_id, balance, nonce, code_hash, storage_root FROM eth.state_accounts WHERE state_id = $1`
 				var account eth.StateAccountModel
 				err = db.Get(&account, pgStr, stateNode.ID)
 				Expect(err).ToNot(HaveOccurred())
@@ -200,7 +201,7 @@ var _ = Describe("PublishAndIndexer", func() {
 					}))
 				}
 			}
-			pgStr = `SELECT * from eth.state_accounts WHERE state_id = $1`
+			pgStr = `SELECT id, state_id, balance, nonce, code_hash, storage_root FROM eth.state_accounts WHERE state_id = $1`
 		})
 
 		It("Publishes and indexes storage IPLDs in a single tx", func() {
@@ -233,5 +234,17 @@ var _ = Describe("PublishAndIndexer", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(data).To(Equal(mocks.StorageLeafNode))
 		})
+
+		It("Publishes contract code under the raw keccak256 CID of the code", func() {
+			code := []byte{0x60, 0x2a, 0x60, 0x00, 0x52, 0x60, 0x20, 0x60, 0x00, 0xf3}
+			payload := mocks.MockConvertedPayload
+			payload.Codes = map[common.Hash][]byte{crypto.Keccak256Hash(code): code}
+			_, err = repo.Publish(payload)
+			Expect(err).ToNot(HaveOccurred())
+			var data []byte
+			err = db.Get(&data, ipfsPgGet, shared.BlockKey(eth.CodeCID(crypto.Keccak256Hash(code))))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(code))
+		})
 	})
 })
@@ -41,6 +41,9 @@ type IPLDPublisher struct {
 	ReceiptTriePutter     ipfs.DagPutter
 	StatePutter           ipfs.DagPutter
 	StoragePutter         ipfs.DagPutter
+	CodePutter            ipfs.DagPutter
+	// If set, the IPLDs of each payload are collected and put to IPFS in one batch rather than one request at a time
+	batchAdder ipfs.BatchAdder
 }
 
 // NewIPLDPublisher creates a pointer to a new IPLDPublisher which satisfies the IPLDPublisher interface
@@ -49,15 +52,29 @@ func NewIPLDPublisher(ipfsPath string) (*IPLDPublisher, error) {
 	if err != nil {
 		return nil, err
 	}
+	return newIPLDPublisher(node), nil
+}
+
+// NewRemoteIPLDPublisher creates a pointer to a new IPLDPublisher which publishes through the HTTP API of a remote IPFS node
+// The IPLDs of each payload are put to the remote node in batches
+func NewRemoteIPLDPublisher(config ipfs.RemoteClientConfig) *IPLDPublisher {
+	client := ipfs.NewRemoteClient(config)
+	pub := newIPLDPublisher(client)
+	pub.batchAdder = client
+	return pub
+}
+
+func newIPLDPublisher(adder ipfs.Adder) *IPLDPublisher {
 	return &IPLDPublisher{
-		HeaderPutter:          dag_putters.NewEthBlockHeaderDagPutter(node),
-		TransactionPutter:     dag_putters.NewEthTxsDagPutter(node),
-		TransactionTriePutter: dag_putters.NewEthTxTrieDagPutter(node),
-		ReceiptPutter:         dag_putters.NewEthReceiptDagPutter(node),
-		ReceiptTriePutter:     dag_putters.NewEthRctTrieDagPutter(node),
-		StatePutter:           dag_putters.NewEthStateDagPutter(node),
-		StoragePutter:         dag_putters.NewEthStorageDagPutter(node),
-	}, nil
+		HeaderPutter:          dag_putters.NewEthBlockHeaderDagPutter(adder),
+		TransactionPutter:     dag_putters.NewEthTxsDagPutter(adder),
+		TransactionTriePutter: dag_putters.NewEthTxTrieDagPutter(adder),
+		ReceiptPutter:         dag_putters.NewEthReceiptDagPutter(adder),
+		ReceiptTriePutter:     dag_putters.NewEthRctTrieDagPutter(adder),
+		StatePutter:           dag_putters.NewEthStateDagPutter(adder),
+		StoragePutter:         dag_putters.NewEthStorageDagPutter(adder),
+		CodePutter:            dag_putters.NewEthCodeDagPutter(adder),
+	}
 }
 
 // Publish publishes an IPLDPayload to IPFS and returns the corresponding CIDPayload
@@ -66,6 +83,23 @@ func (pub *IPLDPublisher) Publish(payload shared.ConvertedData) (shared.CIDsForI
 	if !ok {
 		return nil, fmt.Errorf("eth publisher expected payload type %T got %T", ConvertedPayload{}, payload)
 	}
+	if pub.batchAdder == nil {
+		return pub.publish(ipldPayload)
+	}
+	// The putters of a fresh publisher collect the payload's IPLDs, which are then added in one batch
+	collector := ipfs.NewNodeCollector()
+	cids, err := newIPLDPublisher(collector).publish(ipldPayload)
+	if err != nil {
+		return nil, err
+	}
+	if err := pub.batchAdder.AddMany(collector.Nodes()); err != nil {
+		return nil, err
+	}
+	return cids, nil
+}
+
+// publish puts the IPLDs of the payload with the publisher's putters and returns the corresponding CIDPayload
+func (pub *IPLDPublisher) publish(ipldPayload ConvertedPayload) (*CIDPayload, error) {
 	// Generate the nodes for publishing
 	headerNode, uncleNodes, txNodes, txTrieNodes, rctNodes, rctTrieNodes, err := ipld.FromBlockAndReceipts(ipldPayload.Block, ipldPayload.Receipts)
 	if err != nil {
@@ -133,6 +167,11 @@ func (pub *IPLDPublisher) Publish(payload shared.ConvertedData) (shared.CIDsForI
 		return nil, err
 	}
 
+	// Process and publish contract code, it is keyed by its hash rather than indexed
+	if err := pub.publishCode(ipldPayload.Codes); err != nil {
+		return nil, err
+	}
+
 	// Package CIDs and their metadata into a single struct
 	return &CIDPayload{
 		HeaderCID:       header,
@@ -273,3 +312,16 @@ func (pub *IPLDPublisher) publishStorageNodes(storageNodes map[string][]TrieNode
 	}
 	return storageLeafCids, nil
 }
+
+func (pub *IPLDPublisher) publishCode(codes map[common.Hash][]byte) error {
+	for _, code := range codes {
+		node, err := ipld.NewEthCode(code)
+		if err != nil {
+			return err
+		}
+		if _, err := pub.CodePutter.DagPut(node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
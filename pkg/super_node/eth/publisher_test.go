@@ -18,9 +18,11 @@ package eth_test
 
 import (
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	"github.com/vulcanize/vulcanizedb/pkg/ipfs"
 	mocks2 "github.com/vulcanize/vulcanizedb/pkg/ipfs/mocks"
 	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth"
 	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth/mocks"
@@ -101,5 +103,63 @@ var _ = Describe("Publisher", func() {
 			Expect(cidPayload.StateNodeCIDs[1]).To(Equal(mocks.MockCIDPayload.StateNodeCIDs[1]))
 			Expect(cidPayload.StorageNodeCIDs).To(Equal(mocks.MockCIDPayload.StorageNodeCIDs))
 		})
+
+		It("Publishes to and fetches from a remote IPFS node through its HTTP API", func() {
+			api := mocks2.NewRemoteAPI()
+			defer api.Close()
+			config := ipfs.RemoteClientConfig{URL: api.URL()}
+			publisher := eth.NewRemoteIPLDPublisher(config)
+			payload, err := publisher.Publish(mocks.MockConvertedPayload)
+			Expect(err).ToNot(HaveOccurred())
+			cidPayload, ok := payload.(*eth.CIDPayload)
+			Expect(ok).To(BeTrue())
+			Expect(cidPayload.HeaderCID).To(Equal(mocks.MockCIDPayload.HeaderCID))
+			Expect(cidPayload.TransactionCIDs).To(Equal(mocks.MockCIDPayload.TransactionCIDs))
+			Expect(cidPayload.ReceiptCIDs).To(Equal(mocks.MockCIDPayload.ReceiptCIDs))
+			Expect(cidPayload.StateNodeCIDs).To(Equal(mocks.MockCIDPayload.StateNodeCIDs))
+			Expect(cidPayload.StorageNodeCIDs).To(Equal(mocks.MockCIDPayload.StorageNodeCIDs))
+			data, ok := api.Block(mocks.StorageCID)
+			Expect(ok).To(BeTrue())
+			Expect(data).To(Equal(mocks.StorageIPLD.RawData()))
+			// The payload's IPLDs are put in one batch, which takes a request for each of the header (shared by uncles), tx, tx trie,
+			// receipt, receipt trie, state and storage codecs rather than one for each IPLD
+			Expect(api.Requests("block/put")).To(Equal(7))
+
+			fetcher := eth.NewRemoteIPLDFetcher(config)
+			rcts := make([]eth.ReceiptModel, 0, len(cidPayload.ReceiptCIDs))
+			for _, rct := range cidPayload.ReceiptCIDs {
+				rcts = append(rcts, rct)
+			}
+			i, err := fetcher.Fetch(&eth.CIDWrapper{
+				BlockNumber:  mocks.MockBlock.Number(),
+				Header:       cidPayload.HeaderCID,
+				Transactions: cidPayload.TransactionCIDs,
+				Receipts:     rcts,
+				StateNodes:   cidPayload.StateNodeCIDs,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			iplds, ok := i.(eth.IPLDs)
+			Expect(ok).To(BeTrue())
+			Expect(iplds.Header).To(Equal(ipfs.BlockModel{
+				Data: mocks.HeaderIPLD.RawData(),
+				CID:  mocks.HeaderCID.String(),
+			}))
+			Expect(len(iplds.Transactions)).To(Equal(3))
+			Expect(len(iplds.Receipts)).To(Equal(3))
+			Expect(len(iplds.StateNodes)).To(Equal(2))
+		})
+
+		It("Publishes contract code under the raw keccak256 CID of the code", func() {
+			api := mocks2.NewRemoteAPI()
+			defer api.Close()
+			code := []byte{0x60, 0x2a, 0x60, 0x00, 0x52, 0x60, 0x20, 0x60, 0x00, 0xf3}
+			payload := mocks.MockConvertedPayload
+			payload.Codes = map[common.Hash][]byte{crypto.Keccak256Hash(code): code}
+			_, err := eth.NewRemoteIPLDPublisher(ipfs.RemoteClientConfig{URL: api.URL()}).Publish(payload)
+			Expect(err).ToNot(HaveOccurred())
+			data, ok := api.Block(eth.CodeCID(crypto.Keccak256Hash(code)))
+			Expect(ok).To(BeTrue())
+			Expect(data).To(Equal(code))
+		})
 	})
 })
@@ -0,0 +1,98 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/statediff"
+
+	"github.com/vulcanize/vulcanizedb/pkg/postgres"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+// SpillQueue satisfies the shared.SpillQueue interface for ethereum
+// It holds rlp encoded statediff payloads in Postgres until they can be published and indexed
+type SpillQueue struct {
+	db *postgres.DB
+}
+
+// NewSpillQueue creates a pointer to a new SpillQueue which satisfies the shared.SpillQueue interface
+func NewSpillQueue(db *postgres.DB) *SpillQueue {
+	return &SpillQueue{
+		db: db,
+	}
+}
+
+// Spill adds the statediff payload to the queue and records its height as a known gap until it has been indexed
+func (sq *SpillQueue) Spill(payload shared.RawChainData, height int64) error {
+	stateDiffPayload, ok := payload.(statediff.Payload)
+	if !ok {
+		return fmt.Errorf("eth spill queue: expected payload type %T got %T", statediff.Payload{}, payload)
+	}
+	data, err := rlp.EncodeToBytes(stateDiffPayload)
+	if err != nil {
+		return err
+	}
+	tx, err := sq.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+	if _, err = tx.Exec(`INSERT INTO eth.spill_queue (data, height) VALUES ($1, $2)`, data, height); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO eth.known_gaps (block_number) VALUES ($1) ON CONFLICT DO NOTHING`, height)
+	return err
+}
+
+// Pop removes the oldest statediff payload from the queue and returns it
+// The returned bool is false if the queue is empty
+func (sq *SpillQueue) Pop() (shared.RawChainData, bool, error) {
+	var data []byte
+	err := sq.db.Get(&data, `DELETE FROM eth.spill_queue
+			WHERE id = (SELECT id FROM eth.spill_queue ORDER BY id LIMIT 1 FOR UPDATE SKIP LOCKED)
+			RETURNING data`)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var payload statediff.Payload
+	if err := rlp.DecodeBytes(data, &payload); err != nil {
+		return nil, false, err
+	}
+	return payload, true, nil
+}
+
+// RecordGap records the height of data that was dropped as a known gap
+func (sq *SpillQueue) RecordGap(height int64) error {
+	_, err := sq.db.Exec(`INSERT INTO eth.known_gaps (block_number) VALUES ($1) ON CONFLICT DO NOTHING`, height)
+	return err
+}
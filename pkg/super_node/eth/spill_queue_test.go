@@ -0,0 +1,103 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/vulcanizedb/pkg/postgres"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth/mocks"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+var _ = Describe("SpillQueue", func() {
+	var (
+		db         *postgres.DB
+		err        error
+		spillQueue *eth.SpillQueue
+		repo       *eth.CIDIndexer
+		retriever  *eth.CIDRetriever
+	)
+	BeforeEach(func() {
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+		spillQueue = eth.NewSpillQueue(db)
+		repo = eth.NewCIDIndexer(db)
+		retriever = eth.NewCIDRetriever(db)
+	})
+	AfterEach(func() {
+		eth.TearDownDB(db)
+	})
+
+	Describe("Spill and Pop", func() {
+		It("Durably queues statediff payloads and pops them in the order they were spilled", func() {
+			otherPayload := mocks.MockStateDiffPayload
+			otherPayload.StateObjectRlp = []byte{}
+			err = spillQueue.Spill(mocks.MockStateDiffPayload, 1)
+			Expect(err).ToNot(HaveOccurred())
+			err = spillQueue.Spill(otherPayload, 2)
+			Expect(err).ToNot(HaveOccurred())
+
+			payload, ok, err := spillQueue.Pop()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(payload).To(Equal(mocks.MockStateDiffPayload))
+			payload, ok, err = spillQueue.Pop()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(payload).To(Equal(otherPayload))
+			_, ok, err = spillQueue.Pop()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeFalse())
+		})
+
+		It("Returns an error if the payload is not a statediff payload", func() {
+			err = spillQueue.Spill(mocks.MockConvertedPayload, 1)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Known gaps", func() {
+		It("Reports spilled and dropped heights above the head of the index as gaps until they are indexed", func() {
+			err = repo.Index(mocks.MockCIDPayload)
+			Expect(err).ToNot(HaveOccurred())
+			err = spillQueue.Spill(mocks.MockStateDiffPayload, 2)
+			Expect(err).ToNot(HaveOccurred())
+			err = spillQueue.RecordGap(4)
+			Expect(err).ToNot(HaveOccurred())
+
+			gaps, err := retriever.RetrieveGapsInData(1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(gaps)).To(Equal(3))
+			Expect(shared.ListContainsGap(gaps, shared.Gap{Start: 0, Stop: 0})).To(BeTrue())
+			Expect(shared.ListContainsGap(gaps, shared.Gap{Start: 2, Stop: 2})).To(BeTrue())
+			Expect(shared.ListContainsGap(gaps, shared.Gap{Start: 4, Stop: 4})).To(BeTrue())
+
+			payload := *mocks.MockCIDPayload
+			payload.HeaderCID.BlockNumber = "4"
+			err = repo.Index(&payload)
+			Expect(err).ToNot(HaveOccurred())
+			gaps, err = retriever.RetrieveGapsInData(1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(gaps)).To(Equal(2))
+			Expect(shared.ListContainsGap(gaps, shared.Gap{Start: 0, Stop: 0})).To(BeTrue())
+			Expect(shared.ListContainsGap(gaps, shared.Gap{Start: 2, Stop: 3})).To(BeTrue())
+		})
+	})
+})
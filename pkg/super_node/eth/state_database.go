@@ -0,0 +1,129 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-ipfs-blockstore"
+	"github.com/ipfs/go-ipfs-ds-help"
+	"github.com/lib/pq"
+	"github.com/multiformats/go-multihash"
+
+	"github.com/vulcanize/vulcanizedb/pkg/ipfs/ipld"
+	"github.com/vulcanize/vulcanizedb/pkg/postgres"
+)
+
+var (
+	errNotFound = errors.New("not found")
+	// nodeCodecs are the codecs trie nodes and contract code can be published under
+	nodeCodecs = []uint64{ipld.MEthStateTrie, ipld.MEthStorageTrie, cid.Raw}
+)
+
+// IPLDKeyValueStore is a read-only ethdb.KeyValueStore which resolves trie nodes and contract code from the Postgres blockstore
+// Keys are the keccak256 hashes of the values; values written to the store are held in memory and are never persisted
+type IPLDKeyValueStore struct {
+	*memorydb.Database
+	db *postgres.DB
+}
+
+// NewIPLDKeyValueStore returns a new IPLDKeyValueStore reading from the provided db
+func NewIPLDKeyValueStore(db *postgres.DB) *IPLDKeyValueStore {
+	return &IPLDKeyValueStore{
+		Database: memorydb.New(),
+		db:       db,
+	}
+}
+
+// stateDatabase is a state.Database which reports contract code missing from the blockstore as a codeUnavailableError
+// Code is fetched from the node and published as state diffs are synced, so it is only missing if the account's address could not be resolved
+type stateDatabase struct {
+	state.Database
+}
+
+// NewStateDatabase returns a state.Database which resolves trie nodes and contract code from the Postgres blockstore
+func NewStateDatabase(db *postgres.DB) state.Database {
+	return stateDatabase{state.NewDatabase(rawdb.NewDatabase(NewIPLDKeyValueStore(db)))}
+}
+
+// ContractCode satisfies the state.Database interface
+func (sd stateDatabase) ContractCode(addrHash, codeHash common.Hash) ([]byte, error) {
+	code, err := sd.Database.ContractCode(addrHash, codeHash)
+	if err != nil {
+		return nil, codeUnavailableError{codeHash: codeHash}
+	}
+	return code, nil
+}
+
+// ContractCodeSize satisfies the state.Database interface
+func (sd stateDatabase) ContractCodeSize(addrHash, codeHash common.Hash) (int, error) {
+	size, err := sd.Database.ContractCodeSize(addrHash, codeHash)
+	if err != nil {
+		return 0, codeUnavailableError{codeHash: codeHash}
+	}
+	return size, nil
+}
+
+// Has satisfies the ethdb.KeyValueReader interface
+func (s *IPLDKeyValueStore) Has(key []byte) (bool, error) {
+	_, err := s.Get(key)
+	if err == errNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Get satisfies the ethdb.KeyValueReader interface
+// The key is the keccak256 hash of a trie node or contract code, which is resolved to the blockstore key of the
+// CID the value could have been published under
+func (s *IPLDKeyValueStore) Get(key []byte) ([]byte, error) {
+	if len(key) != common.HashLength {
+		return nil, errNotFound
+	}
+	mhKeys, err := blockstoreKeys(key)
+	if err != nil {
+		return nil, err
+	}
+	var values [][]byte
+	pgStr := `SELECT data FROM public.blocks WHERE key = ANY($1) LIMIT 1`
+	if err := s.db.Select(&values, pgStr, pq.Array(mhKeys)); err != nil {
+		return nil, err
+	}
+	if len(values) < 1 {
+		return nil, errNotFound
+	}
+	return values[0], nil
+}
+
+// blockstoreKeys returns the blockstore keys for the keccak256 hash under each of the node codecs
+func blockstoreKeys(hash []byte) ([]string, error) {
+	mh, err := multihash.Encode(hash, multihash.KECCAK_256)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, len(nodeCodecs))
+	for i, codec := range nodeCodecs {
+		dbKey := dshelp.CidToDsKey(cid.NewCidV1(codec, mh))
+		keys[i] = blockstore.BlockPrefix.String() + dbKey.String()
+	}
+	return keys, nil
+}
@@ -0,0 +1,121 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth_test
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/jmoiron/sqlx"
+	"github.com/multiformats/go-multihash"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/vulcanizedb/pkg/ipfs/ipld"
+	"github.com/vulcanize/vulcanizedb/pkg/postgres"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+var _ = Describe("IPLD state database", func() {
+	var (
+		db        *postgres.DB
+		root      common.Hash
+		addr      = common.HexToAddress("0xaE9BEa628c4Ce503DcFD7E305CaB4e29E7476594")
+		code      = []byte{0x60, 0x00, 0x54, 0x60, 0x00, 0x52, 0x60, 0x20, 0x60, 0x00, 0xf3}
+		slot      = common.HexToHash("0x0")
+		slotValue = common.HexToHash("0x2a")
+	)
+	BeforeEach(func() {
+		var err error
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+		// Build a state trie in memory
+		memDB := rawdb.NewMemoryDatabase()
+		stateDB, err := state.New(common.Hash{}, state.NewDatabase(memDB))
+		Expect(err).ToNot(HaveOccurred())
+		stateDB.SetBalance(addr, big.NewInt(1000))
+		stateDB.SetNonce(addr, 3)
+		stateDB.SetCode(addr, code)
+		stateDB.SetState(addr, slot, slotValue)
+		root, err = stateDB.Commit(true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stateDB.Database().TrieDB().Commit(root, false)).ToNot(HaveOccurred())
+		// Publish all of its nodes and the contract code to the blockstore, as syncing does
+		tx, err := db.Beginx()
+		Expect(err).ToNot(HaveOccurred())
+		publishAll(tx, memDB, crypto.Keccak256Hash(code))
+		Expect(tx.Commit()).ToNot(HaveOccurred())
+	})
+	AfterEach(func() {
+		eth.TearDownDB(db)
+	})
+
+	It("Resolves accounts, storage and code from the blockstore", func() {
+		stateDB, err := state.New(root, eth.NewStateDatabase(db))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stateDB.GetBalance(addr)).To(Equal(big.NewInt(1000)))
+		Expect(stateDB.GetNonce(addr)).To(Equal(uint64(3)))
+		Expect(stateDB.GetCode(addr)).To(Equal(code))
+		Expect(stateDB.GetState(addr, slot)).To(Equal(slotValue))
+		Expect(stateDB.Error()).ToNot(HaveOccurred())
+	})
+
+	It("Reports contract code missing from the blockstore as unavailable", func() {
+		codeCID, err := ipld.RawdataToCid(ipld.RawBinary, code, multihash.KECCAK_256)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = db.Exec(`DELETE FROM public.blocks WHERE key = $1`, shared.BlockKey(codeCID))
+		Expect(err).ToNot(HaveOccurred())
+		stateDB, err := state.New(root, eth.NewStateDatabase(db))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stateDB.GetCode(addr)).To(BeNil())
+		Expect(stateDB.Error()).To(HaveOccurred())
+		Expect(stateDB.Error().Error()).To(ContainSubstring("is unavailable: it has not been fetched from the node"))
+	})
+
+	It("Reports missing trie nodes", func() {
+		_, err := state.New(crypto.Keccak256Hash([]byte{1}), eth.NewStateDatabase(db))
+		Expect(err).To(HaveOccurred())
+		has, err := eth.NewIPLDKeyValueStore(db).Has(root.Bytes())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(has).To(BeTrue())
+		has, err = eth.NewIPLDKeyValueStore(db).Has(crypto.Keccak256([]byte{1}))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(has).To(BeFalse())
+	})
+})
+
+// publishAll writes every trie node in the memory database to the blockstore as a state trie node, and the code as raw
+func publishAll(tx *sqlx.Tx, memDB ethdb.Database, codeHash common.Hash) {
+	it := memDB.NewIterator()
+	defer it.Release()
+	for it.Next() {
+		if len(it.Key()) != common.HashLength {
+			continue
+		}
+		codec := uint64(ipld.MEthStateTrie)
+		if common.BytesToHash(it.Key()) == codeHash {
+			codec = ipld.RawBinary
+		}
+		_, err := shared.PublishRaw(tx, codec, multihash.KECCAK_256, common.CopyBytes(it.Value()))
+		Expect(err).ToNot(HaveOccurred())
+	}
+}
@@ -26,52 +26,54 @@ import (
 
 // SubscriptionSettings config is used by a subscriber to specify what eth data to stream from the super node
 type SubscriptionSettings struct {
-	BackFill      bool
-	BackFillOnly  bool
-	Start         *big.Int
-	End           *big.Int // set to 0 or a negative value to have no ending block
-	HeaderFilter  HeaderFilter
-	TxFilter      TxFilter
-	ReceiptFilter ReceiptFilter
-	StateFilter   StateFilter
-	StorageFilter StorageFilter
+	BackFill      bool                      `json:"backFill"`
+	BackFillOnly  bool                      `json:"backFillOnly"`
+	Start         *big.Int                  `json:"start"`
+	End           *big.Int                  `json:"end"`          // set to 0 or a negative value to have no ending block
+	Cursor        shared.Cursor             `json:"cursor"`       // set to resume from the block after this cursor
+	SlowConsumer  shared.SlowConsumerPolicy `json:"slowConsumer"` // what to do with payloads when the subscription falls behind
+	HeaderFilter  HeaderFilter              `json:"headerFilter"`
+	TxFilter      TxFilter                  `json:"txFilter"`
+	ReceiptFilter ReceiptFilter             `json:"receiptFilter"`
+	StateFilter   StateFilter               `json:"stateFilter"`
+	StorageFilter StorageFilter             `json:"storageFilter"`
 }
 
 // HeaderFilter contains filter settings for headers
 type HeaderFilter struct {
-	Off    bool
-	Uncles bool
+	Off    bool `json:"off"`
+	Uncles bool `json:"uncles"`
 }
 
 // TxFilter contains filter settings for txs
 type TxFilter struct {
-	Off bool
-	Src []string
-	Dst []string
+	Off bool     `json:"off"`
+	Src []string `json:"src"`
+	Dst []string `json:"dst"`
 }
 
 // ReceiptFilter contains filter settings for receipts
 type ReceiptFilter struct {
-	Off bool
+	Off bool `json:"off"`
 	// TODO: change this so that we filter for receipts first and we always return the corresponding transaction
-	MatchTxs     bool     // turn on to retrieve receipts that pair with retrieved transactions
-	LogAddresses []string // receipt contains logs from the provided addresses
-	Topics       [][]string
+	MatchTxs     bool       `json:"matchTxs"`     // turn on to retrieve receipts that pair with retrieved transactions
+	LogAddresses []string   `json:"logAddresses"` // receipt contains logs from the provided addresses
+	Topics       [][]string `json:"topics"`
 }
 
 // StateFilter contains filter settings for state
 type StateFilter struct {
-	Off               bool
-	Addresses         []string // is converted to state key by taking its keccak256 hash
-	IntermediateNodes bool
+	Off               bool     `json:"off"`
+	Addresses         []string `json:"addresses"` // is converted to state key by taking its keccak256 hash
+	IntermediateNodes bool     `json:"intermediateNodes"`
 }
 
 // StorageFilter contains filter settings for storage
 type StorageFilter struct {
-	Off               bool
-	Addresses         []string
-	StorageKeys       []string // need to be the hashs key themselves not slot position
-	IntermediateNodes bool
+	Off               bool     `json:"off"`
+	Addresses         []string `json:"addresses"`
+	StorageKeys       []string `json:"storageKeys"` // need to be the hashs key themselves not slot position
+	IntermediateNodes bool     `json:"intermediateNodes"`
 }
 
 // Init is used to initialize a EthSubscription struct with env variables
@@ -84,6 +86,21 @@ func NewEthSubscriptionConfig() (*SubscriptionSettings, error) {
 	// 0 start means we start at the beginning and 0 end means we continue indefinitely
 	sc.Start = big.NewInt(viper.GetInt64("superNode.ethSubscription.startingBlock"))
 	sc.End = big.NewInt(viper.GetInt64("superNode.ethSubscription.endingBlock"))
+	// Below defaults to the zero cursor, which means we are not resuming a previous subscription
+	cursor, err := shared.ParseCursor(viper.GetString("superNode.ethSubscription.cursor"))
+	if err != nil {
+		return nil, err
+	}
+	sc.Cursor = cursor
+	// Below defaults to dropping payloads when the subscription falls behind
+	mode, err := shared.NewSlowConsumerMode(viper.GetString("superNode.ethSubscription.slowConsumer.policy"))
+	if err != nil {
+		return nil, err
+	}
+	sc.SlowConsumer = shared.SlowConsumerPolicy{
+		Mode:    mode,
+		Timeout: uint64(viper.GetInt64("superNode.ethSubscription.slowConsumer.timeout")),
+	}
 	// Below default to false, which means we get all headers and no uncles by default
 	sc.HeaderFilter = HeaderFilter{
 		Off:    viper.GetBool("superNode.ethSubscription.headerFilter.off"),
@@ -150,3 +167,13 @@ func (sc *SubscriptionSettings) HistoricalDataOnly() bool {
 func (sc *SubscriptionSettings) ChainType() shared.ChainType {
 	return shared.Ethereum
 }
+
+// ResumeCursor satisfies the SubscriptionSettings() interface
+func (sc *SubscriptionSettings) ResumeCursor() shared.Cursor {
+	return sc.Cursor
+}
+
+// SlowConsumerPolicy satisfies the SubscriptionSettings() interface
+func (sc *SubscriptionSettings) SlowConsumerPolicy() shared.SlowConsumerPolicy {
+	return sc.SlowConsumer
+}
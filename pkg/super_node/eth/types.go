@@ -38,6 +38,8 @@ type ConvertedPayload struct {
 	ReceiptMetaData []ReceiptModel
 	StateNodes      []TrieNode
 	StorageNodes    map[string][]TrieNode
+	// Contract code keyed by code hash, fetched from the node by the CodeFetcher since statediffs do not carry it
+	Codes map[common.Hash][]byte
 }
 
 // Height satisfies the StreamedIPLDs interface
@@ -71,6 +73,7 @@ type CIDPayload struct {
 // Passed to IPLDFetcher
 type CIDWrapper struct {
 	BlockNumber  *big.Int
+	BlockHash    string // hash of the block the CIDs belong to, set even when the header is filtered out
 	Header       HeaderModel
 	Uncles       []UncleModel
 	Transactions []TxModel
@@ -0,0 +1,483 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/statediff"
+	"github.com/jmoiron/sqlx"
+	"github.com/multiformats/go-multihash"
+
+	"github.com/vulcanize/vulcanizedb/pkg/ipfs/ipld"
+	"github.com/vulcanize/vulcanizedb/pkg/postgres"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+// Objects of the eth index recorded in the validation audit
+const (
+	headerObject  = "header"
+	uncleObject   = "uncle"
+	txObject      = "transaction"
+	rctObject     = "receipt"
+	stateObject   = "state"
+	storageObject = "storage"
+)
+
+// Validator satisfies the BlockValidator interface for ethereum
+// It compares a block fetched from the node with the rows indexed for it, and publishes and reindexes only the objects that differ
+// Like the IPLDPublisherAndIndexer, it interfaces directly with the public.blocks table of PG-IPFS
+type Validator struct {
+	publisher *IPLDPublisherAndIndexer
+	indexer   *CIDIndexer
+	db        *postgres.DB
+}
+
+// NewValidator creates a pointer to a new Validator which satisfies the BlockValidator interface
+func NewValidator(db *postgres.DB) *Validator {
+	publisher := NewIPLDPublisherAndIndexer(db)
+	return &Validator{
+		publisher: publisher,
+		indexer:   publisher.indexer,
+		db:        db,
+	}
+}
+
+// Validate compares the header, uncles, transactions, receipts, and state and storage nodes of the payload with the indexed ones
+// If the block is not indexed at all it is published and indexed in full, otherwise only the objects that are missing or differ are
+// rewritten and those that were indexed for the block but are not part of it are removed
+// Either way the header is marked validated once more, and the mismatches found are recorded in eth.validation_audit
+func (v *Validator) Validate(payload shared.ConvertedData) (mismatches []shared.Mismatch, err error) {
+	ipldPayload, ok := payload.(ConvertedPayload)
+	if !ok {
+		return nil, fmt.Errorf("eth validator expected payload type %T got %T", ConvertedPayload{}, payload)
+	}
+	blockNumber := ipldPayload.Block.Number().String()
+	if err := v.indexer.ensurePartitions(blockNumber); err != nil {
+		return nil, err
+	}
+
+	// Begin new db tx
+	tx, err := v.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	bv := &blockValidation{
+		tx:          tx,
+		validator:   v,
+		payload:     ipldPayload,
+		blockNumber: blockNumber,
+		recorder:    shared.NewMismatchRecorder(ipldPayload.Block.NumberU64(), ipldPayload.Block.Hash().String()),
+	}
+	if err := bv.validate(); err != nil {
+		return nil, err
+	}
+	if err := v.audit(tx, bv.recorder.Mismatches); err != nil {
+		return nil, err
+	}
+	return bv.recorder.Mismatches, nil
+}
+
+// audit records the mismatches found for a block in eth.validation_audit
+func (v *Validator) audit(tx *sqlx.Tx, mismatches []shared.Mismatch) error {
+	for _, mismatch := range mismatches {
+		if _, err := tx.Exec(`INSERT INTO eth.validation_audit (block_number, block_hash, object, object_key, field, indexed, fetched, action, node_id)
+								VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+			mismatch.BlockNumber, mismatch.BlockHash, mismatch.Object, mismatch.Key, mismatch.Field, mismatch.Indexed,
+			mismatch.Fetched, mismatch.Action, v.db.NodeID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// blockValidation holds the state of the validation of a single block
+type blockValidation struct {
+	tx          *sqlx.Tx
+	validator   *Validator
+	payload     ConvertedPayload
+	blockNumber string
+	headerID    int64
+	recorder    *shared.MismatchRecorder
+}
+
+func (bv *blockValidation) validate() error {
+	headerNode, uncleNodes, txNodes, txTrieNodes, rctNodes, rctTrieNodes, err := ipld.FromBlockAndReceipts(bv.payload.Block, bv.payload.Receipts)
+	if err != nil {
+		return err
+	}
+	header := newHeaderModel(bv.payload, headerNode)
+	var indexed HeaderModel
+	err = bv.tx.Get(&indexed, `SELECT * FROM eth.header_cids WHERE block_number = $1 AND block_hash = $2`, bv.blockNumber, header.BlockHash)
+	if err == sql.ErrNoRows {
+		// The block is not indexed, most likely because a competing block was indexed at this height in its place
+		bv.recorder.Record(headerObject, header.BlockHash, "cid", "", header.CID, shared.InsertedAction)
+		return bv.validator.publisher.publishAndIndex(bv.tx, bv.payload)
+	}
+	if err != nil {
+		return err
+	}
+	bv.headerID = indexed.ID
+
+	if err := bv.validateHeader(indexed, header, headerNode); err != nil {
+		return err
+	}
+	if err := bv.validateUncles(uncleNodes); err != nil {
+		return err
+	}
+	txsChanged, rctsChanged, err := bv.validateTxsAndRcts(txNodes, rctNodes)
+	if err != nil {
+		return err
+	}
+	if txsChanged {
+		for _, node := range txTrieNodes {
+			if err := shared.PublishIPLD(bv.tx, node); err != nil {
+				return err
+			}
+		}
+	}
+	if rctsChanged {
+		for _, node := range rctTrieNodes {
+			if err := shared.PublishIPLD(bv.tx, node); err != nil {
+				return err
+			}
+		}
+	}
+	return bv.validateStateAndStorage()
+}
+
+// validateHeader rewrites the header row if any of its columns differ from the fetched header, and marks it validated and canonical
+func (bv *blockValidation) validateHeader(indexed, header HeaderModel, headerNode *ipld.EthHeader) error {
+	if bv.recorder.Compare(headerObject, header.BlockHash,
+		shared.Field{Name: "cid", Indexed: indexed.CID, Fetched: header.CID},
+		shared.Field{Name: "parent_hash", Indexed: indexed.ParentHash, Fetched: header.ParentHash},
+		shared.Field{Name: "td", Indexed: indexed.TotalDifficulty, Fetched: header.TotalDifficulty},
+		shared.Field{Name: "reward", Indexed: indexed.Reward, Fetched: header.Reward},
+		shared.Field{Name: "state_root", Indexed: indexed.StateRoot, Fetched: header.StateRoot},
+		shared.Field{Name: "tx_root", Indexed: indexed.TxRoot, Fetched: header.TxRoot},
+		shared.Field{Name: "receipt_root", Indexed: indexed.RctRoot, Fetched: header.RctRoot},
+		shared.Field{Name: "uncle_root", Indexed: indexed.UncleRoot, Fetched: header.UncleRoot},
+		shared.Field{Name: "bloom", Indexed: hexutil.Encode(indexed.Bloom), Fetched: hexutil.Encode(header.Bloom)},
+		shared.Field{Name: "timestamp", Indexed: strconv.FormatUint(indexed.Timestamp, 10), Fetched: strconv.FormatUint(header.Timestamp, 10)},
+	) {
+		if indexed.CID != header.CID {
+			if err := shared.PublishIPLD(bv.tx, headerNode); err != nil {
+				return err
+			}
+		}
+		if _, err := bv.tx.Exec(`UPDATE eth.header_cids SET (parent_hash, cid, td, reward, state_root, tx_root, receipt_root, uncle_root, bloom, timestamp) =
+									($3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+									WHERE id = $1 AND block_number = $2`,
+			bv.headerID, bv.blockNumber, header.ParentHash, header.CID, header.TotalDifficulty, header.Reward, header.StateRoot,
+			header.TxRoot, header.RctRoot, header.UncleRoot, header.Bloom, header.Timestamp); err != nil {
+			return err
+		}
+	}
+	if _, err := bv.tx.Exec(`UPDATE eth.header_cids SET times_validated = times_validated + 1 WHERE id = $1 AND block_number = $2`,
+		bv.headerID, bv.blockNumber); err != nil {
+		return err
+	}
+	// The node only serves blocks on its canonical chain
+	if !indexed.Canonical {
+		bv.recorder.Record(headerObject, header.BlockHash, "canonical", "false", "true", shared.RewrittenAction)
+		return bv.validator.indexer.indexCanonical(bv.tx, header)
+	}
+	return nil
+}
+
+func (bv *blockValidation) validateUncles(uncleNodes []*ipld.EthHeader) error {
+	indexedUncles := make([]UncleModel, 0)
+	if err := bv.tx.Select(&indexedUncles, `SELECT id, header_id, block_hash, parent_hash, cid, reward FROM eth.uncle_cids
+									WHERE header_id = $1 AND block_number = $2`, bv.headerID, bv.blockNumber); err != nil {
+		return err
+	}
+	byHash := make(map[string]UncleModel, len(indexedUncles))
+	for _, uncle := range indexedUncles {
+		byHash[uncle.BlockHash] = uncle
+	}
+	for _, uncleNode := range uncleNodes {
+		uncle := newUncleModel(bv.payload, uncleNode)
+		indexed, ok := byHash[uncle.BlockHash]
+		delete(byHash, uncle.BlockHash)
+		if !ok {
+			bv.recorder.Record(uncleObject, uncle.BlockHash, "cid", "", uncle.CID, shared.InsertedAction)
+		} else if !bv.recorder.Compare(uncleObject, uncle.BlockHash,
+			shared.Field{Name: "cid", Indexed: indexed.CID, Fetched: uncle.CID},
+			shared.Field{Name: "parent_hash", Indexed: indexed.ParentHash, Fetched: uncle.ParentHash},
+			shared.Field{Name: "reward", Indexed: indexed.Reward, Fetched: uncle.Reward},
+		) {
+			continue
+		}
+		if err := shared.PublishIPLD(bv.tx, uncleNode); err != nil {
+			return err
+		}
+		if err := bv.validator.indexer.indexUncleCID(bv.tx, uncle, bv.headerID, bv.blockNumber); err != nil {
+			return err
+		}
+	}
+	for _, indexed := range indexedUncles {
+		if _, ok := byHash[indexed.BlockHash]; !ok {
+			continue
+		}
+		bv.recorder.Record(uncleObject, indexed.BlockHash, "cid", indexed.CID, "", shared.RemovedAction)
+		if _, err := bv.tx.Exec(`DELETE FROM eth.uncle_cids WHERE id = $1 AND block_number = $2`, indexed.ID, bv.blockNumber); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexedReceipt is a receipt_cids row along with the hash of the transaction it belongs to
+type indexedReceipt struct {
+	ReceiptModel
+	TxHash string `db:"tx_hash"`
+}
+
+// validateTxsAndRcts rewrites the transactions and receipts that differ from the fetched ones, and returns whether any transactions or any receipts were rewritten
+func (bv *blockValidation) validateTxsAndRcts(txNodes []*ipld.EthTx, rctNodes []*ipld.EthReceipt) (bool, bool, error) {
+	indexedTxs := make([]TxModel, 0)
+	if err := bv.tx.Select(&indexedTxs, `SELECT id, header_id, tx_hash, index, cid, dst, src FROM eth.transaction_cids
+									WHERE header_id = $1 AND block_number = $2
+									ORDER BY index`, bv.headerID, bv.blockNumber); err != nil {
+		return false, false, err
+	}
+	indexedRcts := make([]indexedReceipt, 0)
+	if err := bv.tx.Select(&indexedRcts, `SELECT receipt_cids.id, receipt_cids.tx_id, receipt_cids.cid, receipt_cids.contract,
+									receipt_cids.contract_hash, receipt_cids.topic0s, receipt_cids.topic1s, receipt_cids.topic2s,
+									receipt_cids.topic3s, receipt_cids.log_contracts, transaction_cids.tx_hash
+									FROM eth.receipt_cids
+									INNER JOIN eth.transaction_cids ON (receipt_cids.tx_id = transaction_cids.id AND receipt_cids.block_number = transaction_cids.block_number)
+									WHERE transaction_cids.header_id = $1 AND transaction_cids.block_number = $2`, bv.headerID, bv.blockNumber); err != nil {
+		return false, false, err
+	}
+	txsByHash := make(map[string]TxModel, len(indexedTxs))
+	for _, trx := range indexedTxs {
+		txsByHash[trx.TxHash] = trx
+	}
+	rctsByTxHash := make(map[string]ReceiptModel, len(indexedRcts))
+	for _, rct := range indexedRcts {
+		rctsByTxHash[rct.TxHash] = rct.ReceiptModel
+	}
+
+	var txsChanged, rctsChanged bool
+	for i, txNode := range txNodes {
+		txModel := bv.payload.TxMetaData[i]
+		txModel.CID = txNode.Cid().String()
+		indexed, ok := txsByHash[txModel.TxHash]
+		delete(txsByHash, txModel.TxHash)
+		txID := indexed.ID
+		if !ok {
+			bv.recorder.Record(txObject, txModel.TxHash, "cid", "", txModel.CID, shared.InsertedAction)
+		}
+		if !ok || bv.recorder.Compare(txObject, txModel.TxHash,
+			shared.Field{Name: "cid", Indexed: indexed.CID, Fetched: txModel.CID},
+			shared.Field{Name: "index", Indexed: strconv.FormatInt(indexed.Index, 10), Fetched: strconv.FormatInt(txModel.Index, 10)},
+			shared.Field{Name: "dst", Indexed: indexed.Dst, Fetched: txModel.Dst},
+			shared.Field{Name: "src", Indexed: indexed.Src, Fetched: txModel.Src},
+		) {
+			txsChanged = true
+			if err := shared.PublishIPLD(bv.tx, txNode); err != nil {
+				return false, false, err
+			}
+			var err error
+			txID, err = bv.validator.indexer.indexTransactionCID(bv.tx, txModel, bv.headerID, bv.blockNumber)
+			if err != nil {
+				return false, false, err
+			}
+		}
+
+		rctModel := bv.payload.ReceiptMetaData[i]
+		rctModel.CID = rctNodes[i].Cid().String()
+		indexedRct, ok := rctsByTxHash[txModel.TxHash]
+		if !ok {
+			bv.recorder.Record(rctObject, txModel.TxHash, "cid", "", rctModel.CID, shared.InsertedAction)
+		}
+		if !ok || bv.recorder.Compare(rctObject, txModel.TxHash,
+			shared.Field{Name: "cid", Indexed: indexedRct.CID, Fetched: rctModel.CID},
+			shared.Field{Name: "contract", Indexed: indexedRct.Contract, Fetched: rctModel.Contract},
+			shared.Field{Name: "contract_hash", Indexed: indexedRct.ContractHash, Fetched: rctModel.ContractHash},
+			shared.Field{Name: "topic0s", Indexed: strings.Join(indexedRct.Topic0s, ","), Fetched: strings.Join(rctModel.Topic0s, ",")},
+			shared.Field{Name: "topic1s", Indexed: strings.Join(indexedRct.Topic1s, ","), Fetched: strings.Join(rctModel.Topic1s, ",")},
+			shared.Field{Name: "topic2s", Indexed: strings.Join(indexedRct.Topic2s, ","), Fetched: strings.Join(rctModel.Topic2s, ",")},
+			shared.Field{Name: "topic3s", Indexed: strings.Join(indexedRct.Topic3s, ","), Fetched: strings.Join(rctModel.Topic3s, ",")},
+			shared.Field{Name: "log_contracts", Indexed: strings.Join(indexedRct.LogContracts, ","), Fetched: strings.Join(rctModel.LogContracts, ",")},
+		) {
+			rctsChanged = true
+			if err := shared.PublishIPLD(bv.tx, rctNodes[i]); err != nil {
+				return false, false, err
+			}
+			if err := bv.validator.indexer.indexReceiptCID(bv.tx, rctModel, txID, bv.blockNumber); err != nil {
+				return false, false, err
+			}
+		}
+	}
+	// Transactions that are not part of the block are removed along with their receipts
+	for _, indexed := range indexedTxs {
+		if _, ok := txsByHash[indexed.TxHash]; !ok {
+			continue
+		}
+		bv.recorder.Record(txObject, indexed.TxHash, "cid", indexed.CID, "", shared.RemovedAction)
+		if _, err := bv.tx.Exec(`DELETE FROM eth.transaction_cids WHERE id = $1 AND block_number = $2`, indexed.ID, bv.blockNumber); err != nil {
+			return false, false, err
+		}
+	}
+	return txsChanged, rctsChanged, nil
+}
+
+// validateStateAndStorage rewrites the state and storage nodes that differ from the fetched ones
+// The account of a state leaf is decoded from the node itself, so it only needs to be rewritten along with the node
+func (bv *blockValidation) validateStateAndStorage() error {
+	indexedStateNodes := make([]StateNodeModel, 0)
+	if err := bv.tx.Select(&indexedStateNodes, `SELECT id, header_id, state_path, state_leaf_key, node_type, cid FROM eth.state_cids
+									WHERE header_id = $1 AND block_number = $2`, bv.headerID, bv.blockNumber); err != nil {
+		return err
+	}
+	indexedStorageNodes := make([]indexedStorageNode, 0)
+	if err := bv.tx.Select(&indexedStorageNodes, `SELECT storage_cids.id, storage_cids.state_id, storage_cids.storage_path,
+									storage_cids.storage_leaf_key, storage_cids.node_type, storage_cids.cid, state_cids.state_path
+									FROM eth.storage_cids
+									INNER JOIN eth.state_cids ON (storage_cids.state_id = state_cids.id AND storage_cids.block_number = state_cids.block_number)
+									WHERE state_cids.header_id = $1 AND state_cids.block_number = $2`, bv.headerID, bv.blockNumber); err != nil {
+		return err
+	}
+	statesByPath := make(map[string]StateNodeModel, len(indexedStateNodes))
+	for _, stateNode := range indexedStateNodes {
+		statesByPath[common.Bytes2Hex(stateNode.Path)] = stateNode
+	}
+	storageByPath := make(map[string]StorageNodeModel, len(indexedStorageNodes))
+	for _, storageNode := range indexedStorageNodes {
+		storageByPath[storageNode.key()] = storageNode.StorageNodeModel
+	}
+
+	for _, stateNode := range bv.payload.StateNodes {
+		statePath := common.Bytes2Hex(stateNode.Path)
+		stateCID, err := ipld.RawdataToCid(ipld.MEthStateTrie, stateNode.Value, multihash.KECCAK_256)
+		if err != nil {
+			return err
+		}
+		key := hexutil.Encode(stateNode.Path)
+		indexed, ok := statesByPath[statePath]
+		delete(statesByPath, statePath)
+		stateID := indexed.ID
+		if !ok {
+			bv.recorder.Record(stateObject, key, "cid", "", stateCID.String(), shared.InsertedAction)
+		}
+		if !ok || bv.recorder.Compare(stateObject, key,
+			shared.Field{Name: "cid", Indexed: indexed.CID, Fetched: stateCID.String()},
+			shared.Field{Name: "state_leaf_key", Indexed: indexed.StateKey, Fetched: leafKey(stateNode.LeafKey)},
+			shared.Field{Name: "node_type", Indexed: strconv.Itoa(indexed.NodeType), Fetched: strconv.Itoa(ResolveFromNodeType(stateNode.Type))},
+		) {
+			stateID, err = bv.validator.publisher.publishAndIndexStateNode(bv.tx, stateNode, bv.headerID, bv.blockNumber)
+			if err != nil {
+				return err
+			}
+		}
+		if stateNode.Type != statediff.Leaf {
+			continue
+		}
+		for _, storageNode := range bv.payload.StorageNodes[statePath] {
+			if err := bv.validateStorageNode(storageNode, statePath, stateID, storageByPath); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Nodes that are not part of the block's state diff are removed, removing a state node removes its account and storage nodes too
+	for _, indexed := range indexedStateNodes {
+		if _, ok := statesByPath[common.Bytes2Hex(indexed.Path)]; !ok {
+			continue
+		}
+		bv.recorder.Record(stateObject, hexutil.Encode(indexed.Path), "cid", indexed.CID, "", shared.RemovedAction)
+		if _, err := bv.tx.Exec(`DELETE FROM eth.state_cids WHERE id = $1 AND block_number = $2`, indexed.ID, bv.blockNumber); err != nil {
+			return err
+		}
+	}
+	for _, indexed := range indexedStorageNodes {
+		if _, ok := storageByPath[indexed.key()]; !ok {
+			continue
+		}
+		if _, ok := statesByPath[common.Bytes2Hex(indexed.StatePath)]; ok {
+			// already removed along with its state node
+			continue
+		}
+		bv.recorder.Record(storageObject, indexed.auditKey(), "cid", indexed.CID, "", shared.RemovedAction)
+		if _, err := bv.tx.Exec(`DELETE FROM eth.storage_cids WHERE id = $1 AND block_number = $2`, indexed.ID, bv.blockNumber); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (bv *blockValidation) validateStorageNode(storageNode TrieNode, statePath string, stateID int64, storageByPath map[string]StorageNodeModel) error {
+	storageCID, err := ipld.RawdataToCid(ipld.MEthStorageTrie, storageNode.Value, multihash.KECCAK_256)
+	if err != nil {
+		return err
+	}
+	path := statePath + "/" + common.Bytes2Hex(storageNode.Path)
+	key := "0x" + path
+	indexed, ok := storageByPath[path]
+	delete(storageByPath, path)
+	if !ok {
+		bv.recorder.Record(storageObject, key, "cid", "", storageCID.String(), shared.InsertedAction)
+	} else if !bv.recorder.Compare(storageObject, key,
+		shared.Field{Name: "cid", Indexed: indexed.CID, Fetched: storageCID.String()},
+		shared.Field{Name: "storage_leaf_key", Indexed: indexed.StorageKey, Fetched: leafKey(storageNode.LeafKey)},
+		shared.Field{Name: "node_type", Indexed: strconv.Itoa(indexed.NodeType), Fetched: strconv.Itoa(ResolveFromNodeType(storageNode.Type))},
+	) {
+		return nil
+	}
+	return bv.validator.publisher.publishAndIndexStorageNode(bv.tx, storageNode, stateID, bv.blockNumber)
+}
+
+// indexedStorageNode is a storage_cids row along with the path of the state node it belongs to
+type indexedStorageNode struct {
+	StorageNodeModel
+	StatePath []byte `db:"state_path"`
+}
+
+// key identifies the storage node within the block by its state and storage paths
+func (sn indexedStorageNode) key() string {
+	return common.Bytes2Hex(sn.StatePath) + "/" + common.Bytes2Hex(sn.Path)
+}
+
+func (sn indexedStorageNode) auditKey() string {
+	return "0x" + sn.key()
+}
+
+// leafKey returns the leaf key as it is indexed, the indexer leaves the key of intermediate nodes empty
+func leafKey(key common.Hash) string {
+	if key == nullHash {
+		return ""
+	}
+	return key.String()
+}
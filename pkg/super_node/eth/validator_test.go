@@ -0,0 +1,150 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/vulcanizedb/pkg/postgres"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth/mocks"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+var _ = Describe("Validator", func() {
+	var (
+		db        *postgres.DB
+		err       error
+		validator *eth.Validator
+		blockHash = mocks.MockBlock.Hash().String()
+	)
+	BeforeEach(func() {
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = eth.NewIPLDPublisherAndIndexer(db).Publish(mocks.MockConvertedPayload)
+		Expect(err).ToNot(HaveOccurred())
+		validator = eth.NewValidator(db)
+	})
+	AfterEach(func() {
+		eth.TearDownDB(db)
+	})
+
+	auditedMismatches := func() []shared.Mismatch {
+		audited := make([]shared.Mismatch, 0)
+		err := db.Select(&audited, `SELECT block_number, block_hash, object, object_key, field, indexed, fetched, action
+									FROM eth.validation_audit ORDER BY id`)
+		Expect(err).ToNot(HaveOccurred())
+		return audited
+	}
+
+	It("Finds no mismatches for a block indexed from the same payload, and marks it validated", func() {
+		mismatches, err := validator.Validate(mocks.MockConvertedPayload)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(mismatches)).To(Equal(0))
+		Expect(len(auditedMismatches())).To(Equal(0))
+		var timesValidated int
+		err = db.Get(&timesValidated, `SELECT times_validated FROM eth.header_cids WHERE block_hash = $1`, blockHash)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(timesValidated).To(Equal(2))
+	})
+
+	It("Rewrites only the columns that differ, and audits them", func() {
+		txHash := mocks.MockTransactions[1].Hash().String()
+		_, err = db.Exec(`UPDATE eth.transaction_cids SET dst = $1 WHERE tx_hash = $2`, "0xbad", txHash)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = db.Exec(`UPDATE eth.header_cids SET td = 0`)
+		Expect(err).ToNot(HaveOccurred())
+		mismatches, err := validator.Validate(mocks.MockConvertedPayload)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(mismatches).To(Equal([]shared.Mismatch{
+			{
+				BlockNumber: 1,
+				BlockHash:   blockHash,
+				Object:      "header",
+				Key:         blockHash,
+				Field:       "td",
+				Indexed:     "0",
+				Fetched:     mocks.MockConvertedPayload.TotalDifficulty.String(),
+				Action:      shared.RewrittenAction,
+			},
+			{
+				BlockNumber: 1,
+				BlockHash:   blockHash,
+				Object:      "transaction",
+				Key:         txHash,
+				Field:       "dst",
+				Indexed:     "0xbad",
+				Fetched:     mocks.MockConvertedPayload.TxMetaData[1].Dst,
+				Action:      shared.RewrittenAction,
+			},
+		}))
+		Expect(auditedMismatches()).To(Equal(mismatches))
+		var dst string
+		err = db.Get(&dst, `SELECT dst FROM eth.transaction_cids WHERE tx_hash = $1`, txHash)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(dst).To(Equal(mocks.MockConvertedPayload.TxMetaData[1].Dst))
+		var td string
+		err = db.Get(&td, `SELECT td FROM eth.header_cids WHERE block_hash = $1`, blockHash)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(td).To(Equal(mocks.MockConvertedPayload.TotalDifficulty.String()))
+	})
+
+	It("Inserts missing objects and removes objects that are not part of the block", func() {
+		_, err = db.Exec(`DELETE FROM eth.storage_cids`)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = db.Exec(`INSERT INTO eth.transaction_cids (header_id, tx_hash, cid, dst, src, index, block_number)
+							SELECT id, '0xbad', 'mockCID', '', '', 9, block_number FROM eth.header_cids`)
+		Expect(err).ToNot(HaveOccurred())
+		mismatches, err := validator.Validate(mocks.MockConvertedPayload)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(mismatches)).To(Equal(2))
+		Expect(mismatches[0].Object).To(Equal("transaction"))
+		Expect(mismatches[0].Key).To(Equal("0xbad"))
+		Expect(mismatches[0].Action).To(Equal(shared.RemovedAction))
+		Expect(mismatches[1].Object).To(Equal("storage"))
+		Expect(mismatches[1].Fetched).To(Equal(mocks.StorageCID.String()))
+		Expect(mismatches[1].Action).To(Equal(shared.InsertedAction))
+		var txs, storageNodes int
+		err = db.Get(&txs, `SELECT COUNT(*) FROM eth.transaction_cids`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(txs).To(Equal(len(mocks.MockTransactions)))
+		err = db.Get(&storageNodes, `SELECT COUNT(*) FROM eth.storage_cids`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(storageNodes).To(Equal(1))
+	})
+
+	It("Publishes and indexes a block that is not indexed in full", func() {
+		_, err = db.Exec(`DELETE FROM eth.header_cids`)
+		Expect(err).ToNot(HaveOccurred())
+		mismatches, err := validator.Validate(mocks.MockConvertedPayload)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(mismatches)).To(Equal(1))
+		Expect(mismatches[0].Object).To(Equal("header"))
+		Expect(mismatches[0].Fetched).To(Equal(mocks.HeaderCID.String()))
+		Expect(mismatches[0].Action).To(Equal(shared.InsertedAction))
+		var header eth.HeaderModel
+		err = db.Get(&header, `SELECT * FROM eth.header_cids WHERE block_hash = $1`, blockHash)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(header.TimesValidated).To(Equal(int64(1)))
+		Expect(header.Canonical).To(BeTrue())
+		var txs int
+		err = db.Get(&txs, `SELECT COUNT(*) FROM eth.transaction_cids`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(txs).To(Equal(len(mocks.MockTransactions)))
+	})
+})
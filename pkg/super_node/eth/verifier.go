@@ -0,0 +1,272 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipfs/go-cid"
+	"github.com/jmoiron/sqlx"
+	"github.com/multiformats/go-multihash"
+
+	"github.com/vulcanize/vulcanizedb/pkg/postgres"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+// Verifier satisfies the IPLDVerifier interface for ethereum
+type Verifier struct {
+	db        *postgres.DB
+	retriever *CIDRetriever
+}
+
+// NewVerifier creates a pointer to a new Verifier
+func NewVerifier(db *postgres.DB) *Verifier {
+	return &Verifier{
+		db:        db,
+		retriever: NewCIDRetriever(db),
+	}
+}
+
+// Verify checks every IPLD referenced by the eth cid tables for the headers at the provided height against the multihash of its CID
+// The roots of each header are then cross-checked against the stored IPLDs: the tx and receipt roots against the roots derived from the
+// tx and receipt IPLDs, and the state root against the root node of the state trie if it was indexed for the block
+func (v *Verifier) Verify(blockNumber uint64) (faults []shared.Fault, err error) {
+	tx, err := v.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+	headers := make([]HeaderModel, 0)
+	pgStr := `SELECT * FROM eth.header_cids
+			WHERE block_number = $1
+			ORDER BY id`
+	if err = tx.Select(&headers, pgStr, blockNumber); err != nil {
+		return nil, err
+	}
+	faults = make([]shared.Fault, 0)
+	for _, header := range headers {
+		bv := &blockVerification{
+			tx:          tx,
+			blockNumber: blockNumber,
+			header:      header,
+		}
+		if err = v.verifyBlock(bv); err != nil {
+			return nil, err
+		}
+		faults = append(faults, bv.faults...)
+	}
+	return faults, nil
+}
+
+func (v *Verifier) verifyBlock(bv *blockVerification) error {
+	if err := bv.verifyHeader(); err != nil {
+		return err
+	}
+	uncles, err := v.retriever.RetrieveUncleCIDsByHeaderID(bv.tx, bv.header.ID)
+	if err != nil {
+		return err
+	}
+	for _, uncle := range uncles {
+		if _, _, err := bv.fetch(uncle.CID); err != nil {
+			return err
+		}
+	}
+	if err := v.verifyTxsAndRcts(bv); err != nil {
+		return err
+	}
+	return v.verifyStateAndStorage(bv)
+}
+
+// verifyTxsAndRcts checks the tx and receipt IPLDs, and the tx and receipt roots they derive if all of them are intact
+func (v *Verifier) verifyTxsAndRcts(bv *blockVerification) error {
+	txCIDs, err := v.retriever.RetrieveTxCIDsByHeaderID(bv.tx, bv.header.ID)
+	if err != nil {
+		return err
+	}
+	trxs := make(types.Transactions, 0, len(txCIDs))
+	txIDs := make([]int64, len(txCIDs))
+	for i, txCID := range txCIDs {
+		txIDs[i] = txCID.ID
+		data, ok, err := bv.fetch(txCID.CID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		trx := new(types.Transaction)
+		if err := rlp.DecodeBytes(data, trx); err != nil {
+			bv.fault(txCID.CID, fmt.Sprintf("unable to decode transaction: %v", err))
+			continue
+		}
+		trxs = append(trxs, trx)
+	}
+	if len(trxs) == len(txCIDs) {
+		bv.checkRoot("tx_root", "the root derived from the transactions", types.DeriveSha(trxs), bv.header.TxRoot)
+	}
+
+	rctCIDs, err := v.retriever.RetrieveReceiptCIDsByTxIDs(bv.tx, txIDs)
+	if err != nil {
+		return err
+	}
+	if len(rctCIDs) != len(txCIDs) {
+		bv.fault("", fmt.Sprintf("%d receipts are indexed for %d transactions", len(rctCIDs), len(txCIDs)))
+	}
+	rcts := make(types.Receipts, 0, len(rctCIDs))
+	for _, rctCID := range rctCIDs {
+		data, ok, err := bv.fetch(rctCID.CID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		rct := new(types.Receipt)
+		if err := rlp.DecodeBytes(data, rct); err != nil {
+			bv.fault(rctCID.CID, fmt.Sprintf("unable to decode receipt: %v", err))
+			continue
+		}
+		rcts = append(rcts, rct)
+	}
+	if len(rcts) == len(txCIDs) {
+		bv.checkRoot("receipt_root", "the root derived from the receipts", types.DeriveSha(rcts), bv.header.RctRoot)
+	}
+	return nil
+}
+
+// verifyStateAndStorage checks the state and storage IPLDs, and that the root node of the state trie, if it was indexed, is the one the state root refers to
+func (v *Verifier) verifyStateAndStorage(bv *blockVerification) error {
+	stateNodes := make([]StateNodeModel, 0)
+	pgStr := `SELECT id, header_id, state_path, state_leaf_key, node_type, cid FROM eth.state_cids
+			WHERE header_id = $1`
+	if err := bv.tx.Select(&stateNodes, pgStr, bv.header.ID); err != nil {
+		return err
+	}
+	for _, stateNode := range stateNodes {
+		_, ok, err := bv.fetch(stateNode.CID)
+		if err != nil {
+			return err
+		}
+		if ok && len(stateNode.Path) == 0 {
+			digest, err := cidDigest(stateNode.CID)
+			if err != nil {
+				return err
+			}
+			bv.checkRoot("state_root", "the hash of the root state node", digest, bv.header.StateRoot)
+		}
+	}
+	storageCIDs := make([]string, 0)
+	pgStr = `SELECT storage_cids.cid FROM eth.storage_cids
+			INNER JOIN eth.state_cids ON (storage_cids.state_id = state_cids.id)
+			WHERE state_cids.header_id = $1`
+	if err := bv.tx.Select(&storageCIDs, pgStr, bv.header.ID); err != nil {
+		return err
+	}
+	for _, storageCID := range storageCIDs {
+		if _, _, err := bv.fetch(storageCID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// blockVerification collects the faults found while verifying the IPLDs of a single header
+type blockVerification struct {
+	tx          *sqlx.Tx
+	blockNumber uint64
+	header      HeaderModel
+	faults      []shared.Fault
+}
+
+func (bv *blockVerification) fault(c, reason string) {
+	bv.faults = append(bv.faults, shared.Fault{
+		BlockNumber: bv.blockNumber,
+		BlockHash:   bv.header.BlockHash,
+		CID:         c,
+		Reason:      reason,
+	})
+}
+
+// fetch retrieves an IPLD from public.blocks and verifies it against its CID, the data is only returned if it is intact
+func (bv *blockVerification) fetch(c string) ([]byte, bool, error) {
+	data, err := shared.FetchIPLD(bv.tx, c)
+	if err == sql.ErrNoRows {
+		bv.fault(c, "IPLD is missing from public.blocks")
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if err := shared.VerifyIPLD(c, data); err != nil {
+		bv.fault(c, err.Error())
+		return nil, false, nil
+	}
+	return data, true, nil
+}
+
+// verifyHeader checks the header IPLD, and that it hashes to the indexed block hash and holds the indexed roots
+func (bv *blockVerification) verifyHeader() error {
+	data, ok, err := bv.fetch(bv.header.CID)
+	if err != nil || !ok {
+		return err
+	}
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(data, header); err != nil {
+		bv.fault(bv.header.CID, fmt.Sprintf("unable to decode header: %v", err))
+		return nil
+	}
+	if header.Hash().String() != bv.header.BlockHash {
+		bv.fault(bv.header.CID, fmt.Sprintf("header IPLD hashes to block %s", header.Hash().String()))
+	}
+	bv.checkRoot("tx_root", "the root in the header IPLD", header.TxHash, bv.header.TxRoot)
+	bv.checkRoot("receipt_root", "the root in the header IPLD", header.ReceiptHash, bv.header.RctRoot)
+	bv.checkRoot("state_root", "the root in the header IPLD", header.Root, bv.header.StateRoot)
+	return nil
+}
+
+// checkRoot records a fault if the root derived from the stored IPLDs does not match the root in the header row
+func (bv *blockVerification) checkRoot(column, source string, derived common.Hash, indexed string) {
+	if derived != common.HexToHash(indexed) {
+		bv.fault("", fmt.Sprintf("%s %s does not match %s %s", column, indexed, source, derived.String()))
+	}
+}
+
+// cidDigest returns the digest of the multihash of the cid, for eth IPLDs this is the keccak256 hash that refers to them
+func cidDigest(c string) (common.Hash, error) {
+	dc, err := cid.Decode(c)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	decoded, err := multihash.Decode(dc.Hash())
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return common.BytesToHash(decoded.Digest), nil
+}
@@ -0,0 +1,90 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/vulcanizedb/pkg/postgres"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth/mocks"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+var _ = Describe("Verifier", func() {
+	var (
+		db       *postgres.DB
+		err      error
+		verifier *eth.Verifier
+	)
+	BeforeEach(func() {
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = eth.NewIPLDPublisherAndIndexer(db).Publish(mocks.MockConvertedPayload)
+		Expect(err).ToNot(HaveOccurred())
+		verifier = eth.NewVerifier(db)
+	})
+	AfterEach(func() {
+		eth.TearDownDB(db)
+	})
+
+	It("Finds no faults in an intact block", func() {
+		faults, err := verifier.Verify(mocks.BlockNumber.Uint64())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(faults)).To(Equal(0))
+	})
+
+	It("Finds no faults at a height with nothing indexed", func() {
+		faults, err := verifier.Verify(mocks.BlockNumber.Uint64() + 1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(faults)).To(Equal(0))
+	})
+
+	It("Reports IPLDs whose data does not match their cid", func() {
+		_, err = db.Exec(`UPDATE public.blocks SET data = $1 WHERE key = $2`, []byte("corrupted"), shared.BlockKey(mocks.Trx2CID))
+		Expect(err).ToNot(HaveOccurred())
+		faults, err := verifier.Verify(mocks.BlockNumber.Uint64())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(faults)).To(Equal(1))
+		Expect(faults[0].BlockNumber).To(Equal(mocks.BlockNumber.Uint64()))
+		Expect(faults[0].BlockHash).To(Equal(mocks.MockBlock.Hash().String()))
+		Expect(faults[0].CID).To(Equal(mocks.Trx2CID.String()))
+	})
+
+	It("Reports IPLDs missing from public.blocks", func() {
+		_, err = db.Exec(`DELETE FROM public.blocks WHERE key = $1`, shared.BlockKey(mocks.StorageCID))
+		Expect(err).ToNot(HaveOccurred())
+		faults, err := verifier.Verify(mocks.BlockNumber.Uint64())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(faults)).To(Equal(1))
+		Expect(faults[0].CID).To(Equal(mocks.StorageCID.String()))
+		Expect(faults[0].Reason).To(ContainSubstring("missing"))
+	})
+
+	It("Reports header roots that do not match the roots derived from the stored IPLDs", func() {
+		_, err = db.Exec(`UPDATE eth.header_cids SET tx_root = $1`, mocks.MockBlock.ReceiptHash().String())
+		Expect(err).ToNot(HaveOccurred())
+		faults, err := verifier.Verify(mocks.BlockNumber.Uint64())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(faults)).To(Equal(2))
+		for _, fault := range faults {
+			Expect(fault.CID).To(BeEmpty())
+			Expect(fault.Reason).To(ContainSubstring("tx_root"))
+		}
+	})
+})
@@ -0,0 +1,259 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package super_node
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth"
+)
+
+// PublicEthPubSubAPI serves the standard eth_subscribe "newHeads" and "logs" subscriptions from the super node's Serve loop
+// It is registered under the eth namespace alongside the eth.PublicEthAPI
+type PublicEthPubSubAPI struct {
+	sn SuperNode
+}
+
+// NewPublicEthPubSubAPI creates a new PublicEthPubSubAPI with the provided underlying super node
+func NewPublicEthPubSubAPI(superNodeInterface SuperNode) *PublicEthPubSubAPI {
+	return &PublicEthPubSubAPI{
+		sn: superNodeInterface,
+	}
+}
+
+// NewHeads sends a notification each time a new header is served by the super node
+func (api *PublicEthPubSubAPI) NewHeads(ctx context.Context) (*rpc.Subscription, error) {
+	return subscribe(ctx, api.sn, newHeadsSettings(), func(notifier *rpc.Notifier, id rpc.ID, packet SubscriptionPayload) error {
+		header, err := newHead(packet)
+		if err != nil {
+			log.Errorf("super node eth newHeads subscription %s error: %v", id, err)
+			return nil
+		}
+		if header == nil {
+			return nil
+		}
+		return notifier.Notify(id, header)
+	})
+}
+
+// Logs sends a notification for each new log served by the super node that matches the given filter criteria
+// When logs previously sent are invalidated by a reorg they are sent again with removed set to true
+func (api *PublicEthPubSubAPI) Logs(ctx context.Context, crit filters.FilterCriteria) (*rpc.Subscription, error) {
+	feed := newLogsFeed(crit)
+	return subscribe(ctx, api.sn, logsSettings(), func(notifier *rpc.Notifier, id rpc.ID, packet SubscriptionPayload) error {
+		logs, err := feed.logs(packet)
+		if err != nil {
+			log.Errorf("super node eth logs subscription %s error: %v", id, err)
+			return nil
+		}
+		for _, l := range logs {
+			if err := notifier.Notify(id, l); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// newHeadsSettings returns the subscription settings the newHeads subscription is served with; only headers are screened
+func newHeadsSettings() *eth.SubscriptionSettings {
+	return &eth.SubscriptionSettings{
+		Start:         big.NewInt(0),
+		End:           big.NewInt(0),
+		TxFilter:      eth.TxFilter{Off: true},
+		ReceiptFilter: eth.ReceiptFilter{Off: true},
+		StateFilter:   eth.StateFilter{Off: true},
+		StorageFilter: eth.StorageFilter{Off: true},
+	}
+}
+
+// logsSettings returns the subscription settings the logs subscriptions are served with
+// Every transaction and receipt is screened so that the location of each log in the block is known, the log filter criteria
+// are applied to the screened receipts; this way all logs subscriptions share a single subscription type
+func logsSettings() *eth.SubscriptionSettings {
+	return &eth.SubscriptionSettings{
+		Start:         big.NewInt(0),
+		End:           big.NewInt(0),
+		StateFilter:   eth.StateFilter{Off: true},
+		StorageFilter: eth.StorageFilter{Off: true},
+	}
+}
+
+// newHead decodes the header served in the payload, nil is returned for payloads without data
+func newHead(packet SubscriptionPayload) (*types.Header, error) {
+	if packet.Err != "" {
+		return nil, packet.Error()
+	}
+	if len(packet.Data) == 0 {
+		return nil, nil
+	}
+	var iplds eth.IPLDs
+	if err := rlp.DecodeBytes(packet.Data, &iplds); err != nil {
+		return nil, err
+	}
+	if len(iplds.Header.Data) == 0 {
+		return nil, nil
+	}
+	header := new(types.Header)
+	return header, rlp.DecodeBytes(iplds.Header.Data, header)
+}
+
+// logsFeed turns the payloads served to a logs subscription into the logs it is notified of
+type logsFeed struct {
+	crit filters.FilterCriteria
+	// logs sent for each recent height, so that they can be sent again as removed if their height is invalidated
+	sent map[int64][]*types.Log
+}
+
+func newLogsFeed(crit filters.FilterCriteria) *logsFeed {
+	return &logsFeed{
+		crit: crit,
+		sent: make(map[int64][]*types.Log),
+	}
+}
+
+// logs returns the logs to notify the subscription of for the payload
+// For data payloads these are the logs matching the filter criteria, for reorg payloads they are
+// the logs previously sent at the invalidated heights, with removed set to true
+func (lf *logsFeed) logs(packet SubscriptionPayload) ([]*types.Log, error) {
+	if packet.Err != "" {
+		return nil, packet.Error()
+	}
+	if packet.Reorg() {
+		return lf.removed(packet.InvalidatedHeights), nil
+	}
+	if len(packet.Data) == 0 {
+		return nil, nil
+	}
+	var iplds eth.IPLDs
+	if err := rlp.DecodeBytes(packet.Data, &iplds); err != nil {
+		return nil, err
+	}
+	blockLogs, err := logsFromIPLDs(iplds)
+	if err != nil {
+		return nil, err
+	}
+	matched := filterLogs(blockLogs, lf.crit.Addresses, lf.crit.Topics)
+	height := iplds.BlockNumber.Int64()
+	if len(matched) > 0 {
+		lf.sent[height] = append(lf.sent[height], matched...)
+	}
+	for h := range lf.sent {
+		if h <= height-ReorgTrackingDepth {
+			delete(lf.sent, h)
+		}
+	}
+	return matched, nil
+}
+
+// removed returns copies of the logs sent at the invalidated heights with removed set to true, in descending height order
+func (lf *logsFeed) removed(invalidated []int64) []*types.Log {
+	heights := append([]int64{}, invalidated...)
+	sort.Slice(heights, func(i, j int) bool { return heights[i] > heights[j] })
+	removed := make([]*types.Log, 0)
+	for _, height := range heights {
+		for _, l := range lf.sent[height] {
+			rl := *l
+			rl.Removed = true
+			removed = append(removed, &rl)
+		}
+		delete(lf.sent, height)
+	}
+	return removed
+}
+
+// logsFromIPLDs decodes the logs in the payload's receipts, and fills in their location in the block
+func logsFromIPLDs(iplds eth.IPLDs) ([]*types.Log, error) {
+	var header types.Header
+	if err := rlp.DecodeBytes(iplds.Header.Data, &header); err != nil {
+		return nil, err
+	}
+	if len(iplds.Transactions) != len(iplds.Receipts) {
+		return nil, fmt.Errorf("payload at height %d has %d transactions but %d receipts", iplds.BlockNumber.Int64(), len(iplds.Transactions), len(iplds.Receipts))
+	}
+	blockHash := header.Hash()
+	logs := make([]*types.Log, 0)
+	var logIndex uint
+	for i, rctIPLD := range iplds.Receipts {
+		var tx types.Transaction
+		if err := rlp.DecodeBytes(iplds.Transactions[i].Data, &tx); err != nil {
+			return nil, err
+		}
+		var receipt types.Receipt
+		if err := rlp.DecodeBytes(rctIPLD.Data, &receipt); err != nil {
+			return nil, err
+		}
+		for _, l := range receipt.Logs {
+			l.BlockNumber = header.Number.Uint64()
+			l.BlockHash = blockHash
+			l.TxHash = tx.Hash()
+			l.TxIndex = uint(i)
+			l.Index = logIndex
+			logIndex++
+			logs = append(logs, l)
+		}
+	}
+	return logs, nil
+}
+
+// filterLogs returns the logs which match the addresses and topics, following the eth_getLogs matching rules
+func filterLogs(logs []*types.Log, addresses []common.Address, topics [][]common.Hash) []*types.Log {
+	matched := make([]*types.Log, 0)
+Logs:
+	for _, l := range logs {
+		if len(addresses) > 0 && !includesAddress(addresses, l.Address) {
+			continue
+		}
+		// If the to filtered topics is greater than the amount of topics in logs, skip.
+		if len(topics) > len(l.Topics) {
+			continue
+		}
+		for i, sub := range topics {
+			match := len(sub) == 0 // empty rule set == wildcard
+			for _, topic := range sub {
+				if l.Topics[i] == topic {
+					match = true
+					break
+				}
+			}
+			if !match {
+				continue Logs
+			}
+		}
+		matched = append(matched, l)
+	}
+	return matched
+}
+
+func includesAddress(addresses []common.Address, a common.Address) bool {
+	for _, addr := range addresses {
+		if addr == a {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package super_node_test
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/vulcanizedb/pkg/super_node"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth/mocks"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+	mocks2 "github.com/vulcanize/vulcanizedb/pkg/super_node/shared/mocks"
+)
+
+// passThroughConverter returns the raw payloads it is given, which are already converted
+type passThroughConverter struct{}
+
+func (c passThroughConverter) Convert(payload shared.RawChainData) (shared.ConvertedData, error) {
+	return payload.(eth.ConvertedPayload), nil
+}
+
+var _ = Describe("PublicEthPubSubAPI", func() {
+	var (
+		service     *super_node.Service
+		wg          *sync.WaitGroup
+		client      *rpc.Client
+		payloadChan chan shared.RawChainData
+		// a block competing with mocks.MockBlock at the same height, with the same transactions and receipts
+		competingPayload eth.ConvertedPayload
+	)
+	BeforeEach(func() {
+		sn, err := super_node.NewSuperNode(&super_node.Config{Chain: shared.Ethereum, Workers: 1})
+		Expect(err).ToNot(HaveOccurred())
+		service = sn.(*super_node.Service)
+		payloadChan = make(chan shared.RawChainData, 10)
+		service.PayloadChan = payloadChan
+		service.Streamer = &mocks2.PayloadStreamer{ReturnSub: &rpc.ClientSubscription{}}
+		service.Converter = passThroughConverter{}
+		service.Publisher = &mocks.IPLDPublisher{ReturnCIDPayload: mocks.MockCIDPayload}
+		service.Indexer = &mocks.CIDIndexer{}
+		service.Filterer = eth.NewResponseFilterer()
+		serveChan := make(chan shared.ConvertedData, super_node.PayloadChanBufferSize)
+		wg = new(sync.WaitGroup)
+		service.Serve(wg, serveChan)
+		Expect(service.Sync(wg, serveChan)).ToNot(HaveOccurred())
+
+		server := rpc.NewServer()
+		Expect(server.RegisterName(eth.APIName, super_node.NewPublicEthPubSubAPI(service))).ToNot(HaveOccurred())
+		client = rpc.DialInProc(server)
+
+		header := mocks.MockBlock.Header()
+		header.Extra = []byte("competing")
+		competingPayload = mocks.MockConvertedPayload
+		competingPayload.Block = types.NewBlock(header, mocks.MockTransactions, nil, mocks.MockReceipts)
+	})
+	AfterEach(func() {
+		client.Close()
+		close(service.QuitChan)
+		wg.Wait()
+	})
+
+	waitForSubscription := func() {
+		Eventually(func() int { return len(service.ActiveSubscriptions()) }, time.Second).Should(Equal(1))
+	}
+
+	Describe("NewHeads", func() {
+		It("Sends the headers served by the super node", func() {
+			headers := make(chan *types.Header, 10)
+			sub, err := client.EthSubscribe(context.Background(), headers, "newHeads")
+			Expect(err).ToNot(HaveOccurred())
+			defer sub.Unsubscribe()
+			waitForSubscription()
+
+			payloadChan <- mocks.MockConvertedPayload
+			var header *types.Header
+			Eventually(headers, time.Second).Should(Receive(&header))
+			Expect(header.Hash()).To(Equal(mocks.MockBlock.Hash()))
+		})
+	})
+
+	Describe("Logs", func() {
+		It("Sends the logs matching the filter criteria, and sends them again as removed when their block is reorged out", func() {
+			logs := make(chan types.Log, 10)
+			crit := map[string]interface{}{
+				"address": mocks.Address,
+			}
+			sub, err := client.EthSubscribe(context.Background(), logs, "logs", crit)
+			Expect(err).ToNot(HaveOccurred())
+			defer sub.Unsubscribe()
+			waitForSubscription()
+
+			payloadChan <- mocks.MockConvertedPayload
+			var l types.Log
+			Eventually(logs, time.Second).Should(Receive(&l))
+			Expect(l.Address).To(Equal(mocks.Address))
+			Expect(l.Topics).To(Equal(mocks.MockLog1.Topics))
+			Expect(l.BlockHash).To(Equal(mocks.MockBlock.Hash()))
+			Expect(l.BlockNumber).To(Equal(mocks.BlockNumber.Uint64()))
+			Expect(l.TxHash).To(Equal(mocks.MockTransactions[0].Hash()))
+			Expect(l.TxIndex).To(Equal(uint(0)))
+			Expect(l.Index).To(Equal(uint(0)))
+			Expect(l.Removed).To(BeFalse())
+
+			payloadChan <- competingPayload
+			Eventually(logs, time.Second).Should(Receive(&l))
+			Expect(l.BlockHash).To(Equal(mocks.MockBlock.Hash()))
+			Expect(l.Removed).To(BeTrue())
+			Eventually(logs, time.Second).Should(Receive(&l))
+			Expect(l.BlockHash).To(Equal(competingPayload.Block.Hash()))
+			Expect(l.Removed).To(BeFalse())
+			Consistently(logs).ShouldNot(Receive())
+		})
+
+		It("Filters logs on their topics", func() {
+			logs := make(chan types.Log, 10)
+			crit := map[string]interface{}{
+				"topics": [][]string{{}, {mocks.MockLog2.Topics[1].Hex()}},
+			}
+			sub, err := client.EthSubscribe(context.Background(), logs, "logs", crit)
+			Expect(err).ToNot(HaveOccurred())
+			defer sub.Unsubscribe()
+			waitForSubscription()
+
+			payloadChan <- mocks.MockConvertedPayload
+			var l types.Log
+			Eventually(logs, time.Second).Should(Receive(&l))
+			Expect(l.Address).To(Equal(mocks.AnotherAddress))
+			Expect(l.TxHash).To(Equal(mocks.MockTransactions[1].Hash()))
+			Expect(l.TxIndex).To(Equal(uint(1)))
+			Expect(l.Index).To(Equal(uint(1)))
+			Consistently(logs).ShouldNot(Receive())
+		})
+	})
+})
@@ -0,0 +1,114 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package super_node
+
+import (
+	"time"
+
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/btc"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+const (
+	// HandoffBufferSize is the number of live payloads buffered for a subscription while it is sent historical data
+	HandoffBufferSize = 1000
+	// HandoffRetryInterval is how long the historical data process waits for the index to catch up to the live feed
+	HandoffRetryInterval = time.Second
+	// HandoffRetryLimit is how many times the historical data process waits before handing off to the live feed regardless
+	HandoffRetryLimit = 60
+)
+
+// handoff buffers the live payloads for a subscription while historical data is being sent to it
+// so that the subscription can be handed off from historical to live data without duplicates or gaps
+// it needs to be accessed with subscription access locked
+type handoff struct {
+	payloads []SubscriptionPayload
+}
+
+// add buffers a live payload
+// if the buffer is full the oldest payload is dropped, the historical data process will retrieve it from the index instead
+func (h *handoff) add(payload SubscriptionPayload) {
+	if len(h.payloads) >= HandoffBufferSize {
+		h.payloads = h.payloads[1:]
+	}
+	h.payloads = append(h.payloads, payload)
+}
+
+// ready returns whether or not the buffered live payloads continue on from the provided height without a gap
+// that is, whether the data payloads buffered at or above the height cover a contiguous run of heights starting at it
+// payloads buffered below the height, such as backfilled data forwarded to the live feed, do not count towards this
+// if no such payloads have been buffered, we are ready once we have reached the head the Sync process has streamed
+func (h *handoff) ready(next, head int64) bool {
+	heights := make(map[int64]bool)
+	highest := next
+	for _, payload := range h.payloads {
+		if !isDataPayload(payload) || payload.Height < next {
+			continue
+		}
+		heights[payload.Height] = true
+		if payload.Height > highest {
+			highest = payload.Height
+		}
+	}
+	if len(heights) == 0 {
+		return next > head
+	}
+	for height := next; height <= highest; height++ {
+		if !heights[height] {
+			return false
+		}
+	}
+	return true
+}
+
+// flush returns the buffered live payloads that have not been superseded by historical data below the provided height
+// data payloads below this height have already been sent from the index, unless they replace data invalidated by a reorg
+// flagged payloads are always returned
+func (h *handoff) flush(next int64) []SubscriptionPayload {
+	payloads := make([]SubscriptionPayload, 0, len(h.payloads))
+	invalidated := make(map[int64]bool)
+	for _, payload := range h.payloads {
+		if payload.Reorg() {
+			for _, height := range payload.InvalidatedHeights {
+				invalidated[height] = true
+			}
+		}
+		if isDataPayload(payload) && payload.Height < next && !invalidated[payload.Height] {
+			continue
+		}
+		payloads = append(payloads, payload)
+	}
+	h.payloads = nil
+	return payloads
+}
+
+func isDataPayload(payload SubscriptionPayload) bool {
+	return payload.Flag == EmptyFlag && payload.Err == ""
+}
+
+// cidsBlockHash returns the hash of the block the retrieved CIDs belong to
+func cidsBlockHash(cids shared.CIDsForFetching) string {
+	switch c := cids.(type) {
+	case *eth.CIDWrapper:
+		return c.BlockHash
+	case *btc.CIDWrapper:
+		return c.BlockHash
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,112 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package super_node
+
+import (
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+func dataPayload(height int64) SubscriptionPayload {
+	return SubscriptionPayload{Flag: EmptyFlag, Height: height}
+}
+
+func reorgPayload(height int64, invalidated ...int64) SubscriptionPayload {
+	return SubscriptionPayload{Flag: ReorgFlag, Height: height, InvalidatedHeights: invalidated}
+}
+
+func bufferedHandoff(payloads ...SubscriptionPayload) *handoff {
+	h := new(handoff)
+	for _, payload := range payloads {
+		h.add(payload)
+	}
+	return h
+}
+
+func heightsOf(payloads []SubscriptionPayload) []int64 {
+	heights := make([]int64, 0, len(payloads))
+	for _, payload := range payloads {
+		heights = append(heights, payload.Height)
+	}
+	return heights
+}
+
+var _ = Describe("handoff", func() {
+	table.DescribeTable("ready",
+		func(h *handoff, next, head int64, expected bool) {
+			Expect(h.ready(next, head)).To(Equal(expected))
+		},
+		table.Entry("nothing buffered, below the head", bufferedHandoff(), int64(5), int64(10), false),
+		table.Entry("nothing buffered, past the head", bufferedHandoff(), int64(11), int64(10), true),
+		table.Entry("live payloads continue on from next", bufferedHandoff(dataPayload(5), dataPayload(6)), int64(5), int64(6), true),
+		table.Entry("live payloads overlap the historical data", bufferedHandoff(dataPayload(4), dataPayload(5), dataPayload(6)), int64(5), int64(6), true),
+		table.Entry("live payloads start above next", bufferedHandoff(dataPayload(7), dataPayload(8)), int64(5), int64(8), false),
+		table.Entry("live payloads have a gap", bufferedHandoff(dataPayload(5), dataPayload(7)), int64(5), int64(7), false),
+		table.Entry("backfilled payloads below next interleaved with live payloads above it",
+			bufferedHandoff(dataPayload(8), dataPayload(2), dataPayload(9), dataPayload(3)), int64(5), int64(9), false),
+		table.Entry("backfilled payloads below next interleaved with live payloads from it",
+			bufferedHandoff(dataPayload(5), dataPayload(2), dataPayload(6), dataPayload(3)), int64(5), int64(6), true),
+		table.Entry("only backfilled payloads below next, below the head", bufferedHandoff(dataPayload(2), dataPayload(3)), int64(5), int64(10), false),
+		table.Entry("only backfilled payloads below next, past the head", bufferedHandoff(dataPayload(2), dataPayload(3)), int64(11), int64(10), true),
+		table.Entry("live payloads arrive out of order", bufferedHandoff(dataPayload(6), dataPayload(5), dataPayload(7)), int64(5), int64(7), true),
+		table.Entry("flagged payloads do not count as data", bufferedHandoff(reorgPayload(5, 5), SubscriptionPayload{Err: "err", Height: 5}), int64(5), int64(10), false),
+		table.Entry("live payloads re-sent after a reorg continue on from next",
+			bufferedHandoff(dataPayload(5), dataPayload(6), reorgPayload(5, 5, 6), dataPayload(5), dataPayload(6)), int64(5), int64(6), true),
+		table.Entry("buffer overflowed past next", overflowedHandoff(5), int64(5), int64(5+HandoffBufferSize), false),
+		table.Entry("buffer overflowed below next", overflowedHandoff(5), int64(6), int64(5+HandoffBufferSize), true),
+	)
+
+	table.DescribeTable("flush",
+		func(h *handoff, next int64, expected []int64) {
+			Expect(heightsOf(h.flush(next))).To(Equal(expected))
+			Expect(h.payloads).To(BeEmpty())
+		},
+		table.Entry("nothing buffered", bufferedHandoff(), int64(5), []int64{}),
+		table.Entry("live payloads from next", bufferedHandoff(dataPayload(5), dataPayload(6)), int64(5), []int64{5, 6}),
+		table.Entry("live payloads already sent as historical data are dropped",
+			bufferedHandoff(dataPayload(3), dataPayload(4), dataPayload(5)), int64(5), []int64{5}),
+		table.Entry("backfilled payloads below next interleaved with live payloads are dropped",
+			bufferedHandoff(dataPayload(5), dataPayload(2), dataPayload(6), dataPayload(3)), int64(5), []int64{5, 6}),
+		table.Entry("everything is flushed from height zero",
+			bufferedHandoff(dataPayload(5), dataPayload(2), dataPayload(6)), int64(0), []int64{5, 2, 6}),
+		table.Entry("flagged payloads are kept regardless of their height",
+			bufferedHandoff(SubscriptionPayload{Err: "err", Height: 2}, dataPayload(5)), int64(5), []int64{2, 5}),
+		table.Entry("payloads replacing heights invalidated by a reorg are kept",
+			bufferedHandoff(dataPayload(4), reorgPayload(3, 3, 4), dataPayload(3), dataPayload(4), dataPayload(5)), int64(5), []int64{3, 3, 4, 5}),
+		table.Entry("payloads below next that a reorg did not invalidate are dropped",
+			bufferedHandoff(dataPayload(2), reorgPayload(4, 4), dataPayload(2), dataPayload(4), dataPayload(5)), int64(5), []int64{4, 4, 5}),
+		table.Entry("buffer overflowed", overflowedHandoff(5), int64(6), heightsFrom(6, HandoffBufferSize)),
+	)
+})
+
+// overflowedHandoff buffers one more live payload than fits, starting at the provided height, so that the payload at that height is dropped
+func overflowedHandoff(from int64) *handoff {
+	h := new(handoff)
+	for height := from; height <= from+HandoffBufferSize; height++ {
+		h.add(dataPayload(height))
+	}
+	return h
+}
+
+func heightsFrom(from int64, count int) []int64 {
+	heights := make([]int64, 0, count)
+	for i := 0; i < count; i++ {
+		heights = append(heights, from+int64(i))
+	}
+	return heights
+}
@@ -0,0 +1,90 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package super_node
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+// HealthStatus reports whether a super node instance is keeping up with its chain
+type HealthStatus struct {
+	Chain   string `json:"chain"`
+	Healthy bool   `json:"healthy"`
+	Syncing bool   `json:"syncing"` // whether the Sync process is running
+	Head    int64  `json:"head"`    // height of the latest payload streamed by the Sync process
+	Indexed int64  `json:"indexed"` // height of the latest payload indexed by the publishAndIndex workers
+	// Time the Sync process last received a payload, nil if it has not received one
+	LastPayload *time.Time `json:"lastPayload,omitempty"`
+}
+
+// StaleAfter returns how long a chain can go without a new payload before its super node is reported unhealthy
+func StaleAfter(chain shared.ChainType) time.Duration {
+	switch chain {
+	case shared.Bitcoin, shared.Omni:
+		return time.Hour * 2
+	default:
+		return time.Minute * 5
+	}
+}
+
+// Health returns the health of this service
+// A service that is not syncing is healthy, a syncing one is healthy as long as it has received a payload,
+// or was started, within StaleAfter
+func (sap *Service) Health() HealthStatus {
+	status := HealthStatus{
+		Chain:   sap.chain.String(),
+		Healthy: true,
+		Syncing: atomic.LoadInt32(&sap.syncing) == 1,
+		Head:    atomic.LoadInt64(&sap.head),
+		Indexed: atomic.LoadInt64(&sap.indexed),
+	}
+	if last := atomic.LoadInt64(&sap.lastPayload); last != 0 {
+		lastPayload := time.Unix(0, last)
+		status.LastPayload = &lastPayload
+	}
+	if status.Syncing {
+		last := atomic.LoadInt64(&sap.syncStarted)
+		if status.LastPayload != nil && status.LastPayload.UnixNano() > last {
+			last = status.LastPayload.UnixNano()
+		}
+		status.Healthy = time.Since(time.Unix(0, last)) < StaleAfter(sap.chain)
+	}
+	return status
+}
+
+// HealthHandler serves the health of the provided super nodes as a json array
+// It responds with 503 Service Unavailable if any of them is unhealthy
+func HealthHandler(superNodes ...SuperNode) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		statuses := make([]HealthStatus, len(superNodes))
+		code := http.StatusOK
+		for i, sn := range superNodes {
+			statuses[i] = sn.Health()
+			if !statuses[i].Healthy {
+				code = http.StatusServiceUnavailable
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(statuses)
+	})
+}
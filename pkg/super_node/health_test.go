@@ -0,0 +1,108 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package super_node_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/vulcanizedb/pkg/super_node"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth/mocks"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+	mocks2 "github.com/vulcanize/vulcanizedb/pkg/super_node/shared/mocks"
+)
+
+var _ = Describe("Health", func() {
+	It("Reports a service that is not syncing as healthy", func() {
+		sn, err := super_node.NewSuperNode(&super_node.Config{Chain: shared.Bitcoin, Workers: 1})
+		Expect(err).ToNot(HaveOccurred())
+		status := sn.Health()
+		Expect(status.Chain).To(Equal(shared.Bitcoin.String()))
+		Expect(status.Healthy).To(BeTrue())
+		Expect(status.Syncing).To(BeFalse())
+		Expect(status.LastPayload).To(BeNil())
+	})
+
+	It("Reports the heights and latest payload time of a syncing service", func() {
+		wg := new(sync.WaitGroup)
+		quitChan := make(chan bool)
+		processor := &super_node.Service{
+			Indexer:   &mocks.CIDIndexer{},
+			Publisher: &mocks.IPLDPublisher{ReturnCIDPayload: mocks.MockCIDPayload},
+			Streamer: &mocks2.PayloadStreamer{
+				ReturnSub:      &rpc.ClientSubscription{},
+				StreamPayloads: []shared.RawChainData{mocks.MockStateDiffPayload},
+			},
+			Converter:      &mocks.PayloadConverter{ReturnIPLDPayload: mocks.MockConvertedPayload},
+			PayloadChan:    make(chan shared.RawChainData, 1),
+			QuitChan:       quitChan,
+			WorkerPoolSize: 1,
+		}
+		before := time.Now()
+		Expect(processor.Sync(wg, nil)).To(Succeed())
+		Eventually(func() int64 { return processor.Health().Indexed }).Should(Equal(mocks.MockConvertedPayload.Height()))
+		status := processor.Health()
+		Expect(status.Healthy).To(BeTrue())
+		Expect(status.Syncing).To(BeTrue())
+		Expect(status.Head).To(Equal(mocks.MockConvertedPayload.Height()))
+		Expect(status.LastPayload).ToNot(BeNil())
+		Expect(status.LastPayload.Before(before)).To(BeFalse())
+		close(quitChan)
+		Eventually(func() bool { return processor.Health().Syncing }).Should(BeFalse())
+	})
+
+	It("Serves the health of each super node", func() {
+		ethNode, err := super_node.NewSuperNode(&super_node.Config{Chain: shared.Ethereum, Workers: 1})
+		Expect(err).ToNot(HaveOccurred())
+		btcNode, err := super_node.NewSuperNode(&super_node.Config{Chain: shared.Bitcoin, Workers: 1})
+		Expect(err).ToNot(HaveOccurred())
+		rec := httptest.NewRecorder()
+		super_node.HealthHandler(ethNode, btcNode).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		var statuses []super_node.HealthStatus
+		Expect(json.Unmarshal(rec.Body.Bytes(), &statuses)).To(Succeed())
+		Expect(len(statuses)).To(Equal(2))
+		Expect(statuses[0].Chain).To(Equal(shared.Ethereum.String()))
+		Expect(statuses[1].Chain).To(Equal(shared.Bitcoin.String()))
+	})
+})
+
+var _ = Describe("Chain namespaces", func() {
+	It("Appends the chain to the namespace", func() {
+		Expect(super_node.ChainNamespace("vdb", shared.Ethereum)).To(Equal("vdbEth"))
+		Expect(super_node.ChainNamespace("admin", shared.Bitcoin)).To(Equal("adminBtc"))
+	})
+
+	It("Registers the vdb and admin APIs under chain namespaces when configured to", func() {
+		sn, err := super_node.NewSuperNode(&super_node.Config{Chain: shared.Bitcoin, Workers: 1, ChainNamespaces: true})
+		Expect(err).ToNot(HaveOccurred())
+		namespaces := make(map[string]bool)
+		for _, api := range sn.APIs() {
+			namespaces[api.Namespace] = true
+		}
+		Expect(namespaces["vdbBtc"]).To(BeTrue())
+		Expect(namespaces["adminBtc"]).To(BeTrue())
+		Expect(namespaces[super_node.APIName]).To(BeFalse())
+	})
+})
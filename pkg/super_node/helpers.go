@@ -35,3 +35,8 @@ func sendNonBlockingQuit(sub Subscription) {
 		log.Infof("unable to close subscription %s; channel has no receiver", sub.ID)
 	}
 }
+
+// backFillCompletePayload returns the empty payload that notifies a subscription its historical data has been sent
+func backFillCompletePayload() SubscriptionPayload {
+	return SubscriptionPayload{Data: nil, Err: "", Flag: BackFillCompleteFlag}
+}
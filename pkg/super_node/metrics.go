@@ -0,0 +1,158 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package super_node
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricsNamespace = "vdb"
+	metricsSubsystem = "super_node"
+)
+
+// Processes and stages that are timed
+const (
+	syncProcess     = "sync"
+	backFillProcess = "backfill"
+	convertStage    = "convert"
+	publishStage    = "publish"
+	indexStage      = "index"
+	validateStage   = "validate"
+)
+
+// Buffers that payloads can be dropped from
+const (
+	serveBuffer           = "serve"
+	publishAndIndexBuffer = "publish_and_index"
+	subscriptionBuffer    = "subscription"
+)
+
+var (
+	headHeight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "head_height",
+		Help:      "Height of the latest payload received from the streamer",
+	}, []string{"chain"})
+	indexedHeight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "indexed_height",
+		Help:      "Height of the latest payload indexed by the sync process",
+	}, []string{"chain"})
+	stageDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "stage_duration_seconds",
+		Help:      "Time taken to convert, publish, and index a payload",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 16),
+	}, []string{"chain", "process", "stage"})
+	workers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "workers",
+		Help:      "Number of workers spun up to publish and index payloads",
+	}, []string{"chain", "process"})
+	busyWorkers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "busy_workers",
+		Help:      "Number of workers currently publishing and indexing a payload",
+	}, []string{"chain", "process"})
+	droppedPayloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "dropped_payloads_total",
+		Help:      "Number of payloads dropped because the buffer they were sent to was full",
+	}, []string{"chain", "buffer"})
+	spilledPayloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "spilled_payloads_total",
+		Help:      "Number of payloads spilled to the durable queue because the publishAndIndex workers were behind",
+	}, []string{"chain"})
+	gaps = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "gaps",
+		Help:      "Number of gaps found in the indexed data by the latest backfill pass",
+	}, []string{"chain"})
+	gapBlocks = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "gap_blocks",
+		Help:      "Number of blocks missing from the indexed data as of the latest backfill pass",
+	}, []string{"chain"})
+	backFillBlocks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "backfill_blocks_total",
+		Help:      "Number of blocks fetched and indexed by the backfill process",
+	}, []string{"chain"})
+	backFillBatchDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "backfill_batch_duration_seconds",
+		Help:      "Time taken to fetch, publish, and index a batch of blocks by the backfill process",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 2, 12),
+	}, []string{"chain"})
+	validatedBlocks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "validated_blocks_total",
+		Help:      "Number of blocks fetched and validated against the indexed data by the backfill process",
+	}, []string{"chain"})
+	validationMismatches = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "validation_mismatches_total",
+		Help:      "Number of mismatches between the fetched and indexed data found, and resolved, by the backfill process",
+	}, []string{"chain"})
+	activeSubscriptions = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "active_subscriptions",
+		Help:      "Number of live subscriptions per subscription type",
+	}, []string{"chain", "subscription_type"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		headHeight,
+		indexedHeight,
+		stageDuration,
+		workers,
+		busyWorkers,
+		droppedPayloads,
+		spilledPayloads,
+		gaps,
+		gapBlocks,
+		backFillBlocks,
+		backFillBatchDuration,
+		validatedBlocks,
+		validationMismatches,
+		activeSubscriptions,
+	)
+}
+
+// observeStage records the time taken by a stage of the given process, which began at start
+func observeStage(chain, process, stage string, start time.Time) {
+	stageDuration.WithLabelValues(chain, process, stage).Observe(time.Since(start).Seconds())
+}
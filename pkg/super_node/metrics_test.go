@@ -0,0 +1,215 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package super_node
+
+import (
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth/mocks"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+	mocks2 "github.com/vulcanize/vulcanizedb/pkg/super_node/shared/mocks"
+)
+
+// gathered returns whether a metric with the provided name and labels has been collected, and its number of observations if it is a histogram
+func gathered(name string, labels map[string]string) (bool, uint64) {
+	families, err := prometheus.DefaultGatherer.Gather()
+	Expect(err).ToNot(HaveOccurred())
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if value, ok := labels[label.GetName()]; !ok || value != label.GetValue() {
+					continue metrics
+				}
+			}
+			return true, metric.GetHistogram().GetSampleCount()
+		}
+	}
+	return false, 0
+}
+
+func sampleCount(name string, labels map[string]string) uint64 {
+	_, count := gathered(name, labels)
+	return count
+}
+
+func stageCount(process, stage string) uint64 {
+	return sampleCount("vdb_super_node_stage_duration_seconds", map[string]string{
+		"chain":   shared.Ethereum.String(),
+		"process": process,
+		"stage":   stage,
+	})
+}
+
+// spillQueue is a SpillQueue which only holds the payloads spilled to it
+type spillQueue struct {
+	spilled []shared.RawChainData
+}
+
+func (q *spillQueue) Spill(payload shared.RawChainData, height int64) error {
+	q.spilled = append(q.spilled, payload)
+	return nil
+}
+
+func (q *spillQueue) Pop() (shared.RawChainData, bool, error) {
+	return nil, false, nil
+}
+
+func (q *spillQueue) RecordGap(height int64) error {
+	return nil
+}
+
+var _ = Describe("Metrics", func() {
+	chain := shared.Ethereum.String()
+
+	It("Times the conversion, publishing, and indexing of a synced payload and tracks the heights", func() {
+		converted := stageCount(syncProcess, convertStage)
+		published := stageCount(syncProcess, publishStage)
+		indexed := stageCount(syncProcess, indexStage)
+		droppedServe := testutil.ToFloat64(droppedPayloads.WithLabelValues(chain, serveBuffer))
+
+		quitChan := make(chan bool)
+		service := &Service{
+			Indexer:   &mocks.CIDIndexer{},
+			Publisher: &mocks.IPLDPublisher{ReturnCIDPayload: mocks.MockCIDPayload},
+			Streamer: &mocks2.PayloadStreamer{
+				ReturnSub:      &rpc.ClientSubscription{},
+				StreamPayloads: []shared.RawChainData{mocks.MockStateDiffPayload},
+			},
+			Converter:      &mocks.PayloadConverter{ReturnIPLDPayload: mocks.MockConvertedPayload},
+			PayloadChan:    make(chan shared.RawChainData, 1),
+			QuitChan:       quitChan,
+			WorkerPoolSize: 2,
+			chain:          shared.Ethereum,
+		}
+		wg := new(sync.WaitGroup)
+		// nothing reads from the ScreenAndServe channel, so the payload forwarded to it is dropped
+		err := service.Sync(wg, make(chan shared.ConvertedData))
+		Expect(err).ToNot(HaveOccurred())
+		Eventually(func() uint64 { return stageCount(syncProcess, indexStage) }).Should(Equal(indexed + 1))
+		close(quitChan)
+		wg.Wait()
+
+		Expect(stageCount(syncProcess, convertStage)).To(Equal(converted + 1))
+		Expect(stageCount(syncProcess, publishStage)).To(Equal(published + 1))
+		Expect(testutil.ToFloat64(headHeight.WithLabelValues(chain))).To(Equal(float64(mocks.MockConvertedPayload.Height())))
+		Expect(testutil.ToFloat64(indexedHeight.WithLabelValues(chain))).To(Equal(float64(mocks.MockConvertedPayload.Height())))
+		Expect(testutil.ToFloat64(workers.WithLabelValues(chain, syncProcess))).To(Equal(float64(2)))
+		Expect(testutil.ToFloat64(busyWorkers.WithLabelValues(chain, syncProcess))).To(Equal(float64(0)))
+		Expect(testutil.ToFloat64(droppedPayloads.WithLabelValues(chain, serveBuffer))).To(Equal(droppedServe + 1))
+	})
+
+	It("Counts the payloads spilled to the queue, and those dropped when there is no queue", func() {
+		spilled := testutil.ToFloat64(spilledPayloads.WithLabelValues(chain))
+		dropped := testutil.ToFloat64(droppedPayloads.WithLabelValues(chain, publishAndIndexBuffer))
+		service := &Service{chain: shared.Ethereum}
+		service.spill(mocks.MockStateDiffPayload, 1)
+		Expect(testutil.ToFloat64(droppedPayloads.WithLabelValues(chain, publishAndIndexBuffer))).To(Equal(dropped + 1))
+		Expect(testutil.ToFloat64(spilledPayloads.WithLabelValues(chain))).To(Equal(spilled))
+
+		queue := new(spillQueue)
+		service.SpillQueue = queue
+		service.spill(mocks.MockStateDiffPayload, 2)
+		Expect(len(queue.spilled)).To(Equal(1))
+		Expect(testutil.ToFloat64(spilledPayloads.WithLabelValues(chain))).To(Equal(spilled + 1))
+		Expect(testutil.ToFloat64(droppedPayloads.WithLabelValues(chain, publishAndIndexBuffer))).To(Equal(dropped + 1))
+	})
+
+	It("Counts the blocks backfilled, times the batch, and tracks the gaps", func() {
+		blocks := testutil.ToFloat64(backFillBlocks.WithLabelValues(chain))
+		batches := sampleCount("vdb_super_node_backfill_batch_duration_seconds", map[string]string{"chain": chain})
+		converted := stageCount(backFillProcess, convertStage)
+		indexed := stageCount(backFillProcess, indexStage)
+
+		quitChan := make(chan bool, 1)
+		backFiller := &BackFillService{
+			Indexer:   &mocks.CIDIndexer{},
+			Publisher: &mocks.IterativeIPLDPublisher{ReturnCIDPayload: []*eth.CIDPayload{mocks.MockCIDPayload, mocks.MockCIDPayload}},
+			Converter: &mocks.IterativePayloadConverter{ReturnIPLDPayload: []eth.ConvertedPayload{mocks.MockConvertedPayload, mocks.MockConvertedPayload}},
+			Fetcher: &mocks2.PayloadFetcher{
+				PayloadsToReturn: map[uint64]shared.RawChainData{
+					100: mocks.MockStateDiffPayload,
+					101: mocks.MockStateDiffPayload,
+				},
+			},
+			Retriever:         &mocks2.CIDRetriever{GapsToRetrieve: []shared.Gap{{Start: 100, Stop: 101}}},
+			GapCheckFrequency: time.Second * 2,
+			BatchSize:         DefaultMaxBatchSize,
+			BatchNumber:       DefaultMaxBatchNumber,
+			QuitChan:          quitChan,
+			chain:             shared.Ethereum,
+		}
+		backFiller.BackFill(new(sync.WaitGroup))
+		time.Sleep(time.Second * 3)
+		quitChan <- true
+
+		Expect(testutil.ToFloat64(backFillBlocks.WithLabelValues(chain))).To(Equal(blocks + 2))
+		Expect(sampleCount("vdb_super_node_backfill_batch_duration_seconds", map[string]string{"chain": chain})).To(Equal(batches + 1))
+		Expect(stageCount(backFillProcess, convertStage)).To(Equal(converted + 2))
+		Expect(stageCount(backFillProcess, indexStage)).To(Equal(indexed + 2))
+		Expect(testutil.ToFloat64(gaps.WithLabelValues(chain))).To(Equal(float64(1)))
+		Expect(testutil.ToFloat64(gapBlocks.WithLabelValues(chain))).To(Equal(float64(2)))
+		Expect(testutil.ToFloat64(workers.WithLabelValues(chain, backFillProcess))).To(Equal(float64(DefaultMaxBatchNumber)))
+		Expect(testutil.ToFloat64(busyWorkers.WithLabelValues(chain, backFillProcess))).To(Equal(float64(0)))
+	})
+
+	It("Tracks the live subscriptions of each type and counts the payloads dropped for them", func() {
+		sn, err := NewSuperNode(&Config{Chain: shared.Ethereum, Workers: 1})
+		Expect(err).ToNot(HaveOccurred())
+		service := sn.(*Service)
+		service.Filterer = eth.NewResponseFilterer()
+		payloadChan := make(chan shared.ConvertedData, 1)
+		service.Serve(new(sync.WaitGroup), payloadChan)
+		defer service.Stop()
+
+		params := &eth.SubscriptionSettings{Start: big.NewInt(0), End: big.NewInt(0)}
+		by, err := rlp.EncodeToBytes(params)
+		Expect(err).ToNot(HaveOccurred())
+		subType := crypto.Keccak256Hash(by).Hex()
+		dropped := testutil.ToFloat64(droppedPayloads.WithLabelValues(chain, subscriptionBuffer))
+
+		// nothing reads from the first subscription, so the payload sent to it is dropped
+		first, second := rpc.NewID(), rpc.NewID()
+		service.Subscribe(first, make(chan SubscriptionPayload), make(chan bool, 1), params)
+		service.Subscribe(second, make(chan SubscriptionPayload, 1), make(chan bool, 1), params)
+		Expect(testutil.ToFloat64(activeSubscriptions.WithLabelValues(chain, subType))).To(Equal(float64(2)))
+		payloadChan <- mocks.MockConvertedPayload
+		Eventually(func() float64 {
+			return testutil.ToFloat64(droppedPayloads.WithLabelValues(chain, subscriptionBuffer))
+		}).Should(Equal(dropped + 1))
+
+		service.Unsubscribe(first)
+		Expect(testutil.ToFloat64(activeSubscriptions.WithLabelValues(chain, subType))).To(Equal(float64(1)))
+		service.Unsubscribe(second)
+		found, _ := gathered("vdb_super_node_active_subscriptions", map[string]string{"chain": chain, "subscription_type": subType})
+		Expect(found).To(BeFalse())
+	})
+})
@@ -0,0 +1,151 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package omni
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/vulcanizedb/pkg/postgres"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/btc"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+// CIDRetriever satisfies the CIDRetriever interface for omni
+// Block number, gap and validation level lookups are served from the bitcoin tables by the embedded bitcoin CIDRetriever
+type CIDRetriever struct {
+	*btc.CIDRetriever
+	db *postgres.DB
+}
+
+// NewCIDRetriever returns a pointer to a new CIDRetriever which supports the CIDRetriever interface
+func NewCIDRetriever(db *postgres.DB) *CIDRetriever {
+	return &CIDRetriever{
+		CIDRetriever: btc.NewCIDRetriever(db),
+		db:           db,
+	}
+}
+
+// Retrieve is used to retrieve all of the CIDs which conform to the passed StreamFilters
+func (ocr *CIDRetriever) Retrieve(filter shared.SubscriptionSettings, blockNumber int64) ([]shared.CIDsForFetching, bool, error) {
+	streamFilter, ok := filter.(*SubscriptionSettings)
+	if !ok {
+		return nil, true, fmt.Errorf("omni retriever expected filter type %T got %T", &SubscriptionSettings{}, filter)
+	}
+	log.Debug("retrieving cids")
+
+	// Begin new db tx
+	tx, err := ocr.db.Beginx()
+	if err != nil {
+		return nil, true, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	// Retrieve cached header CIDs
+	headers, err := ocr.RetrieveHeaderCIDs(tx, blockNumber)
+	if err != nil {
+		log.Error("header cid retrieval error")
+		return nil, true, err
+	}
+	cws := make([]shared.CIDsForFetching, len(headers))
+	empty := true
+	for i, header := range headers {
+		cw := new(btc.CIDWrapper)
+		cw.BlockNumber = big.NewInt(blockNumber)
+		cw.BlockHash = header.BlockHash
+		if !streamFilter.HeaderFilter.Off {
+			cw.Header = header
+			empty = false
+		}
+		// Retrieve cached trx CIDs
+		if !streamFilter.TxFilter.Off {
+			cw.Transactions, err = ocr.RetrieveTxCIDs(tx, streamFilter.TxFilter, header.ID)
+			if err != nil {
+				log.Error("transaction cid retrieval error")
+				return nil, true, err
+			}
+			if len(cw.Transactions) > 0 {
+				empty = false
+			}
+		}
+		cws[i] = cw
+	}
+
+	return cws, empty, err
+}
+
+// RetrieveTxCIDs retrieves and returns the cids of the bitcoin transactions under the provided header
+// that carry an omni transaction conforming to the provided filter parameters
+func (ocr *CIDRetriever) RetrieveTxCIDs(tx *sqlx.Tx, txFilter TxFilter, headerID int64) ([]btc.TxModel, error) {
+	log.Debug("retrieving omni transaction cids for header id ", headerID)
+	args := make([]interface{}, 0, 4)
+	results := make([]btc.TxModel, 0)
+	id := 1
+	pgStr := fmt.Sprintf(`SELECT transaction_cids.id, transaction_cids.header_id,
+ 			transaction_cids.tx_hash, transaction_cids.cid,
+ 			transaction_cids.segwit, transaction_cids.witness_hash, transaction_cids.index
+ 			FROM btc.transaction_cids
+			INNER JOIN omni.transactions ON (transactions.tx_id = transaction_cids.id)
+			WHERE transaction_cids.header_id = $%d`, id)
+	args = append(args, headerID)
+	id++
+	if len(txFilter.Types) > 0 {
+		types := make([]int64, len(txFilter.Types))
+		for i, t := range txFilter.Types {
+			types[i] = int64(t)
+		}
+		pgStr += fmt.Sprintf(` AND transactions.tx_type = ANY($%d::INTEGER[])`, id)
+		args = append(args, pq.Array(types))
+		id++
+	}
+	if len(txFilter.PropertyIDs) > 0 {
+		pgStr += fmt.Sprintf(` AND (transactions.property_id = ANY($%d::BIGINT[]) OR transactions.desired_property_id = ANY($%d::BIGINT[]))`, id, id)
+		args = append(args, pq.Array(txFilter.PropertyIDs))
+		id++
+	}
+	if len(txFilter.Addresses) > 0 {
+		pgStr += fmt.Sprintf(` AND (transactions.sender = ANY($%d::VARCHAR(66)[]) OR transactions.reference = ANY($%d::VARCHAR(66)[]))`, id, id)
+		args = append(args, pq.Array(txFilter.Addresses))
+	}
+	pgStr += ` ORDER BY transaction_cids.index`
+	return results, tx.Select(&results, pgStr, args...)
+}
+
+// RetrieveOmniTxs returns the omni transactions carried by the bitcoin transactions under the provided header
+func (ocr *CIDRetriever) RetrieveOmniTxs(tx *sqlx.Tx, headerID int64) ([]TxModel, error) {
+	log.Debug("retrieving omni transactions for header id ", headerID)
+	pgStr := `SELECT transactions.*, transaction_cids.tx_hash, transaction_cids.index AS tx_index
+			FROM omni.transactions
+			INNER JOIN btc.transaction_cids ON (transactions.tx_id = transaction_cids.id)
+			WHERE transaction_cids.header_id = $1
+			ORDER BY transaction_cids.index`
+	omniTxs := make([]TxModel, 0)
+	return omniTxs, tx.Select(&omniTxs, pgStr, headerID)
+}
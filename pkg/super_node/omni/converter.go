@@ -0,0 +1,81 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package omni
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/btc"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+// PayloadConverter satisfies the PayloadConverter interface for omni
+// It converts the bitcoin block like the bitcoin PayloadConverter and extracts the omni transactions from it
+type PayloadConverter struct {
+	btcConverter *btc.PayloadConverter
+	chainConfig  *chaincfg.Params
+}
+
+// NewPayloadConverter creates a pointer to a new PayloadConverter which satisfies the PayloadConverter interface
+func NewPayloadConverter(chainConfig *chaincfg.Params) *PayloadConverter {
+	return &PayloadConverter{
+		btcConverter: btc.NewPayloadConverter(chainConfig),
+		chainConfig:  chainConfig,
+	}
+}
+
+// Convert method is used to convert a bitcoin BlockPayload to an omni ConvertedPayload
+// Satisfies the shared.PayloadConverter interface
+func (pc *PayloadConverter) Convert(payload shared.RawChainData) (shared.ConvertedData, error) {
+	converted, err := pc.btcConverter.Convert(payload)
+	if err != nil {
+		return nil, err
+	}
+	btcPayload, ok := converted.(btc.ConvertedPayload)
+	if !ok {
+		return nil, fmt.Errorf("omni converter: expected converted payload type %T got %T", btc.ConvertedPayload{}, converted)
+	}
+	omniTxs := make([]TxModel, 0)
+	for i, tx := range btcPayload.Txs {
+		omniTx, err := ParseTransaction(tx.MsgTx(), pc.chainConfig)
+		if err == ErrNotOmni {
+			continue
+		}
+		if err != nil {
+			log.Warnf("omni converter: %v", err)
+			continue
+		}
+		model, err := DecodePayload(omniTx.Payload)
+		if err != nil {
+			log.Warnf("omni converter: transaction %s: %v", tx.Hash().String(), err)
+			continue
+		}
+		model.TxHash = tx.Hash().String()
+		model.TxIndex = int64(i)
+		model.Class = omniTx.Class
+		model.Sender = omniTx.Sender
+		model.Reference = omniTx.Reference
+		omniTxs = append(omniTxs, model)
+	}
+	return ConvertedPayload{
+		ConvertedPayload: btcPayload,
+		OmniTxs:          omniTxs,
+	}, nil
+}
@@ -0,0 +1,40 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package omni_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/omni"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/omni/mocks"
+)
+
+var _ = Describe("Converter", func() {
+	Describe("Convert", func() {
+		It("Converts the bitcoin block and extracts its omni transactions", func() {
+			converter := omni.NewPayloadConverter(mocks.Params)
+			payload, err := converter.Convert(mocks.MockBlockPayload)
+			Expect(err).ToNot(HaveOccurred())
+			convertedPayload, ok := payload.(omni.ConvertedPayload)
+			Expect(ok).To(BeTrue())
+			Expect(convertedPayload.BlockPayload).To(Equal(mocks.MockBlockPayload))
+			Expect(len(convertedPayload.TxMetaData)).To(Equal(4))
+			Expect(convertedPayload.OmniTxs).To(Equal(mocks.MockOmniTxs))
+		})
+	})
+})
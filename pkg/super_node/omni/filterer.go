@@ -0,0 +1,138 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package omni
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/multiformats/go-multihash"
+
+	"github.com/vulcanize/vulcanizedb/pkg/ipfs"
+	"github.com/vulcanize/vulcanizedb/pkg/ipfs/ipld"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/btc"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+// ResponseFilterer satisfies the ResponseFilterer interface for omni
+type ResponseFilterer struct{}
+
+// NewResponseFilterer creates a new Filterer satisfying the ResponseFilterer interface
+func NewResponseFilterer() *ResponseFilterer {
+	return &ResponseFilterer{}
+}
+
+// Filter is used to filter through omni data to extract and package requested data into a Payload
+// The response carries the bitcoin header and the bitcoin transactions that contain a matching omni transaction
+func (s *ResponseFilterer) Filter(filter shared.SubscriptionSettings, payload shared.ConvertedData) (shared.IPLDs, error) {
+	omniFilters, ok := filter.(*SubscriptionSettings)
+	if !ok {
+		return btc.IPLDs{}, fmt.Errorf("omni filterer expected filter type %T got %T", &SubscriptionSettings{}, filter)
+	}
+	omniPayload, ok := payload.(ConvertedPayload)
+	if !ok {
+		return btc.IPLDs{}, fmt.Errorf("omni filterer expected payload type %T got %T", ConvertedPayload{}, payload)
+	}
+	height := int64(omniPayload.BlockPayload.BlockHeight)
+	if checkRange(omniFilters.Start.Int64(), omniFilters.End.Int64(), height) {
+		response := new(btc.IPLDs)
+		if err := filterHeaders(omniFilters.HeaderFilter, response, omniPayload); err != nil {
+			return btc.IPLDs{}, err
+		}
+		if err := filterTransactions(omniFilters.TxFilter, response, omniPayload); err != nil {
+			return btc.IPLDs{}, err
+		}
+		response.BlockNumber = big.NewInt(height)
+		return *response, nil
+	}
+	return btc.IPLDs{}, nil
+}
+
+func filterHeaders(headerFilter btc.HeaderFilter, response *btc.IPLDs, payload ConvertedPayload) error {
+	if !headerFilter.Off {
+		headerBuffer := new(bytes.Buffer)
+		if err := payload.Header.Serialize(headerBuffer); err != nil {
+			return err
+		}
+		data := headerBuffer.Bytes()
+		cid, err := ipld.RawdataToCid(ipld.MBitcoinHeader, data, multihash.DBL_SHA2_256)
+		if err != nil {
+			return err
+		}
+		response.Header = ipfs.BlockModel{
+			Data: data,
+			CID:  cid.String(),
+		}
+	}
+	return nil
+}
+
+func checkRange(start, end, actual int64) bool {
+	if (end <= 0 || end >= actual) && start <= actual {
+		return true
+	}
+	return false
+}
+
+func filterTransactions(txFilter TxFilter, response *btc.IPLDs, payload ConvertedPayload) error {
+	if !txFilter.Off {
+		response.Transactions = make([]ipfs.BlockModel, 0, len(payload.OmniTxs))
+		for _, omniTx := range payload.OmniTxs {
+			if checkTransaction(omniTx, txFilter) {
+				txBuffer := new(bytes.Buffer)
+				if err := payload.Txs[omniTx.TxIndex].MsgTx().Serialize(txBuffer); err != nil {
+					return err
+				}
+				data := txBuffer.Bytes()
+				cid, err := ipld.RawdataToCid(ipld.MBitcoinTx, data, multihash.DBL_SHA2_256)
+				if err != nil {
+					return err
+				}
+				response.Transactions = append(response.Transactions, ipfs.BlockModel{
+					Data: data,
+					CID:  cid.String(),
+				})
+			}
+		}
+	}
+	return nil
+}
+
+// checkTransaction returns true if the provided omni transaction has a hit on the filter
+func checkTransaction(omniTx TxModel, txFilter TxFilter) bool {
+	passesTypeFilter := len(txFilter.Types) == 0
+	for _, wantedType := range txFilter.Types {
+		if wantedType == omniTx.TxType {
+			passesTypeFilter = true
+		}
+	}
+	passesPropertyFilter := len(txFilter.PropertyIDs) == 0
+	for _, wantedPropertyID := range txFilter.PropertyIDs {
+		if (omniTx.PropertyID != nil && *omniTx.PropertyID == wantedPropertyID) ||
+			(omniTx.DesiredPropertyID != nil && *omniTx.DesiredPropertyID == wantedPropertyID) {
+			passesPropertyFilter = true
+		}
+	}
+	passesAddressFilter := len(txFilter.Addresses) == 0
+	for _, wantedAddress := range txFilter.Addresses {
+		if wantedAddress == omniTx.Sender || wantedAddress == omniTx.Reference {
+			passesAddressFilter = true
+		}
+	}
+	return passesTypeFilter && passesPropertyFilter && passesAddressFilter
+}
@@ -0,0 +1,103 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package omni_test
+
+import (
+	"bytes"
+	"math/big"
+
+	"github.com/btcsuite/btcd/wire"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/btc"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/omni"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/omni/mocks"
+)
+
+var _ = Describe("Filterer", func() {
+	var (
+		filterer *omni.ResponseFilterer
+		payload  omni.ConvertedPayload
+	)
+	BeforeEach(func() {
+		filterer = omni.NewResponseFilterer()
+		converted, err := omni.NewPayloadConverter(mocks.Params).Convert(mocks.MockBlockPayload)
+		Expect(err).ToNot(HaveOccurred())
+		payload = converted.(omni.ConvertedPayload)
+	})
+
+	Describe("Filter", func() {
+		It("Returns the header and every transaction carrying an omni transaction by default", func() {
+			iplds, err := filterer.Filter(settings(omni.TxFilter{}), payload)
+			Expect(err).ToNot(HaveOccurred())
+			btcIPLDs, ok := iplds.(btc.IPLDs)
+			Expect(ok).To(BeTrue())
+			Expect(btcIPLDs.BlockNumber.Int64()).To(Equal(mocks.MockBlockHeight))
+			Expect(btcIPLDs.Header.Data).ToNot(BeEmpty())
+			Expect(len(btcIPLDs.Transactions)).To(Equal(2))
+			Expect(btcIPLDs.Transactions[0].Data).To(Equal(serialize(mocks.ClassCTx)))
+			Expect(btcIPLDs.Transactions[1].Data).To(Equal(serialize(mocks.ClassBTx)))
+		})
+
+		It("Filters by omni transaction type", func() {
+			iplds, err := filterer.Filter(settings(omni.TxFilter{Types: []uint16{omni.CreatePropertyFixed}}), payload)
+			Expect(err).ToNot(HaveOccurred())
+			btcIPLDs := iplds.(btc.IPLDs)
+			Expect(len(btcIPLDs.Transactions)).To(Equal(1))
+			Expect(btcIPLDs.Transactions[0].Data).To(Equal(serialize(mocks.ClassBTx)))
+		})
+
+		It("Filters by property id", func() {
+			iplds, err := filterer.Filter(settings(omni.TxFilter{PropertyIDs: []int64{31}}), payload)
+			Expect(err).ToNot(HaveOccurred())
+			btcIPLDs := iplds.(btc.IPLDs)
+			Expect(len(btcIPLDs.Transactions)).To(Equal(1))
+			Expect(btcIPLDs.Transactions[0].Data).To(Equal(serialize(mocks.ClassCTx)))
+		})
+
+		It("Filters by sender or reference address", func() {
+			iplds, err := filterer.Filter(settings(omni.TxFilter{Addresses: []string{mocks.ReferenceAddress}}), payload)
+			Expect(err).ToNot(HaveOccurred())
+			btcIPLDs := iplds.(btc.IPLDs)
+			Expect(len(btcIPLDs.Transactions)).To(Equal(1))
+			Expect(btcIPLDs.Transactions[0].Data).To(Equal(serialize(mocks.ClassCTx)))
+		})
+
+		It("Returns nothing for blocks outside of the requested range", func() {
+			filter := settings(omni.TxFilter{})
+			filter.Start = big.NewInt(mocks.MockBlockHeight + 1)
+			iplds, err := filterer.Filter(filter, payload)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(iplds).To(Equal(btc.IPLDs{}))
+		})
+	})
+})
+
+func settings(txFilter omni.TxFilter) *omni.SubscriptionSettings {
+	return &omni.SubscriptionSettings{
+		Start:    big.NewInt(0),
+		End:      big.NewInt(0),
+		TxFilter: txFilter,
+	}
+}
+
+func serialize(tx *wire.MsgTx) []byte {
+	buf := new(bytes.Buffer)
+	Expect(tx.Serialize(buf)).To(Succeed())
+	return buf.Bytes()
+}
@@ -0,0 +1,91 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package omni
+
+import (
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/vulcanizedb/pkg/postgres"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/btc"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+// CIDIndexer satisfies the CIDIndexer interface for omni
+// It indexes the bitcoin CIDs and the omni transactions in the same db tx
+type CIDIndexer struct {
+	db         *postgres.DB
+	btcIndexer *btc.CIDIndexer
+}
+
+// NewCIDIndexer creates a pointer to a new CIDIndexer which satisfies the CIDIndexer interface
+func NewCIDIndexer(db *postgres.DB) *CIDIndexer {
+	return &CIDIndexer{
+		db:         db,
+		btcIndexer: btc.NewCIDIndexer(db),
+	}
+}
+
+// Index indexes a CIDPayload in Postgres
+func (in *CIDIndexer) Index(cids shared.CIDsForIndexing) error {
+	cidWrapper, ok := cids.(*CIDPayload)
+	if !ok {
+		return fmt.Errorf("omni indexer expected cids type %T got %T", &CIDPayload{}, cids)
+	}
+
+	// Begin new db tx
+	tx, err := in.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	if err = in.btcIndexer.IndexCIDs(tx, &cidWrapper.CIDPayload); err != nil {
+		return err
+	}
+	err = indexOmniTxs(tx, cidWrapper.OmniTxs)
+	return err
+}
+
+// indexOmniTxs indexes omni transactions against the bitcoin transactions that carry them, which need to have already been indexed
+func indexOmniTxs(tx *sqlx.Tx, omniTxs []TxModel) error {
+	for _, omniTx := range omniTxs {
+		_, err := tx.Exec(`INSERT INTO omni.transactions (tx_id, class, version, tx_type, sender, reference, property_id, amount,
+							desired_property_id, desired_amount, ecosystem, payload)
+							SELECT id, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12 FROM btc.transaction_cids WHERE tx_hash = $1
+							ON CONFLICT (tx_id) DO UPDATE SET (class, version, tx_type, sender, reference, property_id, amount,
+							desired_property_id, desired_amount, ecosystem, payload) = ($2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+			omniTx.TxHash, omniTx.Class, omniTx.Version, omniTx.TxType, omniTx.Sender, omniTx.Reference, omniTx.PropertyID, omniTx.Amount,
+			omniTx.DesiredPropertyID, omniTx.DesiredAmount, omniTx.Ecosystem, omniTx.Payload)
+		if err != nil {
+			logrus.Error("omni indexer error when indexing omni transactions")
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package omni_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/vulcanizedb/pkg/postgres"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/btc"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/omni"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/omni/mocks"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+var _ = Describe("Indexer", func() {
+	var (
+		db   *postgres.DB
+		err  error
+		repo *omni.CIDIndexer
+	)
+	BeforeEach(func() {
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+		repo = omni.NewCIDIndexer(db)
+	})
+	AfterEach(func() {
+		btc.TearDownDB(db)
+	})
+
+	Describe("Index", func() {
+		It("Indexes the omni transactions against the bitcoin transactions that carry them", func() {
+			err = repo.Index(&mocks.MockCIDPayload)
+			Expect(err).ToNot(HaveOccurred())
+			header := new(btc.HeaderModel)
+			err = db.Get(header, `SELECT * FROM btc.header_cids WHERE block_number = $1`, mocks.MockHeaderMetaData.BlockNumber)
+			Expect(err).ToNot(HaveOccurred())
+
+			tx, err := db.Beginx()
+			Expect(err).ToNot(HaveOccurred())
+			omniTxs, err := omni.NewCIDRetriever(db).RetrieveOmniTxs(tx, header.ID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tx.Commit()).To(Succeed())
+			Expect(len(omniTxs)).To(Equal(2))
+			for i, omniTx := range omniTxs {
+				expected := mocks.MockOmniTxs[i]
+				expected.ID = omniTx.ID
+				expected.TxID = omniTx.TxID
+				Expect(omniTx).To(Equal(expected))
+			}
+		})
+
+		It("Retrieves only the bitcoin transactions whose omni transactions pass the filter", func() {
+			err = repo.Index(&mocks.MockCIDPayload)
+			Expect(err).ToNot(HaveOccurred())
+			retriever := omni.NewCIDRetriever(db)
+			cids, empty, err := retriever.Retrieve(&omni.SubscriptionSettings{
+				HeaderFilter: btc.HeaderFilter{Off: true},
+				TxFilter:     omni.TxFilter{PropertyIDs: []int64{31}},
+			}, mocks.MockBlockHeight)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(empty).To(BeFalse())
+			Expect(len(cids)).To(Equal(1))
+			cidWrapper, ok := cids[0].(*btc.CIDWrapper)
+			Expect(ok).To(BeTrue())
+			Expect(len(cidWrapper.Transactions)).To(Equal(1))
+			Expect(cidWrapper.Transactions[0].CID).To(Equal("mockOmniTxCID2"))
+			Expect(cidWrapper.Transactions[0].TxHash).To(Equal(mocks.ClassCTx.TxHash().String()))
+		})
+	})
+})
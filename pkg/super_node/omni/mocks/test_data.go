@@ -0,0 +1,294 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mocks
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/btc"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/omni"
+)
+
+// Test variables
+var (
+	Params                = &chaincfg.MainNetParams
+	MockBlockHeight int64 = 300000
+
+	senderKey, _     = btcec.PrivKeyFromBytes(btcec.S256(), bytes.Repeat([]byte{0x01}, 32))
+	SenderPubKey     = senderKey.PubKey().SerializeCompressed()
+	SenderAddress    = p2pkhAddress(SenderPubKey)
+	referenceKey, _  = btcec.PrivKeyFromBytes(btcec.S256(), bytes.Repeat([]byte{0x02}, 32))
+	ReferenceAddress = p2pkhAddress(referenceKey.PubKey().SerializeCompressed())
+
+	// SimpleSendPayload sends 0.5 of property 31 to the reference address
+	SimpleSendPayload = append([]byte{
+		0x00, 0x00, // version 0
+		0x00, 0x00, // type 0, simple send
+		0x00, 0x00, 0x00, 0x1f, // property 31
+	}, int64Bytes(50000000)...)
+	// CreatePropertyPayload creates a fixed property of 1000000 indivisible tokens in the main ecosystem
+	CreatePropertyPayload = bytes.Join([][]byte{
+		{
+			0x00, 0x00, // version 0
+			0x00, 0x32, // type 50, create property with fixed supply
+			0x01,       // main ecosystem
+			0x00, 0x01, // indivisible tokens
+			0x00, 0x00, 0x00, 0x00, // new property
+		},
+		[]byte("Companies\x00Bitcoin Mining\x00Quantum Miner\x00tinyurl.com/kwejgoig\x00\x00"),
+		int64Bytes(1000000),
+	}, nil)
+	// ClassBPayload is the CreatePropertyPayload padded out to fill its three packets
+	ClassBPayload = append(CreatePropertyPayload, make([]byte, 3*30-len(CreatePropertyPayload))...)
+
+	CoinbaseTx = &wire.MsgTx{
+		Version: 1,
+		TxIn: []*wire.TxIn{{
+			PreviousOutPoint: wire.OutPoint{Index: 0xffffffff},
+			SignatureScript:  []byte{0x03, 0xe0, 0x93, 0x04},
+			Sequence:         0xffffffff,
+		}},
+		TxOut: []*wire.TxOut{{
+			Value:    2500000000,
+			PkScript: p2pkhScript(ReferenceAddress),
+		}},
+	}
+	ClassCTx = &wire.MsgTx{
+		Version: 1,
+		TxIn:    []*wire.TxIn{senderInput(0)},
+		TxOut: []*wire.TxOut{
+			{
+				Value:    0,
+				PkScript: nullDataScript(append(append([]byte{}, omni.Marker...), SimpleSendPayload...)),
+			},
+			{
+				Value:    546,
+				PkScript: p2pkhScript(ReferenceAddress),
+			},
+		},
+	}
+	ClassBTx = &wire.MsgTx{
+		Version: 1,
+		TxIn:    []*wire.TxIn{senderInput(1)},
+		TxOut: []*wire.TxOut{
+			{
+				Value:    6000,
+				PkScript: p2pkhScript(omni.ExodusAddress(Params)),
+			},
+			{
+				Value:    5460,
+				PkScript: multiSigScript(SenderPubKey, classBPackets[0], classBPackets[1]),
+			},
+			{
+				Value:    5460,
+				PkScript: multiSigScript(SenderPubKey, classBPackets[2]),
+			},
+			{
+				Value:    100000,
+				PkScript: p2pkhScript(SenderAddress),
+			},
+		},
+	}
+	PlainTx = &wire.MsgTx{
+		Version: 1,
+		TxIn:    []*wire.TxIn{senderInput(2)},
+		TxOut: []*wire.TxOut{{
+			Value:    100000,
+			PkScript: p2pkhScript(ReferenceAddress),
+		}},
+	}
+	classBPackets = obfuscate(ClassBPayload, SenderAddress)
+
+	MockBlock = wire.MsgBlock{
+		Header: wire.BlockHeader{
+			Version:    2,
+			PrevBlock:  chainhash.Hash{0x01},
+			MerkleRoot: chainhash.Hash{0x02},
+			Timestamp:  time.Unix(1400000000, 0),
+			Bits:       0x1900896c,
+			Nonce:      0x0a2b3c4d,
+		},
+		Transactions: []*wire.MsgTx{CoinbaseTx, ClassCTx, ClassBTx, PlainTx},
+	}
+	MockBlockPayload = btc.BlockPayload{
+		BlockHeight: MockBlockHeight,
+		Header:      &MockBlock.Header,
+		Txs: []*btcutil.Tx{
+			btcutil.NewTx(CoinbaseTx),
+			btcutil.NewTx(ClassCTx),
+			btcutil.NewTx(ClassBTx),
+			btcutil.NewTx(PlainTx),
+		},
+	}
+
+	MockOmniTxs = []omni.TxModel{
+		{
+			TxHash:     ClassCTx.TxHash().String(),
+			TxIndex:    1,
+			Class:      omni.ClassC,
+			Version:    0,
+			TxType:     omni.SimpleSend,
+			Sender:     SenderAddress,
+			Reference:  ReferenceAddress,
+			PropertyID: int64Ptr(31),
+			Amount:     int64Ptr(50000000),
+			Payload:    SimpleSendPayload,
+		},
+		{
+			TxHash:    ClassBTx.TxHash().String(),
+			TxIndex:   2,
+			Class:     omni.ClassB,
+			Version:   0,
+			TxType:    omni.CreatePropertyFixed,
+			Sender:    SenderAddress,
+			Reference: SenderAddress,
+			Amount:    int64Ptr(1000000),
+			Ecosystem: int64Ptr(1),
+			Payload:   ClassBPayload,
+		},
+	}
+	MockHeaderMetaData = btc.HeaderModel{
+		CID:         "mockOmniHeaderCID",
+		ParentHash:  MockBlock.Header.PrevBlock.String(),
+		BlockNumber: "300000",
+		BlockHash:   MockBlock.Header.BlockHash().String(),
+		Timestamp:   MockBlock.Header.Timestamp.UnixNano(),
+		Bits:        MockBlock.Header.Bits,
+	}
+	MockCIDPayload = omni.CIDPayload{
+		CIDPayload: btc.CIDPayload{
+			HeaderCID: MockHeaderMetaData,
+			TransactionCIDs: []btc.TxModelWithInsAndOuts{
+				{
+					CID:    "mockOmniTxCID1",
+					TxHash: CoinbaseTx.TxHash().String(),
+					Index:  0,
+				},
+				{
+					CID:    "mockOmniTxCID2",
+					TxHash: ClassCTx.TxHash().String(),
+					Index:  1,
+				},
+				{
+					CID:    "mockOmniTxCID3",
+					TxHash: ClassBTx.TxHash().String(),
+					Index:  2,
+				},
+				{
+					CID:    "mockOmniTxCID4",
+					TxHash: PlainTx.TxHash().String(),
+					Index:  3,
+				},
+			},
+		},
+		OmniTxs: MockOmniTxs,
+	}
+)
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
+
+func int64Bytes(i int64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(i))
+	return b
+}
+
+func p2pkhAddress(pubKey []byte) string {
+	addr, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160(pubKey), Params)
+	if err != nil {
+		panic(err)
+	}
+	return addr.EncodeAddress()
+}
+
+func p2pkhScript(address string) []byte {
+	addr, err := btcutil.DecodeAddress(address, Params)
+	if err != nil {
+		panic(err)
+	}
+	script, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		panic(err)
+	}
+	return script
+}
+
+func nullDataScript(data []byte) []byte {
+	script, err := txscript.NullDataScript(data)
+	if err != nil {
+		panic(err)
+	}
+	return script
+}
+
+func multiSigScript(keys ...[]byte) []byte {
+	builder := txscript.NewScriptBuilder().AddOp(txscript.OP_1)
+	for _, key := range keys {
+		builder.AddData(key)
+	}
+	script, err := builder.AddInt64(int64(len(keys))).AddOp(txscript.OP_CHECKMULTISIG).Script()
+	if err != nil {
+		panic(err)
+	}
+	return script
+}
+
+// senderInput returns a p2pkh input spending from the sender address
+func senderInput(index uint32) *wire.TxIn {
+	signature := append(bytes.Repeat([]byte{0x30}, 71), byte(txscript.SigHashAll))
+	script, err := txscript.NewScriptBuilder().AddData(signature).AddData(SenderPubKey).Script()
+	if err != nil {
+		panic(err)
+	}
+	return &wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Hash: chainhash.Hash{0x03}, Index: index},
+		SignatureScript:  script,
+		Sequence:         0xffffffff,
+	}
+}
+
+// obfuscate splits the payload into sequenced class B packets and obfuscates them with the sender address,
+// returning them as the public keys that carry them
+func obfuscate(payload []byte, sender string) [][]byte {
+	keys := make([][]byte, 0, len(payload)/30)
+	hash := sha256.Sum256([]byte(sender))
+	for i := 0; i < len(payload); i += 30 {
+		packet := append([]byte{byte(i/30 + 1)}, payload[i:i+30]...)
+		key := make([]byte, 33)
+		key[0] = 0x02
+		for j := range packet {
+			key[j+1] = packet[j] ^ hash[j]
+		}
+		keys = append(keys, key)
+		hash = sha256.Sum256([]byte(strings.ToUpper(hex.EncodeToString(hash[:]))))
+	}
+	return keys
+}
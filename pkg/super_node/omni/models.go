@@ -0,0 +1,37 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package omni
+
+// TxModel is the db model for omni.transactions table
+// Fields that do not apply to the transaction type are nil
+type TxModel struct {
+	ID                int64  `db:"id"`
+	TxID              int64  `db:"tx_id"`
+	TxHash            string `db:"tx_hash"`  // hash of the bitcoin transaction, joined from btc.transaction_cids
+	TxIndex           int64  `db:"tx_index"` // index of the bitcoin transaction in its block, joined from btc.transaction_cids
+	Class             uint8  `db:"class"`
+	Version           uint16 `db:"version"`
+	TxType            uint16 `db:"tx_type"`
+	Sender            string `db:"sender"`
+	Reference         string `db:"reference"`
+	PropertyID        *int64 `db:"property_id"`
+	Amount            *int64 `db:"amount"`
+	DesiredPropertyID *int64 `db:"desired_property_id"`
+	DesiredAmount     *int64 `db:"desired_amount"`
+	Ecosystem         *int64 `db:"ecosystem"`
+	Payload           []byte `db:"payload"`
+}
@@ -0,0 +1,380 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package omni
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+// Omni transaction encoding classes
+const (
+	ClassB uint8 = 2 // payload packets are obfuscated into the public keys of bare multisig outputs
+	ClassC uint8 = 3 // payload is pushed in an OP_RETURN output after the omni marker
+)
+
+// Omni transaction types that are parsed beyond their header
+const (
+	SimpleSend            uint16 = 0
+	SendToOwners          uint16 = 3
+	SendAll               uint16 = 4
+	TradeOffer            uint16 = 20
+	AcceptOffer           uint16 = 22
+	MetaDExTrade          uint16 = 25
+	MetaDExCancelPrice    uint16 = 26
+	MetaDExCancelPair     uint16 = 27
+	MetaDExCancelAll      uint16 = 28
+	CreatePropertyFixed   uint16 = 50
+	CreatePropertyVar     uint16 = 51
+	CloseCrowdsale        uint16 = 53
+	CreatePropertyManaged uint16 = 54
+	GrantTokens           uint16 = 55
+	RevokeTokens          uint16 = 56
+	ChangeIssuer          uint16 = 70
+	EnableFreezing        uint16 = 71
+	DisableFreezing       uint16 = 72
+	FreezeTokens          uint16 = 185
+	UnfreezeTokens        uint16 = 186
+)
+
+// Marker prefixes the payload of class C transactions
+var Marker = []byte("omni")
+
+// packetSize is the number of payload bytes, after the sequence number, carried by a class B packet
+const packetSize = 30
+
+// ErrNotOmni is returned when a transaction does not carry an omni payload
+var ErrNotOmni = errors.New("not an omni transaction")
+
+// ExodusAddress returns the address every class B transaction pays to on the given network
+func ExodusAddress(params *chaincfg.Params) string {
+	if params.Net == chaincfg.MainNetParams.Net {
+		return "1EXoDusjGwvnjZUyKkxZ4UHEf77z6A5S4P"
+	}
+	return "mpexoDuSkGGqvqrkrjiFng38QPkJQVFyqv"
+}
+
+// Transaction is an omni transaction extracted from a bitcoin transaction
+type Transaction struct {
+	Class     uint8
+	Sender    string
+	Reference string
+	Payload   []byte
+}
+
+// ParseTransaction extracts the omni payload, sender, and reference address from a bitcoin transaction
+// It returns ErrNotOmni if the transaction does not carry an omni payload
+// Omni Core takes the sender to be the address contributing the most input value; since input values are not known without the
+// transactions being spent, the sender is taken to be the address of the first input that can deobfuscate a class B payload,
+// or the address of the first input for class C
+func ParseTransaction(tx *wire.MsgTx, params *chaincfg.Params) (*Transaction, error) {
+	senders := inputAddresses(tx, params)
+	if payload, ok := classCPayload(tx); ok {
+		omniTx := &Transaction{Class: ClassC, Payload: payload}
+		if len(senders) > 0 {
+			omniTx.Sender = senders[0]
+		}
+		omniTx.Reference = referenceAddress(tx, params, omniTx.Sender)
+		return omniTx, nil
+	}
+	packets, ok := classBPackets(tx, params)
+	if !ok {
+		return nil, ErrNotOmni
+	}
+	for _, sender := range senders {
+		if payload, ok := deobfuscate(packets, sender); ok {
+			return &Transaction{
+				Class:     ClassB,
+				Sender:    sender,
+				Reference: referenceAddress(tx, params, sender),
+				Payload:   payload,
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("class B transaction %s could not be deobfuscated with any input address", tx.TxHash().String())
+}
+
+// classCPayload returns the payload pushed after the omni marker in an OP_RETURN output
+func classCPayload(tx *wire.MsgTx) ([]byte, bool) {
+	for _, out := range tx.TxOut {
+		if txscript.GetScriptClass(out.PkScript) != txscript.NullDataTy {
+			continue
+		}
+		pushes, err := txscript.PushedData(out.PkScript)
+		if err != nil {
+			continue
+		}
+		data := bytes.Join(pushes, nil)
+		if bytes.HasPrefix(data, Marker) {
+			return data[len(Marker):], true
+		}
+	}
+	return nil, false
+}
+
+// classBPackets returns the obfuscated packets of a class B transaction, they are carried by every public key of its bare multisig outputs
+// but the first one, which is the sender's
+func classBPackets(tx *wire.MsgTx, params *chaincfg.Params) ([][]byte, bool) {
+	exodus := ExodusAddress(params)
+	toExodus := false
+	var packets [][]byte
+	for _, out := range tx.TxOut {
+		switch txscript.GetScriptClass(out.PkScript) {
+		case txscript.MultiSigTy:
+			keys, err := txscript.PushedData(out.PkScript)
+			if err != nil || len(keys) < 2 {
+				continue
+			}
+			for _, key := range keys[1:] {
+				if len(key) == 33 {
+					packets = append(packets, key[1:32])
+				}
+			}
+		default:
+			_, addresses, _, err := txscript.ExtractPkScriptAddrs(out.PkScript, params)
+			if err == nil && len(addresses) == 1 && addresses[0].EncodeAddress() == exodus {
+				toExodus = true
+			}
+		}
+	}
+	return packets, toExodus && len(packets) > 0
+}
+
+// deobfuscate xors each packet with the sha256 hash chain of the sender address and orders the packets by their sequence number
+// Packet n is xored with the n-th hash in the chain, where the first hash is of the address and every following one is of the
+// uppercase hex of the previous hash
+func deobfuscate(packets [][]byte, sender string) ([]byte, bool) {
+	type packet struct {
+		seq  byte
+		data []byte
+	}
+	clear := make([]packet, 0, len(packets))
+	hash := sha256.Sum256([]byte(sender))
+	for _, obfuscated := range packets {
+		data := make([]byte, len(obfuscated))
+		for i := range obfuscated {
+			data[i] = obfuscated[i] ^ hash[i]
+		}
+		clear = append(clear, packet{seq: data[0], data: data[1:]})
+		hash = sha256.Sum256([]byte(strings.ToUpper(hex.EncodeToString(hash[:]))))
+	}
+	sort.SliceStable(clear, func(i, j int) bool { return clear[i].seq < clear[j].seq })
+	payload := make([]byte, 0, len(clear)*packetSize)
+	for i, p := range clear {
+		if int(p.seq) != i+1 {
+			return nil, false
+		}
+		payload = append(payload, p.data...)
+	}
+	return payload, true
+}
+
+// inputAddresses derives the addresses spent from by the inputs of a transaction from their signature scripts and witnesses
+func inputAddresses(tx *wire.MsgTx, params *chaincfg.Params) []string {
+	addresses := make([]string, 0, len(tx.TxIn))
+	seen := make(map[string]bool, len(tx.TxIn))
+	for _, in := range tx.TxIn {
+		address, ok := inputAddress(in, params)
+		if ok && !seen[address] {
+			seen[address] = true
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses
+}
+
+func inputAddress(in *wire.TxIn, params *chaincfg.Params) (string, bool) {
+	if len(in.SignatureScript) == 0 {
+		// native segwit, p2wpkh witnesses end with the public key
+		if len(in.Witness) == 2 && isPubKey(in.Witness[1]) {
+			addr, err := btcutil.NewAddressWitnessPubKeyHash(btcutil.Hash160(in.Witness[1]), params)
+			if err == nil {
+				return addr.EncodeAddress(), true
+			}
+		}
+		return "", false
+	}
+	pushes, err := txscript.PushedData(in.SignatureScript)
+	if err != nil || len(pushes) == 0 {
+		return "", false
+	}
+	last := pushes[len(pushes)-1]
+	var addr btcutil.Address
+	if isPubKey(last) && len(pushes) == 2 {
+		// p2pkh, the signature followed by the public key
+		addr, err = btcutil.NewAddressPubKeyHash(btcutil.Hash160(last), params)
+	} else {
+		// p2sh, the last push is the redeem script
+		addr, err = btcutil.NewAddressScriptHash(last, params)
+	}
+	if err != nil {
+		return "", false
+	}
+	return addr.EncodeAddress(), true
+}
+
+func isPubKey(data []byte) bool {
+	return (len(data) == 33 && (data[0] == 0x02 || data[0] == 0x03)) || (len(data) == 65 && data[0] == 0x04)
+}
+
+// referenceAddress returns the address of the last output that is neither a data output nor to the exodus address
+// Outputs back to the sender are only used if there is no other candidate
+func referenceAddress(tx *wire.MsgTx, params *chaincfg.Params, sender string) string {
+	exodus := ExodusAddress(params)
+	reference := ""
+	for _, out := range tx.TxOut {
+		class, addresses, _, err := txscript.ExtractPkScriptAddrs(out.PkScript, params)
+		if err != nil || class == txscript.MultiSigTy || class == txscript.NullDataTy || len(addresses) != 1 {
+			continue
+		}
+		address := addresses[0].EncodeAddress()
+		if address == exodus || (address == sender && reference != "") {
+			continue
+		}
+		reference = address
+	}
+	return reference
+}
+
+// DecodePayload decodes the header of an omni payload, and the fields of the transaction types that are known, into a TxModel
+// Fields that cannot be decoded, because the payload is too short, are left unset
+func DecodePayload(payload []byte) (TxModel, error) {
+	r := &payloadReader{payload: payload}
+	version, typ := r.uint16(), r.uint16()
+	if r.err != nil {
+		return TxModel{}, fmt.Errorf("omni payload of %d bytes is too short for a header", len(payload))
+	}
+	model := TxModel{
+		Version: version,
+		TxType:  typ,
+		Payload: payload,
+	}
+	switch typ {
+	case SimpleSend, SendToOwners, TradeOffer, AcceptOffer, GrantTokens, RevokeTokens, FreezeTokens, UnfreezeTokens:
+		property, amount := r.uint32(), r.int64()
+		if r.err == nil {
+			model.PropertyID, model.Amount = int64Ptr(int64(property)), int64Ptr(amount)
+		}
+	case SendAll, MetaDExCancelAll:
+		ecosystem := r.uint8()
+		if r.err == nil {
+			model.Ecosystem = int64Ptr(int64(ecosystem))
+		}
+	case MetaDExTrade, MetaDExCancelPrice:
+		property, amount, desired, desiredAmount := r.uint32(), r.int64(), r.uint32(), r.int64()
+		if r.err == nil {
+			model.PropertyID, model.Amount = int64Ptr(int64(property)), int64Ptr(amount)
+			model.DesiredPropertyID, model.DesiredAmount = int64Ptr(int64(desired)), int64Ptr(desiredAmount)
+		}
+	case MetaDExCancelPair:
+		property, desired := r.uint32(), r.uint32()
+		if r.err == nil {
+			model.PropertyID, model.DesiredPropertyID = int64Ptr(int64(property)), int64Ptr(int64(desired))
+		}
+	case CloseCrowdsale, ChangeIssuer, EnableFreezing, DisableFreezing:
+		property := r.uint32()
+		if r.err == nil {
+			model.PropertyID = int64Ptr(int64(property))
+		}
+	case CreatePropertyFixed, CreatePropertyVar, CreatePropertyManaged:
+		ecosystem := r.uint8()
+		r.uint16() // property type
+		r.uint32() // previous property id
+		for i := 0; i < 5; i++ {
+			r.string() // category, subcategory, name, url, and data
+		}
+		if r.err != nil {
+			break
+		}
+		model.Ecosystem = int64Ptr(int64(ecosystem))
+		switch typ {
+		case CreatePropertyFixed:
+			amount := r.int64()
+			if r.err == nil {
+				model.Amount = int64Ptr(amount)
+			}
+		case CreatePropertyVar:
+			desired, tokensPerUnit := r.uint32(), r.int64()
+			if r.err == nil {
+				model.DesiredPropertyID, model.Amount = int64Ptr(int64(desired)), int64Ptr(tokensPerUnit)
+			}
+		}
+	}
+	return model, nil
+}
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
+
+// payloadReader reads big-endian fields off of an omni payload, recording an error once it runs out of bytes
+type payloadReader struct {
+	payload []byte
+	err     error
+}
+
+func (r *payloadReader) next(n int) []byte {
+	if r.err != nil || len(r.payload) < n {
+		r.err = errors.New("omni payload is too short")
+		return make([]byte, n)
+	}
+	b := r.payload[:n]
+	r.payload = r.payload[n:]
+	return b
+}
+
+func (r *payloadReader) uint8() uint8 {
+	return r.next(1)[0]
+}
+
+func (r *payloadReader) uint16() uint16 {
+	return binary.BigEndian.Uint16(r.next(2))
+}
+
+func (r *payloadReader) uint32() uint32 {
+	return binary.BigEndian.Uint32(r.next(4))
+}
+
+func (r *payloadReader) int64() int64 {
+	return int64(binary.BigEndian.Uint64(r.next(8)))
+}
+
+// string reads a null terminated string
+func (r *payloadReader) string() string {
+	if r.err != nil {
+		return ""
+	}
+	i := bytes.IndexByte(r.payload, 0)
+	if i < 0 {
+		r.err = errors.New("omni payload string is not null terminated")
+		return ""
+	}
+	s := string(r.payload[:i])
+	r.payload = r.payload[i+1:]
+	return s
+}
@@ -0,0 +1,95 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package omni_test
+
+import (
+	"github.com/btcsuite/btcd/chaincfg"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/omni"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/omni/mocks"
+)
+
+var _ = Describe("Parser", func() {
+	Describe("ParseTransaction", func() {
+		It("Extracts the payload of class C transactions from their OP_RETURN output", func() {
+			omniTx, err := omni.ParseTransaction(mocks.ClassCTx, mocks.Params)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(omniTx.Class).To(Equal(omni.ClassC))
+			Expect(omniTx.Sender).To(Equal(mocks.SenderAddress))
+			Expect(omniTx.Reference).To(Equal(mocks.ReferenceAddress))
+			Expect(omniTx.Payload).To(Equal(mocks.SimpleSendPayload))
+		})
+
+		It("Deobfuscates the packets of class B transactions with the sender address", func() {
+			omniTx, err := omni.ParseTransaction(mocks.ClassBTx, mocks.Params)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(omniTx.Class).To(Equal(omni.ClassB))
+			Expect(omniTx.Sender).To(Equal(mocks.SenderAddress))
+			Expect(omniTx.Reference).To(Equal(mocks.SenderAddress))
+			Expect(omniTx.Payload).To(Equal(mocks.ClassBPayload))
+		})
+
+		It("Does not find class B payloads in transactions that are not sent to the exodus address on the network", func() {
+			_, err := omni.ParseTransaction(mocks.ClassBTx, &chaincfg.TestNet3Params)
+			Expect(err).To(Equal(omni.ErrNotOmni))
+		})
+
+		It("Returns ErrNotOmni for transactions without an omni payload", func() {
+			_, err := omni.ParseTransaction(mocks.PlainTx, mocks.Params)
+			Expect(err).To(Equal(omni.ErrNotOmni))
+			_, err = omni.ParseTransaction(mocks.CoinbaseTx, mocks.Params)
+			Expect(err).To(Equal(omni.ErrNotOmni))
+		})
+	})
+
+	Describe("DecodePayload", func() {
+		It("Decodes the property and amount of simple sends", func() {
+			model, err := omni.DecodePayload(mocks.SimpleSendPayload)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(model.Version).To(Equal(uint16(0)))
+			Expect(model.TxType).To(Equal(omni.SimpleSend))
+			Expect(*model.PropertyID).To(Equal(int64(31)))
+			Expect(*model.Amount).To(Equal(int64(50000000)))
+			Expect(model.DesiredPropertyID).To(BeNil())
+			Expect(model.Ecosystem).To(BeNil())
+		})
+
+		It("Decodes the ecosystem and supply of fixed property creations", func() {
+			model, err := omni.DecodePayload(mocks.ClassBPayload)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(model.TxType).To(Equal(omni.CreatePropertyFixed))
+			Expect(*model.Ecosystem).To(Equal(int64(1)))
+			Expect(*model.Amount).To(Equal(int64(1000000)))
+			Expect(model.PropertyID).To(BeNil())
+		})
+
+		It("Leaves the fields of truncated payloads unset", func() {
+			model, err := omni.DecodePayload(mocks.SimpleSendPayload[:10])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(model.TxType).To(Equal(omni.SimpleSend))
+			Expect(model.PropertyID).To(BeNil())
+			Expect(model.Amount).To(BeNil())
+		})
+
+		It("Fails on payloads too short for a header", func() {
+			_, err := omni.DecodePayload([]byte{0x00, 0x00})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
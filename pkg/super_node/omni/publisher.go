@@ -0,0 +1,69 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package omni
+
+import (
+	"fmt"
+
+	"github.com/vulcanize/vulcanizedb/pkg/ipfs"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/btc"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+// IPLDPublisher satisfies the IPLDPublisher interface for omni
+// The IPLDs are the bitcoin ones, the omni transactions are passed along for indexing
+type IPLDPublisher struct {
+	btcPublisher *btc.IPLDPublisher
+}
+
+// NewIPLDPublisher creates a pointer to a new Publisher which satisfies the IPLDPublisher interface
+func NewIPLDPublisher(ipfsPath string) (*IPLDPublisher, error) {
+	btcPublisher, err := btc.NewIPLDPublisher(ipfsPath)
+	if err != nil {
+		return nil, err
+	}
+	return &IPLDPublisher{
+		btcPublisher: btcPublisher,
+	}, nil
+}
+
+// NewRemoteIPLDPublisher creates a pointer to a new Publisher which publishes through the HTTP API of a remote IPFS node
+func NewRemoteIPLDPublisher(config ipfs.RemoteClientConfig) *IPLDPublisher {
+	return &IPLDPublisher{
+		btcPublisher: btc.NewRemoteIPLDPublisher(config),
+	}
+}
+
+// Publish publishes the bitcoin IPLDs to IPFS and returns the corresponding CIDPayload
+func (pub *IPLDPublisher) Publish(payload shared.ConvertedData) (shared.CIDsForIndexing, error) {
+	ipldPayload, ok := payload.(ConvertedPayload)
+	if !ok {
+		return nil, fmt.Errorf("omni publisher expected payload type %T got %T", ConvertedPayload{}, payload)
+	}
+	cids, err := pub.btcPublisher.Publish(ipldPayload.ConvertedPayload)
+	if err != nil {
+		return nil, err
+	}
+	btcCIDs, ok := cids.(*btc.CIDPayload)
+	if !ok {
+		return nil, fmt.Errorf("omni publisher expected btc cids type %T got %T", &btc.CIDPayload{}, cids)
+	}
+	return &CIDPayload{
+		CIDPayload: *btcCIDs,
+		OmniTxs:    ipldPayload.OmniTxs,
+	}, nil
+}
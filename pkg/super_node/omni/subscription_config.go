@@ -0,0 +1,154 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package omni
+
+import (
+	"errors"
+	"math"
+	"math/big"
+
+	"github.com/spf13/viper"
+
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/btc"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+// SubscriptionSettings config is used by a subscriber to specify what omni data to stream from the super node
+type SubscriptionSettings struct {
+	BackFill     bool                      `json:"backFill"`
+	BackFillOnly bool                      `json:"backFillOnly"`
+	Start        *big.Int                  `json:"start"`
+	End          *big.Int                  `json:"end"`          // set to 0 or a negative value to have no ending block
+	Cursor       shared.Cursor             `json:"cursor"`       // set to resume from the block after this cursor
+	SlowConsumer shared.SlowConsumerPolicy `json:"slowConsumer"` // what to do with payloads when the subscription falls behind
+	HeaderFilter btc.HeaderFilter          `json:"headerFilter"`
+	TxFilter     TxFilter                  `json:"txFilter"`
+}
+
+// TxFilter contains filter settings for omni txs
+// Only the bitcoin transactions that carry an omni transaction matching the filter are returned
+type TxFilter struct {
+	Off         bool     `json:"off"`
+	Types       []uint16 `json:"types"`       // allow filtering for specific omni transaction types (e.g. 0 for simple sends)
+	PropertyIDs []int64  `json:"propertyIDs"` // allow filtering for txs that involve at least one of the provided property ids
+	Addresses   []string `json:"addresses"`   // allow filtering for txs with one of the provided addresses as sender or reference
+}
+
+// NewOmniSubscriptionConfig is used to initialize a SubscriptionSettings struct with env variables
+func NewOmniSubscriptionConfig() (*SubscriptionSettings, error) {
+	sc := new(SubscriptionSettings)
+	// Below default to false, which means we do not backfill by default
+	sc.BackFill = viper.GetBool("superNode.omniSubscription.historicalData")
+	sc.BackFillOnly = viper.GetBool("superNode.omniSubscription.historicalDataOnly")
+	// Below default to 0
+	// 0 start means we start at the beginning and 0 end means we continue indefinitely
+	sc.Start = big.NewInt(viper.GetInt64("superNode.omniSubscription.startingBlock"))
+	sc.End = big.NewInt(viper.GetInt64("superNode.omniSubscription.endingBlock"))
+	// Below defaults to the zero cursor, which means we are not resuming a previous subscription
+	cursor, err := shared.ParseCursor(viper.GetString("superNode.omniSubscription.cursor"))
+	if err != nil {
+		return nil, err
+	}
+	sc.Cursor = cursor
+	// Below defaults to dropping payloads when the subscription falls behind
+	mode, err := shared.NewSlowConsumerMode(viper.GetString("superNode.omniSubscription.slowConsumer.policy"))
+	if err != nil {
+		return nil, err
+	}
+	sc.SlowConsumer = shared.SlowConsumerPolicy{
+		Mode:    mode,
+		Timeout: uint64(viper.GetInt64("superNode.omniSubscription.slowConsumer.timeout")),
+	}
+	// Below default to false, which means we get all headers by default
+	sc.HeaderFilter = btc.HeaderFilter{
+		Off: viper.GetBool("superNode.omniSubscription.headerFilter.off"),
+	}
+	// Below defaults to false and slices of length 0
+	// Which means we get all omni transactions by default
+	types := make([]uint16, 0)
+	for _, t := range toInt64s(viper.Get("superNode.omniSubscription.txFilter.types")) {
+		if t < 0 || t > math.MaxUint16 {
+			return nil, errors.New("superNode.omniSubscription.txFilter.types needs to be an array of uint16s")
+		}
+		types = append(types, uint16(t))
+	}
+	sc.TxFilter = TxFilter{
+		Off:         viper.GetBool("superNode.omniSubscription.txFilter.off"),
+		Types:       types,
+		PropertyIDs: toInt64s(viper.Get("superNode.omniSubscription.txFilter.propertyIDs")),
+		Addresses:   viper.GetStringSlice("superNode.omniSubscription.txFilter.addresses"),
+	}
+	return sc, nil
+}
+
+// toInt64s converts a config array of integers into a slice of int64s, it returns an empty slice if the array is not set
+func toInt64s(value interface{}) []int64 {
+	switch v := value.(type) {
+	case []int64:
+		return v
+	case []interface{}:
+		ints := make([]int64, 0, len(v))
+		for _, i := range v {
+			switch n := i.(type) {
+			case int64:
+				ints = append(ints, n)
+			case int:
+				ints = append(ints, int64(n))
+			case float64:
+				ints = append(ints, int64(n))
+			}
+		}
+		return ints
+	default:
+		return []int64{}
+	}
+}
+
+// StartingBlock satisfies the SubscriptionSettings() interface
+func (sc *SubscriptionSettings) StartingBlock() *big.Int {
+	return sc.Start
+}
+
+// EndingBlock satisfies the SubscriptionSettings() interface
+func (sc *SubscriptionSettings) EndingBlock() *big.Int {
+	return sc.End
+}
+
+// HistoricalData satisfies the SubscriptionSettings() interface
+func (sc *SubscriptionSettings) HistoricalData() bool {
+	return sc.BackFill
+}
+
+// HistoricalDataOnly satisfies the SubscriptionSettings() interface
+func (sc *SubscriptionSettings) HistoricalDataOnly() bool {
+	return sc.BackFillOnly
+}
+
+// ChainType satisfies the SubscriptionSettings() interface
+func (sc *SubscriptionSettings) ChainType() shared.ChainType {
+	return shared.Omni
+}
+
+// ResumeCursor satisfies the SubscriptionSettings() interface
+func (sc *SubscriptionSettings) ResumeCursor() shared.Cursor {
+	return sc.Cursor
+}
+
+// SlowConsumerPolicy satisfies the SubscriptionSettings() interface
+func (sc *SubscriptionSettings) SlowConsumerPolicy() shared.SlowConsumerPolicy {
+	return sc.SlowConsumer
+}
@@ -0,0 +1,120 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package super_node
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/btc"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+const (
+	// ReorgTrackingDepth is the number of recent heights the Sync process remembers the block hashes of for detecting reorgs
+	ReorgTrackingDepth = 256
+)
+
+// ReorgTracker tracks the recent chain of payloads streamed into the super node by their parent hash linkage
+// and detects when a newly streamed payload replaces payloads it has previously seen
+type ReorgTracker struct {
+	depth  int64
+	head   int64
+	hashes map[int64]string
+}
+
+// NewReorgTracker returns a new ReorgTracker which remembers the block hashes of the provided number of recent heights
+func NewReorgTracker(depth int64) *ReorgTracker {
+	return &ReorgTracker{
+		depth:  depth,
+		hashes: make(map[int64]string),
+	}
+}
+
+// Track adds the payload to the tracked chain and returns the heights, in ascending order, of the previously seen payloads
+// that it invalidates; these are the heights of any competing payload at the same height and of every payload above it,
+// as well as the height below it if the payload we have there is not its parent
+func (rt *ReorgTracker) Track(payload shared.ConvertedData) ([]int64, error) {
+	hash, parentHash, err := blockHashes(payload)
+	if err != nil {
+		return nil, err
+	}
+	height := payload.Height()
+	start := height + 1
+	if knownHash, ok := rt.hashes[height]; ok {
+		if knownHash == hash {
+			return nil, nil
+		}
+		start = height
+	}
+	if knownParentHash, ok := rt.hashes[height-1]; ok && knownParentHash != parentHash {
+		start = height - 1
+	}
+	invalidated := make([]int64, 0)
+	for h := start; h <= rt.head; h++ {
+		if _, ok := rt.hashes[h]; ok {
+			invalidated = append(invalidated, h)
+			delete(rt.hashes, h)
+		}
+	}
+	rt.hashes[height] = hash
+	if height > rt.head || len(invalidated) > 0 {
+		rt.head = height
+	}
+	for h := range rt.hashes {
+		if h <= rt.head-rt.depth {
+			delete(rt.hashes, h)
+		}
+	}
+	sort.Slice(invalidated, func(i, j int) bool { return invalidated[i] < invalidated[j] })
+	return invalidated, nil
+}
+
+// Forget removes the heights from the tracked chain, so that the payloads which replace them are not taken to invalidate them again
+func (rt *ReorgTracker) Forget(heights []int64) {
+	for _, h := range heights {
+		delete(rt.hashes, h)
+		if h <= rt.head {
+			rt.head = h - 1
+		}
+	}
+}
+
+// blockHashes returns the block hash and parent hash of the converted payload
+func blockHashes(payload shared.ConvertedData) (string, string, error) {
+	switch p := payload.(type) {
+	case eth.ConvertedPayload:
+		return p.Block.Hash().Hex(), p.Block.ParentHash().Hex(), nil
+	case btc.ConvertedPayload:
+		return p.Header.BlockHash().String(), p.Header.PrevBlock.String(), nil
+	default:
+		return "", "", fmt.Errorf("reorg tracker does not support converted data type %T", payload)
+	}
+}
+
+// reorgNotice is forwarded from the Sync process to the Serve process, in line with the converted payloads,
+// to notify subscribers of the heights invalidated by a reorg
+type reorgNotice struct {
+	height      int64
+	invalidated []int64
+}
+
+// Height satisfies the ConvertedData interface
+func (rn reorgNotice) Height() int64 {
+	return rn.height
+}
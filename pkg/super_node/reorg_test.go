@@ -0,0 +1,98 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package super_node_test
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/vulcanizedb/pkg/super_node"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth"
+)
+
+func convertedPayload(number int64, parentHash common.Hash, extra string) eth.ConvertedPayload {
+	return eth.ConvertedPayload{
+		Block: types.NewBlockWithHeader(&types.Header{
+			Number:     big.NewInt(number),
+			ParentHash: parentHash,
+			Extra:      []byte(extra),
+		}),
+	}
+}
+
+var _ = Describe("ReorgTracker", func() {
+	var (
+		tracker *super_node.ReorgTracker
+		a1, a2  eth.ConvertedPayload
+		a3      eth.ConvertedPayload
+	)
+	BeforeEach(func() {
+		tracker = super_node.NewReorgTracker(super_node.ReorgTrackingDepth)
+		a1 = convertedPayload(1, common.Hash{}, "a")
+		a2 = convertedPayload(2, a1.Block.Hash(), "a")
+		a3 = convertedPayload(3, a2.Block.Hash(), "a")
+		for _, payload := range []eth.ConvertedPayload{a1, a2, a3} {
+			invalidated, err := tracker.Track(payload)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(invalidated)).To(Equal(0))
+		}
+	})
+
+	Describe("Track", func() {
+		It("Does not report a reorg for payloads it has already seen", func() {
+			invalidated, err := tracker.Track(a2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(invalidated)).To(Equal(0))
+		})
+
+		It("Invalidates the height of a competing payload and every height above it", func() {
+			b2 := convertedPayload(2, a1.Block.Hash(), "b")
+			invalidated, err := tracker.Track(b2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(invalidated).To(Equal([]int64{2, 3}))
+			b3 := convertedPayload(3, b2.Block.Hash(), "b")
+			invalidated, err = tracker.Track(b3)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(invalidated)).To(Equal(0))
+		})
+
+		It("Invalidates the height below a payload which does not link to it", func() {
+			b4 := convertedPayload(4, common.HexToHash("0xb3"), "b")
+			invalidated, err := tracker.Track(b4)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(invalidated).To(Equal([]int64{3}))
+		})
+	})
+
+	Describe("Forget", func() {
+		It("Does not invalidate forgotten heights again when their replacements are tracked", func() {
+			tracker.Forget([]int64{2, 3})
+			b2 := convertedPayload(2, a1.Block.Hash(), "b")
+			invalidated, err := tracker.Track(b2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(invalidated)).To(Equal(0))
+			b3 := convertedPayload(3, b2.Block.Hash(), "b")
+			invalidated, err = tracker.Track(b3)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(invalidated)).To(Equal(0))
+		})
+	})
+})
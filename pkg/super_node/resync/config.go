@@ -18,12 +18,18 @@ package resync
 
 import (
 	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/spf13/viper"
 
 	"github.com/vulcanize/vulcanizedb/pkg/config"
 	"github.com/vulcanize/vulcanizedb/pkg/eth/core"
+	"github.com/vulcanize/vulcanizedb/pkg/ipfs"
 	"github.com/vulcanize/vulcanizedb/pkg/postgres"
 	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
 	"github.com/vulcanize/vulcanizedb/utils"
@@ -31,31 +37,44 @@ import (
 
 // Env variables
 const (
-	RESYNC_CHAIN            = "RESYNC_CHAIN"
-	RESYNC_START            = "RESYNC_START"
-	RESYNC_STOP             = "RESYNC_STOP"
-	RESYNC_BATCH_SIZE       = "RESYNC_BATCH_SIZE"
-	RESYNC_BATCH_NUMBER     = "RESYNC_BATCH_NUMBER"
-	RESYNC_CLEAR_OLD_CACHE  = "RESYNC_CLEAR_OLD_CACHE"
-	RESYNC_TYPE             = "RESYNC_TYPE"
-	RESYNC_RESET_VALIDATION = "RESYNC_RESET_VALIDATION"
+	RESYNC_CHAIN             = "RESYNC_CHAIN"
+	RESYNC_START             = "RESYNC_START"
+	RESYNC_STOP              = "RESYNC_STOP"
+	RESYNC_BATCH_SIZE        = "RESYNC_BATCH_SIZE"
+	RESYNC_BATCH_NUMBER      = "RESYNC_BATCH_NUMBER"
+	RESYNC_CLEAR_OLD_CACHE   = "RESYNC_CLEAR_OLD_CACHE"
+	RESYNC_TYPE              = "RESYNC_TYPE"
+	RESYNC_RESET_VALIDATION  = "RESYNC_RESET_VALIDATION"
+	RESYNC_BULK_INDEX        = "RESYNC_BULK_INDEX"
+	RESYNC_DETACH_PARTITIONS = "RESYNC_DETACH_PARTITIONS"
+	RESYNC_RANGES            = "RESYNC_RANGES"
+	RESYNC_RANGES_FILE       = "RESYNC_RANGES_FILE"
+	RESYNC_RESUME            = "RESYNC_RESUME"
+	RESYNC_REPORT            = "RESYNC_REPORT"
 )
 
 // Config holds the parameters needed to perform a resync
 type Config struct {
-	Chain           shared.ChainType // The type of resync to perform
-	ResyncType      shared.DataType  // The type of data to resync
-	ClearOldCache   bool             // Resync will first clear all the data within the range
-	ResetValidation bool             // If true, resync will reset the validation level to 0 for the given range
+	Chain            shared.ChainType // The type of resync to perform
+	ResyncType       shared.DataType  // The type of data to resync
+	ClearOldCache    bool             // Resync will first clear all the data within the range
+	ResetValidation  bool             // If true, resync will reset the validation level to 0 for the given range
+	BulkIndex        bool             // If true, resync will publish and index each batch of blocks together with COPY
+	DetachPartitions bool             // If true, clearing the old cache detaches the partitions within the range rather than dropping them
+	Resume           bool             // If true, resync will first resume the unfinished resync jobs of the chain
+	Report           bool             // If true, resync will only report the progress of the resync jobs of the chain
 
 	// DB info
 	DB       *postgres.DB
 	DBConfig config.Database
 	IPFSPath string
 	IPFSMode shared.IPFSMode
+	// Settings for the client of the remote ipfs node, used in the remote ipfs mode
+	IPFSRemoteConfig ipfs.RemoteClientConfig
 
 	HTTPClient  interface{}   // Note this client is expected to support the retrieval of the specified data type(s)
 	NodeInfo    core.Node     // Info for the associated node
+	ChainConfig interface{}   // Chain config (*params.ChainConfig or *chaincfg.Params) selected by network id or genesis file
 	Ranges      [][2]uint64   // The block height ranges to resync
 	BatchSize   uint64        // BatchSize for the resync http calls (client has to support batch sizing)
 	Timeout     time.Duration // HTTP connection timeout in seconds
@@ -69,6 +88,10 @@ func NewReSyncConfig() (*Config, error) {
 
 	viper.BindEnv("resync.start", RESYNC_START)
 	viper.BindEnv("resync.stop", RESYNC_STOP)
+	viper.BindEnv("resync.ranges", RESYNC_RANGES)
+	viper.BindEnv("resync.rangesFile", RESYNC_RANGES_FILE)
+	viper.BindEnv("resync.resume", RESYNC_RESUME)
+	viper.BindEnv("resync.report", RESYNC_REPORT)
 	viper.BindEnv("resync.clearOldCache", RESYNC_CLEAR_OLD_CACHE)
 	viper.BindEnv("resync.type", RESYNC_TYPE)
 	viper.BindEnv("resync.chain", RESYNC_CHAIN)
@@ -77,6 +100,8 @@ func NewReSyncConfig() (*Config, error) {
 	viper.BindEnv("resync.batchSize", RESYNC_BATCH_SIZE)
 	viper.BindEnv("resync.batchNumber", RESYNC_BATCH_NUMBER)
 	viper.BindEnv("resync.resetValidation", RESYNC_RESET_VALIDATION)
+	viper.BindEnv("resync.bulkIndex", RESYNC_BULK_INDEX)
+	viper.BindEnv("resync.detachPartitions", RESYNC_DETACH_PARTITIONS)
 	viper.BindEnv("resync.timeout", shared.HTTP_TIMEOUT)
 
 	timeout := viper.GetInt("resync.timeout")
@@ -85,21 +110,48 @@ func NewReSyncConfig() (*Config, error) {
 	}
 	c.Timeout = time.Second * time.Duration(timeout)
 
-	start := uint64(viper.GetInt64("resync.start"))
-	stop := uint64(viper.GetInt64("resync.stop"))
-	c.Ranges = [][2]uint64{{start, stop}}
+	c.Ranges, err = ParseRanges(viper.GetStringSlice("resync.ranges"))
+	if err != nil {
+		return nil, err
+	}
+	if rangesFile := viper.GetString("resync.rangesFile"); rangesFile != "" {
+		fileRanges, err := readRangesFile(rangesFile)
+		if err != nil {
+			return nil, err
+		}
+		c.Ranges = append(c.Ranges, fileRanges...)
+	}
+	if viper.IsSet("resync.start") || viper.IsSet("resync.stop") {
+		start := uint64(viper.GetInt64("resync.start"))
+		stop := uint64(viper.GetInt64("resync.stop"))
+		if stop < start {
+			return nil, fmt.Errorf("resync range ending block number %d needs to be greater than the starting block number %d", stop, start)
+		}
+		c.Ranges = append(c.Ranges, [2]uint64{start, stop})
+	}
+	c.Ranges = mergeRanges(c.Ranges)
+	c.Resume = viper.GetBool("resync.resume")
+	c.Report = viper.GetBool("resync.report")
 	c.ClearOldCache = viper.GetBool("resync.clearOldCache")
 	c.ResetValidation = viper.GetBool("resync.resetValidation")
+	c.BulkIndex = viper.GetBool("resync.bulkIndex")
+	c.DetachPartitions = viper.GetBool("resync.detachPartitions")
 
 	c.IPFSMode, err = shared.GetIPFSMode()
 	if err != nil {
 		return nil, err
 	}
-	if c.IPFSMode == shared.LocalInterface || c.IPFSMode == shared.RemoteClient {
+	switch c.IPFSMode {
+	case shared.LocalInterface:
 		c.IPFSPath, err = shared.GetIPFSPath()
 		if err != nil {
 			return nil, err
 		}
+	case shared.RemoteClient:
+		c.IPFSRemoteConfig, err = shared.GetIPFSRemoteConfig()
+		if err != nil {
+			return nil, err
+		}
 	}
 	resyncType := viper.GetString("resync.type")
 	c.ResyncType, err = shared.GenerateDataTypeFromString(resyncType)
@@ -111,6 +163,10 @@ func NewReSyncConfig() (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	c.ChainConfig, err = shared.GetChainConfig(c.Chain)
+	if err != nil {
+		return nil, err
+	}
 	if ok, err := shared.SupportedDataType(c.ResyncType, c.Chain); !ok {
 		if err != nil {
 			return nil, err
@@ -125,7 +181,7 @@ func NewReSyncConfig() (*Config, error) {
 		if err != nil {
 			return nil, err
 		}
-	case shared.Bitcoin:
+	case shared.Bitcoin, shared.Omni:
 		btcHTTP := viper.GetString("bitcoin.httpPath")
 		c.NodeInfo, c.HTTPClient = shared.GetBtcNodeAndClient(btcHTTP)
 	}
@@ -138,3 +194,70 @@ func NewReSyncConfig() (*Config, error) {
 	c.BatchNumber = uint64(viper.GetInt64("resync.batchNumber"))
 	return c, nil
 }
+
+// ParseRanges parses block height ranges written as "start-stop"
+// Each string can hold several ranges separated by commas or whitespace
+// The ranges are returned sorted, with overlapping and adjacent ranges merged so that no height is resynced twice
+func ParseRanges(strs []string) ([][2]uint64, error) {
+	ranges := make([][2]uint64, 0)
+	for _, str := range strs {
+		for _, rngStr := range strings.FieldsFunc(str, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+			bounds := strings.Split(rngStr, "-")
+			if len(bounds) != 2 {
+				return nil, fmt.Errorf("invalid resync range %s, expected start-stop", rngStr)
+			}
+			start, err := strconv.ParseUint(bounds[0], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid resync range %s: %v", rngStr, err)
+			}
+			stop, err := strconv.ParseUint(bounds[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid resync range %s: %v", rngStr, err)
+			}
+			if stop < start {
+				return nil, fmt.Errorf("invalid resync range %s, ending block number needs to be greater than the starting block number", rngStr)
+			}
+			ranges = append(ranges, [2]uint64{start, stop})
+		}
+	}
+	return mergeRanges(ranges), nil
+}
+
+// mergeRanges sorts the ranges and merges those that overlap or are adjacent
+func mergeRanges(ranges [][2]uint64) [][2]uint64 {
+	if len(ranges) == 0 {
+		return ranges
+	}
+	sorted := make([][2]uint64, len(ranges))
+	copy(sorted, ranges)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i][0] < sorted[j][0] })
+	merged := [][2]uint64{sorted[0]}
+	for _, rng := range sorted[1:] {
+		last := &merged[len(merged)-1]
+		if rng[0] > last[1] && rng[0]-last[1] > 1 {
+			merged = append(merged, rng)
+			continue
+		}
+		if rng[1] > last[1] {
+			last[1] = rng[1]
+		}
+	}
+	return merged
+}
+
+// readRangesFile reads the ranges listed in a file, skipping blank lines and lines starting with #
+func readRangesFile(path string) ([][2]uint64, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]string, 0)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return ParseRanges(lines)
+}
@@ -0,0 +1,53 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package resync_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/resync"
+)
+
+var _ = Describe("ParseRanges", func() {
+	It("Parses ranges given separately or separated by commas and whitespace", func() {
+		ranges, err := resync.ParseRanges([]string{"0-100", "200-300,400-400", " 500-600 700-800 "})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ranges).To(Equal([][2]uint64{{0, 100}, {200, 300}, {400, 400}, {500, 600}, {700, 800}}))
+	})
+
+	It("Sorts the ranges and merges those that overlap or are adjacent", func() {
+		ranges, err := resync.ParseRanges([]string{"500-600", "0-100,50-150", "151-200", "550-560", "300-400"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ranges).To(Equal([][2]uint64{{0, 200}, {300, 400}, {500, 600}}))
+	})
+
+	It("Returns no ranges when none are given", func() {
+		ranges, err := resync.ParseRanges(nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(ranges)).To(Equal(0))
+	})
+
+	It("Errors on malformed ranges", func() {
+		_, err := resync.ParseRanges([]string{"100"})
+		Expect(err).To(HaveOccurred())
+		_, err = resync.ParseRanges([]string{"a-100"})
+		Expect(err).To(HaveOccurred())
+		_, err = resync.ParseRanges([]string{"100-0"})
+		Expect(err).To(HaveOccurred())
+	})
+})
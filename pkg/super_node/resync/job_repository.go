@@ -0,0 +1,288 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package resync
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	utils "github.com/vulcanize/vulcanizedb/libraries/shared/utilities"
+	"github.com/vulcanize/vulcanizedb/pkg/postgres"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+// Job and batch statuses
+const (
+	PendingStatus  = "pending"
+	RunningStatus  = "running"
+	CompleteStatus = "complete"
+	FailedStatus   = "failed"
+)
+
+// Job is a resync job persisted in public.resync_jobs
+type Job struct {
+	ID              int64       `db:"id"`
+	Chain           string      `db:"chain"`
+	DataType        string      `db:"data_type"`
+	ClearOldCache   bool        `db:"clear_old_cache"`
+	ResetValidation bool        `db:"reset_validation"`
+	Prepared        bool        `db:"prepared"` // whether the old data has been cleared and the validation level reset
+	Status          string      `db:"status"`
+	CreatedAt       time.Time   `db:"created_at"`
+	UpdatedAt       time.Time   `db:"updated_at"`
+	Ranges          [][2]uint64 `db:"-"`
+}
+
+// Batch is a batch of a resync job persisted in public.resync_batches
+type Batch struct {
+	ID          int64          `db:"id"`
+	JobID       int64          `db:"job_id"`
+	Start       uint64         `db:"start_block"`
+	Stop        uint64         `db:"stop_block"`
+	Status      string         `db:"status"`
+	Error       sql.NullString `db:"error"`
+	CompletedAt *time.Time     `db:"completed_at"`
+}
+
+// Heights returns the block heights of the batch
+func (b Batch) Heights() []uint64 {
+	heights := make([]uint64, 0, b.Stop-b.Start+1)
+	for i := b.Start; i <= b.Stop; i++ {
+		heights = append(heights, i)
+	}
+	return heights
+}
+
+// Progress reports the number of batches of a resync job in each state
+type Progress struct {
+	Total    int `db:"total"`
+	Complete int `db:"complete"`
+	Failed   int `db:"failed"`
+}
+
+// String summarizes the progress of a job
+func (p Progress) String() string {
+	return fmt.Sprintf("%d of %d batches complete, %d failed", p.Complete, p.Total, p.Failed)
+}
+
+// JobRepository persists resync jobs and the progress of their batches in Postgres
+type JobRepository struct {
+	db *postgres.DB
+}
+
+// NewJobRepository creates a pointer to a new JobRepository
+func NewJobRepository(db *postgres.DB) *JobRepository {
+	return &JobRepository{
+		db: db,
+	}
+}
+
+// CreateJob persists the job along with its ranges, broken up into batches of the given size, and sets its ID
+// If an unfinished job of the chain with the same data type and ranges already exists, no job is created; the job is set to the
+// existing one instead and false is returned
+func (jr *JobRepository) CreateJob(job *Job, batchSize uint64) (created bool, err error) {
+	tx, err := jr.db.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+	// serialize job creation so that concurrent resyncs of the same ranges cannot both create a job
+	if _, err = tx.Exec(`SELECT pg_advisory_xact_lock(hashtext('public.resync_jobs'))`); err != nil {
+		return false, err
+	}
+	var existing Job
+	err = tx.Get(&existing, `SELECT * FROM public.resync_jobs
+								WHERE chain = $1 AND data_type = $2 AND status != $3
+								AND (SELECT string_agg(start_block || '-' || stop_block, ',' ORDER BY id)
+									FROM public.resync_ranges WHERE job_id = resync_jobs.id) = $4
+								ORDER BY id LIMIT 1`,
+		job.Chain, job.DataType, CompleteStatus, rangesKey(job.Ranges))
+	if err == nil {
+		existing.Ranges = job.Ranges
+		*job = existing
+		return false, nil
+	}
+	if err != sql.ErrNoRows {
+		return false, err
+	}
+	err = tx.QueryRowx(`INSERT INTO public.resync_jobs (chain, data_type, clear_old_cache, reset_validation, status)
+							VALUES ($1, $2, $3, $4, $5)
+							RETURNING id, status, created_at, updated_at`,
+		job.Chain, job.DataType, job.ClearOldCache, job.ResetValidation, PendingStatus).Scan(&job.ID, &job.Status, &job.CreatedAt, &job.UpdatedAt)
+	if err != nil {
+		return false, err
+	}
+	for _, rng := range job.Ranges {
+		if _, err = tx.Exec(`INSERT INTO public.resync_ranges (job_id, start_block, stop_block) VALUES ($1, $2, $3)`,
+			job.ID, rng[0], rng[1]); err != nil {
+			return false, err
+		}
+		var bins [][]uint64
+		bins, err = utils.GetBlockHeightBins(rng[0], rng[1], batchSize)
+		if err != nil {
+			return false, err
+		}
+		for _, heights := range bins {
+			if _, err = tx.Exec(`INSERT INTO public.resync_batches (job_id, start_block, stop_block) VALUES ($1, $2, $3)
+									ON CONFLICT (job_id, start_block) DO NOTHING`,
+				job.ID, heights[0], heights[len(heights)-1]); err != nil {
+				return false, err
+			}
+		}
+	}
+	return true, nil
+}
+
+// rangesKey joins the ranges the way CreateJob aggregates the ranges of a persisted job, to compare them
+func rangesKey(ranges [][2]uint64) string {
+	keys := make([]string, len(ranges))
+	for i, rng := range ranges {
+		keys[i] = fmt.Sprintf("%d-%d", rng[0], rng[1])
+	}
+	return strings.Join(keys, ",")
+}
+
+// JobClaim is a claim on a resync job, which keeps other resyncs from running the job at the same time
+// It is held as a Postgres advisory lock on a dedicated connection, so it is released if the process holding it dies
+type JobClaim struct {
+	conn  *sql.Conn
+	jobID int64
+}
+
+// ClaimJob tries to claim the job, it returns false if the job has already been claimed by another resync
+func (jr *JobRepository) ClaimJob(jobID int64) (*JobClaim, bool, error) {
+	ctx := context.Background()
+	conn, err := jr.db.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	var claimed bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock(hashtext('public.resync_jobs'), $1)`, jobID).Scan(&claimed); err != nil {
+		conn.Close()
+		return nil, false, err
+	}
+	if !claimed {
+		conn.Close()
+		return nil, false, nil
+	}
+	return &JobClaim{conn: conn, jobID: jobID}, true, nil
+}
+
+// Release releases the claim so that the job can be claimed by other resyncs
+func (c *JobClaim) Release() error {
+	defer c.conn.Close()
+	_, err := c.conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock(hashtext('public.resync_jobs'), $1)`, c.jobID)
+	return err
+}
+
+// Jobs returns the resync jobs of the chain, oldest first
+func (jr *JobRepository) Jobs(chain shared.ChainType) ([]Job, error) {
+	return jr.jobs(`SELECT * FROM public.resync_jobs WHERE chain = $1 ORDER BY id`, chain.String())
+}
+
+// Job returns the resync job with the provided ID
+func (jr *JobRepository) Job(jobID int64) (Job, error) {
+	jobs, err := jr.jobs(`SELECT * FROM public.resync_jobs WHERE id = $1`, jobID)
+	if err != nil {
+		return Job{}, err
+	}
+	if len(jobs) == 0 {
+		return Job{}, fmt.Errorf("resync job %d not found", jobID)
+	}
+	return jobs[0], nil
+}
+
+// UnfinishedJobs returns the resync jobs of the chain that have not completed, oldest first
+// This includes jobs being run by other resyncs, a job needs to be claimed with ClaimJob before it is run
+func (jr *JobRepository) UnfinishedJobs(chain shared.ChainType) ([]Job, error) {
+	return jr.jobs(`SELECT * FROM public.resync_jobs WHERE chain = $1 AND status != $2 ORDER BY id`, chain.String(), CompleteStatus)
+}
+
+func (jr *JobRepository) jobs(query string, args ...interface{}) ([]Job, error) {
+	jobs := make([]Job, 0)
+	if err := jr.db.Select(&jobs, query, args...); err != nil {
+		return nil, err
+	}
+	for i := range jobs {
+		ranges := make([]struct {
+			Start uint64 `db:"start_block"`
+			Stop  uint64 `db:"stop_block"`
+		}, 0)
+		if err := jr.db.Select(&ranges, `SELECT start_block, stop_block FROM public.resync_ranges WHERE job_id = $1 ORDER BY id`, jobs[i].ID); err != nil {
+			return nil, err
+		}
+		jobs[i].Ranges = make([][2]uint64, len(ranges))
+		for j, rng := range ranges {
+			jobs[i].Ranges[j] = [2]uint64{rng.Start, rng.Stop}
+		}
+	}
+	return jobs, nil
+}
+
+// UnfinishedBatches returns the batches of the job that have not completed, in order of height
+func (jr *JobRepository) UnfinishedBatches(jobID int64) ([]Batch, error) {
+	batches := make([]Batch, 0)
+	err := jr.db.Select(&batches, `SELECT * FROM public.resync_batches WHERE job_id = $1 AND status != $2 ORDER BY start_block`,
+		jobID, CompleteStatus)
+	return batches, err
+}
+
+// SetPrepared marks that the old data within the ranges of the job has been cleared and its validation level reset
+func (jr *JobRepository) SetPrepared(jobID int64) error {
+	_, err := jr.db.Exec(`UPDATE public.resync_jobs SET (prepared, updated_at) = (true, NOW()) WHERE id = $1`, jobID)
+	return err
+}
+
+// SetStatus sets the status of the job
+func (jr *JobRepository) SetStatus(jobID int64, status string) error {
+	_, err := jr.db.Exec(`UPDATE public.resync_jobs SET (status, updated_at) = ($2, NOW()) WHERE id = $1`, jobID, status)
+	return err
+}
+
+// FinishBatch marks the batch complete, or failed with the provided error, so that completed batches are skipped when the job resumes
+func (jr *JobRepository) FinishBatch(batchID int64, batchErr error) error {
+	if batchErr != nil {
+		_, err := jr.db.Exec(`UPDATE public.resync_batches SET (status, error, completed_at) = ($2, $3, NULL) WHERE id = $1`,
+			batchID, FailedStatus, batchErr.Error())
+		return err
+	}
+	_, err := jr.db.Exec(`UPDATE public.resync_batches SET (status, error, completed_at) = ($2, NULL, NOW()) WHERE id = $1`,
+		batchID, CompleteStatus)
+	return err
+}
+
+// Progress returns the number of batches of the job in total, completed, and failed
+func (jr *JobRepository) Progress(jobID int64) (Progress, error) {
+	var progress Progress
+	err := jr.db.Get(&progress, `SELECT COUNT(*) AS total,
+									COUNT(*) FILTER (WHERE status = $2) AS complete,
+									COUNT(*) FILTER (WHERE status = $3) AS failed
+									FROM public.resync_batches WHERE job_id = $1`, jobID, CompleteStatus, FailedStatus)
+	return progress, err
+}
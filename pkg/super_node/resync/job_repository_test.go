@@ -0,0 +1,192 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package resync_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/vulcanizedb/pkg/postgres"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/resync"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+var _ = Describe("JobRepository", func() {
+	var (
+		db   *postgres.DB
+		err  error
+		repo *resync.JobRepository
+		job  resync.Job
+	)
+	BeforeEach(func() {
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+		repo = resync.NewJobRepository(db)
+		job = resync.Job{
+			Chain:         shared.Ethereum.String(),
+			DataType:      shared.Full.String(),
+			ClearOldCache: true,
+			Ranges:        [][2]uint64{{0, 24}, {100, 104}},
+		}
+		created, err := repo.CreateJob(&job, 10)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(created).To(BeTrue())
+	})
+	AfterEach(func() {
+		_, err := db.Exec(`DELETE FROM public.resync_jobs`)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Persists a job with its ranges broken up into batches", func() {
+		jobs, err := repo.Jobs(shared.Ethereum)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(jobs)).To(Equal(1))
+		Expect(jobs[0].ID).To(Equal(job.ID))
+		Expect(jobs[0].DataType).To(Equal("full"))
+		Expect(jobs[0].ClearOldCache).To(BeTrue())
+		Expect(jobs[0].Prepared).To(BeFalse())
+		Expect(jobs[0].Status).To(Equal(resync.PendingStatus))
+		Expect(jobs[0].Ranges).To(Equal(job.Ranges))
+		batches, err := repo.UnfinishedBatches(job.ID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(batches)).To(Equal(4))
+		Expect(batches[0].Heights()).To(Equal([]uint64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}))
+		Expect(batches[2].Heights()).To(Equal([]uint64{20, 21, 22, 23, 24}))
+		Expect(batches[3].Heights()).To(Equal([]uint64{100, 101, 102, 103, 104}))
+		otherJobs, err := repo.Jobs(shared.Bitcoin)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(otherJobs)).To(Equal(0))
+	})
+
+	It("Skips completed batches and records the errors of failed ones", func() {
+		batches, err := repo.UnfinishedBatches(job.ID)
+		Expect(err).ToNot(HaveOccurred())
+		err = repo.FinishBatch(batches[0].ID, nil)
+		Expect(err).ToNot(HaveOccurred())
+		err = repo.FinishBatch(batches[1].ID, errors.New("fetcher error"))
+		Expect(err).ToNot(HaveOccurred())
+		progress, err := repo.Progress(job.ID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(progress).To(Equal(resync.Progress{Total: 4, Complete: 1, Failed: 1}))
+		unfinished, err := repo.UnfinishedBatches(job.ID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(unfinished)).To(Equal(3))
+		Expect(unfinished[0].ID).To(Equal(batches[1].ID))
+		Expect(unfinished[0].Status).To(Equal(resync.FailedStatus))
+		Expect(unfinished[0].Error.String).To(Equal("fetcher error"))
+
+		err = repo.FinishBatch(batches[1].ID, nil)
+		Expect(err).ToNot(HaveOccurred())
+		progress, err = repo.Progress(job.ID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(progress).To(Equal(resync.Progress{Total: 4, Complete: 2, Failed: 0}))
+	})
+
+	It("Only returns the jobs that have not completed as unfinished", func() {
+		err = repo.SetPrepared(job.ID)
+		Expect(err).ToNot(HaveOccurred())
+		err = repo.SetStatus(job.ID, resync.FailedStatus)
+		Expect(err).ToNot(HaveOccurred())
+		unfinished, err := repo.UnfinishedJobs(shared.Ethereum)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(unfinished)).To(Equal(1))
+		Expect(unfinished[0].Prepared).To(BeTrue())
+		Expect(unfinished[0].Status).To(Equal(resync.FailedStatus))
+
+		err = repo.SetStatus(job.ID, resync.CompleteStatus)
+		Expect(err).ToNot(HaveOccurred())
+		unfinished, err = repo.UnfinishedJobs(shared.Ethereum)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(unfinished)).To(Equal(0))
+	})
+
+	It("Does not create a job for the ranges of an unfinished job of the same chain and data type", func() {
+		duplicate := resync.Job{
+			Chain:    shared.Ethereum.String(),
+			DataType: shared.Full.String(),
+			Ranges:   [][2]uint64{{0, 24}, {100, 104}},
+		}
+		created, err := repo.CreateJob(&duplicate, 10)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(created).To(BeFalse())
+		Expect(duplicate.ID).To(Equal(job.ID))
+		Expect(duplicate.ClearOldCache).To(BeTrue())
+		Expect(duplicate.Ranges).To(Equal(job.Ranges))
+
+		otherRanges := resync.Job{
+			Chain:    shared.Ethereum.String(),
+			DataType: shared.Full.String(),
+			Ranges:   [][2]uint64{{0, 24}},
+		}
+		created, err = repo.CreateJob(&otherRanges, 10)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(created).To(BeTrue())
+		otherType := resync.Job{
+			Chain:    shared.Ethereum.String(),
+			DataType: shared.State.String(),
+			Ranges:   [][2]uint64{{0, 24}, {100, 104}},
+		}
+		created, err = repo.CreateJob(&otherType, 10)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(created).To(BeTrue())
+
+		err = repo.SetStatus(job.ID, resync.CompleteStatus)
+		Expect(err).ToNot(HaveOccurred())
+		rerun := resync.Job{
+			Chain:    shared.Ethereum.String(),
+			DataType: shared.Full.String(),
+			Ranges:   [][2]uint64{{0, 24}, {100, 104}},
+		}
+		created, err = repo.CreateJob(&rerun, 10)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(created).To(BeTrue())
+		Expect(rerun.ID).ToNot(Equal(job.ID))
+		jobs, err := repo.Jobs(shared.Ethereum)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(jobs)).To(Equal(4))
+	})
+
+	It("Lets a job be claimed by only one resync at a time", func() {
+		claim, claimed, err := repo.ClaimJob(job.ID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(claimed).To(BeTrue())
+		_, claimed, err = resync.NewJobRepository(db).ClaimJob(job.ID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(claimed).To(BeFalse())
+
+		err = claim.Release()
+		Expect(err).ToNot(HaveOccurred())
+		claim, claimed, err = resync.NewJobRepository(db).ClaimJob(job.ID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(claimed).To(BeTrue())
+		err = claim.Release()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Reloads a job by its ID", func() {
+		err = repo.SetPrepared(job.ID)
+		Expect(err).ToNot(HaveOccurred())
+		reloaded, err := repo.Job(job.ID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reloaded.Prepared).To(BeTrue())
+		Expect(reloaded.Ranges).To(Equal(job.Ranges))
+		_, err = repo.Job(job.ID + 1)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,92 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package resync
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/vulcanizedb/pkg/super_node"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+// Launcher launches resyncs in the background of a running super node
+// It satisfies the super_node.Resyncer interface
+type Launcher struct {
+	settings *super_node.Config
+}
+
+// NewLauncher creates a new Launcher which resyncs data using the backfill settings of the provided super node config
+func NewLauncher(settings *super_node.Config) (*Launcher, error) {
+	if settings.HTTPClient == nil || settings.BackFillDBConn == nil {
+		return nil, errors.New("resyncing requires the super node backfill settings")
+	}
+	return &Launcher{
+		settings: settings,
+	}, nil
+}
+
+// Launch validates the resync parameters and starts the resync in the background
+func (l *Launcher) Launch(params super_node.ResyncParams) error {
+	if params.Stop < params.Start {
+		return fmt.Errorf("%s resync range ending block number needs to be greater than the starting block number", l.settings.Chain.String())
+	}
+	resyncType, err := shared.GenerateDataTypeFromString(params.Type)
+	if err != nil {
+		return err
+	}
+	if ok, err := shared.SupportedDataType(resyncType, l.settings.Chain); !ok {
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("chain type %s does not support data type %s", l.settings.Chain.String(), resyncType.String())
+	}
+	rService, err := NewResyncService(&Config{
+		Chain:            l.settings.Chain,
+		ResyncType:       resyncType,
+		ClearOldCache:    params.ClearOldCache,
+		ResetValidation:  params.ResetValidation,
+		DetachPartitions: params.DetachPartitions,
+		BulkIndex:        l.settings.BulkIndex,
+		DB:               l.settings.BackFillDBConn,
+		DBConfig:         l.settings.DBConfig,
+		IPFSPath:         l.settings.IPFSPath,
+		IPFSRemoteConfig: l.settings.IPFSRemoteConfig,
+		IPFSMode:         l.settings.IPFSMode,
+		HTTPClient:       l.settings.HTTPClient,
+		NodeInfo:         l.settings.NodeInfo,
+		ChainConfig:      l.settings.ChainConfig,
+		Ranges:           [][2]uint64{{params.Start, params.Stop}},
+		BatchSize:        l.settings.BatchSize,
+		BatchNumber:      l.settings.BatchNumber,
+		Timeout:          l.settings.Timeout,
+	})
+	if err != nil {
+		return err
+	}
+	go func() {
+		logrus.Infof("resyncing %s %s data from %d to %d", l.settings.Chain.String(), resyncType.String(), params.Start, params.Stop)
+		if err := rService.Resync(); err != nil {
+			logrus.Errorf("%s %s resync error: %v", l.settings.Chain.String(), resyncType.String(), err)
+			return
+		}
+		logrus.Infof("%s %s resync from %d to %d finished", l.settings.Chain.String(), resyncType.String(), params.Start, params.Stop)
+	}()
+	return nil
+}
@@ -17,10 +17,13 @@
 package resync
 
 import (
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
+
 	"github.com/sirupsen/logrus"
 
-	utils "github.com/vulcanize/vulcanizedb/libraries/shared/utilities"
 	"github.com/vulcanize/vulcanizedb/pkg/super_node"
 	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
 )
@@ -36,12 +39,16 @@ type Service struct {
 	Publisher shared.IPLDPublisher
 	// Interface for indexing the CIDs of the published IPLDs in Postgres
 	Indexer shared.CIDIndexer
+	// Interface for publishing and indexing each batch of IPLD payloads together, used in place of the Publisher and Indexer if set
+	BulkIndexer shared.BulkPublisherAndIndexer
 	// Interface for searching and retrieving CIDs from Postgres index
 	Retriever shared.CIDRetriever
 	// Interface for fetching payloads over at historical blocks; over http
 	Fetcher shared.PayloadFetcher
 	// Interface for cleaning out data before resyncing (if clearOldCache is on)
 	Cleaner shared.Cleaner
+	// Repository persisting the resync jobs and the progress of their batches
+	Jobs *JobRepository
 	// Size of batch fetches
 	BatchSize uint64
 	// Number of goroutines
@@ -58,11 +65,19 @@ type Service struct {
 	clearOldCache bool
 	// Flag to turn on or off validation level reset
 	resetValidation bool
+	// Flag to turn on or off resuming the unfinished jobs of the chain
+	resume bool
+}
+
+// batch is a batch of heights to resync, along with the WaitGroup of the job it belongs to
+type batch struct {
+	Batch
+	wg *sync.WaitGroup
 }
 
 // NewResyncService creates and returns a resync service from the provided settings
 func NewResyncService(settings *Config) (Resync, error) {
-	publisher, err := super_node.NewIPLDPublisher(settings.Chain, settings.IPFSPath, settings.DB, settings.IPFSMode)
+	publisher, err := super_node.NewIPLDPublisher(settings.Chain, settings.IPFSPath, settings.IPFSRemoteConfig, settings.DB, settings.IPFSMode)
 	if err != nil {
 		return nil, err
 	}
@@ -70,7 +85,7 @@ func NewResyncService(settings *Config) (Resync, error) {
 	if err != nil {
 		return nil, err
 	}
-	converter, err := super_node.NewPayloadConverter(settings.Chain)
+	converter, err := super_node.NewPayloadConverter(settings.Chain, settings.ChainConfig, settings.HTTPClient, settings.Timeout)
 	if err != nil {
 		return nil, err
 	}
@@ -82,10 +97,17 @@ func NewResyncService(settings *Config) (Resync, error) {
 	if err != nil {
 		return nil, err
 	}
-	cleaner, err := super_node.NewCleaner(settings.Chain, settings.DB)
+	cleaner, err := super_node.NewCleaner(settings.Chain, settings.DB, settings.DetachPartitions)
 	if err != nil {
 		return nil, err
 	}
+	var bulkIndexer shared.BulkPublisherAndIndexer
+	if settings.BulkIndex {
+		bulkIndexer, err = super_node.NewBulkPublisherAndIndexer(settings.Chain, settings.DB, settings.IPFSMode)
+		if err != nil {
+			return nil, err
+		}
+	}
 	batchSize := settings.BatchSize
 	if batchSize == 0 {
 		batchSize = super_node.DefaultMaxBatchSize
@@ -96,11 +118,13 @@ func NewResyncService(settings *Config) (Resync, error) {
 	}
 	return &Service{
 		Indexer:         indexer,
+		BulkIndexer:     bulkIndexer,
 		Converter:       converter,
 		Publisher:       publisher,
 		Retriever:       retriever,
 		Fetcher:         fetcher,
 		Cleaner:         cleaner,
+		Jobs:            NewJobRepository(settings.DB),
 		BatchSize:       batchSize,
 		BatchNumber:     int64(batchNumber),
 		quitChan:        make(chan bool),
@@ -109,76 +133,235 @@ func NewResyncService(settings *Config) (Resync, error) {
 		data:            settings.ResyncType,
 		clearOldCache:   settings.ClearOldCache,
 		resetValidation: settings.ResetValidation,
+		resume:          settings.Resume,
 	}, nil
 }
 
+// Resync resumes the unfinished jobs of the chain, if resuming is on, and then runs a new job for the configured ranges
+// Every job is persisted along with the progress of its batches, so a job that is interrupted can be resumed later
+// without resyncing the batches it has already completed
+// Each job is claimed before it is run, and jobs claimed by another resync are skipped, so that concurrent resyncs do not run the same batches
 func (rs *Service) Resync() error {
-	if rs.resetValidation {
-		logrus.Infof("resetting validation level")
-		if err := rs.Cleaner.ResetValidation(rs.ranges); err != nil {
-			return fmt.Errorf("validation reset failed: %v", err)
+	jobs := make([]Job, 0)
+	if rs.resume {
+		unfinished, err := rs.Jobs.UnfinishedJobs(rs.chain)
+		if err != nil {
+			return err
 		}
+		jobs = append(jobs, unfinished...)
 	}
-	if rs.clearOldCache {
-		logrus.Infof("cleaning out old data from Postgres")
-		if err := rs.Cleaner.Clean(rs.ranges, rs.data); err != nil {
-			return fmt.Errorf("%s %s data resync cleaning error: %v", rs.chain.String(), rs.data.String(), err)
+	if len(rs.ranges) > 0 {
+		job := Job{
+			Chain:           rs.chain.String(),
+			DataType:        rs.data.String(),
+			ClearOldCache:   rs.clearOldCache,
+			ResetValidation: rs.resetValidation,
+			Ranges:          rs.ranges,
+		}
+		created, err := rs.Jobs.CreateJob(&job, rs.BatchSize)
+		if err != nil {
+			return err
+		}
+		if created {
+			logrus.Infof("created %s %s resync job %d", rs.chain.String(), job.DataType, job.ID)
+			jobs = append(jobs, job)
+		} else {
+			logrus.Infof("%s %s resync job %d for the same ranges has not finished, running it instead of creating a new job", rs.chain.String(), job.DataType, job.ID)
+			if !containsJob(jobs, job.ID) {
+				jobs = append(jobs, job)
+			}
 		}
 	}
+	if len(jobs) == 0 {
+		logrus.Infof("no %s resync jobs to run", rs.chain.String())
+		return nil
+	}
+
 	// spin up worker goroutines
-	heightsChan := make(chan []uint64)
+	batchChan := make(chan batch)
 	for i := 1; i <= int(rs.BatchNumber); i++ {
-		go rs.resync(i, heightsChan)
+		go rs.resync(i, batchChan)
 	}
-	for _, rng := range rs.ranges {
-		if rng[1] < rng[0] {
-			logrus.Errorf("%s resync range ending block number needs to be greater than the starting block number", rs.chain.String())
+	// send a quit signal to each worker once the jobs are done
+	// this blocks until each worker has finished its current task and can receive from the quit channel
+	defer func() {
+		for i := 1; i <= int(rs.BatchNumber); i++ {
+			rs.quitChan <- true
+		}
+	}()
+	failed := make([]string, 0)
+	for _, job := range jobs {
+		claim, claimed, err := rs.Jobs.ClaimJob(job.ID)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			logrus.Infof("%s %s resync job %d is being run by another resync, skipping it", rs.chain.String(), job.DataType, job.ID)
 			continue
 		}
-		logrus.Infof("resyncing %s data from %d to %d", rs.chain.String(), rng[0], rng[1])
-		// break the range up into bins of smaller ranges
-		blockRangeBins, err := utils.GetBlockHeightBins(rng[0], rng[1], rs.BatchSize)
+		status, err := rs.runClaimedJob(job.ID, batchChan)
+		if releaseErr := claim.Release(); releaseErr != nil {
+			logrus.Errorf("%s resync job %d error releasing claim: %v", rs.chain.String(), job.ID, releaseErr)
+		}
 		if err != nil {
 			return err
 		}
-		for _, heights := range blockRangeBins {
-			heightsChan <- heights
+		if status == FailedStatus {
+			failed = append(failed, fmt.Sprintf("%d", job.ID))
 		}
 	}
-	// send a quit signal to each worker
-	// this blocks until each worker has finished its current task and can receive from the quit channel
-	for i := 1; i <= int(rs.BatchNumber); i++ {
-		rs.quitChan <- true
+	if len(failed) > 0 {
+		return fmt.Errorf("%s resync jobs %s have failed batches, run the resync again to retry them", rs.chain.String(), strings.Join(failed, ", "))
 	}
 	return nil
 }
 
-func (rs *Service) resync(id int, heightChan chan []uint64) {
+// runClaimedJob runs the job and records whether all of its batches completed, it returns the status recorded
+// The job is reloaded once claimed, since another resync may have run it in the meantime
+func (rs *Service) runClaimedJob(jobID int64, batchChan chan batch) (string, error) {
+	job, err := rs.Jobs.Job(jobID)
+	if err != nil {
+		return "", err
+	}
+	if job.Status == CompleteStatus {
+		logrus.Infof("%s %s resync job %d has been completed by another resync", rs.chain.String(), job.DataType, job.ID)
+		return CompleteStatus, nil
+	}
+	if job.Status != PendingStatus {
+		logrus.Infof("resuming %s %s resync job %d", rs.chain.String(), job.DataType, job.ID)
+	}
+	if err := rs.runJob(job, batchChan); err != nil {
+		return "", err
+	}
+	progress, err := rs.Jobs.Progress(job.ID)
+	if err != nil {
+		return "", err
+	}
+	status := CompleteStatus
+	if progress.Complete < progress.Total {
+		status = FailedStatus
+	}
+	if err := rs.Jobs.SetStatus(job.ID, status); err != nil {
+		return "", err
+	}
+	logrus.Infof("%s resync job %d %s: %s", rs.chain.String(), job.ID, status, progress.String())
+	return status, nil
+}
+
+func containsJob(jobs []Job, id int64) bool {
+	for _, job := range jobs {
+		if job.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// runJob clears the old data within the ranges of the job and resets their validation level, if this has not already been done,
+// and sends the batches of the job that have not completed to the workers
+func (rs *Service) runJob(job Job, batchChan chan batch) error {
+	if err := rs.Jobs.SetStatus(job.ID, RunningStatus); err != nil {
+		return err
+	}
+	if !job.Prepared {
+		if job.ResetValidation {
+			logrus.Infof("resetting validation level")
+			if err := rs.Cleaner.ResetValidation(job.Ranges); err != nil {
+				return fmt.Errorf("validation reset failed: %v", err)
+			}
+		}
+		if job.ClearOldCache {
+			dataType, err := shared.GenerateDataTypeFromString(job.DataType)
+			if err != nil {
+				return err
+			}
+			logrus.Infof("cleaning out old data from Postgres")
+			if err := rs.Cleaner.Clean(job.Ranges, dataType); err != nil {
+				return fmt.Errorf("%s %s data resync cleaning error: %v", rs.chain.String(), job.DataType, err)
+			}
+		}
+		if err := rs.Jobs.SetPrepared(job.ID); err != nil {
+			return err
+		}
+	}
+	batches, err := rs.Jobs.UnfinishedBatches(job.ID)
+	if err != nil {
+		return err
+	}
+	for _, rng := range job.Ranges {
+		logrus.Infof("resyncing %s data from %d to %d", rs.chain.String(), rng[0], rng[1])
+	}
+	wg := new(sync.WaitGroup)
+	for _, b := range batches {
+		wg.Add(1)
+		batchChan <- batch{Batch: b, wg: wg}
+	}
+	wg.Wait()
+	return nil
+}
+
+func (rs *Service) resync(id int, batchChan chan batch) {
 	for {
 		select {
-		case heights := <-heightChan:
-			logrus.Debugf("%s resync worker %d processing section from %d to %d", rs.chain.String(), id, heights[0], heights[len(heights)-1])
-			payloads, err := rs.Fetcher.FetchAt(heights)
-			if err != nil {
-				logrus.Errorf("%s resync worker %d fetcher error: %s", rs.chain.String(), id, err.Error())
+		case b := <-batchChan:
+			logrus.Debugf("%s resync worker %d processing section from %d to %d", rs.chain.String(), id, b.Start, b.Stop)
+			batchErr := rs.resyncBatch(id, b.Heights())
+			if err := rs.Jobs.FinishBatch(b.ID, batchErr); err != nil {
+				logrus.Errorf("%s resync worker %d error recording batch progress: %s", rs.chain.String(), id, err.Error())
 			}
-			for _, payload := range payloads {
-				ipldPayload, err := rs.Converter.Convert(payload)
-				if err != nil {
-					logrus.Errorf("%s resync worker %d converter error: %s", rs.chain.String(), id, err.Error())
-				}
-				cidPayload, err := rs.Publisher.Publish(ipldPayload)
-				if err != nil {
-					logrus.Errorf("%s resync worker %d publisher error: %s", rs.chain.String(), id, err.Error())
-				}
-				if err := rs.Indexer.Index(cidPayload); err != nil {
-					logrus.Errorf("%s resync worker %d indexer error: %s", rs.chain.String(), id, err.Error())
-				}
+			if batchErr == nil {
+				logrus.Infof("%s resync worker %d finished section from %d to %d", rs.chain.String(), id, b.Start, b.Stop)
 			}
-			logrus.Infof("%s resync worker %d finished section from %d to %d", rs.chain.String(), id, heights[0], heights[len(heights)-1])
+			if progress, err := rs.Jobs.Progress(b.JobID); err == nil {
+				logrus.Infof("%s resync job %d progress: %s", rs.chain.String(), b.JobID, progress.String())
+			}
+			b.wg.Done()
 		case <-rs.quitChan:
 			logrus.Infof("%s resync worker %d goroutine shutting down", rs.chain.String(), id)
 			return
 		}
 	}
 }
+
+// resyncBatch fetches, converts, publishes, and indexes the data at the heights, and returns the errors encountered
+func (rs *Service) resyncBatch(id int, heights []uint64) error {
+	errs := make([]string, 0)
+	payloads, err := rs.Fetcher.FetchAt(heights)
+	if err != nil {
+		logrus.Errorf("%s resync worker %d fetcher error: %s", rs.chain.String(), id, err.Error())
+		return fmt.Errorf("fetcher error: %v", err)
+	}
+	converted := make([]shared.ConvertedData, 0, len(payloads))
+	for _, payload := range payloads {
+		ipldPayload, err := rs.Converter.Convert(payload)
+		if err != nil {
+			logrus.Errorf("%s resync worker %d converter error: %s", rs.chain.String(), id, err.Error())
+			errs = append(errs, fmt.Sprintf("converter error: %v", err))
+			continue
+		}
+		if rs.BulkIndexer != nil {
+			converted = append(converted, ipldPayload)
+			continue
+		}
+		cidPayload, err := rs.Publisher.Publish(ipldPayload)
+		if err != nil {
+			logrus.Errorf("%s resync worker %d publisher error: %s", rs.chain.String(), id, err.Error())
+			errs = append(errs, fmt.Sprintf("publisher error: %v", err))
+			continue
+		}
+		if err := rs.Indexer.Index(cidPayload); err != nil {
+			logrus.Errorf("%s resync worker %d indexer error: %s", rs.chain.String(), id, err.Error())
+			errs = append(errs, fmt.Sprintf("indexer error: %v", err))
+		}
+	}
+	if rs.BulkIndexer != nil && len(converted) > 0 {
+		if err := rs.BulkIndexer.PublishAndIndexBatch(converted); err != nil {
+			logrus.Errorf("%s resync worker %d bulk indexer error: %s", rs.chain.String(), id, err.Error())
+			errs = append(errs, fmt.Sprintf("bulk indexer error: %v", err))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
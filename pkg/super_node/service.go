@@ -19,6 +19,8 @@ package super_node
 import (
 	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -30,11 +32,14 @@ import (
 
 	"github.com/vulcanize/vulcanizedb/pkg/eth/core"
 	"github.com/vulcanize/vulcanizedb/pkg/postgres"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth"
 	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
 )
 
 const (
 	PayloadChanBufferSize = 2000
+	// SpillCheckFrequency is how often idle publishAndIndex workers check the spill queue for payloads to drain
+	SpillCheckFrequency = time.Second
 )
 
 // SuperNode is the top level interface for streaming, converting to IPLDs, publishing,
@@ -55,6 +60,23 @@ type SuperNode interface {
 	Node() *core.Node
 	// Method to access chain type
 	Chain() shared.ChainType
+	// Method to access the delivery counters of the current subscriptions
+	SubscriptionStats() []SubscriptionStats
+	// Method to access the total number of payloads dropped for subscriptions that fell behind
+	DroppedPayloads() uint64
+	// Method to report the health of the service
+	Health() HealthStatus
+	// Methods to attach the processes that are managed through the admin API
+	AttachBackFiller(backFiller BackFillInterface)
+	AttachResyncer(resyncer Resyncer)
+	// Admin methods for operating the running service
+	ActiveSubscriptions() []SubscriptionInfo
+	CloseSubscription(id rpc.ID) error
+	Gaps(validationLevel int) ([]shared.Gap, error)
+	ValidationLevels() ([]shared.ValidationLevel, error)
+	Resync(params ResyncParams) error
+	PauseBackFill() error
+	ResumeBackFill() error
 }
 
 // Service is the underlying struct for the super node
@@ -75,6 +97,8 @@ type Service struct {
 	IPLDFetcher shared.IPLDFetcher
 	// Interface for searching and retrieving CIDs from Postgres index
 	Retriever shared.CIDRetriever
+	// Interface for durably queueing payloads when the publishAndIndex workers fall behind
+	SpillQueue shared.SpillQueue
 	// Chan the processor uses to subscribe to payloads from the Streamer
 	PayloadChan chan shared.RawChainData
 	// Used to signal shutdown of the service
@@ -83,6 +107,14 @@ type Service struct {
 	Subscriptions map[common.Hash]map[rpc.ID]Subscription
 	// A mapping of subscription params hash to the corresponding subscription params
 	SubscriptionTypes map[common.Hash]shared.SubscriptionSettings
+	// A mapping of rpc.IDs to the delivery state of their subscription, including subscriptions to historical data only
+	deliveries map[rpc.ID]*delivery
+	// Durable queue for the payloads of subscriptions that have fallen behind
+	subscriptionQueue *SubscriptionQueue
+	// BackFill process managed through the admin API, if one is running
+	backFiller BackFillInterface
+	// Used to launch resyncs through the admin API
+	resyncer Resyncer
 	// Info for the Geth node that this super node is working with
 	NodeInfo *core.Node
 	// Number of publishAndIndex workers
@@ -91,10 +123,26 @@ type Service struct {
 	chain shared.ChainType
 	// Path to ipfs data dir
 	ipfsPath string
+	// Chain config (*params.ChainConfig or *chaincfg.Params) used by the public API
+	chainConfig interface{}
 	// Underlying db
 	db *postgres.DB
 	// wg for syncing serve processes
 	serveWg *sync.WaitGroup
+	// height of the latest payload streamed by the Sync process
+	head int64
+	// height of the latest payload indexed by the publishAndIndex workers
+	indexed int64
+	// number of payloads dropped for subscriptions that fell behind
+	dropped uint64
+	// unix nano timestamp of the latest payload streamed by the Sync process
+	lastPayload int64
+	// whether the Sync process is running
+	syncing int32
+	// unix nano timestamp of when the Sync process was started
+	syncStarted int64
+	// whether the vdb and admin APIs are registered under chain-specific namespaces
+	chainNamespaces bool
 }
 
 // NewSuperNode creates a new super_node.Interface using an underlying super_node.Service struct
@@ -107,11 +155,11 @@ func NewSuperNode(settings *Config) (SuperNode, error) {
 		if err != nil {
 			return nil, err
 		}
-		sn.Converter, err = NewPayloadConverter(settings.Chain)
+		sn.Converter, err = NewPayloadConverter(settings.Chain, settings.ChainConfig, settings.WSClient, settings.Timeout)
 		if err != nil {
 			return nil, err
 		}
-		sn.Publisher, err = NewIPLDPublisher(settings.Chain, settings.IPFSPath, settings.SyncDBConn, settings.IPFSMode)
+		sn.Publisher, err = NewIPLDPublisher(settings.Chain, settings.IPFSPath, settings.IPFSRemoteConfig, settings.SyncDBConn, settings.IPFSMode)
 		if err != nil {
 			return nil, err
 		}
@@ -123,6 +171,10 @@ func NewSuperNode(settings *Config) (SuperNode, error) {
 		if err != nil {
 			return nil, err
 		}
+		sn.SpillQueue, err = NewSpillQueue(settings.Chain, settings.SyncDBConn)
+		if err != nil {
+			return nil, err
+		}
 	}
 	// If we are serving, initialize the needed interfaces
 	if settings.Serve {
@@ -130,18 +182,22 @@ func NewSuperNode(settings *Config) (SuperNode, error) {
 		if err != nil {
 			return nil, err
 		}
-		sn.IPLDFetcher, err = NewIPLDFetcher(settings.Chain, settings.IPFSPath, settings.ServeDBConn, settings.IPFSMode)
+		sn.IPLDFetcher, err = NewIPLDFetcher(settings.Chain, settings.IPFSPath, settings.IPFSRemoteConfig, settings.ServeDBConn, settings.IPFSMode, settings.VerifyIPLDs)
 		if err != nil {
 			return nil, err
 		}
 		sn.db = settings.ServeDBConn
+		sn.subscriptionQueue = NewSubscriptionQueue(settings.ServeDBConn)
 	}
 	sn.QuitChan = make(chan bool)
 	sn.Subscriptions = make(map[common.Hash]map[rpc.ID]Subscription)
 	sn.SubscriptionTypes = make(map[common.Hash]shared.SubscriptionSettings)
+	sn.deliveries = make(map[rpc.ID]*delivery)
 	sn.WorkerPoolSize = settings.Workers
 	sn.NodeInfo = &settings.NodeInfo
 	sn.ipfsPath = settings.IPFSPath
+	sn.chainConfig = settings.ChainConfig
+	sn.chainNamespaces = settings.ChainNamespaces
 	sn.chain = settings.Chain
 	return sn, nil
 }
@@ -152,35 +208,35 @@ func (sap *Service) Protocols() []p2p.Protocol {
 }
 
 // APIs returns the RPC descriptors the super node service offers
+// The info APIs shared by every super node in a process are returned by InfoAPIs
 func (sap *Service) APIs() []rpc.API {
-	ifnoAPI := NewInfoAPI()
+	vdbNamespace, adminNamespace := APIName, "admin"
+	if sap.chainNamespaces {
+		vdbNamespace, adminNamespace = ChainNamespace(APIName, sap.chain), ChainNamespace("admin", sap.chain)
+	}
 	apis := []rpc.API{
 		{
-			Namespace: APIName,
+			Namespace: vdbNamespace,
 			Version:   APIVersion,
 			Service:   NewPublicSuperNodeAPI(sap),
 			Public:    true,
 		},
 		{
-			Namespace: "rpc",
-			Version:   APIVersion,
-			Service:   ifnoAPI,
-			Public:    true,
-		},
-		{
-			Namespace: "net",
+			Namespace: adminNamespace,
 			Version:   APIVersion,
-			Service:   ifnoAPI,
-			Public:    true,
+			Service:   NewAdminAPI(sap),
+			Public:    false,
 		},
-		{
-			Namespace: "admin",
-			Version:   APIVersion,
-			Service:   ifnoAPI,
+	}
+	if sap.chain == shared.Ethereum {
+		apis = append(apis, rpc.API{
+			Namespace: eth.APIName,
+			Version:   eth.APIVersion,
+			Service:   NewPublicEthPubSubAPI(sap),
 			Public:    true,
-		},
+		})
 	}
-	chainAPI, err := NewPublicAPI(sap.chain, sap.db, sap.ipfsPath)
+	chainAPI, err := NewPublicAPI(sap.chain, sap.db, sap.ipfsPath, sap.chainConfig)
 	if err != nil {
 		log.Error(err)
 		return apis
@@ -199,34 +255,60 @@ func (sap *Service) Sync(wg *sync.WaitGroup, screenAndServePayload chan<- shared
 	}
 	// spin up publishAndIndex worker goroutines
 	publishAndIndexPayload := make(chan shared.ConvertedData, PayloadChanBufferSize)
+	workers.WithLabelValues(sap.chain.String(), syncProcess).Set(float64(sap.WorkerPoolSize))
 	for i := 1; i <= sap.WorkerPoolSize; i++ {
 		go sap.publishAndIndex(wg, i, publishAndIndexPayload)
 		log.Debugf("%s publishAndIndex worker %d successfully spun up", sap.chain.String(), i)
 	}
+	reorgTracker := NewReorgTracker(ReorgTrackingDepth)
+	atomic.StoreInt64(&sap.syncStarted, time.Now().UnixNano())
+	atomic.StoreInt32(&sap.syncing, 1)
 	go func() {
 		wg.Add(1)
 		defer wg.Done()
+		defer atomic.StoreInt32(&sap.syncing, 0)
 		for {
 			select {
 			case payload := <-sap.PayloadChan:
+				atomic.StoreInt64(&sap.lastPayload, time.Now().UnixNano())
+				if rollback, ok := payload.(shared.RollbackSignal); ok {
+					sap.rollback(rollback, reorgTracker, screenAndServePayload)
+					continue
+				}
+				start := time.Now()
 				ipldPayload, err := sap.Converter.Convert(payload)
 				if err != nil {
 					log.Errorf("super node conversion error for chain %s: %v", sap.chain.String(), err)
 					continue
 				}
+				observeStage(sap.chain.String(), syncProcess, convertStage, start)
 				log.Infof("%s data streamed at head height %d", sap.chain.String(), ipldPayload.Height())
+				atomic.StoreInt64(&sap.head, ipldPayload.Height())
+				headHeight.WithLabelValues(sap.chain.String()).Set(float64(ipldPayload.Height()))
+				invalidated, err := reorgTracker.Track(ipldPayload)
+				if err != nil {
+					log.Errorf("super node reorg tracking error for chain %s: %v", sap.chain.String(), err)
+				}
+				// If data we have already seen is invalidated, notify the ScreenAndServe process ahead of the new data
+				if len(invalidated) > 0 {
+					log.Warnf("%s reorg detected at head height %d, invalidated heights: %v", sap.chain.String(), ipldPayload.Height(), invalidated)
+					if !sap.notifyReorg(reorgNotice{height: ipldPayload.Height(), invalidated: invalidated}, screenAndServePayload) {
+						log.Infof("quiting %s Sync process", sap.chain.String())
+						return
+					}
+				}
 				// If we have a ScreenAndServe process running, forward the iplds to it
 				select {
 				case screenAndServePayload <- ipldPayload:
 				default:
+					sap.dropServePayload(screenAndServePayload)
 				}
 				// Forward the payload to the publishAndIndex workers
-				// this channel acts as a ring buffer
+				// if they have fallen behind, spill the raw payload to the durable queue for them to drain later
 				select {
 				case publishAndIndexPayload <- ipldPayload:
 				default:
-					<-publishAndIndexPayload
-					publishAndIndexPayload <- ipldPayload
+					sap.spill(payload, ipldPayload.Height())
 				}
 			case err := <-sub.Err():
 				log.Errorf("super node subscription error for chain %s: %v", sap.chain.String(), err)
@@ -240,24 +322,83 @@ func (sap *Service) Sync(wg *sync.WaitGroup, screenAndServePayload chan<- shared
 	return nil
 }
 
+// rollback handles blocks the streamer reports as disconnected from the chain
+// they are forgotten by the reorg tracker and the ScreenAndServe process is notified of the invalidated heights
+func (sap *Service) rollback(rollback shared.RollbackSignal, reorgTracker *ReorgTracker, screenAndServePayload chan<- shared.ConvertedData) {
+	invalidated := rollback.InvalidatedHeights()
+	if len(invalidated) == 0 {
+		return
+	}
+	log.Warnf("%s blocks disconnected above height %d, invalidated heights: %v", sap.chain.String(), rollback.Height(), invalidated)
+	reorgTracker.Forget(invalidated)
+	sap.notifyReorg(reorgNotice{height: rollback.Height(), invalidated: invalidated}, screenAndServePayload)
+}
+
+// notifyReorg sends the reorg notice to the ScreenAndServe process, waiting for room in its buffer rather than dropping it,
+// since subscribers rely on it to learn which of the data they were sent has been invalidated
+// it returns false if the service quit while waiting
+func (sap *Service) notifyReorg(notice reorgNotice, screenAndServePayload chan<- shared.ConvertedData) bool {
+	if screenAndServePayload == nil {
+		return true
+	}
+	select {
+	case screenAndServePayload <- notice:
+		return true
+	case <-sap.QuitChan:
+		return false
+	}
+}
+
+// spill adds the raw payload to the spill queue
+// if the payload cannot be spilled it is dropped and its height recorded as a known gap
+func (sap *Service) spill(payload shared.RawChainData, height int64) {
+	if sap.SpillQueue == nil {
+		log.Errorf("%s publishAndIndex workers are behind and there is no spill queue, dropping payload at height %d", sap.chain.String(), height)
+		droppedPayloads.WithLabelValues(sap.chain.String(), publishAndIndexBuffer).Inc()
+		return
+	}
+	log.Warnf("%s publishAndIndex workers are behind, spilling payload at height %d", sap.chain.String(), height)
+	if err := sap.SpillQueue.Spill(payload, height); err != nil {
+		log.Errorf("%s spill queue error, dropping payload at height %d: %v", sap.chain.String(), height, err)
+		droppedPayloads.WithLabelValues(sap.chain.String(), publishAndIndexBuffer).Inc()
+		sap.recordGap(height)
+		return
+	}
+	spilledPayloads.WithLabelValues(sap.chain.String()).Inc()
+}
+
+// dropServePayload counts a payload dropped because the ScreenAndServe process was behind
+// nothing is counted if there is no ScreenAndServe process listening
+func (sap *Service) dropServePayload(screenAndServePayload chan<- shared.ConvertedData) {
+	if screenAndServePayload != nil {
+		droppedPayloads.WithLabelValues(sap.chain.String(), serveBuffer).Inc()
+	}
+}
+
+// recordGap records the height of a payload that was dropped as a known gap, for the backfill process to fill
+func (sap *Service) recordGap(height int64) {
+	if sap.SpillQueue == nil {
+		return
+	}
+	if err := sap.SpillQueue.RecordGap(height); err != nil {
+		log.Errorf("%s unable to record gap at height %d: %v", sap.chain.String(), height, err)
+	}
+}
+
 // publishAndIndex is spun up by SyncAndConvert and receives converted chain data from that process
 // it publishes this data to IPFS and indexes their CIDs with useful metadata in Postgres
+// when there is no converted data waiting, it drains any payloads that were spilled to the spill queue
 func (sap *Service) publishAndIndex(wg *sync.WaitGroup, id int, publishAndIndexPayload <-chan shared.ConvertedData) {
 	wg.Add(1)
 	defer wg.Done()
+	ticker := time.NewTicker(SpillCheckFrequency)
+	defer ticker.Stop()
 	for {
 		select {
 		case payload := <-publishAndIndexPayload:
-			log.Debugf("%s super node publishAndIndex worker %d publishing data streamed at head height %d", sap.chain.String(), id, payload.Height())
-			cidPayload, err := sap.Publisher.Publish(payload)
-			if err != nil {
-				log.Errorf("%s super node publishAndIndex worker %d publishing error: %v", sap.chain.String(), id, err)
-				continue
-			}
-			log.Debugf("%s super node publishAndIndex worker %d indexing data streamed at head height %d", sap.chain.String(), id, payload.Height())
-			if err := sap.Indexer.Index(cidPayload); err != nil {
-				log.Errorf("%s super node publishAndIndex worker %d indexing error: %v", sap.chain.String(), id, err)
-			}
+			sap.publishAndIndexPayload(id, payload)
+		case <-ticker.C:
+			sap.drainSpillQueue(id, publishAndIndexPayload)
 		case <-sap.QuitChan:
 			log.Infof("%s super node publishAndIndex worker %d shutting down", sap.chain.String(), id)
 			return
@@ -265,6 +406,70 @@ func (sap *Service) publishAndIndex(wg *sync.WaitGroup, id int, publishAndIndexP
 	}
 }
 
+// publishAndIndexPayload publishes and indexes a single converted payload
+// if this fails, the height of the payload is recorded as a known gap
+func (sap *Service) publishAndIndexPayload(id int, payload shared.ConvertedData) {
+	busyWorkers.WithLabelValues(sap.chain.String(), syncProcess).Inc()
+	defer busyWorkers.WithLabelValues(sap.chain.String(), syncProcess).Dec()
+	log.Debugf("%s super node publishAndIndex worker %d publishing data streamed at head height %d", sap.chain.String(), id, payload.Height())
+	start := time.Now()
+	cidPayload, err := sap.Publisher.Publish(payload)
+	if err != nil {
+		log.Errorf("%s super node publishAndIndex worker %d publishing error: %v", sap.chain.String(), id, err)
+		sap.recordGap(payload.Height())
+		return
+	}
+	observeStage(sap.chain.String(), syncProcess, publishStage, start)
+	log.Debugf("%s super node publishAndIndex worker %d indexing data streamed at head height %d", sap.chain.String(), id, payload.Height())
+	start = time.Now()
+	if err := sap.Indexer.Index(cidPayload); err != nil {
+		log.Errorf("%s super node publishAndIndex worker %d indexing error: %v", sap.chain.String(), id, err)
+		sap.recordGap(payload.Height())
+		return
+	}
+	observeStage(sap.chain.String(), syncProcess, indexStage, start)
+	sap.setIndexed(payload.Height())
+}
+
+// setIndexed records the height as the latest indexed height, if it is above the current one
+func (sap *Service) setIndexed(height int64) {
+	for {
+		indexed := atomic.LoadInt64(&sap.indexed)
+		if height <= indexed {
+			return
+		}
+		if atomic.CompareAndSwapInt64(&sap.indexed, indexed, height) {
+			indexedHeight.WithLabelValues(sap.chain.String()).Set(float64(height))
+			return
+		}
+	}
+}
+
+// drainSpillQueue pops payloads off of the spill queue, converts them, and publishes and indexes them
+// until either the queue is empty or new converted data is waiting on the publishAndIndexPayload channel
+func (sap *Service) drainSpillQueue(id int, publishAndIndexPayload <-chan shared.ConvertedData) {
+	if sap.SpillQueue == nil {
+		return
+	}
+	for len(publishAndIndexPayload) == 0 {
+		payload, ok, err := sap.SpillQueue.Pop()
+		if err != nil {
+			log.Errorf("%s super node publishAndIndex worker %d spill queue error: %v", sap.chain.String(), id, err)
+			return
+		}
+		if !ok {
+			return
+		}
+		ipldPayload, err := sap.Converter.Convert(payload)
+		if err != nil {
+			log.Errorf("%s super node publishAndIndex worker %d conversion error for spilled payload: %v", sap.chain.String(), id, err)
+			continue
+		}
+		log.Debugf("%s super node publishAndIndex worker %d draining payload spilled at height %d", sap.chain.String(), id, ipldPayload.Height())
+		sap.publishAndIndexPayload(id, ipldPayload)
+	}
+}
+
 // Serve listens for incoming converter data off the screenAndServePayload from the Sync process
 // It filters and sends this data to any subscribers to the service
 // This process can also be stood up alone, without an screenAndServePayload attached to a Sync process
@@ -277,6 +482,10 @@ func (sap *Service) Serve(wg *sync.WaitGroup, screenAndServePayload <-chan share
 		for {
 			select {
 			case payload := <-screenAndServePayload:
+				if notice, ok := payload.(reorgNotice); ok {
+					sap.serveReorg(notice)
+					continue
+				}
 				sap.filterAndServe(payload)
 			case <-sap.QuitChan:
 				log.Infof("quiting %s Serve process", sap.chain.String())
@@ -287,31 +496,56 @@ func (sap *Service) Serve(wg *sync.WaitGroup, screenAndServePayload <-chan share
 	log.Infof("%s Serve goroutine successfully spun up", sap.chain.String())
 }
 
+// serveReorg notifies the subscriptions of the heights invalidated by a reorg
+// subscriptions are only notified of the invalidated heights that fall within their requested range
+func (sap *Service) serveReorg(notice reorgNotice) {
+	log.Debugf("sending %s reorg notification to subscriptions", sap.chain.String())
+	sap.serveWg.Add(1)
+	defer sap.serveWg.Done()
+	for ty, subConfig := range sap.subscriptionTypes() {
+		invalidated := make([]int64, 0, len(notice.invalidated))
+		for _, height := range notice.invalidated {
+			if height < subConfig.StartingBlock().Int64() {
+				continue
+			}
+			if subConfig.EndingBlock().Int64() > 0 && subConfig.EndingBlock().Int64() < height {
+				continue
+			}
+			invalidated = append(invalidated, height)
+		}
+		if len(invalidated) == 0 {
+			continue
+		}
+		sap.serveType(ty, SubscriptionPayload{Err: "", Flag: ReorgFlag, Height: notice.Height(), InvalidatedHeights: invalidated})
+	}
+}
+
 // filterAndServe filters the payload according to each subscription type and sends to the subscriptions
+// Filtering and encoding happen without subscription access locked, so that they do not hold up subscribing and unsubscribing
 func (sap *Service) filterAndServe(payload shared.ConvertedData) {
 	log.Debugf("sending %s payload to subscriptions", sap.chain.String())
-	sap.Lock()
 	sap.serveWg.Add(1)
-	defer sap.Unlock()
 	defer sap.serveWg.Done()
-	for ty, subs := range sap.Subscriptions {
-		// Retrieve the subscription parameters for this subscription type
-		subConfig, ok := sap.SubscriptionTypes[ty]
-		if !ok {
-			log.Errorf("super node %s subscription configuration for subscription type %s not available", sap.chain.String(), ty.Hex())
-			sap.closeType(ty)
-			continue
-		}
+	hash, _, err := blockHashes(payload)
+	if err != nil {
+		log.Errorf("super node cursor error for chain %s: %v", sap.chain.String(), err)
+	}
+	cursor := shared.Cursor{Height: uint64(payload.Height()), Hash: hash}
+	for ty, subConfig := range sap.subscriptionTypes() {
 		if subConfig.EndingBlock().Int64() > 0 && subConfig.EndingBlock().Int64() < payload.Height() {
 			// We are not out of range for this subscription type
 			// close it, and continue to the next
+			sap.Lock()
 			sap.closeType(ty)
+			sap.Unlock()
 			continue
 		}
 		response, err := sap.Filterer.Filter(subConfig, payload)
 		if err != nil {
 			log.Errorf("super node filtering error for chain %s: %v", sap.chain.String(), err)
+			sap.Lock()
 			sap.closeType(ty)
+			sap.Unlock()
 			continue
 		}
 		responseRLP, err := rlp.EncodeToBytes(response)
@@ -319,14 +553,46 @@ func (sap *Service) filterAndServe(payload shared.ConvertedData) {
 			log.Errorf("super node rlp encoding error for chain %s: %v", sap.chain.String(), err)
 			continue
 		}
-		for id, sub := range subs {
-			select {
-			case sub.PayloadChan <- SubscriptionPayload{Data: responseRLP, Err: "", Flag: EmptyFlag, Height: response.Height()}:
-				log.Debugf("sending super node %s payload to subscription %s", sap.chain.String(), id)
-			default:
-				log.Infof("unable to send %s payload to subscription %s; channel has no receiver", sap.chain.String(), id)
-			}
+		sap.serveType(ty, SubscriptionPayload{Data: responseRLP, Err: "", Flag: EmptyFlag, Height: response.Height(), Cursor: cursor})
+	}
+}
+
+// subscriptionTypes returns a copy of the settings for the current subscription types
+// subscription types whose settings are not available are closed
+func (sap *Service) subscriptionTypes() map[common.Hash]shared.SubscriptionSettings {
+	sap.Lock()
+	defer sap.Unlock()
+	types := make(map[common.Hash]shared.SubscriptionSettings, len(sap.Subscriptions))
+	for ty := range sap.Subscriptions {
+		subConfig, ok := sap.SubscriptionTypes[ty]
+		if !ok {
+			log.Errorf("super node %s subscription configuration for subscription type %s not available", sap.chain.String(), ty.Hex())
+			sap.closeType(ty)
+			continue
+		}
+		types[ty] = subConfig
+	}
+	return types
+}
+
+// serveType sends the payload to the subscriptions of the given type
+// If a subscription is still being sent historical data the payload is buffered until it is handed off,
+// otherwise the payload is delivered according to the subscription's slow consumer policy
+// Subscriptions that block on delivery do so from their own outbox, so that they do not hold up the other subscriptions or the serve loop
+func (sap *Service) serveType(subType common.Hash, payload SubscriptionPayload) {
+	sap.Lock()
+	subs := make([]Subscription, 0, len(sap.Subscriptions[subType]))
+	for _, sub := range sap.Subscriptions[subType] {
+		if sub.handoff != nil {
+			sub.handoff.add(payload)
+			continue
 		}
+		subs = append(subs, sub)
+	}
+	sap.Unlock()
+	for _, sub := range subs {
+		log.Debugf("sending super node %s payload to subscription %s", sap.chain.String(), sub.ID)
+		sap.serve(sub, payload)
 	}
 }
 
@@ -354,7 +620,21 @@ func (sap *Service) Subscribe(id rpc.ID, sub chan<- SubscriptionPayload, quitCha
 		return
 	}
 	subscriptionType := crypto.Keccak256Hash(by)
+	subscription.delivery = newDelivery(id, subscriptionType, params)
+	sap.Lock()
+	sap.deliveries[id] = subscription.delivery
+	sap.Unlock()
+	if subscription.delivery.outbox != nil {
+		go sap.drainOutbox(subscription)
+	}
+	// If the subscription requests historical data or is resuming from a cursor, use the Postgres index to lookup and retrieve historical data
+	// Otherwise we only filter new data as it is streamed in from the state diffing geth node
+	historical := params.HistoricalData() || params.HistoricalDataOnly() || !params.ResumeCursor().IsZero()
 	if !params.HistoricalDataOnly() {
+		// Live data is buffered for the subscription until its historical data has been sent
+		if historical {
+			subscription.handoff = new(handoff)
+		}
 		// Add subscriber
 		sap.Lock()
 		if sap.Subscriptions[subscriptionType] == nil {
@@ -362,12 +642,12 @@ func (sap *Service) Subscribe(id rpc.ID, sub chan<- SubscriptionPayload, quitCha
 		}
 		sap.Subscriptions[subscriptionType][id] = subscription
 		sap.SubscriptionTypes[subscriptionType] = params
+		sap.countSubscriptions(subscriptionType)
 		sap.Unlock()
 	}
-	// If the subscription requests a backfill, use the Postgres index to lookup and retrieve historical data
-	// Otherwise we only filter new data as it is streamed in from the state diffing geth node
-	if params.HistoricalData() || params.HistoricalDataOnly() {
-		if err := sap.sendHistoricalData(subscription, id, params); err != nil {
+	if historical {
+		if err := sap.sendHistoricalData(subscription, subscriptionType, id, params); err != nil {
+			sap.Unsubscribe(id)
 			sendNonBlockingErr(subscription, fmt.Errorf("%s super node subscriber backfill error: %v", sap.chain.String(), err))
 			sendNonBlockingQuit(subscription)
 			return
@@ -376,10 +656,10 @@ func (sap *Service) Subscribe(id rpc.ID, sub chan<- SubscriptionPayload, quitCha
 }
 
 // sendHistoricalData sends historical data to the requesting subscription
-func (sap *Service) sendHistoricalData(sub Subscription, id rpc.ID, params shared.SubscriptionSettings) error {
+// Once the subscription has caught up to the live feed it is handed off to it, unless it only requested historical data
+func (sap *Service) sendHistoricalData(sub Subscription, subType common.Hash, id rpc.ID, params shared.SubscriptionSettings) error {
 	log.Infof("Sending %s historical data to subscription %s", sap.chain.String(), id)
 	// Retrieve cached CIDs relevant to this subscriber
-	var endingBlock int64
 	var startingBlock int64
 	var err error
 	startingBlock, err = sap.Retriever.RetrieveFirstBlockNumber()
@@ -389,75 +669,178 @@ func (sap *Service) sendHistoricalData(sub Subscription, id rpc.ID, params share
 	if startingBlock < params.StartingBlock().Int64() {
 		startingBlock = params.StartingBlock().Int64()
 	}
-	endingBlock, err = sap.Retriever.RetrieveLastBlockNumber()
-	if err != nil {
-		return err
-	}
-	if endingBlock > params.EndingBlock().Int64() && params.EndingBlock().Int64() > 0 && params.EndingBlock().Int64() > startingBlock {
-		endingBlock = params.EndingBlock().Int64()
+	if cursor := params.ResumeCursor(); !cursor.IsZero() {
+		resumeFrom, err := sap.resumeFrom(sub, cursor, params)
+		if err != nil {
+			return err
+		}
+		if startingBlock < resumeFrom {
+			startingBlock = resumeFrom
+		}
 	}
-	log.Debugf("%s historical data starting block: %d", sap.chain.String(), params.StartingBlock().Int64())
+	endingBlock := params.EndingBlock().Int64()
+	log.Debugf("%s historical data starting block: %d", sap.chain.String(), startingBlock)
 	log.Debugf("%s historical data ending block: %d", sap.chain.String(), endingBlock)
 	go func() {
 		sap.serveWg.Add(1)
 		defer sap.serveWg.Done()
-		for i := startingBlock; i <= endingBlock; i++ {
-			select {
-			case <-sap.QuitChan:
-				log.Infof("%s super node historical data feed to subscription %s closed", sap.chain.String(), id)
-				return
-			default:
-			}
-			cidWrappers, empty, err := sap.Retriever.Retrieve(params, i)
+		next := startingBlock
+		for attempts := 0; ; attempts++ {
+			lastBlock, err := sap.Retriever.RetrieveLastBlockNumber()
 			if err != nil {
-				sendNonBlockingErr(sub, fmt.Errorf(" %s super node CID Retrieval error at block %d\r%s", sap.chain.String(), i, err.Error()))
-				continue
+				sendNonBlockingErr(sub, fmt.Errorf("%s super node last block number retrieval error\r%s", sap.chain.String(), err.Error()))
+				return
 			}
-			if empty {
-				continue
+			if endingBlock > 0 && endingBlock < lastBlock {
+				lastBlock = endingBlock
 			}
-			for _, cids := range cidWrappers {
-				response, err := sap.IPLDFetcher.Fetch(cids)
-				if err != nil {
-					sendNonBlockingErr(sub, fmt.Errorf("%s super node IPLD Fetching error at block %d\r%s", sap.chain.String(), i, err.Error()))
-					continue
-				}
-				responseRLP, err := rlp.EncodeToBytes(response)
-				if err != nil {
-					log.Error(err)
-					continue
-				}
+			for ; next <= lastBlock; next++ {
 				select {
-				case sub.PayloadChan <- SubscriptionPayload{Data: responseRLP, Err: "", Flag: EmptyFlag, Height: response.Height()}:
-					log.Debugf("sending super node historical data payload to %s subscription %s", sap.chain.String(), id)
+				case <-sap.QuitChan:
+					log.Infof("%s super node historical data feed to subscription %s closed", sap.chain.String(), id)
+					return
+				case <-sub.delivery.done:
+					log.Infof("%s super node historical data feed to subscription %s closed", sap.chain.String(), id)
+					sendNonBlockingQuit(sub)
+					return
 				default:
-					log.Infof("unable to send backFill payload to %s subscription %s; channel has no receiver", sap.chain.String(), id)
+				}
+				if !sap.sendHistoricalBlock(sub, id, params, next) {
+					return
 				}
 			}
-		}
-		// when we are done backfilling send an empty payload signifying so in the msg
-		select {
-		case sub.PayloadChan <- SubscriptionPayload{Data: nil, Err: "", Flag: BackFillCompleteFlag}:
-			log.Debugf("sending backFill completion notice to %s subscription %s", sap.chain.String(), id)
-		default:
-			log.Infof("unable to send backFill completion notice to %s subscription %s", sap.chain.String(), id)
+			if params.HistoricalDataOnly() || (endingBlock > 0 && next > endingBlock) {
+				if sap.deliver(sub, backFillCompletePayload()) {
+					sap.disconnect(sub)
+				}
+				return
+			}
+			if sap.handoff(sub, subType, next, attempts >= HandoffRetryLimit) {
+				return
+			}
+			select {
+			case <-sap.QuitChan:
+				log.Infof("%s super node historical data feed to subscription %s closed", sap.chain.String(), id)
+				return
+			case <-sub.delivery.done:
+				log.Infof("%s super node historical data feed to subscription %s closed", sap.chain.String(), id)
+				sendNonBlockingQuit(sub)
+				return
+			case <-time.After(HandoffRetryInterval):
+			}
 		}
 	}()
 	return nil
 }
 
+// resumeFrom returns the height to resume a subscription from, given the cursor it provided
+// if the block at the cursor is no longer canonical, the subscription is sent a reorg notification and resumes at the cursor height
+func (sap *Service) resumeFrom(sub Subscription, cursor shared.Cursor, params shared.SubscriptionSettings) (int64, error) {
+	height := int64(cursor.Height)
+	cidWrappers, _, err := sap.Retriever.Retrieve(params, height)
+	if err != nil {
+		return 0, err
+	}
+	if len(cidWrappers) == 0 {
+		return height + 1, nil
+	}
+	for _, cids := range cidWrappers {
+		if cidsBlockHash(cids) == cursor.Hash {
+			return height + 1, nil
+		}
+	}
+	log.Infof("%s subscription %s cursor %s is no longer canonical", sap.chain.String(), sub.ID, cursor.String())
+	if sap.deliver(sub, SubscriptionPayload{Err: "", Flag: ReorgFlag, Height: height, InvalidatedHeights: []int64{height}}) {
+		sap.disconnect(sub)
+		return 0, fmt.Errorf("%s subscription %s disconnected before resuming", sap.chain.String(), sub.ID)
+	}
+	return height, nil
+}
+
+// sendHistoricalBlock retrieves and sends the historical data at the provided height to the subscription
+// it returns false if the subscription was disconnected for falling behind
+func (sap *Service) sendHistoricalBlock(sub Subscription, id rpc.ID, params shared.SubscriptionSettings, height int64) bool {
+	cidWrappers, empty, err := sap.Retriever.Retrieve(params, height)
+	if err != nil {
+		sendNonBlockingErr(sub, fmt.Errorf(" %s super node CID Retrieval error at block %d\r%s", sap.chain.String(), height, err.Error()))
+		return true
+	}
+	if empty {
+		return true
+	}
+	for _, cids := range cidWrappers {
+		response, err := sap.IPLDFetcher.Fetch(cids)
+		if err != nil {
+			sendNonBlockingErr(sub, fmt.Errorf("%s super node IPLD Fetching error at block %d\r%s", sap.chain.String(), height, err.Error()))
+			continue
+		}
+		responseRLP, err := rlp.EncodeToBytes(response)
+		if err != nil {
+			log.Error(err)
+			continue
+		}
+		cursor := shared.Cursor{Height: uint64(height), Hash: cidsBlockHash(cids)}
+		log.Debugf("sending super node historical data payload to %s subscription %s", sap.chain.String(), id)
+		if sap.deliver(sub, SubscriptionPayload{Data: responseRLP, Err: "", Flag: EmptyFlag, Height: response.Height(), Cursor: cursor}) {
+			sap.disconnect(sub)
+			return false
+		}
+	}
+	return true
+}
+
+// handoff hands the subscription off from historical data to the live feed, once the historical data sent
+// up to the provided height meets the live payloads buffered for the subscription
+// if force is set the subscription is handed off regardless, and is notified of the gap it is left with
+// it returns false if the subscription is not yet ready to be handed off
+// The buffered payloads are delivered with subscription access unlocked; live payloads that arrive in the meantime
+// keep being buffered and are delivered in turn, until the buffer is empty and the subscription can be switched to the live feed
+func (sap *Service) handoff(sub Subscription, subType common.Hash, next int64, force bool) bool {
+	sap.Lock()
+	current, ok := sap.Subscriptions[subType][sub.ID]
+	if !ok || current.handoff == nil {
+		// the subscription has been closed in the meantime
+		sap.Unlock()
+		return true
+	}
+	head := atomic.LoadInt64(&sap.head)
+	if !current.handoff.ready(next, head) {
+		if !force {
+			sap.Unlock()
+			return false
+		}
+		sendNonBlockingErr(sub, fmt.Errorf("%s super node unable to retrieve historical data from height %d before handing off to the live feed", sap.chain.String(), next))
+	}
+	payloads := append([]SubscriptionPayload{backFillCompletePayload()}, current.handoff.flush(next)...)
+	for len(payloads) > 0 {
+		sap.Unlock()
+		for _, payload := range payloads {
+			if sap.deliver(sub, payload) {
+				sap.disconnect(sub)
+				return true
+			}
+		}
+		sap.Lock()
+		current, ok = sap.Subscriptions[subType][sub.ID]
+		if !ok {
+			sap.Unlock()
+			return true
+		}
+		// everything buffered after the historical data has been sent is live data
+		payloads = current.handoff.flush(0)
+	}
+	current.handoff = nil
+	sap.Subscriptions[subType][sub.ID] = current
+	sap.Unlock()
+	log.Infof("%s subscription %s handed off to the live feed at height %d", sap.chain.String(), sub.ID, next)
+	return true
+}
+
 // Unsubscribe is used by the API to remotely unsubscribe to the StateDiffingService loop
 func (sap *Service) Unsubscribe(id rpc.ID) {
 	log.Infof("Unsubscribing %s from the %s super node service", id, sap.chain.String())
 	sap.Lock()
-	for ty := range sap.Subscriptions {
-		delete(sap.Subscriptions[ty], id)
-		if len(sap.Subscriptions[ty]) == 0 {
-			// If we removed the last subscription of this type, remove the subscription type outright
-			delete(sap.Subscriptions, ty)
-			delete(sap.SubscriptionTypes, ty)
-		}
-	}
+	sap.removeSubscription(id)
 	sap.Unlock()
 }
 
@@ -495,6 +878,22 @@ func (sap *Service) Chain() shared.ChainType {
 	return sap.chain
 }
 
+// SubscriptionStats returns the delivery counters of the current subscriptions
+func (sap *Service) SubscriptionStats() []SubscriptionStats {
+	sap.Lock()
+	defer sap.Unlock()
+	stats := make([]SubscriptionStats, 0, len(sap.deliveries))
+	for _, d := range sap.deliveries {
+		stats = append(stats, d.stats())
+	}
+	return stats
+}
+
+// DroppedPayloads returns the total number of payloads dropped for subscriptions that fell behind
+func (sap *Service) DroppedPayloads() uint64 {
+	return atomic.LoadUint64(&sap.dropped)
+}
+
 // close is used to close all listening subscriptions
 // close needs to be called with subscription access locked
 func (sap *Service) close() {
@@ -502,9 +901,11 @@ func (sap *Service) close() {
 	for subType, subs := range sap.Subscriptions {
 		for _, sub := range subs {
 			sendNonBlockingQuit(sub)
+			sap.releaseDelivery(sub.delivery)
 		}
 		delete(sap.Subscriptions, subType)
 		delete(sap.SubscriptionTypes, subType)
+		sap.countSubscriptions(subType)
 	}
 }
 
@@ -515,7 +916,20 @@ func (sap *Service) closeType(subType common.Hash) {
 	subs := sap.Subscriptions[subType]
 	for _, sub := range subs {
 		sendNonBlockingQuit(sub)
+		sap.releaseDelivery(sub.delivery)
 	}
 	delete(sap.Subscriptions, subType)
 	delete(sap.SubscriptionTypes, subType)
+	sap.countSubscriptions(subType)
+}
+
+// countSubscriptions updates the active subscriptions metric for the given subscription type
+// countSubscriptions needs to be called with subscription access locked
+func (sap *Service) countSubscriptions(subType common.Hash) {
+	count := len(sap.Subscriptions[subType])
+	if count == 0 {
+		activeSubscriptions.DeleteLabelValues(sap.chain.String(), subType.Hex())
+		return
+	}
+	activeSubscriptions.WithLabelValues(sap.chain.String(), subType.Hex()).Set(float64(count))
 }
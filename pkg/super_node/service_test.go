@@ -17,14 +17,18 @@
 package super_node_test
 
 import (
+	"fmt"
+	"math/big"
 	"sync"
 	"time"
 
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rpc"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
 	"github.com/vulcanize/vulcanizedb/pkg/super_node"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth"
 	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth/mocks"
 	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
 	mocks2 "github.com/vulcanize/vulcanizedb/pkg/super_node/shared/mocks"
@@ -75,4 +79,137 @@ var _ = Describe("Service", func() {
 			Expect(mockStreamer.PassedPayloadChan).To(Equal(payloadChan))
 		})
 	})
+
+	Describe("Serve", func() {
+		It("Does not hold up the other subscriptions while delivering to a subscription that blocks", func() {
+			sn, err := super_node.NewSuperNode(&super_node.Config{Chain: shared.Ethereum, Workers: 1})
+			Expect(err).ToNot(HaveOccurred())
+			service := sn.(*super_node.Service)
+			service.Filterer = eth.NewResponseFilterer()
+			payloadChan := make(chan shared.ConvertedData, 2)
+			service.Serve(new(sync.WaitGroup), payloadChan)
+			defer service.Stop()
+
+			// the blocking subscription never reads from its buffer, and waits 10 seconds for room before dropping a payload
+			blockingSub := make(chan super_node.SubscriptionPayload)
+			service.Subscribe(rpc.NewID(), blockingSub, make(chan bool, 1), &eth.SubscriptionSettings{
+				Start:        big.NewInt(0),
+				End:          big.NewInt(0),
+				SlowConsumer: shared.SlowConsumerPolicy{Mode: shared.BlockWithTimeout, Timeout: 10000},
+			})
+			liveSub := make(chan super_node.SubscriptionPayload, 2)
+			service.Subscribe(rpc.NewID(), liveSub, make(chan bool, 1), &eth.SubscriptionSettings{
+				Start: big.NewInt(0),
+				End:   big.NewInt(0),
+			})
+			payloadChan <- mocks.MockConvertedPayload
+			payloadChan <- mocks.MockConvertedPayload
+			for i := 0; i < 2; i++ {
+				select {
+				case payload := <-liveSub:
+					Expect(payload.Err).To(BeEmpty())
+					Expect(payload.Height).To(Equal(mocks.MockConvertedPayload.Height()))
+				case <-time.After(time.Second):
+					Fail("payload was held up by the blocking subscription")
+				}
+			}
+		})
+	})
+
+	Describe("Subscribe", func() {
+		var (
+			service     *super_node.Service
+			retriever   *mocks2.CIDRetriever
+			payloadChan chan shared.ConvertedData
+			settings    *eth.SubscriptionSettings
+		)
+		BeforeEach(func() {
+			sn, err := super_node.NewSuperNode(&super_node.Config{Chain: shared.Ethereum, Workers: 1})
+			Expect(err).ToNot(HaveOccurred())
+			service = sn.(*super_node.Service)
+			retriever = &mocks2.CIDRetriever{
+				FirstBlockNumberToReturn: 1,
+				CIDsToReturn:             make(map[int64][]shared.CIDsForFetching),
+			}
+			for height := int64(1); height <= 5; height++ {
+				retriever.CIDsToReturn[height] = []shared.CIDsForFetching{&eth.CIDWrapper{
+					BlockNumber: big.NewInt(height),
+					BlockHash:   fmt.Sprintf("0x%02d", height),
+				}}
+			}
+			service.Retriever = retriever
+			service.IPLDFetcher = &mocks.IPLDFetcher{}
+			service.Filterer = eth.NewResponseFilterer()
+			payloadChan = make(chan shared.ConvertedData, 2)
+			service.Serve(new(sync.WaitGroup), payloadChan)
+			// historical payloads are delivered straight to the unbuffered subscription, holding up the historical data process until they are read
+			settings = &eth.SubscriptionSettings{
+				Start:         big.NewInt(0),
+				End:           big.NewInt(0),
+				SlowConsumer:  shared.SlowConsumerPolicy{Mode: shared.BlockWithTimeout, Timeout: 10000},
+				TxFilter:      eth.TxFilter{Off: true},
+				ReceiptFilter: eth.ReceiptFilter{Off: true},
+				StateFilter:   eth.StateFilter{Off: true},
+				StorageFilter: eth.StorageFilter{Off: true},
+			}
+		})
+		AfterEach(func() {
+			service.Stop()
+		})
+
+		It("Resumes after the cursor and hands off to the live feed once the index has caught up to the buffered live payloads", func() {
+			// the index is behind the live feed at first, and has caught up to it when checked again
+			retriever.LastBlockNumbersToReturn = []int64{3, 5}
+			settings.Cursor = shared.Cursor{Height: 2, Hash: "0x02"}
+			sub := make(chan super_node.SubscriptionPayload)
+			service.Subscribe(rpc.NewID(), sub, make(chan bool, 1), settings)
+
+			// a height backfilled below the cursor and the live head are buffered while the historical data is being sent
+			payloadChan <- liveConvertedPayload(1)
+			payloadChan <- liveConvertedPayload(6)
+			time.Sleep(100 * time.Millisecond)
+
+			Expect(receiveHeights(sub, 5)).To(Equal([]string{"3", "4", "5", "complete", "6"}))
+			Expect(retriever.PassedBlockNumbers).To(Equal([]int64{2, 3, 4, 5}))
+		})
+
+		It("Resumes at the cursor after a reorg notification if the block at the cursor is no longer canonical", func() {
+			retriever.LastBlockNumbersToReturn = []int64{5}
+			settings.Cursor = shared.Cursor{Height: 2, Hash: "0xreorged"}
+			sub := make(chan super_node.SubscriptionPayload)
+			// the reorg notification is delivered while subscribing
+			go service.Subscribe(rpc.NewID(), sub, make(chan bool, 1), settings)
+
+			Expect(receiveHeights(sub, 6)).To(Equal([]string{"reorg 2", "2", "3", "4", "5", "complete"}))
+		})
+	})
 })
+
+func liveConvertedPayload(height int64) eth.ConvertedPayload {
+	return eth.ConvertedPayload{
+		TotalDifficulty: big.NewInt(1),
+		Block:           types.NewBlock(&types.Header{Number: big.NewInt(height)}, nil, nil, nil),
+	}
+}
+
+// receiveHeights reads the provided number of payloads from the subscription, describing each by its height or its flag
+func receiveHeights(sub chan super_node.SubscriptionPayload, count int) []string {
+	received := make([]string, 0, count)
+	for len(received) < count {
+		select {
+		case payload := <-sub:
+			Expect(payload.Err).To(BeEmpty())
+			switch payload.Flag {
+			case super_node.BackFillCompleteFlag:
+				received = append(received, "complete")
+			case super_node.ReorgFlag:
+				received = append(received, fmt.Sprintf("reorg %d", payload.Height))
+			default:
+				received = append(received, fmt.Sprintf("%d", payload.Height))
+			}
+		case <-time.After(super_node.HandoffRetryInterval * 5):
+			Fail(fmt.Sprintf("timed out waiting for payloads, received %v", received))
+		}
+	}
+	return received
+}
@@ -0,0 +1,193 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package shared
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+	"github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/vulcanizedb/pkg/ipfs"
+	"github.com/vulcanize/vulcanizedb/pkg/postgres"
+)
+
+// indexRecordPrefix is the CID prefix of the blocks that carry the index rows of a block alongside its IPLDs in a CAR file
+// The chains never publish raw blocks, so on import these are told apart from the IPLDs by their codec
+var indexRecordPrefix = cid.Prefix{
+	Version:  1,
+	Codec:    cid.Raw,
+	MhType:   mh.SHA2_256,
+	MhLength: -1,
+}
+
+// NewIndexRecord encodes the CID payload of a block into an index record block
+func NewIndexRecord(payload CIDsForIndexing) (blocks.Block, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	c, err := indexRecordPrefix.Sum(data)
+	if err != nil {
+		return nil, err
+	}
+	return blocks.NewBlockWithCid(data, c)
+}
+
+// IsIndexRecord returns whether or not the block with the provided CID is an index record
+func IsIndexRecord(c cid.Cid) bool {
+	return c.Prefix().Codec == indexRecordPrefix.Codec
+}
+
+// DecodeIndexRecord decodes an index record block into the provided CID payload
+func DecodeIndexRecord(block blocks.Block, payload CIDsForIndexing) error {
+	return json.Unmarshal(block.RawData(), payload)
+}
+
+// WriteCARIPLDs retrieves the IPLDs with the provided CIDs and writes them to the CAR file
+// IPLDs already marked as written are skipped, so that an IPLD shared by several blocks is only written once
+func WriteCARIPLDs(car *ipfs.CARWriter, blockGetter ipfs.BlockGetter, cidStrs []string, written map[cid.Cid]bool) error {
+	cids := make([]cid.Cid, 0, len(cidStrs))
+	for _, cidStr := range cidStrs {
+		if cidStr == "" {
+			continue
+		}
+		c, err := cid.Decode(cidStr)
+		if err != nil {
+			return err
+		}
+		if written[c] {
+			continue
+		}
+		written[c] = true
+		cids = append(cids, c)
+	}
+	if len(cids) == 0 {
+		return nil
+	}
+	fetched := make(map[cid.Cid]blocks.Block, len(cids))
+	for block := range blockGetter.GetBlocks(context.Background(), cids) {
+		fetched[block.Cid()] = block
+	}
+	// blocks are written in CID order so that exporting the same range twice produces the same file
+	sort.Slice(cids, func(i, j int) bool { return cids[i].KeyString() < cids[j].KeyString() })
+	for _, c := range cids {
+		block, ok := fetched[c]
+		if !ok {
+			return fmt.Errorf("unable to retrieve IPLD %s for export", c.String())
+		}
+		if err := car.Put(block); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckCARIPLDs returns an error if any of the provided CIDs are not among the IPLDs imported from a CAR file
+func CheckCARIPLDs(cidStrs []string, imported map[cid.Cid]bool) error {
+	for _, cidStr := range cidStrs {
+		if cidStr == "" {
+			continue
+		}
+		c, err := cid.Decode(cidStr)
+		if err != nil {
+			return err
+		}
+		if !imported[c] {
+			return fmt.Errorf("IPLD %s is referenced by an index record but is missing from the CAR file", cidStr)
+		}
+	}
+	return nil
+}
+
+// PGBlockService satisfies the ipfs.BlockService interface by reading and writing blocks directly in public.blocks
+// It is used to move IPLDs in and out of CAR files in the DirectPostgres ipfs mode
+type PGBlockService struct {
+	db *postgres.DB
+}
+
+// NewPGBlockService creates a pointer to a new PGBlockService
+func NewPGBlockService(db *postgres.DB) *PGBlockService {
+	return &PGBlockService{
+		db: db,
+	}
+}
+
+// GetBlock retrieves a block from public.blocks
+func (s *PGBlockService) GetBlock(ctx context.Context, c cid.Cid) (blocks.Block, error) {
+	var data []byte
+	err := s.db.Get(&data, `SELECT data FROM public.blocks WHERE key = $1`, BlockKey(c))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("block %s not found in public.blocks", c.String())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return blocks.NewBlockWithCid(data, c)
+}
+
+// GetBlocks retrieves blocks from public.blocks, blocks that cannot be retrieved are skipped
+func (s *PGBlockService) GetBlocks(ctx context.Context, cs []cid.Cid) <-chan blocks.Block {
+	blockChan := make(chan blocks.Block)
+	go func() {
+		defer close(blockChan)
+		for _, c := range cs {
+			block, err := s.GetBlock(ctx, c)
+			if err != nil {
+				logrus.Errorf("pg block service: unable to retrieve block %s: %v", c.String(), err)
+				continue
+			}
+			select {
+			case blockChan <- block:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return blockChan
+}
+
+// AddBlocks writes blocks to public.blocks in a single db tx
+func (s *PGBlockService) AddBlocks(bs []blocks.Block) (err error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+	for _, block := range bs {
+		_, err = tx.Exec(`INSERT INTO public.blocks (key, data) VALUES ($1, $2) ON CONFLICT (key) DO NOTHING`, BlockKey(block.Cid()), block.RawData())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package shared
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/spf13/viper"
+)
+
+// GetChainConfig returns the chain configuration for the provided chain type using the configured network id
+// For Ethereum a genesis file, if one is configured, takes precedence over the network id
+func GetChainConfig(chain ChainType) (interface{}, error) {
+	switch chain {
+	case Ethereum:
+		viper.BindEnv("ethereum.networkID", ETH_NETWORK_ID)
+		return NewChainConfig(chain, viper.GetString("ethereum.networkID"))
+	case Bitcoin, Omni:
+		viper.BindEnv("bitcoin.networkID", BTC_NETWORK_ID)
+		return NewChainConfig(chain, viper.GetString("bitcoin.networkID"))
+	default:
+		return nil, fmt.Errorf("invalid chain %s for chain config", chain.String())
+	}
+}
+
+// NewChainConfig returns the chain configuration for the provided chain type and network id
+// For Ethereum this is a *params.ChainConfig, for Bitcoin and Omni it is a *chaincfg.Params
+func NewChainConfig(chain ChainType, networkID string) (interface{}, error) {
+	switch chain {
+	case Ethereum:
+		viper.BindEnv("ethereum.genesisFile", ETH_GENESIS_FILE)
+		if genesisPath := viper.GetString("ethereum.genesisFile"); genesisPath != "" {
+			return LoadEthChainConfig(genesisPath)
+		}
+		return EthChainConfig(networkID)
+	case Bitcoin, Omni:
+		return BtcChainParams(networkID)
+	default:
+		return nil, fmt.Errorf("invalid chain %s for chain config", chain.String())
+	}
+}
+
+// EthChainConfig returns the go-ethereum chain config for a public network id
+// An empty network id defaults to mainnet
+func EthChainConfig(networkID string) (*params.ChainConfig, error) {
+	if networkID == "" {
+		return params.MainnetChainConfig, nil
+	}
+	id, err := strconv.ParseUint(networkID, 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ethereum network id %s: %v", networkID, err)
+	}
+	switch id {
+	case 1:
+		return params.MainnetChainConfig, nil
+	case 3:
+		return params.TestnetChainConfig, nil
+	case 4:
+		return params.RinkebyChainConfig, nil
+	case 5:
+		return params.GoerliChainConfig, nil
+	default:
+		return nil, fmt.Errorf("unknown ethereum network id %s, a genesis file needs to be provided for private networks", networkID)
+	}
+}
+
+// LoadEthChainConfig reads the chain config out of a geth genesis json file
+func LoadEthChainConfig(genesisPath string) (*params.ChainConfig, error) {
+	genesisBytes, err := ioutil.ReadFile(genesisPath)
+	if err != nil {
+		return nil, err
+	}
+	genesis := new(struct {
+		Config *params.ChainConfig `json:"config"`
+	})
+	if err := json.Unmarshal(genesisBytes, genesis); err != nil {
+		return nil, fmt.Errorf("invalid genesis file %s: %v", genesisPath, err)
+	}
+	if genesis.Config == nil || genesis.Config.ChainID == nil {
+		return nil, fmt.Errorf("genesis file %s does not contain a chain config with a chain id", genesisPath)
+	}
+	return genesis.Config, nil
+}
+
+// BtcChainParams returns the btcd chain params for a network id
+// The network id can be the network's magic bytes (e.g. 0xD9B4BEF9) or its name (mainnet, testnet3, regtest, simnet)
+// An empty network id defaults to mainnet
+func BtcChainParams(networkID string) (*chaincfg.Params, error) {
+	if networkID == "" {
+		return &chaincfg.MainNetParams, nil
+	}
+	networks := []*chaincfg.Params{
+		&chaincfg.MainNetParams,
+		&chaincfg.TestNet3Params,
+		&chaincfg.RegressionNetParams,
+		&chaincfg.SimNetParams,
+	}
+	for _, network := range networks {
+		if networkID == network.Name {
+			return network, nil
+		}
+	}
+	magic, err := strconv.ParseUint(networkID, 0, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bitcoin network id %s: %v", networkID, err)
+	}
+	for _, network := range networks {
+		if wire.BitcoinNet(magic) == network.Net {
+			return network, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown bitcoin network id %s", networkID)
+}
@@ -0,0 +1,114 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package shared_test
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/ethereum/go-ethereum/params"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+var _ = Describe("Chain config", func() {
+	Describe("EthChainConfig", func() {
+		It("Defaults to mainnet", func() {
+			config, err := shared.EthChainConfig("")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config).To(Equal(params.MainnetChainConfig))
+		})
+		It("Selects the config for public network ids", func() {
+			config, err := shared.EthChainConfig("1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config).To(Equal(params.MainnetChainConfig))
+			config, err = shared.EthChainConfig("3")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config).To(Equal(params.TestnetChainConfig))
+			config, err = shared.EthChainConfig("0x5")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config).To(Equal(params.GoerliChainConfig))
+		})
+		It("Errors for unknown network ids", func() {
+			_, err := shared.EthChainConfig("1337")
+			Expect(err).To(HaveOccurred())
+			_, err = shared.EthChainConfig("mainnet")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("LoadEthChainConfig", func() {
+		var dir string
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "genesis")
+			Expect(err).ToNot(HaveOccurred())
+		})
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+		It("Reads the chain config out of a genesis file", func() {
+			genesisPath := filepath.Join(dir, "genesis.json")
+			genesis := `{"config":{"chainId":1337,"homesteadBlock":0,"eip155Block":0,"byzantiumBlock":0,"clique":{"period":5,"epoch":30000}},"difficulty":"1","gasLimit":"8000000","alloc":{}}`
+			Expect(ioutil.WriteFile(genesisPath, []byte(genesis), 0644)).To(Succeed())
+			config, err := shared.LoadEthChainConfig(genesisPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.ChainID).To(Equal(big.NewInt(1337)))
+			Expect(config.ByzantiumBlock).To(Equal(big.NewInt(0)))
+			Expect(config.Clique).ToNot(BeNil())
+			Expect(config.Clique.Period).To(Equal(uint64(5)))
+		})
+		It("Errors if the genesis file has no chain config", func() {
+			genesisPath := filepath.Join(dir, "genesis.json")
+			Expect(ioutil.WriteFile(genesisPath, []byte(`{"difficulty":"1"}`), 0644)).To(Succeed())
+			_, err := shared.LoadEthChainConfig(genesisPath)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("BtcChainParams", func() {
+		It("Defaults to mainnet", func() {
+			btcParams, err := shared.BtcChainParams("")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(btcParams).To(Equal(&chaincfg.MainNetParams))
+		})
+		It("Selects params by network magic", func() {
+			btcParams, err := shared.BtcChainParams("0xD9B4BEF9")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(btcParams).To(Equal(&chaincfg.MainNetParams))
+			btcParams, err = shared.BtcChainParams("0x0709110B")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(btcParams).To(Equal(&chaincfg.TestNet3Params))
+		})
+		It("Selects params by network name", func() {
+			btcParams, err := shared.BtcChainParams("regtest")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(btcParams).To(Equal(&chaincfg.RegressionNetParams))
+		})
+		It("Errors for unknown networks", func() {
+			_, err := shared.BtcChainParams("0x01020304")
+			Expect(err).To(HaveOccurred())
+			_, err = shared.BtcChainParams("litecoin")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
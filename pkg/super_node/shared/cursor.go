@@ -0,0 +1,63 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package shared
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Cursor marks a position in a subscription feed by the height and hash of a block
+// A subscriber can resume a subscription from the last cursor it received
+type Cursor struct {
+	Height uint64 `json:"height"`
+	Hash   string `json:"hash"`
+}
+
+// IsZero returns true if the cursor does not mark a position
+func (c Cursor) IsZero() bool {
+	return c.Hash == ""
+}
+
+// String returns the cursor as a token of the form "height:hash"
+func (c Cursor) String() string {
+	if c.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("%d:%s", c.Height, c.Hash)
+}
+
+// ParseCursor parses a cursor from a token of the form "height:hash"
+// An empty token parses to the zero cursor
+func ParseCursor(token string) (Cursor, error) {
+	if token == "" {
+		return Cursor{}, nil
+	}
+	parts := strings.Split(token, ":")
+	if len(parts) != 2 || parts[1] == "" {
+		return Cursor{}, fmt.Errorf("invalid cursor token %s, expected height:hash", token)
+	}
+	height, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("invalid cursor token %s: %v", token, err)
+	}
+	return Cursor{
+		Height: height,
+		Hash:   parts[1],
+	}, nil
+}
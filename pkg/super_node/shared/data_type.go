@@ -122,13 +122,13 @@ func SupportedDataType(d DataType, c ChainType) (bool, error) {
 	case Omni:
 		switch d {
 		case Full:
-			return false, nil
+			return true, nil
 		case Headers:
-			return false, nil
+			return true, nil
 		case Uncles:
 			return false, nil
 		case Transactions:
-			return false, nil
+			return true, nil
 		case Receipts:
 			return false, nil
 		case State:
@@ -17,21 +17,28 @@
 package shared
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/ethereum/go-ethereum/rpc"
 
 	"github.com/btcsuite/btcd/rpcclient"
 	"github.com/spf13/viper"
 	"github.com/vulcanize/vulcanizedb/pkg/eth/core"
+	"github.com/vulcanize/vulcanizedb/pkg/ipfs"
 )
 
 // Env variables
 const (
-	IPFS_PATH    = "IPFS_PATH"
-	IPFS_MODE    = "IPFS_MODE"
-	HTTP_TIMEOUT = "HTTP_TIMEOUT"
+	IPFS_PATH       = "IPFS_PATH"
+	IPFS_MODE       = "IPFS_MODE"
+	IPFS_URL        = "IPFS_URL"
+	IPFS_TIMEOUT    = "IPFS_TIMEOUT"
+	IPFS_RETRIES    = "IPFS_RETRIES"
+	IPFS_BATCH_SIZE = "IPFS_BATCH_SIZE"
+	HTTP_TIMEOUT    = "HTTP_TIMEOUT"
 
 	ETH_WS_PATH       = "ETH_WS_PATH"
 	ETH_HTTP_PATH     = "ETH_HTTP_PATH"
@@ -39,6 +46,7 @@ const (
 	ETH_CLIENT_NAME   = "ETH_CLIENT_NAME"
 	ETH_GENESIS_BLOCK = "ETH_GENESIS_BLOCK"
 	ETH_NETWORK_ID    = "ETH_NETWORK_ID"
+	ETH_GENESIS_FILE  = "ETH_GENESIS_FILE"
 
 	BTC_WS_PATH       = "BTC_WS_PATH"
 	BTC_HTTP_PATH     = "BTC_HTTP_PATH"
@@ -52,21 +60,26 @@ const (
 
 // GetEthNodeAndClient returns eth node info and client from path url
 func GetEthNodeAndClient(path string) (core.Node, *rpc.Client, error) {
+	rpcClient, err := rpc.Dial(path)
+	if err != nil {
+		return core.Node{}, nil, err
+	}
+	return GetEthNodeInfo(), rpcClient, nil
+}
+
+// GetEthNodeInfo returns eth node info from the config, it is used directly by processes that do not connect to an eth node
+func GetEthNodeInfo() core.Node {
 	viper.BindEnv("ethereum.nodeID", ETH_NODE_ID)
 	viper.BindEnv("ethereum.clientName", ETH_CLIENT_NAME)
 	viper.BindEnv("ethereum.genesisBlock", ETH_GENESIS_BLOCK)
 	viper.BindEnv("ethereum.networkID", ETH_NETWORK_ID)
 
-	rpcClient, err := rpc.Dial(path)
-	if err != nil {
-		return core.Node{}, nil, err
-	}
 	return core.Node{
 		ID:           viper.GetString("ethereum.nodeID"),
 		ClientName:   viper.GetString("ethereum.clientName"),
 		GenesisBlock: viper.GetString("ethereum.genesisBlock"),
 		NetworkID:    viper.GetString("ethereum.networkID"),
-	}, rpcClient, nil
+	}
 }
 
 // GetIPFSPath returns the ipfs path from the config or env variable
@@ -83,6 +96,24 @@ func GetIPFSPath() (string, error) {
 	return ipfsPath, nil
 }
 
+// GetIPFSRemoteConfig returns the settings for the client of a remote ipfs node from the config or env variables
+func GetIPFSRemoteConfig() (ipfs.RemoteClientConfig, error) {
+	viper.BindEnv("ipfs.url", IPFS_URL)
+	viper.BindEnv("ipfs.timeout", IPFS_TIMEOUT)
+	viper.BindEnv("ipfs.retries", IPFS_RETRIES)
+	viper.BindEnv("ipfs.batchSize", IPFS_BATCH_SIZE)
+	ipfsURL := viper.GetString("ipfs.url")
+	if ipfsURL == "" {
+		return ipfs.RemoteClientConfig{}, errors.New("the remote ipfs mode requires the url of an ipfs http api")
+	}
+	return ipfs.RemoteClientConfig{
+		URL:        ipfsURL,
+		Timeout:    time.Duration(viper.GetInt("ipfs.timeout")) * time.Second,
+		MaxRetries: viper.GetInt("ipfs.retries"),
+		BatchSize:  viper.GetInt("ipfs.batchSize"),
+	}, nil
+}
+
 // GetIPFSMode returns the ipfs mode of operation from the config or env variable
 func GetIPFSMode() (IPFSMode, error) {
 	viper.BindEnv("ipfs.mode", IPFS_MODE)
@@ -18,6 +18,7 @@ package shared
 
 import (
 	"bytes"
+	"fmt"
 
 	"github.com/vulcanize/vulcanizedb/pkg/ipfs/ipld"
 
@@ -88,13 +89,17 @@ func Rollback(tx *sqlx.Tx) {
 
 // PublishIPLD is used to insert an ipld into Postgres blockstore with the provided tx
 func PublishIPLD(tx *sqlx.Tx, i node.Node) error {
-	dbKey := dshelp.CidToDsKey(i.Cid())
-	prefixedKey := blockstore.BlockPrefix.String() + dbKey.String()
 	raw := i.RawData()
-	_, err := tx.Exec(`INSERT INTO public.blocks (key, data) VALUES ($1, $2) ON CONFLICT (key) DO NOTHING`, prefixedKey, raw)
+	_, err := tx.Exec(`INSERT INTO public.blocks (key, data) VALUES ($1, $2) ON CONFLICT (key) DO NOTHING`, BlockKey(i.Cid()), raw)
 	return err
 }
 
+// BlockKey returns the blockstore-prefixed multihash db key the block with the provided cid is stored under in public.blocks
+func BlockKey(c cid.Cid) string {
+	dbKey := dshelp.CidToDsKey(c)
+	return blockstore.BlockPrefix.String() + dbKey.String()
+}
+
 // FetchIPLD is used to retrieve an ipld from Postgres blockstore with the provided tx
 func FetchIPLD(tx *sqlx.Tx, cid string) ([]byte, error) {
 	mhKey, err := MultihashKeyFromCIDString(cid)
@@ -106,6 +111,22 @@ func FetchIPLD(tx *sqlx.Tx, cid string) ([]byte, error) {
 	return block, tx.Get(&block, pgStr, mhKey)
 }
 
+// VerifyIPLD recomputes the multihash of the provided IPLD data and checks that it matches the cid
+func VerifyIPLD(c string, data []byte) error {
+	dc, err := cid.Decode(c)
+	if err != nil {
+		return err
+	}
+	check, err := dc.Prefix().Sum(data)
+	if err != nil {
+		return err
+	}
+	if !check.Equals(dc) {
+		return fmt.Errorf("IPLD %s is corrupt, its data hashes to %s", c, check.String())
+	}
+	return nil
+}
+
 // MultihashKeyFromCIDString converts a cid string into a blockstore-prefixed multihash db key string
 func MultihashKeyFromCIDString(c string) (string, error) {
 	dc, err := cid.Decode(c)
@@ -122,8 +143,6 @@ func PublishRaw(tx *sqlx.Tx, codec, mh uint64, raw []byte) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	dbKey := dshelp.CidToDsKey(c)
-	prefixedKey := blockstore.BlockPrefix.String() + dbKey.String()
-	_, err = tx.Exec(`INSERT INTO public.blocks (key, data) VALUES ($1, $2) ON CONFLICT (key) DO NOTHING`, prefixedKey, raw)
+	_, err = tx.Exec(`INSERT INTO public.blocks (key, data) VALUES ($1, $2) ON CONFLICT (key) DO NOTHING`, BlockKey(c), raw)
 	return c.String(), err
 }
@@ -0,0 +1,52 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package shared_test
+
+import (
+	"github.com/multiformats/go-multihash"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/vulcanizedb/pkg/ipfs/ipld"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+var _ = Describe("VerifyIPLD", func() {
+	var (
+		data = []byte("mock ipld data")
+		c    string
+	)
+	BeforeEach(func() {
+		dc, err := ipld.RawdataToCid(ipld.MEthHeader, data, multihash.KECCAK_256)
+		Expect(err).ToNot(HaveOccurred())
+		c = dc.String()
+	})
+
+	It("Accepts data that hashes to the multihash of the cid", func() {
+		err := shared.VerifyIPLD(c, data)
+		Expect(err).ToNot(HaveOccurred())
+	})
+	It("Rejects data that does not hash to the multihash of the cid", func() {
+		err := shared.VerifyIPLD(c, []byte("corrupted ipld data"))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring(c))
+	})
+	It("Errors for an invalid cid", func() {
+		err := shared.VerifyIPLD("notACID", data)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -17,6 +17,7 @@
 package shared
 
 import (
+	"io"
 	"math/big"
 )
 
@@ -45,6 +46,12 @@ type CIDIndexer interface {
 	Index(cids CIDsForIndexing) error
 }
 
+// BulkPublisherAndIndexer publishes and indexes a batch of converted payloads together
+// It is used in place of the IPLDPublisher and CIDIndexer by the backfill and resync processes when bulk indexing is on
+type BulkPublisherAndIndexer interface {
+	PublishAndIndexBatch(payloads []ConvertedData) error
+}
+
 // ResponseFilterer applies a filter to an IPLD payload to return a subscription response packet
 type ResponseFilterer interface {
 	Filter(filter SubscriptionSettings, payload ConvertedData) (response IPLDs, err error)
@@ -56,6 +63,8 @@ type CIDRetriever interface {
 	RetrieveFirstBlockNumber() (int64, error)
 	RetrieveLastBlockNumber() (int64, error)
 	RetrieveGapsInData(validationLevel int) ([]Gap, error)
+	RetrieveValidationGaps(validationLevel int) ([]Gap, error)
+	RetrieveValidationLevels() ([]ValidationLevel, error)
 }
 
 // IPLDFetcher uses a CID wrapper to fetch an IPLD wrapper
@@ -63,6 +72,13 @@ type IPLDFetcher interface {
 	Fetch(cids CIDsForFetching) (IPLDs, error)
 }
 
+// RollbackSignal is streamed by a PayloadStreamer, in line with the raw chain data and ahead of the data of the new chain,
+// when blocks it has already streamed are disconnected from the chain
+type RollbackSignal interface {
+	Height() int64
+	InvalidatedHeights() []int64
+}
+
 // ClientSubscription is a general interface for chain data subscriptions
 type ClientSubscription interface {
 	Err() <-chan error
@@ -75,6 +91,35 @@ type Cleaner interface {
 	ResetValidation(rngs [][2]uint64) error
 }
 
+// SpillQueue durably queues raw chain data that cannot be immediately handed off for publishing and indexing
+// and records the heights of data that has been spilled or dropped as known gaps for the backfill process
+type SpillQueue interface {
+	Spill(payload RawChainData, height int64) error
+	Pop() (RawChainData, bool, error)
+	RecordGap(height int64) error
+}
+
+// CARExporter writes the IPLDs indexed for a range of blocks out to a CAR file, along with the index rows that reference them
+type CARExporter interface {
+	Export(w io.Writer, start, stop uint64) (int, error)
+}
+
+// CARImporter loads the IPLDs in a CAR file written by a CARExporter and rebuilds the index rows that reference them
+type CARImporter interface {
+	Import(r io.Reader) (int, error)
+}
+
+// IPLDVerifier checks the IPLDs in public.blocks for the blocks at a height against their CIDs, and against the roots in their headers
+type IPLDVerifier interface {
+	Verify(blockNumber uint64) ([]Fault, error)
+}
+
+// BlockValidator validates the data indexed for a block against the payload fetched for it from the node
+// Only the indexed objects that differ from the payload are rewritten, each mismatch is recorded in the chain's validation audit table
+type BlockValidator interface {
+	Validate(payload ConvertedData) ([]Mismatch, error)
+}
+
 // SubscriptionSettings is the interface every subscription filter type needs to satisfy, no matter the chain
 // Further specifics of the underlying filter type depend on the internal needs of the types
 // which satisfy the ResponseFilterer and CIDRetriever interfaces for a specific chain
@@ -85,4 +130,6 @@ type SubscriptionSettings interface {
 	ChainType() ChainType
 	HistoricalData() bool
 	HistoricalDataOnly() bool
+	ResumeCursor() Cursor
+	SlowConsumerPolicy() SlowConsumerPolicy
 }
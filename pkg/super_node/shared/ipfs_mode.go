@@ -49,7 +49,7 @@ func NewIPFSMode(name string) (IPFSMode, error) {
 	case "local", "interface", "minimal":
 		return LocalInterface, nil
 	case "remote", "client":
-		return RemoteClient, errors.New("remote IPFS client mode is not currently supported")
+		return RemoteClient, nil
 	case "postgres", "direct":
 		return DirectPostgres, nil
 	default:
@@ -0,0 +1,67 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package shared
+
+// Field is an indexed column compared by a BlockValidator, with the value that was indexed and the value fetched from the node
+type Field struct {
+	Name    string
+	Indexed string
+	Fetched string
+}
+
+// MismatchRecorder collects the mismatches a BlockValidator finds while validating a block
+type MismatchRecorder struct {
+	BlockNumber uint64
+	BlockHash   string
+	Mismatches  []Mismatch
+}
+
+// NewMismatchRecorder returns a pointer to a new MismatchRecorder for the block with the provided number and hash
+func NewMismatchRecorder(blockNumber uint64, blockHash string) *MismatchRecorder {
+	return &MismatchRecorder{
+		BlockNumber: blockNumber,
+		BlockHash:   blockHash,
+		Mismatches:  make([]Mismatch, 0),
+	}
+}
+
+// Record records a single mismatch, and the action taken to resolve it
+func (mr *MismatchRecorder) Record(object, key, field, indexed, fetched, action string) {
+	mr.Mismatches = append(mr.Mismatches, Mismatch{
+		BlockNumber: mr.BlockNumber,
+		BlockHash:   mr.BlockHash,
+		Object:      object,
+		Key:         key,
+		Field:       field,
+		Indexed:     indexed,
+		Fetched:     fetched,
+		Action:      action,
+	})
+}
+
+// Compare records a mismatch for each field of an indexed object whose value differs from the one fetched, as rewritten,
+// and returns true if any of them differ
+func (mr *MismatchRecorder) Compare(object, key string, fields ...Field) bool {
+	differs := false
+	for _, field := range fields {
+		if field.Indexed != field.Fetched {
+			mr.Record(object, key, field.Name, field.Indexed, field.Fetched, RewrittenAction)
+			differs = true
+		}
+	}
+	return differs
+}
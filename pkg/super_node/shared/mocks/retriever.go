@@ -25,19 +25,34 @@ import (
 type CIDRetriever struct {
 	GapsToRetrieve              []shared.Gap
 	GapsToRetrieveErr           error
+	ValidationGapsToRetrieve    []shared.Gap
 	CalledTimes                 int
 	FirstBlockNumberToReturn    int64
 	RetrieveFirstBlockNumberErr error
+	// CIDs returned for each block number, a block number without any is returned as empty
+	CIDsToReturn map[int64][]shared.CIDsForFetching
+	// last block numbers returned in turn, the final one is returned for any further calls
+	LastBlockNumbersToReturn []int64
+	PassedBlockNumbers       []int64
 }
 
 // RetrieveCIDs mock method
-func (*CIDRetriever) Retrieve(filter shared.SubscriptionSettings, blockNumber int64) ([]shared.CIDsForFetching, bool, error) {
-	panic("implement me")
+func (mcr *CIDRetriever) Retrieve(filter shared.SubscriptionSettings, blockNumber int64) ([]shared.CIDsForFetching, bool, error) {
+	mcr.PassedBlockNumbers = append(mcr.PassedBlockNumbers, blockNumber)
+	cids := mcr.CIDsToReturn[blockNumber]
+	return cids, len(cids) == 0, nil
 }
 
 // RetrieveLastBlockNumber mock method
-func (*CIDRetriever) RetrieveLastBlockNumber() (int64, error) {
-	panic("implement me")
+func (mcr *CIDRetriever) RetrieveLastBlockNumber() (int64, error) {
+	if len(mcr.LastBlockNumbersToReturn) == 0 {
+		return 0, nil
+	}
+	lastBlock := mcr.LastBlockNumbersToReturn[0]
+	if len(mcr.LastBlockNumbersToReturn) > 1 {
+		mcr.LastBlockNumbersToReturn = mcr.LastBlockNumbersToReturn[1:]
+	}
+	return lastBlock, nil
 }
 
 // RetrieveFirstBlockNumber mock method
@@ -51,6 +66,16 @@ func (mcr *CIDRetriever) RetrieveGapsInData(int) ([]shared.Gap, error) {
 	return mcr.GapsToRetrieve, mcr.GapsToRetrieveErr
 }
 
+// RetrieveValidationGaps mock method
+func (mcr *CIDRetriever) RetrieveValidationGaps(int) ([]shared.Gap, error) {
+	return mcr.ValidationGapsToRetrieve, nil
+}
+
+// RetrieveValidationLevels mock method
+func (mcr *CIDRetriever) RetrieveValidationLevels() ([]shared.ValidationLevel, error) {
+	panic("implement me")
+}
+
 // SetGapsToRetrieve mock method
 func (mcr *CIDRetriever) SetGapsToRetrieve(gaps []shared.Gap) {
 	if mcr.GapsToRetrieve == nil {
@@ -0,0 +1,96 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package shared
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// DefaultSlowConsumerTimeout is how long, in milliseconds, a payload is held for a full subscription
+// with the BlockWithTimeout policy when the subscription does not specify a timeout
+const DefaultSlowConsumerTimeout = 5000
+
+// SlowConsumerMode enum for specifying what the super node does when a subscription's payload buffer is full
+type SlowConsumerMode uint
+
+const (
+	DropPayload SlowConsumerMode = iota
+	BlockWithTimeout
+	Disconnect
+	SpillToQueue
+)
+
+func (m SlowConsumerMode) String() string {
+	switch m {
+	case DropPayload:
+		return "drop"
+	case BlockWithTimeout:
+		return "block"
+	case Disconnect:
+		return "disconnect"
+	case SpillToQueue:
+		return "spill"
+	default:
+		return ""
+	}
+}
+
+func NewSlowConsumerMode(name string) (SlowConsumerMode, error) {
+	switch strings.ToLower(name) {
+	case "drop", "":
+		return DropPayload, nil
+	case "block", "wait":
+		return BlockWithTimeout, nil
+	case "disconnect", "close":
+		return Disconnect, nil
+	case "spill", "queue":
+		return SpillToQueue, nil
+	default:
+		return DropPayload, errors.New("unrecognized name for slow consumer mode")
+	}
+}
+
+// MarshalText satisfies encoding.TextMarshaler so that the mode is written by name in JSON subscription params
+func (m SlowConsumerMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText satisfies encoding.TextUnmarshaler so that the mode can be given by name in JSON subscription params
+func (m *SlowConsumerMode) UnmarshalText(text []byte) error {
+	mode, err := NewSlowConsumerMode(string(text))
+	if err != nil {
+		return err
+	}
+	*m = mode
+	return nil
+}
+
+// SlowConsumerPolicy is used by a subscriber to specify how payloads are handled when it falls behind
+type SlowConsumerPolicy struct {
+	Mode    SlowConsumerMode `json:"mode"`
+	Timeout uint64           `json:"timeout"` // milliseconds to wait for room in the buffer with the BlockWithTimeout mode
+}
+
+// BlockTimeout returns how long to wait for room in the subscription's buffer before dropping a payload
+func (p SlowConsumerPolicy) BlockTimeout() time.Duration {
+	if p.Timeout == 0 {
+		return DefaultSlowConsumerTimeout * time.Millisecond
+	}
+	return time.Duration(p.Timeout) * time.Millisecond
+}
@@ -36,3 +36,36 @@ type Gap struct {
 	Start uint64
 	Stop  uint64
 }
+
+// ValidationLevel is the number of blocks in the index that have been validated a given number of times
+type ValidationLevel struct {
+	TimesValidated int   `db:"times_validated" json:"timesValidated"`
+	Blocks         int64 `db:"blocks" json:"blocks"`
+}
+
+// Fault is an IPLD, or the index rows that reference it, found to be corrupt when verifying a block
+type Fault struct {
+	BlockNumber uint64 `json:"blockNumber"`
+	BlockHash   string `json:"blockHash"`
+	CID         string `json:"cid,omitempty"` // CID of the corrupt IPLD, empty if the fault is a root mismatch between several IPLDs and the header
+	Reason      string `json:"reason"`
+}
+
+// Mismatch is a difference found by a validator between a block fetched from the node and the data indexed for it, along with what was done about it
+type Mismatch struct {
+	BlockNumber uint64 `db:"block_number" json:"blockNumber"`
+	BlockHash   string `db:"block_hash" json:"blockHash"`
+	Object      string `db:"object" json:"object"`   // Type of the indexed object, e.g. header, transaction, or state
+	Key         string `db:"object_key" json:"key"`  // Identifies the object within the block, e.g. its hash or trie path
+	Field       string `db:"field" json:"field"`     // Column that differs, or the object's cid if the whole object is missing or extra
+	Indexed     string `db:"indexed" json:"indexed"` // Value that was indexed, empty if the object was missing
+	Fetched     string `db:"fetched" json:"fetched"` // Value fetched from the node, empty if the object was extra
+	Action      string `db:"action" json:"action"`   // Change made to the index to resolve the mismatch
+}
+
+// Actions taken by a validator to resolve a mismatch
+const (
+	InsertedAction  = "inserted"
+	RewrittenAction = "rewritten"
+	RemovedAction   = "removed"
+)
@@ -20,6 +20,8 @@ import (
 	"errors"
 
 	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
 )
 
 type Flag int32
@@ -27,6 +29,8 @@ type Flag int32
 const (
 	EmptyFlag Flag = iota
 	BackFillCompleteFlag
+	ReorgFlag
+	DisconnectFlag
 )
 
 // Subscription holds the information for an individual client subscription to the super node
@@ -34,15 +38,34 @@ type Subscription struct {
 	ID          rpc.ID
 	PayloadChan chan<- SubscriptionPayload
 	QuitChan    chan<- bool
+	// buffers live payloads while the subscription is being sent historical data, nil once it is receiving live data
+	handoff *handoff
+	// applies the subscription's slow consumer policy and counts its delivered and dropped payloads
+	delivery *delivery
 }
 
 // SubscriptionPayload is the struct for a super node stream payload
 // It carries data of a type specific to the chain being supported/queried and an error message
 type SubscriptionPayload struct {
-	Data   []byte `json:"data"` // e.g. for Ethereum rlp serialized eth.StreamPayload
-	Height int64  `json:"height"`
-	Err    string `json:"err"`  // field for error
-	Flag   Flag   `json:"flag"` // field for message
+	Data   []byte        `json:"data"` // e.g. for Ethereum rlp serialized eth.StreamPayload
+	Height int64         `json:"height"`
+	Cursor shared.Cursor `json:"cursor"` // position of this payload in the feed, can be used to resume a subscription
+	Err    string        `json:"err"`    // field for error
+	Flag   Flag          `json:"flag"`   // field for message
+	// heights of previously sent data which have been invalidated by a reorg, set alongside the ReorgFlag
+	InvalidatedHeights []int64 `json:"invalidatedHeights,omitempty"`
+}
+
+// JSONSubscriptionPayload is the struct for a super node JSON stream payload
+// It is the SubscriptionPayload with its data decoded into a JSON object specific to the chain being supported/queried
+type JSONSubscriptionPayload struct {
+	Data   interface{}   `json:"data"` // e.g. for Ethereum eth.JSONPayload
+	Height int64         `json:"height"`
+	Cursor shared.Cursor `json:"cursor"`
+	Err    string        `json:"err"`
+	Flag   Flag          `json:"flag"`
+	// heights of previously sent data which have been invalidated by a reorg, set alongside the ReorgFlag
+	InvalidatedHeights []int64 `json:"invalidatedHeights,omitempty"`
 }
 
 func (sp SubscriptionPayload) Error() error {
@@ -58,3 +81,17 @@ func (sp SubscriptionPayload) BackFillComplete() bool {
 	}
 	return false
 }
+
+func (sp SubscriptionPayload) Reorg() bool {
+	if sp.Flag == ReorgFlag {
+		return true
+	}
+	return false
+}
+
+func (sp SubscriptionPayload) Disconnected() bool {
+	if sp.Flag == DisconnectFlag {
+		return true
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package super_node
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/vulcanize/vulcanizedb/pkg/postgres"
+)
+
+// SubscriptionQueue durably holds the payloads of subscriptions that have fallen behind, in Postgres
+// Each subscription's payloads are kept in the order they were pushed until they can be delivered
+type SubscriptionQueue struct {
+	db *postgres.DB
+}
+
+// NewSubscriptionQueue creates a pointer to a new SubscriptionQueue
+func NewSubscriptionQueue(db *postgres.DB) *SubscriptionQueue {
+	return &SubscriptionQueue{
+		db: db,
+	}
+}
+
+// Push adds the payload to the back of the subscription's queue
+func (sq *SubscriptionQueue) Push(id rpc.ID, payload SubscriptionPayload) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = sq.db.Exec(`INSERT INTO public.subscription_queue (subscription_id, data) VALUES ($1, $2)`, string(id), data)
+	return err
+}
+
+// Pop removes the payload at the front of the subscription's queue and returns it
+// The returned bool is false if the queue is empty
+func (sq *SubscriptionQueue) Pop(id rpc.ID) (SubscriptionPayload, bool, error) {
+	var data []byte
+	err := sq.db.Get(&data, `DELETE FROM public.subscription_queue
+			WHERE id = (SELECT id FROM public.subscription_queue WHERE subscription_id = $1 ORDER BY id LIMIT 1)
+			RETURNING data`, string(id))
+	if err == sql.ErrNoRows {
+		return SubscriptionPayload{}, false, nil
+	}
+	if err != nil {
+		return SubscriptionPayload{}, false, err
+	}
+	var payload SubscriptionPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return SubscriptionPayload{}, false, err
+	}
+	return payload, true, nil
+}
+
+// Clear removes all of the payloads queued for the subscription
+func (sq *SubscriptionQueue) Clear(id rpc.ID) error {
+	_, err := sq.db.Exec(`DELETE FROM public.subscription_queue WHERE subscription_id = $1`, string(id))
+	return err
+}
@@ -0,0 +1,96 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package super_node_test
+
+import (
+	"github.com/ethereum/go-ethereum/rpc"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/vulcanizedb/pkg/postgres"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+var _ = Describe("SubscriptionQueue", func() {
+	var (
+		db    *postgres.DB
+		err   error
+		queue *super_node.SubscriptionQueue
+		first = super_node.SubscriptionPayload{
+			Data:   []byte{1, 2, 3},
+			Height: 1,
+			Cursor: shared.Cursor{Height: 1, Hash: "0x01"},
+		}
+		second = super_node.SubscriptionPayload{
+			Flag:               super_node.ReorgFlag,
+			Height:             2,
+			InvalidatedHeights: []int64{1},
+		}
+	)
+	BeforeEach(func() {
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+		queue = super_node.NewSubscriptionQueue(db)
+	})
+	AfterEach(func() {
+		_, err = db.Exec(`DELETE FROM public.subscription_queue`)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Pops each subscription's payloads in the order they were pushed", func() {
+		err = queue.Push(rpc.ID("0x1"), first)
+		Expect(err).ToNot(HaveOccurred())
+		err = queue.Push(rpc.ID("0x2"), first)
+		Expect(err).ToNot(HaveOccurred())
+		err = queue.Push(rpc.ID("0x1"), second)
+		Expect(err).ToNot(HaveOccurred())
+
+		payload, ok, err := queue.Pop(rpc.ID("0x1"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(payload).To(Equal(first))
+		payload, ok, err = queue.Pop(rpc.ID("0x1"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(payload).To(Equal(second))
+		_, ok, err = queue.Pop(rpc.ID("0x1"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		payload, ok, err = queue.Pop(rpc.ID("0x2"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(payload).To(Equal(first))
+	})
+
+	It("Clears a subscription's payloads", func() {
+		err = queue.Push(rpc.ID("0x1"), first)
+		Expect(err).ToNot(HaveOccurred())
+		err = queue.Push(rpc.ID("0x2"), second)
+		Expect(err).ToNot(HaveOccurred())
+		err = queue.Clear(rpc.ID("0x1"))
+		Expect(err).ToNot(HaveOccurred())
+
+		_, ok, err := queue.Pop(rpc.ID("0x1"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		payload, ok, err := queue.Pop(rpc.ID("0x2"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(payload).To(Equal(second))
+	})
+})
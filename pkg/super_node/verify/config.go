@@ -0,0 +1,120 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package verify
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/vulcanize/vulcanizedb/pkg/config"
+	"github.com/vulcanize/vulcanizedb/pkg/eth/core"
+	"github.com/vulcanize/vulcanizedb/pkg/postgres"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+	"github.com/vulcanize/vulcanizedb/utils"
+)
+
+// Env variables
+const (
+	VERIFY_CHAIN      = "VERIFY_CHAIN"
+	VERIFY_START      = "VERIFY_START"
+	VERIFY_STOP       = "VERIFY_STOP"
+	VERIFY_REPAIR     = "VERIFY_REPAIR"
+	VERIFY_BATCH_SIZE = "VERIFY_BATCH_SIZE"
+	VERIFY_REPORT     = "VERIFY_REPORT"
+)
+
+// Config holds the parameters needed to verify the IPLDs stored for a range of blocks
+type Config struct {
+	Chain      shared.ChainType // The chain whose IPLDs are verified
+	Start      uint64           // First block height to verify
+	Stop       uint64           // Last block height to verify
+	Repair     bool             // If true, blocks with faults are re-fetched from the node and republished
+	BatchSize  uint64           // Number of blocks re-fetched from the node per request when repairing
+	ReportFile string           // If set, the faults found are written to this file as JSON
+
+	// DB info
+	DB       *postgres.DB
+	DBConfig config.Database
+
+	HTTPClient  interface{}   // Client for the node that blocks are re-fetched from, only set when repairing
+	NodeInfo    core.Node     // Info for the associated node
+	ChainConfig interface{}   // Chain config (*params.ChainConfig or *chaincfg.Params) selected by network id or genesis file
+	Timeout     time.Duration // HTTP connection timeout in seconds
+}
+
+// NewVerifyConfig fills and returns a verify config from toml parameters
+func NewVerifyConfig() (*Config, error) {
+	c := new(Config)
+	var err error
+
+	viper.BindEnv("verify.chain", VERIFY_CHAIN)
+	viper.BindEnv("verify.start", VERIFY_START)
+	viper.BindEnv("verify.stop", VERIFY_STOP)
+	viper.BindEnv("verify.repair", VERIFY_REPAIR)
+	viper.BindEnv("verify.batchSize", VERIFY_BATCH_SIZE)
+	viper.BindEnv("verify.report", VERIFY_REPORT)
+	viper.BindEnv("verify.timeout", shared.HTTP_TIMEOUT)
+	viper.BindEnv("ethereum.httpPath", shared.ETH_HTTP_PATH)
+	viper.BindEnv("bitcoin.httpPath", shared.BTC_HTTP_PATH)
+
+	c.Start = uint64(viper.GetInt64("verify.start"))
+	c.Stop = uint64(viper.GetInt64("verify.stop"))
+	if c.Stop < c.Start {
+		return nil, fmt.Errorf("verify stop height %d is below the start height %d", c.Stop, c.Start)
+	}
+	c.Repair = viper.GetBool("verify.repair")
+	c.BatchSize = uint64(viper.GetInt64("verify.batchSize"))
+	c.ReportFile = viper.GetString("verify.report")
+	timeout := viper.GetInt("verify.timeout")
+	if timeout < 5 {
+		timeout = 5
+	}
+	c.Timeout = time.Second * time.Duration(timeout)
+
+	chain := viper.GetString("verify.chain")
+	c.Chain, err = shared.NewChainType(chain)
+	if err != nil {
+		return nil, err
+	}
+	c.ChainConfig, err = shared.GetChainConfig(c.Chain)
+	if err != nil {
+		return nil, err
+	}
+
+	switch c.Chain {
+	case shared.Ethereum:
+		if c.Repair {
+			ethHTTP := viper.GetString("ethereum.httpPath")
+			c.NodeInfo, c.HTTPClient, err = shared.GetEthNodeAndClient(fmt.Sprintf("http://%s", ethHTTP))
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			c.NodeInfo = shared.GetEthNodeInfo()
+		}
+	case shared.Bitcoin, shared.Omni:
+		btcHTTP := viper.GetString("bitcoin.httpPath")
+		c.NodeInfo, c.HTTPClient = shared.GetBtcNodeAndClient(btcHTTP)
+	}
+
+	c.DBConfig.Init()
+	db := utils.LoadPostgres(c.DBConfig, c.NodeInfo)
+	c.DB = &db
+	return c, nil
+}
@@ -0,0 +1,208 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package verify
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/vulcanizedb/pkg/ipfs"
+	"github.com/vulcanize/vulcanizedb/pkg/postgres"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
+)
+
+// Report is the outcome of a verification run
+type Report struct {
+	Chain     string         `json:"chain"`
+	Start     uint64         `json:"start"`
+	Stop      uint64         `json:"stop"`
+	Faults    []shared.Fault `json:"faults"`              // The faults found in the range
+	Repaired  []uint64       `json:"repaired,omitempty"`  // Heights that were re-fetched from the node and republished
+	Remaining []shared.Fault `json:"remaining,omitempty"` // Faults still found at the repaired heights afterwards
+}
+
+// Service verifies the IPLDs stored in public.blocks for a range of blocks, and optionally repairs the blocks found to be corrupt
+type Service struct {
+	// Interface for verifying the IPLDs stored for a block height
+	Verifier shared.IPLDVerifier
+	// Interfaces used to re-fetch, convert, and republish the blocks with faults when repairing
+	Fetcher   shared.PayloadFetcher
+	Converter shared.PayloadConverter
+	Publisher shared.IPLDPublisher
+	Indexer   shared.CIDIndexer
+
+	db        *postgres.DB
+	chain     shared.ChainType
+	start     uint64
+	stop      uint64
+	repair    bool
+	batchSize uint64
+}
+
+// NewVerifyService creates and returns a verify Service from the provided settings
+func NewVerifyService(settings *Config) (*Service, error) {
+	verifier, err := super_node.NewIPLDVerifier(settings.Chain, settings.DB)
+	if err != nil {
+		return nil, err
+	}
+	s := &Service{
+		Verifier:  verifier,
+		db:        settings.DB,
+		chain:     settings.Chain,
+		start:     settings.Start,
+		stop:      settings.Stop,
+		repair:    settings.Repair,
+		batchSize: settings.BatchSize,
+	}
+	if s.batchSize == 0 {
+		s.batchSize = super_node.DefaultMaxBatchSize
+	}
+	if !settings.Repair {
+		return s, nil
+	}
+	// repaired blocks are republished straight into public.blocks, which is what is being verified
+	s.Publisher, err = super_node.NewIPLDPublisher(settings.Chain, "", ipfs.RemoteClientConfig{}, settings.DB, shared.DirectPostgres)
+	if err != nil {
+		return nil, err
+	}
+	s.Indexer, err = super_node.NewCIDIndexer(settings.Chain, settings.DB, shared.DirectPostgres)
+	if err != nil {
+		return nil, err
+	}
+	s.Converter, err = super_node.NewPayloadConverter(settings.Chain, settings.ChainConfig, settings.HTTPClient, settings.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	s.Fetcher, err = super_node.NewPaylaodFetcher(settings.Chain, settings.HTTPClient, settings.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Verify verifies every block in the range, logging each fault found
+// If repairing, the corrupt IPLDs are removed and the blocks with faults are re-fetched from the node and republished, then verified again
+func (s *Service) Verify() (*Report, error) {
+	report := &Report{
+		Chain:  s.chain.String(),
+		Start:  s.start,
+		Stop:   s.stop,
+		Faults: make([]shared.Fault, 0),
+	}
+	faultyHeights := make([]uint64, 0)
+	for height := s.start; height <= s.stop; height++ {
+		faults, err := s.Verifier.Verify(height)
+		if err != nil {
+			return report, fmt.Errorf("%s verification error at block %d: %v", s.chain.String(), height, err)
+		}
+		for _, fault := range faults {
+			logFault(fault)
+		}
+		if len(faults) > 0 {
+			report.Faults = append(report.Faults, faults...)
+			faultyHeights = append(faultyHeights, height)
+		}
+		if (height-s.start+1)%1000 == 0 {
+			logrus.Infof("%s verified up to block %d, %d faults found so far", s.chain.String(), height, len(report.Faults))
+		}
+	}
+	if !s.repair || len(faultyHeights) == 0 {
+		return report, nil
+	}
+
+	logrus.Infof("repairing %d %s blocks", len(faultyHeights), s.chain.String())
+	if err := s.removeCorruptIPLDs(report.Faults); err != nil {
+		return report, err
+	}
+	for i := 0; i < len(faultyHeights); i += int(s.batchSize) {
+		end := i + int(s.batchSize)
+		if end > len(faultyHeights) {
+			end = len(faultyHeights)
+		}
+		if err := s.republish(faultyHeights[i:end]); err != nil {
+			return report, err
+		}
+	}
+	report.Repaired = faultyHeights
+	for _, height := range faultyHeights {
+		faults, err := s.Verifier.Verify(height)
+		if err != nil {
+			return report, fmt.Errorf("%s verification error at block %d: %v", s.chain.String(), height, err)
+		}
+		for _, fault := range faults {
+			logrus.Errorf("%s block %d still has a fault after repair: %s", s.chain.String(), height, fault.Reason)
+		}
+		report.Remaining = append(report.Remaining, faults...)
+	}
+	return report, nil
+}
+
+// removeCorruptIPLDs deletes the corrupt IPLDs from public.blocks, so that republishing does not skip over them as already present
+func (s *Service) removeCorruptIPLDs(faults []shared.Fault) error {
+	for _, fault := range faults {
+		if fault.CID == "" {
+			continue
+		}
+		key, err := shared.MultihashKeyFromCIDString(fault.CID)
+		if err != nil {
+			return err
+		}
+		if _, err := s.db.Exec(`DELETE FROM public.blocks WHERE key = $1`, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// republish re-fetches the blocks at the provided heights from the node, and publishes and indexes them again
+func (s *Service) republish(heights []uint64) error {
+	logrus.Debugf("%s re-fetching blocks %v", s.chain.String(), heights)
+	payloads, err := s.Fetcher.FetchAt(heights)
+	if err != nil {
+		return fmt.Errorf("%s repair fetcher error: %v", s.chain.String(), err)
+	}
+	if len(payloads) != len(heights) {
+		logrus.Errorf("%s repair fetcher returned %d of the %d blocks requested", s.chain.String(), len(payloads), len(heights))
+	}
+	for _, payload := range payloads {
+		ipldPayload, err := s.Converter.Convert(payload)
+		if err != nil {
+			return fmt.Errorf("%s repair converter error: %v", s.chain.String(), err)
+		}
+		cidPayload, err := s.Publisher.Publish(ipldPayload)
+		if err != nil {
+			return fmt.Errorf("%s repair publisher error: %v", s.chain.String(), err)
+		}
+		if err := s.Indexer.Index(cidPayload); err != nil {
+			return fmt.Errorf("%s repair indexer error: %v", s.chain.String(), err)
+		}
+	}
+	return nil
+}
+
+func logFault(fault shared.Fault) {
+	entry := logrus.WithFields(logrus.Fields{
+		"block_number": fault.BlockNumber,
+		"block_hash":   fault.BlockHash,
+	})
+	if fault.CID != "" {
+		entry = entry.WithField("cid", fault.CID)
+	}
+	entry.Warn(fault.Reason)
+}
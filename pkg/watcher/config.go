@@ -32,6 +32,7 @@ import (
 	"github.com/vulcanize/vulcanizedb/pkg/postgres"
 	"github.com/vulcanize/vulcanizedb/pkg/super_node/btc"
 	"github.com/vulcanize/vulcanizedb/pkg/super_node/eth"
+	"github.com/vulcanize/vulcanizedb/pkg/super_node/omni"
 	"github.com/vulcanize/vulcanizedb/pkg/super_node/shared"
 	shared2 "github.com/vulcanize/vulcanizedb/pkg/watcher/shared"
 	"github.com/vulcanize/vulcanizedb/utils"
@@ -57,6 +58,8 @@ type Config struct {
 	Source shared2.SourceType
 	// Info for the node
 	NodeInfo core.Node
+	// Chain config (*params.ChainConfig or *chaincfg.Params) for the network the super node is syncing
+	ChainConfig interface{}
 }
 
 func NewWatcherConfig() (*Config, error) {
@@ -79,7 +82,10 @@ func NewWatcherConfig() (*Config, error) {
 			return nil, err
 		}
 	case shared.Omni:
-		return nil, errors.New("omni chain type currently not supported")
+		c.SubscriptionConfig, err = omni.NewOmniSubscriptionConfig()
+		if err != nil {
+			return nil, err
+		}
 	default:
 		return nil, fmt.Errorf("unexpected chain type %s", c.Chain.String())
 	}
@@ -109,6 +115,10 @@ func NewWatcherConfig() (*Config, error) {
 		}
 		c.NodeInfo = nodeInfo
 		c.Client = cli
+		c.ChainConfig, err = shared.NewChainConfig(c.Chain, nodeInfo.NetworkID)
+		if err != nil {
+			return nil, err
+		}
 	default:
 		return nil, fmt.Errorf("unexpected data source type %s", c.Source.String())
 	}
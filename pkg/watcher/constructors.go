@@ -19,6 +19,8 @@ package watcher
 import (
 	"fmt"
 
+	"github.com/ethereum/go-ethereum/params"
+
 	"github.com/vulcanize/vulcanizedb/libraries/shared/streamer"
 	"github.com/vulcanize/vulcanizedb/pkg/eth/core"
 	"github.com/vulcanize/vulcanizedb/pkg/postgres"
@@ -43,10 +45,14 @@ func NewSuperNodeStreamer(source shared.SourceType, client interface{}) (shared.
 }
 
 // NewRepository constructs and returns a new Repository that satisfies the shared.Repository interface for the specified chain
-func NewRepository(chain shared2.ChainType, db *postgres.DB, triggerFuncs []string) (shared.Repository, error) {
+func NewRepository(chain shared2.ChainType, db *postgres.DB, triggerFuncs []string, chainConfig interface{}) (shared.Repository, error) {
 	switch chain {
 	case shared2.Ethereum:
-		return eth.NewRepository(db, triggerFuncs), nil
+		ethConfig, ok := chainConfig.(*params.ChainConfig)
+		if !ok {
+			return nil, fmt.Errorf("ethereum NewRepository constructor expected config type %T got %T", &params.ChainConfig{}, chainConfig)
+		}
+		return eth.NewRepository(db, triggerFuncs, ethConfig), nil
 	default:
 		return nil, fmt.Errorf("NewRepository constructor unexpected chain type %s", chain.String())
 	}
@@ -42,10 +42,10 @@ type Repository struct {
 }
 
 // NewRepository returns a new eth.Repository that satisfies the shared.Repository interface
-func NewRepository(db *postgres.DB, triggerFunctions []string) shared.Repository {
+func NewRepository(db *postgres.DB, triggerFunctions []string, chainConfig *params.ChainConfig) shared.Repository {
 	return &Repository{
 		cidIndexer:       eth.NewCIDIndexer(db),
-		converter:        NewWatcherConverter(params.MainnetChainConfig),
+		converter:        NewWatcherConverter(chainConfig),
 		db:               db,
 		triggerFunctions: triggerFunctions,
 		deleteCalls:      0,
@@ -59,7 +59,7 @@ type Service struct {
 
 // NewWatcher returns a new Service which satisfies the Watcher interface
 func NewWatcher(c *Config, quitChan chan bool) (Watcher, error) {
-	repo, err := NewRepository(c.SubscriptionConfig.ChainType(), c.DB, c.TriggerFunctions)
+	repo, err := NewRepository(c.SubscriptionConfig.ChainType(), c.DB, c.TriggerFunctions, c.ChainConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -127,6 +127,11 @@ func (s *Service) combinedQueuing(wg *sync.WaitGroup, sub *rpc.ClientSubscriptio
 					logrus.Error(payload.Error())
 					continue
 				}
+				// If the payload is a reorg notification there is no data to queue, the replacement data follows it
+				if payload.Reorg() {
+					logrus.Warnf("super node reported a reorg invalidating heights %v", payload.InvalidatedHeights)
+					continue
+				}
 				if payload.Height == atomic.LoadInt64(s.payloadIndex) {
 					// If the data is at our current index it is ready to be processed
 					// add it to the ready data queue and increment the index